@@ -1,6 +1,13 @@
 package decredplugin
 
-import "encoding/json"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 // Plugin settings, kinda doesn;t go here but for now it is fine
 const (
@@ -10,11 +17,49 @@ const (
 	CmdCastVotes         = "castvotes"
 	CmdBestBlock         = "bestblock"
 	CmdProposalVotes     = "proposalvotes"
-	MDStreamVotes        = 13 // Votes
+	CmdVoteReceipt       = "votereceipt"
+	CmdAuditSnapshot     = "auditsnapshot"
+	MDStreamVotes        = 13 // Journal of cast votes, see util/journal
 	MDStreamVoteBits     = 14 // Vote bits and mask
 	MDStreamVoteSnapshot = 15 // Vote tickets and start/end parameters
 )
 
+// VoteT is the type of a vote.
+type VoteT int
+
+// Vote types.  Votes that predate the vote types are approval votes.
+const (
+	VoteTypeInvalid      VoteT = 0 // Invalid vote type
+	VoteTypeApproval     VoteT = 1 // Vote to approve or reject the proposal
+	VoteTypeMultiOption  VoteT = 2 // Poll with more than two outcomes
+	VoteTypeRankedChoice VoteT = 3 // Poll where voters rank the options
+)
+
+// RankingSeparator separates the bits of the ranked options in the vote bit
+// of a ranked-choice cast vote, e.g. "4,1" ranks the option with bits 0x4
+// first and the one with bits 0x1 second.  Options may be left unranked.
+const RankingSeparator = ","
+
+// Vote policy.  A percentage of zero in a vote stands for the default one.
+const (
+	VoteDurationMin             = 2016     // Minimum vote duration in blocks
+	VoteDurationMax             = 2016 * 2 // Maximum vote duration in blocks
+	VoteQuorumPercentageMin     = 1        // Minimum quorum percentage
+	VoteQuorumPercentageMax     = 100      // Maximum quorum percentage
+	VoteQuorumPercentageDefault = 20       // Quorum percentage of older votes
+	VotePassPercentageMin       = 51       // Minimum pass percentage
+	VotePassPercentageMax       = 100      // Maximum pass percentage
+	VotePassPercentageDefault   = 60       // Pass percentage of older votes
+	VoteOptionsMax              = 16       // Maximum number of vote options
+
+	// Every approval vote offers to approve and to reject the proposal.
+	// Voters that abstain may pick the abstain option when the vote offers
+	// it.
+	VoteOptionIDApprove = "yes"
+	VoteOptionIDReject  = "no"
+	VoteOptionIDAbstain = "abstain"
+)
+
 // CastVote is a signed vote.
 type CastVote struct {
 	Token     string `json:"token"`     // Proposal ID
@@ -82,11 +127,149 @@ type VoteOption struct {
 }
 
 // Vote represents the vote options for vote that is identified by its token.
+// The quorum percentage is the percentage of the eligible tickets that must
+// vote for the vote to count, and the pass percentage is the percentage of
+// the cast votes that an option must receive to win: the approve option of an
+// approval vote, or any option of a multi-option vote.  The votes of a
+// ranked-choice vote go to the most preferred option that was not eliminated
+// yet.
 type Vote struct {
-	Token    string `json:"token"`    // Token that identifies vote
-	Mask     uint64 `json:"mask"`     // Valid votebits
-	Duration uint32 `json:"duration"` // Duration in blocks
-	Options  []VoteOption
+	Token            string `json:"token"`                      // Token that identifies vote
+	Type             VoteT  `json:"type,omitempty"`             // Type of vote
+	Mask             uint64 `json:"mask"`                       // Valid votebits
+	Duration         uint32 `json:"duration"`                   // Duration in blocks
+	QuorumPercentage uint32 `json:"quorumpercentage,omitempty"` // Percentage of eligible tickets required to vote
+	PassPercentage   uint32 `json:"passpercentage,omitempty"`   // Percentage of cast votes required to pass
+	Options          []VoteOption
+}
+
+// SetDefaults sets the type and the percentages that were not provided to
+// the default ones.
+func (v *Vote) SetDefaults() {
+	if v.Type == VoteTypeInvalid {
+		v.Type = VoteTypeApproval
+	}
+	if v.QuorumPercentage == 0 {
+		v.QuorumPercentage = VoteQuorumPercentageDefault
+	}
+	if v.PassPercentage == 0 {
+		v.PassPercentage = VotePassPercentageDefault
+	}
+}
+
+// VerifyOptions returns an error if the vote options are malformed: every
+// option must be a single bit that the mask covers and the bits and ids of
+// the options must be distinct.  An approval vote offers the approve and
+// reject options and optionally the abstain one, while multi-option and
+// ranked-choice votes offer from two to VoteOptionsMax described options.
+// The defaults must be set first.
+func (v *Vote) VerifyOptions() error {
+	if len(v.Options) > VoteOptionsMax {
+		return fmt.Errorf("too many options: %v (%v)", len(v.Options),
+			VoteOptionsMax)
+	}
+
+	var bits uint64
+	ids := make(map[string]bool, len(v.Options))
+	for _, o := range v.Options {
+		switch {
+		case o.Id == "":
+			return fmt.Errorf("invalid option id: %q", o.Id)
+		case v.Type == VoteTypeApproval && o.Id != VoteOptionIDApprove &&
+			o.Id != VoteOptionIDReject && o.Id != VoteOptionIDAbstain:
+			return fmt.Errorf("invalid approval option: %v", o.Id)
+		case (v.Type == VoteTypeMultiOption ||
+			v.Type == VoteTypeRankedChoice) && o.Description == "":
+			return fmt.Errorf("missing option description: %v", o.Id)
+		case ids[o.Id]:
+			return fmt.Errorf("duplicate option id: %v", o.Id)
+		case o.Bits == 0 || o.Bits&(o.Bits-1) != 0:
+			return fmt.Errorf("invalid option bits: %v 0x%x", o.Id,
+				o.Bits)
+		case bits&o.Bits != 0:
+			return fmt.Errorf("duplicate option bits: %v 0x%x", o.Id,
+				o.Bits)
+		case v.Mask&o.Bits != o.Bits:
+			return fmt.Errorf("option bits not in mask: %v 0x%x",
+				o.Id, o.Bits)
+		}
+		ids[o.Id] = true
+		bits |= o.Bits
+	}
+
+	switch v.Type {
+	case VoteTypeApproval:
+		for _, id := range []string{VoteOptionIDReject,
+			VoteOptionIDApprove} {
+			if !ids[id] {
+				return fmt.Errorf("missing option: %v", id)
+			}
+		}
+	case VoteTypeMultiOption, VoteTypeRankedChoice:
+		if len(v.Options) < 2 {
+			return fmt.Errorf("too few options: %v (2)",
+				len(v.Options))
+		}
+	default:
+		return fmt.Errorf("invalid vote type: %v", v.Type)
+	}
+	return nil
+}
+
+// VerifyParams returns an error if the duration or the percentages of the
+// vote are outside of the vote policy.  The defaults must be set first.
+func (v *Vote) VerifyParams() error {
+	switch {
+	case v.Duration < VoteDurationMin || v.Duration > VoteDurationMax:
+		return fmt.Errorf("invalid duration: %v (%v - %v)", v.Duration,
+			VoteDurationMin, VoteDurationMax)
+	case v.QuorumPercentage < VoteQuorumPercentageMin ||
+		v.QuorumPercentage > VoteQuorumPercentageMax:
+		return fmt.Errorf("invalid quorum percentage: %v (%v - %v)",
+			v.QuorumPercentage, VoteQuorumPercentageMin,
+			VoteQuorumPercentageMax)
+	case v.PassPercentage < VotePassPercentageMin ||
+		v.PassPercentage > VotePassPercentageMax:
+		return fmt.Errorf("invalid pass percentage: %v (%v - %v)",
+			v.PassPercentage, VotePassPercentageMin,
+			VotePassPercentageMax)
+	}
+	return nil
+}
+
+// DecodeRanking decodes the vote bit of a ranked-choice cast vote into the bits
+// of the ranked options, most preferred first.  It returns an error unless the
+// ranking ranks distinct options of the vote.
+func (v *Vote) DecodeRanking(voteBit string) ([]uint64, error) {
+	ranks := strings.Split(voteBit, RankingSeparator)
+	if len(ranks) > len(v.Options) {
+		return nil, fmt.Errorf("too many ranks: %v", len(ranks))
+	}
+
+	ranking := make([]uint64, 0, len(ranks))
+	ranked := make(map[uint64]bool, len(ranks))
+	for _, r := range ranks {
+		bits, err := strconv.ParseUint(r, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank: %q", r)
+		}
+		var found bool
+		for _, o := range v.Options {
+			if o.Bits == bits {
+				found = true
+				break
+			}
+		}
+		switch {
+		case !found:
+			return nil, fmt.Errorf("bit not found 0x%x", bits)
+		case ranked[bits]:
+			return nil, fmt.Errorf("duplicate rank 0x%x", bits)
+		}
+		ranked[bits] = true
+		ranking = append(ranking, bits)
+	}
+	return ranking, nil
 }
 
 // EncodeVote encodes Vote into a JSON byte slice.
@@ -117,12 +300,42 @@ type StartVote struct {
 	Vote Vote `json:"vote"` // Vote + options
 }
 
-// StartVoteReply is the reply to StartVote.
+// StartVoteReply is the reply to StartVote.  The signature of politeiad
+// commits it to the snapshot and the window of the vote; votes that were
+// started before the signature was introduced don't have one.
 type StartVoteReply struct {
-	StartBlockHeight string   `json:"startblockheight"` // Block height
-	StartBlockHash   string   `json:"startblockhash"`   // Block hash
-	EndHeight        string   `json:"endheight"`        // Height of vote end
-	EligibleTickets  []string `json:"eligibletickets"`  // Valid voting tickets
+	StartBlockHeight string   `json:"startblockheight"`    // Block height
+	StartBlockHash   string   `json:"startblockhash"`      // Block hash
+	EndHeight        string   `json:"endheight"`           // Height of vote end
+	EligibleTickets  []string `json:"eligibletickets"`     // Valid voting tickets
+	Signature        string   `json:"signature,omitempty"` // Signature of SignatureMessage by politeiad
+}
+
+// SignatureMessage returns the message that politeiad signs for the provided
+// vote: one line for each of the token, the start block hash, the start and
+// end heights, the hex encoded SHA256 digest of the eligible tickets joined in
+// order by commas, the type, the mask, the duration, the quorum and pass
+// percentages and the hex encoded SHA256 digest of the JSON encoded options.
+// Free form fields are only included through their digests so that no field
+// can spill into the next one.
+func (s *StartVoteReply) SignatureMessage(vote Vote) []byte {
+	tickets := sha256.Sum256([]byte(strings.Join(s.EligibleTickets, ",")))
+	// Encoding the options can't fail.
+	b, _ := json.Marshal(vote.Options)
+	options := sha256.Sum256(b)
+	return []byte(strings.Join([]string{
+		vote.Token,
+		s.StartBlockHash,
+		s.StartBlockHeight,
+		s.EndHeight,
+		hex.EncodeToString(tickets[:]),
+		strconv.FormatInt(int64(vote.Type), 10),
+		strconv.FormatUint(vote.Mask, 10),
+		strconv.FormatUint(uint64(vote.Duration), 10),
+		strconv.FormatUint(uint64(vote.QuorumPercentage), 10),
+		strconv.FormatUint(uint64(vote.PassPercentage), 10),
+		hex.EncodeToString(options[:]),
+	}, "\n"))
 }
 
 // EncodeStartVoteReply encodes StartVoteReply into a JSON byte slice.
@@ -199,3 +412,127 @@ func DecodeVoteResultsReply(payload []byte) (*VoteResultsReply, error) {
 
 	return &v, nil
 }
+
+// VoteReceipt retrieves the vote that a ticket cast on a proposal.
+type VoteReceipt struct {
+	Token  string `json:"token"`  // Censorship token
+	Ticket string `json:"ticket"` // Ticket ID
+}
+
+// VoteReceiptReply is the reply to VoteReceipt.  The signature is the one of
+// the CastVoteReply that accepted the vote.
+type VoteReceiptReply struct {
+	CastVote  CastVote `json:"castvote"`  // Stored vote
+	Signature string   `json:"signature"` // Signature of the ClientSignature by politeiad
+}
+
+// EncodeVoteReceipt encodes VoteReceipt into a JSON byte slice.
+func EncodeVoteReceipt(v VoteReceipt) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeVoteReceipt decodes a JSON byte slice into a VoteReceipt.
+func DecodeVoteReceipt(payload []byte) (*VoteReceipt, error) {
+	var v VoteReceipt
+
+	err := json.Unmarshal(payload, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// EncodeVoteReceiptReply encodes VoteReceiptReply into a JSON byte slice.
+func EncodeVoteReceiptReply(v VoteReceiptReply) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeVoteReceiptReply decodes a JSON byte slice into a VoteReceiptReply.
+func DecodeVoteReceiptReply(payload []byte) (*VoteReceiptReply, error) {
+	var v VoteReceiptReply
+
+	err := json.Unmarshal(payload, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// AuditSnapshot verifies that the stored vote snapshot of a proposal is the
+// ticket pool at the recorded snapshot block.
+type AuditSnapshot struct {
+	Token string `json:"token"` // Censorship token
+}
+
+// AuditSnapshotReply is the report of a snapshot audit.  The snapshot is
+// reproduced when the recorded block is still in the main chain and the
+// stored eligible tickets are the ticket pool at that block.
+type AuditSnapshotReply struct {
+	Token            string   `json:"token"`            // Censorship token
+	StartBlockHeight string   `json:"startblockheight"` // Recorded snapshot block height
+	StartBlockHash   string   `json:"startblockhash"`   // Recorded snapshot block hash
+	BlockHash        string   `json:"blockhash"`        // Hash of the main chain block at the snapshot height
+	StoredTickets    int      `json:"storedtickets"`    // Number of stored eligible tickets
+	PoolTickets      int      `json:"pooltickets"`      // Number of tickets in the pool at the snapshot block
+	Missing          []string `json:"missing"`          // Pool tickets that were not stored
+	Unexpected       []string `json:"unexpected"`       // Stored tickets that are not in the pool
+	Duplicates       []string `json:"duplicates"`       // Tickets that were stored more than once
+	Match            bool     `json:"match"`            // Whether the snapshot is reproduced
+}
+
+// EncodeAuditSnapshot encodes AuditSnapshot into a JSON byte slice.
+func EncodeAuditSnapshot(a AuditSnapshot) ([]byte, error) {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeAuditSnapshot decodes a JSON byte slice into an AuditSnapshot.
+func DecodeAuditSnapshot(payload []byte) (*AuditSnapshot, error) {
+	var a AuditSnapshot
+
+	err := json.Unmarshal(payload, &a)
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// EncodeAuditSnapshotReply encodes AuditSnapshotReply into a JSON byte slice.
+func EncodeAuditSnapshotReply(a AuditSnapshotReply) ([]byte, error) {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeAuditSnapshotReply decodes a JSON byte slice into an
+// AuditSnapshotReply.
+func DecodeAuditSnapshotReply(payload []byte) (*AuditSnapshotReply, error) {
+	var a AuditSnapshotReply
+
+	err := json.Unmarshal(payload, &a)
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
@@ -6,6 +6,7 @@ var (
 	// validMimeTypesList is a list of all acceptable MIME types that
 	// can be communicated between client and server.
 	validMimeTypesList = []string{
+		"image/jpeg",
 		"image/png",
 		"image/svg+xml",
 		"text/plain",
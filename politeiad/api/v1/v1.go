@@ -55,6 +55,10 @@ const (
 	ErrorStatusDuplicateFilename             ErrorStatusT = 12
 	ErrorStatusFileNotFound                  ErrorStatusT = 13
 	ErrorStatusNoChanges                     ErrorStatusT = 14
+	ErrorStatusRecordNotFound                ErrorStatusT = 15
+	ErrorStatusInvalidRecordStatus           ErrorStatusT = 16
+	ErrorStatusVoteStarted                   ErrorStatusT = 17
+	ErrorStatusVoteNotFound                  ErrorStatusT = 18
 
 	// Record status codes (set and get)
 	RecordStatusInvalid           RecordStatusT = 0 // Invalid status
@@ -92,6 +96,10 @@ var (
 		ErrorStatusDuplicateFilename:             "duplicate filename",
 		ErrorStatusFileNotFound:                  "file not found",
 		ErrorStatusNoChanges:                     "no changes in record",
+		ErrorStatusRecordNotFound:                "record not found",
+		ErrorStatusInvalidRecordStatus:           "invalid record status",
+		ErrorStatusVoteStarted:                   "vote already started",
+		ErrorStatusVoteNotFound:                  "vote not found",
 	}
 
 	// RecordStatus converts record status codes to human readable text.
@@ -46,6 +46,17 @@ func (c ContentVerificationError) Error() string {
 	return fmt.Sprintf("%v: %v", v1.ErrorStatus[c.ErrorCode], c.ErrorContext)
 }
 
+// PluginUserError is returned when a plugin command can't be carried out
+// because of the request.
+type PluginUserError struct {
+	ErrorCode    v1.ErrorStatusT
+	ErrorContext []string
+}
+
+func (p PluginUserError) Error() string {
+	return fmt.Sprintf("%v: %v", v1.ErrorStatus[p.ErrorCode], p.ErrorContext)
+}
+
 type File struct {
 	Name    string // Basename of the file
 	MIME    string // MIME type
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"time"
 
@@ -19,9 +20,11 @@ import (
 	"github.com/decred/dcrd/wire"
 	dcrdataapi "github.com/decred/dcrdata/api/types"
 	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/politeiad/backend"
 	"github.com/decred/politeia/util"
+	"github.com/decred/politeia/util/journal"
 )
 
 // XXX plugins really need to become an interface. Run with this for now.
@@ -34,7 +37,8 @@ var (
 	decredPluginSettings map[string]string // [key]setting
 
 	// cached values, requires lock
-	decredPluginVoteCache = make(map[string]*decredplugin.Vote) // [token]vote
+	decredPluginVoteCache         = make(map[string]*decredplugin.Vote) // [token]vote
+	decredPluginVoteSnapshotCache = make(map[string]*voteSnapshot)      // [token]snapshot
 )
 
 func getDecredPlugin(testnet bool) backend.Plugin {
@@ -247,17 +251,45 @@ func (g *gitBackEnd) pluginStartVote(payload string) (string, error) {
 		return "", fmt.Errorf("DecodeVote %v", err)
 	}
 
-	// XXX verify vote bits are sane
-
-	// XXX verify proposal exists
-
-	// XXX verify proposal is in the right state
+	// Make sure the vote parameters are within policy.  The defaults are
+	// stored along with the vote so that its outcome doesn't depend on
+	// later changes of the defaults.
+	// XXX calculate the duration range for testnet instead of using hard
+	// coded values.
+	vote.SetDefaults()
+	err = vote.VerifyParams()
+	if err != nil {
+		// XXX return a user error instead of an internal error
+		return "", err
+	}
+	err = vote.VerifyOptions()
+	if err != nil {
+		// XXX return a user error instead of an internal error
+		return "", err
+	}
+	voteb, err := decredplugin.EncodeVote(*vote)
+	if err != nil {
+		return "", fmt.Errorf("EncodeVote: %v", err)
+	}
 
 	token, err := util.ConvertStringToken(vote.Token)
 	if err != nil {
 		return "", fmt.Errorf("ConvertStringToken %v", err)
 	}
 
+	// Make sure the record can be voted on before taking the snapshot.
+	// The record is claimed until its vote bits are stored so that
+	// concurrent requests can't start the vote twice.
+	err = g.claimVoteRecord(token)
+	if err != nil {
+		return "", err
+	}
+	defer g.releaseVoteRecord(token)
+	fi, err := decredPluginFullIdentity()
+	if err != nil {
+		return "", err
+	}
+
 	// 1. Get best block
 	bb, err := bestBlock()
 	if err != nil {
@@ -279,14 +311,6 @@ func (g *gitBackEnd) pluginStartVote(payload string) (string, error) {
 		return "", fmt.Errorf("snapshot %v", err)
 	}
 
-	// Make sure vote duration isn't too large. Assume < 2 weeks
-	// XXX calculate this value for testnet instead of using hard coded values.
-	if vote.Duration < 2016 || vote.Duration > 2016*2 {
-		// XXX return a user error instead of an internal error
-		return "", fmt.Errorf("invalid duration: %v (%v - %v)",
-			vote.Duration, 2016, 2016*2)
-	}
-
 	svr := decredplugin.StartVoteReply{
 		StartBlockHeight: strconv.FormatUint(uint64(snapshotBlock.Height),
 			10),
@@ -295,6 +319,8 @@ func (g *gitBackEnd) pluginStartVote(payload string) (string, error) {
 			vote.Duration), 10),
 		EligibleTickets: snapshot,
 	}
+	signature := fi.SignMessage(svr.SignatureMessage(*vote))
+	svr.Signature = hex.EncodeToString(signature[:])
 	svrb, err := decredplugin.EncodeStartVoteReply(svr)
 	if err != nil {
 		return "", fmt.Errorf("EncodeStartVoteReply: %v", err)
@@ -304,7 +330,7 @@ func (g *gitBackEnd) pluginStartVote(payload string) (string, error) {
 	err = g.UpdateVettedMetadata(token, nil, []backend.MetadataStream{
 		{
 			ID:      decredplugin.MDStreamVoteBits,
-			Payload: payload, // Contains incoming vote request
+			Payload: string(voteb), // Contains vote request and defaults
 		},
 		{
 			ID:      decredplugin.MDStreamVoteSnapshot,
@@ -322,6 +348,82 @@ func (g *gitBackEnd) pluginStartVote(payload string) (string, error) {
 	return string(svrb), nil
 }
 
+// decredPluginFullIdentity returns the identity that the plugin signs with.
+func decredPluginFullIdentity() (*identity.FullIdentity, error) {
+	// XXX this should become part of some sort of context
+	fiJSON, ok := decredPluginSettings[decredPluginIdentity]
+	if !ok {
+		return nil, fmt.Errorf("full identity not set")
+	}
+	return identity.UnmarshalFullIdentity([]byte(fiJSON))
+}
+
+// verifyVoteRecord returns a plugin user error unless the provided token is
+// the one of a vetted record whose vote did not start.  Censored and locked
+// records can't be voted on.
+func (g *gitBackEnd) verifyVoteRecord(token []byte) error {
+	record, err := g.getRecordLock(token, g.vetted, false)
+	if err == backend.ErrRecordNotFound {
+		return backend.PluginUserError{
+			ErrorCode:    pd.ErrorStatusRecordNotFound,
+			ErrorContext: []string{hex.EncodeToString(token)},
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if record.RecordMetadata.Status != backend.MDStatusVetted {
+		return backend.PluginUserError{
+			ErrorCode: pd.ErrorStatusInvalidRecordStatus,
+			ErrorContext: []string{
+				backend.MDStatus[record.RecordMetadata.Status],
+			},
+		}
+	}
+	for _, v := range record.Metadata {
+		if v.ID == decredplugin.MDStreamVoteBits {
+			return backend.PluginUserError{
+				ErrorCode:    pd.ErrorStatusVoteStarted,
+				ErrorContext: []string{hex.EncodeToString(token)},
+			}
+		}
+	}
+
+	return nil
+}
+
+// claimVoteRecord verifies that the vote of the provided record can be
+// started and marks it as being started.  It returns a plugin user error if
+// the vote of the record is already being started.  Claimed records must be
+// released with releaseVoteRecord.
+func (g *gitBackEnd) claimVoteRecord(token []byte) error {
+	id := hex.EncodeToString(token)
+
+	g.votesMtx.Lock()
+	defer g.votesMtx.Unlock()
+
+	if _, ok := g.votesStarting[id]; ok {
+		return backend.PluginUserError{
+			ErrorCode:    pd.ErrorStatusVoteStarted,
+			ErrorContext: []string{id},
+		}
+	}
+	err := g.verifyVoteRecord(token)
+	if err != nil {
+		return err
+	}
+	g.votesStarting[id] = struct{}{}
+
+	return nil
+}
+
+// releaseVoteRecord releases a record that was claimed by claimVoteRecord.
+func (g *gitBackEnd) releaseVoteRecord(token []byte) {
+	g.votesMtx.Lock()
+	delete(g.votesStarting, hex.EncodeToString(token))
+	g.votesMtx.Unlock()
+}
+
 // validateVote validates that vote is signed correctly.
 func (g *gitBackEnd) validateVote(token, ticket, votebit, signature string) error {
 	// Figure out addresses
@@ -362,11 +464,26 @@ func (i invalidVoteBitError) Error() string {
 }
 
 // _validateVoteBit iterates over all vote bits and ensure the sent in vote bit
-// exists.
-func _validateVoteBit(vote decredplugin.Vote, bit uint64) error {
+// exists.  The vote bit of a ranked-choice vote must rank distinct options.
+func _validateVoteBit(vote decredplugin.Vote, voteBit string) error {
 	if len(vote.Options) == 0 {
 		return fmt.Errorf("_validateVoteBit vote corrupt")
 	}
+	if vote.Type == decredplugin.VoteTypeRankedChoice {
+		_, err := vote.DecodeRanking(voteBit)
+		if err != nil {
+			return invalidVoteBitError{
+				err: err,
+			}
+		}
+		return nil
+	}
+	bit, err := strconv.ParseUint(voteBit, 16, 64)
+	if err != nil {
+		return invalidVoteBitError{
+			err: fmt.Errorf("invalid bit %q", voteBit),
+		}
+	}
 	if bit == 0 {
 		return invalidVoteBitError{
 			err: fmt.Errorf("invalid bit 0x%x", bit),
@@ -386,12 +503,7 @@ func _validateVoteBit(vote decredplugin.Vote, bit uint64) error {
 // This function is expensive due to it's filesystem touches and therefore is
 // lazily cached. This could stand a rewrite.
 func (g *gitBackEnd) validateVoteBit(token, bit string) error {
-	b, err := strconv.ParseUint(bit, 16, 64)
-	if err != nil {
-		return err
-	}
-
-	err = g.lock.Lock(LockDuration)
+	err := g.lock.Lock(LockDuration)
 	if err != nil {
 		return err
 	}
@@ -407,7 +519,7 @@ func (g *gitBackEnd) validateVoteBit(token, bit string) error {
 
 	vote, ok := decredPluginVoteCache[token]
 	if ok {
-		return _validateVoteBit(*vote, b)
+		return _validateVoteBit(*vote, bit)
 	}
 
 	// git checkout master
@@ -438,7 +550,159 @@ func (g *gitBackEnd) validateVoteBit(token, bit string) error {
 
 	decredPluginVoteCache[token] = vote
 
-	return _validateVoteBit(*vote, b)
+	return _validateVoteBit(*vote, bit)
+}
+
+// voteSnapshot is the part of the snapshot of a vote that cast votes are
+// checked against.
+type voteSnapshot struct {
+	endHeight uint64              // Height of the last block of the vote
+	eligible  map[string]struct{} // [ticket]
+}
+
+type invalidVoteTicketError struct {
+	err error
+}
+
+func (i invalidVoteTicketError) Error() string {
+	return i.err.Error()
+}
+
+// _validateVoteTicket ensures that the provided ticket is eligible to vote and
+// that the vote did not end at the provided best block.
+func _validateVoteTicket(snapshot voteSnapshot, ticket string, bestBlock uint64) error {
+	if bestBlock > snapshot.endHeight {
+		return invalidVoteTicketError{
+			err: fmt.Errorf("vote ended at height %v",
+				snapshot.endHeight),
+		}
+	}
+	if _, ok := snapshot.eligible[ticket]; !ok {
+		return invalidVoteTicketError{
+			err: fmt.Errorf("ticket not eligible to vote %v", ticket),
+		}
+	}
+	return nil
+}
+
+// validateVoteTicket ensures that the passed in ticket may vote on the
+// proposal at the passed in best block.  Like validateVoteBit the snapshot is
+// lazily cached.
+func (g *gitBackEnd) validateVoteTicket(token, ticket string, bestBlock uint64) error {
+	err := g.lock.Lock(LockDuration)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := g.lock.Unlock()
+		if err != nil {
+			log.Errorf("validateVoteTicket unlock error: %v", err)
+		}
+	}()
+	if g.shutdown {
+		return backend.ErrShutdown
+	}
+
+	snapshot, ok := decredPluginVoteSnapshotCache[token]
+	if ok {
+		return _validateVoteTicket(*snapshot, ticket, bestBlock)
+	}
+
+	svr, err := g.loadStartVoteReply(token)
+	if err != nil {
+		return err
+	}
+	endHeight, err := strconv.ParseUint(svr.EndHeight, 10, 64)
+	if err != nil {
+		return err
+	}
+	snapshot = &voteSnapshot{
+		endHeight: endHeight,
+		eligible:  make(map[string]struct{}, len(svr.EligibleTickets)),
+	}
+	for _, v := range svr.EligibleTickets {
+		snapshot.eligible[v] = struct{}{}
+	}
+
+	decredPluginVoteSnapshotCache[token] = snapshot
+
+	return _validateVoteTicket(*snapshot, ticket, bestBlock)
+}
+
+// loadStartVoteReply loads the vote snapshot of the vetted record with the
+// provided token from the master branch.
+//
+// This function must be called WITH the lock held.
+func (g *gitBackEnd) loadStartVoteReply(token string) (*decredplugin.StartVoteReply, error) {
+	// git checkout master
+	err := g.gitCheckout(g.vetted, "master")
+	if err != nil {
+		return nil, err
+	}
+
+	// Load md stream
+	f, err := os.Open(mdFilename(g.vetted, token,
+		decredplugin.MDStreamVoteSnapshot))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var svr decredplugin.StartVoteReply
+	d := json.NewDecoder(f)
+	err = d.Decode(&svr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &svr, nil
+}
+
+// decodeCastVoteEntry decodes the cast vote of a vote journal entry.
+func decodeCastVoteEntry(e journal.Entry) (*decredplugin.CastVote, error) {
+	if e.Action != journal.ActionAdd {
+		return nil, fmt.Errorf("invalid cast vote action: %v", e.Action)
+	}
+
+	var cv decredplugin.CastVote
+	err := json.Unmarshal(e.Payload, &cv)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cv, nil
+}
+
+// castVoteKey returns the journal key of a cast vote.  A ticket votes once on a
+// proposal.
+func castVoteKey(e journal.Entry) (string, error) {
+	cv, err := decodeCastVoteEntry(e)
+	if err != nil {
+		return "", err
+	}
+	return cv.Token + cv.Ticket, nil
+}
+
+// replayCastVotes calls replay with each vote of the vote journal with the
+// provided filename in the order the votes were cast.  A journal that doesn't
+// exist holds no votes.
+func replayCastVotes(filename string, replay func(decredplugin.CastVote)) error {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return journal.Replay(f, castVoteKey, func(e journal.Entry) error {
+		cv, err := decodeCastVoteEntry(e)
+		if err != nil {
+			return err
+		}
+		replay(*cv)
+		return nil
+	})
 }
 
 func (g *gitBackEnd) pluginCastVotes(payload string) (string, error) {
@@ -448,16 +712,17 @@ func (g *gitBackEnd) pluginCastVotes(payload string) (string, error) {
 		return "", fmt.Errorf("DecodeVote %v", err)
 	}
 
-	// XXX this should become part of some sort of context
-	fiJSON, ok := decredPluginSettings[decredPluginIdentity]
-	if !ok {
-		return "", fmt.Errorf("full identity not set")
-	}
-	fi, err := identity.UnmarshalFullIdentity([]byte(fiJSON))
+	fi, err := decredPluginFullIdentity()
 	if err != nil {
 		return "", err
 	}
 
+	// Votes are only accepted up to the end height of their vote
+	bb, err := bestBlock()
+	if err != nil {
+		return "", fmt.Errorf("bestBlock %v", err)
+	}
+
 	// Go over all votes and verify signature
 	type dedupVote struct {
 		vote  *decredplugin.CastVote
@@ -488,6 +753,20 @@ func (g *gitBackEnd) pluginCastVotes(payload string) (string, error) {
 			continue
 		}
 
+		// Ensure that the ticket may vote
+		err = g.validateVoteTicket(v.Token, v.Ticket, uint64(bb.Height))
+		if err != nil {
+			if e, ok := err.(invalidVoteTicketError); ok {
+				cbr[k].Error = e.err.Error()
+				continue
+			}
+			t := time.Now().Unix()
+			log.Errorf("pluginCastVotes: validateVoteTicket %v %v %v",
+				v.Token, t, err)
+			cbr[k].Error = fmt.Sprintf("internal error %v", t)
+			continue
+		}
+
 		cbr[k].ClientSignature = v.Signature
 		// Verify that vote is signed correctly
 		err = g.validateVote(v.Token, v.Ticket, v.VoteBit, v.Signature)
@@ -499,9 +778,6 @@ func (g *gitBackEnd) pluginCastVotes(payload string) (string, error) {
 			continue
 		}
 
-		// Sign ClientSignature
-		signature := fi.SignMessage([]byte(v.Signature))
-		cbr[k].Signature = hex.EncodeToString(signature[:])
 		dedupVotes[key] = dedupVote{
 			vote:  &votes[k],
 			index: k,
@@ -562,122 +838,86 @@ func (g *gitBackEnd) pluginCastVotes(payload string) (string, error) {
 
 	// Check for dups
 	type file struct {
-		fileHandle *os.File
 		token      string
 		mdFilename string
-		index      int
 		content    map[string]struct{} // [token+ticket]
+		entries    []journal.Entry     // Votes to append
+		indexes    []int               // Indexes of the votes to append
 	}
 	files := make(map[string]*file)
 	for _, v := range dedupVotes {
-		// This loop must be exited in order to close all open file
-		// handles.
-		var f *file
-		if f, ok = files[v.vote.Token]; !ok {
-			// Lazily open files and recreate content
-			filename := mdFilename(g.unvetted, v.vote.Token,
-				decredplugin.MDStreamVotes)
-			fh, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE,
-				0666)
-			if err != nil {
-				t := time.Now().Unix()
-				log.Errorf("pluginCastVotes: OpenFile %v %v %v",
-					v.vote.Token, t, err)
-				cbr[v.index].Error = fmt.Sprintf("internal error %v", t)
-				continue
-			}
+		f, ok := files[v.vote.Token]
+		if !ok {
+			// Lazily recreate content
 			f = &file{
-				fileHandle: fh,
-				token:      v.vote.Token,
+				token: v.vote.Token,
 				mdFilename: strconv.FormatUint(uint64(decredplugin.MDStreamVotes),
 					10) + defaultMDFilenameSuffix,
-				index:   v.index,
 				content: make(map[string]struct{}),
 			}
-
-			// Decode file content
-			cvs := make([]decredplugin.CastVote, 0, len(dedupVotes))
-			d := json.NewDecoder(fh)
-			for {
-				var cv decredplugin.CastVote
-				err = d.Decode(&cv)
-				if err != nil {
-					if err == io.EOF {
-						break
-					}
-
-					t := time.Now().Unix()
-					log.Errorf("pluginCastVotes: Decode %v %v %v",
-						v.vote.Token, t, err)
-					cbr[v.index].Error = fmt.Sprintf("internal error %v", t)
-					continue
-				}
-				cvs = append(cvs, cv)
-			}
-
-			// Recreate keys
-			for _, vv := range cvs {
-				key := vv.Token + vv.Ticket
-				// Sanity
-				if _, ok := f.content[key]; ok {
-					t := time.Now().Unix()
-					log.Errorf("pluginCastVotes: not found %v %v %v",
-						key, t, err)
-					cbr[v.index].Error = fmt.Sprintf("internal error %v", t)
-					continue
-				}
-				f.content[key] = struct{}{}
+			err = replayCastVotes(mdFilename(g.unvetted, v.vote.Token,
+				decredplugin.MDStreamVotes),
+				func(cv decredplugin.CastVote) {
+					f.content[cv.Token+cv.Ticket] = struct{}{}
+				})
+			if err != nil {
+				t := time.Now().Unix()
+				log.Errorf("pluginCastVotes: replayCastVotes %v %v %v",
+					v.vote.Token, t, err)
+				cbr[v.index].Error = fmt.Sprintf("internal error %v", t)
+				continue
 			}
-
 			files[v.vote.Token] = f
 		}
 
 		// Check for dups in file content
 		key := v.vote.Token + v.vote.Ticket
 		if _, ok := f.content[key]; ok {
-			index := dedupVotes[key].index
-			cbr[index].Error = "ticket already voted on proposal"
+			cbr[v.index].Error = "ticket already voted on proposal"
 			log.Debugf("duplicate vote token %v ticket %v",
 				v.vote.Token, v.vote.Ticket)
 			continue
 		}
 
-		// Append vote
-		_, err = f.fileHandle.Seek(0, 2)
-		if err != nil {
-			t := time.Now().Unix()
-			log.Errorf("pluginCastVotes: Seek %v %v %v",
-				v.vote.Token, t, err)
-			cbr[v.index].Error = fmt.Sprintf("internal error %v", t)
-			continue
-		}
-		e := json.NewEncoder(f.fileHandle)
-		err = e.Encode(*v.vote)
+		e, err := journal.NewEntry(journal.ActionAdd, *v.vote)
 		if err != nil {
 			t := time.Now().Unix()
-			log.Errorf("pluginCastVotes: Encode %v %v %v",
+			log.Errorf("pluginCastVotes: NewEntry %v %v %v",
 				v.vote.Token, t, err)
 			cbr[v.index].Error = fmt.Sprintf("internal error %v", t)
 			continue
 		}
+		f.entries = append(f.entries, e)
+		f.indexes = append(f.indexes, v.index)
 	}
 
-	// Unwind all opens
+	// Append votes and add the journals to the repo.  Only the votes that
+	// were stored get a signature.
 	for _, v := range files {
-		if v.fileHandle == nil {
+		if len(v.entries) == 0 {
 			continue
 		}
-		v.fileHandle.Close()
-
-		// Add file to repo
-		err = g.gitAdd(g.unvetted, filepath.Join(v.token, v.mdFilename))
+		err = journal.Append(mdFilename(g.unvetted, v.token,
+			decredplugin.MDStreamVotes), v.entries...)
+		if err == nil {
+			err = g.gitAdd(g.unvetted, filepath.Join(v.token,
+				v.mdFilename))
+		}
 		if err != nil {
 			t := time.Now().Unix()
-			log.Errorf("pluginCastVotes: gitAdd %v %v %v",
+			log.Errorf("pluginCastVotes: append %v %v %v",
 				v.token, t, err)
-			cbr[v.index].Error = fmt.Sprintf("internal error %v", t)
+			for _, i := range v.indexes {
+				cbr[i].Error = fmt.Sprintf("internal error %v", t)
+			}
 			continue
 		}
+
+		// Sign ClientSignature
+		for _, i := range v.indexes {
+			signature := fi.SignMessage([]byte(cbr[i].ClientSignature))
+			cbr[i].Signature = hex.EncodeToString(signature[:])
+		}
 	}
 
 	// If there are no changes DO NOT update the record and reply with no
@@ -750,8 +990,8 @@ func (g *gitBackEnd) pluginProposalVotes(payload string) (string, error) {
 	}
 
 	var (
-		d, dd *json.Decoder
-		f, ff *os.File
+		dd *json.Decoder
+		ff *os.File
 	)
 	// Fill out vote
 	filename = mdFilename(g.vetted, vote.Token,
@@ -775,34 +1015,244 @@ func (g *gitBackEnd) pluginProposalVotes(payload string) (string, error) {
 	}
 
 	// Fill out cast votes
-	filename = mdFilename(g.vetted, vote.Token, decredplugin.MDStreamVotes)
-	f, err = os.Open(filename)
+	err = replayCastVotes(mdFilename(g.vetted, vote.Token,
+		decredplugin.MDStreamVotes), func(cv decredplugin.CastVote) {
+		vrr.CastVotes = append(vrr.CastVotes, cv)
+	})
 	if err != nil {
-		if os.IsNotExist(err) {
-			goto nodata
+		return "", err
+	}
+
+nodata:
+	reply, err := decredplugin.EncodeVoteResultsReply(vrr)
+	if err != nil {
+		return "", fmt.Errorf("Could not encode VoteResultsReply %v",
+			err)
+	}
+
+	return string(reply), nil
+}
+
+// pluginVoteReceipt replies with the vote that a ticket cast on a vetted
+// record along with the signature that politeiad returned when it accepted
+// the vote.  The signature is deterministic so it is recreated from the stored
+// vote instead of being stored along with it.
+func (g *gitBackEnd) pluginVoteReceipt(payload string) (string, error) {
+	log.Tracef("pluginVoteReceipt: %v", payload)
+
+	vr, err := decredplugin.DecodeVoteReceipt([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeVoteReceipt %v", err)
+	}
+	token, err := util.ConvertStringToken(vr.Token)
+	if err != nil {
+		return "", fmt.Errorf("ConvertStringToken %v", err)
+	}
+
+	fi, err := decredPluginFullIdentity()
+	if err != nil {
+		return "", err
+	}
+
+	// Lock tree while we look for the vote
+	err = g.lock.Lock(LockDuration)
+	if err != nil {
+		return "", fmt.Errorf("pluginVoteReceipt: lock error "+
+			"try again later: %v", err)
+	}
+	defer func() {
+		err := g.lock.Unlock()
+		if err != nil {
+			log.Errorf("pluginVoteReceipt unlock error: %v", err)
 		}
+	}()
+	if g.shutdown {
+		return "", backend.ErrShutdown
+	}
+
+	// git checkout master
+	err = g.gitCheckout(g.vetted, "master")
+	if err != nil {
 		return "", err
 	}
-	defer f.Close()
-	d = json.NewDecoder(f)
 
-	for {
-		var cv decredplugin.CastVote
-		err = d.Decode(&cv)
+	// Make sure proposal exists
+	_, err = os.Stat(filepath.Join(g.vetted, hex.EncodeToString(token)))
+	if os.IsNotExist(err) {
+		return "", backend.PluginUserError{
+			ErrorCode:    pd.ErrorStatusRecordNotFound,
+			ErrorContext: []string{vr.Token},
+		}
+	} else if err != nil {
+		return "", err
+	}
+
+	// Look for the vote of the ticket
+	var (
+		cv    decredplugin.CastVote
+		found bool
+	)
+	err = replayCastVotes(mdFilename(g.vetted, hex.EncodeToString(token),
+		decredplugin.MDStreamVotes), func(v decredplugin.CastVote) {
+		if v.Ticket == vr.Ticket {
+			cv = v
+			found = true
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", backend.PluginUserError{
+			ErrorCode:    pd.ErrorStatusVoteNotFound,
+			ErrorContext: []string{vr.Ticket},
+		}
+	}
+
+	signature := fi.SignMessage([]byte(cv.Signature))
+	reply, err := decredplugin.EncodeVoteReceiptReply(
+		decredplugin.VoteReceiptReply{
+			CastVote:  cv,
+			Signature: hex.EncodeToString(signature[:]),
+		})
+	if err != nil {
+		return "", fmt.Errorf("Could not encode VoteReceiptReply %v",
+			err)
+	}
+
+	return string(reply), nil
+}
+
+// auditSnapshot compares the provided stored vote snapshot with the provided
+// hash of the main chain block at the snapshot height and the ticket pool at
+// the snapshot block.  The discrepancies are sorted.
+func auditSnapshot(token string, svr decredplugin.StartVoteReply, blockHash string, pool []string) decredplugin.AuditSnapshotReply {
+	asr := decredplugin.AuditSnapshotReply{
+		Token:            token,
+		StartBlockHeight: svr.StartBlockHeight,
+		StartBlockHash:   svr.StartBlockHash,
+		BlockHash:        blockHash,
+		StoredTickets:    len(svr.EligibleTickets),
+		PoolTickets:      len(pool),
+		Missing:          []string{},
+		Unexpected:       []string{},
+		Duplicates:       []string{},
+	}
+
+	inPool := make(map[string]struct{}, len(pool))
+	for _, v := range pool {
+		inPool[v] = struct{}{}
+	}
+	stored := make(map[string]struct{}, len(svr.EligibleTickets))
+	for _, v := range svr.EligibleTickets {
+		if _, ok := stored[v]; ok {
+			asr.Duplicates = append(asr.Duplicates, v)
+			continue
+		}
+		stored[v] = struct{}{}
+		if _, ok := inPool[v]; !ok {
+			asr.Unexpected = append(asr.Unexpected, v)
+		}
+	}
+	for v := range inPool {
+		if _, ok := stored[v]; !ok {
+			asr.Missing = append(asr.Missing, v)
+		}
+	}
+	sort.Strings(asr.Missing)
+	sort.Strings(asr.Unexpected)
+	sort.Strings(asr.Duplicates)
+
+	asr.Match = blockHash == svr.StartBlockHash &&
+		len(asr.Missing) == 0 && len(asr.Unexpected) == 0 &&
+		len(asr.Duplicates) == 0
+
+	return asr
+}
+
+// pluginAuditSnapshot re-queries the ticket pool at the snapshot block of the
+// vote on a vetted record and replies with the discrepancies between the pool
+// and the stored eligible tickets.
+func (g *gitBackEnd) pluginAuditSnapshot(payload string) (string, error) {
+	log.Tracef("pluginAuditSnapshot: %v", payload)
+
+	as, err := decredplugin.DecodeAuditSnapshot([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeAuditSnapshot %v", err)
+	}
+	token, err := util.ConvertStringToken(as.Token)
+	if err != nil {
+		return "", fmt.Errorf("ConvertStringToken %v", err)
+	}
+
+	// Load the snapshot with the tree locked but query dcrdata without
+	// holding the lock.
+	svr, err := func() (*decredplugin.StartVoteReply, error) {
+		err := g.lock.Lock(LockDuration)
 		if err != nil {
-			if err == io.EOF {
-				break
+			return nil, fmt.Errorf("pluginAuditSnapshot: lock "+
+				"error try again later: %v", err)
+		}
+		defer func() {
+			err := g.lock.Unlock()
+			if err != nil {
+				log.Errorf("pluginAuditSnapshot unlock error: %v",
+					err)
 			}
-			return "", err
+		}()
+		if g.shutdown {
+			return nil, backend.ErrShutdown
 		}
 
-		vrr.CastVotes = append(vrr.CastVotes, cv)
+		svr, err := g.loadStartVoteReply(hex.EncodeToString(token))
+		if os.IsNotExist(err) {
+			// Tell apart missing records from votes that did not
+			// start.
+			_, err = os.Stat(filepath.Join(g.vetted,
+				hex.EncodeToString(token)))
+			if os.IsNotExist(err) {
+				return nil, backend.PluginUserError{
+					ErrorCode:    pd.ErrorStatusRecordNotFound,
+					ErrorContext: []string{as.Token},
+				}
+			} else if err != nil {
+				return nil, err
+			}
+			return nil, backend.PluginUserError{
+				ErrorCode:    pd.ErrorStatusVoteNotFound,
+				ErrorContext: []string{as.Token},
+			}
+		}
+		return svr, err
+	}()
+	if err != nil {
+		return "", err
 	}
 
-nodata:
-	reply, err := decredplugin.EncodeVoteResultsReply(vrr)
+	height, err := strconv.ParseUint(svr.StartBlockHeight, 10, 32)
 	if err != nil {
-		return "", fmt.Errorf("Could not encode VoteResultsReply %v",
+		return "", fmt.Errorf("invalid start block height %v: %v",
+			svr.StartBlockHeight, err)
+	}
+	b, err := block(uint32(height))
+	if err != nil {
+		return "", fmt.Errorf("block %v", err)
+	}
+	pool, err := snapshot(svr.StartBlockHash)
+	if err != nil {
+		return "", fmt.Errorf("snapshot %v", err)
+	}
+
+	asr := auditSnapshot(as.Token, *svr, b.Hash, pool)
+	if !asr.Match {
+		log.Infof("Snapshot audit failed for: %v block %v missing %v "+
+			"unexpected %v duplicates %v", as.Token, asr.BlockHash,
+			len(asr.Missing), len(asr.Unexpected),
+			len(asr.Duplicates))
+	}
+	reply, err := decredplugin.EncodeAuditSnapshotReply(asr)
+	if err != nil {
+		return "", fmt.Errorf("Could not encode AuditSnapshotReply %v",
 			err)
 	}
 
@@ -0,0 +1,437 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gitbe
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/btcsuite/btclog"
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/util"
+)
+
+func TestVerifyVoteRecord(t *testing.T) {
+	log := btclog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g, err := New(&chaincfg.TestNet2Params, dir, "", "", nil,
+		testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.test = true
+
+	payload := "this is a record"
+	rm, err := g.New([]backend.MetadataStream{{
+		ID:      0,
+		Payload: "this is metadata",
+	}}, []backend.File{{
+		Name:    "index.md",
+		MIME:    http.DetectContentType([]byte(payload)),
+		Digest:  hex.EncodeToString(util.Digest([]byte(payload))),
+		Payload: base64.StdEncoding.EncodeToString([]byte(payload)),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectUserError := func(code pd.ErrorStatusT) {
+		t.Helper()
+		err := g.verifyVoteRecord(rm.Token)
+		userErr, ok := err.(backend.PluginUserError)
+		if !ok || userErr.ErrorCode != code {
+			t.Fatalf("unexpected error: got %v want %v", err,
+				pd.ErrorStatus[code])
+		}
+	}
+
+	// Unvetted records can't be voted on.
+	expectUserError(pd.ErrorStatusRecordNotFound)
+
+	emptyMD := []backend.MetadataStream{}
+	_, err = g.SetUnvettedStatus(rm.Token, backend.MDStatusVetted,
+		emptyMD, emptyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.verifyVoteRecord(rm.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A vote can't be started while another start is in progress.
+	err = g.claimVoteRecord(rm.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.claimVoteRecord(rm.Token)
+	userErr, ok := err.(backend.PluginUserError)
+	if !ok || userErr.ErrorCode != pd.ErrorStatusVoteStarted {
+		t.Fatalf("unexpected error: got %v want %v", err,
+			pd.ErrorStatus[pd.ErrorStatusVoteStarted])
+	}
+	g.releaseVoteRecord(rm.Token)
+	err = g.claimVoteRecord(rm.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.releaseVoteRecord(rm.Token)
+
+	// A record can only be voted on once.
+	vote, err := decredplugin.EncodeVote(decredplugin.Vote{
+		Token: hex.EncodeToString(rm.Token),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.UpdateVettedMetadata(rm.Token, nil, []backend.MetadataStream{{
+		ID:      decredplugin.MDStreamVoteBits,
+		Payload: string(vote),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectUserError(pd.ErrorStatusVoteStarted)
+}
+
+func TestValidateVoteBit(t *testing.T) {
+	options := []decredplugin.VoteOption{
+		{Id: "a", Description: "Option A", Bits: 0x1},
+		{Id: "b", Description: "Option B", Bits: 0x2},
+	}
+	tests := []struct {
+		voteType decredplugin.VoteT
+		voteBit  string
+		valid    bool
+	}{
+		{decredplugin.VoteTypeMultiOption, "2", true},
+		{decredplugin.VoteTypeMultiOption, "4", false},
+		{decredplugin.VoteTypeMultiOption, "2,1", false},
+		{decredplugin.VoteTypeRankedChoice, "2,1", true},
+		{decredplugin.VoteTypeRankedChoice, "1", true},
+		{decredplugin.VoteTypeRankedChoice, "1,1", false},
+		{decredplugin.VoteTypeRankedChoice, "1,4", false},
+	}
+	for _, test := range tests {
+		vote := decredplugin.Vote{
+			Type:    test.voteType,
+			Mask:    0x3,
+			Options: options,
+		}
+		err := _validateVoteBit(vote, test.voteBit)
+		if test.valid && err != nil {
+			t.Fatalf("%v %q: %v", test.voteType, test.voteBit, err)
+		}
+		if _, ok := err.(invalidVoteBitError); !test.valid && !ok {
+			t.Fatalf("%v %q: unexpected error %v", test.voteType,
+				test.voteBit, err)
+		}
+	}
+}
+
+func TestValidateVoteTicket(t *testing.T) {
+	snapshot := voteSnapshot{
+		endHeight: 200,
+		eligible: map[string]struct{}{
+			"a": {},
+			"b": {},
+		},
+	}
+	tests := []struct {
+		ticket    string
+		bestBlock uint64
+		valid     bool
+	}{
+		{"a", 150, true},
+		{"b", 200, true},
+		{"c", 150, false},
+		{"a", 201, false},
+	}
+	for _, test := range tests {
+		err := _validateVoteTicket(snapshot, test.ticket, test.bestBlock)
+		if test.valid && err != nil {
+			t.Fatalf("%v %v: %v", test.ticket, test.bestBlock, err)
+		}
+		if _, ok := err.(invalidVoteTicketError); !test.valid && !ok {
+			t.Fatalf("%v %v: unexpected error %v", test.ticket,
+				test.bestBlock, err)
+		}
+	}
+}
+
+func TestAuditSnapshot(t *testing.T) {
+	svr := decredplugin.StartVoteReply{
+		StartBlockHeight: "282893",
+		StartBlockHash:   "000000000227ff9b6bf3af53accb81e4fd1690ae44d521a665cb988bcd02ad94",
+		EndHeight:        "284909",
+		EligibleTickets:  []string{"a", "b", "c"},
+	}
+
+	// A reproduced snapshot matches regardless of the ticket order.
+	asr := auditSnapshot("token", svr, svr.StartBlockHash,
+		[]string{"c", "b", "a"})
+	if !asr.Match || asr.StoredTickets != 3 || asr.PoolTickets != 3 {
+		t.Fatalf("unexpected report %v", asr)
+	}
+
+	// Every discrepancy is reported.
+	svr.EligibleTickets = []string{"a", "d", "b", "a"}
+	asr = auditSnapshot("token", svr, svr.StartBlockHash,
+		[]string{"c", "b", "a", "e"})
+	if asr.Match {
+		t.Fatalf("expected the snapshot not to match")
+	}
+	if len(asr.Missing) != 2 || asr.Missing[0] != "c" ||
+		asr.Missing[1] != "e" {
+		t.Fatalf("unexpected missing tickets %v", asr.Missing)
+	}
+	if len(asr.Unexpected) != 1 || asr.Unexpected[0] != "d" {
+		t.Fatalf("unexpected unexpected tickets %v", asr.Unexpected)
+	}
+	if len(asr.Duplicates) != 1 || asr.Duplicates[0] != "a" {
+		t.Fatalf("unexpected duplicate tickets %v", asr.Duplicates)
+	}
+
+	// A snapshot block that is no longer in the main chain doesn't match.
+	svr.EligibleTickets = []string{"a", "b"}
+	asr = auditSnapshot("token", svr, "00000000", []string{"a", "b"})
+	if asr.Match {
+		t.Fatalf("expected the snapshot not to match")
+	}
+}
+
+func TestStartVoteReplySignature(t *testing.T) {
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idJSON, err := id.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	setDecredPluginSetting(decredPluginIdentity, string(idJSON))
+	defer setDecredPluginSetting(decredPluginIdentity, "")
+
+	fi, err := decredPluginFullIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vote := decredplugin.Vote{
+		Token:            "642eb2f3798090b3234d8787aaba046f1f4409436d40994643213b63cb3f41da",
+		Type:             decredplugin.VoteTypeApproval,
+		Mask:             0x3,
+		Duration:         2016,
+		QuorumPercentage: 20,
+		PassPercentage:   60,
+		Options: []decredplugin.VoteOption{
+			{Id: "no", Description: "Don't approve proposal", Bits: 0x1},
+			{Id: "yes", Description: "Approve proposal", Bits: 0x2},
+		},
+	}
+	svr := decredplugin.StartVoteReply{
+		StartBlockHeight: "282893",
+		StartBlockHash:   "000000000227ff9b6bf3af53accb81e4fd1690ae44d521a665cb988bcd02ad94",
+		EndHeight:        "284909",
+		EligibleTickets:  []string{"a", "b"},
+	}
+
+	// Known message.
+	want := vote.Token + "\n" +
+		svr.StartBlockHash + "\n" +
+		"282893\n" +
+		"284909\n" +
+		"1eb7c54d52831bbfe8942af0b1c56b7409523a59ed6ca99c1174fef7eb32c1b5\n" +
+		"1\n" +
+		"3\n" +
+		"2016\n" +
+		"20\n" +
+		"60\n" +
+		"a6a3c0deb71f64432366b72eb489fa27ffa66af073d413fd03ab30419e893ca1"
+	if got := string(svr.SignatureMessage(vote)); got != want {
+		t.Fatalf("got message %q, want %q", got, want)
+	}
+
+	signature := fi.SignMessage(svr.SignatureMessage(vote))
+	if !id.Public.VerifyMessage(svr.SignatureMessage(vote), signature) {
+		t.Fatalf("expected the signature to verify")
+	}
+
+	// The signature commits to the window, the snapshot and the vote
+	// parameters, and fields can't be shifted into one another.
+	tests := []struct {
+		name   string
+		modify func(*decredplugin.StartVoteReply, *decredplugin.Vote)
+	}{
+		{"end height", func(s *decredplugin.StartVoteReply, v *decredplugin.Vote) {
+			s.EndHeight = "284910"
+		}},
+		{"ticket order", func(s *decredplugin.StartVoteReply, v *decredplugin.Vote) {
+			s.EligibleTickets = []string{"b", "a"}
+		}},
+		{"ticket boundary", func(s *decredplugin.StartVoteReply, v *decredplugin.Vote) {
+			s.EligibleTickets = []string{"ab"}
+		}},
+		{"height boundary", func(s *decredplugin.StartVoteReply, v *decredplugin.Vote) {
+			s.StartBlockHeight = "28289"
+			s.EndHeight = "3284909"
+		}},
+		{"duration", func(s *decredplugin.StartVoteReply, v *decredplugin.Vote) {
+			v.Duration = 2017
+		}},
+		{"quorum", func(s *decredplugin.StartVoteReply, v *decredplugin.Vote) {
+			v.QuorumPercentage = 10
+		}},
+		{"pass", func(s *decredplugin.StartVoteReply, v *decredplugin.Vote) {
+			v.PassPercentage = 50
+		}},
+		{"options", func(s *decredplugin.StartVoteReply, v *decredplugin.Vote) {
+			v.Options = []decredplugin.VoteOption{v.Options[1],
+				v.Options[0]}
+		}},
+	}
+	for _, test := range tests {
+		s, v := svr, vote
+		test.modify(&s, &v)
+		if id.Public.VerifyMessage(s.SignatureMessage(v), signature) {
+			t.Fatalf("%v: expected the signature not to verify",
+				test.name)
+		}
+	}
+}
+
+func TestVoteReceipt(t *testing.T) {
+	log := btclog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idJSON, err := id.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	setDecredPluginSetting(decredPluginIdentity, string(idJSON))
+	defer setDecredPluginSetting(decredPluginIdentity, "")
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g, err := New(&chaincfg.TestNet2Params, dir, "", "", nil,
+		testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.test = true
+
+	payload := "this is a record"
+	rm, err := g.New([]backend.MetadataStream{{
+		ID:      0,
+		Payload: "this is metadata",
+	}}, []backend.File{{
+		Name:    "index.md",
+		MIME:    http.DetectContentType([]byte(payload)),
+		Digest:  hex.EncodeToString(util.Digest([]byte(payload))),
+		Payload: base64.StdEncoding.EncodeToString([]byte(payload)),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	emptyMD := []backend.MetadataStream{}
+	_, err = g.SetUnvettedStatus(rm.Token, backend.MDStatusVetted,
+		emptyMD, emptyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := hex.EncodeToString(rm.Token)
+
+	expectUserError := func(ticket string, code pd.ErrorStatusT) {
+		t.Helper()
+		vr, err := decredplugin.EncodeVoteReceipt(decredplugin.VoteReceipt{
+			Token:  token,
+			Ticket: ticket,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = g.pluginVoteReceipt(string(vr))
+		userErr, ok := err.(backend.PluginUserError)
+		if !ok || userErr.ErrorCode != code {
+			t.Fatalf("unexpected error: got %v want %v", err,
+				pd.ErrorStatus[code])
+		}
+	}
+
+	// No vote was cast yet.
+	expectUserError("a", pd.ErrorStatusVoteNotFound)
+
+	var votes []byte
+	for _, ticket := range []string{"a", "b"} {
+		cv, err := json.Marshal(decredplugin.CastVote{
+			Token:     token,
+			Ticket:    ticket,
+			VoteBit:   "1",
+			Signature: "signature " + ticket,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		votes = append(votes, append(cv, '\n')...)
+	}
+	err = g.UpdateVettedMetadata(rm.Token, nil, []backend.MetadataStream{{
+		ID:      decredplugin.MDStreamVotes,
+		Payload: string(votes),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vr, err := decredplugin.EncodeVoteReceipt(decredplugin.VoteReceipt{
+		Token:  token,
+		Ticket: "b",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := g.pluginVoteReceipt(string(vr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vrr, err := decredplugin.DecodeVoteReceiptReply([]byte(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vrr.CastVote.Ticket != "b" || vrr.CastVote.Signature != "signature b" {
+		t.Fatalf("unexpected cast vote %v", vrr.CastVote)
+	}
+	signature, err := util.ConvertSignature(vrr.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !id.Public.VerifyMessage([]byte(vrr.CastVote.Signature), signature) {
+		t.Fatalf("expected the receipt signature to verify")
+	}
+
+	expectUserError("c", pd.ErrorStatusVoteNotFound)
+}
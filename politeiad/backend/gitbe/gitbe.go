@@ -16,6 +16,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
@@ -122,6 +123,11 @@ type gitBackEnd struct {
 	checkAnchor     chan struct{}      // Work notification
 	plugins         []backend.Plugin   // Plugins
 
+	// votesStarting contains the records whose vote is being started.  It
+	// is protected by votesMtx.
+	votesMtx      sync.Mutex
+	votesStarting map[string]struct{} // [token]struct{}
+
 	// The following items are used for testing only
 	testAnchors map[string]bool // [digest]anchored
 }
@@ -2018,6 +2024,12 @@ func (g *gitBackEnd) Plugin(command, payload string) (string, string, error) {
 	case decredplugin.CmdProposalVotes:
 		payload, err := g.pluginProposalVotes(payload)
 		return decredplugin.CmdProposalVotes, payload, err
+	case decredplugin.CmdVoteReceipt:
+		payload, err := g.pluginVoteReceipt(payload)
+		return decredplugin.CmdVoteReceipt, payload, err
+	case decredplugin.CmdAuditSnapshot:
+		payload, err := g.pluginAuditSnapshot(payload)
+		return decredplugin.CmdAuditSnapshot, payload, err
 	case decredplugin.CmdBestBlock:
 		payload, err := g.pluginBestBlock()
 		return decredplugin.CmdBestBlock, payload, err
@@ -2205,6 +2217,7 @@ func New(anp *chaincfg.Params, root string, dcrtimeHost string, gitPath string,
 		checkAnchor:     make(chan struct{}),
 		testAnchors:     make(map[string]bool),
 		plugins:         []backend.Plugin{getDecredPlugin(anp.Name != "mainnet")},
+		votesStarting:   make(map[string]struct{}),
 	}
 	idJSON, err := id.Marshal()
 	if err != nil {
@@ -23,6 +23,7 @@ import (
 
 	"github.com/decred/dcrd/dcrutil"
 	"github.com/decred/dcrtime/merkle"
+	"github.com/decred/politeia/decredplugin"
 	"github.com/decred/politeia/politeiad/api/v1"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/util"
@@ -69,6 +70,8 @@ func usage() {
 		"inventory\n")
 	fmt.Fprintf(os.Stderr, "  inventory         - Inventory records "+
 		"<vetted count> <branches count>\n")
+	fmt.Fprintf(os.Stderr, "  auditsnapshot     - Verify the vote "+
+		"snapshot of a record against the ticket pool <token>\n")
 	fmt.Fprintf(os.Stderr, "  new               - Create new record "+
 		"[metadata<id>]... <filename>...\n")
 	fmt.Fprintf(os.Stderr, "  getunvetted       - Retrieve record "+
@@ -260,26 +263,22 @@ func pluginInventory() (*v1.PluginInventoryReply, error) {
 	return &ir, nil
 }
 
-func plugin() error {
-	flags := flag.Args()[1:] // Chop off action.
-
-	if len(flags) != 4 {
-		return fmt.Errorf("not enough parameters")
-	}
-
+// pluginCommand sends the provided plugin command to politeiad and returns
+// its verified reply.
+func pluginCommand(id, command, commandID, payload string) (*v1.PluginCommandReply, error) {
 	challenge, err := util.Random(v1.ChallengeSize)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	b, err := json.Marshal(v1.PluginCommand{
 		Challenge: hex.EncodeToString(challenge),
-		ID:        flags[0],
-		Command:   flags[1],
-		CommandID: flags[2],
-		Payload:   flags[3],
+		ID:        id,
+		Command:   command,
+		CommandID: commandID,
+		Payload:   payload,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if *printJson {
@@ -288,25 +287,25 @@ func plugin() error {
 
 	c, err := util.NewClient(verify, *rpccert)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req, err := http.NewRequest("POST", *rpchost+v1.PluginCommandRoute,
 		bytes.NewReader(b))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req.SetBasicAuth(*rpcuser, *rpcpass)
 	r, err := c.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer r.Body.Close()
 	if r.StatusCode != http.StatusOK {
 		e, err := getErrorFromResponse(r)
 		if err != nil {
-			return fmt.Errorf("%v", r.Status)
+			return nil, fmt.Errorf("%v", r.Status)
 		}
-		return fmt.Errorf("%v: %v", r.Status, e)
+		return nil, fmt.Errorf("%v: %v", r.Status, e)
 	}
 
 	bodyBytes := util.ConvertBodyToByteArray(r.Body, *printJson)
@@ -314,17 +313,86 @@ func plugin() error {
 	var pcr v1.PluginCommandReply
 	err = json.Unmarshal(bodyBytes, &pcr)
 	if err != nil {
-		return fmt.Errorf("Could node unmarshal "+
+		return nil, fmt.Errorf("Could node unmarshal "+
 			"PluginCommandReply: %v", err)
 	}
 
 	// Fetch remote identity
-	id, err := identity.LoadPublicIdentity(*identityFilename)
+	pid, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	err = util.VerifyChallenge(pid, challenge, pcr.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pcr, nil
+}
+
+func plugin() error {
+	flags := flag.Args()[1:] // Chop off action.
+
+	if len(flags) != 4 {
+		return fmt.Errorf("not enough parameters")
+	}
+
+	_, err := pluginCommand(flags[0], flags[1], flags[2], flags[3])
+	return err
+}
+
+// auditSnapshot verifies that the stored vote snapshot of a proposal is the
+// ticket pool at the recorded snapshot block and prints the discrepancies.
+func auditSnapshot() error {
+	flags := flag.Args()[1:] // Chop off action.
+
+	if len(flags) != 1 {
+		return fmt.Errorf("must provide token")
+	}
+
+	payload, err := decredplugin.EncodeAuditSnapshot(
+		decredplugin.AuditSnapshot{
+			Token: flags[0],
+		})
+	if err != nil {
+		return err
+	}
+	pcr, err := pluginCommand(decredplugin.ID,
+		decredplugin.CmdAuditSnapshot, decredplugin.CmdAuditSnapshot,
+		string(payload))
 	if err != nil {
 		return err
 	}
+	asr, err := decredplugin.DecodeAuditSnapshotReply([]byte(pcr.Payload))
+	if err != nil {
+		return fmt.Errorf("Could not decode AuditSnapshotReply: %v",
+			err)
+	}
 
-	return util.VerifyChallenge(id, challenge, pcr.Response)
+	if !*printJson {
+		fmt.Printf("Snapshot audit: %v\n", asr.Token)
+		fmt.Printf("  Start block height: %v\n", asr.StartBlockHeight)
+		fmt.Printf("  Start block hash  : %v\n", asr.StartBlockHash)
+		fmt.Printf("  Main chain hash   : %v\n", asr.BlockHash)
+		fmt.Printf("  Stored tickets    : %v\n", asr.StoredTickets)
+		fmt.Printf("  Pool tickets      : %v\n", asr.PoolTickets)
+		for _, v := range asr.Missing {
+			fmt.Printf("  Missing           : %v\n", v)
+		}
+		for _, v := range asr.Unexpected {
+			fmt.Printf("  Unexpected        : %v\n", v)
+		}
+		for _, v := range asr.Duplicates {
+			fmt.Printf("  Duplicate         : %v\n", v)
+		}
+		fmt.Printf("  Match             : %v\n", asr.Match)
+	}
+
+	if !asr.Match {
+		return fmt.Errorf("snapshot does not match the ticket pool")
+	}
+	return nil
 }
 
 func getPluginInventory() error {
@@ -1180,6 +1248,8 @@ func _main() error {
 				return plugin()
 			case "plugininventory":
 				return getPluginInventory()
+			case "auditsnapshot":
+				return auditSnapshot()
 			case "inventory":
 				return inventory()
 			case "getunvetted":
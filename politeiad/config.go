@@ -54,6 +54,7 @@ var runServiceCommand func(string) error
 type config struct {
 	HomeDir     string   `short:"A" long:"appdata" description:"Path to application home directory"`
 	ShowVersion bool     `short:"V" long:"version" description:"Display version information and exit"`
+	Validate    bool     `long:"validate" description:"Validate the configuration and the connectivity to dcrtime and exit"`
 	ConfigFile  string   `short:"C" long:"configfile" description:"Path to configuration file"`
 	DataDir     string   `short:"b" long:"datadir" description:"Directory to store data"`
 	LogDir      string   `long:"logdir" description:"Directory to log output."`
@@ -73,6 +74,12 @@ type config struct {
 	DcrtimeCert string `long:"dcrtimecert" description:"File containing the https certificate file for dcrtimehost"`
 	Identity    string `long:"identity" description:"File containing the politeiad identity file"`
 	GitTrace    bool   `long:"gittrace" description:"Enable git tracing in logs"`
+
+	VaultAddress string `long:"vaultaddress" description:"Address of the Vault server that vault: secrets are read from"`
+	VaultToken   string `long:"vaulttoken" description:"Token used to authenticate with the Vault server"`
+	KMSRegion    string `long:"kmsregion" description:"AWS region of the KMS key that kms: secrets are encrypted with"`
+	KMSAccessKey string `long:"kmsaccesskey" description:"AWS access key ID used to decrypt kms: secrets"`
+	KMSSecretKey string `long:"kmssecretkey" description:"AWS secret access key used to decrypt kms: secrets"`
 }
 
 // serviceOptions defines the configuration options for the daemon as a service
@@ -488,6 +495,21 @@ func loadConfig() (*config, []string, error) {
 	}
 	cfg.Identity = cleanAndExpandPath(cfg.Identity)
 
+	// Fetch the secrets that are stored in an external secret store.
+	secrets, err := util.NewConfiguredSecrets(cfg.VaultAddress,
+		cfg.VaultToken, util.AWSCredentials{
+			Region:    cfg.KMSRegion,
+			AccessKey: cfg.KMSAccessKey,
+			SecretKey: cfg.KMSSecretKey,
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+	err = secrets.ResolveAll(&cfg.RPCUser, &cfg.RPCPass)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Set random username and password when not specified
 	if cfg.RPCUser == "" {
 		name, err := util.Random(32)
@@ -732,6 +732,15 @@ func (p *politeia) pluginCommand(w http.ResponseWriter, r *http.Request) {
 
 	cid, payload, err := p.backend.Plugin(pc.Command, pc.Payload)
 	if err != nil {
+		// Check for user error.
+		if userErr, ok := err.(backend.PluginUserError); ok {
+			log.Errorf("%v plugin command %v user error: %v",
+				remoteAddr(r), pc.Command, userErr)
+			p.respondWithUserError(w, userErr.ErrorCode,
+				userErr.ErrorContext)
+			return
+		}
+
 		// Generic internal error.
 		errorCode := time.Now().Unix()
 		log.Errorf("%v New record error code %v: %v", remoteAddr(r),
@@ -782,8 +791,12 @@ func logging(f http.HandlerFunc) http.HandlerFunc {
 			return string(trace)
 		}))
 
-		// Log incoming connection
-		log.Infof("%v %v %v %v", remoteAddr(r), r.Method, r.URL, r.Proto)
+		// Log incoming connection along with the request ID that was
+		// forwarded by politeiawww.
+		id := util.RequestIDFromHTTP(r)
+		w.Header().Set(util.RequestIDHeader, id)
+		log.Infof("reqid=%v remote=%v method=%v url=%v proto=%v", id,
+			remoteAddr(r), r.Method, r.URL, r.Proto)
 		f(w, r)
 	}
 }
@@ -813,6 +826,10 @@ func _main() error {
 		}
 	}()
 
+	if loadedCfg.Validate {
+		return validate(loadedCfg)
+	}
+
 	log.Infof("Version : %v", version())
 	log.Infof("Network : %v", activeNetParams.Params.Name)
 	log.Infof("Home dir: %v", loadedCfg.HomeDir)
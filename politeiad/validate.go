@@ -0,0 +1,107 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/util"
+)
+
+// validateTimeout is the timeout of the connectivity checks.
+const validateTimeout = 30 * time.Second
+
+// validate checks the configuration files, the data directory and the
+// connectivity to dcrtime without modifying any state.  It prints a report
+// and returns an error if any of the checks failed.
+func validate(cfg *config) error {
+	checks := []util.Check{
+		{
+			Name: "https certificate and key",
+			Run: func() error {
+				_, err := tls.LoadX509KeyPair(cfg.HTTPSCert,
+					cfg.HTTPSKey)
+				return err
+			},
+		},
+		{
+			Name: "identity",
+			Run: func() error {
+				_, err := identity.LoadFullIdentity(cfg.Identity)
+				return err
+			},
+		},
+		{
+			Name: "data directory",
+			Run: func() error {
+				// Only inspect the directory; validating
+				// must not modify the data of the daemon.
+				fi, err := os.Stat(cfg.DataDir)
+				if os.IsNotExist(err) {
+					return util.SkipCheck("does not exist " +
+						"yet, it is created on startup")
+				} else if err != nil {
+					return err
+				}
+				if !fi.IsDir() {
+					return fmt.Errorf("%v is not a directory",
+						cfg.DataDir)
+				}
+				if fi.Mode().Perm()&0200 == 0 {
+					return fmt.Errorf("%v is not writable",
+						cfg.DataDir)
+				}
+				return nil
+			},
+		},
+		{
+			Name: "git",
+			Run: func() error {
+				_, err := exec.LookPath("git")
+				return err
+			},
+		},
+		{
+			Name: "dcrtime",
+			Run: func() error {
+				tlsConfig := &tls.Config{}
+				if len(cfg.DcrtimeCert) != 0 {
+					cert, err := ioutil.ReadFile(cfg.DcrtimeCert)
+					if err != nil {
+						return err
+					}
+					tlsConfig.RootCAs = x509.NewCertPool()
+					if !tlsConfig.RootCAs.AppendCertsFromPEM(cert) {
+						return fmt.Errorf("no certificates "+
+							"found in %v", cfg.DcrtimeCert)
+					}
+				}
+				client := &http.Client{
+					Timeout: validateTimeout,
+					Transport: &http.Transport{
+						TLSClientConfig: tlsConfig,
+					},
+				}
+				r, err := client.Get(cfg.DcrtimeHost)
+				if err != nil {
+					return err
+				}
+				r.Body.Close()
+				return nil
+			},
+		},
+	}
+
+	fmt.Printf("Validating politeiad configuration\n")
+	return util.RunChecks(os.Stdout, checks)
+}
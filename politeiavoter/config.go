@@ -26,7 +26,7 @@ const (
 	defaultLogDirname       = "logs"
 	defaultLogFilename      = "politeiavoter.log"
 	defaultIdentityFilename = "identity.json"
-	defaultWalletHost       = "https://127.0.0.1" // Only allow localhost for now
+	defaultWalletHost       = "127.0.0.1" // Only allow localhost for now
 	defaultWalletCert       = "~/.dcrwallet/rpc.cert"
 
 	defaultMainnetPort = "49374"
@@ -65,6 +65,7 @@ type config struct {
 	Listeners        []string `long:"listen" description:"Add an interface/port to listen for connections (default all interfaces port: 49152, testnet: 59152)"`
 	Version          string
 	Identity         string `long:"identity" description:"File containing the politeiad identity file"`
+	WalletHost       string `long:"wallethost" description:"Wallet GRPC host (default: localhost on the wallet GRPC port of the network)"`
 	WalletCert       string `long:"walletgrpccert" description:"Wallet GRPC certificate"`
 	WalletPassphrase string `long:"walletpassphrase" description:"Wallet passphrase"`
 }
@@ -430,6 +431,12 @@ func loadConfig() (*config, []string, error) {
 	}
 	cfg.Identity = cleanAndExpandPath(cfg.Identity)
 
+	// Wallet host
+	if cfg.WalletHost == "" {
+		cfg.WalletHost = net.JoinHostPort(defaultWalletHost,
+			activeNetParams.WalletRPCServerPort)
+	}
+
 	// Wallet cert
 	if cfg.WalletCert == "" {
 		cfg.WalletCert = defaultWalletCert
@@ -15,17 +15,14 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/decred/dcrd/chaincfg/chainhash"
-	pb "github.com/decred/dcrwallet/rpc/walletrpc"
 	"github.com/decred/politeia/decredplugin"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/politeiawww/api/v1"
 	"github.com/decred/politeia/util"
+	"github.com/decred/politeia/util/wallet"
 	"github.com/gorilla/schema"
 	"golang.org/x/crypto/ssh/terminal"
 	"golang.org/x/net/publicsuffix"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 )
 
 var (
@@ -74,9 +71,7 @@ type ctx struct {
 
 	// wallet grpc
 	ctx    context.Context
-	creds  credentials.TransportCredentials
-	conn   *grpc.ClientConn
-	wallet pb.WalletServiceClient
+	wallet *wallet.Client
 }
 
 func newClient(skipVerify bool, cfg *config) (*ctx, error) {
@@ -94,22 +89,15 @@ func newClient(skipVerify bool, cfg *config) (*ctx, error) {
 	}
 
 	// Wallet GRPC
-	creds, err := credentials.NewClientTLSFromFile(cfg.WalletCert, "")
+	w, err := wallet.New(cfg.WalletHost, cfg.WalletCert)
 	if err != nil {
 		return nil, err
 	}
-	conn, err := grpc.Dial("127.0.0.1:19111", grpc.WithTransportCredentials(creds))
-	if err != nil {
-		return nil, err
-	}
-	wallet := pb.NewWalletServiceClient(conn)
 
 	// return context
 	return &ctx{
 		ctx:    context.Background(),
-		creds:  creds,
-		conn:   conn,
-		wallet: wallet,
+		wallet: w,
 		cfg:    cfg,
 		client: &http.Client{
 			Transport: tr,
@@ -182,18 +170,6 @@ func firstContact(cfg *config) (*ctx, error) {
 	return c, nil
 }
 
-func convertTicketHashes(h []string) ([][]byte, error) {
-	hashes := make([][]byte, 0, len(h))
-	for _, v := range h {
-		hh, err := chainhash.NewHashFromStr(v)
-		if err != nil {
-			return nil, err
-		}
-		hashes = append(hashes, hh[:])
-	}
-	return hashes, nil
-}
-
 func (c *ctx) makeRequest(method, route string, b interface{}) ([]byte, error) {
 	var requestBody []byte
 	var queryParams string
@@ -277,11 +253,10 @@ func (c *ctx) inventory() error {
 	}
 
 	// Get latest block
-	ar, err := c.wallet.Accounts(c.ctx, &pb.AccountsRequest{})
+	latestBlock, err := c.wallet.BestBlock(c.ctx)
 	if err != nil {
 		return err
 	}
-	latestBlock := ar.CurrentBlockHeight
 	//fmt.Printf("Current block: %v\n", latestBlock)
 
 	for _, v := range i.Votes {
@@ -314,16 +289,8 @@ func (c *ctx) inventory() error {
 		}
 
 		// Ensure eligibility
-		tix, err := convertTicketHashes(v.VoteDetails.EligibleTickets)
-		if err != nil {
-			fmt.Printf("Ticket pool corrupt: %v %v\n",
-				v.Vote.Token, err)
-			continue
-		}
-		ctres, err := c.wallet.CommittedTickets(c.ctx,
-			&pb.CommittedTicketsRequest{
-				Tickets: tix,
-			})
+		tickets, err := c.wallet.EligibleTickets(c.ctx,
+			v.VoteDetails.EligibleTickets)
 		if err != nil {
 			fmt.Printf("Ticket pool verification: %v %v\n",
 				v.Vote.Token, err)
@@ -331,7 +298,7 @@ func (c *ctx) inventory() error {
 		}
 
 		// Bail if there are no eligible tickets
-		if len(ctres.TicketAddresses) == 0 {
+		if len(tickets) == 0 {
 			fmt.Printf("No eligible tickets: %v\n", v.Vote.Token)
 		}
 
@@ -341,7 +308,7 @@ func (c *ctx) inventory() error {
 		fmt.Printf("  Start block     : %v\n", v.VoteDetails.StartBlockHeight)
 		fmt.Printf("  End block       : %v\n", v.VoteDetails.EndHeight)
 		fmt.Printf("  Mask            : %v\n", v.Vote.Mask)
-		fmt.Printf("  Eligible tickets: %v\n", len(ctres.TicketAddresses))
+		fmt.Printf("  Eligible tickets: %v\n", len(tickets))
 		for _, vo := range v.Vote.Options {
 			fmt.Printf("  Vote Option:\n")
 			fmt.Printf("    Id                   : %v\n", vo.Id)
@@ -400,20 +367,13 @@ func (c *ctx) _vote(token, voteId string) ([]string, *v1.BallotReply, error) {
 	}
 
 	// Find eligble tickets
-	tix, err := convertTicketHashes(prop.VoteDetails.EligibleTickets)
-	if err != nil {
-		return nil, nil, fmt.Errorf("ticket pool corrupt: %v %v",
-			token, err)
-	}
-	ctres, err := c.wallet.CommittedTickets(c.ctx,
-		&pb.CommittedTicketsRequest{
-			Tickets: tix,
-		})
+	eligible, err := c.wallet.EligibleTickets(c.ctx,
+		prop.VoteDetails.EligibleTickets)
 	if err != nil {
 		return nil, nil, fmt.Errorf("ticket pool verification: %v %v",
 			token, err)
 	}
-	if len(ctres.TicketAddresses) == 0 {
+	if len(eligible) == 0 {
 		return nil, nil, fmt.Errorf("no eligible tickets found")
 	}
 
@@ -423,54 +383,17 @@ func (c *ctx) _vote(token, voteId string) ([]string, *v1.BallotReply, error) {
 	}
 
 	// Sign all tickets
-	sm := &pb.SignMessagesRequest{
-		Passphrase: passphrase,
-		Messages: make([]*pb.SignMessagesRequest_Message, 0,
-			len(ctres.TicketAddresses)),
-	}
-	for _, v := range ctres.TicketAddresses {
-		h, err := chainhash.NewHash(v.Ticket)
-		if err != nil {
-			return nil, nil, err
-		}
-		msg := token + h.String() + voteBit
-		sm.Messages = append(sm.Messages, &pb.SignMessagesRequest_Message{
-			Address: v.Address,
-			Message: msg,
-		})
-	}
-	smr, err := c.wallet.SignMessages(c.ctx, sm)
+	votes, err := c.wallet.SignVotes(c.ctx, passphrase, token, voteBit,
+		eligible)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	// Make sure all signatures worked
-	for k, v := range smr.Replies {
-		if v.Error == "" {
-			continue
-		}
-		return nil, nil, fmt.Errorf("signature failed index %v: %v",
-			k, v.Error)
-	}
-
-	// Note that ctres, sm and smr use the same index.
 	cv := v1.Ballot{
-		Votes: make([]decredplugin.CastVote, 0, len(ctres.TicketAddresses)),
+		Votes: votes,
 	}
-	tickets := make([]string, 0, len(ctres.TicketAddresses))
-	for k, v := range ctres.TicketAddresses {
-		h, err := chainhash.NewHash(v.Ticket)
-		if err != nil {
-			return nil, nil, err
-		}
-		signature := hex.EncodeToString(smr.Replies[k].Signature)
-		cv.Votes = append(cv.Votes, decredplugin.CastVote{
-			Token:     token,
-			Ticket:    h.String(),
-			VoteBit:   voteBit,
-			Signature: signature,
-		})
-		tickets = append(tickets, h.String())
+	tickets := make([]string, 0, len(eligible))
+	for _, v := range eligible {
+		tickets = append(tickets, v.Hash)
 	}
 
 	// Vote on the supplied proposal
@@ -699,7 +622,7 @@ func _main() error {
 		return err
 	}
 	// Close GRPC
-	defer c.conn.Close()
+	defer c.wallet.Close()
 
 	// Scan through command line arguments.
 	for i, a := range args {
@@ -0,0 +1,103 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+// activeVotes returns the inventory records of the proposals whose vote is
+// running at the provided best block, ordered by end height and token.
+//
+// This function must be called WITH the mutex held.
+func (b *backend) activeVotes(bestBlock uint64) []*inventoryRecord {
+	type activeVote struct {
+		ir        *inventoryRecord
+		endHeight uint64
+	}
+	var votes []activeVote
+	for _, i := range b.inventory {
+		// Use StartBlockHeight as a canary
+		if len(i.voting.StartBlockHeight) == 0 {
+			continue
+		}
+		ee, err := strconv.ParseUint(i.voting.EndHeight, 10, 64)
+		if err != nil {
+			log.Errorf("invalid ee, should not happen: %v", err)
+			continue
+		}
+		if bestBlock > ee {
+			// expired vote
+			continue
+		}
+		votes = append(votes, activeVote{ir: i, endHeight: ee})
+	}
+
+	sort.Slice(votes, func(i, j int) bool {
+		if votes[i].endHeight != votes[j].endHeight {
+			return votes[i].endHeight < votes[j].endHeight
+		}
+		return votes[i].ir.record.CensorshipRecord.Token <
+			votes[j].ir.record.CensorshipRecord.Token
+	})
+
+	records := make([]*inventoryRecord, 0, len(votes))
+	for _, v := range votes {
+		records = append(records, v.ir)
+	}
+	return records
+}
+
+// activeVoteSummary summarizes the votes that are running at the provided
+// best block.
+//
+// This function must be called WITH the mutex held.
+func (b *backend) activeVoteSummary(bestBlock uint64) (*www.ActiveVoteSummaryReply, error) {
+	avsr := www.ActiveVoteSummaryReply{
+		BestBlock: bestBlock,
+		Votes:     []www.VoteSummary{},
+	}
+	for _, i := range b.activeVotes(bestBlock) {
+		start, err := strconv.ParseUint(i.voting.StartBlockHeight, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		end, err := strconv.ParseUint(i.voting.EndHeight, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		avsr.Votes = append(avsr.Votes, www.VoteSummary{
+			Token:            i.record.CensorshipRecord.Token,
+			Name:             i.proposalMD.Name,
+			Vote:             i.votebits,
+			StartBlockHeight: start,
+			EndHeight:        end,
+			EligibleTickets:  uint64(len(i.voting.EligibleTickets)),
+		})
+	}
+
+	return &avsr, nil
+}
+
+// ProcessActiveVoteSummary returns the summaries of the active votes.  Unlike
+// ProcessActiveVote, it leaves the snapshots of eligible tickets out, which
+// voting clients need but listings don't.
+func (b *backend) ProcessActiveVoteSummary(ctx context.Context) (*www.ActiveVoteSummaryReply, error) {
+	log.Tracef("ProcessActiveVoteSummary")
+
+	bestBlock, err := b.getBestBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b.RLock()
+	defer b.RUnlock()
+
+	return b.activeVoteSummary(bestBlock)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/decred/politeia/decredplugin"
+)
+
+func TestActiveVoteSummary(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	var tokens []string
+	for i := 0; i < 3; i++ {
+		_, npr, err := createNewProposal(b, t, user, id)
+		assertSuccess(t, err)
+		tokens = append(tokens, npr.CensorshipRecord.Token)
+		publishProposal(b, tokens[i], t, user, id)
+	}
+
+	// The first vote ends last, the second one ended and the third one
+	// didn't start.
+	vote := decredplugin.Vote{
+		Mask: 0x3,
+		Options: []decredplugin.VoteOption{
+			{Id: "no", Bits: 0x1},
+			{Id: "yes", Bits: 0x2},
+		},
+	}
+	b.Lock()
+	b.inventory[tokens[0]].votebits = vote
+	b.inventory[tokens[0]].voting = decredplugin.StartVoteReply{
+		StartBlockHeight: "100",
+		EndHeight:        "300",
+		EligibleTickets:  []string{"a", "b", "c"},
+	}
+	b.inventory[tokens[1]].votebits = vote
+	b.inventory[tokens[1]].voting = decredplugin.StartVoteReply{
+		StartBlockHeight: "50",
+		EndHeight:        "149",
+		EligibleTickets:  []string{"a"},
+	}
+	b.Unlock()
+
+	b.RLock()
+	avsr, err := b.activeVoteSummary(150)
+	b.RUnlock()
+	assertSuccess(t, err)
+	if avsr.BestBlock != 150 || len(avsr.Votes) != 1 {
+		t.Fatalf("unexpected summary %v", avsr)
+	}
+	v := avsr.Votes[0]
+	if v.Token != tokens[0] || v.StartBlockHeight != 100 ||
+		v.EndHeight != 300 || v.EligibleTickets != 3 ||
+		len(v.Vote.Options) != 2 {
+		t.Fatalf("unexpected vote summary %v", v)
+	}
+
+	// The votes are ordered by end height.
+	b.RLock()
+	avsr, err = b.activeVoteSummary(149)
+	b.RUnlock()
+	assertSuccess(t, err)
+	if len(avsr.Votes) != 2 || avsr.Votes[0].Token != tokens[1] ||
+		avsr.Votes[1].Token != tokens[0] {
+		t.Fatalf("unexpected summary %v", avsr)
+	}
+}
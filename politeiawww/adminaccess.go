@@ -0,0 +1,71 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/util"
+)
+
+// adminListenerKey is the context key that marks the requests that arrived on
+// an admin listener.
+type adminListenerKey struct{}
+
+// withAdminListener marks the requests that the provided handler serves as
+// arrived on an admin listener.
+func withAdminListener(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), adminListenerKey{}, true)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// adminAllowed returns true if the provided request may reach the admin
+// routes.  When admin listeners are configured, the request must have arrived
+// on one of them, and when admin networks are configured, the client must be
+// in one of them.  Requests that a replica forwarded were received on a
+// listener of the replica, which checked it.
+func (p *politeiawww) adminAllowed(r *http.Request) bool {
+	if len(p.cfg.AdminListeners) != 0 && !validClusterToken(p.cfg, r) {
+		admin, _ := r.Context().Value(adminListenerKey{}).(bool)
+		if !admin {
+			return false
+		}
+	}
+
+	if len(p.cfg.AdminNetworks) == 0 {
+		return true
+	}
+	ip := p.clientIP(r)
+	if ip == nil {
+		return false
+	}
+	for _, v := range p.cfg.AdminNetworks {
+		if v.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictAdmin rejects the requests to the admin routes that don't come from
+// an admin listener or an admin network before calling the next function.
+func (p *politeiawww) restrictAdmin(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !p.adminAllowed(r) {
+			log.Debugf("restrictAdmin: %v denied %v %v", remoteAddr(r),
+				r.Method, r.URL)
+			util.RespondWithJSON(w, http.StatusForbidden, v1.ErrorReply{
+				ErrorCode: int64(v1.ErrorStatusAdminRestricted),
+			})
+			return
+		}
+
+		f(w, r)
+	}
+}
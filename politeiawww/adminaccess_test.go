@@ -0,0 +1,52 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRestrictAdmin(t *testing.T) {
+	p := &politeiawww{
+		cfg: &config{},
+	}
+	handler := withAdminListener(p.restrictAdmin(
+		func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(h http.Handler, remote string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Admin routes are only served on the admin listeners.
+	p.cfg.AdminListeners = []string{"127.0.0.1:49153"}
+	if code := request(p.restrictAdmin(
+		func(w http.ResponseWriter, r *http.Request) {}),
+		"10.0.0.1:1234"); code != http.StatusForbidden {
+		t.Fatalf("got %v, want %v", code, http.StatusForbidden)
+	}
+	if code := request(handler, "10.0.0.1:1234"); code != http.StatusOK {
+		t.Fatalf("got %v, want %v", code, http.StatusOK)
+	}
+
+	// Admin routes are only served to the admin networks.
+	_, network, err := net.ParseCIDR("10.0.0.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.cfg.AdminNetworks = []*net.IPNet{network}
+	if code := request(handler, "10.0.1.1:1234"); code != http.StatusForbidden {
+		t.Fatalf("got %v, want %v", code, http.StatusForbidden)
+	}
+	if code := request(handler, "10.0.0.1:1234"); code != http.StatusOK {
+		t.Fatalf("got %v, want %v", code, http.StatusOK)
+	}
+}
@@ -8,84 +8,366 @@ import (
 
 type ErrorStatusT int
 type PropStatusT int
+type PropVoteStatusT int
+type EmailNotificationT int
+type EmailDigestT int
 
 const (
 	PoliteiaWWWAPIVersion = 1 // API version this backend understands
 
-	CsrfToken = "X-CSRF-Token"    // CSRF token for replies
-	Forward   = "X-Forwarded-For" // Proxy header
+	CsrfToken     = "X-CSRF-Token"             // CSRF token for replies
+	Forward       = "X-Forwarded-For"          // Proxy header
+	Impersonating = "X-Politeia-Impersonating" // Id of the impersonated user
 
 	RouteUserMe              = "/user/me"
 	RouteNewUser             = "/user/new"
+	RouteSignupChallenge     = "/user/new/challenge"
+	RouteResendVerification  = "/user/new/resend"
+	RouteEditUser            = "/user/edit"
+	RouteUnsubscribe         = "/user/unsubscribe"
+	RouteSESWebhook          = "/email/webhook/ses"
+	RouteSendgridWebhook     = "/email/webhook/sendgrid"
 	RouteVerifyNewUser       = "/user/verify"
 	RouteUpdateUserKey       = "/user/key"
 	RouteVerifyUpdateUserKey = "/user/key/verify"
+	RouteUserKeyChallenge    = "/user/key/challenge"
+	RouteAddUserKey          = "/user/key/add"
+	RouteDeactivateUserKey   = "/user/key/deactivate"
+	RouteUserIdentities      = "/user/identities"
 	RouteChangePassword      = "/user/password/change"
+	RouteChangeEmail         = "/user/email"
+	RouteDeactivateUser      = "/user/deactivate"
+	RouteUserSessions        = "/user/sessions"
+	RouteSecurityEvents      = "/user/security-events"
+	RouteUserExport          = "/user/export"
+	RouteUserExportDownload  = "/user/export/download"
+	RouteRevokeSessions      = "/user/sessions/revoke"
+	RouteAPITokens           = "/user/tokens"
+	RouteNewAPIToken         = "/user/tokens/new"
+	RouteRevokeAPIToken      = "/user/tokens/revoke"
+	RouteVerifyChangeEmail   = "/user/email/verify"
 	RouteResetPassword       = "/user/password/reset"
+	RouteRecoveryChallenge   = "/user/recover/challenge"
+	RouteRecoverAccount      = "/user/recover"
 	RouteUserProposals       = "/user/proposals"
+	RouteUserStats           = "/user/stats"
+	RouteDrafts              = "/user/drafts"
+	RouteNewDraft            = "/user/drafts/new"
+	RouteEditDraft           = "/user/drafts/edit"
+	RouteDeleteDraft         = "/user/drafts/delete"
+	RouteUserDetails         = "/user/{uuid:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}"
 	RouteVerifyUserPaymentTx = "/user/verifypaymenttx"
+	RouteProposalCredits     = "/user/credits"
+	RoutePurchaseCredits     = "/user/credits/purchase"
+	RouteVerifyCreditsTx     = "/user/credits/verify"
+	RouteSetTOTP             = "/user/totp"
+	RouteVerifyTOTP          = "/user/totp/verify"
+	RouteDisableTOTP         = "/user/totp/disable"
 	RouteLogin               = "/login"
 	RouteLogout              = "/logout"
+	RouteRefreshToken        = "/token/refresh"
+	RouteRevokeToken         = "/token/revoke"
+	RouteOIDCLogin           = "/oidc/login"
+	RouteOIDCLink            = "/oidc/link"
+	RouteOIDCCallback        = "/oidc/callback"
+	RouteOIDCUnlink          = "/oidc/unlink"
 	RouteSecret              = "/secret"
 	RouteAllVetted           = "/proposals/vetted"
 	RouteAllUnvetted         = "/proposals/unvetted"
 	RouteNewProposal         = "/proposals/new"
+	RouteEditProposal        = "/proposals/edit"
 	RouteProposalDetails     = "/proposals/{token:[A-z0-9]{64}}"
+	RouteProposalFile        = "/proposals/{token:[A-z0-9]{64}}/files/{name}"
+	RouteProposalBundle      = "/proposals/{token:[A-z0-9]{64}}/bundle"
 	RouteSetProposalStatus   = "/proposals/{token:[A-z0-9]{64}}/status"
+	RouteStatusHistory       = "/proposals/{token:[A-z0-9]{64}}/statushistory"
+	RouteVoteStatus          = "/proposals/{token:[A-z0-9]{64}}/votestatus"
+	RouteVoteReceipt         = "/proposals/{token:[A-z0-9]{64}}/votes/{ticket:[A-z0-9]{64}}"
+	RouteSetProposalTags     = "/proposals/tags"   // Admin only
+	RouteImportProposal      = "/proposals/import" // Admin only
+	RouteProposalsStats      = "/proposals/stats"
+	RouteProposalsRSS        = "/proposals/feed.rss"
+	RouteProposalsAtom       = "/proposals/feed.atom"
 	RoutePolicy              = "/policy"
 	RouteVersion             = "/version"
 	RouteNewComment          = "/comments/new"
+	RouteLikeComment         = "/comments/like"
+	RouteCensorComment       = "/comments/censor" // Admin only
 	RouteCommentsGet         = "/proposals/{token:[A-z0-9]{64}}/comments"
 	RouteStartVote           = "/proposals/startvote"
 	RouteActiveVote          = "/proposals/activevote" // XXX rename to ActiveVotes
+	RouteActiveVoteSummary   = "/proposals/activevote/summary"
 	RouteCastVotes           = "/proposals/castvotes"
+	RouteVoteStream          = "/proposals/votestream" // WebSocket
 	// XXX should we use a fancy route like the one underneath?
 	//RouteProposalVotes    = "/proposals/{token:[A-z0-9]{64}}/votes"
 	RouteProposalVotes = "/proposals/voteresults"
 
+	// IP ban routes, admin only
+	RouteIPBans  = "/ipbans"
+	RouteBanIP   = "/ipbans/ban"
+	RouteUnbanIP = "/ipbans/unban"
+
+	// User management routes, admin only
+	RouteUsers               = "/users"
+	RouteUserSearch          = "/users/search"
+	RouteAdminDeactivateUser = "/users/deactivate"
+	RouteAdminVerifyUser     = "/users/verify"
+	RouteGrantCredits        = "/users/credits/grant"
+	RouteSetUserAdmin        = "/users/admin"
+	RouteAdminActions        = "/users/adminactions"
+	RouteImpersonate         = "/users/impersonate"
+	RouteStopImpersonation   = "/users/impersonate/stop"
+
+	// Approval routes of destructive admin actions, admin only
+	RoutePendingActions       = "/pendingactions"
+	RouteApprovePendingAction = "/pendingactions/approve"
+	RouteRejectPendingAction  = "/pendingactions/reject"
+
+	// Review routes of the proposals that the spam filters held, admin only
+	RouteHeldProposals       = "/proposals/held"
+	RouteReleaseHeldProposal = "/proposals/held/release"
+	RouteRejectHeldProposal  = "/proposals/held/reject"
+
+	// Webhook routes, admin only
+	RouteWebhookDeliveries = "/webhooks/deliveries"
+
+	// Feature flag routes, setting a flag is admin only
+	RouteFeatures   = "/features"
+	RouteSetFeature = "/features/set"
+
+	// RouteClusterEvent is used between the politeiawww instances of a
+	// cluster.
+	RouteClusterEvent = "/cluster/event"
+
 	// VerificationTokenSize is the size of verification token in bytes
 	VerificationTokenSize = 32
 
-	// VerificationExpiryHours is the number of hours before the
-	// verification token expires
+	// VerificationExpiryHours is the default number of hours before the
+	// verification token expires.  The server may configure the expiry of
+	// each type of token.
 	VerificationExpiryHours = 48
 
-	// PolicyMaxImages is the maximum number of images accepted
-	// when creating a new proposal
+	// PolicyMaxImages is the default maximum number of images accepted
+	// when creating a new proposal.  Servers may be configured with other
+	// limits than the Policy* defaults marked as such; PolicyReply carries
+	// the limits that apply.
 	PolicyMaxImages = 5
 
-	// PolicyMaxImageSize is the maximum image file size (in bytes)
-	// accepted when creating a new proposal
+	// PolicyMaxImageSize is the default maximum image file size (in
+	// bytes) accepted when creating a new proposal
 	PolicyMaxImageSize = 512 * 1024
 
-	// PolicyMaxMDs is the maximum number of markdown files accepted
-	// when creating a new proposal
-	PolicyMaxMDs = 1
+	// PolicyMaxImageWidth is the maximum width of images in pixels
+	PolicyMaxImageWidth = 4096
+
+	// PolicyMaxImageHeight is the maximum height of images in pixels
+	PolicyMaxImageHeight = 4096
 
-	// PolicyMaxMDSize is the maximum markdown file size (in bytes)
+	// PolicyMaxImagePixels is the maximum number of pixels of images
+	PolicyMaxImagePixels = 8 * 1024 * 1024
+
+	// PolicyMaxMDs is the default maximum number of markdown files
 	// accepted when creating a new proposal
+	PolicyMaxMDs = 5
+
+	// PolicyMaxMDSize is the default maximum markdown file size (in
+	// bytes) accepted when creating a new proposal
 	PolicyMaxMDSize = 512 * 1024
 
 	// PolicyPasswordMinChars is the minimum number of characters
 	// accepted for user passwords
 	PolicyPasswordMinChars = 8
 
-	// PolicyMaxProposalNameLength is the max length of a proposal name
+	// PolicyPasswordMinEntropy is the minimum estimated entropy in bits
+	// accepted for user passwords
+	PolicyPasswordMinEntropy = 36
+
+	// Reasons that a password is rejected for, returned in the error
+	// context of ErrorStatusMalformedPassword
+	PasswordReasonTooShort      = "tooshort"      // Below PolicyPasswordMinChars
+	PasswordReasonTooWeak       = "tooweak"       // Below PolicyPasswordMinEntropy
+	PasswordReasonCommon        = "common"        // Common password
+	PasswordReasonContainsEmail = "containsemail" // Contains the email address
+
+	// Reasons that a markdown file is rejected for, returned in the error
+	// context of ErrorStatusMalformedMarkdown along with the file name and
+	// the line
+	MarkdownReasonMissingTitle = "missingtitle" // First line of the index file is blank
+	MarkdownReasonInvalidUTF8  = "invalidutf8"  // Not valid UTF-8
+	MarkdownReasonBinary       = "binary"       // Binary content labeled as text
+	MarkdownReasonHTML         = "html"         // Raw HTML, if the server rejects it
+
+	// Reasons that an image file is rejected for, returned in the error
+	// context of ErrorStatusInvalidImage along with the file name
+	ImageReasonCorrupt    = "corrupt"    // Can't be decoded
+	ImageReasonDimensions = "dimensions" // Exceeds the image dimension policy
+
+	// Reasons that a proposal timeline is rejected for, returned in the
+	// error context of ErrorStatusInvalidProposalTimeline
+	TimelineReasonAmount   = "amount"   // Exceeds the funding policy
+	TimelineReasonDates    = "dates"    // Missing date, or ends before it starts
+	TimelineReasonPast     = "past"     // Ends before the submission
+	TimelineReasonDuration = "duration" // Exceeds the duration policy
+
+	// Formats of the archives of ProposalBundle
+	ProposalBundleFormatTarGz = "tar.gz" // Gzipped tar, the default
+	ProposalBundleFormatZip   = "zip"
+
+	// Orders that the vetted proposals can be listed in
+	ProposalSortNewest        = "newest"        // Newest first
+	ProposalSortOldest        = "oldest"        // Oldest first
+	ProposalSortMostCommented = "mostcommented" // Most comments first
+	ProposalSortMostViewed    = "mostviewed"    // Most views first
+	ProposalSortVoteEnding    = "voteending"    // Ongoing votes that end soonest first
+
+	// Reasons that the page order of a proposal is rejected for, returned
+	// in the error context of ErrorStatusInvalidProposalPages along with
+	// the file name
+	PagesReasonIndex     = "index"     // The index file is not the first page
+	PagesReasonNotFound  = "notfound"  // Not a markdown file of the proposal
+	PagesReasonDuplicate = "duplicate" // Listed more than once
+	PagesReasonMissing   = "missing"   // Markdown file that is not listed
+
+	// Reasons that a proposal bundle is rejected for, returned in the error
+	// context of ErrorStatusInvalidProposalBundle along with a detail
+	BundleReasonMalformed  = "malformed"  // Not an archive of the format
+	BundleReasonMissing    = "missing"    // Lacks an entry
+	BundleReasonDigest     = "digest"     // File that doesn't match its digest
+	BundleReasonSignature  = "signature"  // Author signature doesn't verify
+	BundleReasonCensorship = "censorship" // Censorship record doesn't verify
+	BundleReasonDuplicate  = "duplicate"  // Proposal was already imported
+
+	// Spam filters that contribute to the spam score of a new proposal
+	SpamReasonKeyword   = "keyword"   // Contains a configured keyword
+	SpamReasonLinks     = "links"     // Is mostly links
+	SpamReasonDuplicate = "duplicate" // Resembles a recent submission
+
+	// Reasons that the co-authors of a proposal are rejected for, returned
+	// in the error context of ErrorStatusInvalidProposalCoAuthor along with
+	// the public key of the co-author
+	CoAuthorReasonKey       = "key"       // Not a valid public key
+	CoAuthorReasonSignature = "signature" // Signature doesn't verify
+	CoAuthorReasonDuplicate = "duplicate" // Listed twice, or the author
+	CoAuthorReasonTooMany   = "toomany"   // Exceeds the co-author policy
+
+	// PolicyMaxProposalNameLength is the default max length of a
 	// proposal name
 	PolicyMaxProposalNameLength = 80
 
-	// PolicyMinProposalNameLength is the min length of a proposal name
+	// PolicyMinProposalNameLength is the default min length of a
 	// proposal name
 	PolicyMinProposalNameLength = 8
 
-	// PolicyMaxCommentLength is the maximum number of characters
-	// accepted for comments
+	// PolicyMaxCommentLength is the default maximum number of
+	// characters accepted for comments
 	PolicyMaxCommentLength = 8000
 
+	// PolicyMaxDrafts is the maximum number of proposal drafts that a user
+	// can save
+	PolicyMaxDrafts = 10
+
+	// PolicyMaxProposalTags is the maximum number of tags of a proposal
+	PolicyMaxProposalTags = 5
+
+	// PolicyMaxProposalTagLength is the maximum length of a proposal tag
+	PolicyMaxProposalTagLength = 32
+
+	// PolicyMaxProposalCoAuthors is the maximum number of co-authors of a
+	// proposal
+	PolicyMaxProposalCoAuthors = 5
+
+	// PolicyMaxProposalAmount is the maximum funding that a proposal may
+	// request, in US cents
+	PolicyMaxProposalAmount = 100000000
+
+	// PolicyMaxProposalDuration is the maximum time between the start and
+	// the end date of a proposal, in seconds
+	PolicyMaxProposalDuration = 366 * 24 * 60 * 60
+
+	// PolicyVoteQuorumPercentage is the default percentage of the eligible
+	// tickets that must vote for a vote to count
+	PolicyVoteQuorumPercentage = decredplugin.VoteQuorumPercentageDefault
+
+	// PolicyVotePassPercentage is the default percentage of the cast votes
+	// that must approve a proposal for it to pass
+	PolicyVotePassPercentage = decredplugin.VotePassPercentageDefault
+
+	// VoteOptionIDApprove is the id of the vote option that approves a
+	// proposal
+	VoteOptionIDApprove = decredplugin.VoteOptionIDApprove
+
 	// ProposalListPageSize is the maximum number of proposals returned
 	// for the routes that return lists of proposals
 	ProposalListPageSize = 20
 
+	// UserListPageSize is the maximum number of users returned for the
+	// routes that return lists of users
+	UserListPageSize = 20
+
+	// User list sort orders
+	UserSortCreated = "created" // Order of creation, oldest first
+	UserSortEmail   = "email"   // Email address
+
+	// APITokenMaxPerUser is the maximum number of personal access tokens
+	// of a user
+	APITokenMaxPerUser = 10
+
+	// APITokenMaxNameLength is the maximum length of the names of
+	// personal access tokens
+	APITokenMaxNameLength = 64
+
+	// UserKeyMaxActive is the maximum number of active public keys of a
+	// user
+	UserKeyMaxActive = 5
+
+	// ProposalCreditsMaxPurchase is the maximum number of proposal credits
+	// that can be bought or granted at once
+	ProposalCreditsMaxPurchase = 100
+
+	// Security event types
+	SecurityEventLogin          = "login"          // Successful login
+	SecurityEventLoginFailed    = "loginfailed"    // Failed login
+	SecurityEventVerify         = "verify"         // Email address verified on signup
+	SecurityEventPasswordChange = "passwordchange" // Password changed
+	SecurityEventPasswordReset  = "passwordreset"  // Password reset by email
+	SecurityEventEmailChange    = "emailchange"    // Email address changed
+	SecurityEventKeyAdded       = "keyadded"       // Public key activated
+	SecurityEventKeyDeactivated = "keydeactivated" // Public key deactivated
+	SecurityEventTOTPEnabled    = "totpenabled"    // Second factor enabled
+	SecurityEventTOTPDisabled   = "totpdisabled"   // Second factor disabled
+	SecurityEventRecovery       = "recovery"       // Account recovered with a public key
+
+	// Admin action types
+	AdminActionGrantAdmin        = "grantadmin"        // Admin privileges granted
+	AdminActionRevokeAdmin       = "revokeadmin"       // Admin privileges revoked
+	AdminActionImpersonate       = "impersonate"       // Impersonation started
+	AdminActionStopImpersonation = "stopimpersonation" // Impersonation ended
+	AdminActionVerifyUser        = "verifyuser"        // Email address verified by an admin
+	AdminActionCensorComment     = "censorcomment"     // Comment of the user censored
+	AdminActionDeactivateUser    = "deactivateuser"    // User deactivated by an admin
+
+	// Types of the admin actions that require the approval of a second
+	// admin when the server is configured to
+	PendingActionCensorProposal = "censorproposal" // Proposal censored
+	PendingActionDeactivateUser = "deactivateuser" // User deactivated by an admin
+
+	// Decisions on pending admin actions
+	PendingActionApprove = "approve"
+	PendingActionReject  = "reject"
+
+	// Types of the proposal lifecycle events that are sent to the webhooks
+	WebhookEventProposalSubmitted = "proposal.submitted" // Proposal submitted
+	WebhookEventProposalPublished = "proposal.published" // Proposal made public
+	WebhookEventProposalCensored  = "proposal.censored"  // Proposal censored
+	WebhookEventVoteStarted       = "vote.started"       // Vote started
+	WebhookEventVoteEnded         = "vote.ended"         // Vote ended
+
+	// Personal access token scopes
+	APITokenScopeRead     = "read"     // GET requests only
+	APITokenScopeProposal = "proposal" // Read and submit proposals
+	APITokenScopeAdmin    = "admin"    // All requests, admins only
+
 	// Error status codes
 	ErrorStatusInvalid                     ErrorStatusT = 0
 	ErrorStatusInvalidEmailOrPassword      ErrorStatusT = 1
@@ -118,6 +400,64 @@ const (
 	ErrorStatusWrongStatus                 ErrorStatusT = 28
 	ErrorStatusNotLoggedIn                 ErrorStatusT = 29
 	ErrorStatusUserNotPaid                 ErrorStatusT = 30
+	ErrorStatusIPBanned                    ErrorStatusT = 31
+	ErrorStatusInvalidIPNetwork            ErrorStatusT = 32
+	ErrorStatusIPBanNotFound               ErrorStatusT = 33
+	ErrorStatusInvalidSignupChallenge      ErrorStatusT = 34
+	ErrorStatusInvalidProofOfWork          ErrorStatusT = 35
+	ErrorStatusInvalidFeature              ErrorStatusT = 36
+	ErrorStatusFeatureNotToggleable        ErrorStatusT = 37
+	ErrorStatusFeatureDisabled             ErrorStatusT = 38
+	ErrorStatusTOTPCodeRequired            ErrorStatusT = 39
+	ErrorStatusInvalidTOTPCode             ErrorStatusT = 40
+	ErrorStatusTOTPEnabled                 ErrorStatusT = 41
+	ErrorStatusTOTPNotEnabled              ErrorStatusT = 42
+	ErrorStatusRateLimited                 ErrorStatusT = 43
+	ErrorStatusDuplicateEmail              ErrorStatusT = 44
+	ErrorStatusUserDeactivated             ErrorStatusT = 45
+	ErrorStatusSessionNotFound             ErrorStatusT = 46
+	ErrorStatusInvalidToken                ErrorStatusT = 47
+	ErrorStatusInvalidAPITokenScope        ErrorStatusT = 48
+	ErrorStatusAPITokenNotFound            ErrorStatusT = 49
+	ErrorStatusTooManyAPITokens            ErrorStatusT = 50
+	ErrorStatusInvalidOIDCLogin            ErrorStatusT = 51
+	ErrorStatusOIDCAccountNotLinked        ErrorStatusT = 52
+	ErrorStatusOIDCAccountLinked           ErrorStatusT = 53
+	ErrorStatusTooManyPublicKeys           ErrorStatusT = 54
+	ErrorStatusDuplicatePublicKey          ErrorStatusT = 55
+	ErrorStatusLastPublicKey               ErrorStatusT = 56
+	ErrorStatusInvalidKeyChallenge         ErrorStatusT = 57
+	ErrorStatusNoProposalCredits           ErrorStatusT = 58
+	ErrorStatusNoCreditPurchase            ErrorStatusT = 59
+	ErrorStatusPaymentTxUsed               ErrorStatusT = 60
+	ErrorStatusUserExportNotReady          ErrorStatusT = 61
+	ErrorStatusImpersonationReadOnly       ErrorStatusT = 62
+	ErrorStatusPendingActionNotFound       ErrorStatusT = 63
+	ErrorStatusPendingActionExists         ErrorStatusT = 64
+	ErrorStatusApprovalBySameAdmin         ErrorStatusT = 65
+	ErrorStatusAdminRestricted             ErrorStatusT = 66
+	ErrorStatusInvalidLikeCommentAction    ErrorStatusT = 67
+	ErrorStatusCommentIsCensored           ErrorStatusT = 68
+	ErrorStatusUserNotAuthor               ErrorStatusT = 69
+	ErrorStatusDraftNotFound               ErrorStatusT = 70
+	ErrorStatusMaxDraftsExceededPolicy     ErrorStatusT = 71
+	ErrorStatusInvalidProposalTag          ErrorStatusT = 72
+	ErrorStatusMaxProposalTagsExceeded     ErrorStatusT = 73
+	ErrorStatusChangeMessageCannotBeBlank  ErrorStatusT = 74
+	ErrorStatusMalformedMarkdown           ErrorStatusT = 75
+	ErrorStatusInvalidImage                ErrorStatusT = 76
+	ErrorStatusProposalFileNotFound        ErrorStatusT = 77
+	ErrorStatusInvalidProposalTimeline     ErrorStatusT = 78
+	ErrorStatusInvalidProposalPages        ErrorStatusT = 79
+	ErrorStatusInvalidProposalBundle       ErrorStatusT = 80
+	ErrorStatusProposalHeld                ErrorStatusT = 81
+	ErrorStatusHeldProposalNotFound        ErrorStatusT = 82
+	ErrorStatusInvalidProposalCoAuthor     ErrorStatusT = 83
+	ErrorStatusInvalidPublishTime          ErrorStatusT = 84
+	ErrorStatusInvalidVoteParams           ErrorStatusT = 85
+	ErrorStatusVoteAlreadyStarted          ErrorStatusT = 86
+	ErrorStatusVoteNotFound                ErrorStatusT = 87
+	ErrorStatusInvalidIPBan                ErrorStatusT = 88
 
 	// Proposal status codes (set and get)
 	PropStatusInvalid     PropStatusT = 0 // Invalid status
@@ -126,6 +466,31 @@ const (
 	PropStatusCensored    PropStatusT = 3 // Proposal has been censored
 	PropStatusPublic      PropStatusT = 4 // Proposal is publicly visible
 	PropStatusLocked      PropStatusT = 6 // Proposal is locked
+
+	// Proposal vote status codes
+	PropVoteStatusInvalid    PropVoteStatusT = 0 // Invalid vote status
+	PropVoteStatusNotStarted PropVoteStatusT = 1 // Vote has not started
+	PropVoteStatusActive     PropVoteStatusT = 2 // Vote is ongoing
+	PropVoteStatusFinished   PropVoteStatusT = 3 // Vote has ended
+
+	// Email notification types.  These are bit flags that are stored in
+	// the user record and determine which notifications a user receives.
+	NotificationEmailMyProposalStatusChange EmailNotificationT = 1 << 0
+	NotificationEmailAdminProposalNew       EmailNotificationT = 1 << 1
+	NotificationEmailVoteStarted            EmailNotificationT = 1 << 2
+	NotificationEmailVoteEnded              EmailNotificationT = 1 << 3
+
+	// Email digest frequencies
+	EmailDigestNone   EmailDigestT = 0 // Don't email a digest
+	EmailDigestDaily  EmailDigestT = 1 // Email a digest once a day
+	EmailDigestWeekly EmailDigestT = 2 // Email a digest once a week
+
+	// Feature flags
+	FeatureComments   = "comments"   // Reading and posting comments
+	FeatureCredits    = "credits"    // Proposals cost a proposal credit
+	FeaturePaywall    = "paywall"    // User registration paywall
+	FeatureSearch     = "search"     // Search
+	FeatureWebSockets = "websockets" // WebSocket notifications
 )
 
 var (
@@ -175,6 +540,64 @@ var (
 		ErrorStatusWrongStatus:                 "wrong status",
 		ErrorStatusNotLoggedIn:                 "user not logged in",
 		ErrorStatusUserNotPaid:                 "user not paid paywall",
+		ErrorStatusIPBanned:                    "ip address is banned",
+		ErrorStatusInvalidIPNetwork:            "invalid ip address or network",
+		ErrorStatusIPBanNotFound:               "ip ban not found",
+		ErrorStatusInvalidSignupChallenge:      "invalid or expired signup challenge",
+		ErrorStatusInvalidProofOfWork:          "invalid proof of work",
+		ErrorStatusInvalidFeature:              "invalid feature",
+		ErrorStatusFeatureNotToggleable:        "feature can't be toggled at runtime",
+		ErrorStatusFeatureDisabled:             "feature is disabled",
+		ErrorStatusTOTPCodeRequired:            "two-factor code required",
+		ErrorStatusInvalidTOTPCode:             "invalid two-factor code",
+		ErrorStatusTOTPEnabled:                 "two-factor authentication already enabled",
+		ErrorStatusTOTPNotEnabled:              "two-factor authentication not enabled",
+		ErrorStatusRateLimited:                 "too many requests",
+		ErrorStatusDuplicateEmail:              "email address already in use",
+		ErrorStatusUserDeactivated:             "user account is deactivated",
+		ErrorStatusSessionNotFound:             "session not found",
+		ErrorStatusInvalidToken:                "invalid or expired token",
+		ErrorStatusInvalidAPITokenScope:        "invalid API token scope",
+		ErrorStatusAPITokenNotFound:            "API token not found",
+		ErrorStatusTooManyAPITokens:            "too many API tokens",
+		ErrorStatusInvalidOIDCLogin:            "invalid or expired federated login",
+		ErrorStatusOIDCAccountNotLinked:        "no user linked to the provider account",
+		ErrorStatusOIDCAccountLinked:           "provider account linked to another user",
+		ErrorStatusTooManyPublicKeys:           "too many active public keys",
+		ErrorStatusDuplicatePublicKey:          "public key already in use",
+		ErrorStatusLastPublicKey:               "cannot deactivate the last active public key",
+		ErrorStatusInvalidKeyChallenge:         "invalid or expired key challenge",
+		ErrorStatusNoProposalCredits:           "no proposal credits",
+		ErrorStatusNoCreditPurchase:            "no pending proposal credit purchase",
+		ErrorStatusPaymentTxUsed:               "payment transaction already used",
+		ErrorStatusUserExportNotReady:          "user data export not ready",
+		ErrorStatusImpersonationReadOnly:       "impersonation is read-only",
+		ErrorStatusPendingActionNotFound:       "pending action not found or expired",
+		ErrorStatusPendingActionExists:         "action already awaits approval",
+		ErrorStatusApprovalBySameAdmin:         "action must be approved by another admin",
+		ErrorStatusAdminRestricted:             "admin routes are not available from this address",
+		ErrorStatusInvalidLikeCommentAction:    "invalid like comment action",
+		ErrorStatusCommentIsCensored:           "comment is censored",
+		ErrorStatusUserNotAuthor:               "user is not the author of the proposal",
+		ErrorStatusDraftNotFound:               "draft not found",
+		ErrorStatusMaxDraftsExceededPolicy:     "maximum number of drafts exceeded",
+		ErrorStatusInvalidProposalTag:          "invalid proposal tag",
+		ErrorStatusMaxProposalTagsExceeded:     "maximum number of proposal tags exceeded",
+		ErrorStatusChangeMessageCannotBeBlank:  "status change message cannot be blank",
+		ErrorStatusMalformedMarkdown:           "malformed markdown file",
+		ErrorStatusInvalidImage:                "invalid image file",
+		ErrorStatusProposalFileNotFound:        "proposal file not found",
+		ErrorStatusInvalidProposalTimeline:     "invalid proposal timeline",
+		ErrorStatusInvalidProposalPages:        "invalid proposal page order",
+		ErrorStatusInvalidProposalBundle:       "invalid proposal bundle",
+		ErrorStatusProposalHeld:                "proposal held for review",
+		ErrorStatusHeldProposalNotFound:        "held proposal not found",
+		ErrorStatusInvalidProposalCoAuthor:     "invalid proposal co-author",
+		ErrorStatusInvalidPublishTime:          "invalid publication time",
+		ErrorStatusInvalidVoteParams:           "invalid vote parameters",
+		ErrorStatusVoteAlreadyStarted:          "vote already started",
+		ErrorStatusVoteNotFound:                "vote not found",
+		ErrorStatusInvalidIPBan:                "invalid ip ban",
 	}
 )
 
@@ -212,8 +635,47 @@ type ProposalRecord struct {
 	UserId      string      `json:"userid"`      // ID of user who submitted proposal
 	PublicKey   string      `json:"publickey"`   // Key used for signature.
 	Signature   string      `json:"signature"`   // Signature of merkle root
+	Version     uint64      `json:"version"`     // Version of the files, starting at 1
 	Files       []File      `json:"files"`       // Files that make up the proposal
 	NumComments uint        `json:"numcomments"` // Number of comments on the proposal
+	Tags        []string    `json:"tags"`        // Tags of the proposal
+
+	// Timeline is the funding and the schedule that the author provided,
+	// if any.
+	Timeline *ProposalTimeline `json:"timeline,omitempty"`
+
+	// Pages are the names of the markdown files of the proposal in the
+	// order they are read, starting with the index file.  They are omitted
+	// when the files of the proposal are not returned and the proposal has
+	// a single page.
+	Pages []string `json:"pages,omitempty"`
+
+	// StatusChangeMessage is the reason of the last status change.
+	StatusChangeMessage string `json:"statuschangemessage,omitempty"`
+
+	// StatusChangePublicKey and StatusChangeSignature are the key and the
+	// signature of the SetProposalStatus command of the last status
+	// change.  They are absent from changes that predate their storage.
+	StatusChangePublicKey string `json:"statuschangepublickey,omitempty"`
+	StatusChangeSignature string `json:"statuschangesignature,omitempty"`
+
+	// PublishAt is the time that the proposal is scheduled to be
+	// published at.  It is only returned in the unvetted listing.
+	PublishAt int64 `json:"publishat,omitempty"`
+
+	// Views is the number of views of the proposal.  It is only returned
+	// in the listings.
+	Views uint64 `json:"views,omitempty"`
+
+	// CoAuthors are the authors besides the submitter whose signatures of
+	// the merkle root were verified at submission.
+	CoAuthors []CoAuthor `json:"coauthors,omitempty"`
+
+	// SpamScore and SpamReasons are the spam score of the proposal and the
+	// filters that contributed to it when it was flagged on submission.
+	// They are only returned in the unvetted listing.
+	SpamScore   int      `json:"spamscore,omitempty"`
+	SpamReasons []string `json:"spamreasons,omitempty"`
 
 	CensorshipRecord CensorshipRecord `json:"censorshiprecord"`
 }
@@ -279,6 +741,11 @@ type NewUser struct {
 	Email     string `json:"email"`
 	Password  string `json:"password"`
 	PublicKey string `json:"publickey"`
+
+	// Proof of work, required when the policy has a signup proof of work
+	// difficulty.
+	Challenge string `json:"challenge,omitempty"` // Challenge from SignupChallengeReply
+	Nonce     string `json:"nonce,omitempty"`     // Hex encoded solution of the challenge
 }
 
 // NewUserReply is used to reply to the NewUser command with an error
@@ -290,6 +757,32 @@ type NewUserReply struct {
 	VerificationToken  string `json:"verificationtoken"`  // Server verification token
 }
 
+// ResendVerification sends the verification email of a new user again.  The
+// verification token is regenerated if it expired.
+type ResendVerification struct {
+	Email string `json:"email"`
+}
+
+// ResendVerificationReply is used to reply to the ResendVerification
+// command.  The reply is the same whether or not the user exists.
+type ResendVerificationReply struct {
+	VerificationToken string `json:"verificationtoken"` // Server verification token
+}
+
+// SignupChallenge requests a proof of work challenge that must be solved
+// before creating a new user.
+type SignupChallenge struct{}
+
+// SignupChallengeReply returns a proof of work challenge.  The challenge is
+// solved by a nonce for which the SHA256 digest of the decoded challenge
+// followed by the nonce starts with at least Difficulty zero bits.  A
+// challenge can only be used once and must be used before it expires.
+type SignupChallengeReply struct {
+	Challenge  string `json:"challenge"`  // Hex encoded challenge
+	Difficulty uint   `json:"difficulty"` // Required number of leading zero bits
+	Expiry     int64  `json:"expiry"`     // Unix timestamp of expiration
+}
+
 // VerifyNewUser is used to perform verification for the user created through
 // the NewUser command using the token provided in NewUserReply.
 type VerifyNewUser struct {
@@ -320,6 +813,85 @@ type VerifyUpdateUserKey struct {
 // VerifyUpdateUserKeyReply replies to the VerifyUpdateUserKey command.
 type VerifyUpdateUserKeyReply struct{}
 
+// UserKeyChallenge requests a challenge that the provided public key must
+// sign before it can be added with AddUserKey.
+type UserKeyChallenge struct {
+	PublicKey string `json:"publickey"` // New public key
+}
+
+// UserKeyChallengeReply returns a single use challenge.
+type UserKeyChallengeReply struct {
+	Challenge string `json:"challenge"` // Random hex encoded challenge
+	Expiry    int64  `json:"expiry"`    // Unix time the challenge expires
+}
+
+// RecoveryChallenge requests a challenge that the provided public key must
+// sign to recover the account of its user with RecoverAccount.
+type RecoveryChallenge struct {
+	PublicKey string `json:"publickey"` // Active public key of the user
+}
+
+// RecoveryChallengeReply returns a single use challenge.
+type RecoveryChallengeReply struct {
+	Challenge string `json:"challenge"` // Random hex encoded challenge
+	Expiry    int64  `json:"expiry"`    // Unix time the challenge expires
+}
+
+// RecoverAccount sets a new password for a user that lost access to its email
+// address and requests a change to the new email address, which must be
+// verified with VerifyChangeEmail.  The user proves ownership of the account
+// by signing a challenge from RecoveryChallengeReply with one of its active
+// public keys.  All sessions and API tokens of the user are revoked.
+type RecoverAccount struct {
+	PublicKey   string `json:"publickey"`   // Active public key of the user
+	Challenge   string `json:"challenge"`   // Challenge from RecoveryChallengeReply
+	Signature   string `json:"signature"`   // Signature of Challenge
+	NewEmail    string `json:"newemail"`    // New email address
+	NewPassword string `json:"newpassword"` // New password
+}
+
+// RecoverAccountReply is the reply for the RecoverAccount command.
+type RecoverAccountReply struct {
+	VerificationToken string `json:"verificationtoken,omitempty"` // Server verification token
+}
+
+// AddUserKey activates an additional public key for the logged in user.  The
+// new key must be signed by one of the active keys of the user, and must sign
+// a challenge from UserKeyChallengeReply to prove that the user controls it.
+type AddUserKey struct {
+	PublicKey          string `json:"publickey"`          // New public key
+	Signature          string `json:"signature"`          // Signature of PublicKey by an active key
+	Challenge          string `json:"challenge"`          // Challenge from UserKeyChallengeReply
+	ChallengeSignature string `json:"challengesignature"` // Signature of Challenge by PublicKey
+}
+
+// AddUserKeyReply replies to the AddUserKey command.
+type AddUserKeyReply struct{}
+
+// DeactivateUserKey deactivates one of the public keys of the logged in
+// user.  Signatures that were made with the key remain valid.
+type DeactivateUserKey struct {
+	PublicKey string `json:"publickey"` // Public key to deactivate
+}
+
+// DeactivateUserKeyReply replies to the DeactivateUserKey command.
+type DeactivateUserKeyReply struct{}
+
+// UserIdentity describes a public key of a user.
+type UserIdentity struct {
+	PublicKey   string `json:"publickey"`   // Public key
+	Activated   int64  `json:"activated"`   // Time the key was activated
+	Deactivated int64  `json:"deactivated"` // Time the key was deactivated, 0 if active
+}
+
+// UserIdentities requests the public key history of the logged in user.
+type UserIdentities struct{}
+
+// UserIdentitiesReply is the reply for the UserIdentities command.
+type UserIdentitiesReply struct {
+	Identities []UserIdentity `json:"identities"`
+}
+
 // ChangePassword is used to perform a password change while the user
 // is logged in.
 type ChangePassword struct {
@@ -331,6 +903,396 @@ type ChangePassword struct {
 // is logged in.
 type ChangePasswordReply struct{}
 
+// ChangeEmail is used to request a change of the email address of the logged
+// in user.  The current email address remains in use until the new one is
+// verified with VerifyChangeEmail.
+type ChangeEmail struct {
+	NewEmail string `json:"newemail"` // New email address
+	Password string `json:"password"` // Current password
+}
+
+// ChangeEmailReply replies to the ChangeEmail command.
+type ChangeEmailReply struct {
+	VerificationToken string `json:"verificationtoken"` // Server verification token
+}
+
+// VerifyChangeEmail is used to verify the new email address with the token
+// that was emailed to it.
+type VerifyChangeEmail struct {
+	VerificationToken string `json:"verificationtoken"` // Server provided verification token
+}
+
+// VerifyChangeEmailReply replies to the VerifyChangeEmail command.
+type VerifyChangeEmailReply struct{}
+
+// DeactivateUser deactivates the account of the logged in user.  Deactivated
+// users can't log in and are not emailed; their proposals and comments are
+// kept.
+type DeactivateUser struct {
+	Password string `json:"password"`
+}
+
+// DeactivateUserReply is the reply for the DeactivateUser command.
+type DeactivateUserReply struct{}
+
+// UserSession describes a login session of the logged in user.
+type UserSession struct {
+	ID        string `json:"id"`        // Session id
+	IP        string `json:"ip"`        // IP address of the login
+	UserAgent string `json:"useragent"` // User agent of the login
+	Timestamp int64  `json:"timestamp"` // Time of the login
+	Current   bool   `json:"current"`   // Set for the session of the request
+}
+
+// UserSessions requests the login sessions of the logged in user.
+type UserSessions struct{}
+
+// UserSessionsReply is the reply for the UserSessions command.
+type UserSessionsReply struct {
+	Sessions []UserSession `json:"sessions"`
+}
+
+// SecurityEvent is an event of the account of a user, such as a login or a
+// password change.
+type SecurityEvent struct {
+	Type      string `json:"type"`      // One of the SecurityEvent types
+	IP        string `json:"ip"`        // IP address of the client
+	UserAgent string `json:"useragent"` // User agent of the client
+	Context   string `json:"context"`   // Details, such as a public key
+	Timestamp int64  `json:"timestamp"` // Time of the event
+}
+
+// SecurityEvents requests the security events of the logged in user.
+type SecurityEvents struct{}
+
+// SecurityEventsReply is the reply for the SecurityEvents command.  The
+// events are sorted oldest first.
+type SecurityEventsReply struct {
+	Events []SecurityEvent `json:"events"`
+}
+
+// UserExport starts generating the archive of the data stored about the
+// logged in user.  The archive is downloaded with UserExportDownload once it
+// is ready.
+type UserExport struct{}
+
+// UserExportReply is the reply for the UserExport command.
+type UserExportReply struct{}
+
+// UserExportDownload downloads the archive of the data stored about the
+// logged in user.  The reply is the UserDataExport archive.
+type UserExportDownload struct{}
+
+// UserDataAccount contains the account settings of a user.
+type UserDataAccount struct {
+	ID                 string `json:"id"`                 // User id
+	Email              string `json:"email"`              // Email address
+	Admin              bool   `json:"admin"`              // Set if the user is an admin
+	PaywallAddress     string `json:"paywalladdress"`     // Registration paywall address
+	PaywallAmount      uint64 `json:"paywallamount"`      // Registration paywall amount in atoms
+	PaywallTxID        string `json:"paywalltxid"`        // Registration payment transaction
+	EmailNotifications uint64 `json:"emailnotifications"` // Notification emails the user opted into
+	EmailDigest        int    `json:"emaildigest"`        // Digest email frequency
+	TOTPEnabled        bool   `json:"totpenabled"`        // Set if the second factor is enabled
+	ProposalCredits    uint64 `json:"proposalcredits"`    // Proposal credit balance
+	Deactivated        int64  `json:"deactivated"`        // Time the account was deactivated, 0 if active
+}
+
+// UserDataExport is the archive of the data stored about a user.
+type UserDataExport struct {
+	Timestamp       int64                    `json:"timestamp"`       // Time the archive was generated
+	Account         UserDataAccount          `json:"account"`         // Account settings
+	Identities      []UserIdentity           `json:"identities"`      // Public key history
+	Sessions        []UserSession            `json:"sessions"`        // Login sessions
+	APITokens       []APIToken               `json:"apitokens"`       // Personal access tokens
+	CreditPurchases []ProposalCreditPurchase `json:"creditpurchases"` // Proposal credit history
+	Proposals       []ProposalRecord         `json:"proposals"`       // Proposals authored by the user
+	SecurityEvents  []SecurityEvent          `json:"securityevents"`  // Security log
+	Drafts          []Draft                  `json:"drafts"`          // Proposal drafts
+}
+
+// RefreshToken renews the tokens of an API client.  The provided refresh
+// token can only be used once.
+type RefreshToken struct {
+	RefreshToken string `json:"refreshtoken"` // Refresh token
+}
+
+// RefreshTokenReply returns a new access and refresh token.
+type RefreshTokenReply struct {
+	AccessToken  string `json:"accesstoken"`  // Bearer token
+	RefreshToken string `json:"refreshtoken"` // Renews the access token
+	TokenExpiry  int64  `json:"tokenexpiry"`  // Access token expiry
+}
+
+// RevokeToken revokes the provided access or refresh token.
+type RevokeToken struct {
+	Token string `json:"token"` // Access or refresh token
+}
+
+// RevokeTokenReply is the reply for the RevokeToken command.
+type RevokeTokenReply struct{}
+
+// OIDCAuth requests the URL of the OpenID Connect provider that starts a
+// federated login.  For RouteOIDCLink the provider account is linked to the
+// logged in user instead.
+type OIDCAuth struct{}
+
+// OIDCAuthReply returns the URL that the user must be redirected to.
+type OIDCAuthReply struct {
+	URL string `json:"url"` // Authorization URL of the provider
+}
+
+// OIDCCallback completes a federated login or link with the code and state
+// that the provider passed to the redirect URL.  The reply is a LoginReply.
+type OIDCCallback struct {
+	Code  string `json:"code"`  // Authorization code
+	State string `json:"state"` // State of the authorization request
+
+	// TOTPCode is required if the user enabled TOTP.
+	TOTPCode string `json:"totpcode,omitempty"`
+
+	// IssueTokens requests API tokens instead of a session cookie, see
+	// Login.
+	IssueTokens bool `json:"issuetokens,omitempty"`
+}
+
+// OIDCUnlink unlinks the provider account of the logged in user.
+type OIDCUnlink struct{}
+
+// OIDCUnlinkReply is the reply for the OIDCUnlink command.
+type OIDCUnlinkReply struct{}
+
+// RevokeSessions logs out the login session with the provided id, or all login
+// sessions of the logged in user, including the current one, if All is set.
+type RevokeSessions struct {
+	ID  string `json:"id"`  // Session id
+	All bool   `json:"all"` // Revoke all sessions
+}
+
+// RevokeSessionsReply is the reply for the RevokeSessions command.
+type RevokeSessionsReply struct{}
+
+// NewAPIToken creates a personal access token for the logged in user.  The
+// token is sent in an "Authorization: Bearer" header and is only allowed to
+// make the requests of its scope.
+type NewAPIToken struct {
+	Name  string `json:"name"`  // Name of the token
+	Scope string `json:"scope"` // One of the APITokenScope values
+}
+
+// NewAPITokenReply returns the new token.  The token can't be retrieved
+// again.
+type NewAPITokenReply struct {
+	ID    string `json:"id"`    // Token id
+	Token string `json:"token"` // Bearer token
+}
+
+// APIToken describes a personal access token without the token itself.
+type APIToken struct {
+	ID        string `json:"id"`        // Token id
+	Name      string `json:"name"`      // Name of the token
+	Scope     string `json:"scope"`     // Scope of the token
+	Timestamp int64  `json:"timestamp"` // Time the token was created
+}
+
+// APITokens requests the personal access tokens of the logged in user.
+type APITokens struct{}
+
+// APITokensReply is the reply for the APITokens command.
+type APITokensReply struct {
+	Tokens []APIToken `json:"tokens"`
+}
+
+// RevokeAPIToken revokes the personal access token with the provided id.
+type RevokeAPIToken struct {
+	ID string `json:"id"` // Token id
+}
+
+// RevokeAPITokenReply is the reply for the RevokeAPIToken command.
+type RevokeAPITokenReply struct{}
+
+// AdminDeactivateUser deactivates the account of the provided user on behalf
+// of an admin.
+type AdminDeactivateUser struct {
+	UserID    string `json:"userid"`    // User id
+	Reason    string `json:"reason"`    // Reason of the deactivation
+	PublicKey string `json:"publickey"` // Public key of the admin
+	Signature string `json:"signature"` // Signature of UserID+AdminActionDeactivateUser
+}
+
+// AdminDeactivateUserReply is the reply for the AdminDeactivateUser command.
+// If deactivations require approval, the user is only deactivated once
+// another admin approves the returned pending action.
+type AdminDeactivateUserReply struct {
+	PendingAction *PendingAction `json:"pendingaction,omitempty"`
+}
+
+// AdminVerifyUser marks the email address of the provided user as verified on
+// behalf of an admin, for users that can't receive the verification email.
+// The override is recorded in the admin audit log.
+type AdminVerifyUser struct {
+	UserID    string `json:"userid"`    // User id
+	Reason    string `json:"reason"`    // Reason of the override
+	PublicKey string `json:"publickey"` // Public key of the admin
+	Signature string `json:"signature"` // Signature of UserID+Action
+}
+
+// AdminVerifyUserReply is the reply for the AdminVerifyUser command.
+type AdminVerifyUserReply struct{}
+
+// GrantCredits adds proposal credits to the provided user on behalf of an
+// admin.
+type GrantCredits struct {
+	UserID  string `json:"userid"`  // User id
+	Credits uint64 `json:"credits"` // Number of credits
+	Reason  string `json:"reason"`  // Reason of the grant
+}
+
+// GrantCreditsReply is the reply for the GrantCredits command.
+type GrantCreditsReply struct {
+	Credits uint64 `json:"credits"` // New balance of the user
+}
+
+// SetUserAdmin grants or revokes the admin privileges of the provided user on
+// behalf of an admin.  The change is recorded in the admin audit log.
+type SetUserAdmin struct {
+	UserID    string `json:"userid"`    // User id
+	Admin     bool   `json:"admin"`     // Grant admin privileges if set, revoke them otherwise
+	Reason    string `json:"reason"`    // Reason of the change
+	PublicKey string `json:"publickey"` // Public key of the admin
+	Signature string `json:"signature"` // Signature of UserID+Action
+}
+
+// SetUserAdminReply is the reply for the SetUserAdmin command.
+type SetUserAdminReply struct{}
+
+// AdminAction is an entry of the admin audit log.
+type AdminAction struct {
+	AdminID   string `json:"adminid"`   // Admin that performed the action
+	UserID    string `json:"userid"`    // User the action applies to
+	Action    string `json:"action"`    // Type of the action
+	PublicKey string `json:"publickey"` // Public key of the admin
+	Signature string `json:"signature"` // Signature of UserID+Action
+	Reason    string `json:"reason"`    // Reason provided by the admin
+	Timestamp int64  `json:"timestamp"` // Time of the action
+}
+
+// Impersonate lets an admin impersonate the provided user in order to debug
+// an issue.  The session of the admin is logged in as the user until the
+// impersonation is stopped or expires, but it can't change anything.
+type Impersonate struct {
+	UserID    string `json:"userid"`    // User id
+	Reason    string `json:"reason"`    // Reason of the impersonation
+	PublicKey string `json:"publickey"` // Public key of the admin
+	Signature string `json:"signature"` // Signature of UserID+Action
+}
+
+// ImpersonateReply is the reply for the Impersonate command.
+type ImpersonateReply struct {
+	UserID string `json:"userid"` // Impersonated user id
+	Expiry int64  `json:"expiry"` // Time the impersonation expires
+}
+
+// StopImpersonation ends the impersonation of the session.
+type StopImpersonation struct{}
+
+// StopImpersonationReply is the reply for the StopImpersonation command.
+type StopImpersonationReply struct{}
+
+// PendingAction is a destructive admin action that awaits the approval of a
+// second admin before it takes effect.
+type PendingAction struct {
+	ID        string `json:"id"`               // Unique id
+	Action    string `json:"action"`           // Type of the action
+	AdminID   string `json:"adminid"`          // Admin that requested the action
+	Token     string `json:"token,omitempty"`  // Censorship token of the proposal, proposal actions only
+	UserID    string `json:"userid,omitempty"` // User id, user actions only
+	Reason    string `json:"reason"`           // Reason provided by the admin
+	Timestamp int64  `json:"timestamp"`        // Time of the request
+	Expiry    int64  `json:"expiry"`           // Time the request expires unless it is approved
+}
+
+// PendingActions retrieves the admin actions that await approval.
+type PendingActions struct{}
+
+// PendingActionsReply returns the admin actions that await approval, oldest
+// first.
+type PendingActionsReply struct {
+	Actions []PendingAction `json:"actions"`
+}
+
+// ApprovePendingAction approves an action that another admin requested.  The
+// action takes effect as requested.
+type ApprovePendingAction struct {
+	ID        string `json:"id"`        // Pending action id
+	PublicKey string `json:"publickey"` // Public key of the admin
+	Signature string `json:"signature"` // Signature of ID+"approve"
+}
+
+// ApprovePendingActionReply is the reply for the ApprovePendingAction
+// command.
+type ApprovePendingActionReply struct{}
+
+// RejectPendingAction rejects an action that awaits approval.  Admins may
+// reject their own actions to cancel them.
+type RejectPendingAction struct {
+	ID        string `json:"id"`        // Pending action id
+	Reason    string `json:"reason"`    // Reason of the rejection
+	PublicKey string `json:"publickey"` // Public key of the admin
+	Signature string `json:"signature"` // Signature of ID+"reject"
+}
+
+// RejectPendingActionReply is the reply for the RejectPendingAction command.
+type RejectPendingActionReply struct{}
+
+// HeldProposal is a new proposal that the spam filters held for admin
+// review.  It is only sent to politeiad once an admin releases it.
+type HeldProposal struct {
+	ID        string   `json:"id"`        // Unique id
+	UserID    string   `json:"userid"`    // User that submitted the proposal
+	Name      string   `json:"name"`      // Name of the proposal
+	Files     []File   `json:"files"`     // Files of the proposal
+	Score     int      `json:"score"`     // Spam score of the proposal
+	Reasons   []string `json:"reasons"`   // Filters that contributed to the score
+	Timestamp int64    `json:"timestamp"` // Time of the submission
+}
+
+// HeldProposals retrieves the proposals that await admin review.
+type HeldProposals struct{}
+
+// HeldProposalsReply returns the proposals that await admin review, oldest
+// first.
+type HeldProposalsReply struct {
+	Proposals []HeldProposal `json:"proposals"`
+}
+
+// ReleaseHeldProposal submits a held proposal as if it had not been held.
+type ReleaseHeldProposal struct {
+	ID string `json:"id"` // Held proposal id
+}
+
+// ReleaseHeldProposalReply returns the censorship record of the submitted
+// proposal.
+type ReleaseHeldProposalReply struct {
+	CensorshipRecord CensorshipRecord `json:"censorshiprecord"`
+}
+
+// RejectHeldProposal discards a held proposal.
+type RejectHeldProposal struct {
+	ID string `json:"id"` // Held proposal id
+}
+
+// RejectHeldProposalReply is the reply for the RejectHeldProposal command.
+type RejectHeldProposalReply struct{}
+
+// AdminActions retrieves the admin audit log.
+type AdminActions struct{}
+
+// AdminActionsReply returns the admin audit log, oldest first.
+type AdminActionsReply struct {
+	Actions []AdminAction `json:"actions"`
+}
+
 // ResetPassword is used to perform a password change when the
 // user is not logged in.
 type ResetPassword struct {
@@ -345,22 +1307,280 @@ type ResetPasswordReply struct {
 	VerificationToken string `json:"verificationtoken"`
 }
 
+// EditUser edits the settings of the logged in user.  Only the fields that
+// are set are updated.
+type EditUser struct {
+	EmailNotifications *uint64       `json:"emailnotifications"` // Notify the user via emails
+	EmailDigest        *EmailDigestT `json:"emaildigest"`        // Digest email frequency
+}
+
+// EditUserReply is the reply for the EditUser command.
+type EditUserReply struct{}
+
+// Unsubscribe unsubscribes a user from an email notification without
+// requiring the user to log in.  The parameters are provided by the signed
+// link that is included in notification emails.
+type Unsubscribe struct {
+	UserID       string `schema:"userid"`       // User id
+	Notification uint64 `schema:"notification"` // Email notification, 0 for the email digest
+	Signature    string `schema:"signature"`    // Server signature of the link
+}
+
+// UnsubscribeReply is the reply for the Unsubscribe command.
+type UnsubscribeReply struct{}
+
+// IPBan describes a banned IP address or network.
+type IPBan struct {
+	Network   string `json:"network"`   // IP address or network in CIDR notation
+	Reason    string `json:"reason"`    // Reason of the ban
+	AdminID   string `json:"adminid"`   // Admin that created the ban
+	Automatic bool   `json:"automatic"` // Set if the ban was created because of abuse
+	Timestamp int64  `json:"timestamp"` // Time the ban was created
+	Expiry    int64  `json:"expiry"`    // Time the ban expires, 0 if it doesn't expire
+}
+
+// IPBans requests the list of IP bans that are in effect.
+type IPBans struct{}
+
+// IPBansReply is the reply for the IPBans command.
+type IPBansReply struct {
+	Bans []IPBan `json:"bans"`
+}
+
+// BanIP bans an IP address or network.  A ban of the same network that is
+// already in effect is replaced.  Duration is the length of the ban in
+// seconds, 0 bans the network until it is unbanned.
+type BanIP struct {
+	Network  string `json:"network"`  // IP address or network in CIDR notation
+	Reason   string `json:"reason"`   // Reason of the ban
+	Duration int64  `json:"duration"` // Length of the ban in seconds
+}
+
+// BanIPReply is the reply for the BanIP command.
+type BanIPReply struct {
+	Ban IPBan `json:"ban"`
+}
+
+// UnbanIP lifts the ban of an IP address or network.
+type UnbanIP struct {
+	Network string `json:"network"` // Banned IP address or network
+}
+
+// UnbanIPReply is the reply for the UnbanIP command.
+type UnbanIPReply struct{}
+
+// AbridgedUser is a short summary of a user that is returned in lists of
+// users.
+type AbridgedUser struct {
+	ID          string `json:"id"`          // User id
+	UUID        string `json:"uuid"`        // Stable user id
+	Email       string `json:"email"`       // User email
+	Admin       bool   `json:"admin"`       // Set if user is an admin
+	Verified    bool   `json:"verified"`    // Set if the email address is verified
+	PublicKey   string `json:"publickey"`   // Active public key
+	Deactivated int64  `json:"deactivated"` // Time the account was deactivated, 0 if it is active
+}
+
+// Users requests a page of users sorted by the provided order, which
+// defaults to UserSortCreated.  The number of users returned is dictated by
+// UserListPageSize.  Start is the cursor that is returned with the previous
+// page; the first page is returned if it is empty.
+type Users struct {
+	Sort  string `schema:"sort"`
+	Start string `schema:"start"`
+}
+
+// UsersReply is the reply for the Users command.  Next is the cursor of the
+// next page and is empty if there are no more users.
+type UsersReply struct {
+	Users []AbridgedUser `json:"users"`
+	Next  string         `json:"next,omitempty"`
+}
+
+// UserSearch searches the users whose email contains Email, ignoring case,
+// and that have used PublicKey.  At least one of them must be provided.  At
+// most UserListPageSize users are returned, sorted by email.
+type UserSearch struct {
+	Email     string `schema:"email"`     // Part of an email address
+	PublicKey string `schema:"publickey"` // Public key that a user has used
+}
+
+// UserSearchReply is the reply for the UserSearch command.
+type UserSearchReply struct {
+	Users []AbridgedUser `json:"users"`
+}
+
+// Feature describes a feature flag.  Features that are not toggleable can
+// only be set in the configuration.
+type Feature struct {
+	Name       string `json:"name"`       // Name of the feature
+	Enabled    bool   `json:"enabled"`    // Set if the feature is enabled
+	Toggleable bool   `json:"toggleable"` // Set if admins can toggle the feature
+}
+
+// Features requests the feature flags.
+type Features struct{}
+
+// FeaturesReply is the reply for the Features command.
+type FeaturesReply struct {
+	Features []Feature `json:"features"`
+}
+
+// SetFeature enables or disables a toggleable feature.
+type SetFeature struct {
+	Name    string `json:"name"`    // Name of the feature
+	Enabled bool   `json:"enabled"` // Enable or disable the feature
+}
+
+// SetFeatureReply is the reply for the SetFeature command.
+type SetFeatureReply struct {
+	Feature Feature `json:"feature"`
+}
+
+// SetTOTP starts the enrollment of a TOTP second factor.  The secret replaces
+// any pending secret and is only used for logins once the enrollment is
+// confirmed with VerifyTOTP.
+type SetTOTP struct{}
+
+// SetTOTPReply is the reply for the SetTOTP command.
+type SetTOTPReply struct {
+	Secret string `json:"secret"` // Base32 secret for manual entry
+	URI    string `json:"uri"`    // otpauth provisioning URI, usually shown as a QR code
+}
+
+// VerifyTOTP confirms the enrollment of a TOTP second factor with a code
+// generated from the pending secret.
+type VerifyTOTP struct {
+	Code string `json:"code"` // TOTP code
+}
+
+// VerifyTOTPReply is the reply for the VerifyTOTP command.  Each recovery
+// code can be used once in place of a TOTP code.
+type VerifyTOTPReply struct {
+	RecoveryCodes []string `json:"recoverycodes"`
+}
+
+// DisableTOTP disables the TOTP second factor.
+type DisableTOTP struct {
+	Code string `json:"code"` // TOTP or recovery code
+}
+
+// DisableTOTPReply is the reply for the DisableTOTP command.
+type DisableTOTPReply struct{}
+
 // UserProposals is used to request a list of proposals that the
 // user has submitted. This command optionally takes either a Before
 // or After parameter, which specify a proposal's censorship token.
 // If After is specified, the "page" returned starts after the proposal
 // whose censorship token is provided. If Before is specified, the "page"
 // returned starts before the proposal whose censorship token is provided.
+// The listing can be narrowed down to some statuses, to the proposals
+// submitted within a time window and to the proposals with a tag.
 type UserProposals struct {
 	UserId string `schema:"userid"`
 	Before string `schema:"before"`
 	After  string `schema:"after"`
+
+	// Filters, all optional.
+	Status          []PropStatusT `schema:"status"`          // Only these statuses
+	SubmittedAfter  int64         `schema:"submittedafter"`  // Submitted at or after this Unix time
+	SubmittedBefore int64         `schema:"submittedbefore"` // Submitted before this Unix time
+	Tag             string        `schema:"tag"`             // Only proposals with this tag
 }
 
 // UserProposalsReply replies to the UserProposals command with
 // a list of proposals that the user has submitted.
 type UserProposalsReply struct {
 	Proposals []ProposalRecord `json:"proposals"`
+	Total     uint             `json:"total"` // Number of proposals of the user
+}
+
+// UserStats requests the statistics of a user.
+type UserStats struct {
+	UserID string `schema:"userid"` // User id
+}
+
+// UserStatsReply returns the statistics of a user.  Votes are cast with
+// tickets that are not linked to users, so the vote participation is that of
+// the proposals of the user.
+type UserStatsReply struct {
+	Proposals uint64 `json:"proposals"` // Proposals submitted
+	Published uint64 `json:"published"` // Proposals published
+	Censored  uint64 `json:"censored"`  // Proposals censored
+	Voted     uint64 `json:"voted"`     // Proposals put up for a vote
+	Comments  uint64 `json:"comments"`  // Comments written
+}
+
+// ProposalsStats requests the statistics of all proposals.
+type ProposalsStats struct{}
+
+// ProposalsStatsReply returns the statistics of all proposals.  Proposals
+// are counted by their current status; proposals with unreviewed changes
+// count as not reviewed.
+type ProposalsStatsReply struct {
+	Proposals   uint64               `json:"proposals"`   // Proposals submitted
+	NotReviewed uint64               `json:"notreviewed"` // Proposals not reviewed yet
+	Censored    uint64               `json:"censored"`    // Proposals censored
+	Public      uint64               `json:"public"`      // Proposals published
+	Locked      uint64               `json:"locked"`      // Proposals locked
+	Weeks       []ProposalsWeekStats `json:"weeks"`       // Weeks with activity, oldest first
+}
+
+// ProposalsWeekStats are the statistics of the proposals in a week.  Weeks
+// start on Monday at 00:00 UTC.
+type ProposalsWeekStats struct {
+	Week        int64  `json:"week"`        // Unix time of the start of the week
+	Submissions uint64 `json:"submissions"` // Proposals submitted in the week
+	Censorships uint64 `json:"censorships"` // Proposals censored in the week
+}
+
+// WebhookEvent is the payload that is POSTed to the webhooks when a proposal
+// lifecycle event occurs.  The ID is the same for every webhook and every
+// attempt so that duplicate deliveries can be recognized.
+type WebhookEvent struct {
+	ID        string      `json:"id"`             // Unique id of the event
+	Type      string      `json:"type"`           // Type of the event
+	Timestamp int64       `json:"timestamp"`      // Time the event occurred
+	Token     string      `json:"token"`          // Censorship token of the proposal
+	Status    PropStatusT `json:"status"`         // Status of the proposal
+	Name      string      `json:"name,omitempty"` // Name of public proposals
+}
+
+// WebhookDeliveries requests the log of the recent webhook deliveries.
+type WebhookDeliveries struct{}
+
+// WebhookDeliveriesReply returns the recent webhook delivery attempts, newest
+// first.
+type WebhookDeliveriesReply struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+// WebhookDelivery is an attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	EventID    string `json:"eventid"`         // Id of the event
+	Type       string `json:"type"`            // Type of the event
+	Token      string `json:"token"`           // Censorship token of the proposal
+	URL        string `json:"url"`             // URL of the webhook
+	Attempt    int    `json:"attempt"`         // Attempt number, starting at 1
+	StatusCode int    `json:"statuscode"`      // HTTP status code, 0 if no reply
+	Error      string `json:"error,omitempty"` // Reason the attempt failed
+	Timestamp  int64  `json:"timestamp"`       // Time of the attempt
+}
+
+// UserDetails requests the public details of the user with the provided
+// uuid.  The uuid is read from the route.
+type UserDetails struct {
+	UUID string `json:"uuid"` // Stable user id
+}
+
+// UserDetailsReply returns the public details of a user.  The email address
+// of the user is never returned.
+type UserDetailsReply struct {
+	UUID        string `json:"uuid"`        // Stable user id
+	UserID      string `json:"userid"`      // User id
+	PublicKey   string `json:"publickey"`   // Active public key
+	Admin       bool   `json:"admin"`       // Set if user is an admin
+	Deactivated bool   `json:"deactivated"` // Set if the account is deactivated
 }
 
 // VerifyUserPaymentTx is used to request the server to check for the
@@ -374,22 +1594,94 @@ type VerifyUserPaymentTxReply struct {
 	HasPaid bool `json:"haspaid"`
 }
 
+// ProposalCreditPurchase describes proposal credits that a user bought or
+// that an admin granted.
+type ProposalCreditPurchase struct {
+	Credits   uint64 `json:"credits"`   // Number of credits
+	Amount    uint64 `json:"amount"`    // Price in atoms, 0 if granted
+	TxID      string `json:"txid"`      // Payment transaction, empty if granted
+	Timestamp int64  `json:"timestamp"` // Time the credits were added
+}
+
+// ProposalCredits requests the proposal credits of the logged in user.
+type ProposalCredits struct{}
+
+// ProposalCreditsReply returns the proposal credit balance and purchase
+// history of the logged in user.  One credit is spent per submitted proposal
+// when the credits feature is enabled.
+type ProposalCreditsReply struct {
+	Credits   uint64                   `json:"credits"`   // Current balance
+	Price     uint64                   `json:"price"`     // Price of a credit in atoms
+	Purchases []ProposalCreditPurchase `json:"purchases"` // Purchase history, oldest first
+}
+
+// PurchaseCredits starts a purchase of proposal credits.  The credits are
+// added once the payment is verified with VerifyCreditsTx.  A new purchase
+// replaces the pending one.
+type PurchaseCredits struct {
+	Credits uint64 `json:"credits"` // Number of credits to buy
+}
+
+// PurchaseCreditsReply returns the payment that completes the purchase.
+type PurchaseCreditsReply struct {
+	PaywallAddress     string `json:"paywalladdress"`     // Address to pay to
+	PaywallAmount      uint64 `json:"paywallamount"`      // Amount in atoms
+	PaywallTxNotBefore int64  `json:"paywalltxnotbefore"` // Minimum timestamp for the payment tx
+}
+
+// VerifyCreditsTx checks the payment of the pending proposal credit purchase.
+type VerifyCreditsTx struct {
+	TxID string `json:"txid"` // Payment transaction id
+}
+
+// VerifyCreditsTxReply returns whether the payment was accepted and the new
+// proposal credit balance.
+type VerifyCreditsTxReply struct {
+	HasPaid bool   `json:"haspaid"` // Set if the credits were added
+	Credits uint64 `json:"credits"` // Current balance
+}
+
 // Login attempts to login the user.  Note that by necessity the password
 // travels in the clear.
 type Login struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Code     string `json:"code,omitempty"` // TOTP or recovery code
+
+	// IssueTokens requests an access and a refresh token for API clients
+	// instead of a session cookie.
+	IssueTokens bool `json:"issuetokens,omitempty"`
 }
 
 // LoginReply is used to reply to the Login command.
 type LoginReply struct {
 	IsAdmin            bool   `json:"isadmin"`            // Set if user is an admin
 	UserID             string `json:"userid"`             // User id
+	UUID               string `json:"uuid"`               // Stable user id
 	Email              string `json:"email"`              // User email
 	PublicKey          string `json:"publickey"`          // Active public key
 	PaywallAddress     string `json:"paywalladdress"`     // Registration paywall address
 	PaywallAmount      uint64 `json:"paywallamount"`      // Registration paywall amount in atoms
 	PaywallTxNotBefore int64  `json:"paywalltxnotbefore"` // Minimum timestamp for paywall tx
+	EmailNotifications uint64 `json:"emailnotifications"` // Notify the user via emails
+
+	EmailDigest EmailDigestT `json:"emaildigest"` // Digest email frequency
+	TOTPEnabled bool         `json:"totpenabled"` // Set if login requires a TOTP code
+
+	// Account security metadata.  On login, LastLogin and FailedLogins
+	// describe the logins that preceded this one.
+	LastLogin       int64  `json:"lastlogin"`       // Time of the last successful login
+	FailedLogins    uint64 `json:"failedlogins"`    // Failed logins since the last successful login
+	HasPaid         bool   `json:"haspaid"`         // Set if the registration fee was paid
+	ProposalCredits uint64 `json:"proposalcredits"` // Proposal credit balance
+
+	// Tokens that are only returned if Login.IssueTokens is set.
+	AccessToken  string `json:"accesstoken,omitempty"`  // Bearer token
+	RefreshToken string `json:"refreshtoken,omitempty"` // Renews the access token
+	TokenExpiry  int64  `json:"tokenexpiry,omitempty"`  // Access token expiry
+
+	// ImpersonatedBy is the id of the admin that impersonates the user.
+	ImpersonatedBy string `json:"impersonatedby,omitempty"`
 }
 
 //Logout attempts to log the user out.
@@ -406,9 +1698,30 @@ type Me struct{}
 
 // NewProposal attempts to submit a new proposal.
 type NewProposal struct {
-	Files     []File `json:"files"`     // Proposal files
-	PublicKey string `json:"publickey"` // Key used for signature.
-	Signature string `json:"signature"` // Signature of merkle root
+	Files     []File            `json:"files"`               // Proposal files
+	PublicKey string            `json:"publickey"`           // Key used for signature.
+	Signature string            `json:"signature"`           // Signature of merkle root
+	Tags      []string          `json:"tags,omitempty"`      // Optional proposal tags
+	Timeline  *ProposalTimeline `json:"timeline,omitempty"`  // Optional funding and schedule
+	Pages     []string          `json:"pages,omitempty"`     // Optional order of the markdown files
+	CoAuthors []CoAuthor        `json:"coauthors,omitempty"` // Optional co-authors
+}
+
+// CoAuthor is an author of a proposal besides the user that submits it.  The
+// co-author signs the merkle root of the files with its own key.
+type CoAuthor struct {
+	PublicKey string `json:"publickey"`        // Key of the co-author
+	Signature string `json:"signature"`        // Signature of merkle root
+	UserID    string `json:"userid,omitempty"` // User of the key, if known at submission
+}
+
+// ProposalTimeline is the funding that a proposal requests and the period
+// that it covers.  Each field is optional; the dates are either both
+// provided or both omitted.
+type ProposalTimeline struct {
+	Amount    uint64 `json:"amount,omitempty"`    // Requested funding in US cents
+	StartDate int64  `json:"startdate,omitempty"` // Unix timestamp of the start
+	EndDate   int64  `json:"enddate,omitempty"`   // Unix timestamp of the end
 }
 
 // NewProposalReply is used to reply to the NewProposal command.
@@ -416,12 +1729,93 @@ type NewProposalReply struct {
 	CensorshipRecord CensorshipRecord `json:"censorshiprecord"`
 }
 
+// EditProposal replaces the files of a proposal that has not been reviewed
+// yet.  Only the author of the proposal can edit it.
+type EditProposal struct {
+	Token     string     `json:"token"`               // Censorship token
+	Files     []File     `json:"files"`               // New proposal files
+	PublicKey string     `json:"publickey"`           // Key used for signature.
+	Signature string     `json:"signature"`           // Signature of merkle root
+	Pages     []string   `json:"pages,omitempty"`     // Optional order of the markdown files
+	CoAuthors []CoAuthor `json:"coauthors,omitempty"` // Optional co-authors, who sign again
+}
+
+// EditProposalReply returns the new version of the proposal.
+type EditProposalReply struct {
+	Proposal ProposalRecord `json:"proposal"`
+}
+
+// Draft is an unsigned proposal that the user saved in order to finish it
+// later.  Drafts are only stored by politeiawww; they are submitted with
+// NewProposal once they are complete.
+type Draft struct {
+	ID        string `json:"id"`        // Draft id
+	Name      string `json:"name"`      // Name derived from the index file, if any
+	Files     []File `json:"files"`     // Files of the draft
+	Timestamp int64  `json:"timestamp"` // Last update of the draft
+}
+
+// Drafts retrieves the proposal drafts of the user of the session.
+type Drafts struct{}
+
+// DraftsReply returns the proposal drafts of the user, most recently updated
+// first.
+type DraftsReply struct {
+	Drafts []Draft `json:"drafts"`
+}
+
+// NewDraft saves a new proposal draft.  The files follow the file policy of
+// proposals but the draft doesn't have to be complete.
+type NewDraft struct {
+	Files []File `json:"files"` // Files of the draft
+}
+
+// NewDraftReply returns the saved draft.
+type NewDraftReply struct {
+	Draft Draft `json:"draft"`
+}
+
+// EditDraft replaces the files of a proposal draft.
+type EditDraft struct {
+	ID    string `json:"id"`    // Draft id
+	Files []File `json:"files"` // New files of the draft
+}
+
+// EditDraftReply returns the saved draft.
+type EditDraftReply struct {
+	Draft Draft `json:"draft"`
+}
+
+// DeleteDraft deletes a proposal draft.
+type DeleteDraft struct {
+	ID string `json:"id"` // Draft id
+}
+
+// DeleteDraftReply is the reply to the DeleteDraft command.
+type DeleteDraftReply struct{}
+
 // ProposalsDetails is used to retrieve a proposal.
 // XXX clarify URL vs Direct
 type ProposalsDetails struct {
 	Token string `json:"token"`
 }
 
+// ProposalFile is used to download a file of a proposal.  The token and the
+// file name are part of the URL.  The reply is the decoded file rather than
+// JSON.
+type ProposalFile struct {
+	Token string `json:"token"` // Censorship token
+	Name  string `json:"name"`  // File name
+}
+
+// ProposalBundle is used to download an archive of a proposal that holds
+// everything needed to verify it offline.  The token is part of the URL.  The
+// reply is the archive rather than JSON.
+type ProposalBundle struct {
+	Token  string `schema:"-"`      // Censorship token
+	Format string `schema:"format"` // One of the ProposalBundleFormat* formats
+}
+
 // ProposalDetailsReply is used to reply to a proposal details command.
 type ProposalDetailsReply struct {
 	Proposal ProposalRecord `json:"proposal"`
@@ -431,12 +1825,81 @@ type ProposalDetailsReply struct {
 type SetProposalStatus struct {
 	Token          string      `json:"token"`
 	ProposalStatus PropStatusT `json:"proposalstatus"`
-	Signature      string      `json:"signature"` // Signature of Token+string(ProposalStatus)
+	Signature      string      `json:"signature"` // Signature of Token+string(ProposalStatus)+StatusChangeMessage
 	PublicKey      string      `json:"publickey"`
+
+	// Reason of the status change that is stored with the proposal and
+	// recorded in its status history.  It is required when censoring.
+	StatusChangeMessage string `json:"statuschangemessage,omitempty"`
+
+	// PublishAt is the unix time that the proposal is published at.  It
+	// is only valid with PropStatusPublic, and is appended to the signed
+	// message when it's provided.  Zero publishes the proposal right away.
+	PublishAt int64 `json:"publishat,omitempty"`
 }
 
 // SetProposalStatusReply is used to reply to a SetProposalStatus command.
+// If censorships require approval, the proposal is only censored once another
+// admin approves the returned pending action.
 type SetProposalStatusReply struct {
+	Proposal      ProposalRecord `json:"proposal"`
+	PendingAction *PendingAction `json:"pendingaction,omitempty"`
+	PublishAt     int64          `json:"publishat,omitempty"` // Time the proposal is scheduled to be published at
+}
+
+// StatusChange is a status transition of a proposal.
+type StatusChange struct {
+	AdminID   string      `json:"adminid"`   // Admin that changed the status
+	PublicKey string      `json:"publickey"` // Public key of the admin
+	Signature string      `json:"signature"` // Signature of Token+string(NewStatus)
+	OldStatus PropStatusT `json:"oldstatus"` // Status before the change
+	NewStatus PropStatusT `json:"newstatus"` // Status after the change
+	Reason    string      `json:"reason"`    // Reason provided by the admin
+	Timestamp int64       `json:"timestamp"` // Time of the change
+}
+
+// StatusHistory retrieves the status changes of a proposal.
+type StatusHistory struct {
+	Token string `json:"token"`
+}
+
+// StatusHistoryReply lists the status changes of a proposal, oldest first.
+type StatusHistoryReply struct {
+	Changes []StatusChange `json:"changes"`
+}
+
+// ImportProposal replays a proposal bundle that was downloaded from another
+// deployment into this one.  The bundle is base64 encoded.  politeiad assigns
+// the imported proposal a new censorship token; the other metadata, including
+// the timestamps and the status changes, is preserved.
+//
+// Note: This call requires admin privileges.
+type ImportProposal struct {
+	Bundle string `json:"bundle"` // Base64 encoded proposal bundle
+	Format string `json:"format"` // One of the ProposalBundleFormat* formats
+}
+
+// ImportProposalReply returns the imported proposal along with its token in
+// the deployment that it was exported from.
+type ImportProposalReply struct {
+	OriginalToken string         `json:"originaltoken"`
+	Proposal      ProposalRecord `json:"proposal"`
+}
+
+// SetProposalTags replaces the tags of a proposal.  Tags are lowercase and
+// consist of letters, digits and dashes.  The signature is of the token and
+// the tags joined by commas.
+//
+// Note: This call requires admin privileges.
+type SetProposalTags struct {
+	Token     string   `json:"token"`     // Censorship token
+	Tags      []string `json:"tags"`      // New tags of the proposal
+	Signature string   `json:"signature"` // Signature of Token+Tags
+	PublicKey string   `json:"publickey"` // Public key of the admin
+}
+
+// SetProposalTagsReply returns the proposal with its new tags.
+type SetProposalTagsReply struct {
 	Proposal ProposalRecord `json:"proposal"`
 }
 
@@ -445,17 +1908,26 @@ type SetProposalStatusReply struct {
 // a Before or After parameter, which specify a proposal's censorship token.
 // If After is specified, the "page" returned starts after the proposal whose
 // censorship token is provided. If Before is specified, the "page" returned
-// starts before the proposal whose censorship token is provided.
+// starts before the proposal whose censorship token is provided. The listing
+// can be narrowed down to some statuses, to the proposals submitted within a
+// time window and to the proposals with a tag.
 //
 // Note: This call requires admin privileges.
 type GetAllUnvetted struct {
 	Before string `schema:"before"`
 	After  string `schema:"after"`
+
+	// Filters, all optional.
+	Status          []PropStatusT `schema:"status"`          // Only these statuses
+	SubmittedAfter  int64         `schema:"submittedafter"`  // Submitted at or after this Unix time
+	SubmittedBefore int64         `schema:"submittedbefore"` // Submitted before this Unix time
+	Tag             string        `schema:"tag"`             // Only proposals with this tag
 }
 
 // GetAllUnvettedReply is used to reply with a list of all unvetted proposals.
 type GetAllUnvettedReply struct {
 	Proposals []ProposalRecord `json:"proposals"`
+	Total     uint             `json:"total"` // Number of unvetted proposals
 }
 
 // GetAllVetted retrieves vetted proposals; the maximum number returned is dictated
@@ -463,15 +1935,25 @@ type GetAllUnvettedReply struct {
 // parameter, which specify a proposal's censorship token. If After is specified,
 // the "page" returned starts after the proposal whose censorship token is provided.
 // If Before is specified, the "page" returned starts before the proposal whose
-// censorship token is provided.
+// censorship token is provided. The listing can be narrowed down to the
+// proposals submitted within a time window and to the proposals with a tag.
 type GetAllVetted struct {
 	Before string `schema:"before"`
 	After  string `schema:"after"`
+
+	// Filters, all optional.
+	Status          []PropStatusT `schema:"status"`          // Only these statuses
+	SubmittedAfter  int64         `schema:"submittedafter"`  // Submitted at or after this Unix time
+	SubmittedBefore int64         `schema:"submittedbefore"` // Submitted before this Unix time
+	Tag             string        `schema:"tag"`             // Only proposals with this tag
+
+	Sort string `schema:"sort"` // One of the ProposalSort* orders, newest by default
 }
 
 // GetAllVettedReply is used to reply with a list of vetted proposals.
 type GetAllVettedReply struct {
 	Proposals []ProposalRecord `json:"proposals"`
+	Total     uint             `json:"total"` // Number of vetted proposals
 }
 
 // Policy returns a struct with various maxima.  The client shall observe the
@@ -482,6 +1964,7 @@ type Policy struct{}
 // the file upload restrictions set for Politeia.
 type PolicyReply struct {
 	PasswordMinChars     uint     `json:"passwordminchars"`
+	PasswordMinEntropy   uint     `json:"passwordminentropy"`
 	ProposalListPageSize uint     `json:"proposallistpagesize"`
 	MaxImages            uint     `json:"maximages"`
 	MaxImageSize         uint     `json:"maximagesize"`
@@ -492,7 +1975,18 @@ type PolicyReply struct {
 	MinNameLength        uint     `json:"minnamelength"`
 	SupportedCharacters  []string `json:"supportedcharacters"`
 	MaxCommentLength     uint     `json:"maxcommentlength"`
+	MaxDrafts            uint     `json:"maxdrafts"`
+	MaxProposalTags      uint     `json:"maxproposaltags"`
+	MaxProposalTagLength uint     `json:"maxproposaltaglength"`
+	MaxProposalCoAuthors uint     `json:"maxproposalcoauthors"`
 	BackendPublicKey     string   `json:"backendpublickey"`
+	SignupPoWDifficulty  uint     `json:"signuppowdifficulty"` // 0 when no proof of work is required
+	MaxImageWidth        uint     `json:"maximagewidth"`
+	MaxImageHeight       uint     `json:"maximageheight"`
+	MaxImagePixels       uint     `json:"maximagepixels"`
+	RawHTMLAllowed       bool     `json:"rawhtmlallowed"`      // Markdown may contain raw HTML
+	MaxProposalAmount    uint64   `json:"maxproposalamount"`   // US cents
+	MaxProposalDuration  uint64   `json:"maxproposalduration"` // Seconds
 }
 
 // NewComment sends a comment from a user to a specific proposal.  Note that
@@ -527,8 +2021,52 @@ type Comment struct {
 	ParentID  string `json:"parentid"`  // Parent comment ID
 	Comment   string `json:"comment"`   // Comment
 	Signature string `json:"signature"` // Signature of Token+ParentID+Comment
+	Censored  bool   `json:"censored"`  // Comment was censored by an admin
+
+	// Score
+	TotalVotes  uint64 `json:"totalvotes"`  // Number of up and down votes
+	ResultVotes int64  `json:"resultvotes"` // Up votes minus down votes
+}
+
+// Actions of LikeComment.
+const (
+	LikeCommentUp   = "1"  // Up vote
+	LikeCommentDown = "-1" // Down vote
+)
+
+// LikeComment up or down votes a comment on behalf of the user of the session.
+// Each user has one vote per comment; a vote replaces the previous vote of the
+// user and repeating it withdraws it.
+type LikeComment struct {
+	Token     string `json:"token"`     // Censorship token
+	CommentID string `json:"commentid"` // Comment ID
+	Action    string `json:"action"`    // LikeCommentUp or LikeCommentDown
+	Signature string `json:"signature"` // Signature of Token+CommentID+Action
+	PublicKey string `json:"publickey"` // Public key used for Signature
+}
+
+// LikeCommentReply returns the new score of the comment.
+type LikeCommentReply struct {
+	Total  uint64 `json:"total"`  // Number of up and down votes
+	Result int64  `json:"result"` // Up votes minus down votes
+}
+
+// CensoredCommentPlaceholder replaces the text of censored comments.
+const CensoredCommentPlaceholder = "This comment was censored by an admin."
+
+// CensorComment censors a comment on behalf of the admin of the session.  The
+// text of the comment is replaced by CensoredCommentPlaceholder.
+type CensorComment struct {
+	Token     string `json:"token"`     // Censorship token
+	CommentID string `json:"commentid"` // Comment ID
+	Reason    string `json:"reason"`    // Reason for the censorship
+	Signature string `json:"signature"` // Signature of Token+CommentID+Reason
+	PublicKey string `json:"publickey"` // Public key used for Signature
 }
 
+// CensorCommentReply is the reply to the CensorComment command.
+type CensorCommentReply struct{}
+
 // GetCommentsReply returns the provided number of comments.
 type GetCommentsReply struct {
 	Comments []Comment `json:"comments"` // Comments
@@ -549,6 +2087,27 @@ type ActiveVoteReply struct {
 	Votes []ProposalVoteTuple `json:"votes"` // Active votes
 }
 
+// ActiveVoteSummary obtains the summaries of the active votes.
+type ActiveVoteSummary struct{}
+
+// VoteSummary is the vote of a proposal without its snapshot of eligible
+// tickets.
+type VoteSummary struct {
+	Token            string            `json:"token"`            // Censorship token
+	Name             string            `json:"name"`             // Proposal name
+	Vote             decredplugin.Vote `json:"vote"`             // Vote bits and mask
+	StartBlockHeight uint64            `json:"startblockheight"` // Height of the vote start
+	EndHeight        uint64            `json:"endheight"`        // Height of the vote end
+	EligibleTickets  uint64            `json:"eligibletickets"`  // Size of the ticket snapshot
+}
+
+// ActiveVoteSummaryReply returns the summaries of the active votes ordered by
+// end height.
+type ActiveVoteSummaryReply struct {
+	BestBlock uint64        `json:"bestblock"` // Height of the best block
+	Votes     []VoteSummary `json:"votes"`     // Active votes
+}
+
 // plugin commands
 // StartVote starts the voting process for a proposal.
 type StartVote struct {
@@ -582,3 +2141,61 @@ type ProposalVotesReply struct {
 	Vote      decredplugin.Vote       `json:"vote"`      // Original vote
 	CastVotes []decredplugin.CastVote `json:"castvotes"` // Vote results
 }
+
+// VoteStreamSubscribe is sent over the vote stream to replace the proposals
+// whose votes are pushed to the client.
+type VoteStreamSubscribe struct {
+	Tokens []string `json:"tokens"` // Censorship tokens
+}
+
+// VoteStreamEvent is pushed over the vote stream with the current vote status
+// of a subscribed proposal when it is subscribed to and whenever votes on it
+// are accepted.
+type VoteStreamEvent struct {
+	Token     string                  `json:"token"`               // Censorship token
+	CastVotes []decredplugin.CastVote `json:"castvotes,omitempty"` // Accepted votes
+	Status    VoteStatusReply         `json:"status"`              // Updated vote status
+}
+
+// VoteReceipt retrieves the vote that a ticket cast on a proposal.
+type VoteReceipt struct {
+	Token  string `json:"token"`  // Censorship token
+	Ticket string `json:"ticket"` // Ticket ID
+}
+
+// VoteReceiptReply proves that politeiad accepted a vote.  The signature is
+// the one of the CastVoteReply that accepted the vote.
+type VoteReceiptReply struct {
+	CastVote  decredplugin.CastVote `json:"castvote"`  // Stored vote
+	Signature string                `json:"signature"` // Signature of the ClientSignature by politeiad
+}
+
+// VoteStatus retrieves the summary of the vote on a proposal.
+type VoteStatus struct {
+	Token string `json:"token"`
+}
+
+// VoteOptionResult is the number of votes that a vote option received.
+type VoteOptionResult struct {
+	Option        decredplugin.VoteOption `json:"option"`        // Vote option
+	VotesReceived uint64                  `json:"votesreceived"` // Number of votes for the option
+}
+
+// VoteStatusReply summarizes the vote on a proposal without its cast votes.
+// Passing projects the outcome of the vote as if it ended with the current
+// votes.
+type VoteStatusReply struct {
+	Token            string             `json:"token"`            // Censorship token
+	Status           PropVoteStatusT    `json:"status"`           // Vote status
+	Type             decredplugin.VoteT `json:"type"`             // Vote type
+	StartBlockHeight uint64             `json:"startblockheight"` // Height of the vote start
+	EndHeight        uint64             `json:"endheight"`        // Height of the vote end
+	BestBlock        uint64             `json:"bestblock"`        // Height of the best block
+	EligibleTickets  uint64             `json:"eligibletickets"`  // Number of tickets that can vote
+	TotalVotes       uint64             `json:"totalvotes"`       // Number of cast votes
+	QuorumVotes      uint64             `json:"quorumvotes"`      // Number of votes required for the vote to count
+	PassPercentage   uint32             `json:"passpercentage"`   // Percentage of votes an option requires to win
+	OptionsResult    []VoteOptionResult `json:"optionsresult"`    // Votes of each option
+	Passing          bool               `json:"passing"`          // Whether an option wins with the current votes
+	Winner           string             `json:"winner,omitempty"` // Id of the option that wins with the current votes
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// apiTokenPrefix distinguishes personal access tokens from the access
+	// tokens that are issued on login.  Personal access tokens have the
+	// form pat_<user id>_<secret>.
+	apiTokenPrefix = "pat_"
+
+	// apiTokenIDSize is the size of personal access token ids in bytes.
+	apiTokenIDSize = 8
+
+	// apiTokenSecretSize is the size of the secret of personal access
+	// tokens in bytes.
+	apiTokenSecretSize = 32
+)
+
+// hashAPIToken returns the digest of the provided personal access token that
+// is stored in the user database.  Tokens are random so a plain digest is
+// sufficient.
+func hashAPIToken(token string) []byte {
+	digest := sha256.Sum256([]byte(token))
+	return digest[:]
+}
+
+// isAPIToken returns true if the provided bearer token is a personal access
+// token.
+func isAPIToken(token string) bool {
+	return strings.HasPrefix(token, apiTokenPrefix)
+}
+
+// validAPITokenScope returns true if the provided scope exists.
+func validAPITokenScope(scope string) bool {
+	switch scope {
+	case www.APITokenScopeRead, www.APITokenScopeProposal,
+		www.APITokenScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// apiTokenAllows returns true if a personal access token with the provided
+// scope may make the provided request.  The admin routes additionally require
+// the admin scope, see isAdmin.
+func apiTokenAllows(scope string, r *http.Request) bool {
+	switch scope {
+	case www.APITokenScopeAdmin:
+		return true
+	case www.APITokenScopeProposal:
+		if r.Method == http.MethodPost && r.URL.Path ==
+			www.PoliteiaWWWAPIRoute+www.RouteNewProposal {
+			return true
+		}
+	}
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+// verifyAPIToken returns the user and the record of the provided personal
+// access token.  A nil user is returned if the token is invalid or revoked,
+// or if its user is deactivated or no longer exists.
+func (b *backend) verifyAPIToken(token string) (*database.User, *database.APIToken, error) {
+	parts := strings.SplitN(strings.TrimPrefix(token, apiTokenPrefix), "_", 2)
+	if len(parts) != 2 {
+		return nil, nil, nil
+	}
+	userID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, nil, nil
+	}
+
+	user, err := b.db.UserGetById(userID)
+	if err == database.ErrUserNotFound {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if user.Deactivated != 0 {
+		return nil, nil, nil
+	}
+
+	digest := hashAPIToken(token)
+	for k, v := range user.APITokens {
+		if subtle.ConstantTimeCompare(v.Digest, digest) == 1 {
+			return user, &user.APITokens[k], nil
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// ProcessNewAPIToken creates a personal access token for the provided user.
+// Only admins may create tokens with the admin scope.
+func (b *backend) ProcessNewAPIToken(user *database.User, nt www.NewAPIToken) (*www.NewAPITokenReply, error) {
+	name := strings.TrimSpace(nt.Name)
+	if name == "" || len(name) > www.APITokenMaxNameLength {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+	if !validAPITokenScope(nt.Scope) ||
+		(nt.Scope == www.APITokenScopeAdmin && !user.Admin) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidAPITokenScope,
+		}
+	}
+	if len(user.APITokens) >= www.APITokenMaxPerUser {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusTooManyAPITokens,
+		}
+	}
+
+	id, err := util.Random(apiTokenIDSize)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := util.Random(apiTokenSecretSize)
+	if err != nil {
+		return nil, err
+	}
+	token := apiTokenPrefix + strconv.FormatUint(user.ID, 10) + "_" +
+		hex.EncodeToString(secret)
+
+	t := database.APIToken{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Scope:     nt.Scope,
+		Digest:    hashAPIToken(token),
+		Timestamp: time.Now().Unix(),
+	}
+	user.APITokens = append(user.APITokens, t)
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.NewAPITokenReply{
+		ID:    t.ID,
+		Token: token,
+	}, nil
+}
+
+// ProcessAPITokens returns the personal access tokens of the provided user.
+func (b *backend) ProcessAPITokens(user *database.User) *www.APITokensReply {
+	reply := www.APITokensReply{
+		Tokens: make([]www.APIToken, 0, len(user.APITokens)),
+	}
+	for _, v := range user.APITokens {
+		reply.Tokens = append(reply.Tokens, www.APIToken{
+			ID:        v.ID,
+			Name:      v.Name,
+			Scope:     v.Scope,
+			Timestamp: v.Timestamp,
+		})
+	}
+	return &reply
+}
+
+// ProcessRevokeAPIToken removes the provided personal access token of the
+// provided user.
+func (b *backend) ProcessRevokeAPIToken(user *database.User, rt www.RevokeAPIToken) (*www.RevokeAPITokenReply, error) {
+	for k, v := range user.APITokens {
+		if v.ID != rt.ID {
+			continue
+		}
+		user.APITokens = append(user.APITokens[:k], user.APITokens[k+1:]...)
+		err := b.db.UserUpdate(*user)
+		if err != nil {
+			return nil, err
+		}
+		return &www.RevokeAPITokenReply{}, nil
+	}
+
+	return nil, www.UserError{
+		ErrorCode: www.ErrorStatusAPITokenNotFound,
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestAPITokens(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, _ := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+
+	// Only admins can create admin tokens.
+	_, err = b.ProcessNewAPIToken(user, www.NewAPIToken{
+		Name:  "monitor",
+		Scope: www.APITokenScopeAdmin,
+	})
+	assertError(t, err, www.ErrorStatusInvalidAPITokenScope)
+	_, err = b.ProcessNewAPIToken(user, www.NewAPIToken{
+		Name:  "monitor",
+		Scope: "invalid",
+	})
+	assertError(t, err, www.ErrorStatusInvalidAPITokenScope)
+	_, err = b.ProcessNewAPIToken(user, www.NewAPIToken{
+		Scope: www.APITokenScopeRead,
+	})
+	assertError(t, err, www.ErrorStatusInvalidInput)
+
+	reply, err := b.ProcessNewAPIToken(user, www.NewAPIToken{
+		Name:  "monitor",
+		Scope: www.APITokenScopeRead,
+	})
+	assertSuccess(t, err)
+	if !isAPIToken(reply.Token) {
+		t.Fatalf("unexpected token %v", reply.Token)
+	}
+
+	u, token, err := b.verifyAPIToken(reply.Token)
+	assertSuccess(t, err)
+	if u == nil || u.ID != user.ID || token.ID != reply.ID {
+		t.Fatalf("unexpected token user %v token %v", u, token)
+	}
+	u, _, err = b.verifyAPIToken(reply.Token + "0")
+	assertSuccess(t, err)
+	if u != nil {
+		t.Fatalf("invalid token accepted")
+	}
+
+	tokens := b.ProcessAPITokens(user)
+	if len(tokens.Tokens) != 1 || tokens.Tokens[0].Name != "monitor" {
+		t.Fatalf("unexpected tokens %v", tokens.Tokens)
+	}
+
+	// Scopes limit the requests.
+	newProposal := www.PoliteiaWWWAPIRoute + www.RouteNewProposal
+	tests := []struct {
+		scope  string
+		method string
+		path   string
+		want   bool
+	}{
+		{www.APITokenScopeRead, http.MethodGet, newProposal, true},
+		{www.APITokenScopeRead, http.MethodPost, newProposal, false},
+		{www.APITokenScopeProposal, http.MethodPost, newProposal, true},
+		{www.APITokenScopeProposal, http.MethodPost, "/v1/user/edit", false},
+		{www.APITokenScopeAdmin, http.MethodPost, "/v1/user/edit", true},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if apiTokenAllows(test.scope, r) != test.want {
+			t.Errorf("%v %v %v: got %v, want %v", test.scope,
+				test.method, test.path, !test.want, test.want)
+		}
+	}
+
+	// Revoked tokens are rejected.
+	_, err = b.ProcessRevokeAPIToken(user, www.RevokeAPIToken{ID: "invalid"})
+	assertError(t, err, www.ErrorStatusAPITokenNotFound)
+	_, err = b.ProcessRevokeAPIToken(user, www.RevokeAPIToken{ID: reply.ID})
+	assertSuccess(t, err)
+	u, _, err = b.verifyAPIToken(reply.Token)
+	assertSuccess(t, err)
+	if u != nil {
+		t.Fatalf("revoked token accepted")
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+// pendingActionIDSize is the size of the ids of the pending admin actions in
+// bytes.
+const pendingActionIDSize = 16
+
+// approvalRequired returns true if censorships and user deactivations await
+// the approval of a second admin.
+func (b *backend) approvalRequired() bool {
+	return b.cfg.AdminApprovalWindow != 0
+}
+
+// convertWWWPendingActionFromDatabase converts a pending admin action from
+// the database to www.  The details of the action are read from the command
+// that the admin requested.
+func convertWWWPendingActionFromDatabase(pa database.PendingAction) (*www.PendingAction, error) {
+	action := www.PendingAction{
+		ID:        pa.ID,
+		Action:    pa.Action,
+		AdminID:   strconv.FormatUint(pa.AdminID, 10),
+		Timestamp: pa.Timestamp,
+		Expiry:    pa.Expiry,
+	}
+
+	switch pa.Action {
+	case www.PendingActionCensorProposal:
+		var sps www.SetProposalStatus
+		err := json.Unmarshal(pa.Payload, &sps)
+		if err != nil {
+			return nil, err
+		}
+		action.Token = sps.Token
+		action.Reason = sps.StatusChangeMessage
+	case www.PendingActionDeactivateUser:
+		var adu www.AdminDeactivateUser
+		err := json.Unmarshal(pa.Payload, &adu)
+		if err != nil {
+			return nil, err
+		}
+		action.UserID = adu.UserID
+		action.Reason = adu.Reason
+	default:
+		return nil, fmt.Errorf("invalid pending action type: %v", pa.Action)
+	}
+
+	return &action, nil
+}
+
+// pendingActions returns the admin actions that await approval.  Expired
+// actions are removed.
+func (b *backend) pendingActions() ([]www.PendingAction, error) {
+	actions, err := b.db.AllPendingActions()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	pending := make([]www.PendingAction, 0, len(actions))
+	for _, v := range actions {
+		if now >= v.Expiry {
+			err := b.db.PendingActionDelete(v.ID)
+			if err != nil && err != database.ErrPendingActionNotFound {
+				return nil, err
+			}
+			continue
+		}
+		pa, err := convertWWWPendingActionFromDatabase(v)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, *pa)
+	}
+
+	return pending, nil
+}
+
+// newPendingAction queues the provided command of the provided admin until a
+// second admin approves it.  Only one action of each proposal or user may
+// await approval at a time.
+func (b *backend) newPendingAction(action string, cmd interface{}, admin *database.User) (*www.PendingAction, error) {
+	payload, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, err
+	}
+	id, err := util.Random(pendingActionIDSize)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	pa := database.PendingAction{
+		ID:        hex.EncodeToString(id),
+		Action:    action,
+		AdminID:   admin.ID,
+		Payload:   payload,
+		Timestamp: now.Unix(),
+		Expiry:    now.Add(b.cfg.AdminApprovalWindow).Unix(),
+	}
+	reply, err := convertWWWPendingActionFromDatabase(pa)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := b.pendingActions()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range pending {
+		if v.Action == reply.Action && v.Token == reply.Token &&
+			v.UserID == reply.UserID {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusPendingActionExists,
+			}
+		}
+	}
+
+	err = b.db.PendingActionNew(pa)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Admin %v requested %v %v%v: %v", admin.ID, reply.Action,
+		reply.Token, reply.UserID, reply.Reason)
+
+	return reply, nil
+}
+
+// claimPendingAction removes the unexpired pending admin action with the
+// provided id from the queue and returns it.  Only one caller can claim an
+// action.
+func (b *backend) claimPendingAction(id string) (*database.PendingAction, error) {
+	actions, err := b.db.AllPendingActions()
+	if err != nil {
+		return nil, err
+	}
+
+	var pa *database.PendingAction
+	for k, v := range actions {
+		if v.ID == id {
+			pa = &actions[k]
+			break
+		}
+	}
+	if pa == nil || time.Now().Unix() >= pa.Expiry {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusPendingActionNotFound,
+		}
+	}
+
+	err = b.db.PendingActionDelete(id)
+	if err != nil {
+		if err == database.ErrPendingActionNotFound {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusPendingActionNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	return pa, nil
+}
+
+// releasePendingAction returns a claimed pending admin action to the queue so
+// that it can be approved or rejected again.
+func (b *backend) releasePendingAction(pa *database.PendingAction) {
+	err := b.db.PendingActionNew(*pa)
+	if err != nil {
+		log.Errorf("releasePendingAction %v: %v", pa.ID, err)
+	}
+}
+
+// ProcessPendingActions returns the admin actions that await approval.
+func (b *backend) ProcessPendingActions() (*www.PendingActionsReply, error) {
+	pending, err := b.pendingActions()
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.PendingActionsReply{
+		Actions: pending,
+	}, nil
+}
+
+// ProcessApprovePendingAction carries out an action that another admin
+// requested on behalf of the provided admin.  The action is carried out as
+// the admin that requested it, provided that admin is still an admin.  An
+// action that can't be carried out is returned to the queue.
+func (b *backend) ProcessApprovePendingAction(ctx context.Context, apa www.ApprovePendingAction, admin *database.User) (*www.ApprovePendingActionReply, error) {
+	err := checkPublicKeyAndSignature(admin, apa.PublicKey, apa.Signature,
+		apa.ID, www.PendingActionApprove)
+	if err != nil {
+		return nil, err
+	}
+
+	// Admins can't approve their own actions.  Check it before claiming
+	// the action so that it remains queued.
+	actions, err := b.db.AllPendingActions()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range actions {
+		if v.ID == apa.ID && v.AdminID == admin.ID {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusApprovalBySameAdmin,
+			}
+		}
+	}
+
+	pa, err := b.claimPendingAction(apa.ID)
+	if err != nil {
+		return nil, err
+	}
+	requester, err := b.db.UserGetById(pa.AdminID)
+	if err != nil {
+		b.releasePendingAction(pa)
+		return nil, err
+	}
+	if !requester.Admin || requester.Deactivated != 0 {
+		log.Infof("Pending action %v dropped: admin %v lost its "+
+			"privileges", pa.ID, requester.ID)
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusPendingActionNotFound,
+		}
+	}
+
+	switch pa.Action {
+	case www.PendingActionCensorProposal:
+		var sps www.SetProposalStatus
+		err = json.Unmarshal(pa.Payload, &sps)
+		if err == nil {
+			_, err = b.setProposalStatus(ctx, sps, requester)
+		}
+	case www.PendingActionDeactivateUser:
+		var adu www.AdminDeactivateUser
+		err = json.Unmarshal(pa.Payload, &adu)
+		if err == nil {
+			err = b.adminDeactivateUser(adu, requester)
+		}
+	default:
+		err = fmt.Errorf("invalid pending action type: %v", pa.Action)
+	}
+	if err != nil {
+		b.releasePendingAction(pa)
+		return nil, err
+	}
+
+	log.Infof("Admin %v approved %v %v of admin %v", admin.ID, pa.Action,
+		pa.ID, pa.AdminID)
+
+	return &www.ApprovePendingActionReply{}, nil
+}
+
+// ProcessRejectPendingAction removes an action that awaits approval on
+// behalf of the provided admin.
+func (b *backend) ProcessRejectPendingAction(rpa www.RejectPendingAction, admin *database.User) (*www.RejectPendingActionReply, error) {
+	err := checkPublicKeyAndSignature(admin, rpa.PublicKey, rpa.Signature,
+		rpa.ID, www.PendingActionReject)
+	if err != nil {
+		return nil, err
+	}
+
+	pa, err := b.claimPendingAction(rpa.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Admin %v rejected %v %v of admin %v: %v", admin.ID,
+		pa.Action, pa.ID, pa.AdminID, rpa.Reason)
+
+	return &www.RejectPendingActionReply{}, nil
+}
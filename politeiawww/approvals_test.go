@@ -0,0 +1,162 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+func TestPendingActions(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+	b.cfg.AdminApprovalWindow = time.Hour
+
+	createAdmin := func() (*database.User, *identity.FullIdentity) {
+		nu, id := createAndVerifyUser(t, b)
+		admin, err := b.db.UserGet(nu.Email)
+		assertSuccess(t, err)
+		admin.Admin = true
+		assertSuccess(t, b.db.UserUpdate(*admin))
+		return admin, id
+	}
+	admin1, id1 := createAdmin()
+	admin2, id2 := createAdmin()
+	sign := func(id *identity.FullIdentity, elements ...string) string {
+		var msg string
+		for _, v := range elements {
+			msg += v
+		}
+		sig := id.SignMessage([]byte(msg))
+		return hex.EncodeToString(sig[:])
+	}
+	approve := func(admin *database.User, id *identity.FullIdentity, paID string) error {
+		_, err := b.ProcessApprovePendingAction(context.Background(),
+			www.ApprovePendingAction{
+				ID:        paID,
+				PublicKey: id.Public.String(),
+				Signature: sign(id, paID, www.PendingActionApprove),
+			}, admin)
+		return err
+	}
+
+	deactivate := func(user *database.User) www.AdminDeactivateUser {
+		userID := strconv.FormatUint(user.ID, 10)
+		return www.AdminDeactivateUser{
+			UserID:    userID,
+			Reason:    "spam",
+			PublicKey: id1.Public.String(),
+			Signature: sign(id1, userID, www.AdminActionDeactivateUser),
+		}
+	}
+
+	// Censorships are only forwarded once another admin approves them.
+	_, npr, err := createNewProposal(b, t, admin1, id1)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+	sps := www.SetProposalStatus{
+		Token:               token,
+		ProposalStatus:      www.PropStatusCensored,
+		StatusChangeMessage: "spam",
+		PublicKey:           id1.Public.String(),
+		Signature: sign(id1, token,
+			strconv.FormatUint(uint64(www.PropStatusCensored), 10),
+			"spam"),
+	}
+	spsr, err := b.ProcessSetProposalStatus(context.Background(), sps,
+		admin1)
+	assertSuccess(t, err)
+	if spsr.PendingAction == nil || spsr.PendingAction.Token != token {
+		t.Fatalf("censorship was not queued")
+	}
+	_, err = b.ProcessSetProposalStatus(context.Background(), sps, admin1)
+	assertError(t, err, www.ErrorStatusPendingActionExists)
+	shr, err := b.ProcessStatusHistory(www.StatusHistory{Token: token})
+	assertSuccess(t, err)
+	if len(shr.Changes) != 0 {
+		t.Fatalf("censorship was forwarded before approval")
+	}
+
+	err = approve(admin1, id1, spsr.PendingAction.ID)
+	assertError(t, err, www.ErrorStatusApprovalBySameAdmin)
+	err = approve(admin2, id2, spsr.PendingAction.ID)
+	assertSuccess(t, err)
+	shr, err = b.ProcessStatusHistory(www.StatusHistory{Token: token})
+	assertSuccess(t, err)
+	if len(shr.Changes) != 1 ||
+		shr.Changes[0].AdminID != strconv.FormatUint(admin1.ID, 10) {
+		t.Fatalf("unexpected status changes %v", shr.Changes)
+	}
+	err = approve(admin2, id2, spsr.PendingAction.ID)
+	assertError(t, err, www.ErrorStatusPendingActionNotFound)
+
+	// Actions that can't be carried out remain queued.
+	nu, _ := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	adur, err := b.ProcessAdminDeactivateUser(deactivate(user), admin1)
+	assertSuccess(t, err)
+	user.Deactivated = time.Now().Unix()
+	assertSuccess(t, b.db.UserUpdate(*user))
+	err = approve(admin2, id2, adur.PendingAction.ID)
+	if err == nil {
+		t.Fatalf("deactivated a deactivated user")
+	}
+	par, err := b.ProcessPendingActions()
+	assertSuccess(t, err)
+	if len(par.Actions) != 1 || par.Actions[0].ID != adur.PendingAction.ID {
+		t.Fatalf("unexpected pending actions %v", par.Actions)
+	}
+	user.Deactivated = 0
+	assertSuccess(t, b.db.UserUpdate(*user))
+	err = approve(admin2, id2, adur.PendingAction.ID)
+	assertSuccess(t, err)
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	if user.Deactivated == 0 {
+		t.Fatalf("user was not deactivated")
+	}
+	aar, err := b.ProcessAdminActions()
+	assertSuccess(t, err)
+	if len(aar.Actions) != 1 ||
+		aar.Actions[0].Action != www.AdminActionDeactivateUser ||
+		aar.Actions[0].AdminID != strconv.FormatUint(admin1.ID, 10) {
+		t.Fatalf("unexpected audit log %v", aar.Actions)
+	}
+
+	// Rejected deactivations are dropped.
+	nu, _ = createAndVerifyUser(t, b)
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	adur, err = b.ProcessAdminDeactivateUser(deactivate(user), admin1)
+	assertSuccess(t, err)
+	par, err = b.ProcessPendingActions()
+	assertSuccess(t, err)
+	if len(par.Actions) != 1 || par.Actions[0].Reason != "spam" {
+		t.Fatalf("unexpected pending actions %v", par.Actions)
+	}
+
+	paID := adur.PendingAction.ID
+	_, err = b.ProcessRejectPendingAction(www.RejectPendingAction{
+		ID:        paID,
+		PublicKey: id2.Public.String(),
+		Signature: sign(id2, paID, www.PendingActionReject),
+	}, admin2)
+	assertSuccess(t, err)
+	err = approve(admin2, id2, paID)
+	assertError(t, err, www.ErrorStatusPendingActionNotFound)
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	if user.Deactivated != 0 {
+		t.Fatalf("user was deactivated")
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"gopkg.in/ldap.v2"
+)
+
+const (
+	// Authenticators.
+	authenticatorLocal = "local"
+	authenticatorLDAP  = "ldap"
+
+	// ldapTimeout is the timeout of the binds to the LDAP server.
+	ldapTimeout = 10 * time.Second
+)
+
+var (
+	errInvalidPassword = fmt.Errorf("invalid password")
+)
+
+// authenticator verifies the passwords of the users.  The identities and the
+// sessions of the users are managed by politeiawww regardless of the
+// authenticator.
+type authenticator interface {
+	// Authenticate returns errInvalidPassword if the provided password is
+	// not the password of the provided user.
+	Authenticate(user *database.User, password string) error
+}
+
+// localAuthenticator is an authenticator that verifies the passwords against
+// the password hashes of the user database.
+type localAuthenticator struct{}
+
+// Authenticate satisfies the authenticator interface.
+func (localAuthenticator) Authenticate(user *database.User, password string) error {
+	return comparePasswordHash(user.HashedPassword, password)
+}
+
+// ldapAuthenticator is an authenticator that verifies the passwords with a
+// simple bind to an LDAP server or an Active Directory domain controller.
+// Passwords are only ever sent over TLS: ldaps servers are dialed with TLS and
+// ldap servers must accept StartTLS before the bind.
+type ldapAuthenticator struct {
+	address   string      // Host and port of the server
+	tlsConfig *tls.Config // TLS config of the connections
+	startTLS  bool        // Upgrade the connections with StartTLS
+	bindDN    string      // Template of the DN that users bind as
+}
+
+// newLDAPAuthenticator returns the LDAP authenticator that is configured by
+// the provided config.
+func newLDAPAuthenticator(cfg *config) (*ldapAuthenticator, error) {
+	u, err := url.Parse(cfg.LDAPURL)
+	if err != nil {
+		return nil, err
+	}
+
+	a := ldapAuthenticator{
+		address: u.Host,
+		tlsConfig: &tls.Config{
+			ServerName: u.Hostname(),
+		},
+		bindDN: cfg.LDAPBindDN,
+	}
+	var port string
+	switch u.Scheme {
+	case "ldap":
+		port = "389"
+		a.startTLS = true
+	case "ldaps":
+		port = "636"
+	default:
+		return nil, fmt.Errorf("invalid ldap scheme: %v", u.Scheme)
+	}
+	if u.Port() == "" {
+		a.address = net.JoinHostPort(u.Hostname(), port)
+	}
+	if cfg.LDAPCert != "" {
+		cert, err := ioutil.ReadFile(cfg.LDAPCert)
+		if err != nil {
+			return nil, err
+		}
+		a.tlsConfig.RootCAs = x509.NewCertPool()
+		if !a.tlsConfig.RootCAs.AppendCertsFromPEM(cert) {
+			return nil, fmt.Errorf("invalid ldap certificate: %v",
+				cfg.LDAPCert)
+		}
+	}
+
+	return &a, nil
+}
+
+// ldapEscapeDN escapes the characters of the provided attribute value that
+// are special in a DN as specified by RFC 4514.
+func ldapEscapeDN(value string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == 0:
+			b.WriteString(`\00`)
+			continue
+		case strings.IndexByte(`"+,;<=>\`, c) != -1,
+			c == '#' && i == 0,
+			c == ' ' && (i == 0 || i == len(value)-1):
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// ldapBindDN returns the DN that the provided user binds as.  The {email} and
+// {username} placeholders of the template are replaced by the email address
+// of the user and its local part.
+func ldapBindDN(template, email string) string {
+	username := email
+	if i := strings.LastIndexByte(email, '@'); i != -1 {
+		username = email[:i]
+	}
+	return strings.NewReplacer(
+		"{email}", ldapEscapeDN(email),
+		"{username}", ldapEscapeDN(username),
+	).Replace(template)
+}
+
+// bind binds to the LDAP server as the provided DN with the provided
+// password.  The connection is secured with TLS before the bind; servers that
+// refuse StartTLS are refused in turn rather than receiving the password in
+// cleartext.
+func (a *ldapAuthenticator) bind(dn, password string) error {
+	dialer := net.Dialer{Timeout: ldapTimeout}
+	var (
+		c   net.Conn
+		err error
+	)
+	if a.startTLS {
+		c, err = dialer.Dial("tcp", a.address)
+	} else {
+		c, err = tls.DialWithDialer(&dialer, "tcp", a.address,
+			a.tlsConfig)
+	}
+	if err != nil {
+		return err
+	}
+	conn := ldap.NewConn(c, !a.startTLS)
+	conn.SetTimeout(ldapTimeout)
+	conn.Start()
+	defer conn.Close()
+
+	if a.startTLS {
+		err = conn.StartTLS(a.tlsConfig)
+		if err != nil {
+			return fmt.Errorf("starttls: %v", err)
+		}
+	}
+
+	return conn.Bind(dn, password)
+}
+
+// Authenticate satisfies the authenticator interface.
+func (a *ldapAuthenticator) Authenticate(user *database.User, password string) error {
+	// A bind without a password is an anonymous bind that succeeds
+	// regardless of the DN.
+	if password == "" {
+		return errInvalidPassword
+	}
+
+	dn := ldapBindDN(a.bindDN, user.Email)
+	err := a.bind(dn, password)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return errInvalidPassword
+	} else if err != nil {
+		return fmt.Errorf("ldap bind %v: %v", dn, err)
+	}
+	return nil
+}
+
+// verifyPassword verifies the password of the provided user with the
+// configured authenticator.
+func (b *backend) verifyPassword(user *database.User, password string) error {
+	err := b.authenticator.Authenticate(user, password)
+	if err == errInvalidPassword {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusInvalidEmailOrPassword,
+		}
+	}
+	return err
+}
@@ -0,0 +1,298 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/elliptic"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/certgen"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"gopkg.in/asn1-ber.v1"
+	"gopkg.in/ldap.v2"
+)
+
+// ldapStartTLSOID is the name of the StartTLS extended request.
+const ldapStartTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// ldapServer is a fake LDAP server that replies to StartTLS and simple bind
+// requests.  Binds succeed if the DN and password match the configured ones.
+type ldapServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	certFile  string
+
+	startTLS bool // Accept StartTLS requests
+	dn       string
+	password string
+
+	binds chan bool // Whether each bind was received over TLS
+}
+
+// newLDAPServer starts a fake LDAP server with a new self-signed certificate.
+// Connections are served over TLS from the start if ldaps is set.
+func newLDAPServer(t *testing.T, ldaps, startTLS bool) *ldapServer {
+	t.Helper()
+
+	cert, key, err := certgen.NewTLSCertPair(elliptic.P256(),
+		"politeiawww ldap test", time.Now().Add(time.Hour), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "politeiawww.ldap.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, "ldap.cert")
+	err = ioutil.WriteFile(certFile, cert, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &ldapServer{
+		tlsConfig: &tls.Config{
+			Certificates: []tls.Certificate{pair},
+		},
+		certFile: certFile,
+		startTLS: startTLS,
+		dn:       "uid=alice,dc=example,dc=com",
+		password: "password",
+		binds:    make(chan bool, 16),
+	}
+	if ldaps {
+		s.listener, err = tls.Listen("tcp", "127.0.0.1:0", s.tlsConfig)
+	} else {
+		s.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.serve(ldaps)
+
+	return s
+}
+
+// close stops the fake LDAP server and removes its certificate.
+func (s *ldapServer) close() {
+	s.listener.Close()
+	os.RemoveAll(filepath.Dir(s.certFile))
+}
+
+// ldapResponse returns an LDAP response with the provided message id,
+// protocol operation and result code.
+func ldapResponse(id int64, op ber.Tag, code int64) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed,
+		ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive,
+		ber.TagInteger, id, "MessageID"))
+	r := ber.Encode(ber.ClassApplication, ber.TypeConstructed, op, nil,
+		"Response")
+	r.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive,
+		ber.TagEnumerated, code, "resultCode"))
+	r.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive,
+		ber.TagOctetString, "", "matchedDN"))
+	r.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive,
+		ber.TagOctetString, "", "diagnosticMessage"))
+	p.AppendChild(r)
+	return p
+}
+
+// serve replies to the requests of the connections that the server accepts.
+func (s *ldapServer) serve(ldaps bool) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveConn(conn, ldaps)
+	}
+}
+
+// serveConn replies to the requests of the provided connection until it is
+// closed.
+func (s *ldapServer) serveConn(conn net.Conn, secure bool) {
+	// The connection is replaced when it is upgraded with StartTLS.
+	defer func() {
+		conn.Close()
+	}()
+
+	for {
+		p, err := ber.ReadPacket(conn)
+		if err != nil || len(p.Children) < 2 {
+			return
+		}
+		id, _ := p.Children[0].Value.(int64)
+		request := p.Children[1]
+
+		switch request.Tag {
+		case ldap.ApplicationExtendedRequest:
+			name := string(request.Children[0].Data.Bytes())
+			if name != ldapStartTLSOID || !s.startTLS {
+				conn.Write(ldapResponse(id,
+					ldap.ApplicationExtendedResponse,
+					ldap.LDAPResultProtocolError).Bytes())
+				continue
+			}
+			conn.Write(ldapResponse(id,
+				ldap.ApplicationExtendedResponse,
+				ldap.LDAPResultSuccess).Bytes())
+			conn = tls.Server(conn, s.tlsConfig)
+			secure = true
+
+		case ldap.ApplicationBindRequest:
+			s.binds <- secure
+			name, _ := request.Children[1].Value.(string)
+			password := request.Children[2].Data.String()
+			code := int64(ldap.LDAPResultSuccess)
+			if name != s.dn || password != s.password {
+				code = ldap.LDAPResultInvalidCredentials
+			}
+			conn.Write(ldapResponse(id, ldap.ApplicationBindResponse,
+				code).Bytes())
+
+		default:
+			return
+		}
+	}
+}
+
+func TestLDAPBindDN(t *testing.T) {
+	tests := []struct {
+		template string
+		email    string
+		want     string
+	}{
+		{"uid={username},dc=example,dc=com", "alice@example.com",
+			"uid=alice,dc=example,dc=com"},
+		{"{email}", "alice@example.com", "alice@example.com"},
+		{"cn={username},dc=example,dc=com", "a,dc=x+b@example.com",
+			`cn=a\,dc\=x\+b,dc=example,dc=com`},
+		{"cn={username}", "#a @example.com", `cn=\#a\ `},
+	}
+	for _, test := range tests {
+		got := ldapBindDN(test.template, test.email)
+		if got != test.want {
+			t.Errorf("ldapBindDN(%q, %q) = %q, want %q",
+				test.template, test.email, got, test.want)
+		}
+	}
+}
+
+func TestLDAPAuthenticator(t *testing.T) {
+	tests := []struct {
+		name     string
+		scheme   string
+		ldaps    bool
+		startTLS bool
+	}{
+		{"ldaps", "ldaps", true, false},
+		{"ldap with starttls", "ldap", false, true},
+	}
+	for _, test := range tests {
+		s := newLDAPServer(t, test.ldaps, test.startTLS)
+		a, err := newLDAPAuthenticator(&config{
+			LDAPURL:    test.scheme + "://" + s.listener.Addr().String(),
+			LDAPBindDN: "uid={username},dc=example,dc=com",
+			LDAPCert:   s.certFile,
+		})
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		user := &database.User{
+			Email: "alice@example.com",
+		}
+		err = a.Authenticate(user, "password")
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		err = a.Authenticate(user, "wrong")
+		if err != errInvalidPassword {
+			t.Fatalf("%v: got %v, want %v", test.name, err,
+				errInvalidPassword)
+		}
+		err = a.Authenticate(user, "")
+		if err != errInvalidPassword {
+			t.Fatalf("%v: got %v, want %v", test.name, err,
+				errInvalidPassword)
+		}
+		s.close()
+
+		// Every bind was sent over TLS.
+		for len(s.binds) > 0 {
+			if !<-s.binds {
+				t.Fatalf("%v: cleartext bind", test.name)
+			}
+		}
+	}
+}
+
+func TestLDAPAuthenticatorNoStartTLS(t *testing.T) {
+	s := newLDAPServer(t, false, false)
+	defer s.close()
+
+	a, err := newLDAPAuthenticator(&config{
+		LDAPURL:    "ldap://" + s.listener.Addr().String(),
+		LDAPBindDN: "uid={username},dc=example,dc=com",
+		LDAPCert:   s.certFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The password is never sent to servers that refuse StartTLS.
+	err = a.Authenticate(&database.User{
+		Email: "alice@example.com",
+	}, "password")
+	if err == nil || err == errInvalidPassword {
+		t.Fatalf("expected a starttls error, got %v", err)
+	}
+	select {
+	case <-s.binds:
+		t.Fatalf("unexpected bind")
+	default:
+	}
+}
+
+func TestLDAPAuthenticatorBackend(t *testing.T) {
+	s := newLDAPServer(t, true, false)
+	defer s.close()
+
+	a, err := newLDAPAuthenticator(&config{
+		LDAPURL:    "ldaps://" + s.listener.Addr().String(),
+		LDAPBindDN: "uid={username},dc=example,dc=com",
+		LDAPCert:   s.certFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The local password of the user is not used anymore.
+	b := createBackend(t)
+	defer b.db.Close()
+	u, _ := createAndVerifyUser(t, b)
+	_, err = b.ProcessLogin(www.Login{
+		Email:    u.Email,
+		Password: u.Password,
+	})
+	assertSuccess(t, err)
+	b.authenticator = a
+	_, err = b.ProcessLogin(www.Login{
+		Email:    u.Email,
+		Password: u.Password,
+	})
+	assertError(t, err, www.ErrorStatusInvalidEmailOrPassword)
+}
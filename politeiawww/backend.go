@@ -2,23 +2,27 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
 
-	"github.com/dajohi/goemail"
+	"github.com/badoux/checkmail"
 	"github.com/decred/dcrd/chaincfg"
 	"github.com/decred/dcrtime/merkle"
 	"github.com/decred/politeia/decredplugin"
@@ -36,18 +40,37 @@ const (
 	indexFile = "index.md"
 
 	// mdStream* indicate the metadata stream used for various types
-	mdStreamGeneral  = 0 // General information for this proposal
-	mdStreamComments = 1 // Comments
-	mdStreamChanges  = 2 // Changes to record
+	mdStreamGeneral   = 0 // General information for this proposal
+	mdStreamComments  = 1 // Comments
+	mdStreamChanges   = 2 // Changes to record
+	mdStreamTags      = 3 // Proposal tags
+	mdStreamAuthor    = 4 // Author of the proposal
+	mdStreamTimeline  = 5 // Funding and schedule of the proposal
+	mdStreamPages     = 6 // Order of the markdown files of the proposal
+	mdStreamSpam      = 7 // Spam score of a flagged proposal
+	mdStreamCoAuthors = 8 // Co-authors of the proposal
+	mdStreamImport    = 9 // Origin of an imported proposal
 	// Note that 13 is in use by the decred plugin
 	// Note that 14 is in use by the decred plugin
 	// Note that 15 is in use by the decred plugin
+
+	// verificationResendCooldown is the minimum time between two
+	// verification emails that are resent to a user.
+	verificationResendCooldown = 10 * time.Minute
 )
 
+// MDStreamChanges is a status change of a proposal.  The change is signed by
+// the admin: the signature is the one of the SetProposalStatus command, so it
+// covers the token, the www status, the reason and the publication time when
+// it is set.
 type MDStreamChanges struct {
-	AdminPubKey string           // Identity of the administrator
-	NewStatus   pd.RecordStatusT // NewStatus
-	Timestamp   int64            // Timestamp of the change
+	AdminPubKey         string           // Identity of the administrator
+	NewStatus           pd.RecordStatusT // NewStatus
+	StatusChangeMessage string           // Reason of the change
+	Timestamp           int64            // Timestamp of the change
+	PublicKey           string           // Key that signed the change
+	Signature           string           // Signature of the change
+	PublishAt           int64            // Signed publication time, if any
 }
 
 // politeiawww backend construct
@@ -61,6 +84,88 @@ type backend struct {
 	commentJournalDir  string
 	commentJournalFile string
 	userPubkeys        map[string]string // [pubkey][userid]
+	emailQueue         chan *emailMessage
+	emailLimiter       *emailRateLimiter
+	unsubscribeKey     []byte // Key used to sign unsubscribe links
+	jwtKey             []byte // Key used to sign API tokens
+	signupKey          []byte // Key used to sign signup challenges
+
+	// Shutdown of the background workers and the email queue.
+	quit             chan struct{}  // Closed to stop the workers
+	workers          sync.WaitGroup // Running background workers
+	emailQueueMtx    sync.RWMutex   // Guards emailQueueClosed and emailDeferred
+	emailQueueClosed bool           // Set once no more emails are accepted
+	emailDeferred    int            // Emails waiting for the rate limit
+	emailWorkerDone  chan struct{}  // Closed once the email queue is drained
+
+	// Sessions and rate limit counters.
+	stateStore stateStore
+
+	// Federated login provider, nil if disabled.
+	oidc *oidcProvider
+
+	// Verifies the passwords of the users.
+	authenticator authenticator
+
+	// Hashes the passwords of the users.
+	passwordHasher passwordHasher
+
+	// State of the feature flags.
+	featuresMtx sync.RWMutex
+	features    map[string]bool // [name]enabled
+
+	// Serializes the changes of the proposal credit balances.
+	creditsMtx sync.Mutex
+
+	// Serializes the proposal imports so that a bundle can't be imported
+	// twice while politeiad creates its record.
+	importMtx sync.Mutex
+
+	// IP bans that are enforced.
+	ipBansMtx sync.RWMutex
+	ipBans    []ipBan
+
+	// Members of the cluster this instance is part of.
+	clusterClient   *http.Client // Client used to contact the members
+	commentFlushMtx sync.Mutex   // Serializes the flushes of new comments
+
+	// Serializes the checks of TOTP codes so that a code is only accepted
+	// once.
+	totpMtx sync.Mutex
+
+	// Serializes the refreshes of API tokens so that a refresh token is
+	// only accepted once.
+	jwtMtx sync.Mutex
+
+	// Summaries of the index files of vetted proposals for the feeds.
+	// Vetted files can't change, so they never go stale.
+	feedSummariesMtx sync.Mutex
+	feedSummaries    map[string]string // [token]summary
+
+	// Matches the valid proposal names, which depend on the configured
+	// name lengths.
+	validProposalName *regexp.Regexp
+
+	// Webhooks that proposal lifecycle events are sent to and the log of
+	// the recent delivery attempts.
+	webhookClient  *http.Client
+	webhookBackoff time.Duration // Time before the first retry
+	webhookLogMtx  sync.Mutex
+	webhookLog     []www.WebhookDelivery // Oldest first
+
+	// Certificates that AWS SNS signs the email provider notifications
+	// with.
+	snsCertsMtx sync.Mutex
+	snsCerts    map[string]*x509.Certificate // [url]certificate
+
+	// Websocket clients that are subscribed to the votes on proposals.
+	voteStreamMtx sync.Mutex
+	voteStreams   map[*voteStream]struct{}
+
+	// Pending new proposal notifications when admin notifications are
+	// batched.
+	adminNotificationsMtx sync.Mutex
+	adminNotifications    []newProposalNotification
 
 	// These properties are only used for testing.
 	test                   bool
@@ -72,10 +177,31 @@ type backend struct {
 
 	// inventory will eventually replace inventory
 	inventory map[string]*inventoryRecord // Current inventory
+
+	// Statistics of the users and of the proposals that are maintained
+	// as the inventory changes.
+	userStats     map[string]*userStats  // [userid]statistics
+	proposalStats *proposalStats         // Statistics of all proposals
+	recordStats   map[string]recordStats // [token]contribution to the stats
+
+	// fileCache holds the files of the proposals that were fetched last.
+	fileCache *fileCache
+
+	// spamFilters score the new proposals for spam.
+	spamFilters []spamFilter
+
+	// views counts the views of the proposals.
+	views *viewCounter
+
+	// statusIndex holds the proposals of each status in the order they
+	// are listed so that listings only visit the requested statuses.  It
+	// is maintained along with the statistics.
+	statusIndex map[www.PropStatusT][]statusIndexEntry
 }
 
 const (
 	BackendProposalMetadataVersion = 1
+	BackendProposalAuthorVersion   = 1
 
 	politeiaMailName = "Politeia"
 )
@@ -86,6 +212,11 @@ type BackendProposalMetadata struct {
 	Name      string `json:"name"`      // Generated proposal name
 	PublicKey string `json:"publickey"` // Key used for signature.
 	Signature string `json:"signature"` // Signature of merkle root
+
+	// ProposalVersion is the version of the files of the proposal.  It is
+	// bumped when the author edits the proposal and is absent from the
+	// proposals that were never edited.
+	ProposalVersion uint64 `json:"proposalversion,omitempty"`
 }
 
 // encodeBackendProposalMetadata encodes BackendProposalMetadata into a JSON
@@ -112,6 +243,39 @@ func decodeBackendProposalMetadata(payload []byte) (*BackendProposalMetadata, er
 	return &md, nil
 }
 
+// BackendProposalAuthor is the metadata stream that records the author of a
+// proposal when it is submitted.  It is never changed afterwards.
+type BackendProposalAuthor struct {
+	Version   uint64 `json:"version"`   // BackendProposalAuthor version
+	UserID    string `json:"userid"`    // ID of the user who submitted the proposal
+	PublicKey string `json:"publickey"` // Key that signed the submission
+	Timestamp int64  `json:"timestamp"` // Submission time
+}
+
+// encodeBackendProposalAuthor encodes BackendProposalAuthor into a JSON byte
+// slice.
+func encodeBackendProposalAuthor(md BackendProposalAuthor) ([]byte, error) {
+	b, err := json.Marshal(md)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// decodeBackendProposalAuthor decodes a JSON byte slice into a
+// BackendProposalAuthor.
+func decodeBackendProposalAuthor(payload []byte) (*BackendProposalAuthor, error) {
+	var md BackendProposalAuthor
+
+	err := json.Unmarshal(payload, &md)
+	if err != nil {
+		return nil, err
+	}
+
+	return &md, nil
+}
+
 // checkPublicKeyAndSignature validates the public key and signature.
 func checkPublicKeyAndSignature(user *database.User, publicKey string, signature string, elements ...string) error {
 	id, err := checkPublicKey(user, publicKey)
@@ -122,22 +286,25 @@ func checkPublicKeyAndSignature(user *database.User, publicKey string, signature
 	return checkSignature(id, signature, elements...)
 }
 
-// checkPublicKey compares the supplied public key against the one stored in
-// the user database. It will return the active identity if there are no errors.
+// checkPublicKey compares the supplied public key against the active ones
+// stored in the user database. It will return the matching identity if there
+// are no errors.
 func checkPublicKey(user *database.User, pk string) ([]byte, error) {
-	id, ok := database.ActiveIdentity(user.Identities)
-	if !ok {
+	ids := database.ActiveIdentities(user.Identities)
+	if len(ids) == 0 {
 		return nil, www.UserError{
 			ErrorCode: www.ErrorStatusNoPublicKey,
 		}
 	}
 
-	if hex.EncodeToString(id[:]) != pk {
-		return nil, www.UserError{
-			ErrorCode: www.ErrorStatusInvalidSigningKey,
+	for _, id := range ids {
+		if hex.EncodeToString(id[:]) == pk {
+			return id[:], nil
 		}
 	}
-	return id[:], nil
+	return nil, www.UserError{
+		ErrorCode: www.ErrorStatusInvalidSigningKey,
+	}
 }
 
 // checkSignature validates an incoming signature against the specified user's pubkey.
@@ -165,33 +332,10 @@ func checkSignature(id []byte, signature string, elements ...string) error {
 	return nil
 }
 
-func (b *backend) getVerificationExpiryTime() time.Duration {
-	if b.verificationExpiryTime != time.Duration(0) {
-		return b.verificationExpiryTime
-	}
-	return time.Duration(www.VerificationExpiryHours) * time.Hour
-}
-
-func (b *backend) generateVerificationTokenAndExpiry() ([]byte, int64, error) {
-	token, err := util.Random(www.VerificationTokenSize)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	expiry := time.Now().Add(b.getVerificationExpiryTime()).Unix()
-
-	return token, expiry, nil
-}
-
-// hashPassword hashes the given password string with the default bcrypt cost
-// or the minimum cost if the test flag is set to speed up running tests.
+// hashPassword hashes the given password string with the configured
+// password hashing algorithm.
 func (b *backend) hashPassword(password string) ([]byte, error) {
-	if b.test {
-		return bcrypt.GenerateFromPassword([]byte(password),
-			bcrypt.MinCost)
-	}
-	return bcrypt.GenerateFromPassword([]byte(password),
-		bcrypt.DefaultCost)
+	return b.passwordHasher.Hash(password)
 }
 
 // initUserPubkeys initializes the userPubkeys map with all the pubkey-userid
@@ -226,7 +370,7 @@ func (b *backend) setUserPubkeyAssociaton(user *database.User, publicKey string)
 // emailNewUserVerificationLink emails the link with the new user verification token
 // if the email server is set up.
 func (b *backend) emailNewUserVerificationLink(email, token string) error {
-	if b.cfg.SMTP == nil {
+	if b.cfg.Mailer == nil {
 		return nil
 	}
 
@@ -248,21 +392,17 @@ func (b *backend) emailNewUserVerificationLink(email, token string) error {
 	if err != nil {
 		return err
 	}
-	from := "noreply@decred.org"
 	subject := "Verify Your Email"
 	body := buf.String()
 
-	msg := goemail.NewHTMLMessage(from, subject, body)
-	msg.AddTo(email)
-
-	msg.SetName(politeiaMailName)
-	return b.cfg.SMTP.Send(msg)
+	msg := newEmailMessage(email, subject, body)
+	return b.sendEmail(msg)
 }
 
 // emailResetPasswordVerificationLink emails the link with the reset password
 // verification token if the email server is set up.
 func (b *backend) emailResetPasswordVerificationLink(email, token string) error {
-	if b.cfg.SMTP == nil {
+	if b.cfg.Mailer == nil {
 		return nil
 	}
 
@@ -284,21 +424,17 @@ func (b *backend) emailResetPasswordVerificationLink(email, token string) error
 	if err != nil {
 		return err
 	}
-	from := "noreply@decred.org"
 	subject := "Reset Your Password"
 	body := buf.String()
 
-	msg := goemail.NewHTMLMessage(from, subject, body)
-	msg.AddTo(email)
-
-	msg.SetName(politeiaMailName)
-	return b.cfg.SMTP.Send(msg)
+	msg := newEmailMessage(email, subject, body)
+	return b.sendEmail(msg)
 }
 
 // emailUpdateUserKeyVerificationLink emails the link with the verification token
 // used for setting a new key pair if the email server is set up.
 func (b *backend) emailUpdateUserKeyVerificationLink(email, publicKey, token string) error {
-	if b.cfg.SMTP == nil {
+	if b.cfg.Mailer == nil {
 		return nil
 	}
 
@@ -320,20 +456,157 @@ func (b *backend) emailUpdateUserKeyVerificationLink(email, publicKey, token str
 	if err != nil {
 		return err
 	}
-	from := "noreply@decred.org"
 	subject := "Set New Key Pair"
 	body := buf.String()
 
-	msg := goemail.NewHTMLMessage(from, subject, body)
-	msg.AddTo(email)
+	msg := newEmailMessage(email, subject, body)
+	return b.sendEmail(msg)
+}
+
+// emailChangeEmailVerificationLink emails the link with the verification
+// token used for changing the email address of a user to the new email
+// address if the email server is set up.
+func (b *backend) emailChangeEmailVerificationLink(email, token string) error {
+	if b.cfg.Mailer == nil {
+		return nil
+	}
+
+	l, err := url.Parse(b.cfg.WebServerAddress + www.RouteVerifyChangeEmail)
+	if err != nil {
+		return err
+	}
+	q := l.Query()
+	q.Set("verificationtoken", token)
+	l.RawQuery = q.Encode()
+
+	var buf bytes.Buffer
+	tplData := changeEmailTemplateData{
+		Email: email,
+		Link:  l.String(),
+	}
+	err = templateChangeEmail.Execute(&buf, &tplData)
+	if err != nil {
+		return err
+	}
+	subject := "Verify Your New Email"
+	body := buf.String()
+
+	msg := newEmailMessage(email, subject, body)
+	return b.sendEmail(msg)
+}
+
+// emailNewDeviceLogin warns a user about a login from a device that the user
+// has not logged in from before if the email server is set up.  The email is
+// queued so that it doesn't delay the login.
+func (b *backend) emailNewDeviceLogin(email, ip, userAgent string, t time.Time) error {
+	if b.cfg.Mailer == nil {
+		return nil
+	}
+
+	l, err := url.Parse(b.cfg.WebServerAddress + www.RouteResetPassword)
+	if err != nil {
+		return err
+	}
+	q := l.Query()
+	q.Set("email", email)
+	l.RawQuery = q.Encode()
+
+	var buf bytes.Buffer
+	tplData := newDeviceLoginTemplateData{
+		Email:     email,
+		IP:        ip,
+		UserAgent: userAgent,
+		Time:      t.UTC().Format(time.RFC1123),
+		Link:      l.String(),
+	}
+	err = templateNewDeviceLogin.Execute(&buf, &tplData)
+	if err != nil {
+		return err
+	}
+	subject := "New Login To Your Account"
+	body := buf.String()
+
+	b.enqueueEmail(newEmailMessage(email, subject, body))
+	return nil
+}
+
+// emailUserAdminChanged notifies a user that an admin granted or revoked its
+// admin privileges if the email server is set up.
+func (b *backend) emailUserAdminChanged(email string, admin bool, reason string) error {
+	if b.cfg.Mailer == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	tplData := userAdminChangedTemplateData{
+		Email:  email,
+		Admin:  admin,
+		Reason: reason,
+	}
+	err := templateUserAdminChanged.Execute(&buf, &tplData)
+	if err != nil {
+		return err
+	}
+	subject := "Admin Privileges Revoked"
+	if admin {
+		subject = "Admin Privileges Granted"
+	}
+	body := buf.String()
+
+	msg := newEmailMessage(email, subject, body)
+	return b.sendEmail(msg)
+}
+
+// emailUserVerifiedByAdmin notifies a user that an admin verified its email
+// address if the email server is set up.  The email is queued so that it
+// doesn't delay the admin.
+func (b *backend) emailUserVerifiedByAdmin(email, reason string) error {
+	if b.cfg.Mailer == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	tplData := userVerifiedByAdminTemplateData{
+		Email:  email,
+		Reason: reason,
+	}
+	err := templateUserVerifiedByAdmin.Execute(&buf, &tplData)
+	if err != nil {
+		return err
+	}
+	subject := "Your Account Was Verified"
+	body := buf.String()
+
+	b.enqueueEmail(newEmailMessage(email, subject, body))
+	return nil
+}
+
+// emailAccountRecovered notifies the previous email address of a user that
+// the account was recovered if the email server is set up.
+func (b *backend) emailAccountRecovered(email string) error {
+	if b.cfg.Mailer == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	tplData := accountRecoveredTemplateData{
+		Email: email,
+	}
+	err := templateAccountRecovered.Execute(&buf, &tplData)
+	if err != nil {
+		return err
+	}
+	subject := "Your Account Was Recovered"
+	body := buf.String()
 
-	msg.SetName(politeiaMailName)
-	return b.cfg.SMTP.Send(msg)
+	msg := newEmailMessage(email, subject, body)
+	return b.sendEmail(msg)
 }
 
 // makeRequest makes an http request to the method and route provided, serializing
-// the provided object as the request body.
-func (b *backend) makeRequest(method string, route string, v interface{}) ([]byte, error) {
+// the provided object as the request body.  The request ID that is carried by
+// the provided context is forwarded to politeiad.
+func (b *backend) makeRequest(ctx context.Context, method string, route string, v interface{}) ([]byte, error) {
 	var (
 		requestBody []byte
 		err         error
@@ -359,6 +632,12 @@ func (b *backend) makeRequest(method string, route string, v interface{}) ([]byt
 		return nil, err
 	}
 	req.SetBasicAuth(b.cfg.RPCUser, b.cfg.RPCPass)
+	if id := util.RequestID(ctx); id != "" {
+		req.Header.Set(util.RequestIDHeader, id)
+	}
+	if err := injectFault(faultPoliteiad); err != nil {
+		return nil, err
+	}
 	r, err := b.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -383,7 +662,7 @@ func (b *backend) makeRequest(method string, route string, v interface{}) ([]byt
 }
 
 // remoteInventory fetches the entire inventory of proposals from politeiad.
-func (b *backend) remoteInventory() (*pd.InventoryReply, error) {
+func (b *backend) remoteInventory(ctx context.Context) (*pd.InventoryReply, error) {
 	challenge, err := util.Random(pd.ChallengeSize)
 	if err != nil {
 		return nil, err
@@ -395,7 +674,7 @@ func (b *backend) remoteInventory() (*pd.InventoryReply, error) {
 		BranchesCount: 0,
 	}
 
-	responseBody, err := b.makeRequest(http.MethodPost, pd.InventoryRoute, inv)
+	responseBody, err := b.makeRequest(ctx, http.MethodPost, pd.InventoryRoute, inv)
 	if err != nil {
 		return nil, err
 	}
@@ -415,16 +694,6 @@ func (b *backend) remoteInventory() (*pd.InventoryReply, error) {
 	return &ir, nil
 }
 
-func (b *backend) validatePassword(password string) error {
-	if len(password) < www.PolicyPasswordMinChars {
-		return www.UserError{
-			ErrorCode: www.ErrorStatusMalformedPassword,
-		}
-	}
-
-	return nil
-}
-
 func (b *backend) validateProposal(np www.NewProposal, user *database.User) error {
 	log.Tracef("validateProposal")
 
@@ -474,9 +743,13 @@ func (b *backend) validateProposal(np www.NewProposal, user *database.User) erro
 			if err != nil {
 				return err
 			}
-			if len(data) > www.PolicyMaxImageSize {
+			if len(data) > b.cfg.MaxProposalImageSize {
 				imageExceedsMaxSize = true
 			}
+			err = validateImage(v.Name, v.MIME, data)
+			if err != nil {
+				return err
+			}
 		} else {
 			numMDs++
 
@@ -488,9 +761,14 @@ func (b *backend) validateProposal(np www.NewProposal, user *database.User) erro
 			if err != nil {
 				return err
 			}
-			if len(data) > www.PolicyMaxMDSize {
+			if len(data) > b.cfg.MaxProposalMDSize {
 				mdExceedsMaxSize = true
 			}
+			err = validateMarkdown(v.Name, data,
+				b.cfg.RejectProposalHTML)
+			if err != nil {
+				return err
+			}
 		}
 
 		// Append digest to array for merkle root calculation
@@ -524,13 +802,18 @@ func (b *backend) validateProposal(np www.NewProposal, user *database.User) erro
 		}
 	}
 
-	if numMDs > www.PolicyMaxMDs {
+	_, err = proposalPages(np.Files, np.Pages)
+	if err != nil {
+		return err
+	}
+
+	if numMDs > b.cfg.MaxProposalMDs {
 		return www.UserError{
 			ErrorCode: www.ErrorStatusMaxMDsExceededPolicy,
 		}
 	}
 
-	if numImages > www.PolicyMaxImages {
+	if numImages > b.cfg.MaxProposalImages {
 		return www.UserError{
 			ErrorCode: www.ErrorStatusMaxImagesExceededPolicy,
 		}
@@ -553,10 +836,10 @@ func (b *backend) validateProposal(np www.NewProposal, user *database.User) erro
 	if err != nil {
 		return err
 	}
-	if !util.IsValidProposalName(name) {
+	if !b.validProposalName.MatchString(name) {
 		return www.UserError{
 			ErrorCode:    www.ErrorStatusProposalInvalidTitle,
-			ErrorContext: []string{util.CreateProposalTitleRegex()},
+			ErrorContext: []string{b.validProposalName.String()},
 		}
 	}
 
@@ -568,29 +851,25 @@ func (b *backend) validateProposal(np www.NewProposal, user *database.User) erro
 		}
 	}
 
-	return nil
+	return validateCoAuthors(np.CoAuthors, np.PublicKey,
+		hex.EncodeToString(mr[:]))
 }
 
 func (b *backend) emailResetPassword(user *database.User, rp www.ResetPassword, rpr *www.ResetPasswordReply) error {
-	if user.ResetPasswordVerificationToken != nil {
-		currentTime := time.Now().Unix()
-		if currentTime < user.ResetPasswordVerificationExpiry {
-			// The verification token is present and hasn't expired, so do nothing.
-			return nil
-		}
+	if pendingVerificationToken(user, verificationResetPassword) {
+		// The verification token is present and hasn't expired, so do nothing.
+		return nil
 	}
 
 	// The verification token isn't present or is present but expired.
 
-	// Generate a new verification token and expiry.
-	token, expiry, err := b.generateVerificationTokenAndExpiry()
+	// Generate a new verification token.
+	token, err := b.issueVerificationToken(user, verificationResetPassword)
 	if err != nil {
 		return err
 	}
 
 	// Add the updated user information to the db.
-	user.ResetPasswordVerificationToken = token
-	user.ResetPasswordVerificationExpiry = expiry
 	err = b.db.UserUpdate(*user)
 	if err != nil {
 		return err
@@ -605,7 +884,7 @@ func (b *backend) emailResetPassword(user *database.User, rp www.ResetPassword,
 	}
 
 	// Only set the token if email verification is disabled.
-	if b.cfg.SMTP == nil {
+	if b.cfg.Mailer == nil {
 		rpr.VerificationToken = hex.EncodeToString(token)
 	}
 
@@ -613,31 +892,15 @@ func (b *backend) emailResetPassword(user *database.User, rp www.ResetPassword,
 }
 
 func (b *backend) verifyResetPassword(user *database.User, rp www.ResetPassword, rpr *www.ResetPasswordReply) error {
-	// Decode the verification token.
-	token, err := hex.DecodeString(rp.VerificationToken)
+	// Check the verification token.
+	err := verifyVerificationToken(user, verificationResetPassword,
+		rp.VerificationToken)
 	if err != nil {
-		return www.UserError{
-			ErrorCode: www.ErrorStatusVerificationTokenInvalid,
-		}
-	}
-
-	// Check that the verification token matches.
-	if !bytes.Equal(token, user.ResetPasswordVerificationToken) {
-		return www.UserError{
-			ErrorCode: www.ErrorStatusVerificationTokenInvalid,
-		}
-	}
-
-	// Check that the token hasn't expired.
-	currentTime := time.Now().Unix()
-	if currentTime > user.ResetPasswordVerificationExpiry {
-		return www.UserError{
-			ErrorCode: www.ErrorStatusVerificationTokenExpired,
-		}
+		return err
 	}
 
 	// Validate the new password.
-	err = b.validatePassword(rp.NewPassword)
+	err = b.validatePassword(rp.NewPassword, user.Email)
 	if err != nil {
 		return err
 	}
@@ -648,10 +911,10 @@ func (b *backend) verifyResetPassword(user *database.User, rp www.ResetPassword,
 		return err
 	}
 
-	// Clear out the verification token fields and set the new password in the db.
-	user.ResetPasswordVerificationToken = nil
-	user.ResetPasswordVerificationExpiry = 0
+	// Use up the verification token and set the new password in the db.
+	useVerificationToken(user, verificationResetPassword)
 	user.HashedPassword = hashedPassword
+	user.EmailUndeliverable = false
 
 	return b.db.UserUpdate(*user)
 }
@@ -659,9 +922,9 @@ func (b *backend) verifyResetPassword(user *database.User, rp www.ResetPassword,
 // loadInventory calls the politeaid RPC call to load the current inventory.
 // Note that this function fakes out the inventory during test and therefore
 // must be called WITH the lock held.
-func (b *backend) loadInventory() (*pd.InventoryReply, error) {
+func (b *backend) loadInventory(ctx context.Context) (*pd.InventoryReply, error) {
 	if !b.test {
-		return b.remoteInventory()
+		return b.remoteInventory(ctx)
 	}
 
 	// Following is test code only.
@@ -694,8 +957,18 @@ func (b *backend) CreateLoginReply(user *database.User) *www.LoginReply {
 	reply := www.LoginReply{
 		IsAdmin:   user.Admin,
 		UserID:    strconv.FormatUint(user.ID, 10),
+		UUID:      user.UUID,
 		Email:     user.Email,
 		PublicKey: activeIdentity,
+
+		EmailNotifications: user.EmailNotifications,
+		EmailDigest:        www.EmailDigestT(user.EmailDigest),
+		TOTPEnabled:        user.TOTPEnabled,
+
+		LastLogin:       user.LastLogin,
+		FailedLogins:    user.FailedLogins,
+		HasPaid:         b.VerifyUserPaid(user),
+		ProposalCredits: user.ProposalCredits,
 	}
 
 	if user.NewUserPaywallTx == "" {
@@ -709,7 +982,7 @@ func (b *backend) CreateLoginReply(user *database.User) *www.LoginReply {
 
 // LoadInventory fetches the entire inventory of proposals from politeiad and
 // caches it, sorted by most recent timestamp.
-func (b *backend) LoadInventory() error {
+func (b *backend) LoadInventory(ctx context.Context) error {
 	b.Lock()
 	defer b.Unlock()
 
@@ -718,7 +991,7 @@ func (b *backend) LoadInventory() error {
 	}
 
 	// Fetch remote inventory.
-	inv, err := b.loadInventory()
+	inv, err := b.loadInventory(ctx)
 	if err != nil {
 		return fmt.Errorf("LoadInventory: %v", err)
 	}
@@ -745,10 +1018,15 @@ func (b *backend) LoadInventory() error {
 func (b *backend) ProcessNewUser(u www.NewUser) (*www.NewUserReply, error) {
 	var reply www.NewUserReply
 	var token []byte
-	var expiry int64
 
 	// XXX this function really needs to be cleaned up.
 
+	// Check the proof of work before doing any other work.
+	err := b.verifySignupProofOfWork(u)
+	if err != nil {
+		return nil, err
+	}
+
 	// Ensure we got a proper pubkey.
 	var emptyPK [identity.PublicKeySize]byte
 	pk, err := hex.DecodeString(u.PublicKey)
@@ -767,31 +1045,29 @@ func (b *backend) ProcessNewUser(u www.NewUser) (*www.NewUserReply, error) {
 	// Check if the user already exists.
 	if user, err := b.db.UserGet(u.Email); err == nil {
 		// Check if the user is already verified.
-		if user.NewUserVerificationToken == nil {
+		if userVerified(user) {
 			return &reply, nil
 		}
 
 		// Check if the verification token hasn't expired yet.
-		if currentTime := time.Now().Unix(); currentTime < user.NewUserVerificationExpiry {
+		if pendingVerificationToken(user, verificationNewUser) {
 			return &reply, nil
 		}
 
-		// Generate a new verification token and expiry.
-		token, expiry, err = b.generateVerificationTokenAndExpiry()
+		// Generate a new verification token.
+		token, err = b.issueVerificationToken(user, verificationNewUser)
 		if err != nil {
 			return nil, err
 		}
 
 		// Add the updated user information to the db.
-		user.NewUserVerificationToken = token
-		user.NewUserVerificationExpiry = expiry
 		err = b.db.UserUpdate(*user)
 		if err != nil {
 			return nil, err
 		}
 	} else {
 		// Validate the password.
-		err = b.validatePassword(u.Password)
+		err = b.validatePassword(u.Password, u.Email)
 		if err != nil {
 			return nil, err
 		}
@@ -802,25 +1078,26 @@ func (b *backend) ProcessNewUser(u www.NewUser) (*www.NewUserReply, error) {
 			return nil, err
 		}
 
-		// Generate the verification token and expiry.
-		token, expiry, err = b.generateVerificationTokenAndExpiry()
-		if err != nil {
-			return nil, err
-		}
-
 		// Add the user and hashed password to the db.
 		newUser := database.User{
 			Email:          strings.ToLower(u.Email),
 			HashedPassword: hashedPassword,
 			Admin:          false,
-			NewUserVerificationToken:  token,
-			NewUserVerificationExpiry: expiry,
-			Identities: []database.Identity{{
-				Activated: time.Now().Unix(),
-			}},
+			EmailNotifications: uint64(
+				www.NotificationEmailMyProposalStatusChange),
+			// The key is activated once the user proves that
+			// they control it by signing the verification token.
+			Identities: []database.Identity{{}},
 		}
 		copy(newUser.Identities[0].Key[:], pk)
 
+		// Generate the verification token.
+		token, err = b.issueVerificationToken(&newUser,
+			verificationNewUser)
+		if err != nil {
+			return nil, err
+		}
+
 		err = b.db.UserNew(newUser)
 		if err != nil {
 			if err == database.ErrInvalidEmail {
@@ -847,7 +1124,8 @@ func (b *backend) ProcessNewUser(u www.NewUser) (*www.NewUserReply, error) {
 		// Derive a paywall address for this user if the paywall is enabled.
 		paywallAddress := ""
 		paywallAmount := uint64(0)
-		if b.cfg.PaywallXpub != "" {
+		if b.cfg.PaywallXpub != "" &&
+			b.featureEnabled(www.FeaturePaywall) {
 			paywallAddress, err = util.DerivePaywallAddress(b.params,
 				b.cfg.PaywallXpub, uint32(user.ID))
 			if err != nil {
@@ -882,12 +1160,69 @@ func (b *backend) ProcessNewUser(u www.NewUser) (*www.NewUserReply, error) {
 	}
 
 	// Only set the token if email verification is disabled.
-	if b.cfg.SMTP == nil {
+	if b.cfg.Mailer == nil {
 		reply.VerificationToken = hex.EncodeToString(token)
 	}
 	return &reply, nil
 }
 
+// ProcessResendVerification sends the verification email of the unverified
+// user with the provided email again.  A new token replaces the previous one
+// since only the digests of the tokens are stored.  A user is sent at most one
+// email per verificationResendCooldown.  Unknown, verified and deactivated users are
+// ignored so that the reply doesn't reveal which emails are registered.
+func (b *backend) ProcessResendVerification(rv www.ResendVerification) (*www.ResendVerificationReply, error) {
+	var reply www.ResendVerificationReply
+
+	user, err := b.db.UserGet(rv.Email)
+	if err == database.ErrUserNotFound {
+		return &reply, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if userVerified(user) || user.Deactivated != 0 {
+		return &reply, nil
+	}
+
+	key := "resendverification:" + user.Email
+	_, err = b.stateStore.Get(key)
+	if err == nil {
+		return &reply, nil
+	} else if err != errStateNotFound {
+		return nil, err
+	}
+	err = b.stateStore.Set(key, []byte{1}, verificationResendCooldown)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the digest of the previous token is known, so a new token
+	// replaces it.
+	t, err := b.issueVerificationToken(user, verificationNewUser)
+	if err != nil {
+		return nil, err
+	}
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(t)
+
+	if !b.test {
+		// This is conditional on the email server being setup.
+		err := b.emailNewUserVerificationLink(user.Email, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Only set the token if email verification is disabled.
+	if b.cfg.Mailer == nil {
+		reply.VerificationToken = token
+	}
+	return &reply, nil
+}
+
 // ProcessVerifyNewUser verifies the token generated for a recently created
 // user.  It ensures that the token matches with the input and that the token
 // hasn't expired.  On success it returns database user record.
@@ -903,26 +1238,11 @@ func (b *backend) ProcessVerifyNewUser(u www.VerifyNewUser) (*database.User, err
 		return nil, err
 	}
 
-	// Decode the verification token.
-	token, err := hex.DecodeString(u.VerificationToken)
+	// Check the verification token.
+	err = verifyVerificationToken(user, verificationNewUser,
+		u.VerificationToken)
 	if err != nil {
-		return nil, www.UserError{
-			ErrorCode: www.ErrorStatusVerificationTokenInvalid,
-		}
-	}
-
-	// Check that the verification token matches.
-	if !bytes.Equal(token, user.NewUserVerificationToken) {
-		return nil, www.UserError{
-			ErrorCode: www.ErrorStatusVerificationTokenInvalid,
-		}
-	}
-
-	// Check that the token hasn't expired.
-	if currentTime := time.Now().Unix(); currentTime > user.NewUserVerificationExpiry {
-		return nil, www.UserError{
-			ErrorCode: www.ErrorStatusVerificationTokenExpired,
-		}
+		return nil, err
 	}
 
 	// Check signature
@@ -933,7 +1253,8 @@ func (b *backend) ProcessVerifyNewUser(u www.VerifyNewUser) (*database.User, err
 		}
 	}
 	var pi *identity.PublicIdentity
-	for _, v := range user.Identities {
+	index := -1
+	for k, v := range user.Identities {
 		if v.Deactivated != 0 {
 			continue
 		}
@@ -941,6 +1262,7 @@ func (b *backend) ProcessVerifyNewUser(u www.VerifyNewUser) (*database.User, err
 		if err != nil {
 			return nil, err
 		}
+		index = k
 	}
 	if pi == nil {
 		return nil, www.UserError{
@@ -953,9 +1275,13 @@ func (b *backend) ProcessVerifyNewUser(u www.VerifyNewUser) (*database.User, err
 		}
 	}
 
-	// Clear out the verification token fields in the db.
-	user.NewUserVerificationToken = nil
-	user.NewUserVerificationExpiry = 0
+	// Use up the verification token and activate the key that signed
+	// it.
+	useVerificationToken(user, verificationNewUser)
+	user.EmailUndeliverable = false
+	if user.Identities[index].Activated == 0 {
+		user.Identities[index].Activated = time.Now().Unix()
+	}
 	return user, b.db.UserUpdate(*user)
 }
 
@@ -964,8 +1290,6 @@ func (b *backend) ProcessVerifyNewUser(u www.VerifyNewUser) (*database.User, err
 // token is already set and is expired, it generates a new one.
 func (b *backend) ProcessUpdateUserKey(user *database.User, u www.UpdateUserKey) (*www.UpdateUserKeyReply, error) {
 	var reply www.UpdateUserKeyReply
-	var token []byte
-	var expiry int64
 
 	// Ensure we have a proper pubkey.
 	var emptyPK [identity.PublicKeySize]byte
@@ -983,22 +1307,17 @@ func (b *backend) ProcessUpdateUserKey(user *database.User, u www.UpdateUserKey)
 	}
 
 	// Check if the verification token hasn't expired yet.
-	if user.UpdateKeyVerificationToken != nil {
-		if currentTime := time.Now().Unix(); currentTime < user.UpdateKeyVerificationExpiry {
-			return &reply, nil
-		}
+	if pendingVerificationToken(user, verificationUpdateKey) {
+		return &reply, nil
 	}
 
-	// Generate a new verification token and expiry.
-	token, expiry, err = b.generateVerificationTokenAndExpiry()
+	// Generate a new verification token.
+	token, err := b.issueVerificationToken(user, verificationUpdateKey)
 	if err != nil {
 		return nil, err
 	}
 
 	// Add the updated user information to the db.
-	user.UpdateKeyVerificationToken = token
-	user.UpdateKeyVerificationExpiry = expiry
-
 	identity := database.Identity{}
 	copy(identity.Key[:], pk)
 	user.Identities = append(user.Identities, identity)
@@ -1018,7 +1337,7 @@ func (b *backend) ProcessUpdateUserKey(user *database.User, u www.UpdateUserKey)
 	}
 
 	// Only set the token if email verification is disabled.
-	if b.cfg.SMTP == nil {
+	if b.cfg.Mailer == nil {
 		reply.VerificationToken = hex.EncodeToString(token)
 	}
 	return &reply, nil
@@ -1028,26 +1347,11 @@ func (b *backend) ProcessUpdateUserKey(user *database.User, u www.UpdateUserKey)
 // generated key pair. It ensures that the token matches with the input and
 // that the token hasn't expired.
 func (b *backend) ProcessVerifyUpdateUserKey(user *database.User, vu www.VerifyUpdateUserKey) (*database.User, error) {
-	// Decode the verification token.
-	token, err := hex.DecodeString(vu.VerificationToken)
+	// Check the verification token.
+	err := verifyVerificationToken(user, verificationUpdateKey,
+		vu.VerificationToken)
 	if err != nil {
-		return nil, www.UserError{
-			ErrorCode: www.ErrorStatusVerificationTokenInvalid,
-		}
-	}
-
-	// Check that the verification token matches.
-	if !bytes.Equal(token, user.UpdateKeyVerificationToken) {
-		return nil, www.UserError{
-			ErrorCode: www.ErrorStatusVerificationTokenInvalid,
-		}
-	}
-
-	// Check that the token hasn't expired.
-	if currentTime := time.Now().Unix(); currentTime > user.UpdateKeyVerificationExpiry {
-		return nil, www.UserError{
-			ErrorCode: www.ErrorStatusVerificationTokenExpired,
-		}
+		return nil, err
 	}
 
 	// Check signature
@@ -1073,16 +1377,16 @@ func (b *backend) ProcessVerifyUpdateUserKey(user *database.User, vu www.VerifyU
 	// Associate the user id with the new public key.
 	b.setUserPubkeyAssociaton(user, pi.String())
 
-	// Clear out the verification token fields in the db and activate
-	// the key and deactivate the one it's replacing.
-	user.UpdateKeyVerificationToken = nil
-	user.UpdateKeyVerificationExpiry = 0
+	// Use up the verification token and activate the key and deactivate
+	// the ones it's replacing.  Deactivated keys are kept so that the
+	// signatures they made still verify.
+	useVerificationToken(user, verificationUpdateKey)
+	user.EmailUndeliverable = false
 
 	t := time.Now().Unix()
 	for k, v := range user.Identities {
-		if v.Deactivated == 0 {
+		if v.Activated != 0 && v.Deactivated == 0 {
 			user.Identities[k].Deactivated = t
-			break
 		}
 	}
 	user.Identities[len(user.Identities)-1].Activated = t
@@ -1105,24 +1409,53 @@ func (b *backend) ProcessLogin(l www.Login) (*www.LoginReply, error) {
 	}
 
 	// Check that the user is verified.
-	if user.NewUserVerificationToken != nil {
+	if !userVerified(user) {
 		return nil, www.UserError{
 			ErrorCode: www.ErrorStatusInvalidEmailOrPassword,
 		}
 	}
 
 	// Check the user's password.
-	err = bcrypt.CompareHashAndPassword(user.HashedPassword,
-		[]byte(l.Password))
+	err = b.verifyPassword(user, l.Password)
 	if err != nil {
+		if _, ok := err.(www.UserError); ok {
+			b.recordFailedLogin(user)
+		}
+		return nil, err
+	}
+
+	if user.Deactivated != 0 {
 		return nil, www.UserError{
-			ErrorCode: www.ErrorStatusInvalidEmailOrPassword,
+			ErrorCode: www.ErrorStatusUserDeactivated,
 		}
 	}
 
+	// Check the second factor once the password is known to be correct.
+	err = b.verifyTOTP(user, l.Code)
+	if err != nil {
+		if e, ok := err.(www.UserError); ok &&
+			e.ErrorCode == www.ErrorStatusInvalidTOTPCode {
+			b.recordFailedLogin(user)
+		}
+		return nil, err
+	}
+
+	// Hash the password again if the hashing parameters changed.
+	b.rehashPassword(user, l.Password)
+
 	return b.CreateLoginReply(user), nil
 }
 
+// recordFailedLogin counts a failed login of the provided user.  Failures are
+// only logged so that the login fails with the original error.
+func (b *backend) recordFailedLogin(user *database.User) {
+	user.FailedLogins++
+	err := b.db.UserUpdate(*user)
+	if err != nil {
+		log.Errorf("recordFailedLogin %v: %v", user.ID, err)
+	}
+}
+
 // ProcessChangePassword checks that the current password matches the one
 // in the database, then changes it to the new password.
 func (b *backend) ProcessChangePassword(email string, cp www.ChangePassword) (*www.ChangePasswordReply, error) {
@@ -1135,16 +1468,13 @@ func (b *backend) ProcessChangePassword(email string, cp www.ChangePassword) (*w
 	}
 
 	// Check the user's password.
-	err = bcrypt.CompareHashAndPassword(user.HashedPassword,
-		[]byte(cp.CurrentPassword))
+	err = b.verifyPassword(user, cp.CurrentPassword)
 	if err != nil {
-		return nil, www.UserError{
-			ErrorCode: www.ErrorStatusInvalidEmailOrPassword,
-		}
+		return nil, err
 	}
 
 	// Validate the new password.
-	err = b.validatePassword(cp.NewPassword)
+	err = b.validatePassword(cp.NewPassword, email)
 	if err != nil {
 		return nil, err
 	}
@@ -1165,8 +1495,180 @@ func (b *backend) ProcessChangePassword(email string, cp www.ChangePassword) (*w
 	return &reply, nil
 }
 
-// ProcessResetPassword is intended to be called twice; in the first call, an
-// email is provided and the function checks if the user exists. If the user exists, it
+// ProcessChangeEmail sets a verification token and expiry that allow the
+// user to replace its email address with the provided one, and emails the
+// token to the new address.  The current email address remains in use until
+// the new one is verified.
+func (b *backend) ProcessChangeEmail(user *database.User, ce www.ChangeEmail) (*www.ChangeEmailReply, error) {
+	var reply www.ChangeEmailReply
+
+	// Check the user's password.
+	err := b.verifyPassword(user, ce.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the new email address.
+	newEmail := strings.ToLower(ce.NewEmail)
+	if newEmail == user.Email {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+	if err := checkmail.ValidateFormat(newEmail); err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusMalformedEmail,
+		}
+	}
+	_, err = b.db.UserGet(newEmail)
+	if err == nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusDuplicateEmail,
+		}
+	} else if err != database.ErrUserNotFound {
+		return nil, err
+	}
+
+	// Generate a new verification token.
+	token, err := b.issueVerificationToken(user, verificationChangeEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the updated user information to the db.
+	user.NewEmail = newEmail
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	if !b.test {
+		// This is conditional on the email server being setup.
+		err := b.emailChangeEmailVerificationLink(newEmail,
+			hex.EncodeToString(token))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Only set the token if email verification is disabled.
+	if b.cfg.Mailer == nil {
+		reply.VerificationToken = hex.EncodeToString(token)
+	}
+	return &reply, nil
+}
+
+// ProcessVerifyChangeEmail verifies the token that was emailed to the new
+// email address of the user and replaces the email address of the user with
+// it.  The change is recorded in the user's email history.
+func (b *backend) ProcessVerifyChangeEmail(user *database.User, vce www.VerifyChangeEmail) (*database.User, error) {
+	// Check the verification token.
+	err := verifyVerificationToken(user, verificationChangeEmail,
+		vce.VerificationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replace the email address and use up the verification token.  The
+	// new address was just proven to be deliverable.
+	oldEmail := user.Email
+	user.EmailChanges = append(user.EmailChanges, database.EmailChange{
+		OldEmail:  oldEmail,
+		NewEmail:  user.NewEmail,
+		Timestamp: time.Now().Unix(),
+	})
+	user.Email = user.NewEmail
+	user.NewEmail = ""
+	useVerificationToken(user, verificationChangeEmail)
+	user.EmailUndeliverable = false
+
+	err = b.db.UserChangeEmail(oldEmail, *user)
+	if err == database.ErrUserExists {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusDuplicateEmail,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	log.Infof("User %v changed email from %v to %v", user.ID, oldEmail,
+		user.Email)
+
+	return user, nil
+}
+
+// ProcessEditUser updates the settings of the provided user.
+func (b *backend) ProcessEditUser(user *database.User, eu www.EditUser) (*www.EditUserReply, error) {
+	var reply www.EditUserReply
+
+	if eu.EmailNotifications != nil {
+		user.EmailNotifications = *eu.EmailNotifications
+	}
+	if eu.EmailDigest != nil {
+		switch *eu.EmailDigest {
+		case www.EmailDigestNone, www.EmailDigestDaily,
+			www.EmailDigestWeekly:
+		default:
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			}
+		}
+		user.EmailDigest = int(*eu.EmailDigest)
+	}
+
+	err := b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reply, nil
+}
+
+// ProcessUnsubscribe unsubscribes a user from an email notification, or from
+// the email digest if the notification is 0.  The request must carry a valid
+// signature of an unsubscribe link.
+func (b *backend) ProcessUnsubscribe(u www.Unsubscribe) (*www.UnsubscribeReply, error) {
+	var reply www.UnsubscribeReply
+
+	expected := b.unsubscribeSignature(u.UserID, u.Notification)
+	if !hmac.Equal([]byte(expected), []byte(u.Signature)) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidSignature,
+		}
+	}
+
+	userID, err := strconv.ParseUint(u.UserID, 10, 64)
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+	user, err := b.db.UserGetById(userID)
+	if err != nil {
+		if err == database.ErrUserNotFound {
+			err = www.UserError{
+				ErrorCode: www.ErrorStatusUserNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	if u.Notification == 0 {
+		user.EmailDigest = int(www.EmailDigestNone)
+	} else {
+		user.EmailNotifications &^= u.Notification
+	}
+
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reply, nil
+}
+
+// ProcessResetPassword is intended to be called twice; in the first call, an
+// email is provided and the function checks if the user exists. If the user exists, it
 // generates a verification token and stores it in the database. In the second
 // call, the email, verification token and a new password are provided. If everything
 // matches, then the user's password is updated in the database.
@@ -1187,6 +1689,11 @@ func (b *backend) ProcessResetPassword(rp www.ResetPassword) (*www.ResetPassword
 		return nil, err
 	}
 
+	// Deactivated users are treated like unknown users.
+	if user.Deactivated != 0 {
+		return &reply, nil
+	}
+
 	if rp.VerificationToken == "" {
 		err = b.emailResetPassword(user, rp, &reply)
 	} else {
@@ -1200,39 +1707,139 @@ func (b *backend) ProcessResetPassword(rp www.ResetPassword) (*www.ResetPassword
 	return &reply, nil
 }
 
-// ProcessAllVetted returns an array of vetted proposals. The maximum number
-// of proposals returned is dictated by www.ProposalListPageSize.
-func (b *backend) ProcessAllVetted(v www.GetAllVetted) *www.GetAllVettedReply {
+// ProcessAllVetted returns a page of vetted proposals in the requested order,
+// newest first by default. The maximum number of proposals returned is
+// dictated by www.ProposalListPageSize.
+func (b *backend) ProcessAllVetted(ctx context.Context, v www.GetAllVetted) (*www.GetAllVettedReply, error) {
+	var bestBlock uint64
+	switch v.Sort {
+	case "", www.ProposalSortNewest, www.ProposalSortOldest,
+		www.ProposalSortMostCommented, www.ProposalSortMostViewed:
+	case www.ProposalSortVoteEnding:
+		if !b.test {
+			var err error
+			bestBlock, err = b.getBestBlock(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, www.UserError{
+			ErrorCode:    www.ErrorStatusInvalidInput,
+			ErrorContext: []string{v.Sort},
+		}
+	}
+
+	proposals, total := b.getProposals(proposalsRequest{
+		After:  v.After,
+		Before: v.Before,
+		StatusMap: filterStatuses(map[www.PropStatusT]bool{
+			www.PropStatusPublic: true,
+		}, v.Status),
+		SubmittedAfter:  v.SubmittedAfter,
+		SubmittedBefore: v.SubmittedBefore,
+		Tag:             strings.ToLower(v.Tag),
+		Sort:            v.Sort,
+		BestBlock:       bestBlock,
+	})
 	return &www.GetAllVettedReply{
-		Proposals: b.getProposals(proposalsRequest{
-			After:  v.After,
-			Before: v.Before,
-			StatusMap: map[www.PropStatusT]bool{
-				www.PropStatusPublic: true,
-			},
-		}),
-	}
+		Proposals: proposals,
+		Total:     uint(total),
+	}, nil
 }
 
-// ProcessAllUnvetted returns an array of all unvetted proposals in reverse order,
-// because they're sorted by oldest timestamp first.
-func (b *backend) ProcessAllUnvetted(u www.GetAllUnvetted) *www.GetAllUnvettedReply {
-	return &www.GetAllUnvettedReply{
-		Proposals: b.getProposals(proposalsRequest{
-			After:  u.After,
-			Before: u.Before,
-			StatusMap: map[www.PropStatusT]bool{
-				www.PropStatusNotReviewed: true,
-				www.PropStatusCensored:    true,
-			},
-		}),
+// ProcessAllUnvetted returns a page of unvetted proposals, newest first. The
+// maximum number of proposals returned is dictated by
+// www.ProposalListPageSize.  Admins list every unvetted proposal; other users
+// only list the metadata of their own.
+func (b *backend) ProcessAllUnvetted(u www.GetAllUnvetted, user *database.User) (*www.GetAllUnvettedReply, error) {
+	if user == nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusNotLoggedIn,
+		}
+	}
+
+	var userID string
+	if !user.Admin {
+		userID = strconv.FormatUint(user.ID, 10)
+	}
+	proposals, total := b.getProposals(proposalsRequest{
+		After:  u.After,
+		Before: u.Before,
+		UserId: userID,
+		StatusMap: filterStatuses(map[www.PropStatusT]bool{
+			www.PropStatusNotReviewed: true,
+			www.PropStatusCensored:    true,
+		}, u.Status),
+		SubmittedAfter:  u.SubmittedAfter,
+		SubmittedBefore: u.SubmittedBefore,
+		Tag:             strings.ToLower(u.Tag),
+	})
+
+	if !user.Admin {
+		for k, v := range proposals {
+			proposals[k] = unvettedProposalMetadata(v, true)
+		}
+		return &www.GetAllUnvettedReply{
+			Proposals: proposals,
+			Total:     uint(total),
+		}, nil
+	}
+
+	// Only admins learn why proposals were flagged and when they are
+	// scheduled to be published.
+	publishAt, err := b.scheduledPublications()
+	if err != nil {
+		return nil, err
+	}
+	b.RLock()
+	for k, v := range proposals {
+		proposals[k].PublishAt = publishAt[v.CensorshipRecord.Token]
+		ir, ok := b.inventory[v.CensorshipRecord.Token]
+		if !ok {
+			continue
+		}
+		if spam := recordSpam(ir.record); spam != nil {
+			proposals[k].SpamScore = spam.Score
+			proposals[k].SpamReasons = spam.Reasons
+		}
 	}
+	b.RUnlock()
+
+	return &www.GetAllUnvettedReply{
+		Proposals: proposals,
+		Total:     uint(total),
+	}, nil
+}
+
+// unvettedProposalMetadata returns the metadata of the provided unvetted
+// proposal that is viewable by non-admins, such as its status and censorship
+// record.  The name is only viewable by the author.
+func unvettedProposalMetadata(p www.ProposalRecord, isAuthor bool) www.ProposalRecord {
+	metadata := www.ProposalRecord{
+		Status:           p.Status,
+		Timestamp:        p.Timestamp,
+		PublicKey:        p.PublicKey,
+		Signature:        p.Signature,
+		CensorshipRecord: p.CensorshipRecord,
+		NumComments:      p.NumComments,
+	}
+	if isAuthor {
+		metadata.Name = p.Name
+	}
+	return metadata
 }
 
 // ProcessNewProposal tries to submit a new proposal to politeiad.
-func (b *backend) ProcessNewProposal(np www.NewProposal, user *database.User) (*www.NewProposalReply, error) {
+func (b *backend) ProcessNewProposal(ctx context.Context, np www.NewProposal, user *database.User) (*www.NewProposalReply, error) {
 	log.Tracef("ProcessNewProposal")
 
+	if user.Deactivated != 0 {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusUserDeactivated,
+		}
+	}
+
 	if !b.VerifyUserPaid(user) {
 		return nil, www.UserError{
 			ErrorCode: www.ErrorStatusUserNotPaid,
@@ -1244,6 +1851,50 @@ func (b *backend) ProcessNewProposal(np www.NewProposal, user *database.User) (*
 		return nil, err
 	}
 
+	// Suspicious proposals are either held for admin review or submitted
+	// along with their spam score.
+	var spam *BackendProposalSpam
+	score, reasons := b.spamScore(np)
+	switch {
+	case b.cfg.SpamHoldScore > 0 && score >= b.cfg.SpamHoldScore:
+		return nil, b.holdProposal(np, user, score, reasons)
+	case b.cfg.SpamFlagScore > 0 && score >= b.cfg.SpamFlagScore:
+		spam = &BackendProposalSpam{
+			Version: BackendProposalSpamVersion,
+			Score:   score,
+			Reasons: reasons,
+		}
+	}
+
+	return b.submitProposal(ctx, np, user, spam, false)
+}
+
+// submitProposal submits a validated proposal of the provided user to
+// politeiad, spending a proposal credit unless the credit was already spent
+// when the proposal was held.  Proposals that the spam filters flagged carry
+// their spam score.
+func (b *backend) submitProposal(ctx context.Context, np www.NewProposal, user *database.User, spam *BackendProposalSpam, creditSpent bool) (*www.NewProposalReply, error) {
+	// Spend the proposal credit up front so that concurrent submissions
+	// can't overdraw the balance.  It is refunded if the submission fails.
+	var (
+		spent bool
+		err   error
+	)
+	if !creditSpent {
+		spent, err = b.spendProposalCredit(user)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var submitted bool
+	if spent {
+		defer func() {
+			if !submitted {
+				b.refundProposalCredit(user)
+			}
+		}()
+	}
+
 	var reply www.NewProposalReply
 	challenge, err := util.Random(pd.ChallengeSize)
 	if err != nil {
@@ -1255,8 +1906,23 @@ func (b *backend) ProcessNewProposal(np www.NewProposal, user *database.User) (*
 		return nil, err
 	}
 
-	// Assemble metdata record
+	tags, err := normalizeProposalTags(np.Tags)
+	if err != nil {
+		return nil, err
+	}
+
 	ts := time.Now().Unix()
+	err = validateProposalTimeline(np.Timeline, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := proposalPages(np.Files, np.Pages)
+	if err != nil {
+		return nil, err
+	}
+
+	// Assemble metdata record
 	md, err := encodeBackendProposalMetadata(BackendProposalMetadata{
 		Version:   BackendProposalMetadataVersion,
 		Timestamp: ts,
@@ -1267,15 +1933,65 @@ func (b *backend) ProcessNewProposal(np www.NewProposal, user *database.User) (*
 	if err != nil {
 		return nil, err
 	}
+	author, err := encodeBackendProposalAuthor(BackendProposalAuthor{
+		Version:   BackendProposalAuthorVersion,
+		UserID:    strconv.FormatUint(user.ID, 10),
+		PublicKey: np.PublicKey,
+		Timestamp: ts,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	n := pd.NewRecord{
 		Challenge: hex.EncodeToString(challenge),
 		Metadata: []pd.MetadataStream{{
 			ID:      mdStreamGeneral,
 			Payload: string(md),
+		}, {
+			ID:      mdStreamAuthor,
+			Payload: string(author),
 		}},
 		Files: convertPropFilesFromWWW(np.Files),
 	}
+	if len(tags) != 0 {
+		ms, err := proposalTagsStream(tags)
+		if err != nil {
+			return nil, err
+		}
+		n.Metadata = append(n.Metadata, *ms)
+	}
+	if np.Timeline != nil && *np.Timeline != (www.ProposalTimeline{}) {
+		ms, err := proposalTimelineStream(*np.Timeline, ts)
+		if err != nil {
+			return nil, err
+		}
+		n.Metadata = append(n.Metadata, *ms)
+	}
+	if len(pages) > 1 {
+		ms, err := proposalPagesStream(pages, ts)
+		if err != nil {
+			return nil, err
+		}
+		n.Metadata = append(n.Metadata, *ms)
+	}
+	if len(np.CoAuthors) != 0 {
+		ms, err := b.proposalCoAuthorsStream(np.CoAuthors, ts)
+		if err != nil {
+			return nil, err
+		}
+		n.Metadata = append(n.Metadata, *ms)
+	}
+	if spam != nil {
+		md, err := encodeBackendProposalSpam(*spam)
+		if err != nil {
+			return nil, err
+		}
+		n.Metadata = append(n.Metadata, pd.MetadataStream{
+			ID:      mdStreamSpam,
+			Payload: string(md),
+		})
+	}
 
 	var pdReply pd.NewRecordReply
 	if b.test {
@@ -1302,7 +2018,7 @@ func (b *backend) ProcessNewProposal(np www.NewProposal, user *database.User) (*
 		}
 		b.Unlock()
 	} else {
-		responseBody, err := b.makeRequest(http.MethodPost,
+		responseBody, err := b.makeRequest(ctx, http.MethodPost,
 			pd.NewRecordRoute, n)
 		if err != nil {
 			return nil, err
@@ -1335,106 +2051,524 @@ func (b *backend) ProcessNewProposal(np www.NewProposal, user *database.User) (*
 			Files:            n.Files,
 		})
 		b.Unlock()
+		b.publish(clusterEvent{
+			Type:  clusterEventInventory,
+			Token: pdReply.CensorshipRecord.Token,
+		})
+
+		// Let the admins know that there is a new proposal to review.
+		err = b.notifyAdminsNewProposal(user, name,
+			pdReply.CensorshipRecord.Token)
+		if err != nil {
+			log.Errorf("notifyAdminsNewProposal %v: %v",
+				pdReply.CensorshipRecord.Token, err)
+		}
 	}
 
+	b.emitWebhook(www.WebhookEventProposalSubmitted,
+		pdReply.CensorshipRecord.Token, www.PropStatusNotReviewed, "")
+
+	submitted = true
 	reply.CensorshipRecord = convertPropCensorFromPD(pdReply.CensorshipRecord)
 	return &reply, nil
 }
 
-// ProcessSetProposalStatus changes the status of an existing proposal
-// from unreviewed to either published or censored.
-func (b *backend) ProcessSetProposalStatus(sps www.SetProposalStatus, user *database.User) (*www.SetProposalStatusReply, error) {
-	err := checkPublicKeyAndSignature(user, sps.PublicKey, sps.Signature,
-		sps.Token, strconv.FormatUint(uint64(sps.ProposalStatus), 10))
+// getUnvettedRecord fetches the unvetted record with the provided token,
+// including its files, from politeiad.
+func (b *backend) getUnvettedRecord(ctx context.Context, token string) (*pd.Record, error) {
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := b.makeRequest(ctx, http.MethodPost,
+		pd.GetUnvettedRoute, pd.GetUnvetted{
+			Token:     token,
+			Challenge: hex.EncodeToString(challenge),
+		})
 	if err != nil {
 		return nil, err
 	}
 
-	// Create change record
-	newStatus := convertPropStatusFromWWW(sps.ProposalStatus)
-	r := MDStreamChanges{
-		Timestamp: time.Now().Unix(),
-		NewStatus: newStatus,
+	var reply pd.GetUnvettedReply
+	err = json.Unmarshal(responseBody, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal "+
+			"GetUnvettedReply: %v", err)
+	}
+	err = util.VerifyChallenge(b.cfg.Identity, challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Record.Status == pd.RecordStatusNotFound {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
 	}
 
-	blob, err := json.Marshal(r)
+	return &reply.Record, nil
+}
+
+// ProcessEditProposal replaces the files of a proposal that has not been
+// reviewed yet with a new version.  Only the author of the proposal can edit
+// it.  The files are validated like the files of a new proposal.
+func (b *backend) ProcessEditProposal(ctx context.Context, ep www.EditProposal, user *database.User) (*www.EditProposalReply, error) {
+	log.Tracef("ProcessEditProposal: %v", ep.Token)
+
+	if user.Deactivated != 0 {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusUserDeactivated,
+		}
+	}
+
+	b.RLock()
+	ir, ok := b.inventory[ep.Token]
+	if !ok {
+		b.RUnlock()
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+	cachedProposal := convertPropFromInventoryRecord(ir, b.userPubkeys)
+	oldFiles := ir.record.Files
+	censorship := ir.record.CensorshipRecord
+	b.RUnlock()
+
+	if cachedProposal.UserId != strconv.FormatUint(user.ID, 10) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusUserNotAuthor,
+		}
+	}
+	// politeiad can only update the files of unvetted records.
+	if cachedProposal.Status != www.PropStatusNotReviewed {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusWrongStatus,
+		}
+	}
+
+	err := b.validateProposal(www.NewProposal{
+		Files:     ep.Files,
+		PublicKey: ep.PublicKey,
+		Signature: ep.Signature,
+		Pages:     ep.Pages,
+		CoAuthors: ep.CoAuthors,
+	}, user)
 	if err != nil {
 		return nil, err
 	}
 
-	var reply www.SetProposalStatusReply
-	var pdReply pd.SetUnvettedStatusReply
+	pages, err := proposalPages(ep.Files, ep.Pages)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := getProposalName(ep.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	// Assemble metdata record
+	ts := time.Now().Unix()
+	md, err := encodeBackendProposalMetadata(BackendProposalMetadata{
+		Version:         BackendProposalMetadataVersion,
+		Timestamp:       ts,
+		Name:            name,
+		PublicKey:       ep.PublicKey,
+		Signature:       ep.Signature,
+		ProposalVersion: cachedProposal.Version + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	mdGeneral := pd.MetadataStream{
+		ID:      mdStreamGeneral,
+		Payload: string(md),
+	}
+
+	// The page order is always overwritten so that the order of the
+	// previous version doesn't outlive its files.
+	mdPages, err := proposalPagesStream(pages, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	// So are the co-authors, whose signatures only cover the files that
+	// they signed.
+	mdCoAuthors, err := b.proposalCoAuthorsStream(ep.CoAuthors, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The files that are not part of the new version are deleted.  The
+	// inventory doesn't hold the files of the records that were loaded
+	// from politeiad.
+	if !b.test {
+		record, err := b.getUnvettedRecord(ctx, ep.Token)
+		if err != nil {
+			return nil, err
+		}
+		oldFiles = record.Files
+	}
+	files := convertPropFilesFromWWW(ep.Files)
+	keep := make(map[string]bool, len(files))
+	for _, v := range files {
+		keep[v.Name] = true
+	}
+	var filesDel []string
+	for _, v := range oldFiles {
+		if !keep[v.Name] {
+			filesDel = append(filesDel, v.Name)
+		}
+	}
+
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	overwrite := []pd.MetadataStream{mdGeneral, *mdPages, *mdCoAuthors}
+	uu := pd.UpdateUnvetted{
+		Challenge:   hex.EncodeToString(challenge),
+		Token:       ep.Token,
+		MDOverwrite: overwrite,
+		FilesDel:    filesDel,
+		FilesAdd:    files,
+	}
+
+	var pdReply pd.UpdateUnvettedReply
+	status := pd.RecordStatusUnreviewedChanges
 	if b.test {
-		pdReply.Record.Status = convertPropStatusFromWWW(sps.ProposalStatus)
+		pdReply.CensorshipRecord = censorship
+		status = pd.RecordStatusNotReviewed
 	} else {
-		// XXX Expensive to lock but do it for now.
-		// Lock is needed to prevent a race into this record and it
-		// needs to be updated in the cache.
-		b.Lock()
-		defer b.Unlock()
-
-		// Flush comments while here, we really should make the
-		// comments flow with the SetUnvettedStatus command but for now
-		// do it separately.
-		err := b.flushCommentJournal(sps.Token)
-		if err != nil && !os.IsNotExist(err) {
+		responseBody, err := b.makeRequest(ctx, http.MethodPost,
+			pd.UpdateUnvettedRoute, uu)
+		if err != nil {
 			return nil, err
 		}
 
-		challenge, err := util.Random(pd.ChallengeSize)
+		err = json.Unmarshal(responseBody, &pdReply)
+		if err != nil {
+			return nil, fmt.Errorf("Unmarshal UpdateUnvettedReply: %v",
+				err)
+		}
+
+		// Verify the challenge.
+		err = util.VerifyChallenge(b.cfg.Identity, challenge,
+			pdReply.Response)
 		if err != nil {
 			return nil, err
 		}
 
-		var ok bool
-		r.AdminPubKey, ok = database.ActiveIdentityString(user.Identities)
-		if !ok {
-			return nil, fmt.Errorf("invalid admin identity: %v",
-				user.ID)
+		log.Infof("Edited proposal %v: version %v", ep.Token,
+			cachedProposal.Version+1)
+		for k, f := range files {
+			log.Infof("%02v: %v %v", k, f.Name, f.Digest)
 		}
+	}
 
-		sus := pd.SetUnvettedStatus{
-			Token:     sps.Token,
-			Status:    newStatus,
-			Challenge: hex.EncodeToString(challenge),
-			MDAppend: []pd.MetadataStream{
-				{
-					ID:      mdStreamChanges,
-					Payload: string(blob),
-				},
-			},
+	// Replace the proposal in the inventory cache.
+	b.Lock()
+	ir, ok = b.inventory[ep.Token]
+	if !ok {
+		b.Unlock()
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+	metadata := overwrite
+	for _, v := range ir.record.Metadata {
+		switch v.ID {
+		case mdStreamGeneral, mdStreamPages, mdStreamCoAuthors:
+		default:
+			metadata = append(metadata, v)
+		}
+	}
+	ir.record.Status = status
+	ir.record.Timestamp = ts
+	ir.record.CensorshipRecord = pdReply.CensorshipRecord
+	ir.record.Metadata = metadata
+	ir.record.Files = files
+	b.indexUserStats(ep.Token)
+	reply := www.EditProposalReply{
+		Proposal: convertPropFromInventoryRecord(ir, b.userPubkeys),
+	}
+	b.Unlock()
+
+	b.publish(clusterEvent{
+		Type:  clusterEventInventory,
+		Token: ep.Token,
+	})
+
+	return &reply, nil
+}
+
+// ProcessSetProposalStatus changes the status of an existing proposal
+// from unreviewed to either published or censored.  Censorships await the
+// approval of another admin if the server requires it.
+func (b *backend) ProcessSetProposalStatus(ctx context.Context, sps www.SetProposalStatus, user *database.User) (*www.SetProposalStatusReply, error) {
+	elements := []string{sps.Token,
+		strconv.FormatUint(uint64(sps.ProposalStatus), 10),
+		sps.StatusChangeMessage}
+	if sps.PublishAt != 0 {
+		elements = append(elements, strconv.FormatInt(sps.PublishAt, 10))
+	}
+	err := checkPublicKeyAndSignature(user, sps.PublicKey, sps.Signature,
+		elements...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Censorships must be explained to the author and the public.
+	if sps.ProposalStatus == www.PropStatusCensored &&
+		strings.TrimSpace(sps.StatusChangeMessage) == "" {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusChangeMessageCannotBeBlank,
+		}
+	}
+
+	// Publications may be scheduled for later.
+	if sps.PublishAt != 0 {
+		return b.schedulePublication(sps, user)
+	}
+
+	if sps.ProposalStatus == www.PropStatusCensored &&
+		b.approvalRequired() {
+		b.RLock()
+		ir, ok := b.inventory[sps.Token]
+		b.RUnlock()
+		if !ok {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}
 		}
 
-		responseBody, err := b.makeRequest(http.MethodPost,
-			pd.SetUnvettedStatusRoute, sus)
+		pa, err := b.newPendingAction(www.PendingActionCensorProposal,
+			sps, user)
 		if err != nil {
 			return nil, err
 		}
+		return &www.SetProposalStatusReply{
+			Proposal:      convertPropFromPD(ir.record),
+			PendingAction: pa,
+		}, nil
+	}
 
-		err = json.Unmarshal(responseBody, &pdReply)
-		if err != nil {
-			return nil, fmt.Errorf("Could not unmarshal SetUnvettedStatusReply: %v",
-				err)
+	return b.setProposalStatus(ctx, sps, user)
+}
+
+// setProposalStatus forwards the status change of a proposal, which was signed
+// by the provided admin, to politeiad and records it in the status history.
+func (b *backend) setProposalStatus(ctx context.Context, sps www.SetProposalStatus, user *database.User) (*www.SetProposalStatusReply, error) {
+	// Look up the current status for the status history.
+	b.RLock()
+	ir, ok := b.inventory[sps.Token]
+	b.RUnlock()
+	if !ok {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
 		}
+	}
+	oldStatus := convertPropStatusFromPD(ir.record.Status)
 
-		// Verify the challenge.
-		err = util.VerifyChallenge(b.cfg.Identity, challenge, pdReply.Response)
+	adminPubKey, ok := database.ActiveIdentityString(user.Identities)
+	if !ok {
+		return nil, fmt.Errorf("invalid admin identity: %v", user.ID)
+	}
+
+	// Create change record
+	newStatus := convertPropStatusFromWWW(sps.ProposalStatus)
+	r := MDStreamChanges{
+		AdminPubKey:         adminPubKey,
+		Timestamp:           time.Now().Unix(),
+		NewStatus:           newStatus,
+		StatusChangeMessage: sps.StatusChangeMessage,
+		PublicKey:           sps.PublicKey,
+		Signature:           sps.Signature,
+		PublishAt:           sps.PublishAt,
+	}
+
+	blob, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply www.SetProposalStatusReply
+	var pdReply *pd.SetUnvettedStatusReply
+	if b.test {
+		// Record the change in the inventory cache.
+		b.Lock()
+		ir.record.Status = newStatus
+		ir.changes = append(ir.changes, r)
+		b.indexUserStats(sps.Token)
+		pdReply = &pd.SetUnvettedStatusReply{
+			Record: ir.record,
+		}
+		b.Unlock()
+	} else {
+		pdReply, err = b.setUnvettedStatus(ctx, sps.Token, newStatus,
+			blob)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		// Update the inventory with the metadata changes.
-		b.updateInventoryRecord(pdReply.Record)
+	// Notify the author of the status change.  The author is looked up in
+	// the user database, so this is done without the mutex held.
+	proposal := convertPropFromPD(pdReply.Record)
+	if proposal.UserId == "" {
+		b.RLock()
+		proposal.UserId = b.userPubkeys[proposal.PublicKey]
+		b.RUnlock()
 	}
+	err = b.emailProposalStatusChange(proposal, sps.StatusChangeMessage)
+	if err != nil {
+		log.Errorf("emailProposalStatusChange %v: %v", sps.Token, err)
+	}
+
+	// Record the change in the status history.
+	err = b.db.StatusChangeNew(database.StatusChange{
+		Token:     sps.Token,
+		AdminID:   user.ID,
+		PublicKey: sps.PublicKey,
+		Signature: sps.Signature,
+		OldStatus: int(oldStatus),
+		NewStatus: int(sps.ProposalStatus),
+		Reason:    sps.StatusChangeMessage,
+		Timestamp: r.Timestamp,
+	})
+	if err != nil {
+		log.Errorf("StatusChangeNew %v: %v", sps.Token, err)
+	}
+
+	// The status change supersedes the scheduled publication, if any.
+	err = b.db.ScheduledPublicationDelete(sps.Token)
+	if err != nil && err != database.ErrScheduledPublicationNotFound {
+		log.Errorf("ScheduledPublicationDelete %v: %v", sps.Token, err)
+	}
+
+	b.publish(clusterEvent{
+		Type:  clusterEventInventory,
+		Token: sps.Token,
+	})
 
 	// Return the reply.
 	reply.Proposal = convertPropFromPD(pdReply.Record)
+	reply.Proposal.StatusChangeMessage = sps.StatusChangeMessage
+	reply.Proposal.StatusChangePublicKey = sps.PublicKey
+	reply.Proposal.StatusChangeSignature = sps.Signature
+
+	switch sps.ProposalStatus {
+	case www.PropStatusPublic:
+		b.emitWebhook(www.WebhookEventProposalPublished, sps.Token,
+			www.PropStatusPublic, reply.Proposal.Name)
+	case www.PropStatusCensored:
+		b.emitWebhook(www.WebhookEventProposalCensored, sps.Token,
+			www.PropStatusCensored, "")
+	}
+
+	return &reply, nil
+}
+
+// setUnvettedStatus sets the status of the proposal with the provided token
+// in politeiad, appends the provided status change record to its metadata and
+// updates the inventory.
+//
+// This function must be called WITHOUT the mutex held.
+func (b *backend) setUnvettedStatus(ctx context.Context, token string, status pd.RecordStatusT, blob []byte) (*pd.SetUnvettedStatusReply, error) {
+	// XXX Expensive to lock but do it for now.
+	// Lock is needed to prevent a race into this record and it
+	// needs to be updated in the cache.
+	b.Lock()
+	defer b.Unlock()
+
+	// Flush comments while here, we really should make the
+	// comments flow with the SetUnvettedStatus command but for now
+	// do it separately.
+	err := b.flushCommentJournal(ctx, token)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	sus := pd.SetUnvettedStatus{
+		Token:     token,
+		Status:    status,
+		Challenge: hex.EncodeToString(challenge),
+		MDAppend: []pd.MetadataStream{
+			{
+				ID:      mdStreamChanges,
+				Payload: string(blob),
+			},
+		},
+	}
+
+	responseBody, err := b.makeRequest(ctx, http.MethodPost,
+		pd.SetUnvettedStatusRoute, sus)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply pd.SetUnvettedStatusReply
+	err = json.Unmarshal(responseBody, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal SetUnvettedStatusReply: %v",
+			err)
+	}
+
+	// Verify the challenge.
+	err = util.VerifyChallenge(b.cfg.Identity, challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the inventory with the metadata changes.
+	b.updateInventoryRecord(reply.Record)
+	b.loadRecord(reply.Record)
+
+	return &reply, nil
+}
+
+// ProcessStatusHistory returns the status changes of a proposal.
+func (b *backend) ProcessStatusHistory(sh www.StatusHistory) (*www.StatusHistoryReply, error) {
+	b.RLock()
+	_, ok := b.inventory[sh.Token]
+	b.RUnlock()
+	if !ok {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+
+	changes, err := b.db.StatusChanges(sh.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := www.StatusHistoryReply{
+		Changes: make([]www.StatusChange, 0, len(changes)),
+	}
+	for _, v := range changes {
+		reply.Changes = append(reply.Changes, www.StatusChange{
+			AdminID:   strconv.FormatUint(v.AdminID, 10),
+			PublicKey: v.PublicKey,
+			Signature: v.Signature,
+			OldStatus: www.PropStatusT(v.OldStatus),
+			NewStatus: www.PropStatusT(v.NewStatus),
+			Reason:    v.Reason,
+			Timestamp: v.Timestamp,
+		})
+	}
 
 	return &reply, nil
 }
 
-// ProcessProposalDetails tries to fetch the full details of a proposal from politeiad.
-func (b *backend) ProcessProposalDetails(propDetails www.ProposalsDetails, user *database.User) (*www.ProposalDetailsReply, error) {
+// ProcessProposalDetails tries to fetch the full details of a proposal from
+// politeiad.  The files of the proposals that were fetched last are served
+// from the file cache.
+func (b *backend) ProcessProposalDetails(ctx context.Context, propDetails www.ProposalsDetails, user *database.User) (*www.ProposalDetailsReply, error) {
 	var reply www.ProposalDetailsReply
 	challenge, err := util.Random(pd.ChallengeSize)
 	if err != nil {
@@ -1449,8 +2583,11 @@ func (b *backend) ProcessProposalDetails(propDetails www.ProposalsDetails, user
 			ErrorCode: www.ErrorStatusProposalNotFound,
 		}
 	}
-	b.RUnlock()
+	// The status changes and comments of the inventory record are
+	// updated under the lock, so it is only ever read with the lock held.
+	cachedRecord := p.record
 	cachedProposal := convertPropFromInventoryRecord(p, b.userPubkeys)
+	b.RUnlock()
 
 	var isVettedProposal bool
 	var requestObject interface{}
@@ -1478,15 +2615,7 @@ func (b *backend) ProcessProposalDetails(propDetails www.ProposalsDetails, user
 	// should be publicly viewable.
 	isUserAdmin := user != nil && user.Admin
 	if !isVettedProposal && !isUserAdmin {
-		reply.Proposal = www.ProposalRecord{
-			Status:           cachedProposal.Status,
-			Timestamp:        cachedProposal.Timestamp,
-			PublicKey:        cachedProposal.PublicKey,
-			Signature:        cachedProposal.Signature,
-			CensorshipRecord: cachedProposal.CensorshipRecord,
-			NumComments:      cachedProposal.NumComments,
-		}
-
+		isAuthor := false
 		if user != nil {
 			stringUserID := cachedProposal.UserId
 			userID, err := strconv.ParseUint(stringUserID, 10, 64)
@@ -1494,10 +2623,26 @@ func (b *backend) ProcessProposalDetails(propDetails www.ProposalsDetails, user
 				return nil, err
 			}
 
-			if user.ID == userID {
-				reply.Proposal.Name = cachedProposal.Name
-			}
+			isAuthor = user.ID == userID
 		}
+		reply.Proposal = unvettedProposalMetadata(cachedProposal,
+			isAuthor)
+		return &reply, nil
+	}
+
+	// The inventory holds everything but the files.
+	files, ok := b.fileCache.get(propDetails.Token,
+		cachedRecord.CensorshipRecord.Merkle)
+	if ok {
+		cachedRecord.Files = files
+		b.RLock()
+		reply.Proposal = convertPropFromInventoryRecord(&inventoryRecord{
+			record:       cachedRecord,
+			changes:      p.changes,
+			comments:     p.comments,
+			commentVotes: p.commentVotes,
+		}, b.userPubkeys)
+		b.RUnlock()
 		return &reply, nil
 	}
 
@@ -1508,7 +2653,7 @@ func (b *backend) ProcessProposalDetails(propDetails www.ProposalsDetails, user
 		route = pd.GetUnvettedRoute
 	}
 
-	responseBody, err := b.makeRequest(http.MethodPost, route, requestObject)
+	responseBody, err := b.makeRequest(ctx, http.MethodPost, route, requestObject)
 	if err != nil {
 		return nil, err
 	}
@@ -1542,15 +2687,48 @@ func (b *backend) ProcessProposalDetails(propDetails www.ProposalsDetails, user
 	if err != nil {
 		return nil, err
 	}
+	b.fileCache.put(propDetails.Token, fullRecord.CensorshipRecord.Merkle,
+		fullRecord.Files)
 
+	b.RLock()
 	reply.Proposal = convertPropFromInventoryRecord(&inventoryRecord{
-		record:   fullRecord,
-		changes:  p.changes,
-		comments: p.comments,
+		record:       fullRecord,
+		changes:      p.changes,
+		comments:     p.comments,
+		commentVotes: p.commentVotes,
 	}, b.userPubkeys)
+	b.RUnlock()
 	return &reply, nil
 }
 
+// ProcessProposalFile returns the MIME type and the decoded payload of a file
+// of a proposal.  The files of unvetted proposals are only available to the
+// users that can see them in the proposal details.
+func (b *backend) ProcessProposalFile(ctx context.Context, pf www.ProposalFile, user *database.User) (string, []byte, error) {
+	pdr, err := b.ProcessProposalDetails(ctx, www.ProposalsDetails{
+		Token: pf.Token,
+	}, user)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, v := range pdr.Proposal.Files {
+		if v.Name != pf.Name {
+			continue
+		}
+		payload, err := base64.StdEncoding.DecodeString(v.Payload)
+		if err != nil {
+			return "", nil, err
+		}
+		return v.MIME, payload, nil
+	}
+
+	return "", nil, www.UserError{
+		ErrorCode:    www.ErrorStatusProposalFileNotFound,
+		ErrorContext: []string{pf.Name},
+	}
+}
+
 // ProcessComment processes a submitted comment.  It ensures user has paid
 // the paywall, and the proposal and the parent exists.  A parent ID of 0
 // indicates that it is a comment on the proposal whereas non-zero
@@ -1558,13 +2736,18 @@ func (b *backend) ProcessProposalDetails(propDetails www.ProposalsDetails, user
 func (b *backend) ProcessComment(c www.NewComment, user *database.User) (*www.NewCommentReply, error) {
 	log.Debugf("ProcessComment: %v %v", c.Token, user.ID)
 
+	err := b.checkFeature(www.FeatureComments)
+	if err != nil {
+		return nil, err
+	}
+
 	if !b.VerifyUserPaid(user) {
 		return nil, www.UserError{
 			ErrorCode: www.ErrorStatusUserNotPaid,
 		}
 	}
 
-	err := checkPublicKeyAndSignature(user, c.PublicKey, c.Signature,
+	err = checkPublicKeyAndSignature(user, c.PublicKey, c.Signature,
 		c.Token, c.ParentID, c.Comment)
 	if err != nil {
 		return nil, err
@@ -1600,13 +2783,155 @@ func (b *backend) ProcessComment(c www.NewComment, user *database.User) (*www.Ne
 		}
 	}
 
-	return b.addComment(c, user.ID)
+	cr, err := b.addComment(c, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	b.publishComments(c.Token)
+
+	return cr, nil
+}
+
+// ProcessLikeComment records the up or down vote of the provided user on a
+// comment.  It ensures the user has paid the paywall and that the comment
+// exists.
+func (b *backend) ProcessLikeComment(lc www.LikeComment, user *database.User) (*www.LikeCommentReply, error) {
+	log.Debugf("ProcessLikeComment: %v %v %v", lc.Token, lc.CommentID,
+		user.ID)
+
+	err := b.checkFeature(www.FeatureComments)
+	if err != nil {
+		return nil, err
+	}
+
+	if !b.VerifyUserPaid(user) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusUserNotPaid,
+		}
+	}
+
+	err = checkPublicKeyAndSignature(user, lc.PublicKey, lc.Signature,
+		lc.Token, lc.CommentID, lc.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	m, ok := b.inventory[lc.Token]
+	if !ok {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+	cid, err := strconv.ParseUint(lc.CommentID, 10, 64)
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusCommentNotFound,
+		}
+	}
+	if _, ok := m.comments[cid]; !ok {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusCommentNotFound,
+		}
+	}
+
+	lcr, err := b.likeComment(lc, cid, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	b.publishComments(lc.Token)
+
+	return lcr, nil
+}
+
+// ProcessCensorComment censors a comment on behalf of the provided admin and
+// records the censorship in the admin audit log.
+func (b *backend) ProcessCensorComment(cc www.CensorComment, admin *database.User) (*www.CensorCommentReply, error) {
+	log.Debugf("ProcessCensorComment: %v %v %v", cc.Token, cc.CommentID,
+		admin.ID)
+
+	err := b.checkFeature(www.FeatureComments)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(cc.Reason) == "" {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+
+	err = checkPublicKeyAndSignature(admin, cc.PublicKey, cc.Signature,
+		cc.Token, cc.CommentID, cc.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	m, ok := b.inventory[cc.Token]
+	if !ok {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+	cid, err := strconv.ParseUint(cc.CommentID, 10, 64)
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusCommentNotFound,
+		}
+	}
+	c, ok := m.comments[cid]
+	if !ok {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusCommentNotFound,
+		}
+	}
+	if c.Censored {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusCommentIsCensored,
+		}
+	}
+	authorID, err := strconv.ParseUint(c.UserID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.censorComment(cc, cid, admin.ID)
+	if err != nil {
+		return nil, err
+	}
+	b.publishComments(cc.Token)
+
+	err = b.db.AdminActionNew(database.AdminAction{
+		AdminID:   admin.ID,
+		UserID:    authorID,
+		Action:    www.AdminActionCensorComment,
+		PublicKey: cc.PublicKey,
+		Signature: cc.Signature,
+		Reason:    cc.Reason,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Admin %v censored comment %v of proposal %v: %v",
+		admin.ID, cc.CommentID, cc.Token, cc.Reason)
+
+	return &www.CensorCommentReply{}, nil
 }
 
 // ProcessCommentGet returns all comments for a given proposal.
 func (b *backend) ProcessCommentGet(token string) (*www.GetCommentsReply, error) {
 	log.Debugf("ProcessCommentGet: %v", token)
 
+	err := b.checkFeature(www.FeatureComments)
+	if err != nil {
+		return nil, err
+	}
+
 	c, err := b.getComments(token)
 	if err != nil {
 		return nil, err
@@ -1616,28 +2941,30 @@ func (b *backend) ProcessCommentGet(token string) (*www.GetCommentsReply, error)
 
 // ProcessUserProposals returns the proposals for the given user.
 func (b *backend) ProcessUserProposals(up *www.UserProposals, isCurrentUser, isAdminUser bool) (*www.UserProposalsReply, error) {
+	proposals, total := b.getProposals(proposalsRequest{
+		After:  up.After,
+		Before: up.Before,
+		UserId: up.UserId,
+		StatusMap: filterStatuses(map[www.PropStatusT]bool{
+			www.PropStatusNotReviewed: isCurrentUser || isAdminUser,
+			www.PropStatusCensored:    isCurrentUser || isAdminUser,
+			www.PropStatusPublic:      true,
+		}, up.Status),
+		SubmittedAfter:  up.SubmittedAfter,
+		SubmittedBefore: up.SubmittedBefore,
+		Tag:             strings.ToLower(up.Tag),
+	})
 	return &www.UserProposalsReply{
-		Proposals: b.getProposals(proposalsRequest{
-			After:  up.After,
-			Before: up.Before,
-			UserId: up.UserId,
-			StatusMap: map[www.PropStatusT]bool{
-				www.PropStatusNotReviewed: isCurrentUser || isAdminUser,
-				www.PropStatusCensored:    isCurrentUser || isAdminUser,
-				www.PropStatusPublic:      true,
-			},
-		}),
+		Proposals: proposals,
+		Total:     uint(total),
 	}, nil
 }
 
-func (b *backend) ProcessActiveVote() (*www.ActiveVoteReply, error) {
-	log.Tracef("ProcessActiveVote")
-
-	//  We need to determine best block height here and only return active
-	//  votes.
+// getBestBlock asks politeiad for the current best block height.
+func (b *backend) getBestBlock(ctx context.Context) (uint64, error) {
 	challenge, err := util.Random(pd.ChallengeSize)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
 	pc := pd.PluginCommand{
@@ -1648,26 +2975,34 @@ func (b *backend) ProcessActiveVote() (*www.ActiveVoteReply, error) {
 		Payload:   "",
 	}
 
-	responseBody, err := b.makeRequest(http.MethodPost,
+	responseBody, err := b.makeRequest(ctx, http.MethodPost,
 		pd.PluginCommandRoute, pc)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
 	var reply pd.PluginCommandReply
 	err = json.Unmarshal(responseBody, &reply)
 	if err != nil {
-		return nil, fmt.Errorf("Could not unmarshal "+
+		return 0, fmt.Errorf("Could not unmarshal "+
 			"PluginCommandReply: %v", err)
 	}
 
 	// Verify the challenge.
 	err = util.VerifyChallenge(b.cfg.Identity, challenge, reply.Response)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	bestBlock, err := strconv.ParseUint(reply.Payload, 10, 64)
+	return strconv.ParseUint(reply.Payload, 10, 64)
+}
+
+func (b *backend) ProcessActiveVote(ctx context.Context) (*www.ActiveVoteReply, error) {
+	log.Tracef("ProcessActiveVote")
+
+	//  We need to determine best block height here and only return active
+	//  votes.
+	bestBlock, err := b.getBestBlock(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -1675,23 +3010,8 @@ func (b *backend) ProcessActiveVote() (*www.ActiveVoteReply, error) {
 	b.RLock()
 	defer b.RUnlock()
 
-	// iterate over all props and see what is active
 	var avr www.ActiveVoteReply
-	for _, i := range b.inventory {
-		// Use StartBlockHeight as a canary
-		if len(i.voting.StartBlockHeight) == 0 {
-			continue
-		}
-		ee, err := strconv.ParseUint(i.voting.EndHeight, 10, 64)
-		if err != nil {
-			log.Errorf("invalid ee, should not happen: %v", err)
-			continue
-		}
-		if bestBlock > ee {
-			// expired vote
-			continue
-		}
-
+	for _, i := range b.activeVotes(bestBlock) {
 		avr.Votes = append(avr.Votes, www.ProposalVoteTuple{
 			Proposal:    convertPropFromPD(i.record),
 			Vote:        i.votebits,
@@ -1702,16 +3022,26 @@ func (b *backend) ProcessActiveVote() (*www.ActiveVoteReply, error) {
 	return &avr, nil
 }
 
-func (b *backend) ProcessCastVotes(cv *www.Ballot) (*www.BallotReply, error) {
+func (b *backend) ProcessCastVotes(ctx context.Context, cv *www.Ballot) (*www.BallotReply, error) {
 	log.Tracef("ProcessCastVotes")
 
+	// Votes on proposals that aren't being voted on are answered here.
+	receipts, forward := b.screenBallot(cv.Votes)
+	if len(forward) == 0 {
+		return &www.BallotReply{Receipts: receipts}, nil
+	}
+	votes := make([]decredplugin.CastVote, 0, len(forward))
+	for _, i := range forward {
+		votes = append(votes, cv.Votes[i])
+	}
+
 	challenge, err := util.Random(pd.ChallengeSize)
 	if err != nil {
 		return nil, err
 	}
 
 	// encode cast votes for plugin
-	payload, err := decredplugin.EncodeCastVotes(cv.Votes)
+	payload, err := decredplugin.EncodeCastVotes(votes)
 	if err != nil {
 		return nil, err
 	}
@@ -1723,7 +3053,7 @@ func (b *backend) ProcessCastVotes(cv *www.Ballot) (*www.BallotReply, error) {
 		Payload:   string(payload),
 	}
 
-	responseBody, err := b.makeRequest(http.MethodPost,
+	responseBody, err := b.makeRequest(ctx, http.MethodPost,
 		pd.PluginCommandRoute, pc)
 	if err != nil {
 		return nil, err
@@ -1743,15 +3073,24 @@ func (b *backend) ProcessCastVotes(cv *www.Ballot) (*www.BallotReply, error) {
 	}
 
 	// Decode plugin reply
-	receipts, err := decredplugin.DecodeCastVoteReplies([]byte(reply.Payload))
+	replies, err := decredplugin.DecodeCastVoteReplies([]byte(reply.Payload))
 	if err != nil {
 		return nil, err
 	}
+	if len(replies) != len(forward) {
+		return nil, fmt.Errorf("unexpected number of receipts: got %v "+
+			"want %v", len(replies), len(forward))
+	}
+	for k, i := range forward {
+		receipts[i] = replies[k]
+	}
+	b.tallyBallot(votes, replies)
+	b.streamBallot(votes, replies)
 
 	return &www.BallotReply{Receipts: receipts}, nil
 }
 
-func (b *backend) ProcessStartVote(sv www.StartVote, user *database.User) (*www.StartVoteReply, error) {
+func (b *backend) ProcessStartVote(ctx context.Context, sv www.StartVote, user *database.User) (*www.StartVoteReply, error) {
 	log.Tracef("ProcessStartVote %v", sv.Vote.Token)
 
 	// XXX Verify user
@@ -1760,7 +3099,19 @@ func (b *backend) ProcessStartVote(sv www.StartVote, user *database.User) (*www.
 	//	return nil, err
 	//}
 
-	// XXX validate vote bits
+	// The defaults are set here as well so that the inventory holds the
+	// vote as politeiad stores it.
+	sv.Vote.SetDefaults()
+	err := sv.Vote.VerifyParams()
+	if err == nil {
+		err = sv.Vote.VerifyOptions()
+	}
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode:    www.ErrorStatusInvalidVoteParams,
+			ErrorContext: []string{err.Error()},
+		}
+	}
 
 	// Create vote bits as plugin payload
 	payload, err := decredplugin.EncodeVote(sv.Vote)
@@ -1787,6 +3138,11 @@ func (b *backend) ProcessStartVote(sv www.StartVote, user *database.User) (*www.
 			ErrorCode: www.ErrorStatusWrongStatus,
 		}
 	}
+	if ir.voting.StartBlockHeight != "" {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusVoteAlreadyStarted,
+		}
+	}
 
 	// Tell decred plugin to start voting
 	challenge, err := util.Random(pd.ChallengeSize)
@@ -1802,7 +3158,7 @@ func (b *backend) ProcessStartVote(sv www.StartVote, user *database.User) (*www.
 		Payload:   string(payload),
 	}
 
-	responseBody, err := b.makeRequest(http.MethodPost,
+	responseBody, err := b.makeRequest(ctx, http.MethodPost,
 		pd.PluginCommandRoute, pc)
 	if err != nil {
 		return nil, err
@@ -1827,16 +3183,31 @@ func (b *backend) ProcessStartVote(sv www.StartVote, user *database.User) (*www.
 	if err != nil {
 		return nil, err
 	}
+
+	// Make sure politeiad committed to the snapshot and the window.
+	sig, err := util.ConvertSignature(vr.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if !b.cfg.Identity.VerifyMessage(vr.SignatureMessage(sv.Vote), sig) {
+		return nil, fmt.Errorf("invalid start vote reply signature")
+	}
 	ir.voting = *vr
 	ir.votebits = sv.Vote
 	b.inventory[sv.Vote.Token] = &ir
+	b.indexUserStats(sv.Vote.Token)
+
+	b.publish(clusterEvent{
+		Type:  clusterEventInventory,
+		Token: sv.Vote.Token,
+	})
 
 	return &www.StartVoteReply{
 		VoteDetails: *vr,
 	}, nil
 }
 
-func (b *backend) ProcessProposalVotes(gpv *www.ProposalVotes) (*www.ProposalVotesReply, error) {
+func (b *backend) ProcessProposalVotes(ctx context.Context, gpv *www.ProposalVotes) (*www.ProposalVotesReply, error) {
 	log.Tracef("ProcessProposalVotes")
 
 	payload, err := decredplugin.EncodeVoteResults(gpv.Vote)
@@ -1859,7 +3230,7 @@ func (b *backend) ProcessProposalVotes(gpv *www.ProposalVotes) (*www.ProposalVot
 		Payload: string(payload),
 	}
 
-	responseBody, err := b.makeRequest(http.MethodPost,
+	responseBody, err := b.makeRequest(ctx, http.MethodPost,
 		pd.PluginCommandRoute, pc)
 	if err != nil {
 		return nil, err
@@ -1893,17 +3264,54 @@ func (b *backend) ProcessProposalVotes(gpv *www.ProposalVotes) (*www.ProposalVot
 func (b *backend) ProcessPolicy(p www.Policy) *www.PolicyReply {
 	return &www.PolicyReply{
 		PasswordMinChars:     www.PolicyPasswordMinChars,
+		PasswordMinEntropy:   www.PolicyPasswordMinEntropy,
 		ProposalListPageSize: www.ProposalListPageSize,
-		MaxImages:            www.PolicyMaxImages,
-		MaxImageSize:         www.PolicyMaxImageSize,
-		MaxMDs:               www.PolicyMaxMDs,
-		MaxMDSize:            www.PolicyMaxMDSize,
+		MaxImages:            uint(b.cfg.MaxProposalImages),
+		MaxImageSize:         uint(b.cfg.MaxProposalImageSize),
+		MaxMDs:               uint(b.cfg.MaxProposalMDs),
+		MaxMDSize:            uint(b.cfg.MaxProposalMDSize),
 		ValidMIMETypes:       mime.ValidMimeTypes(),
-		MaxNameLength:        www.PolicyMaxProposalNameLength,
-		MinNameLength:        www.PolicyMinProposalNameLength,
+		MaxNameLength:        uint(b.cfg.MaxProposalNameLength),
+		MinNameLength:        uint(b.cfg.MinProposalNameLength),
 		SupportedCharacters:  www.PolicyProposalNameSupportedCharacters,
-		MaxCommentLength:     www.PolicyMaxCommentLength,
+		MaxCommentLength:     uint(b.cfg.MaxCommentLength),
+		MaxDrafts:            www.PolicyMaxDrafts,
+		MaxProposalTags:      www.PolicyMaxProposalTags,
+		MaxProposalTagLength: www.PolicyMaxProposalTagLength,
+		MaxProposalCoAuthors: www.PolicyMaxProposalCoAuthors,
+		SignupPoWDifficulty:  b.cfg.SignupPoWDifficulty,
+		MaxImageWidth:        www.PolicyMaxImageWidth,
+		MaxImageHeight:       www.PolicyMaxImageHeight,
+		MaxImagePixels:       www.PolicyMaxImagePixels,
+		RawHTMLAllowed:       !b.cfg.RejectProposalHTML,
+		MaxProposalAmount:    www.PolicyMaxProposalAmount,
+		MaxProposalDuration:  www.PolicyMaxProposalDuration,
+	}
+}
+
+// loadKey loads the secret key with the provided filename from the data
+// directory.  A random key is generated and persisted if it doesn't exist.
+func loadKey(dataDir, filename string) ([]byte, error) {
+	keyFile := filepath.Join(dataDir, filename)
+	key, err := ioutil.ReadFile(keyFile)
+	if err == nil {
+		return key, nil
+	}
+
+	key, err = util.Random(32)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dataDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	err = ioutil.WriteFile(keyFile, key, 0400)
+	if err != nil {
+		return nil, err
 	}
+
+	return key, nil
 }
 
 // NewBackend creates a new backend context for use in www and tests.
@@ -1917,17 +3325,118 @@ func NewBackend(cfg *config) (*backend, error) {
 
 	// Context
 	b := &backend{
-		db:          db,
+		db:          faultDatabase(db),
 		cfg:         cfg,
 		userPubkeys: make(map[string]string),
 		commentJournalDir: filepath.Join(cfg.DataDir,
 			defaultCommentJournalDir),
 		commentID: 1, // Replay will set this value
+		quit:      make(chan struct{}),
+
+		webhookClient:  newWebhookClient(),
+		webhookBackoff: webhookInitialBackoff,
+
+		validProposalName: regexp.MustCompile(util.ProposalNameRegex(
+			cfg.MinProposalNameLength, cfg.MaxProposalNameLength)),
+
+		fileCache:   newFileCache(cfg.FileCacheSize * 1024 * 1024),
+		spamFilters: newSpamFilters(cfg),
+
+		snsCerts:    make(map[string]*x509.Certificate),
+		voteStreams: make(map[*voteStream]struct{}),
+	}
+
+	b.stateStore, err = newStateStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	b.initFeatures()
+
+	// Load the keys that sign the unsubscribe links, the API tokens and
+	// the signup challenges.
+	b.unsubscribeKey, err = loadKey(cfg.DataDir, unsubscribeKeyFilename)
+	if err != nil {
+		return nil, err
+	}
+	b.jwtKey, err = loadKey(cfg.DataDir, jwtKeyFilename)
+	if err != nil {
+		return nil, err
+	}
+	b.signupKey, err = loadKey(cfg.DataDir, signupKeyFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.OIDCIssuer != "" {
+		b.oidc = newOIDCProvider(cfg)
+	}
+
+	b.passwordHasher = newPasswordHasher(cfg)
+	b.authenticator = localAuthenticator{}
+	if cfg.Authenticator == authenticatorLDAP {
+		b.authenticator, err = newLDAPAuthenticator(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cfg.ClusterPeers) != 0 || cfg.ClusterPrimary != "" {
+		b.clusterClient, err = newClusterClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Setup the notification email queue.  Replicas don't send emails
+	// since the primary owns the users.
+	if cfg.Mailer != nil && !b.isReplica() {
+		b.emailLimiter = newEmailRateLimiter(cfg.MaxEmailsPerHour,
+			cfg.MaxEmailsPerDay, b.stateStore)
+		b.emailQueue = make(chan *emailMessage, emailQueueSize)
+		b.emailWorkerDone = make(chan struct{})
+		go b.emailWorker()
+
+		if cfg.AdminNotifications == adminNotificationsBatched {
+			b.startWorker(b.adminNotificationWorker)
+		}
+
+		b.startWorker(b.digestWorker)
+	}
+
+	// Watch for votes that start or end to notify the users and the
+	// webhooks.
+	if (cfg.Mailer != nil || len(cfg.Webhooks) != 0) && !b.isReplica() {
+		b.startWorker(b.voteWatcher)
+	}
+
+	// Publish the proposals whose publication was scheduled by an admin.
+	// Replicas forward the status changes to the primary.
+	if !b.isReplica() {
+		b.startWorker(b.publicationScheduler)
 	}
 
 	// Setup comments
 	os.MkdirAll(b.commentJournalDir, 0744)
 
+	// Load the IP bans
+	err = b.initIPBans()
+	if err != nil {
+		return nil, err
+	}
+
+	// Purge the expired sessions that are kept in the database.
+	if cfg.SessionStore == sessionStoreDatabase {
+		b.startWorker(b.sessionPurger)
+	}
+
+	// Load the proposal views and write the new ones periodically.
+	views, err := b.db.AllProposalViews()
+	if err != nil {
+		return nil, err
+	}
+	b.views = newViewCounter(views)
+	b.startWorker(b.viewWorker)
+
 	// Setup pubkey-userid map
 	err = b.initUserPubkeys()
 	if err != nil {
@@ -1935,7 +3444,7 @@ func NewBackend(cfg *config) (*backend, error) {
 	}
 
 	// Flush comments
-	err = b.flushCommentJournals()
+	err = b.flushCommentJournals(context.Background())
 	if err != nil {
 		return nil, err
 	}
@@ -1943,6 +3452,91 @@ func NewBackend(cfg *config) (*backend, error) {
 	return b, nil
 }
 
+// startWorker runs the provided background worker.  The worker must return
+// once the quit channel is closed.
+func (b *backend) startWorker(worker func()) {
+	b.workers.Add(1)
+	go func() {
+		defer b.workers.Done()
+		worker()
+	}()
+}
+
+// waitContext waits until the provided channel is closed or until the
+// provided context expires.
+func waitContext(ctx context.Context, c <-chan struct{}) error {
+	select {
+	case <-c:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close shuts the backend down.  It stops the background workers, sends the
+// pending notification emails, flushes the comment journals to politeiad and
+// closes the user database.  Close gives up waiting on the workers and the
+// email queue once the provided context expires.
+//
+// This function must be called WITHOUT the mutex held and after the http
+// servers have been shut down.
+func (b *backend) Close(ctx context.Context) error {
+	// Stop the background workers.
+	close(b.quit)
+	workersDone := make(chan struct{})
+	go func() {
+		b.workers.Wait()
+		close(workersDone)
+	}()
+	err := waitContext(ctx, workersDone)
+	if err != nil {
+		log.Errorf("Close: background workers: %v", err)
+	}
+
+	// Send the batched admin notifications that are still pending.
+	if b.emailQueue != nil {
+		b.adminNotificationsMtx.Lock()
+		batch := b.adminNotifications
+		b.adminNotifications = nil
+		b.adminNotificationsMtx.Unlock()
+		if len(batch) != 0 {
+			err := b.emailAdminsNewProposals(batch)
+			if err != nil {
+				log.Errorf("Close: admin notifications: %v", err)
+			}
+		}
+	}
+
+	// Drain the email queue.  Senders never block while holding the
+	// queue mutex, so closing the queue doesn't outlast the context.
+	if b.emailQueue != nil {
+		b.emailQueueMtx.Lock()
+		b.emailQueueClosed = true
+		close(b.emailQueue)
+		b.emailQueueMtx.Unlock()
+
+		log.Infof("Sending %v queued emails", len(b.emailQueue))
+		err := waitContext(ctx, b.emailWorkerDone)
+		if err != nil {
+			log.Errorf("Close: %v emails not sent: %v",
+				len(b.emailQueue), err)
+		}
+	}
+
+	// Comments are journaled synchronously with the mutex held so taking
+	// the mutex waits out any write in progress.
+	b.Lock()
+	if !b.test {
+		err = b.flushCommentJournals(ctx)
+		if err != nil {
+			log.Errorf("Close: flush comment journals: %v", err)
+		}
+	}
+	b.Unlock()
+
+	return b.db.Close()
+}
+
 // getProposalName returns the proposal name based on the index markdown file.
 func getProposalName(files []www.File) (string, error) {
 	for _, file := range files {
@@ -1,10 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sort"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/decred/politeia/politeiad/api/v1/identity"
@@ -14,6 +23,7 @@ import (
 	www "github.com/decred/politeia/politeiawww/api/v1"
 	"github.com/decred/politeia/politeiawww/database"
 	"github.com/decred/politeia/util"
+	"github.com/gorilla/sessions"
 )
 
 // getSignature signs the msg with the given identity and returns
@@ -50,6 +60,20 @@ func getProposalSignature(files []pd.File, id *identity.FullIdentity) (string, e
 	return getSignature([]byte(encodedMerkleRoot), id)
 }
 
+// generatePNG returns a valid PNG image that is padded to the provided size.
+// Decoders ignore the data after the end of the image.
+func generatePNG(t *testing.T, size int) []byte {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size > buf.Len() {
+		buf.WriteString(generateRandomString(size - buf.Len()))
+	}
+	return buf.Bytes()
+}
+
 func createNewProposal(b *backend, t *testing.T, user *database.User, id *identity.FullIdentity) (*www.NewProposal, *www.NewProposalReply, error) {
 	return createNewProposalWithFiles(b, t, user, id, 1, 0)
 }
@@ -84,8 +108,7 @@ func createNewProposalWithFileSizes(b *backend, t *testing.T, user *database.Use
 
 	for i := uint(0); i < numImageFiles; i++ {
 		name = generateRandomString(5) + ".png"
-		imgSizeWithName := int(imageSize) - len(name) - len("\n")
-		payload := []byte(name + "\n" + generateRandomString(imgSizeWithName))
+		payload := generatePNG(t, int(imageSize))
 
 		files = append(files, pd.File{
 			Name:    name,
@@ -105,7 +128,7 @@ func createNewProposalWithFileSizes(b *backend, t *testing.T, user *database.Use
 		Signature: signature,
 	}
 
-	npr, err := b.ProcessNewProposal(np, user)
+	npr, err := b.ProcessNewProposal(context.Background(), np, user)
 	return &np, npr, err
 }
 
@@ -135,7 +158,7 @@ func createNewProposalWithInvalidTitle(b *backend, t *testing.T, user *database.
 		Signature: signature,
 	}
 
-	npr, err := b.ProcessNewProposal(np, user)
+	npr, err := b.ProcessNewProposal(context.Background(), np, user)
 	return &np, npr, err
 }
 
@@ -164,7 +187,7 @@ func createNewProposalTitleSize(b *backend, t *testing.T, user *database.User, i
 		Signature: signature,
 	}
 
-	npr, err := b.ProcessNewProposal(np, user)
+	npr, err := b.ProcessNewProposal(context.Background(), np, user)
 	return &np, npr, err
 }
 
@@ -196,7 +219,7 @@ func createNewProposalWithDuplicateFiles(b *backend, t *testing.T, user *databas
 		Signature: signature,
 	}
 
-	npr, err := b.ProcessNewProposal(np, user)
+	npr, err := b.ProcessNewProposal(context.Background(), np, user)
 	return &np, npr, err
 }
 
@@ -220,7 +243,7 @@ func createNewProposalWithoutIndexFile(b *backend, t *testing.T, user *database.
 		Signature: signature,
 	}
 
-	npr, err := b.ProcessNewProposal(np, user)
+	npr, err := b.ProcessNewProposal(context.Background(), np, user)
 	return &np, npr, err
 }
 
@@ -239,7 +262,7 @@ func publishProposal(b *backend, token string, t *testing.T, user *database.User
 
 	sps.PublicKey = id.Public.String()
 
-	_, err = b.ProcessSetProposalStatus(sps, user)
+	_, err = b.ProcessSetProposalStatus(context.Background(), sps, user)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -247,11 +270,13 @@ func publishProposal(b *backend, token string, t *testing.T, user *database.User
 
 func censorProposal(b *backend, token string, t *testing.T, user *database.User, id *identity.FullIdentity) {
 	sps := www.SetProposalStatus{
-		Token:          token,
-		ProposalStatus: www.PropStatusCensored,
+		Token:               token,
+		ProposalStatus:      www.PropStatusCensored,
+		StatusChangeMessage: "spam",
 	}
 
-	msg := sps.Token + strconv.FormatUint(uint64(sps.ProposalStatus), 10)
+	msg := sps.Token + strconv.FormatUint(uint64(sps.ProposalStatus), 10) +
+		sps.StatusChangeMessage
 	signature, err := getSignature([]byte(msg), id)
 	if err != nil {
 		t.Fatal(err)
@@ -260,7 +285,7 @@ func censorProposal(b *backend, token string, t *testing.T, user *database.User,
 
 	sps.PublicKey = id.Public.String()
 
-	_, err = b.ProcessSetProposalStatus(sps, user)
+	_, err = b.ProcessSetProposalStatus(context.Background(), sps, user)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -270,7 +295,7 @@ func getProposalDetails(b *backend, token string, t *testing.T) *www.ProposalDet
 	pd := www.ProposalsDetails{
 		Token: token,
 	}
-	pdr, err := b.ProcessProposalDetails(pd, nil)
+	pdr, err := b.ProcessProposalDetails(context.Background(), pd, nil)
 	if err != nil {
 		t.Error(err)
 	}
@@ -295,7 +320,9 @@ func verifyProposals(p1 www.ProposalRecord, p2 www.ProposalRecord, t *testing.T)
 
 func verifyProposalsSorted(b *backend, vettedProposals, unvettedProposals []www.ProposalRecord, t *testing.T) {
 	// Verify that the proposals are returned sorted correctly.
-	allVettedReply := b.ProcessAllVetted(www.GetAllVetted{})
+	allVettedReply, err := b.ProcessAllVetted(context.Background(),
+		www.GetAllVetted{})
+	assertSuccess(t, err)
 	if len(allVettedReply.Proposals) != len(vettedProposals) {
 		t.Fatalf("expected %v proposals, got %v", len(vettedProposals),
 			len(allVettedReply.Proposals))
@@ -305,7 +332,9 @@ func verifyProposalsSorted(b *backend, vettedProposals, unvettedProposals []www.
 			vettedProposals[len(allVettedReply.Proposals)-i-1], t)
 	}
 
-	allUnvettedReply := b.ProcessAllUnvetted(www.GetAllUnvetted{})
+	allUnvettedReply, err := b.ProcessAllUnvetted(www.GetAllUnvetted{},
+		&database.User{Admin: true})
+	assertSuccess(t, err)
 	if len(allUnvettedReply.Proposals) != len(unvettedProposals) {
 		t.Fatalf("expected %v proposals, got %v", len(unvettedProposals),
 			len(allUnvettedReply.Proposals))
@@ -357,6 +386,28 @@ func TestNewProposalPolicyRestrictions(t *testing.T) {
 	assertErrorWithContext(t, err, www.ErrorStatusProposalMissingFiles, []string{indexFile})
 }
 
+func TestNewProposalConfiguredPolicy(t *testing.T) {
+	b := createBackend(t)
+	b.cfg.MaxProposalImages = 1
+	b.cfg.MaxProposalMDSize = 128
+	u, id := createAndVerifyUser(t, b)
+	user, _ := b.db.UserGet(u.Email)
+
+	p := b.ProcessPolicy(www.Policy{})
+	if p.MaxImages != 1 || p.MaxMDSize != 128 {
+		t.Fatalf("policy doesn't report the configured limits: %v", p)
+	}
+
+	_, _, err := createNewProposalWithFileSizes(b, t, user, id, 1, 1, 128, 64)
+	assertSuccess(t, err)
+
+	_, _, err = createNewProposalWithFiles(b, t, user, id, 1, 2)
+	assertError(t, err, www.ErrorStatusMaxImagesExceededPolicy)
+
+	_, _, err = createNewProposalWithFileSizes(b, t, user, id, 1, 0, 129, 0)
+	assertError(t, err, www.ErrorStatusMaxMDSizeExceededPolicy)
+}
+
 // Tests creates a new proposal with an invalid signature.
 func TestNewProposalWithInvalidSignature(t *testing.T) {
 	b := createBackend(t)
@@ -388,7 +439,7 @@ func TestNewProposalWithInvalidSignature(t *testing.T) {
 		Signature: signature,
 	}
 
-	_, err = b.ProcessNewProposal(np, user)
+	_, err = b.ProcessNewProposal(context.Background(), np, user)
 	assertError(t, err, www.ErrorStatusInvalidSignature)
 
 	b.db.Close()
@@ -429,7 +480,7 @@ func TestNewProposalWithInvalidSigningKey(t *testing.T) {
 		Signature: signature,
 	}
 
-	_, err = b.ProcessNewProposal(np, user)
+	_, err = b.ProcessNewProposal(context.Background(), np, user)
 	assertError(t, err, www.ErrorStatusInvalidSigningKey)
 
 	b.db.Close()
@@ -450,6 +501,43 @@ func TestUnreviewedProposal(t *testing.T) {
 	b.db.Close()
 }
 
+// Tests that non-admins only list the metadata of their own unvetted
+// proposals.
+func TestUnvettedListingAccess(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	u, id := createAndVerifyUser(t, b)
+	user, _ := b.db.UserGet(u.Email)
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	u2, id2 := createAndVerifyUser(t, b)
+	user2, _ := b.db.UserGet(u2.Email)
+	_, _, err = createNewProposal(b, t, user2, id2)
+	assertSuccess(t, err)
+
+	_, err = b.ProcessAllUnvetted(www.GetAllUnvetted{}, nil)
+	assertError(t, err, www.ErrorStatusNotLoggedIn)
+
+	ur, err := b.ProcessAllUnvetted(www.GetAllUnvetted{}, user)
+	assertSuccess(t, err)
+	if len(ur.Proposals) != 1 || ur.Total != 1 {
+		t.Fatalf("expected 1 proposal, got %v", ur.Proposals)
+	}
+	p := ur.Proposals[0]
+	if p.CensorshipRecord.Token != npr.CensorshipRecord.Token ||
+		p.Name == "" || p.UserId != "" || len(p.Tags) != 0 {
+		t.Fatalf("unexpected proposal %v", p)
+	}
+
+	ur, err = b.ProcessAllUnvetted(www.GetAllUnvetted{},
+		&database.User{ID: user2.ID + 1, Admin: true})
+	assertSuccess(t, err)
+	if ur.Total != 2 {
+		t.Fatalf("expected 2 proposals, got %v", ur.Total)
+	}
+}
+
 // Tests censoring a proposal and then fetching its details.
 func TestCensoredProposal(t *testing.T) {
 	b := createBackend(t)
@@ -459,13 +547,68 @@ func TestCensoredProposal(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	// Censorships require a reason.
+	sps := www.SetProposalStatus{
+		Token:          npr.CensorshipRecord.Token,
+		ProposalStatus: www.PropStatusCensored,
+		PublicKey:      id.Public.String(),
+	}
+	sps.Signature, err = getSignature([]byte(sps.Token+
+		strconv.FormatUint(uint64(sps.ProposalStatus), 10)), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.ProcessSetProposalStatus(context.Background(), sps, user)
+	assertError(t, err, www.ErrorStatusChangeMessageCannotBeBlank)
+
 	censorProposal(b, npr.CensorshipRecord.Token, t, user, id)
 	pdr := getProposalDetails(b, npr.CensorshipRecord.Token, t)
 	verifyProposalDetails(np, pdr.Proposal, t)
+	if pdr.Proposal.Status != www.PropStatusCensored ||
+		pdr.Proposal.StatusChangeMessage != "spam" {
+		t.Fatalf("unexpected status %v, reason %q", pdr.Proposal.Status,
+			pdr.Proposal.StatusChangeMessage)
+	}
+
+	// The signed reason is stored with the proposal.
+	sig, err := util.ConvertSignature(pdr.Proposal.StatusChangeSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pdr.Proposal.StatusChangePublicKey != id.Public.String() ||
+		!id.Public.VerifyMessage([]byte(sps.Token+
+			strconv.FormatUint(uint64(sps.ProposalStatus), 10)+
+			pdr.Proposal.StatusChangeMessage), sig) {
+		t.Fatalf("unexpected status change signature %v %v",
+			pdr.Proposal.StatusChangePublicKey,
+			pdr.Proposal.StatusChangeSignature)
+	}
 
 	b.db.Close()
 }
 
+// Tests that the author of a proposal is recorded with the proposal rather
+// than inferred from the signing key.
+func TestProposalAuthor(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	u, id := createAndVerifyUser(t, b)
+	user, _ := b.db.UserGet(u.Email)
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+
+	b.Lock()
+	delete(b.userPubkeys, id.Public.String())
+	b.Unlock()
+
+	pdr := getProposalDetails(b, npr.CensorshipRecord.Token, t)
+	if pdr.Proposal.UserId != strconv.FormatUint(user.ID, 10) {
+		t.Fatalf("got author %v, want %v", pdr.Proposal.UserId, user.ID)
+	}
+}
+
 // Tests publishing a proposal and then fetching its details.
 func TestPublishedProposal(t *testing.T) {
 	b := createBackend(t)
@@ -482,6 +625,46 @@ func TestPublishedProposal(t *testing.T) {
 	b.db.Close()
 }
 
+// Tests that status changes are recorded in the status history.
+func TestStatusHistory(t *testing.T) {
+	b := createBackend(t)
+	u, id := createAndVerifyUser(t, b)
+	user, _ := b.db.UserGet(u.Email)
+	_, npr, err := createNewProposal(b, t, user, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := npr.CensorshipRecord.Token
+
+	shr, err := b.ProcessStatusHistory(www.StatusHistory{Token: token})
+	assertSuccess(t, err)
+	if len(shr.Changes) != 0 {
+		t.Fatalf("got %v status changes, want 0", len(shr.Changes))
+	}
+
+	censorProposal(b, token, t, user, id)
+	shr, err = b.ProcessStatusHistory(www.StatusHistory{Token: token})
+	assertSuccess(t, err)
+	if len(shr.Changes) != 1 {
+		t.Fatalf("got %v status changes, want 1", len(shr.Changes))
+	}
+	sc := shr.Changes[0]
+	if sc.OldStatus != www.PropStatusNotReviewed ||
+		sc.NewStatus != www.PropStatusCensored ||
+		sc.PublicKey != id.Public.String() ||
+		sc.AdminID != strconv.FormatUint(user.ID, 10) ||
+		sc.Reason != "spam" {
+		t.Fatalf("unexpected status change: %v", sc)
+	}
+
+	_, err = b.ProcessStatusHistory(www.StatusHistory{
+		Token: strings.Repeat("0", 64),
+	})
+	assertError(t, err, www.ErrorStatusProposalNotFound)
+
+	b.db.Close()
+}
+
 // Tests that the inventory is always sorted by timestamp.
 // XXX must be fixed by @sndurkin
 //func TestInventorySorted(t *testing.T) {
@@ -526,87 +709,401 @@ func TestPublishedProposal(t *testing.T) {
 //	b.db.Close()
 //}
 
-// XXX must be fixed by @sndurkin
-//func TestProposalListPaging(t *testing.T) {
-//	b := createBackend(t)
-//	nu, id := createAndVerifyUser(t, b)
-//	user, _ := b.db.UserGet(nu.Email)
-//
-//	tokens := make([]string, www.ProposalListPageSize+1)
-//	for i := 0; i < www.ProposalListPageSize+1; i++ {
-//		_, npr, err := createNewProposal(b, t, user, id)
-//		if err != nil {
-//			t.Fatal(err)
-//		}
-//
-//		tokens[i] = npr.CensorshipRecord.Token
-//
-//		// Sleep to ensure the proposals have different timestamps.
-//		time.Sleep(time.Duration(1) * time.Second)
-//	}
-//
-//	var u www.GetAllUnvetted
-//	ur := b.ProcessAllUnvetted(u)
-//	if len(ur.Proposals) != www.ProposalListPageSize {
-//		t.Fatalf("expected %v proposals, got %v", www.ProposalListPageSize,
-//			len(ur.Proposals))
-//	}
-//
-//	// Test fetching the next page using the After field.
-//	u.After = ur.Proposals[len(ur.Proposals)-1].CensorshipRecord.Token
-//	ur = b.ProcessAllUnvetted(u)
-//	if len(ur.Proposals) != 1 {
-//		t.Fatalf("expected 1 proposal, got %v", len(ur.Proposals))
-//	}
-//	for _, v := range ur.Proposals {
-//		if v.CensorshipRecord.Token == u.After {
-//			t.Fatalf("Proposal with token provided for 'After' field should " +
-//				"not exist in the next page")
-//		}
-//	}
-//
-//	// Test fetching the previous page using the Before field.
-//	u.After = ""
-//	u.Before = ur.Proposals[0].CensorshipRecord.Token
-//	ur = b.ProcessAllUnvetted(u)
-//	if len(ur.Proposals) != www.ProposalListPageSize {
-//		t.Fatalf("expected %v proposals, got %v", www.ProposalListPageSize,
-//			len(ur.Proposals))
-//	}
-//	for _, v := range ur.Proposals {
-//		if v.CensorshipRecord.Token == u.Before {
-//			t.Fatalf("Proposal with token provided for 'Before' field should " +
-//				"not exist in the previous page")
-//		}
-//	}
-//
-//	// Publish all the proposals.
-//	for _, token := range tokens {
-//		publishProposal(b, token, t, user, id)
-//	}
-//
-//	var v www.GetAllVetted
-//	vr := b.ProcessAllVetted(v)
-//	if len(vr.Proposals) != www.ProposalListPageSize {
-//		t.Fatalf("expected %v proposals, got %v", www.ProposalListPageSize,
-//			len(vr.Proposals))
-//	}
-//
-//	// Test fetching the next page using the After field.
-//	v.After = vr.Proposals[len(vr.Proposals)-1].CensorshipRecord.Token
-//	vr = b.ProcessAllVetted(v)
-//	if len(vr.Proposals) != 1 {
-//		t.Fatalf("expected 1 proposal, got %v", len(vr.Proposals))
-//	}
-//
-//	// Test fetching the previous page using the Before field.
-//	v.After = ""
-//	v.Before = vr.Proposals[0].CensorshipRecord.Token
-//	vr = b.ProcessAllVetted(v)
-//	if len(vr.Proposals) != www.ProposalListPageSize {
-//		t.Fatalf("expected %v proposals, got %v", www.ProposalListPageSize,
-//			len(vr.Proposals))
-//	}
-//
-//	b.db.Close()
-//}
+// Tests that the proposal listings are paged with the Before and After
+// fields.
+func TestProposalListPaging(t *testing.T) {
+	b := createBackend(t)
+	nu, id := createAndVerifyUser(t, b)
+	user, _ := b.db.UserGet(nu.Email)
+
+	// The proposals share timestamps; the listings are ordered by token
+	// within the same second.
+	tokens := make([]string, www.ProposalListPageSize+1)
+	for i := 0; i < www.ProposalListPageSize+1; i++ {
+		_, npr, err := createNewProposal(b, t, user, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tokens[i] = npr.CensorshipRecord.Token
+	}
+
+	admin := &database.User{ID: user.ID + 1, Admin: true}
+	var u www.GetAllUnvetted
+	ur, err := b.ProcessAllUnvetted(u, admin)
+	assertSuccess(t, err)
+	if len(ur.Proposals) != www.ProposalListPageSize {
+		t.Fatalf("expected %v proposals, got %v", www.ProposalListPageSize,
+			len(ur.Proposals))
+	}
+	if ur.Total != uint(len(tokens)) {
+		t.Fatalf("expected %v total proposals, got %v", len(tokens),
+			ur.Total)
+	}
+
+	// Test fetching the next page using the After field.
+	u.After = ur.Proposals[len(ur.Proposals)-1].CensorshipRecord.Token
+	ur, err = b.ProcessAllUnvetted(u, admin)
+	assertSuccess(t, err)
+	if len(ur.Proposals) != 1 {
+		t.Fatalf("expected 1 proposal, got %v", len(ur.Proposals))
+	}
+	for _, v := range ur.Proposals {
+		if v.CensorshipRecord.Token == u.After {
+			t.Fatalf("Proposal with token provided for 'After' field should " +
+				"not exist in the next page")
+		}
+	}
+
+	// Test fetching the previous page using the Before field.
+	u.After = ""
+	u.Before = ur.Proposals[0].CensorshipRecord.Token
+	ur, err = b.ProcessAllUnvetted(u, admin)
+	assertSuccess(t, err)
+	if len(ur.Proposals) != www.ProposalListPageSize {
+		t.Fatalf("expected %v proposals, got %v", www.ProposalListPageSize,
+			len(ur.Proposals))
+	}
+	for _, v := range ur.Proposals {
+		if v.CensorshipRecord.Token == u.Before {
+			t.Fatalf("Proposal with token provided for 'Before' field should " +
+				"not exist in the previous page")
+		}
+	}
+
+	// Publish all the proposals.
+	for _, token := range tokens {
+		publishProposal(b, token, t, user, id)
+	}
+
+	var v www.GetAllVetted
+	vr, err := b.ProcessAllVetted(context.Background(), v)
+	assertSuccess(t, err)
+	if len(vr.Proposals) != www.ProposalListPageSize {
+		t.Fatalf("expected %v proposals, got %v", www.ProposalListPageSize,
+			len(vr.Proposals))
+	}
+	if vr.Total != uint(len(tokens)) {
+		t.Fatalf("expected %v total proposals, got %v", len(tokens),
+			vr.Total)
+	}
+
+	// Test fetching the next page using the After field.
+	v.After = vr.Proposals[len(vr.Proposals)-1].CensorshipRecord.Token
+	vr, err = b.ProcessAllVetted(context.Background(), v)
+	assertSuccess(t, err)
+	if len(vr.Proposals) != 1 {
+		t.Fatalf("expected 1 proposal, got %v", len(vr.Proposals))
+	}
+
+	// Test fetching the previous page using the Before field.
+	v.After = ""
+	v.Before = vr.Proposals[0].CensorshipRecord.Token
+	vr, err = b.ProcessAllVetted(context.Background(), v)
+	assertSuccess(t, err)
+	if len(vr.Proposals) != www.ProposalListPageSize {
+		t.Fatalf("expected %v proposals, got %v", www.ProposalListPageSize,
+			len(vr.Proposals))
+	}
+
+	b.db.Close()
+}
+
+// Tests that the proposal listings are filtered by status and submission
+// time.
+func TestProposalListFilters(t *testing.T) {
+	b := createBackend(t)
+	nu, id := createAndVerifyUser(t, b)
+	user, _ := b.db.UserGet(nu.Email)
+
+	_, npr, err := createNewProposal(b, t, user, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, npr2, err := createNewProposal(b, t, user, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	censorProposal(b, npr2.CensorshipRecord.Token, t, user, id)
+
+	// Only the requested statuses are listed.
+	admin := &database.User{ID: user.ID + 1, Admin: true}
+	ur, err := b.ProcessAllUnvetted(www.GetAllUnvetted{
+		Status: []www.PropStatusT{www.PropStatusCensored},
+	}, admin)
+	assertSuccess(t, err)
+	if len(ur.Proposals) != 1 || ur.Total != 1 ||
+		ur.Proposals[0].CensorshipRecord.Token != npr2.CensorshipRecord.Token {
+		t.Fatalf("unexpected censored proposals %v", ur.Proposals)
+	}
+
+	// Requesting statuses that the listing doesn't return lists nothing.
+	ur, err = b.ProcessAllUnvetted(www.GetAllUnvetted{
+		Status: []www.PropStatusT{www.PropStatusPublic},
+	}, admin)
+	assertSuccess(t, err)
+	if len(ur.Proposals) != 0 {
+		t.Fatalf("unexpected public proposals %v", ur.Proposals)
+	}
+
+	// Only the proposals submitted within the window are listed.
+	pdr := getProposalDetails(b, npr.CensorshipRecord.Token, t)
+	ts := pdr.Proposal.Timestamp
+	ur, err = b.ProcessAllUnvetted(www.GetAllUnvetted{
+		SubmittedAfter:  ts - 60,
+		SubmittedBefore: ts + 60,
+	}, admin)
+	assertSuccess(t, err)
+	if ur.Total != 2 {
+		t.Fatalf("expected 2 proposals, got %v", ur.Total)
+	}
+	ur, err = b.ProcessAllUnvetted(www.GetAllUnvetted{
+		SubmittedAfter: ts + 60,
+	}, admin)
+	assertSuccess(t, err)
+	if ur.Total != 0 {
+		t.Fatalf("expected no proposals, got %v", ur.Total)
+	}
+	ur, err = b.ProcessAllUnvetted(www.GetAllUnvetted{
+		SubmittedBefore: ts - 60,
+	}, admin)
+	assertSuccess(t, err)
+	if ur.Total != 0 {
+		t.Fatalf("expected no proposals, got %v", ur.Total)
+	}
+
+	b.db.Close()
+}
+
+// Tests that authors can replace the files of their unvetted proposals.
+func TestEditProposal(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	u, id := createAndVerifyUser(t, b)
+	user, _ := b.db.UserGet(u.Email)
+	_, npr, err := createNewProposalWithFiles(b, t, user, id, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := npr.CensorshipRecord.Token
+
+	payload := []byte("New version\nThe new description")
+	files := []pd.File{{
+		Name:    indexFile,
+		MIME:    "text/plain; charset=utf-8",
+		Payload: base64.StdEncoding.EncodeToString(payload),
+	}}
+	signature, err := getProposalSignature(files, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ep := www.EditProposal{
+		Token:     token,
+		Files:     convertPropFilesFromPD(files),
+		PublicKey: id.Public.String(),
+		Signature: signature,
+	}
+
+	// Only the author can edit the proposal.
+	u2, id2 := createAndVerifyUser(t, b)
+	user2, _ := b.db.UserGet(u2.Email)
+	ep2 := ep
+	ep2.PublicKey = id2.Public.String()
+	ep2.Signature, err = getProposalSignature(files, id2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.ProcessEditProposal(context.Background(), ep2, user2)
+	assertError(t, err, www.ErrorStatusUserNotAuthor)
+
+	epr, err := b.ProcessEditProposal(context.Background(), ep, user)
+	assertSuccess(t, err)
+	if epr.Proposal.Version != 2 || epr.Proposal.Name != "New version" ||
+		len(epr.Proposal.Files) != 1 ||
+		epr.Proposal.Status != www.PropStatusNotReviewed {
+		t.Fatalf("unexpected proposal %v", epr.Proposal)
+	}
+	pdr := getProposalDetails(b, token, t)
+	if pdr.Proposal.Version != 2 || pdr.Proposal.Signature != signature {
+		t.Fatalf("proposal was not updated in the inventory")
+	}
+
+	// Vetted proposals can't be edited.
+	publishProposal(b, token, t, user, id)
+	_, err = b.ProcessEditProposal(context.Background(), ep, user)
+	assertError(t, err, www.ErrorStatusWrongStatus)
+}
+
+// Tests that edits which carry images fit within the request body limit of
+// the edit route.
+func TestEditProposalWithImage(t *testing.T) {
+	p := &politeiawww{
+		backend: createBackend(t),
+		store:   sessions.NewCookieStore([]byte("edit proposal test key")),
+	}
+	p.cfg = p.backend.cfg
+	b := p.backend
+	defer b.db.Close()
+
+	u, id := createAndVerifyUser(t, b)
+	user, _ := b.db.UserGet(u.Email)
+	_, npr, err := createNewProposal(b, t, user, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := npr.CensorshipRecord.Token
+
+	payload := []byte("New version\nThe new description")
+	img := generatePNG(t, b.cfg.MaxProposalImageSize)
+	files := []pd.File{{
+		Name:    indexFile,
+		MIME:    "text/plain; charset=utf-8",
+		Payload: base64.StdEncoding.EncodeToString(payload),
+	}, {
+		Name:    "image.png",
+		MIME:    "image/png",
+		Payload: base64.StdEncoding.EncodeToString(img),
+	}}
+	signature, err := getProposalSignature(files, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(www.EditProposal{
+		Token:     token,
+		Files:     convertPropFilesFromPD(files),
+		PublicKey: id.Public.String(),
+		Signature: signature,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost,
+		www.PoliteiaWWWAPIRoute+www.RouteEditProposal,
+		bytes.NewReader(body))
+	for _, c := range loginCookies(t, p, u.Email) {
+		r.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	limitBody(maxRequestBodySize(p.cfg, www.RouteEditProposal),
+		p.isLoggedIn(p.handleEditProposal))(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %v: %v", w.Code, w.Body.String())
+	}
+
+	pdr := getProposalDetails(b, token, t)
+	if pdr.Proposal.Version != 2 || len(pdr.Proposal.Files) != 2 {
+		t.Fatalf("proposal was not updated in the inventory")
+	}
+}
+
+func TestProposalFile(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, _ := b.db.UserGet(nu.Email)
+	np, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+
+	mime, payload, err := b.ProcessProposalFile(context.Background(),
+		www.ProposalFile{
+			Token: token,
+			Name:  indexFile,
+		}, user)
+	assertSuccess(t, err)
+	if mime != np.Files[0].MIME ||
+		base64.StdEncoding.EncodeToString(payload) != np.Files[0].Payload {
+		t.Fatalf("unexpected file %v %q", mime, payload)
+	}
+
+	_, _, err = b.ProcessProposalFile(context.Background(),
+		www.ProposalFile{
+			Token: token,
+			Name:  "missing.md",
+		}, user)
+	assertError(t, err, www.ErrorStatusProposalFileNotFound)
+}
+
+// Tests that the status index follows the status changes of the proposals.
+func TestStatusIndex(t *testing.T) {
+	b := createBackend(t)
+	nu, id := createAndVerifyUser(t, b)
+	user, _ := b.db.UserGet(nu.Email)
+
+	tokens := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		_, npr, err := createNewProposal(b, t, user, id)
+		assertSuccess(t, err)
+		tokens = append(tokens, npr.CensorshipRecord.Token)
+	}
+	publishProposal(b, tokens[0], t, user, id)
+	censorProposal(b, tokens[1], t, user, id)
+
+	expected := map[www.PropStatusT]string{
+		www.PropStatusPublic:      tokens[0],
+		www.PropStatusCensored:    tokens[1],
+		www.PropStatusNotReviewed: tokens[2],
+	}
+	for status, token := range expected {
+		entries := b.statusIndex[status]
+		if len(entries) != 1 || entries[0].token != token {
+			t.Fatalf("unexpected entries for status %v: %v", status,
+				entries)
+		}
+	}
+}
+
+// Tests the sort orders of the vetted listing.
+func TestListedBefore(t *testing.T) {
+	listed := func(token string, ts int64, comments uint, voteEnd uint64, ended bool) listedProposal {
+		return listedProposal{
+			proposal: www.ProposalRecord{
+				Timestamp:        ts,
+				NumComments:      comments,
+				CensorshipRecord: www.CensorshipRecord{Token: token},
+			},
+			voteEnd:   voteEnd,
+			voteEnded: ended,
+		}
+	}
+	proposals := []listedProposal{
+		listed("a", 1, 5, 0, false),
+		listed("b", 2, 0, 100, true),
+		listed("c", 3, 5, 300, false),
+		listed("d", 4, 1, 400, false),
+	}
+	for k, views := range []uint64{2, 7, 0, 2} {
+		proposals[k].proposal.Views = views
+	}
+
+	for sortOrder, expected := range map[string]string{
+		"":                            "dcba",
+		www.ProposalSortNewest:        "dcba",
+		www.ProposalSortOldest:        "abcd",
+		www.ProposalSortMostCommented: "cadb",
+		www.ProposalSortVoteEnding:    "cdba",
+		www.ProposalSortMostViewed:    "bdac",
+	} {
+		sorted := append([]listedProposal(nil), proposals...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return listedBefore(sortOrder, sorted[i], sorted[j])
+		})
+		var tokens string
+		for _, v := range sorted {
+			tokens += v.proposal.CensorshipRecord.Token
+		}
+		if tokens != expected {
+			t.Fatalf("expected order %v for %q, got %v", expected,
+				sortOrder, tokens)
+		}
+	}
+
+	b := createBackend(t)
+	_, err := b.ProcessAllVetted(context.Background(),
+		www.GetAllVetted{Sort: "invalid"})
+	assertErrorWithContext(t, err, www.ErrorStatusInvalidInput,
+		[]string{"invalid"})
+}
@@ -16,7 +16,9 @@ import (
 	"github.com/decred/dcrd/chaincfg"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
 	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
 	"github.com/decred/politeia/util"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
@@ -33,8 +35,10 @@ func generateRandomEmail() string {
 	return generateRandomString(8) + "@example.com"
 }
 
+// generateRandomPassword returns a password that is long enough to pass the
+// entropy check however its letters are drawn.
 func generateRandomPassword() string {
-	return generateRandomString(www.PolicyPasswordMinChars)
+	return generateRandomString(2 * www.PolicyPasswordMinChars)
 }
 
 func generateIdentity() (*identity.FullIdentity, error) {
@@ -74,6 +78,16 @@ func createBackend(t *testing.T) *backend {
 		PaywallAmount: 1e7,
 		PaywallXpub:   "tpubVobLtToNtTq6TZNw4raWQok35PRPZou53vegZqNubtBTJMMFmuMpWybFCfweJ52N8uZJPZZdHE5SRnBBuuRPfC5jdNstfKjiAs8JtbYG9jx",
 		TestNet:       true,
+		StateStore:    stateStoreMemory,
+		BcryptCost:    bcrypt.MinCost, // Speed up the tests
+
+		MaxProposalImages:     www.PolicyMaxImages,
+		MaxProposalImageSize:  www.PolicyMaxImageSize,
+		MaxProposalMDs:        www.PolicyMaxMDs,
+		MaxProposalMDSize:     www.PolicyMaxMDSize,
+		MinProposalNameLength: www.PolicyMinProposalNameLength,
+		MaxProposalNameLength: www.PolicyMaxProposalNameLength,
+		MaxCommentLength:      www.PolicyMaxCommentLength,
 	}
 
 	b, err := NewBackend(cfg)
@@ -212,6 +226,109 @@ func TestProcessNewUserWithExpiredToken(t *testing.T) {
 	b.db.Close()
 }
 
+// Tests resending the verification email of a new user.
+func TestProcessResendVerification(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	// Unknown users get the same reply without a token.
+	reply, err := b.ProcessResendVerification(www.ResendVerification{
+		Email: generateRandomEmail(),
+	})
+	assertSuccess(t, err)
+	if reply.VerificationToken != "" {
+		t.Fatalf("unexpected token for unknown user")
+	}
+
+	nu, _ := createNewUserCommandWithIdentity(t)
+	nur, err := b.ProcessNewUser(nu)
+	assertSuccess(t, err)
+
+	// A new token replaces the previous one.
+	rv := www.ResendVerification{Email: nu.Email}
+	reply, err = b.ProcessResendVerification(rv)
+	assertSuccess(t, err)
+	if reply.VerificationToken == "" ||
+		reply.VerificationToken == nur.VerificationToken {
+		t.Fatalf("token was not replaced")
+	}
+	resent := reply.VerificationToken
+
+	// Nothing is sent during the cooldown.
+	reply, err = b.ProcessResendVerification(rv)
+	assertSuccess(t, err)
+	if reply.VerificationToken != "" {
+		t.Fatalf("token resent during the cooldown")
+	}
+
+	// An expired token is replaced.
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	verificationToken(user, verificationNewUser).Expiry = time.Now().Unix() - 1
+	assertSuccess(t, b.db.UserUpdate(*user))
+	assertSuccess(t, b.stateStore.Delete("resendverification:"+user.Email))
+	reply, err = b.ProcessResendVerification(rv)
+	assertSuccess(t, err)
+	if reply.VerificationToken == "" ||
+		reply.VerificationToken == resent {
+		t.Fatalf("expired token was not replaced")
+	}
+}
+
+// Tests creating a new user when signups require a proof of work.
+func TestProcessNewUserWithProofOfWork(t *testing.T) {
+	b := createBackend(t)
+	b.cfg.SignupPoWDifficulty = 8
+
+	// Missing challenge.
+	nu, _ := createNewUserCommandWithIdentity(t)
+	_, err := b.ProcessNewUser(nu)
+	assertError(t, err, www.ErrorStatusInvalidSignupChallenge)
+
+	// Wrong solution.
+	scr, err := b.ProcessSignupChallenge()
+	assertSuccess(t, err)
+	challenge, err := hex.DecodeString(scr.Challenge)
+	assertSuccess(t, err)
+	nonce := util.SolveProofOfWork(challenge, scr.Difficulty)
+	wrong := []byte{0}
+	for util.ProofOfWorkValid(challenge, wrong, scr.Difficulty) {
+		wrong[0]++
+	}
+	nu.Challenge = scr.Challenge
+	nu.Nonce = hex.EncodeToString(wrong)
+	_, err = b.ProcessNewUser(nu)
+	assertError(t, err, www.ErrorStatusInvalidProofOfWork)
+
+	// Forged and expired challenges.
+	forged := append([]byte{}, challenge...)
+	forged[signupChallengeRandomSize]++
+	expired, err := b.newSignupChallenge(time.Now().Add(-time.Second))
+	assertSuccess(t, err)
+	for _, c := range [][]byte{forged, expired} {
+		nu.Challenge = hex.EncodeToString(c)
+		nu.Nonce = hex.EncodeToString(util.SolveProofOfWork(c,
+			scr.Difficulty))
+		_, err = b.ProcessNewUser(nu)
+		assertError(t, err, www.ErrorStatusInvalidSignupChallenge)
+	}
+
+	// Valid solution.
+	nu.Challenge = scr.Challenge
+	nu.Nonce = hex.EncodeToString(nonce)
+	_, err = b.ProcessNewUser(nu)
+	assertSuccess(t, err)
+
+	// The challenge can't be reused.
+	nu2, _ := createNewUserCommandWithIdentity(t)
+	nu2.Challenge = nu.Challenge
+	nu2.Nonce = nu.Nonce
+	_, err = b.ProcessNewUser(nu2)
+	assertError(t, err, www.ErrorStatusInvalidSignupChallenge)
+
+	b.db.Close()
+}
+
 // Tests creating a new user with a malformed email.
 func TestProcessNewUserWithMalformedEmail(t *testing.T) {
 	b := createBackend(t)
@@ -233,7 +350,8 @@ func TestProcessNewUserWithMalformedPassword(t *testing.T) {
 	nu.Password = generateRandomString(www.PolicyPasswordMinChars - 1)
 
 	_, err := b.ProcessNewUser(nu)
-	assertError(t, err, www.ErrorStatusMalformedPassword)
+	assertErrorWithContext(t, err, www.ErrorStatusMalformedPassword,
+		[]string{www.PasswordReasonTooShort})
 
 	b.db.Close()
 }
@@ -361,6 +479,69 @@ func TestLoginWithVerifiedUser(t *testing.T) {
 	b.db.Close()
 }
 
+// Tests the TOTP enrollment and the logins that require a TOTP code.
+func TestProcessLoginWithTOTP(t *testing.T) {
+	b := createBackend(t)
+	u, _ := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(u.Email)
+	assertSuccess(t, err)
+
+	// Enroll.
+	str, err := b.ProcessSetTOTP(user)
+	assertSuccess(t, err)
+	secret, err := util.TOTPEncoding.DecodeString(str.Secret)
+	assertSuccess(t, err)
+	now := time.Now()
+	code := util.TOTPCode(secret, util.TOTPStep(now))
+	_, err = b.ProcessVerifyTOTP(user, www.VerifyTOTP{Code: "invalid"})
+	assertError(t, err, www.ErrorStatusInvalidTOTPCode)
+	vtr, err := b.ProcessVerifyTOTP(user, www.VerifyTOTP{Code: code})
+	assertSuccess(t, err)
+	if len(vtr.RecoveryCodes) != totpRecoveryCodes {
+		t.Fatalf("got %v recovery codes", len(vtr.RecoveryCodes))
+	}
+	_, err = b.ProcessSetTOTP(user)
+	assertError(t, err, www.ErrorStatusTOTPEnabled)
+
+	// The password alone is not enough anymore and codes can't be
+	// replayed.
+	l := www.Login{
+		Email:    u.Email,
+		Password: u.Password,
+	}
+	_, err = b.ProcessLogin(l)
+	assertError(t, err, www.ErrorStatusTOTPCodeRequired)
+	l.Code = code
+	_, err = b.ProcessLogin(l)
+	assertError(t, err, www.ErrorStatusInvalidTOTPCode)
+	l.Code = util.TOTPCode(secret, util.TOTPStep(now)+1)
+	lr, err := b.ProcessLogin(l)
+	assertSuccess(t, err)
+	if !lr.TOTPEnabled {
+		t.Fatalf("TOTP not enabled")
+	}
+
+	// Recovery codes can be used once.
+	l.Code = vtr.RecoveryCodes[0]
+	_, err = b.ProcessLogin(l)
+	assertSuccess(t, err)
+	_, err = b.ProcessLogin(l)
+	assertError(t, err, www.ErrorStatusInvalidTOTPCode)
+
+	// Disable.
+	user, err = b.db.UserGet(u.Email)
+	assertSuccess(t, err)
+	_, err = b.ProcessDisableTOTP(user, www.DisableTOTP{
+		Code: vtr.RecoveryCodes[1],
+	})
+	assertSuccess(t, err)
+	l.Code = ""
+	_, err = b.ProcessLogin(l)
+	assertSuccess(t, err)
+
+	b.db.Close()
+}
+
 // Tests changing a user's password with an incorrect current password
 // and a malformed new password.
 func TestProcessChangePasswordWithBadPasswords(t *testing.T) {
@@ -388,7 +569,8 @@ func TestProcessChangePasswordWithBadPasswords(t *testing.T) {
 		NewPassword:     generateRandomString(www.PolicyPasswordMinChars - 1),
 	}
 	_, err = b.ProcessChangePassword(u.Email, cp)
-	assertError(t, err, www.ErrorStatusMalformedPassword)
+	assertErrorWithContext(t, err, www.ErrorStatusMalformedPassword,
+		[]string{www.PasswordReasonTooShort})
 
 	b.db.Close()
 }
@@ -508,6 +690,72 @@ func TestProcessResetPassword(t *testing.T) {
 	b.db.Close()
 }
 
+// Tests changing a user's email address.
+func TestProcessChangeEmail(t *testing.T) {
+	b := createBackend(t)
+	u, _ := createAndVerifyUser(t, b)
+	other, _ := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(u.Email)
+	assertSuccess(t, err)
+
+	// Invalid requests.
+	_, err = b.ProcessChangeEmail(user, www.ChangeEmail{
+		NewEmail: generateRandomEmail(),
+		Password: generateRandomPassword(),
+	})
+	assertError(t, err, www.ErrorStatusInvalidEmailOrPassword)
+	_, err = b.ProcessChangeEmail(user, www.ChangeEmail{
+		NewEmail: "invalid",
+		Password: u.Password,
+	})
+	assertError(t, err, www.ErrorStatusMalformedEmail)
+	_, err = b.ProcessChangeEmail(user, www.ChangeEmail{
+		NewEmail: other.Email,
+		Password: u.Password,
+	})
+	assertError(t, err, www.ErrorStatusDuplicateEmail)
+
+	// The old email remains in use until the new one is verified.
+	newEmail := generateRandomEmail()
+	cer, err := b.ProcessChangeEmail(user, www.ChangeEmail{
+		NewEmail: newEmail,
+		Password: u.Password,
+	})
+	assertSuccess(t, err)
+	l := www.Login{
+		Email:    u.Email,
+		Password: u.Password,
+	}
+	_, err = b.ProcessLogin(l)
+	assertSuccess(t, err)
+
+	_, err = b.ProcessVerifyChangeEmail(user, www.VerifyChangeEmail{
+		VerificationToken: hex.EncodeToString(make([]byte,
+			www.VerificationTokenSize)),
+	})
+	assertError(t, err, www.ErrorStatusVerificationTokenInvalid)
+	user, err = b.ProcessVerifyChangeEmail(user, www.VerifyChangeEmail{
+		VerificationToken: cer.VerificationToken,
+	})
+	assertSuccess(t, err)
+	if len(user.EmailChanges) != 1 ||
+		user.EmailChanges[0].OldEmail != strings.ToLower(u.Email) {
+		t.Fatalf("unexpected email changes: %v", user.EmailChanges)
+	}
+
+	// Only the new email can be used to log in.
+	_, err = b.ProcessLogin(l)
+	assertError(t, err, www.ErrorStatusInvalidEmailOrPassword)
+	l.Email = newEmail
+	lr, err := b.ProcessLogin(l)
+	assertSuccess(t, err)
+	if lr.Email != strings.ToLower(newEmail) {
+		t.Fatalf("got email %v, want %v", lr.Email, newEmail)
+	}
+
+	b.db.Close()
+}
+
 // Tests fetching a user's own proposals.
 func TestProcessUserProposalsOwn(t *testing.T) {
 	b := createBackend(t)
@@ -562,3 +810,78 @@ func TestProcessUserProposalsOther(t *testing.T) {
 
 	b.db.Close()
 }
+
+// Tests that the verification tokens expire after the expiry of their type.
+func TestVerificationExpiry(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	b.cfg.NewUserTokenExpiry = time.Hour
+	b.cfg.ResetPasswordTokenExpiry = 2 * time.Hour
+	b.cfg.ChangeEmailTokenExpiry = 3 * time.Hour
+
+	tests := []struct {
+		typ    database.VerificationTokenT
+		expiry time.Duration
+	}{
+		{verificationNewUser, time.Hour},
+		{verificationResetPassword, 2 * time.Hour},
+		{verificationChangeEmail, 3 * time.Hour},
+		{verificationUpdateKey, www.VerificationExpiryHours * time.Hour},
+	}
+	for _, test := range tests {
+		var user database.User
+		now := time.Now()
+		_, err := b.issueVerificationToken(&user, test.typ)
+		assertSuccess(t, err)
+		expiry := verificationToken(&user, test.typ).Expiry
+		if expiry < now.Add(test.expiry).Unix() ||
+			expiry > time.Now().Add(test.expiry).Unix() {
+			t.Fatalf("type %v: got expiry %v, want %v", test.typ,
+				time.Unix(expiry, 0), now.Add(test.expiry))
+		}
+	}
+}
+
+// Tests that the login reply reports the previous logins of the user.
+func TestProcessLoginSecurityMetadata(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, _ := createAndVerifyUser(t, b)
+	login := func(password string) (*www.LoginReply, error) {
+		return b.ProcessLogin(www.Login{
+			Email:    nu.Email,
+			Password: password,
+		})
+	}
+
+	lr, err := login(nu.Password)
+	assertSuccess(t, err)
+	if lr.LastLogin != 0 || lr.FailedLogins != 0 || !lr.HasPaid {
+		t.Fatalf("unexpected first login reply %v", lr)
+	}
+	_, err = b.newLoginSession(nu.Email, "10.0.0.1", "agent",
+		sessionMaxAge)
+	assertSuccess(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = login("invalid")
+		assertError(t, err, www.ErrorStatusInvalidEmailOrPassword)
+	}
+	lr, err = login(nu.Password)
+	assertSuccess(t, err)
+	if lr.LastLogin == 0 || lr.FailedLogins != 2 {
+		t.Fatalf("unexpected login reply %v", lr)
+	}
+
+	// The failures are reset by the login.
+	_, err = b.newLoginSession(nu.Email, "10.0.0.1", "agent",
+		sessionMaxAge)
+	assertSuccess(t, err)
+	lr, err = login(nu.Password)
+	assertSuccess(t, err)
+	if lr.FailedLogins != 0 {
+		t.Fatalf("failed logins not reset: %v", lr.FailedLogins)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/decred/politeia/decredplugin"
+)
+
+// screenBallot sorts out the votes of a ballot that can't be counted because
+// their proposal is unknown or its vote did not start, so that they don't
+// reach politeiad.  It returns a receipt for every vote, in the order of the
+// ballot, and the indexes of the votes that must be forwarded to politeiad
+// to fill in the remaining receipts.
+func (b *backend) screenBallot(votes []decredplugin.CastVote) ([]decredplugin.CastVoteReply, []int) {
+	b.RLock()
+	defer b.RUnlock()
+
+	receipts := make([]decredplugin.CastVoteReply, len(votes))
+	forward := make([]int, 0, len(votes))
+	for k, v := range votes {
+		ir, ok := b.inventory[v.Token]
+		if !ok || ir.voting.StartBlockHeight == "" {
+			receipts[k].ClientSignature = v.Signature
+			receipts[k].Error = fmt.Sprintf("vote not active token %v",
+				v.Token)
+			continue
+		}
+		forward = append(forward, k)
+	}
+
+	return receipts, forward
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/decred/politeia/decredplugin"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestScreenBallot(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	var tokens []string
+	for i := 0; i < 2; i++ {
+		_, npr, err := createNewProposal(b, t, user, id)
+		assertSuccess(t, err)
+		tokens = append(tokens, npr.CensorshipRecord.Token)
+		publishProposal(b, tokens[i], t, user, id)
+	}
+
+	// Only the vote on the first proposal started.
+	b.Lock()
+	b.inventory[tokens[0]].voting = decredplugin.StartVoteReply{
+		StartBlockHeight: "100",
+		EndHeight:        "200",
+	}
+	b.Unlock()
+
+	votes := []decredplugin.CastVote{
+		{Token: tokens[1], Ticket: "a", VoteBit: "1", Signature: "s0"},
+		{Token: tokens[0], Ticket: "a", VoteBit: "1", Signature: "s1"},
+		{Token: "unknown", Ticket: "a", VoteBit: "1", Signature: "s2"},
+		{Token: tokens[0], Ticket: "b", VoteBit: "2", Signature: "s3"},
+	}
+	receipts, forward := b.screenBallot(votes)
+	if len(receipts) != 4 || len(forward) != 2 || forward[0] != 1 ||
+		forward[1] != 3 {
+		t.Fatalf("unexpected screening %v %v", receipts, forward)
+	}
+	if receipts[0].Error == "" || receipts[0].ClientSignature != "s0" ||
+		receipts[2].Error == "" || receipts[1].Error != "" {
+		t.Fatalf("unexpected receipts %v", receipts)
+	}
+
+	// A ballot without votes to count doesn't reach politeiad.
+	br, err := b.ProcessCastVotes(context.Background(), &www.Ballot{
+		Votes: []decredplugin.CastVote{votes[0], votes[2]},
+	})
+	assertSuccess(t, err)
+	if len(br.Receipts) != 2 || br.Receipts[0].Error == "" ||
+		br.Receipts[1].Error == "" || br.Receipts[1].Signature != "" {
+		t.Fatalf("unexpected receipts %v", br.Receipts)
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+const (
+	// maxSNSCertSize is the maximum size of an SNS signing certificate.
+	maxSNSCertSize = 64 * 1024
+)
+
+var (
+	// snsCertHost matches the hosts that SNS signing certificates are
+	// downloaded from.
+	snsCertHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+)
+
+// bounceEvent is a provider agnostic notification that an email could not be
+// delivered to an address or that the recipient complained about it.
+type bounceEvent struct {
+	Email     string // Affected email address
+	Complaint bool   // Set if the recipient marked the email as spam
+}
+
+// sesNotification is an AWS SNS message that carries an SES notification.
+type sesNotification struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+	SubscribeURL     string `json:"SubscribeURL"`
+}
+
+// signedString returns the string that SNS signs for the notification, see
+// https://docs.aws.amazon.com/sns/latest/dg/sns-verify-signature-of-message.html
+func (n *sesNotification) signedString() string {
+	var fields []string
+	switch n.Type {
+	case "Notification":
+		fields = []string{"Message", n.Message, "MessageId", n.MessageID}
+		if n.Subject != "" {
+			fields = append(fields, "Subject", n.Subject)
+		}
+		fields = append(fields, "Timestamp", n.Timestamp,
+			"TopicArn", n.TopicArn, "Type", n.Type)
+	default:
+		fields = []string{"Message", n.Message, "MessageId", n.MessageID,
+			"SubscribeURL", n.SubscribeURL, "Timestamp", n.Timestamp,
+			"Token", n.Token, "TopicArn", n.TopicArn, "Type", n.Type}
+	}
+	return strings.Join(fields, "\n") + "\n"
+}
+
+type sesRecipient struct {
+	EmailAddress string `json:"emailAddress"`
+}
+
+// sesMessage is the SES bounce or complaint notification.
+type sesMessage struct {
+	NotificationType string `json:"notificationType"`
+	Bounce           struct {
+		BounceType        string         `json:"bounceType"`
+		BouncedRecipients []sesRecipient `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint struct {
+		ComplainedRecipients []sesRecipient `json:"complainedRecipients"`
+	} `json:"complaint"`
+}
+
+// snsCert returns the SNS signing certificate at the provided URL.  The URL
+// must point to SNS over https.  Certificates are cached by URL since SNS
+// signs every message with the same certificate until it is rotated.
+func (b *backend) snsCert(certURL string) (*x509.Certificate, error) {
+	u, err := url.Parse(certURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" || !snsCertHost.MatchString(u.Hostname()) {
+		return nil, fmt.Errorf("invalid signing certificate url: %v",
+			certURL)
+	}
+
+	b.snsCertsMtx.Lock()
+	cert, ok := b.snsCerts[certURL]
+	b.snsCertsMtx.Unlock()
+	if ok {
+		return cert, nil
+	}
+
+	client := &http.Client{Timeout: mailerTimeout}
+	r, err := client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signing certificate: %v", r.Status)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSNSCertSize))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(body)
+	if block == nil {
+		return nil, fmt.Errorf("signing certificate is not pem encoded")
+	}
+	cert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	b.snsCertsMtx.Lock()
+	b.snsCerts[certURL] = cert
+	b.snsCertsMtx.Unlock()
+
+	return cert, nil
+}
+
+// verifySNSNotification verifies that the provided SNS message was signed by
+// SNS.
+func (b *backend) verifySNSNotification(n *sesNotification) error {
+	var hash crypto.Hash
+	switch n.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+	case "2":
+		hash = crypto.SHA256
+	default:
+		return fmt.Errorf("unsupported signature version: %v",
+			n.SignatureVersion)
+	}
+	sig, err := base64.StdEncoding.DecodeString(n.Signature)
+	if err != nil {
+		return err
+	}
+
+	cert, err := b.snsCert(n.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return fmt.Errorf("signing certificate expired")
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("signing certificate is not an rsa certificate")
+	}
+
+	h := hash.New()
+	h.Write([]byte(n.signedString()))
+	return rsa.VerifyPKCS1v15(pub, hash, h.Sum(nil), sig)
+}
+
+// parseSESNotification returns the bounce events of the provided SNS message.
+// Messages that aren't signed by SNS are rejected.  Subscription
+// confirmations are confirmed so that SNS starts delivering notifications.
+// Transient bounces are ignored.
+func (b *backend) parseSESNotification(body []byte) ([]bounceEvent, error) {
+	var n sesNotification
+	err := json.Unmarshal(body, &n)
+	if err != nil {
+		return nil, err
+	}
+	err = b.verifySNSNotification(&n)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Type {
+	case "SubscriptionConfirmation":
+		return nil, confirmSESSubscription(n.SubscribeURL)
+	case "Notification":
+	default:
+		return nil, nil
+	}
+
+	var m sesMessage
+	err = json.Unmarshal([]byte(n.Message), &m)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []bounceEvent
+	switch m.NotificationType {
+	case "Bounce":
+		if m.Bounce.BounceType != "Permanent" {
+			return nil, nil
+		}
+		for _, v := range m.Bounce.BouncedRecipients {
+			events = append(events, bounceEvent{
+				Email: v.EmailAddress,
+			})
+		}
+	case "Complaint":
+		for _, v := range m.Complaint.ComplainedRecipients {
+			events = append(events, bounceEvent{
+				Email:     v.EmailAddress,
+				Complaint: true,
+			})
+		}
+	}
+
+	return events, nil
+}
+
+// confirmSESSubscription confirms an SNS subscription by visiting the
+// provided subscribe URL, which must point to AWS.
+func confirmSESSubscription(subscribeURL string) error {
+	u, err := url.Parse(subscribeURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" || !strings.HasSuffix(u.Hostname(),
+		".amazonaws.com") {
+		log.Warnf("Ignoring SNS subscription confirmation for %v",
+			subscribeURL)
+		return nil
+	}
+
+	client := &http.Client{Timeout: mailerTimeout}
+	r, err := client.Get(u.String())
+	if err != nil {
+		return err
+	}
+	r.Body.Close()
+
+	log.Infof("Confirmed SNS subscription")
+	return nil
+}
+
+// sendgridEvent is a single event of the Sendgrid event webhook.
+type sendgridEvent struct {
+	Email string `json:"email"`
+	Event string `json:"event"`
+	Type  string `json:"type"` // Bounce type, bounce or blocked
+}
+
+// parseSendgridEvents returns the bounce events of the provided Sendgrid
+// event webhook payload.  Blocked emails are ignored since they are usually
+// temporary.
+func parseSendgridEvents(body []byte) ([]bounceEvent, error) {
+	var se []sendgridEvent
+	err := json.Unmarshal(body, &se)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []bounceEvent
+	for _, v := range se {
+		switch v.Event {
+		case "bounce":
+			if v.Type == "blocked" {
+				continue
+			}
+			events = append(events, bounceEvent{
+				Email: v.Email,
+			})
+		case "spamreport":
+			events = append(events, bounceEvent{
+				Email:     v.Email,
+				Complaint: true,
+			})
+		}
+	}
+
+	return events, nil
+}
+
+// ProcessBounceEvents marks the email addresses of the provided events as
+// undeliverable.  No further notification emails are sent to these addresses
+// until the user verifies the address again.
+func (b *backend) ProcessBounceEvents(events []bounceEvent) error {
+	for _, e := range events {
+		user, err := b.db.UserGet(e.Email)
+		if err != nil {
+			if err == database.ErrUserNotFound {
+				continue
+			}
+			return err
+		}
+		if user.EmailUndeliverable {
+			continue
+		}
+
+		log.Infof("Marking email of user %v as undeliverable "+
+			"(complaint: %v)", user.ID, e.Complaint)
+
+		user.EmailUndeliverable = true
+		err = b.db.UserUpdate(*user)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testSNSCertURL = "https://sns.us-east-1.amazonaws.com/" +
+	"SimpleNotificationService-test.pem"
+
+// signSNSNotification signs the provided SNS message with the provided key
+// and returns its encoding.
+func signSNSNotification(t *testing.T, key *rsa.PrivateKey, n sesNotification) []byte {
+	t.Helper()
+
+	hash := crypto.SHA256
+	if n.SignatureVersion == "1" {
+		hash = crypto.SHA1
+	}
+	h := hash.New()
+	h.Write([]byte(n.signedString()))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, hash, h.Sum(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Signature = base64.StdEncoding.EncodeToString(sig)
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestParseSESNotification(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	// Register a self signed certificate as the SNS signing certificate.
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl,
+		&key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.snsCerts[testSNSCertURL] = cert
+
+	notification := func(message string) sesNotification {
+		return sesNotification{
+			Type:             "Notification",
+			MessageID:        "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+			TopicArn:         "arn:aws:sns:us-east-1:123456789012:ses",
+			Message:          message,
+			Timestamp:        "2018-10-16T16:45:00.000Z",
+			SignatureVersion: "2",
+			SigningCertURL:   testSNSCertURL,
+		}
+	}
+	permanent := `{"notificationType":"Bounce","bounce":{"bounceType":` +
+		`"Permanent","bouncedRecipients":[{"emailAddress":` +
+		`"a@example.com"}]}}`
+	transient := `{"notificationType":"Bounce","bounce":{"bounceType":` +
+		`"Transient","bouncedRecipients":[{"emailAddress":` +
+		`"a@example.com"}]}}`
+	complaint := `{"notificationType":"Complaint","complaint":` +
+		`{"complainedRecipients":[{"emailAddress":"b@example.com"}]}}`
+
+	sha1 := notification(permanent)
+	sha1.SignatureVersion = "1"
+	subject := notification(complaint)
+	subject.Subject = "Amazon SES Email Event Notification"
+
+	tampered := signSNSNotification(t, key, notification(transient))
+	var n sesNotification
+	err = json.Unmarshal(tampered, &n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Message = permanent
+	tampered, err = json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	badURL := notification(permanent)
+	badURL.SigningCertURL = "https://example.com/" +
+		"SimpleNotificationService-test.pem"
+	unsigned, err := json.Marshal(notification(permanent))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name    string
+		body    []byte
+		events  []bounceEvent
+		wantErr bool
+	}{
+		{"permanent bounce",
+			signSNSNotification(t, key, notification(permanent)),
+			[]bounceEvent{{Email: "a@example.com"}}, false},
+		{"transient bounce",
+			signSNSNotification(t, key, notification(transient)),
+			nil, false},
+		{"complaint", signSNSNotification(t, key, subject),
+			[]bounceEvent{{Email: "b@example.com", Complaint: true}},
+			false},
+		{"signature version 1", signSNSNotification(t, key, sha1),
+			[]bounceEvent{{Email: "a@example.com"}}, false},
+		{"unsigned", unsigned, nil, true},
+		{"tampered", tampered, nil, true},
+		{"other key",
+			signSNSNotification(t, otherKey, notification(permanent)),
+			nil, true},
+		{"certificate not on sns",
+			signSNSNotification(t, key, badURL), nil, true},
+		{"invalid json", []byte("{"), nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, err := b.parseSESNotification(test.body)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err,
+					test.wantErr)
+			}
+			if !reflect.DeepEqual(events, test.events) {
+				t.Fatalf("got %v, want %v", events, test.events)
+			}
+		})
+	}
+}
+
+func TestParseSendgridEvents(t *testing.T) {
+	var tests = []struct {
+		name    string
+		body    string
+		events  []bounceEvent
+		wantErr bool
+	}{
+		{"bounce",
+			`[{"email":"a@example.com","event":"bounce","type":"bounce"}]`,
+			[]bounceEvent{{Email: "a@example.com"}}, false},
+		{"blocked",
+			`[{"email":"a@example.com","event":"bounce","type":"blocked"}]`,
+			nil, false},
+		{"spam report",
+			`[{"email":"b@example.com","event":"spamreport"}]`,
+			[]bounceEvent{{Email: "b@example.com", Complaint: true}},
+			false},
+		{"other events",
+			`[{"email":"a@example.com","event":"delivered"},` +
+				`{"email":"a@example.com","event":"open"}]`,
+			nil, false},
+		{"batch",
+			`[{"email":"a@example.com","event":"bounce","type":"bounce"},` +
+				`{"email":"b@example.com","event":"spamreport"}]`,
+			[]bounceEvent{{Email: "a@example.com"},
+				{Email: "b@example.com", Complaint: true}}, false},
+		{"invalid json", `{"email":"a@example.com"}`, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, err := parseSendgridEvents([]byte(test.body))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err,
+					test.wantErr)
+			}
+			if !reflect.DeepEqual(events, test.events) {
+				t.Fatalf("got %v, want %v", events, test.events)
+			}
+		})
+	}
+}
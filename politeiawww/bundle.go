@@ -0,0 +1,290 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+// errBundleTooLarge is returned when the decompressed entries of a bundle
+// exceed the size that a bundle may have.
+var errBundleTooLarge = errors.New("bundle is too large")
+
+// bundleCensorshipRecord is the censorship record file of a proposal bundle.
+// It carries the key of politeiad so that the bundle can be verified offline.
+type bundleCensorshipRecord struct {
+	CensorshipRecord www.CensorshipRecord `json:"censorshiprecord"`
+	ServerPublicKey  string               `json:"serverpublickey"` // Key of politeiad
+}
+
+// bundleWriter writes the files of a proposal bundle into an archive.
+type bundleWriter interface {
+	writeFile(name string, data []byte) error
+	close() error
+}
+
+// tarBundleWriter writes a gzipped tar archive.
+type tarBundleWriter struct {
+	gz      *gzip.Writer
+	tw      *tar.Writer
+	modTime time.Time
+}
+
+func (w *tarBundleWriter) writeFile(name string, data []byte) error {
+	err := w.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: w.modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.tw.Write(data)
+	return err
+}
+
+func (w *tarBundleWriter) close() error {
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+// zipBundleWriter writes a zip archive.
+type zipBundleWriter struct {
+	zw      *zip.Writer
+	modTime time.Time
+}
+
+func (w *zipBundleWriter) writeFile(name string, data []byte) error {
+	h := &zip.FileHeader{
+		Name:   name,
+		Method: zip.Deflate,
+	}
+	h.SetModTime(w.modTime)
+	f, err := w.zw.CreateHeader(h)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func (w *zipBundleWriter) close() error {
+	return w.zw.Close()
+}
+
+// newBundleWriter returns a writer of archives of the provided format into
+// the provided buffer.  The files of the archive are dated with the provided
+// time so that the archive of a proposal doesn't change between downloads.
+func newBundleWriter(format string, buf *bytes.Buffer, modTime time.Time) (bundleWriter, string, error) {
+	switch format {
+	case "", www.ProposalBundleFormatTarGz:
+		gz := gzip.NewWriter(buf)
+		return &tarBundleWriter{
+			gz:      gz,
+			tw:      tar.NewWriter(gz),
+			modTime: modTime,
+		}, "application/gzip", nil
+	case www.ProposalBundleFormatZip:
+		return &zipBundleWriter{
+			zw:      zip.NewWriter(buf),
+			modTime: modTime,
+		}, "application/zip", nil
+	}
+	return nil, "", www.UserError{
+		ErrorCode:    www.ErrorStatusInvalidInput,
+		ErrorContext: []string{format},
+	}
+}
+
+// readBundle returns the entries of the provided archive of the provided
+// format by name.  Archives whose entries add up to more than maxSize bytes
+// are rejected so that they can't exhaust the memory.
+func readBundle(format string, bundle []byte, maxSize int64) (map[string][]byte, error) {
+	entries := make(map[string][]byte)
+	read := func(r io.Reader) ([]byte, error) {
+		data, err := ioutil.ReadAll(io.LimitReader(r, maxSize+1))
+		if err != nil {
+			return nil, err
+		}
+		maxSize -= int64(len(data))
+		if maxSize < 0 {
+			return nil, errBundleTooLarge
+		}
+		return data, nil
+	}
+
+	switch format {
+	case "", www.ProposalBundleFormatTarGz:
+		gz, err := gzip.NewReader(bytes.NewReader(bundle))
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(gz)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			if h.Typeflag != tar.TypeReg {
+				continue
+			}
+			entries[h.Name], err = read(tr)
+			if err != nil {
+				return nil, err
+			}
+		}
+	case www.ProposalBundleFormatZip:
+		zr, err := zip.NewReader(bytes.NewReader(bundle),
+			int64(len(bundle)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			r, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			entries[f.Name], err = read(r)
+			r.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, www.UserError{
+			ErrorCode:    www.ErrorStatusInvalidInput,
+			ErrorContext: []string{format},
+		}
+	}
+
+	return entries, nil
+}
+
+// ProcessProposalBundle returns an archive of a proposal for offline
+// archival and verification, along with its MIME type and its file name.
+// The archive holds the proposal, its files, its metadata streams and its
+// censorship record.  Bundles of unvetted proposals are only available to
+// the users that can see their files.
+func (b *backend) ProcessProposalBundle(ctx context.Context, pb www.ProposalBundle, user *database.User) (string, string, []byte, error) {
+	log.Tracef("ProcessProposalBundle: %v", pb.Token)
+
+	pdr, err := b.ProcessProposalDetails(ctx, www.ProposalsDetails{
+		Token: pb.Token,
+	}, user)
+	if err != nil {
+		return "", "", nil, err
+	}
+	p := pdr.Proposal
+	if len(p.Files) == 0 {
+		return "", "", nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+
+	b.RLock()
+	ir, ok := b.inventory[pb.Token]
+	if !ok {
+		b.RUnlock()
+		return "", "", nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+	metadata := ir.record.Metadata
+	b.RUnlock()
+
+	var buf bytes.Buffer
+	w, mimeType, err := newBundleWriter(pb.Format, &buf,
+		time.Unix(p.Timestamp, 0))
+	if err != nil {
+		return "", "", nil, err
+	}
+	dir := pb.Token
+
+	// The files are stored decoded; the proposal only holds their
+	// names, MIME types and digests.
+	files := p.Files
+	p.Files = make([]www.File, 0, len(files))
+	for _, v := range files {
+		payload, err := base64.StdEncoding.DecodeString(v.Payload)
+		if err != nil {
+			return "", "", nil, err
+		}
+		err = w.writeFile(path.Join(dir, "files", path.Base(v.Name)),
+			payload)
+		if err != nil {
+			return "", "", nil, err
+		}
+		v.Payload = ""
+		p.Files = append(p.Files, v)
+	}
+
+	proposal, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return "", "", nil, err
+	}
+	err = w.writeFile(path.Join(dir, "proposal.json"), proposal)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	cr := bundleCensorshipRecord{
+		CensorshipRecord: p.CensorshipRecord,
+	}
+	if b.cfg.Identity != nil {
+		cr.ServerPublicKey = b.cfg.Identity.String()
+	}
+	censorship, err := json.MarshalIndent(cr, "", "  ")
+	if err != nil {
+		return "", "", nil, err
+	}
+	err = w.writeFile(path.Join(dir, "censorshiprecord.json"), censorship)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	// Metadata streams are named like in politeiad.  Spam scores are
+	// only meant for the admins.
+	for _, v := range metadata {
+		if v.ID == mdStreamSpam {
+			continue
+		}
+		err = w.writeFile(path.Join(dir, "metadata",
+			fmt.Sprintf("%02v.metadata.txt", v.ID)), []byte(v.Payload))
+		if err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	err = w.close()
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	format := pb.Format
+	if format == "" {
+		format = www.ProposalBundleFormatTarGz
+	}
+	return mimeType, pb.Token + "." + format, buf.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"path"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestProposalBundle(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	np, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+	index, err := base64.StdEncoding.DecodeString(np.Files[0].Payload)
+	assertSuccess(t, err)
+
+	for _, format := range []string{www.ProposalBundleFormatTarGz,
+		www.ProposalBundleFormatZip} {
+		_, name, bundle, err := b.ProcessProposalBundle(
+			context.Background(), www.ProposalBundle{
+				Token:  token,
+				Format: format,
+			}, user)
+		assertSuccess(t, err)
+		if name != token+"."+format {
+			t.Fatalf("unexpected name %v", name)
+		}
+
+		files, err := readBundle(format, bundle, int64(len(bundle))*100)
+		assertSuccess(t, err)
+		if !bytes.Equal(files[path.Join(token, "files", indexFile)],
+			index) {
+			t.Fatalf("unexpected index file in %v bundle", format)
+		}
+		var p www.ProposalRecord
+		err = json.Unmarshal(files[path.Join(token, "proposal.json")], &p)
+		assertSuccess(t, err)
+		if p.CensorshipRecord.Token != token || p.Files[0].Payload != "" {
+			t.Fatalf("unexpected proposal in %v bundle: %v", format, p)
+		}
+		var cr bundleCensorshipRecord
+		err = json.Unmarshal(files[path.Join(token,
+			"censorshiprecord.json")], &cr)
+		assertSuccess(t, err)
+		if cr.CensorshipRecord.Token != token {
+			t.Fatalf("unexpected censorship record in %v bundle: %v",
+				format, cr)
+		}
+		if _, ok := files[path.Join(token, "metadata",
+			"00.metadata.txt")]; !ok {
+			t.Fatalf("missing general metadata in %v bundle", format)
+		}
+	}
+
+	_, _, _, err = b.ProcessProposalBundle(context.Background(),
+		www.ProposalBundle{
+			Token:  token,
+			Format: "rar",
+		}, user)
+	assertErrorWithContext(t, err, www.ErrorStatusInvalidInput,
+		[]string{"rar"})
+}
@@ -0,0 +1,347 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+// A cluster is made up of a primary politeiawww instance and of replicas that
+// run against the same politeiad.  The instances don't share their state.
+// Instead the primary owns all of it: the user database, the sessions, the
+// signup challenges, the rate limits, the proposal views and the emails.  The
+// replicas only serve the anonymous requests of replicaRoutes from their own
+// inventory cache and forward all other requests to the primary.  The primary
+// publishes the changes that the replicas need to keep their cache in sync as
+// cluster events.
+
+const (
+	// clusterTokenHeader is the header that carries the shared secret that
+	// authenticates the members of a cluster to each other.
+	clusterTokenHeader = "X-Politeia-Cluster-Token"
+
+	// clusterTimeout is the timeout of the requests between the members
+	// of a cluster.
+	clusterTimeout = 30 * time.Second
+)
+
+// replicaRoutes are the GET routes that replicas serve themselves.  They only
+// read the proposal inventory, the comments and the votes, which the replicas
+// cache, and the IP bans and features, which the primary publishes.  Routes
+// that read or modify any other state, such as the signup challenges, the
+// proposal views that the proposal details count or the vote streams that the
+// ballots of the primary feed, are forwarded.
+var replicaRoutes = map[string]bool{
+	www.RouteAllVetted:         true,
+	www.RouteProposalFile:      true,
+	www.RouteProposalBundle:    true,
+	www.RouteStatusHistory:     true,
+	www.RouteProposalsRSS:      true,
+	www.RouteProposalsAtom:     true,
+	www.RoutePolicy:            true,
+	www.RouteCommentsGet:       true,
+	www.RouteActiveVote:        true,
+	www.RouteActiveVoteSummary: true,
+	www.RouteVoteStatus:        true,
+	www.RouteVoteReceipt:       true,
+	www.RouteFeatures:          true,
+}
+
+// clusterEventT is the type of a cluster event.
+type clusterEventT string
+
+const (
+	clusterEventInventory clusterEventT = "inventory" // Proposal changed
+	clusterEventIPBan     clusterEventT = "ipban"     // Network banned
+	clusterEventIPUnban   clusterEventT = "ipunban"   // Network unbanned
+	clusterEventFeature   clusterEventT = "feature"   // Feature toggled
+)
+
+// clusterEvent is a change that the primary publishes to the replicas.
+type clusterEvent struct {
+	Type    clusterEventT   `json:"type"`
+	Token   string          `json:"token,omitempty"`   // Proposal token
+	IPBan   *database.IPBan `json:"ipban,omitempty"`   // New IP ban
+	Network string          `json:"network,omitempty"` // Unbanned network
+	Feature string          `json:"feature,omitempty"` // Toggled feature
+	Enabled bool            `json:"enabled,omitempty"` // Feature state
+}
+
+// isReplica returns true if this instance is a replica of a cluster.
+func (b *backend) isReplica() bool {
+	return b.cfg.ClusterPrimary != ""
+}
+
+// newClusterClient returns the client used to contact the other members of
+// the cluster.
+func newClusterClient(cfg *config) (*http.Client, error) {
+	client, err := util.NewClient(false, cfg.ClusterCert)
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = clusterTimeout
+	return client, nil
+}
+
+// validClusterToken returns true if the provided request was sent by a member
+// of the cluster.
+func validClusterToken(cfg *config, r *http.Request) bool {
+	token := r.Header.Get(clusterTokenHeader)
+	return cfg.ClusterToken != "" && subtle.ConstantTimeCompare(
+		[]byte(token), []byte(cfg.ClusterToken)) == 1
+}
+
+// publish sends the provided event to the replicas in the background.
+// Delivery is best effort; a replica that misses an event catches up when it
+// reloads its inventory.
+func (b *backend) publish(e clusterEvent) {
+	if len(b.cfg.ClusterPeers) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Errorf("publish %v: %v", e.Type, err)
+		return
+	}
+	for _, v := range b.cfg.ClusterPeers {
+		go func(peer string) {
+			err := b.sendClusterEvent(peer, payload)
+			if err != nil {
+				log.Errorf("publish %v to %v: %v", e.Type, peer, err)
+			}
+		}(v)
+	}
+}
+
+// sendClusterEvent sends the provided encoded event to the provided replica.
+func (b *backend) sendClusterEvent(peer string, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(peer,
+		"/")+www.PoliteiaWWWAPIRoute+www.RouteClusterEvent,
+		bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(clusterTokenHeader, b.cfg.ClusterToken)
+
+	r, err := b.clusterClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v", r.Status)
+	}
+	return nil
+}
+
+// publishComments pushes the comment journal of the provided proposal to
+// politeiad so that the replicas pick up the new comments.  Comments are
+// otherwise only flushed on startup and shutdown.
+func (b *backend) publishComments(token string) {
+	if len(b.cfg.ClusterPeers) == 0 || b.test {
+		return
+	}
+
+	go func() {
+		// Flushes overwrite the comments in politeiad so they must not
+		// overtake each other.
+		b.commentFlushMtx.Lock()
+		err := b.flushCommentJournal(context.Background(), token)
+		b.commentFlushMtx.Unlock()
+		if err != nil {
+			log.Errorf("publishComments %v: %v", token, err)
+			return
+		}
+
+		b.publish(clusterEvent{
+			Type:  clusterEventInventory,
+			Token: token,
+		})
+	}()
+}
+
+// fetchRecord fetches the record with the provided token, without its files,
+// from politeiad.
+func (b *backend) fetchRecord(ctx context.Context, token string) (*pd.Record, error) {
+	for _, route := range []string{pd.GetVettedRoute, pd.GetUnvettedRoute} {
+		challenge, err := util.Random(pd.ChallengeSize)
+		if err != nil {
+			return nil, err
+		}
+		responseBody, err := b.makeRequest(ctx, http.MethodPost, route,
+			pd.GetVetted{
+				Token:     token,
+				Challenge: hex.EncodeToString(challenge),
+			})
+		if err != nil {
+			return nil, err
+		}
+
+		// The vetted and unvetted replies are identical.
+		var reply pd.GetVettedReply
+		err = json.Unmarshal(responseBody, &reply)
+		if err != nil {
+			return nil, fmt.Errorf("Could not unmarshal "+
+				"GetVettedReply: %v", err)
+		}
+		err = util.VerifyChallenge(b.cfg.Identity, challenge,
+			reply.Response)
+		if err != nil {
+			return nil, err
+		}
+
+		if reply.Record.Status != pd.RecordStatusNotFound {
+			reply.Record.Files = nil
+			return &reply.Record, nil
+		}
+	}
+	return nil, errRecordNotFound
+}
+
+// reloadInventoryRecord replaces the cached record with the provided token
+// with the one in politeiad.
+//
+// This function must be called WITHOUT the mutex held.
+func (b *backend) reloadInventoryRecord(ctx context.Context, token string) error {
+	record, err := b.fetchRecord(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	// The record is part of the inventory once it is loaded.
+	if b.inventory == nil {
+		return nil
+	}
+
+	b.updateInventoryRecord(*record)
+	b.loadRecord(*record)
+
+	return nil
+}
+
+// ProcessClusterEvent applies an event that the primary published.
+func (b *backend) ProcessClusterEvent(ctx context.Context, e clusterEvent) error {
+	log.Debugf("ProcessClusterEvent: %v", e.Type)
+
+	switch e.Type {
+	case clusterEventInventory:
+		return b.reloadInventoryRecord(ctx, e.Token)
+	case clusterEventIPBan:
+		if e.IPBan == nil {
+			break
+		}
+		_, err := b.banIP(*e.IPBan)
+		return err
+	case clusterEventIPUnban:
+		err := b.unbanIP(e.Network)
+		if err == database.ErrIPBanNotFound {
+			return nil
+		}
+		return err
+	case clusterEventFeature:
+		if !validFeature(e.Feature) {
+			break
+		}
+		b.setFeature(e.Feature, e.Enabled)
+		return nil
+	}
+
+	return www.UserError{
+		ErrorCode: www.ErrorStatusInvalidInput,
+	}
+}
+
+// newPrimaryProxy returns the reverse proxy that forwards requests from a
+// replica to the primary.  The proxy authenticates itself so that the primary
+// trusts the client address that it forwards.
+func newPrimaryProxy(cfg *config, client *http.Client) (*httputil.ReverseProxy, error) {
+	u, err := url.Parse(cfg.ClusterPrimary)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(u)
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Host = u.Host
+		r.Header.Set(clusterTokenHeader, cfg.ClusterToken)
+	}
+	proxy.Transport = client.Transport
+	return proxy, nil
+}
+
+// forwardToPrimary forwards the requests of the provided route to the primary
+// when this instance is a replica.  Replicas only serve the anonymous requests
+// of replicaRoutes themselves since they share neither the user database nor
+// the sessions nor any other state of the primary.
+func (p *politeiawww) forwardToPrimary(method, route string, perm permission, f http.HandlerFunc) http.HandlerFunc {
+	if p.primary == nil || route == www.RouteClusterEvent {
+		return f
+	}
+	forward := logging(p.primary.ServeHTTP)
+	if method != http.MethodGet || perm != permissionPublic ||
+		!replicaRoutes[route] {
+		return forward
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie(www.CookieSession); err == nil {
+			forward(w, r)
+			return
+		}
+		f(w, r)
+	}
+}
+
+// handleClusterEvent handles the events that the primary publishes.
+func (p *politeiawww) handleClusterEvent(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleClusterEvent")
+
+	if !validClusterToken(p.cfg, r) {
+		util.RespondWithJSON(w, http.StatusForbidden, www.ErrorReply{})
+		return
+	}
+
+	var e clusterEvent
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&e); err != nil {
+		RespondWithError(w, r, 0, "handleClusterEvent: unmarshal",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	err := p.backend.ProcessClusterEvent(r.Context(), e)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleClusterEvent: ProcessClusterEvent %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, struct{}{})
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+func TestClusterIPBans(t *testing.T) {
+	const token = "secret"
+
+	replica := &politeiawww{
+		backend: createBackend(t),
+	}
+	replica.cfg = replica.backend.cfg
+	replica.cfg.ClusterPrimary = "https://primary.example.com"
+	replica.cfg.ClusterToken = token
+	server := httptest.NewServer(http.HandlerFunc(replica.handleClusterEvent))
+	defer server.Close()
+
+	primary := createBackend(t)
+	primary.cfg.ClusterPeers = []string{server.URL}
+	primary.cfg.ClusterToken = token
+	primary.clusterClient = server.Client()
+
+	// The replica enforces the bans of the primary.
+	admin := &database.User{ID: 1, Admin: true}
+	_, err := primary.ProcessBanIP(www.BanIP{Network: "10.0.0.0/8"}, admin,
+		nil)
+	assertSuccess(t, err)
+	ip := net.ParseIP("10.1.2.3")
+	waitFor(t, func() bool { return replica.backend.ipBanned(ip) != nil })
+
+	_, err = primary.ProcessUnbanIP(www.UnbanIP{Network: "10.0.0.0/8"},
+		admin)
+	assertSuccess(t, err)
+	waitFor(t, func() bool { return replica.backend.ipBanned(ip) == nil })
+
+	// Events without the cluster token are rejected.
+	primary.cfg.ClusterToken = "wrong"
+	err = primary.sendClusterEvent(server.URL, []byte(`{"type":"ipunban"}`))
+	if err == nil {
+		t.Fatalf("expected unauthenticated event to fail")
+	}
+}
+
+func TestClusterForwardToPrimary(t *testing.T) {
+	var forwarded int
+	primary := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(clusterTokenHeader) != "secret" {
+				t.Errorf("missing cluster token")
+			}
+			forwarded++
+		}))
+	defer primary.Close()
+
+	cfg := &config{
+		ClusterPrimary: primary.URL,
+		ClusterToken:   "secret",
+	}
+	proxy, err := newPrimaryProxy(cfg, primary.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &politeiawww{
+		cfg:     cfg,
+		primary: proxy,
+	}
+
+	var served int
+	local := func(w http.ResponseWriter, r *http.Request) {
+		served++
+	}
+
+	tests := []struct {
+		method  string
+		route   string
+		perm    permission
+		session bool
+		local   bool
+	}{
+		{http.MethodGet, www.RoutePolicy, permissionPublic, false, true},
+		{http.MethodGet, www.RoutePolicy, permissionPublic, true, false},
+		{http.MethodGet, www.RoutePolicy, permissionLogin, false, false},
+		{http.MethodPost, www.RoutePolicy, permissionPublic, false, false},
+
+		// Routes that use the state of the primary.
+		{http.MethodGet, www.RouteSignupChallenge, permissionPublic,
+			false, false},
+		{http.MethodGet, www.RouteProposalDetails, permissionPublic,
+			false, false},
+		{http.MethodGet, www.RouteUserDetails, permissionPublic,
+			false, false},
+	}
+	for k, test := range tests {
+		served, forwarded = 0, 0
+		handler := p.forwardToPrimary(test.method, test.route,
+			test.perm, local)
+		r := httptest.NewRequest(test.method, test.route, nil)
+		if test.session {
+			r.AddCookie(&http.Cookie{
+				Name:  www.CookieSession,
+				Value: "session",
+			})
+		}
+		handler(httptest.NewRecorder(), r)
+
+		if (served == 1) != test.local || (forwarded == 1) == test.local {
+			t.Fatalf("test %v: served %v forwarded %v", k, served,
+				forwarded)
+		}
+	}
+}
+
+// waitFor waits for the provided condition to become true.
+func waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timeout")
+}
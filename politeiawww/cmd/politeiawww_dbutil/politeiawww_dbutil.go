@@ -51,6 +51,56 @@ func dumpAction() error {
 		} else if string(key) == localdb.LastUserIdKey {
 			fmt.Printf("Key    : %v\n", string(key))
 			fmt.Printf("Record : %v\n", binary.LittleEndian.Uint64(value))
+		} else if strings.HasPrefix(string(key),
+			localdb.StatusChangesPrefix) {
+			changes, err := localdb.DecodeStatusChanges(value)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Key    : %v\n", string(key))
+			fmt.Printf("Record : %v", spew.Sdump(changes))
+		} else if strings.HasPrefix(string(key),
+			localdb.SecurityEventsPrefix) {
+			events, err := localdb.DecodeSecurityEvents(value)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Key    : %v\n", string(key))
+			fmt.Printf("Record : %v", spew.Sdump(events))
+		} else if strings.HasPrefix(string(key),
+			localdb.DevicesPrefix) {
+			fmt.Printf("Key    : %v\n", string(key))
+		} else if strings.HasPrefix(string(key),
+			localdb.VoteNotificationsPrefix) {
+			fmt.Printf("Key    : %v\n", string(key))
+			fmt.Printf("Record : %s\n", value)
+		} else if strings.HasPrefix(string(key),
+			localdb.DraftsPrefix) {
+			drafts, err := localdb.DecodeDrafts(value)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Key    : %v\n", string(key))
+			fmt.Printf("Record : %v", spew.Sdump(drafts))
+		} else if string(key) == localdb.IPBansKey {
+			bans, err := localdb.DecodeIPBans(value)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Key    : %v\n", string(key))
+			fmt.Printf("Record : %v", spew.Sdump(bans))
+		} else if string(key) == localdb.AdminActionsKey {
+			actions, err := localdb.DecodeAdminActions(value)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Key    : %v\n", string(key))
+			fmt.Printf("Record : %v", spew.Sdump(actions))
 		} else {
 			u, err := localdb.DecodeUser(value)
 			if err != nil {
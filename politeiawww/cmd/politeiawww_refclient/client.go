@@ -189,7 +189,23 @@ func idFromString(s string) (*identity.FullIdentity, error) {
 	return id, nil
 }
 
-func (c *ctx) newUser(email string, password string) (string, *identity.FullIdentity, string, uint64, error) {
+func (c *ctx) signupChallenge() (*v1.SignupChallengeReply, error) {
+	responseBody, err := c.makeRequest("GET", v1.RouteSignupChallenge, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var scr v1.SignupChallengeReply
+	err = json.Unmarshal(responseBody, &scr)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal "+
+			"SignupChallengeReply: %v", err)
+	}
+
+	return &scr, nil
+}
+
+func (c *ctx) newUser(email string, password string, powDifficulty uint) (string, *identity.FullIdentity, string, uint64, error) {
 	id, err := idFromString(email)
 	if err != nil {
 		return "", nil, "", 0, err
@@ -200,6 +216,21 @@ func (c *ctx) newUser(email string, password string) (string, *identity.FullIden
 		PublicKey: hex.EncodeToString(id.Public.Key[:]),
 	}
 
+	// Solve the signup proof of work when the policy requires one.
+	if powDifficulty != 0 {
+		scr, err := c.signupChallenge()
+		if err != nil {
+			return "", nil, "", 0, err
+		}
+		challenge, err := hex.DecodeString(scr.Challenge)
+		if err != nil {
+			return "", nil, "", 0, err
+		}
+		nonce := util.SolveProofOfWork(challenge, scr.Difficulty)
+		u.Challenge = scr.Challenge
+		u.Nonce = hex.EncodeToString(nonce)
+	}
+
 	responseBody, err := c.makeRequest("POST", v1.RouteNewUser, u)
 	if err != nil {
 		return "", nil, "", 0, err
@@ -499,14 +530,16 @@ func (c *ctx) getProp(token string) (*v1.ProposalDetailsReply, error) {
 	return &pr, nil
 }
 
-func (c *ctx) setPropStatus(id *identity.FullIdentity, token string, status v1.PropStatusT) (*v1.SetProposalStatusReply, error) {
+func (c *ctx) setPropStatus(id *identity.FullIdentity, token string, status v1.PropStatusT, message string) (*v1.SetProposalStatusReply, error) {
 	ps := v1.SetProposalStatus{
-		Token:          token,
-		ProposalStatus: status,
+		Token:               token,
+		ProposalStatus:      status,
+		StatusChangeMessage: message,
 	}
-	// Sign token+string(status)
+	// Sign token+string(status)+message
 	msg := []byte(ps.Token +
-		strconv.FormatUint(uint64(ps.ProposalStatus), 10))
+		strconv.FormatUint(uint64(ps.ProposalStatus), 10) +
+		ps.StatusChangeMessage)
 	var err error
 	sig := id.SignMessage(msg)
 	ps.Signature = hex.EncodeToString(sig[:])
@@ -100,7 +100,7 @@ func vote() error {
 
 	// move prop to vetted
 	psr1, err := c.setPropStatus(adminID,
-		myprop1.CensorshipRecord.Token, v1.PropStatusPublic)
+		myprop1.CensorshipRecord.Token, v1.PropStatusPublic, "")
 	if err != nil {
 		return err
 	}
@@ -179,10 +179,17 @@ func _main() error {
 	}
 
 	email := hex.EncodeToString(b) + "@example.com"
+
+	// The password must not contain the email address.
+	b, err = util.Random(int(pr.PasswordMinChars))
+	if err != nil {
+		return err
+	}
 	password := hex.EncodeToString(b)
 
 	// New User
-	token, id, paywallAddress, paywallAmount, err := c.newUser(email, password)
+	token, id, paywallAddress, paywallAmount, err := c.newUser(email, password,
+		pr.SignupPoWDifficulty)
 	if err != nil {
 		return err
 	}
@@ -453,7 +460,7 @@ func _main() error {
 
 			// Move first proposal to published
 			psr1, err := c.setPropStatus(adminID,
-				myprop1.CensorshipRecord.Token, v1.PropStatusPublic)
+				myprop1.CensorshipRecord.Token, v1.PropStatusPublic, "")
 			if err != nil {
 				return err
 			}
@@ -465,7 +472,8 @@ func _main() error {
 
 			// Move second proposal to censored
 			psr2, err := c.setPropStatus(adminID,
-				myprop2.CensorshipRecord.Token, v1.PropStatusCensored)
+				myprop2.CensorshipRecord.Token, v1.PropStatusCensored,
+				"refclient test")
 			if err != nil {
 				return err
 			}
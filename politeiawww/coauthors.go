@@ -0,0 +1,154 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/util"
+)
+
+// BackendProposalCoAuthorsVersion is the version of BackendProposalCoAuthors.
+const BackendProposalCoAuthorsVersion = 1
+
+// BackendCoAuthor is a co-author whose signature of the merkle root of a
+// proposal was verified.
+type BackendCoAuthor struct {
+	PublicKey string `json:"publickey"` // Key of the co-author
+	Signature string `json:"signature"` // Signature of merkle root
+	UserID    string `json:"userid"`    // User of the key, if any
+}
+
+// BackendProposalCoAuthors is the metadata stream that holds the co-authors
+// of a proposal.  It is overwritten when the proposal is edited.
+type BackendProposalCoAuthors struct {
+	Version   uint64            `json:"version"`   // BackendProposalCoAuthors version
+	Timestamp int64             `json:"timestamp"` // Submission of the co-authors
+	CoAuthors []BackendCoAuthor `json:"coauthors"` // Co-authors of the proposal
+}
+
+// encodeBackendProposalCoAuthors encodes BackendProposalCoAuthors into a JSON
+// byte slice.
+func encodeBackendProposalCoAuthors(md BackendProposalCoAuthors) ([]byte, error) {
+	b, err := json.Marshal(md)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// decodeBackendProposalCoAuthors decodes a JSON byte slice into a
+// BackendProposalCoAuthors.
+func decodeBackendProposalCoAuthors(payload []byte) (*BackendProposalCoAuthors, error) {
+	var md BackendProposalCoAuthors
+
+	err := json.Unmarshal(payload, &md)
+	if err != nil {
+		return nil, err
+	}
+
+	return &md, nil
+}
+
+// coAuthorError returns the error of a proposal co-author that is rejected
+// for the provided reason.
+func coAuthorError(reason, detail string) error {
+	return www.UserError{
+		ErrorCode:    www.ErrorStatusInvalidProposalCoAuthor,
+		ErrorContext: []string{reason, detail},
+	}
+}
+
+// validateCoAuthors checks that the provided co-authors of a proposal whose
+// author signed with the provided key each signed the provided merkle root.
+func validateCoAuthors(coAuthors []www.CoAuthor, publicKey, merkle string) error {
+	if len(coAuthors) > www.PolicyMaxProposalCoAuthors {
+		return coAuthorError(www.CoAuthorReasonTooMany, "")
+	}
+
+	keys := map[string]bool{
+		publicKey: true,
+	}
+	for _, v := range coAuthors {
+		pk, err := util.IdentityFromString(v.PublicKey)
+		if err != nil {
+			return coAuthorError(www.CoAuthorReasonKey, v.PublicKey)
+		}
+		if keys[v.PublicKey] {
+			return coAuthorError(www.CoAuthorReasonDuplicate, v.PublicKey)
+		}
+		keys[v.PublicKey] = true
+
+		sig, err := util.ConvertSignature(v.Signature)
+		if err != nil || !pk.VerifyMessage([]byte(merkle), sig) {
+			return coAuthorError(www.CoAuthorReasonSignature,
+				v.PublicKey)
+		}
+	}
+
+	return nil
+}
+
+// proposalCoAuthorsStream returns the metadata stream that holds the provided
+// co-authors, along with the users of their keys.
+func (b *backend) proposalCoAuthorsStream(coAuthors []www.CoAuthor, ts int64) (*pd.MetadataStream, error) {
+	md := BackendProposalCoAuthors{
+		Version:   BackendProposalCoAuthorsVersion,
+		Timestamp: ts,
+		CoAuthors: make([]BackendCoAuthor, 0, len(coAuthors)),
+	}
+	b.RLock()
+	for _, v := range coAuthors {
+		md.CoAuthors = append(md.CoAuthors, BackendCoAuthor{
+			PublicKey: v.PublicKey,
+			Signature: v.Signature,
+			UserID:    b.userPubkeys[v.PublicKey],
+		})
+	}
+	b.RUnlock()
+
+	payload, err := encodeBackendProposalCoAuthors(md)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pd.MetadataStream{
+		ID:      mdStreamCoAuthors,
+		Payload: string(payload),
+	}, nil
+}
+
+// proposalCoAuthors returns the co-authors of the provided record, or nil if
+// it has none.
+func proposalCoAuthors(p pd.Record) []www.CoAuthor {
+	for _, v := range p.Metadata {
+		if v.ID != mdStreamCoAuthors {
+			continue
+		}
+		md, err := decodeBackendProposalCoAuthors([]byte(v.Payload))
+		if err != nil {
+			log.Errorf("could not decode co-authors '%v' token '%v': %v",
+				v.Payload, p.CensorshipRecord.Token, err)
+			break
+		}
+		if len(md.CoAuthors) == 0 {
+			break
+		}
+		coAuthors := make([]www.CoAuthor, 0, len(md.CoAuthors))
+		for _, c := range md.CoAuthors {
+			coAuthors = append(coAuthors, www.CoAuthor{
+				PublicKey: c.PublicKey,
+				Signature: c.Signature,
+				UserID:    c.UserID,
+			})
+		}
+		return coAuthors
+	}
+
+	return nil
+}
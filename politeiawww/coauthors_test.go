@@ -0,0 +1,62 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestProposalCoAuthors(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	nu2, id2 := createAndVerifyUser(t, b)
+	user2, err := b.db.UserGet(nu2.Email)
+	assertSuccess(t, err)
+
+	np := newTextProposal(t, id, "Joint proposal\nWe will do it together.")
+	signature, err := getProposalSignature(convertPropFilesFromWWW(np.Files),
+		id2)
+	assertSuccess(t, err)
+	coAuthor := www.CoAuthor{
+		PublicKey: id2.Public.String(),
+		Signature: signature,
+	}
+
+	// The co-author signs the same files as the author.
+	np.CoAuthors = []www.CoAuthor{{
+		PublicKey: coAuthor.PublicKey,
+		Signature: np.Signature,
+	}}
+	_, err = b.ProcessNewProposal(context.Background(), np, user)
+	assertErrorWithContext(t, err, www.ErrorStatusInvalidProposalCoAuthor,
+		[]string{www.CoAuthorReasonSignature, coAuthor.PublicKey})
+
+	// The author is not a co-author.
+	np.CoAuthors = []www.CoAuthor{{
+		PublicKey: np.PublicKey,
+		Signature: np.Signature,
+	}}
+	_, err = b.ProcessNewProposal(context.Background(), np, user)
+	assertErrorWithContext(t, err, www.ErrorStatusInvalidProposalCoAuthor,
+		[]string{www.CoAuthorReasonDuplicate, np.PublicKey})
+
+	np.CoAuthors = []www.CoAuthor{coAuthor}
+	npr, err := b.ProcessNewProposal(context.Background(), np, user)
+	assertSuccess(t, err)
+
+	p := getProposalDetails(b, npr.CensorshipRecord.Token, t).Proposal
+	coAuthor.UserID = strconv.FormatUint(user2.ID, 10)
+	if len(p.CoAuthors) != 1 || p.CoAuthors[0] != coAuthor {
+		t.Fatalf("unexpected co-authors %v", p.CoAuthors)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -28,6 +29,8 @@ const (
 	CommentActionInvalid CommentActionT = 0 // Invalid action
 	CommentActionAdd     CommentActionT = 1 // Add comment
 	CommentActionDelete  CommentActionT = 2 // Delete comment
+	CommentActionLike    CommentActionT = 3 // Up or down vote comment
+	CommentActionCensor  CommentActionT = 4 // Censor comment
 )
 
 // BackendComment wraps www.Comment into an internal usable structure.
@@ -45,20 +48,89 @@ type BackendComment struct {
 	Token     string // Censorship token
 	ParentID  string // Parent comment ID
 	Comment   string // Comment
-	Signature string // Signature of Token+ParentID+Comment
+	Like      string // Vote action of CommentActionLike
+	Reason    string // Censorship reason of CommentActionCensor
+	Signature string // Signature of Token+ParentID+Comment, Token+CommentID+Like or Token+CommentID+Reason
+
+	// In memory only
+	Censored bool `json:"-"` // Comment was censored by an admin
+}
+
+// backendCommentToComment converts BackendComment to www.Comment.  The score
+// of the comment is computed from the provided votes.
+// Censored comments are replaced by a placeholder.
+func backendCommentToComment(bec BackendComment, votes map[string]int64) www.Comment {
+	total, result := commentScore(votes)
+	c := www.Comment{
+		Timestamp:   bec.Timestamp,
+		UserID:      bec.UserID,
+		CommentID:   bec.CommentID,
+		Token:       bec.Token,
+		ParentID:    bec.ParentID,
+		Comment:     bec.Comment,
+		Signature:   bec.Signature,
+		TotalVotes:  total,
+		ResultVotes: result,
+	}
+	if bec.Censored {
+		c.Comment = www.CensoredCommentPlaceholder
+		c.Censored = true
+	}
+	return c
 }
 
-// backendCommentToComment converts BackendComment to www.Comment.
-func backendCommentToComment(bec BackendComment) www.Comment {
-	return www.Comment{
-		Timestamp: bec.Timestamp,
-		UserID:    bec.UserID,
-		CommentID: bec.CommentID,
-		Token:     bec.Token,
-		ParentID:  bec.ParentID,
-		Comment:   bec.Comment,
-		Signature: bec.Signature,
+// commentScore returns the number of votes and the sum of the up and down
+// votes of the provided votes of a comment.
+func commentScore(votes map[string]int64) (uint64, int64) {
+	var result int64
+	for _, v := range votes {
+		result += v
 	}
+	return uint64(len(votes)), result
+}
+
+// likeCommentVote converts the provided like comment action to a vote.
+func likeCommentVote(action string) (int64, error) {
+	switch action {
+	case www.LikeCommentUp:
+		return 1, nil
+	case www.LikeCommentDown:
+		return -1, nil
+	}
+	return 0, www.UserError{
+		ErrorCode: www.ErrorStatusInvalidLikeCommentAction,
+	}
+}
+
+// applyCommentLike records the vote of the provided like comment action in
+// the votes of its proposal.  Each user has at most one vote per comment; a
+// vote replaces the previous vote of the user and repeating a vote withdraws
+// it.  The journal is replayed through this call so that it rebuilds the
+// same totals.
+//
+// This call must be called with the lock held.
+func (b *backend) applyCommentLike(c BackendComment, cid uint64) error {
+	vote, err := likeCommentVote(c.Like)
+	if err != nil {
+		return err
+	}
+
+	ir := b.inventory[c.Token]
+	if ir.commentVotes == nil {
+		ir.commentVotes = make(map[uint64]map[string]int64)
+	}
+	votes, ok := ir.commentVotes[cid]
+	if !ok {
+		votes = make(map[string]int64)
+		ir.commentVotes[cid] = votes
+	}
+	if votes[c.UserID] == vote {
+		delete(votes, c.UserID)
+	} else {
+		votes[c.UserID] = vote
+	}
+
+	return nil
 }
 
 // initComment initializes the comment map for the given token.  This call must
@@ -87,9 +159,9 @@ func (b *backend) getComments(token string) (*www.GetCommentsReply, error) {
 	gcr := &www.GetCommentsReply{
 		Comments: make([]www.Comment, 0, len(c.comments)),
 	}
-	for _, v := range c.comments {
+	for k, v := range c.comments {
 		gcr.Comments = append(gcr.Comments,
-			backendCommentToComment(v))
+			backendCommentToComment(v, c.commentVotes[k]))
 	}
 
 	return gcr, nil
@@ -101,7 +173,7 @@ func (b *backend) addComment(c www.NewComment, userID uint64) (*www.NewCommentRe
 
 	// validations
 	// XXX this needs to be more rigorous
-	if err := validateComment(c); err != nil {
+	if err := validateComment(c, b.cfg.MaxCommentLength); err != nil {
 		return nil, err
 	}
 
@@ -117,23 +189,14 @@ func (b *backend) addComment(c www.NewComment, userID uint64) (*www.NewCommentRe
 		Comment:   c.Comment,
 		Signature: c.Signature,
 	}
-	cb, err := json.Marshal(comment)
+	err := b.journalComment(comment)
 	if err != nil {
 		return nil, err
 	}
 
-	if !b.test {
-		f, err := os.OpenFile(path.Join(b.commentJournalDir, c.Token),
-			os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
-		if err != nil {
-			return nil, err
-		}
-		defer f.Close()
-		fmt.Fprintf(f, "%s\n", cb)
-	}
-
 	// Store comment in memory for quick lookup
 	b.inventory[c.Token].comments[b.commentID] = comment
+	b.indexUserStats(c.Token)
 	cr := www.NewCommentReply{
 		CommentID: comment.CommentID,
 	}
@@ -142,6 +205,100 @@ func (b *backend) addComment(c www.NewComment, userID uint64) (*www.NewCommentRe
 	return &cr, nil
 }
 
+// likeComment journals the vote of the provided user on a comment and
+// records it in the memory map.  It returns the new score of the comment.
+// This call must be called with the lock held.
+func (b *backend) likeComment(lc www.LikeComment, cid uint64, userID uint64) (*www.LikeCommentReply, error) {
+	comment := BackendComment{
+		Version:   defaultCommentVersion,
+		Action:    CommentActionLike,
+		Timestamp: time.Now().Unix(),
+		UserID:    strconv.FormatUint(userID, 10),
+		CommentID: lc.CommentID,
+		Token:     lc.Token,
+		Like:      lc.Action,
+		Signature: lc.Signature,
+	}
+
+	// Validate the vote before it is journaled.
+	_, err := likeCommentVote(comment.Like)
+	if err != nil {
+		return nil, err
+	}
+	err = b.journalComment(comment)
+	if err != nil {
+		return nil, err
+	}
+	err = b.applyCommentLike(comment, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	total, result := commentScore(b.inventory[lc.Token].commentVotes[cid])
+	return &www.LikeCommentReply{
+		Total:  total,
+		Result: result,
+	}, nil
+}
+
+// censorComment journals the censorship of a comment by the provided admin
+// and replaces the comment in the memory map.  The original comment remains
+// in the journal.
+// This call must be called with the lock held.
+func (b *backend) censorComment(cc www.CensorComment, cid uint64, adminID uint64) error {
+	comment := BackendComment{
+		Version:   defaultCommentVersion,
+		Action:    CommentActionCensor,
+		Timestamp: time.Now().Unix(),
+		UserID:    strconv.FormatUint(adminID, 10),
+		CommentID: cc.CommentID,
+		Token:     cc.Token,
+		Reason:    cc.Reason,
+		Signature: cc.Signature,
+	}
+	err := b.journalComment(comment)
+	if err != nil {
+		return err
+	}
+	b.applyCommentCensor(comment, cid)
+
+	return nil
+}
+
+// applyCommentCensor replaces the censored comment in the memory map.
+// This call must be called with the lock held.
+func (b *backend) applyCommentCensor(c BackendComment, cid uint64) {
+	comment, ok := b.inventory[c.Token].comments[cid]
+	if !ok {
+		return
+	}
+	comment.Comment = ""
+	comment.Censored = true
+	b.inventory[c.Token].comments[cid] = comment
+}
+
+// journalComment appends the provided comment action to the comment journal
+// of its proposal.
+func (b *backend) journalComment(comment BackendComment) error {
+	cb, err := json.Marshal(comment)
+	if err != nil {
+		return err
+	}
+
+	if b.test {
+		return nil
+	}
+
+	f, err := os.OpenFile(path.Join(b.commentJournalDir, comment.Token),
+		os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s\n", cb)
+	return err
+}
+
 func (b *backend) loadComments(token, comments string) error {
 	// Replay journal
 	f := strings.NewReader(comments)
@@ -174,6 +331,15 @@ func (b *backend) loadComments(token, comments string) error {
 			b.inventory[c.Token].comments[cid] = c
 		case CommentActionDelete:
 			delete(b.inventory[c.Token].comments, cid)
+		case CommentActionLike:
+			err = b.applyCommentLike(c, cid)
+			if err != nil {
+				log.Errorf("invalid comment like: %v token %v "+
+					"comment id %v", c.Like, c.Token,
+					c.CommentID)
+			}
+		case CommentActionCensor:
+			b.applyCommentCensor(c, cid)
 		default:
 			log.Errorf("invalid comment action: %v token %v "+
 				"comment id %v", c.Action, c.Token, c.CommentID)
@@ -189,7 +355,7 @@ func (b *backend) loadComments(token, comments string) error {
 	return nil
 }
 
-func (b *backend) flushCommentJournal(filename string) error {
+func (b *backend) flushCommentJournal(ctx context.Context, filename string) error {
 	_, err := util.ConvertStringToken(filename)
 	if err != nil {
 		return fmt.Errorf("skipping %v", filename)
@@ -217,7 +383,7 @@ func (b *backend) flushCommentJournal(filename string) error {
 		}},
 	}
 
-	responseBody, err := b.makeRequest(http.MethodPost,
+	responseBody, err := b.makeRequest(ctx, http.MethodPost,
 		pd.UpdateVettedMetadataRoute, upd)
 	if err != nil {
 		e, ok := err.(www.PDError)
@@ -245,14 +411,17 @@ func (b *backend) flushCommentJournal(filename string) error {
 
 // flushCommentJournal flushes all comments to politeiad. For now this uses the
 // large hammer approach of always flushing all comments.
-func (b *backend) flushCommentJournals() error {
+func (b *backend) flushCommentJournals(ctx context.Context) error {
 	fi, err := ioutil.ReadDir(b.commentJournalDir)
 	if err != nil {
 		return err
 	}
 
 	for _, v := range fi {
-		err := b.flushCommentJournal(v.Name())
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := b.flushCommentJournal(ctx, v.Name())
 		if err != nil {
 			log.Errorf("flushCommentJournal: %v", err)
 			continue
@@ -262,9 +431,9 @@ func (b *backend) flushCommentJournals() error {
 	return nil
 }
 
-func validateComment(c www.NewComment) error {
+func validateComment(c www.NewComment, maxLength int) error {
 	// max length
-	if len(c.Comment) > www.PolicyMaxCommentLength {
+	if len(c.Comment) > maxLength {
 		return www.UserError{
 			ErrorCode: www.ErrorStatusCommentLengthExceededPolicy,
 		}
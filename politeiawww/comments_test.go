@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/decred/politeia/politeiad/api/v1/identity"
 	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -94,3 +102,232 @@ func (s *CommentsTestSuite) TestAddComment() {
 	}
 
 }
+
+func TestLikeComment(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+
+	sig, _ := getSignature([]byte(token+"comment"), id)
+	ncr, err := b.ProcessComment(www.NewComment{
+		Token:     token,
+		Comment:   "comment",
+		Signature: sig,
+		PublicKey: id.Public.String(),
+	}, user)
+	assertSuccess(t, err)
+
+	var journal []string
+	like := func(u *database.User, id *identity.FullIdentity, commentID, action string) (*www.LikeCommentReply, error) {
+		sig, _ := getSignature([]byte(token+commentID+action), id)
+		lcr, err := b.ProcessLikeComment(www.LikeComment{
+			Token:     token,
+			CommentID: commentID,
+			Action:    action,
+			Signature: sig,
+			PublicKey: id.Public.String(),
+		}, u)
+		if err == nil {
+			jb, _ := json.Marshal(BackendComment{
+				Version:   defaultCommentVersion,
+				Action:    CommentActionLike,
+				UserID:    strconv.FormatUint(u.ID, 10),
+				CommentID: commentID,
+				Token:     token,
+				Like:      action,
+			})
+			journal = append(journal, string(jb))
+		}
+		return lcr, err
+	}
+	assertScore := func(lcr *www.LikeCommentReply, total uint64, result int64) {
+		t.Helper()
+		if lcr.Total != total || lcr.Result != result {
+			t.Fatalf("got score %v/%v, want %v/%v", lcr.Total,
+				lcr.Result, total, result)
+		}
+	}
+
+	// Votes are deduplicated per user.
+	lcr, err := like(user, id, ncr.CommentID, www.LikeCommentUp)
+	assertSuccess(t, err)
+	assertScore(lcr, 1, 1)
+	lcr, err = like(user, id, ncr.CommentID, www.LikeCommentDown)
+	assertSuccess(t, err)
+	assertScore(lcr, 1, -1)
+	nu2, id2 := createAndVerifyUser(t, b)
+	user2, err := b.db.UserGet(nu2.Email)
+	assertSuccess(t, err)
+	lcr, err = like(user2, id2, ncr.CommentID, www.LikeCommentDown)
+	assertSuccess(t, err)
+	assertScore(lcr, 2, -2)
+	lcr, err = like(user, id, ncr.CommentID, www.LikeCommentDown)
+	assertSuccess(t, err)
+	assertScore(lcr, 1, -1)
+
+	_, err = like(user, id, ncr.CommentID, "2")
+	assertError(t, err, www.ErrorStatusInvalidLikeCommentAction)
+	_, err = like(user, id, "999", www.LikeCommentUp)
+	assertError(t, err, www.ErrorStatusCommentNotFound)
+
+	// The score is returned with the comments and is rebuilt from the
+	// journal.
+	for i := 0; i < 2; i++ {
+		gcr, err := b.ProcessCommentGet(token)
+		assertSuccess(t, err)
+		if len(gcr.Comments) != 1 || gcr.Comments[0].TotalVotes != 1 ||
+			gcr.Comments[0].ResultVotes != -1 {
+			t.Fatalf("unexpected comments %v", gcr.Comments)
+		}
+
+		b.inventory[token].commentVotes = nil
+		err = b.loadComments(token, strings.Join(journal, "\n"))
+		assertSuccess(t, err)
+	}
+}
+
+func TestCensorComment(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	nu, adminID := createAndVerifyUser(t, b)
+	admin, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	admin.Admin = true
+	assertSuccess(t, b.db.UserUpdate(*admin))
+
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+	sig, _ := getSignature([]byte(token+"spam"), id)
+	ncr, err := b.ProcessComment(www.NewComment{
+		Token:     token,
+		Comment:   "spam",
+		Signature: sig,
+		PublicKey: id.Public.String(),
+	}, user)
+	assertSuccess(t, err)
+
+	censor := func(reason string) error {
+		sig, _ := getSignature([]byte(token+ncr.CommentID+reason),
+			adminID)
+		_, err := b.ProcessCensorComment(www.CensorComment{
+			Token:     token,
+			CommentID: ncr.CommentID,
+			Reason:    reason,
+			Signature: sig,
+			PublicKey: adminID.Public.String(),
+		}, admin)
+		return err
+	}
+	assertCensored := func() {
+		t.Helper()
+		gcr, err := b.ProcessCommentGet(token)
+		assertSuccess(t, err)
+		if len(gcr.Comments) != 1 || !gcr.Comments[0].Censored ||
+			gcr.Comments[0].Comment != www.CensoredCommentPlaceholder {
+			t.Fatalf("unexpected comments %v", gcr.Comments)
+		}
+	}
+
+	assertError(t, censor(""), www.ErrorStatusInvalidInput)
+	assertSuccess(t, censor("spam"))
+	assertCensored()
+	assertError(t, censor("spam"), www.ErrorStatusCommentIsCensored)
+
+	actions, err := b.db.AllAdminActions()
+	assertSuccess(t, err)
+	if len(actions) != 1 || actions[0].UserID != user.ID ||
+		actions[0].Action != www.AdminActionCensorComment {
+		t.Fatalf("unexpected admin actions %v", actions)
+	}
+
+	// The censorship is replayed from the journal, which keeps the
+	// original comment.
+	cid, err := strconv.ParseUint(ncr.CommentID, 10, 64)
+	assertSuccess(t, err)
+	add, _ := json.Marshal(BackendComment{
+		Version:   defaultCommentVersion,
+		Action:    CommentActionAdd,
+		UserID:    strconv.FormatUint(user.ID, 10),
+		CommentID: ncr.CommentID,
+		Token:     token,
+		Comment:   "spam",
+	})
+	cen, _ := json.Marshal(BackendComment{
+		Version:   defaultCommentVersion,
+		Action:    CommentActionCensor,
+		UserID:    strconv.FormatUint(admin.ID, 10),
+		CommentID: ncr.CommentID,
+		Token:     token,
+		Reason:    "spam",
+	})
+	delete(b.inventory[token].comments, cid)
+	err = b.loadComments(token, string(add)+"\n"+string(cen)+"\n")
+	assertSuccess(t, err)
+	assertCensored()
+}
+
+// TestCommentProposalDetailsRace reads the details of a proposal while it is
+// commented on.  It is meant to be run with -race.
+func TestCommentProposalDetailsRace(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*n)
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			comment := fmt.Sprintf("comment %v", i)
+			sig, _ := getSignature([]byte(token+comment), id)
+			_, err := b.ProcessComment(www.NewComment{
+				Token:     token,
+				Comment:   comment,
+				Signature: sig,
+				PublicKey: id.Public.String(),
+			}, user)
+			errs <- err
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := b.ProcessProposalDetails(context.Background(),
+				www.ProposalsDetails{
+					Token: token,
+				}, user)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assertSuccess(t, err)
+	}
+
+	pdr, err := b.ProcessProposalDetails(context.Background(),
+		www.ProposalsDetails{
+			Token: token,
+		}, user)
+	assertSuccess(t, err)
+	if pdr.Proposal.NumComments != n {
+		t.Fatalf("got %v comments, want %v", pdr.Proposal.NumComments, n)
+	}
+}
@@ -17,15 +17,17 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/decred/dcrd/hdkeychain"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
 
 	flags "github.com/btcsuite/go-flags"
-	"github.com/dajohi/goemail"
 	"github.com/decred/politeia/politeiad/api/v1"
+	www "github.com/decred/politeia/politeiawww/api/v1"
 	"github.com/decred/politeia/politeiawww/sharedconfig"
 	"github.com/decred/politeia/util"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -41,6 +43,55 @@ const (
 
 	defaultPaywallMinConfirmations = uint64(2)
 	defaultPaywallAmount           = uint64(0)
+	defaultProposalCreditPrice     = uint64(10000000)
+
+	// adminNotifications* are the supported delivery modes for the emails
+	// that notify admins of new proposals awaiting review.
+	adminNotificationsOff       = "off"
+	adminNotificationsImmediate = "immediate"
+	adminNotificationsBatched   = "batched"
+
+	defaultAdminNotifications        = adminNotificationsImmediate
+	defaultAdminNotificationInterval = time.Hour
+
+	// Maximum number of emails that are sent to a single recipient.
+	defaultMaxEmailsPerHour = 10
+	defaultMaxEmailsPerDay  = 30
+
+	// Time the verification tokens that are emailed to users are valid.
+	defaultVerificationExpiry = www.VerificationExpiryHours * time.Hour
+
+	defaultMailProvider = mailProviderSMTP
+
+	defaultStateStore   = stateStoreMemory
+	defaultSessionStore = sessionStoreState
+	defaultRedisAddress = "127.0.0.1:6379"
+
+	defaultAuthenticator = authenticatorLocal
+
+	// Password hashing parameters.  The argon2id parameters are the ones
+	// that the argon2 specification recommends for constrained memory.
+	defaultPasswordHash  = passwordHashBcrypt
+	defaultBcryptCost    = bcrypt.DefaultCost
+	defaultArgon2Time    = 3
+	defaultArgon2Memory  = 64 * 1024
+	defaultArgon2Threads = 4
+
+	defaultFileCacheSize = 64 // MiB
+
+	// IPs that abuse politeiawww this many times within the auto ban
+	// window are banned for the auto ban duration.
+	defaultAutoBanThreshold = 20
+	defaultAutoBanWindow    = time.Hour
+	defaultAutoBanDuration  = 24 * time.Hour
+
+	// IPs may log in, sign up and reset passwords this many times each
+	// within the rate limit window.
+	defaultRateLimitBurst  = 10
+	defaultRateLimitWindow = 10 * time.Minute
+
+	// maxSignupPoWDifficulty keeps the signup proof of work solvable.
+	maxSignupPoWDifficulty = 32
 
 	// dust value can be found increasing the amount value until we get false
 	// from IsDustAmount function. Amounts can not be lower than dust
@@ -64,6 +115,32 @@ var (
 		template.New("reset_password_email_template").Parse(templateResetPasswordEmailRaw))
 	templateUpdateUserKeyEmail = template.Must(
 		template.New("update_user_key_email_template").Parse(templateUpdateUserKeyEmailRaw))
+	templateChangeEmail = template.Must(
+		template.New("change_email_template").Parse(templateChangeEmailRaw))
+	templateNewDeviceLogin = template.Must(
+		template.New("new_device_login_template").Parse(templateNewDeviceLoginRaw))
+	templateUserAdminChanged = template.Must(
+		template.New("user_admin_changed_template").Parse(templateUserAdminChangedRaw))
+	templateUserVerifiedByAdmin = template.Must(
+		template.New("user_verified_by_admin_template").Parse(templateUserVerifiedByAdminRaw))
+	templateAccountRecovered = template.Must(
+		template.New("account_recovered_template").Parse(templateAccountRecoveredRaw))
+	templateProposalVetted = template.Must(
+		template.New("proposal_vetted_template").Parse(templateProposalVettedRaw))
+	templateProposalCensored = template.Must(
+		template.New("proposal_censored_template").Parse(templateProposalCensoredRaw))
+	templateAdminProposalNew = template.Must(
+		template.New("admin_proposal_new_template").Parse(templateAdminProposalNewRaw))
+	templateVoteStarted = template.Must(
+		template.New("vote_started_template").Parse(templateVoteStartedRaw))
+	templateVoteEnded = template.Must(
+		template.New("vote_ended_template").Parse(templateVoteEndedRaw))
+	templateDigest = template.Must(
+		template.New("digest_template").Parse(templateDigestRaw))
+	templateUnsubscribe = template.Must(
+		template.New("unsubscribe_template").Parse(templateUnsubscribeRaw))
+	templateUnsubscribeConfirm = template.Must(
+		template.New("unsubscribe_confirm_template").Parse(templateUnsubscribeConfirmRaw))
 )
 
 // runServiceCommand is only set to a real function on Windows.  It is used
@@ -76,6 +153,7 @@ var runServiceCommand func(string) error
 type config struct {
 	HomeDir                  string   `short:"A" long:"appdata" description:"Path to application home directory"`
 	ShowVersion              bool     `short:"V" long:"version" description:"Display version information and exit"`
+	Validate                 bool     `long:"validate" description:"Validate the configuration and the connectivity to external services and exit"`
 	ConfigFile               string   `short:"C" long:"configfile" description:"Path to configuration file"`
 	DataDir                  string   `short:"b" long:"datadir" description:"Directory to store data"`
 	LogDir                   string   `long:"logdir" description:"Directory to log output."`
@@ -99,14 +177,109 @@ type config struct {
 	MailHost                 string `long:"mailhost" description:"Email server address in this format: <host>:<port>"`
 	MailUser                 string `long:"mailuser" description:"Email server username"`
 	MailPass                 string `long:"mailpass" description:"Email server password"`
-	SMTP                     *goemail.SMTP
+	Mailer                   mailer
 	FetchIdentity            bool   `long:"fetchidentity" description:"Whether or not politeiawww fetches the identity from politeiad."`
 	WebServerAddress         string `long:"webserveraddress" description:"Address for the Politeia web server; it should have this format: <scheme>://<host>[:<port>]"`
+	APIAddress               string `long:"apiaddress" description:"Public address of the politeiawww API that mail clients can unsubscribe from notification emails with; it should have this format: <scheme>://<host>[:<port>][/<path>]"`
 	Proxy                    bool   `long:"proxy" description:"Run in proxy mode (no CSRF)."`
 	Interactive              string `long:"interactive" description:"Set to i-know-this-is-a-bad-idea to turn off interactive mode during --fetchidentity."`
 	PaywallAmount            uint64 `long:"paywallamount" description:"Amount of DCR (in atoms) required for a user to register."`
 	PaywallXpub              string `long:"paywallxpub" description:"Extended public key for deriving paywall addresses."`
 	MinConfirmationsRequired uint64 `long:"minconfirmations" description:"Minimum blocks confirmation for accepting paywall as paid. Only works in TestNet."`
+	ProposalCreditPrice      uint64 `long:"proposalcreditprice" description:"Amount of DCR (in atoms) of a proposal credit."`
+
+	AdminNotifications        string        `long:"adminnotifications" description:"Email admins when new proposals are awaiting review {off, immediate, batched}"`
+	AdminNotificationInterval time.Duration `long:"adminnotificationinterval" description:"Interval at which batched admin notifications are sent"`
+	MaxEmailsPerHour          int           `long:"maxemailsperhour" description:"Maximum number of emails sent to a single recipient per hour (0 for no limit)"`
+	MaxEmailsPerDay           int           `long:"maxemailsperday" description:"Maximum number of emails sent to a single recipient per day (0 for no limit)"`
+
+	NewUserTokenExpiry       time.Duration `long:"newusertokenexpiry" description:"Time a new user has to verify the email address"`
+	ResetPasswordTokenExpiry time.Duration `long:"resetpasswordtokenexpiry" description:"Time a password reset token is valid"`
+	ChangeEmailTokenExpiry   time.Duration `long:"changeemailtokenexpiry" description:"Time a user has to verify a new email address"`
+
+	MailProvider   string `long:"mailprovider" description:"Email provider {smtp, ses, sendgrid}"`
+	SESRegion      string `long:"sesregion" description:"AWS region of the SES email provider"`
+	SESAccessKey   string `long:"sesaccesskey" description:"AWS access key ID for the SES email provider"`
+	SESSecretKey   string `long:"sessecretkey" description:"AWS secret access key for the SES email provider"`
+	SendgridAPIKey string `long:"sendgridapikey" description:"API key for the Sendgrid email provider"`
+
+	MailWebhookToken string `long:"mailwebhooktoken" description:"Secret token that email providers must provide when calling the bounce and complaint webhooks; the webhooks are disabled if not set"`
+
+	AutoCertHosts []string `long:"autocerthost" description:"Obtain the https certificate for this host name from Let's Encrypt instead of using httpscert and httpskey; may be repeated"`
+	AutoCertEmail string   `long:"autocertemail" description:"Contact email address for the Let's Encrypt account"`
+	HTTPRedirect  string   `long:"httpredirect" description:"Listen for plain http on this interface/port (e.g. :80) and redirect to https; also answers the Let's Encrypt challenges"`
+
+	AutoBanThreshold int           `long:"autobanthreshold" description:"Number of abuses, such as failed logins, after which an IP is banned automatically (0 to disable)"`
+	AutoBanWindow    time.Duration `long:"autobanwindow" description:"Period over which the abuses of an IP are counted"`
+	AutoBanDuration  time.Duration `long:"autobanduration" description:"Length of automatic IP bans"`
+
+	RateLimitBurst  int           `long:"ratelimitburst" description:"Number of logins, signups and password resets that an IP may attempt each within ratelimitwindow (0 to disable)"`
+	RateLimitWindow time.Duration `long:"ratelimitwindow" description:"Period over which the logins, signups and password resets of an IP are counted"`
+
+	SignupPoWDifficulty uint `long:"signuppowdifficulty" description:"Number of leading zero bits of the proof of work that signups require (0 to disable)"`
+
+	VaultAddress string `long:"vaultaddress" description:"Address of the Vault server that vault: secrets are read from"`
+	VaultToken   string `long:"vaulttoken" description:"Token used to authenticate with the Vault server"`
+	KMSRegion    string `long:"kmsregion" description:"AWS region of the KMS key that kms: secrets are encrypted with"`
+	KMSAccessKey string `long:"kmsaccesskey" description:"AWS access key ID used to decrypt kms: secrets"`
+	KMSSecretKey string `long:"kmssecretkey" description:"AWS secret access key used to decrypt kms: secrets"`
+
+	ErrorReportDSN string `long:"errorreportdsn" description:"Sentry DSN that handler panics are reported to"`
+
+	ClusterPrimary string   `long:"clusterprimary" description:"URL of the primary politeiawww instance; makes this instance a replica that forwards everything but the anonymous public GET requests to the primary"`
+	ClusterPeers   []string `long:"clusterpeer" description:"URL of a replica that the primary publishes changes to; may be repeated"`
+	ClusterToken   string   `long:"clustertoken" description:"Secret token shared by the primary and the replicas"`
+	ClusterCert    string   `long:"clustercert" description:"Certificate that the https certificates of the other politeiawww instances of the cluster chain to"`
+
+	StateStore    string `long:"statestore" description:"Store for the sessions and the rate limit counters {memory, redis}; memory keeps the sessions in the data directory"`
+	SessionStore  string `long:"sessionstore" description:"Store for the sessions {statestore, database}; database keeps them in the user database"`
+	RedisAddress  string `long:"redisaddress" description:"Address of the Redis server of the redis state store"`
+	RedisPassword string `long:"redispassword" description:"Password of the Redis server"`
+	RedisDB       int    `long:"redisdb" description:"Redis database number"`
+
+	OIDCIssuer       string `long:"oidcissuer" description:"Issuer URL of the OpenID Connect provider that users may log in with; federated login is disabled if not set"`
+	OIDCClientID     string `long:"oidcclientid" description:"Client ID registered with the OpenID Connect provider"`
+	OIDCClientSecret string `long:"oidcclientsecret" description:"Client secret registered with the OpenID Connect provider"`
+	OIDCRedirectURL  string `long:"oidcredirecturl" description:"URL that the OpenID Connect provider redirects to after a login; it must pass the code and state to the oidc callback route"`
+
+	Authenticator string `long:"authenticator" description:"Backend that verifies the passwords of the users {local, ldap}; ldap binds to the LDAP server as the user"`
+	LDAPURL       string `long:"ldapurl" description:"URL of the LDAP server or Active Directory domain controller of the ldap authenticator {ldap://host[:port], ldaps://host[:port]}; ldap servers must support StartTLS"`
+	LDAPBindDN    string `long:"ldapbinddn" description:"DN that users bind as; {email} and {username} are replaced by the email address of the user and its local part"`
+	LDAPCert      string `long:"ldapcert" description:"Certificate that the certificate of the LDAP server chains to"`
+
+	PasswordHash  string `long:"passwordhash" description:"Algorithm that hashes the passwords of the users {bcrypt, argon2id}; passwords are hashed again on login when the algorithm or its parameters change"`
+	BcryptCost    int    `long:"bcryptcost" description:"Cost of the bcrypt password hashes"`
+	Argon2Time    uint32 `long:"argon2time" description:"Number of passes over the memory of the argon2id password hashes"`
+	Argon2Memory  uint32 `long:"argon2memory" description:"Memory in KiB of the argon2id password hashes"`
+	Argon2Threads uint8  `long:"argon2threads" description:"Degree of parallelism of the argon2id password hashes"`
+
+	AdminListeners []string `long:"adminlisten" description:"Add an interface/port that the admin routes are restricted to; the other listeners reject the admin routes; may be repeated"`
+	AdminAllowIPs  []string `long:"adminallowip" description:"IP address or network in CIDR notation that the admin routes are restricted to; may be repeated"`
+	AdminNetworks  []*net.IPNet
+
+	RejectProposalHTML bool `long:"rejectproposalhtml" description:"Reject proposals whose markdown contains raw HTML"`
+
+	MaxProposalImages     int `long:"maxproposalimages" description:"Maximum number of images of a proposal"`
+	MaxProposalImageSize  int `long:"maxproposalimagesize" description:"Maximum size in bytes of an image of a proposal"`
+	MaxProposalMDs        int `long:"maxproposalmds" description:"Maximum number of markdown files of a proposal"`
+	MaxProposalMDSize     int `long:"maxproposalmdsize" description:"Maximum size in bytes of a markdown file of a proposal"`
+	MinProposalNameLength int `long:"minproposalnamelength" description:"Minimum length of the name of a proposal"`
+	MaxProposalNameLength int `long:"maxproposalnamelength" description:"Maximum length of the name of a proposal"`
+	MaxCommentLength      int `long:"maxcommentlength" description:"Maximum length of a comment"`
+
+	FileCacheSize int `long:"filecachesize" description:"Maximum size in MiB of the proposal files that are cached in memory; 0 disables the cache"`
+
+	SpamKeywords  []string `long:"spamkeyword" description:"Word or phrase that adds to the spam score of the new proposals that contain it; may be repeated"`
+	SpamFlagScore int      `long:"spamflagscore" description:"Spam score from which new proposals are flagged to the admins in the unvetted listing; 0 disables flagging"`
+	SpamHoldScore int      `long:"spamholdscore" description:"Spam score from which new proposals are held for admin review instead of being submitted; 0 disables holding"`
+
+	Webhooks      []string `long:"webhook" description:"URL that proposal lifecycle events are POSTed to; may be repeated"`
+	WebhookSecret string   `long:"webhooksecret" description:"Secret that the payloads of the webhooks are signed with using HMAC-SHA256"`
+
+	AdminApprovalWindow time.Duration `long:"adminapprovalwindow" description:"Time a second admin has to approve the censorship of a proposal or the deactivation of a user by an admin (0 to carry them out without approval)"`
+
+	EnableFeatures  []string `long:"enablefeature" description:"Enable a feature {comments, credits, paywall, search, websockets}; may be repeated"`
+	DisableFeatures []string `long:"disablefeature" description:"Disable a feature {comments, credits, paywall, search, websockets}; may be repeated"`
 }
 
 // serviceOptions defines the configuration options for the rpc as a service
@@ -256,26 +429,57 @@ func newConfigParser(cfg *config, so *serviceOptions, options flags.Options) *fl
 	return parser
 }
 
-func initSMTP(cfg *config) error {
-	// Check that either all MailServer options are populated or none are,
-	// and then initialize the SMTP object if they're all populated.
-	cfg.SMTP = nil
-	if cfg.MailHost != "" || cfg.MailUser != "" ||
-		cfg.MailPass != "" || cfg.WebServerAddress != "" {
-		if cfg.MailHost == "" || cfg.MailUser == "" ||
-			cfg.MailPass == "" || cfg.WebServerAddress == "" {
-			err := fmt.Errorf("either all or none of the " +
-				"following config options should be supplied:" +
-				" mailhost, mailuser, mailpass, webserveraddress")
-			return err
-		}
-
-		var err error
-		cfg.SMTP, err = goemail.NewSMTP("smtps://" + cfg.MailUser +
-			":" + cfg.MailPass + "@" + cfg.MailHost)
-		if err != nil {
-			return err
+func initMailer(cfg *config) error {
+	// Check that either all options of the email provider are populated
+	// or none are, and then initialize the mailer if they're all
+	// populated.
+	cfg.Mailer = nil
+	switch cfg.MailProvider {
+	case mailProviderSMTP:
+		if cfg.MailHost != "" || cfg.MailUser != "" ||
+			cfg.MailPass != "" || cfg.WebServerAddress != "" {
+			if cfg.MailHost == "" || cfg.MailUser == "" ||
+				cfg.MailPass == "" || cfg.WebServerAddress == "" {
+				err := fmt.Errorf("either all or none of the " +
+					"following config options should be " +
+					"supplied: mailhost, mailuser, mailpass, " +
+					"webserveraddress")
+				return err
+			}
+
+			var err error
+			cfg.Mailer, err = newSMTPMailer(cfg.MailHost,
+				cfg.MailUser, cfg.MailPass)
+			if err != nil {
+				return err
+			}
 		}
+	case mailProviderSES:
+		if cfg.SESRegion != "" || cfg.SESAccessKey != "" ||
+			cfg.SESSecretKey != "" || cfg.WebServerAddress != "" {
+			if cfg.SESRegion == "" || cfg.SESAccessKey == "" ||
+				cfg.SESSecretKey == "" || cfg.WebServerAddress == "" {
+				err := fmt.Errorf("either all or none of the " +
+					"following config options should be " +
+					"supplied: sesregion, sesaccesskey, " +
+					"sessecretkey, webserveraddress")
+				return err
+			}
+			cfg.Mailer = newSESMailer(cfg.SESRegion, cfg.SESAccessKey,
+				cfg.SESSecretKey)
+		}
+	case mailProviderSendgrid:
+		if cfg.SendgridAPIKey != "" || cfg.WebServerAddress != "" {
+			if cfg.SendgridAPIKey == "" || cfg.WebServerAddress == "" {
+				err := fmt.Errorf("either all or none of the " +
+					"following config options should be " +
+					"supplied: sendgridapikey, webserveraddress")
+				return err
+			}
+			cfg.Mailer = newSendgridMailer(cfg.SendgridAPIKey)
+		}
+	default:
+		return fmt.Errorf("invalid mailprovider: %v", cfg.MailProvider)
 	}
 
 	return nil
@@ -339,7 +543,46 @@ func loadConfig() (*config, []string, error) {
 		CookieKeyFile:            defaultCookieKeyFile,
 		PaywallAmount:            defaultPaywallAmount,
 		MinConfirmationsRequired: defaultPaywallMinConfirmations,
+		ProposalCreditPrice:      defaultProposalCreditPrice,
 		Version:                  version(),
+
+		AdminNotifications:        defaultAdminNotifications,
+		AdminNotificationInterval: defaultAdminNotificationInterval,
+		MaxEmailsPerHour:          defaultMaxEmailsPerHour,
+		MaxEmailsPerDay:           defaultMaxEmailsPerDay,
+		MailProvider:              defaultMailProvider,
+
+		NewUserTokenExpiry:       defaultVerificationExpiry,
+		ResetPasswordTokenExpiry: defaultVerificationExpiry,
+		ChangeEmailTokenExpiry:   defaultVerificationExpiry,
+
+		AutoBanThreshold: defaultAutoBanThreshold,
+		AutoBanWindow:    defaultAutoBanWindow,
+		AutoBanDuration:  defaultAutoBanDuration,
+		RateLimitBurst:   defaultRateLimitBurst,
+		RateLimitWindow:  defaultRateLimitWindow,
+
+		StateStore:   defaultStateStore,
+		SessionStore: defaultSessionStore,
+		RedisAddress: defaultRedisAddress,
+
+		Authenticator: defaultAuthenticator,
+
+		PasswordHash:  defaultPasswordHash,
+		BcryptCost:    defaultBcryptCost,
+		Argon2Time:    defaultArgon2Time,
+		Argon2Memory:  defaultArgon2Memory,
+		Argon2Threads: defaultArgon2Threads,
+
+		MaxProposalImages:     www.PolicyMaxImages,
+		MaxProposalImageSize:  www.PolicyMaxImageSize,
+		MaxProposalMDs:        www.PolicyMaxMDs,
+		MaxProposalMDSize:     www.PolicyMaxMDSize,
+		MinProposalNameLength: www.PolicyMinProposalNameLength,
+		MaxProposalNameLength: www.PolicyMaxProposalNameLength,
+		MaxCommentLength:      www.PolicyMaxCommentLength,
+
+		FileCacheSize: defaultFileCacheSize,
 	}
 
 	// Service options which are only added on Windows.
@@ -554,6 +797,25 @@ func loadConfig() (*config, []string, error) {
 	// duplicate addresses.
 	cfg.Listeners = normalizeAddresses(cfg.Listeners, port)
 
+	// The admin listeners serve all routes, so they must differ from the
+	// other listeners.
+	cfg.AdminListeners = normalizeAddresses(cfg.AdminListeners, port)
+	for _, v := range cfg.AdminListeners {
+		for _, listener := range cfg.Listeners {
+			if v == listener {
+				return nil, nil, fmt.Errorf("adminlisten %v is "+
+					"also a listen address", v)
+			}
+		}
+	}
+	for _, v := range cfg.AdminAllowIPs {
+		network, err := parseIPNetwork(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid adminallowip: %v", v)
+		}
+		cfg.AdminNetworks = append(cfg.AdminNetworks, network)
+	}
+
 	if cfg.TestNet {
 		//var timeHost string
 		//if len(cfg.DcrtimeHost) == 0 {
@@ -613,6 +875,25 @@ func loadConfig() (*config, []string, error) {
 	}
 	cfg.RPCHost = u.String()
 
+	// Fetch the secrets that are stored in an external secret store.
+	secrets, err := util.NewConfiguredSecrets(cfg.VaultAddress,
+		cfg.VaultToken, util.AWSCredentials{
+			Region:    cfg.KMSRegion,
+			AccessKey: cfg.KMSAccessKey,
+			SecretKey: cfg.KMSSecretKey,
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+	err = secrets.ResolveAll(&cfg.RPCUser, &cfg.RPCPass, &cfg.MailUser,
+		&cfg.MailPass, &cfg.SESAccessKey, &cfg.SESSecretKey,
+		&cfg.SendgridAPIKey, &cfg.MailWebhookToken, &cfg.ErrorReportDSN,
+		&cfg.ClusterToken, &cfg.RedisPassword, &cfg.OIDCClientSecret,
+		&cfg.WebhookSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Set random username and password when not specified
 	if cfg.RPCUser == "" {
 		name, err := util.Random(32)
@@ -631,11 +912,221 @@ func loadConfig() (*config, []string, error) {
 		log.Warnf("RPC password not set, using random value")
 	}
 
-	if err := initSMTP(&cfg); err != nil {
+	if err := initMailer(&cfg); err != nil {
 		return nil, nil, err
 	}
 
-	if err := loadIdentity(&cfg); err != nil {
+	// Validate the admin notification settings.
+	switch cfg.AdminNotifications {
+	case adminNotificationsOff, adminNotificationsImmediate:
+	case adminNotificationsBatched:
+		if cfg.AdminNotificationInterval <= 0 {
+			return nil, nil, fmt.Errorf("adminnotificationinterval " +
+				"must be positive")
+		}
+	default:
+		return nil, nil, fmt.Errorf("invalid adminnotifications: %v",
+			cfg.AdminNotifications)
+	}
+
+	if cfg.MaxEmailsPerHour < 0 || cfg.MaxEmailsPerDay < 0 {
+		return nil, nil, fmt.Errorf("maxemailsperhour and " +
+			"maxemailsperday must not be negative")
+	}
+
+	if cfg.NewUserTokenExpiry <= 0 || cfg.ResetPasswordTokenExpiry <= 0 ||
+		cfg.ChangeEmailTokenExpiry <= 0 {
+		return nil, nil, fmt.Errorf("newusertokenexpiry, " +
+			"resetpasswordtokenexpiry and changeemailtokenexpiry " +
+			"must be positive")
+	}
+
+	if cfg.AdminApprovalWindow < 0 {
+		return nil, nil, fmt.Errorf("adminapprovalwindow must not be " +
+			"negative")
+	}
+
+	if cfg.AutoBanThreshold < 0 {
+		return nil, nil, fmt.Errorf("autobanthreshold must not be " +
+			"negative")
+	}
+	if cfg.AutoBanThreshold > 0 && (cfg.AutoBanWindow <= 0 ||
+		cfg.AutoBanDuration <= 0) {
+		return nil, nil, fmt.Errorf("autobanwindow and " +
+			"autobanduration must be positive")
+	}
+
+	if cfg.RateLimitBurst < 0 {
+		return nil, nil, fmt.Errorf("ratelimitburst must not be " +
+			"negative")
+	}
+	if cfg.RateLimitBurst > 0 && cfg.RateLimitWindow <= 0 {
+		return nil, nil, fmt.Errorf("ratelimitwindow must be positive")
+	}
+
+	if cfg.ClusterPrimary != "" || len(cfg.ClusterPeers) != 0 {
+		if cfg.ClusterPrimary != "" && len(cfg.ClusterPeers) != 0 {
+			return nil, nil, fmt.Errorf("clusterprimary and " +
+				"clusterpeer are mutually exclusive")
+		}
+		if cfg.ClusterToken == "" {
+			return nil, nil, fmt.Errorf("clustertoken is required " +
+				"to run in a cluster")
+		}
+		for _, v := range append(cfg.ClusterPeers, cfg.ClusterPrimary) {
+			if v == "" {
+				continue
+			}
+			u, err := url.Parse(v)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return nil, nil, fmt.Errorf("invalid cluster "+
+					"instance url: %v", v)
+			}
+		}
+		if cfg.ClusterCert != "" {
+			cfg.ClusterCert = cleanAndExpandPath(cfg.ClusterCert)
+		}
+	}
+
+	if cfg.MaxProposalImages < 0 || cfg.MaxProposalImageSize <= 0 ||
+		cfg.MaxProposalMDs <= 0 || cfg.MaxProposalMDSize <= 0 ||
+		cfg.MaxCommentLength <= 0 {
+		return nil, nil, fmt.Errorf("maxproposalimages must not be " +
+			"negative and maxproposalimagesize, maxproposalmds, " +
+			"maxproposalmdsize and maxcommentlength must be positive")
+	}
+	if cfg.MinProposalNameLength <= 0 ||
+		cfg.MaxProposalNameLength < cfg.MinProposalNameLength {
+		return nil, nil, fmt.Errorf("minproposalnamelength must be " +
+			"positive and at most maxproposalnamelength")
+	}
+
+	if cfg.FileCacheSize < 0 {
+		return nil, nil, fmt.Errorf("filecachesize must not be negative")
+	}
+
+	if cfg.SpamFlagScore < 0 || cfg.SpamHoldScore < 0 {
+		return nil, nil, fmt.Errorf("spamflagscore and spamholdscore " +
+			"must not be negative")
+	}
+	for k, v := range cfg.SpamKeywords {
+		cfg.SpamKeywords[k] = strings.ToLower(strings.TrimSpace(v))
+		if cfg.SpamKeywords[k] == "" {
+			return nil, nil, fmt.Errorf("spamkeyword must not be " +
+				"empty")
+		}
+	}
+
+	if len(cfg.Webhooks) != 0 {
+		if cfg.WebhookSecret == "" {
+			return nil, nil, fmt.Errorf("webhooksecret is required " +
+				"to send webhooks")
+		}
+		for _, v := range cfg.Webhooks {
+			u, err := url.Parse(v)
+			if err != nil || (u.Scheme != "http" &&
+				u.Scheme != "https") || u.Host == "" {
+				return nil, nil, fmt.Errorf("invalid webhook "+
+					"url: %v", v)
+			}
+		}
+	}
+
+	switch cfg.StateStore {
+	case stateStoreMemory, stateStoreRedis:
+	default:
+		return nil, nil, fmt.Errorf("invalid statestore: %v",
+			cfg.StateStore)
+	}
+	switch cfg.SessionStore {
+	case sessionStoreState, sessionStoreDatabase:
+	default:
+		return nil, nil, fmt.Errorf("invalid sessionstore: %v",
+			cfg.SessionStore)
+	}
+
+	if cfg.OIDCIssuer != "" {
+		if cfg.OIDCClientID == "" || cfg.OIDCClientSecret == "" ||
+			cfg.OIDCRedirectURL == "" {
+			return nil, nil, fmt.Errorf("oidcclientid, " +
+				"oidcclientsecret and oidcredirecturl are " +
+				"required by oidcissuer")
+		}
+		for _, v := range []string{cfg.OIDCIssuer, cfg.OIDCRedirectURL} {
+			u, err := url.Parse(v)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return nil, nil, fmt.Errorf("invalid oidc url: %v",
+					v)
+			}
+		}
+	}
+
+	if cfg.APIAddress != "" {
+		u, err := url.Parse(cfg.APIAddress)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, nil, fmt.Errorf("invalid apiaddress: %v",
+				cfg.APIAddress)
+		}
+		cfg.APIAddress = strings.TrimSuffix(cfg.APIAddress, "/")
+	}
+
+	switch cfg.Authenticator {
+	case authenticatorLocal:
+	case authenticatorLDAP:
+		u, err := url.Parse(cfg.LDAPURL)
+		if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") ||
+			u.Hostname() == "" {
+			return nil, nil, fmt.Errorf("invalid ldapurl: %v",
+				cfg.LDAPURL)
+		}
+		if !strings.Contains(cfg.LDAPBindDN, "{email}") &&
+			!strings.Contains(cfg.LDAPBindDN, "{username}") {
+			return nil, nil, fmt.Errorf("ldapbinddn must contain " +
+				"{email} or {username}")
+		}
+		if cfg.LDAPCert != "" {
+			cfg.LDAPCert = cleanAndExpandPath(cfg.LDAPCert)
+		}
+	default:
+		return nil, nil, fmt.Errorf("invalid authenticator: %v",
+			cfg.Authenticator)
+	}
+
+	switch cfg.PasswordHash {
+	case passwordHashBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost ||
+			cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, nil, fmt.Errorf("bcryptcost must be between "+
+				"%v and %v", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case passwordHashArgon2id:
+		if cfg.Argon2Time == 0 || cfg.Argon2Threads == 0 {
+			return nil, nil, fmt.Errorf("argon2time and argon2threads " +
+				"must be positive")
+		}
+		// Argon2 needs at least 8 KiB per thread.
+		if cfg.Argon2Memory < 8*uint32(cfg.Argon2Threads) {
+			return nil, nil, fmt.Errorf("argon2memory must be at least "+
+				"%v KiB", 8*uint32(cfg.Argon2Threads))
+		}
+	default:
+		return nil, nil, fmt.Errorf("invalid passwordhash: %v",
+			cfg.PasswordHash)
+	}
+
+	for _, v := range append(cfg.EnableFeatures, cfg.DisableFeatures...) {
+		if !validFeature(v) {
+			return nil, nil, fmt.Errorf("invalid feature: %v", v)
+		}
+	}
+
+	if cfg.SignupPoWDifficulty > maxSignupPoWDifficulty {
+		return nil, nil, fmt.Errorf("signuppowdifficulty must not "+
+			"exceed %v", maxSignupPoWDifficulty)
+	}
+
+	// The identity is reported by the validation instead when validating.
+	if err := loadIdentity(&cfg); err != nil && !cfg.Validate {
 		return nil, nil, err
 	}
 
@@ -668,6 +1159,10 @@ func loadConfig() (*config, []string, error) {
 			return nil, nil, fmt.Errorf("[ERR]: Paywall amount needs to be "+
 				"higher than %v", dust)
 		}
+		if cfg.ProposalCreditPrice < dust {
+			return nil, nil, fmt.Errorf("[ERR]: Proposal credit price "+
+				"needs to be higher than %v", dust)
+		}
 		paywallKey, err := hdkeychain.NewKeyFromString(cfg.PaywallXpub)
 		if err != nil {
 			return nil, nil, fmt.Errorf("error processing extended public key: %v",
@@ -75,7 +75,8 @@ func convertPropStatusFromPD(s pd.RecordStatusT) www.PropStatusT {
 	switch s {
 	case pd.RecordStatusNotFound:
 		return www.PropStatusNotFound
-	case pd.RecordStatusNotReviewed:
+	case pd.RecordStatusNotReviewed, pd.RecordStatusUnreviewedChanges:
+		// Edited proposals have unreviewed changes.
 		return www.PropStatusNotReviewed
 	case pd.RecordStatusCensored:
 		return www.PropStatusCensored
@@ -115,25 +116,51 @@ func convertPropCensorFromPD(f pd.CensorshipRecord) www.CensorshipRecord {
 func convertPropFromInventoryRecord(r *inventoryRecord, userPubkeys map[string]string) www.ProposalRecord {
 	proposal := convertPropFromPD(r.record)
 
-	// Set the most up-to-date status.
+	// Set the most up-to-date status and the signed reason of the change.
 	for _, v := range r.changes {
 		proposal.Status = convertPropStatusFromPD(v.NewStatus)
+		proposal.StatusChangeMessage = v.StatusChangeMessage
+		proposal.StatusChangePublicKey = v.PublicKey
+		proposal.StatusChangeSignature = v.Signature
 	}
 
 	// Set the comments num.
 	proposal.NumComments = uint(len(r.comments))
 
-	// Set the user id.
-	var ok bool
-	proposal.UserId, ok = userPubkeys[proposal.PublicKey]
-	if !ok {
-		log.Errorf("user not found for public key %v, for proposal %v",
-			proposal.PublicKey, proposal.CensorshipRecord.Token)
+	// Proposals that were submitted before their author was recorded are
+	// attributed to the owner of the signing key.
+	if proposal.UserId == "" {
+		var ok bool
+		proposal.UserId, ok = userPubkeys[proposal.PublicKey]
+		if !ok {
+			log.Errorf("user not found for public key %v, for "+
+				"proposal %v", proposal.PublicKey,
+				proposal.CensorshipRecord.Token)
+		}
 	}
 
 	return proposal
 }
 
+// proposalAuthor returns the id of the user who submitted the provided
+// record, or an empty string if the record doesn't record its author.
+func proposalAuthor(p pd.Record) string {
+	for _, v := range p.Metadata {
+		if v.ID != mdStreamAuthor {
+			continue
+		}
+		md, err := decodeBackendProposalAuthor([]byte(v.Payload))
+		if err != nil {
+			log.Errorf("could not decode author '%v' token '%v': %v",
+				v.Payload, p.CensorshipRecord.Token, err)
+			break
+		}
+		return md.UserID
+	}
+
+	return ""
+}
+
 func convertPropFromPD(p pd.Record) www.ProposalRecord {
 	md := &BackendProposalMetadata{}
 	for _, v := range p.Metadata {
@@ -149,13 +176,25 @@ func convertPropFromPD(p pd.Record) www.ProposalRecord {
 		md = m
 	}
 
+	// Proposals that were never edited don't record their version.
+	version := md.ProposalVersion
+	if version == 0 {
+		version = 1
+	}
+
 	return www.ProposalRecord{
 		Name:             md.Name,
 		Status:           convertPropStatusFromPD(p.Status),
 		Timestamp:        md.Timestamp,
 		PublicKey:        md.PublicKey,
 		Signature:        md.Signature,
+		Version:          version,
 		Files:            convertPropFilesFromPD(p.Files),
+		UserId:           proposalAuthor(p),
+		Tags:             proposalTags(p),
+		Timeline:         proposalTimeline(p),
+		Pages:            recordPages(p),
+		CoAuthors:        proposalCoAuthors(p),
 		CensorshipRecord: convertPropCensorFromPD(p.CensorshipRecord),
 	}
 }
@@ -172,6 +211,14 @@ func convertErrorStatusFromPD(s int) www.ErrorStatusT {
 		return www.ErrorStatusUnsupportedMIMEType
 	case pd.ErrorStatusInvalidRecordStatusTransition:
 		return www.ErrorStatusInvalidPropStatusTransition
+	case pd.ErrorStatusRecordNotFound:
+		return www.ErrorStatusProposalNotFound
+	case pd.ErrorStatusInvalidRecordStatus:
+		return www.ErrorStatusWrongStatus
+	case pd.ErrorStatusVoteStarted:
+		return www.ErrorStatusVoteAlreadyStarted
+	case pd.ErrorStatusVoteNotFound:
+		return www.ErrorStatusVoteNotFound
 
 		// These cases are intentionally omitted because
 		// they are indicative of some internal server error,
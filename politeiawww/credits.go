@@ -0,0 +1,249 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strconv"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+// ProcessProposalCredits returns the proposal credit balance and purchase
+// history of the provided user.
+func (b *backend) ProcessProposalCredits(user *database.User) *www.ProposalCreditsReply {
+	reply := www.ProposalCreditsReply{
+		Credits:   user.ProposalCredits,
+		Price:     b.cfg.ProposalCreditPrice,
+		Purchases: make([]www.ProposalCreditPurchase, 0, len(user.ProposalCreditPurchases)),
+	}
+	for _, v := range user.ProposalCreditPurchases {
+		reply.Purchases = append(reply.Purchases, www.ProposalCreditPurchase{
+			Credits:   v.Credits,
+			Amount:    v.Amount,
+			TxID:      v.TxID,
+			Timestamp: v.Timestamp,
+		})
+	}
+	return &reply
+}
+
+// ProcessPurchaseCredits starts a purchase of proposal credits by the
+// provided user.  The payment goes to the paywall address of the user.
+func (b *backend) ProcessPurchaseCredits(user *database.User, pc www.PurchaseCredits) (*www.PurchaseCreditsReply, error) {
+	if pc.Credits == 0 || pc.Credits > www.ProposalCreditsMaxPurchase {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+	if b.cfg.PaywallXpub == "" {
+		return nil, www.UserError{
+			ErrorCode:    www.ErrorStatusFeatureDisabled,
+			ErrorContext: []string{www.FeaturePaywall},
+		}
+	}
+
+	// Users that signed up while the paywall was disabled have no paywall
+	// address yet.
+	address := user.NewUserPaywallAddress
+	if address == "" {
+		var err error
+		address, err = util.DerivePaywallAddress(b.params,
+			b.cfg.PaywallXpub, uint32(user.ID))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().Unix()
+	purchase := database.ProposalCreditPurchase{
+		Credits:     pc.Credits,
+		Amount:      pc.Credits * b.cfg.ProposalCreditPrice,
+		Address:     address,
+		TxNotBefore: now,
+		Timestamp:   now,
+	}
+
+	b.creditsMtx.Lock()
+	defer b.creditsMtx.Unlock()
+
+	u, err := b.db.UserGetById(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	u.PendingCreditPurchase = &purchase
+	err = b.db.UserUpdate(*u)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.PurchaseCreditsReply{
+		PaywallAddress:     purchase.Address,
+		PaywallAmount:      purchase.Amount,
+		PaywallTxNotBefore: purchase.TxNotBefore,
+	}, nil
+}
+
+// ProcessVerifyCreditsTx verifies that the provided transaction pays the
+// pending proposal credit purchase of the provided user and adds the credits.
+func (b *backend) ProcessVerifyCreditsTx(user *database.User, vc www.VerifyCreditsTx) (*www.VerifyCreditsTxReply, error) {
+	purchase := user.PendingCreditPurchase
+	if purchase == nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusNoCreditPurchase,
+		}
+	}
+
+	// A transaction can only pay for one purchase.
+	if vc.TxID == user.NewUserPaywallTx {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusPaymentTxUsed,
+		}
+	}
+	for _, v := range user.ProposalCreditPurchases {
+		if v.TxID == vc.TxID {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusPaymentTxUsed,
+			}
+		}
+	}
+
+	verified, err := util.VerifyTxWithBlockExplorers(purchase.Address,
+		purchase.Amount, vc.TxID, purchase.TxNotBefore,
+		b.cfg.MinConfirmationsRequired)
+	if err != nil {
+		return nil, err
+	}
+	if !verified {
+		return &www.VerifyCreditsTxReply{
+			Credits: user.ProposalCredits,
+		}, nil
+	}
+
+	b.creditsMtx.Lock()
+	defer b.creditsMtx.Unlock()
+
+	// The purchase may have been replaced or paid in the meantime.
+	u, err := b.db.UserGetById(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if u.PendingCreditPurchase == nil ||
+		*u.PendingCreditPurchase != *purchase {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusNoCreditPurchase,
+		}
+	}
+
+	purchase.TxID = vc.TxID
+	u.PendingCreditPurchase = nil
+	u.ProposalCredits += purchase.Credits
+	u.ProposalCreditPurchases = append(u.ProposalCreditPurchases, *purchase)
+	err = b.db.UserUpdate(*u)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.VerifyCreditsTxReply{
+		HasPaid: true,
+		Credits: u.ProposalCredits,
+	}, nil
+}
+
+// ProcessGrantCredits adds proposal credits to a user on behalf of an admin.
+func (b *backend) ProcessGrantCredits(gc www.GrantCredits, admin *database.User) (*www.GrantCreditsReply, error) {
+	if gc.Credits == 0 || gc.Credits > www.ProposalCreditsMaxPurchase {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+	id, err := strconv.ParseUint(gc.UserID, 10, 64)
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+
+	b.creditsMtx.Lock()
+	defer b.creditsMtx.Unlock()
+
+	user, err := b.db.UserGetById(id)
+	if err != nil {
+		if err == database.ErrUserNotFound {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusUserNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	user.ProposalCredits += gc.Credits
+	user.ProposalCreditPurchases = append(user.ProposalCreditPurchases,
+		database.ProposalCreditPurchase{
+			Credits:   gc.Credits,
+			AdminID:   admin.ID,
+			Timestamp: time.Now().Unix(),
+		})
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Admin %v granted %v proposal credits to user %v: %v",
+		admin.ID, gc.Credits, user.ID, gc.Reason)
+
+	return &www.GrantCreditsReply{
+		Credits: user.ProposalCredits,
+	}, nil
+}
+
+// spendProposalCredit deducts a proposal credit from the provided user when
+// the credits feature is enabled.  It returns true if a credit was spent.
+func (b *backend) spendProposalCredit(user *database.User) (bool, error) {
+	if !b.featureEnabled(www.FeatureCredits) {
+		return false, nil
+	}
+
+	b.creditsMtx.Lock()
+	defer b.creditsMtx.Unlock()
+
+	u, err := b.db.UserGetById(user.ID)
+	if err != nil {
+		return false, err
+	}
+	if u.ProposalCredits == 0 {
+		return false, www.UserError{
+			ErrorCode: www.ErrorStatusNoProposalCredits,
+		}
+	}
+	u.ProposalCredits--
+	err = b.db.UserUpdate(*u)
+	if err != nil {
+		return false, err
+	}
+	user.ProposalCredits = u.ProposalCredits
+
+	return true, nil
+}
+
+// refundProposalCredit gives back the proposal credit that was spent on a
+// submission that failed.
+func (b *backend) refundProposalCredit(user *database.User) {
+	b.creditsMtx.Lock()
+	defer b.creditsMtx.Unlock()
+
+	u, err := b.db.UserGetById(user.ID)
+	if err == nil {
+		u.ProposalCredits++
+		err = b.db.UserUpdate(*u)
+	}
+	if err != nil {
+		log.Errorf("refundProposalCredit %v: %v", user.ID, err)
+		return
+	}
+	user.ProposalCredits = u.ProposalCredits
+}
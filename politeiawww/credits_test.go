@@ -0,0 +1,86 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+func TestProposalCredits(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+	b.cfg.ProposalCreditPrice = 1000000
+	admin := &database.User{ID: 1, Admin: true}
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+
+	// Proposals are free while the feature is disabled.
+	_, _, err = createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+
+	b.setFeature(www.FeatureCredits, true)
+	_, _, err = createNewProposal(b, t, user, id)
+	assertError(t, err, www.ErrorStatusNoProposalCredits)
+
+	_, err = b.ProcessGrantCredits(www.GrantCredits{
+		UserID:  strconv.FormatUint(user.ID, 10),
+		Credits: www.ProposalCreditsMaxPurchase + 1,
+	}, admin)
+	assertError(t, err, www.ErrorStatusInvalidInput)
+	gcr, err := b.ProcessGrantCredits(www.GrantCredits{
+		UserID:  strconv.FormatUint(user.ID, 10),
+		Credits: 2,
+	}, admin)
+	assertSuccess(t, err)
+	if gcr.Credits != 2 {
+		t.Fatalf("got %v credits, want 2", gcr.Credits)
+	}
+
+	// Each proposal spends a credit, invalid proposals don't.
+	_, _, err = createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	_, _, err = createNewProposalWithInvalidTitle(b, t, user, id)
+	if err == nil {
+		t.Fatalf("invalid proposal accepted")
+	}
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	reply := b.ProcessProposalCredits(user)
+	if reply.Credits != 1 || reply.Price != b.cfg.ProposalCreditPrice ||
+		len(reply.Purchases) != 1 || reply.Purchases[0].Credits != 2 {
+		t.Fatalf("unexpected credits %v", reply)
+	}
+
+	// Purchases are paid to the paywall address of the user.
+	_, err = b.ProcessVerifyCreditsTx(user, www.VerifyCreditsTx{
+		TxID: "tx",
+	})
+	assertError(t, err, www.ErrorStatusNoCreditPurchase)
+	_, err = b.ProcessPurchaseCredits(user, www.PurchaseCredits{})
+	assertError(t, err, www.ErrorStatusInvalidInput)
+	pcr, err := b.ProcessPurchaseCredits(user, www.PurchaseCredits{
+		Credits: 3,
+	})
+	assertSuccess(t, err)
+	if pcr.PaywallAmount != 3*b.cfg.ProposalCreditPrice ||
+		pcr.PaywallTxNotBefore == 0 {
+		t.Fatalf("unexpected purchase %v", pcr)
+	}
+
+	// Transactions can't pay for several purchases.
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	user.ProposalCreditPurchases[0].TxID = "tx"
+	_, err = b.ProcessVerifyCreditsTx(user, www.VerifyCreditsTx{
+		TxID: "tx",
+	})
+	assertError(t, err, www.ErrorStatusPaymentTxUsed)
+}
@@ -22,6 +22,31 @@ var (
 	// ErrInvalidEmail indicates that a user's email is not properly formatted.
 	ErrInvalidEmail = errors.New("invalid user email")
 
+	// ErrIPBanNotFound indicates that an IP ban was not found in the
+	// database.
+	ErrIPBanNotFound = errors.New("ip ban not found")
+
+	// ErrSessionNotFound indicates that a session was not found in the
+	// database or that it expired.
+	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrPendingActionNotFound indicates that a pending admin action was
+	// not found in the database.
+	ErrPendingActionNotFound = errors.New("pending action not found")
+
+	// ErrDraftNotFound indicates that a proposal draft was not found in
+	// the database.
+	ErrDraftNotFound = errors.New("draft not found")
+
+	// ErrHeldProposalNotFound indicates that a held proposal was not found
+	// in the database.
+	ErrHeldProposalNotFound = errors.New("held proposal not found")
+
+	// ErrScheduledPublicationNotFound indicates that a scheduled
+	// publication was not found in the database.
+	ErrScheduledPublicationNotFound = errors.New("scheduled publication " +
+		"not found")
+
 	// ErrShutdown is emitted when the database is shutting down.
 	ErrShutdown = errors.New("database is shutting down")
 )
@@ -34,17 +59,34 @@ type Identity struct {
 	Deactivated int64                        // Time key was deactivated
 }
 
-// ActiveIdentity returns a the current active key.  If there is no active
+// EmailChange records a change of the email address of a user.
+type EmailChange struct {
+	OldEmail  string // Previous email address
+	NewEmail  string // Email address that replaced it
+	Timestamp int64  // Time of the change
+}
+
+// ActiveIdentity returns a the current active key.  If the user has several
+// active keys the most recently added one is returned.  If there is no active
 // valid key the call returns all 0s and false.
 func ActiveIdentity(i []Identity) ([identity.PublicKeySize]byte, bool) {
+	ids := ActiveIdentities(i)
+	if len(ids) == 0 {
+		return [identity.PublicKeySize]byte{}, false
+	}
+	return ids[len(ids)-1], true
+}
+
+// ActiveIdentities returns all active keys in the order they were added.
+func ActiveIdentities(i []Identity) [][identity.PublicKeySize]byte {
+	var ids [][identity.PublicKeySize]byte
 	for _, v := range i {
 		if v.Activated == 0 || v.Deactivated != 0 {
 			continue
 		}
-		return v.Key, true
+		ids = append(ids, v.Key)
 	}
-
-	return [identity.PublicKeySize]byte{}, false
+	return ids
 }
 
 // ActiveIdentityString returns a string representation of the current active
@@ -54,22 +96,90 @@ func ActiveIdentityString(i []Identity) (string, bool) {
 	return hex.EncodeToString(key[:]), ok
 }
 
+// VerificationTokenT is the type of a verification token.
+type VerificationTokenT int
+
+const (
+	VerificationTokenNewUser       VerificationTokenT = 0 // Verifies the email of a new user
+	VerificationTokenResetPassword VerificationTokenT = 1 // Resets the password
+	VerificationTokenChangeEmail   VerificationTokenT = 2 // Verifies a new email
+	VerificationTokenUpdateKey     VerificationTokenT = 3 // Activates a new public key
+)
+
+// VerificationToken is a token that is emailed to a user to verify an action.
+// Only the SHA256 digest of the token is stored so that the tokens can't be
+// used by someone who reads the database.
+type VerificationToken struct {
+	Type      VerificationTokenT // Type of the token
+	Hash      []byte             // SHA256 digest of the token
+	Expiry    int64              // Time the token expires
+	SingleUse bool               // Set if the token is removed once used
+}
+
 // User record.
 type User struct {
-	ID                              uint64 // Unique id
-	Email                           string // Email address + lookup key.
-	HashedPassword                  []byte // Blowfish hash
-	Admin                           bool   // Is user an admin
-	NewUserPaywallAddress           string // Address the user needs to send to
-	NewUserPaywallAmount            uint64 // Amount the user needs to send
-	NewUserPaywallTx                string // Paywall transaction id
-	NewUserPaywallTxNotBefore       int64  // Transactions occurring before this time will not be valid.
-	NewUserVerificationToken        []byte // Verification token during signup
-	NewUserVerificationExpiry       int64  // Verification expiration
-	UpdateKeyVerificationToken      []byte // Verification token for updating keypair
-	UpdateKeyVerificationExpiry     int64  // Verification expiration
-	ResetPasswordVerificationToken  []byte // Reset password token
-	ResetPasswordVerificationExpiry int64  // Reset password token expiration
+	ID                        uint64 // Unique id
+	UUID                      string // Stable public id that doesn't reveal the email
+	Email                     string // Email address + lookup key.
+	HashedPassword            []byte // Password hash
+	Admin                     bool   // Is user an admin
+	NewUserPaywallAddress     string // Address the user needs to send to
+	NewUserPaywallAmount      uint64 // Amount the user needs to send
+	NewUserPaywallTx          string // Paywall transaction id
+	NewUserPaywallTxNotBefore int64  // Transactions occurring before this time will not be valid.
+	EmailNotifications        uint64 // Notify the user via emails
+
+	// Verification tokens that were emailed to the user and are pending,
+	// at most one per type.
+	VerificationTokens []VerificationToken
+
+	// Email address that replaces the current one once the user verifies
+	// it, and the history of the email address changes.
+	NewEmail     string
+	EmailChanges []EmailChange
+
+	// Digest email frequency and the time the last digest was emailed.
+	EmailDigest     int
+	LastEmailDigest int64
+
+	// Set when an email to the user bounced or the user complained about
+	// an email.  No notification emails are sent until the email address
+	// is verified again.
+	EmailUndeliverable bool
+
+	// TOTP second factor.  The secret is pending until the user confirms
+	// the enrollment with a code.  TOTPLastStep is the time step of the
+	// last accepted code so that codes can't be replayed.  Recovery codes
+	// are stored as SHA256 digests and are removed once used.
+	TOTPSecret        []byte
+	TOTPEnabled       bool
+	TOTPLastStep      int64
+	TOTPRecoveryCodes [][]byte
+
+	// Time the account was deactivated by the user or an admin, 0 if it
+	// is active.
+	Deactivated int64
+
+	// Time of the last successful login and number of failed logins since
+	// then.
+	LastLogin    int64
+	FailedLogins uint64
+
+	// Login sessions that have not been revoked.  The expired sessions are
+	// removed on login.
+	LoginSessions []LoginSession
+
+	// Personal access tokens of the user.
+	APITokens []APIToken
+
+	// Accounts of OpenID Connect providers that the user may log in with.
+	OIDCIdentities []OIDCIdentity
+
+	// Proposal credits.  One credit is spent per submitted proposal.  The
+	// pending purchase awaits its payment.
+	ProposalCredits         uint64
+	ProposalCreditPurchases []ProposalCreditPurchase
+	PendingCreditPurchase   *ProposalCreditPurchase
 
 	// All dentitiesuser has ever used.  User should only have one
 	// active key at a time.  We allow multiples in order to deal with key
@@ -77,13 +187,239 @@ type User struct {
 	Identities []Identity
 }
 
+// APIToken is a personal access token that authenticates the scripts of a
+// user.  Only the SHA256 digest of the token is stored.
+type APIToken struct {
+	ID        string // Unique id
+	Name      string // Name chosen by the user
+	Scope     string // Requests the token is allowed to make
+	Digest    []byte // SHA256 digest of the token
+	Timestamp int64  // Time the token was created
+}
+
+// ProposalCreditPurchase records proposal credits that a user bought or that
+// an admin granted.
+type ProposalCreditPurchase struct {
+	Credits     uint64 // Number of credits
+	Amount      uint64 // Price in atoms, 0 if granted by an admin
+	Address     string // Paywall address of the payment
+	TxID        string // Payment transaction, empty if granted
+	TxNotBefore int64  // Transactions before this time are not valid
+	AdminID     uint64 // Admin that granted the credits
+	Timestamp   int64  // Time the purchase was started or granted
+}
+
+// OIDCIdentity links a user to an account of an OpenID Connect provider.
+type OIDCIdentity struct {
+	Issuer    string // Issuer of the provider
+	Subject   string // Id of the account at the provider
+	Email     string // Email address reported by the provider
+	Timestamp int64  // Time the account was linked
+}
+
+// IPBan bans an IP address or network from using the web server.
+type IPBan struct {
+	Network   string // IP address or network in CIDR notation + lookup key
+	Reason    string // Reason of the ban
+	AdminID   uint64 // Admin that created the ban
+	Automatic bool   // Set if the ban was created because of abuse
+	Timestamp int64  // Time the ban was created
+	Expiry    int64  // Time the ban expires, 0 if it doesn't expire
+}
+
+// StatusChange is a status transition of a proposal.  The statuses are www
+// proposal statuses.
+type StatusChange struct {
+	Token     string // Censorship token of the proposal + lookup key
+	AdminID   uint64 // Admin that changed the status
+	PublicKey string // Public key of the admin
+	Signature string // Signature of Token+string(NewStatus)
+	OldStatus int    // Status before the change
+	NewStatus int    // Status after the change
+	Reason    string // Reason provided by the admin
+	Timestamp int64  // Time of the change
+}
+
+// SecurityEvent records an event of the account of a user, such as a login or
+// a password change, so that the user can detect a compromise of the account.
+// The types are www security event types.
+type SecurityEvent struct {
+	UserID    uint64 // User of the event + lookup key
+	Type      string // Type of the event
+	IP        string // IP address of the client
+	UserAgent string // User agent of the client
+	Context   string // Details of the event, such as a public key
+	Timestamp int64  // Time of the event
+}
+
+// AdminAction records an action of an admin on the account of another user,
+// such as granting or revoking admin privileges.  The actions are www admin
+// action types.
+type AdminAction struct {
+	AdminID   uint64 // Admin that performed the action
+	UserID    uint64 // User the action applies to
+	Action    string // Type of the action
+	PublicKey string // Public key of the admin
+	Signature string // Signature of UserID+Action
+	Reason    string // Reason provided by the admin
+	Timestamp int64  // Time of the action
+}
+
+// PendingAction is a destructive admin action, such as a censorship, that
+// awaits the approval of a second admin before it takes effect.  The actions
+// are www pending action types.
+type PendingAction struct {
+	ID        string // Unique id + lookup key
+	Action    string // Type of the action
+	AdminID   uint64 // Admin that requested the action
+	Payload   []byte // JSON encoded www command of the action
+	Timestamp int64  // Time of the request
+	Expiry    int64  // Time the request expires unless it is approved
+}
+
+// HeldProposal is a new proposal that the spam filters held for admin review.
+// Held proposals are sent to politeiad once an admin releases them.
+type HeldProposal struct {
+	ID        string   // Unique id + lookup key
+	UserID    uint64   // User that submitted the proposal
+	Proposal  []byte   // JSON encoded www new proposal command
+	Score     int      // Spam score of the proposal
+	Reasons   []string // Filters that contributed to the score
+	Timestamp int64    // Time of the submission
+
+	// CreditSpent is set if a proposal credit was reserved for the
+	// proposal when it was held.
+	CreditSpent bool
+}
+
+// ScheduledPublication is an unvetted proposal that an admin approved for
+// publication at a later time.
+type ScheduledPublication struct {
+	Token     string // Censorship token + lookup key
+	AdminID   uint64 // Admin that approved the proposal
+	Payload   []byte // JSON encoded www set proposal status command
+	PublishAt int64  // Time the proposal is published
+	Timestamp int64  // Time of the approval
+}
+
+// Draft is an unsigned proposal that a user saved in order to finish it
+// later.  Drafts are never sent to politeiad.
+type Draft struct {
+	ID        string // Unique id
+	UserID    uint64 // User that owns the draft
+	Files     []byte // JSON encoded www files of the draft
+	Timestamp int64  // Last update of the draft
+}
+
+// LoginSession describes a login of a user.  Its id is stored in the session
+// of the web server so that the session can be revoked by removing the login
+// session from the user.
+type LoginSession struct {
+	ID        string // Unique id
+	IP        string // IP address of the login
+	UserAgent string // User agent of the login
+	Timestamp int64  // Time of the login
+	Expiry    int64  // Time the login session expires
+}
+
+// Session is a session of the web server.  The values are encoded by the web
+// server.
+type Session struct {
+	ID     string // Session id + lookup key
+	Values []byte // Encoded session values
+	Expiry int64  // Time the session expires
+}
+
+// UserSortT is the order of the users returned by UsersPage.
+type UserSortT int
+
+const (
+	// UserSortID sorts users by id.  Ids are assigned in sequence so this
+	// is the order in which the users were created.
+	UserSortID UserSortT = 0
+
+	// UserSortEmail sorts users by email.
+	UserSortEmail UserSortT = 1
+)
+
+// UsersQuery selects a page of users.  The page starts at the provided id or
+// email, depending on the sort order.
+type UsersQuery struct {
+	Sort      UserSortT // Sort order
+	FromID    uint64    // Return users with this id or greater, UserSortID only
+	FromEmail string    // Return users with this email or greater, UserSortEmail only
+	Limit     int       // Maximum number of users to return
+}
+
 // Database interface that is required by the web server.
 type Database interface {
 	// User functions
 	UserGet(string) (*User, error)           // Return user record, key is email
+	UserGetById(uint64) (*User, error)       // Return user record given its id
+	UserGetByPubKey(string) (*User, error)   // Return user record given a public key it has used
+	UserGetByUUID(string) (*User, error)     // Return user record given its uuid
 	UserNew(User) error                      // Add new user
 	UserUpdate(User) error                   // Update existing user
+	UserChangeEmail(string, User) error      // Update existing user and move it to its email, key is old email
 	AllUsers(callbackFn func(u *User)) error // Iterate all users
+	UsersPage(UsersQuery) ([]User, error)    // Return a page of users
+
+	// Federated login functions
+	UserGetByOIDCSubject(issuer, subject string) (*User, error) // Return user record given a linked provider account
+
+	// IP ban functions
+	IPBanNew(IPBan) error        // Add or replace IP ban
+	IPBanDelete(string) error    // Remove IP ban, key is network
+	AllIPBans() ([]IPBan, error) // Return all IP bans
+
+	// Session functions
+	SessionGet(string) (*Session, error) // Return unexpired session, key is id
+	SessionSet(Session) error            // Add or replace session
+	SessionDelete(string) error          // Remove session, key is id
+	SessionsPurge(int64) error           // Remove sessions that expire before the provided time
+
+	// Proposal status change functions
+	StatusChangeNew(StatusChange) error           // Append status change
+	StatusChanges(string) ([]StatusChange, error) // Return status changes, key is token
+
+	// Security event functions
+	SecurityEventNew(SecurityEvent) error           // Append security event, the oldest events are dropped
+	SecurityEvents(uint64) ([]SecurityEvent, error) // Return security events, key is user id
+
+	// Device functions
+	DeviceNew(uint64, string) error            // Add device of a user, key is user id and device id
+	DeviceExists(uint64, string) (bool, error) // Return true if the device of a user is known
+
+	// Admin audit log functions
+	AdminActionNew(AdminAction) error        // Append admin action
+	AllAdminActions() ([]AdminAction, error) // Return all admin actions
+
+	// Pending admin action functions
+	PendingActionNew(PendingAction) error        // Add pending action
+	PendingActionDelete(string) error            // Remove pending action, key is id
+	AllPendingActions() ([]PendingAction, error) // Return all pending actions, including the expired ones
+
+	// Proposal draft functions
+	DraftSet(Draft) error             // Add or replace draft
+	DraftDelete(uint64, string) error // Remove draft, keys are user id and draft id
+	Drafts(uint64) ([]Draft, error)   // Return drafts, key is user id
+
+	// Held proposal functions
+	HeldProposalNew(HeldProposal) error        // Add held proposal
+	HeldProposalDelete(string) error           // Remove held proposal, key is id
+	AllHeldProposals() ([]HeldProposal, error) // Return all held proposals
+
+	// Scheduled publication functions
+	ScheduledPublicationSet(ScheduledPublication) error        // Add or replace scheduled publication
+	ScheduledPublicationDelete(string) error                   // Remove scheduled publication, key is token
+	AllScheduledPublications() ([]ScheduledPublication, error) // Return all scheduled publications
+
+	// Proposal view functions
+	ProposalViewsAdd(map[string]uint64) error     // Add views, key is token
+	AllProposalViews() (map[string]uint64, error) // Return views by token
+
+	// Vote notification functions
+	VoteNotificationNew(string, uint64) (bool, error) // Record sent vote notification, false if it already was, key is token
 
 	// Close performs cleanup of the backend.
 	Close() error
@@ -1,12 +1,16 @@
 package localdb
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"fmt"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/decred/politeia/politeiawww/database"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
 )
 
 // EncodeVersion encodes Version into a JSON byte slice.
@@ -31,8 +35,37 @@ func DecodeVersion(payload []byte) (*Version, error) {
 	return &version, nil
 }
 
-// openUserDB opens the user database and writes out the version record if
-// needed.
+// Verify opens the user database that is stored in the provided root
+// directory read only and verifies that its version is supported.  Unlike
+// New it neither creates nor upgrades the database.
+func Verify(root string) error {
+	db, err := leveldb.OpenFile(filepath.Join(root, UserdbPath),
+		&opt.Options{
+			ErrorIfMissing: true,
+			ReadOnly:       true,
+		})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	payload, err := db.Get([]byte(UserVersionKey), nil)
+	if err != nil {
+		return fmt.Errorf("version record: %v", err)
+	}
+	version, err := DecodeVersion(payload)
+	if err != nil {
+		return err
+	}
+	if version.Version > UserVersion {
+		return fmt.Errorf("unsupported version %v", version.Version)
+	}
+
+	return nil
+}
+
+// openUserDB opens the user database, upgrades it and writes out the version
+// record if needed.
 func (l *localdb) openUserDB(path string) error {
 	// open database
 	var err error
@@ -42,8 +75,56 @@ func (l *localdb) openUserDB(path string) error {
 	}
 
 	// See if we need to write a version record
-	exists, err := l.userdb.Has([]byte(UserVersionKey), nil)
-	if err != nil || exists {
+	payload, err := l.userdb.Get([]byte(UserVersionKey), nil)
+	if err == nil {
+		version, err := DecodeVersion(payload)
+		if err != nil {
+			return err
+		}
+		if version.Version >= UserVersion {
+			return nil
+		}
+
+		// Version 2 adds the public key records.
+		if version.Version < 2 {
+			err = l.indexPublicKeys()
+			if err != nil {
+				return err
+			}
+		}
+
+		// Version 3 adds the uuids of the users.
+		if version.Version < 3 {
+			err = l.assignUUIDs()
+			if err != nil {
+				return err
+			}
+		}
+
+		// Version 4 replaces the verification token fields of the users
+		// with hashed verification tokens.
+		if version.Version < 4 {
+			err = l.migrateVerificationTokens()
+			if err != nil {
+				return err
+			}
+		}
+
+		// Version 5 adds the id records.
+		if version.Version < 5 {
+			err = l.indexUserIDs()
+			if err != nil {
+				return err
+			}
+		}
+
+		// Version 6 moves the vote notifications from the users to
+		// their proposals.
+		err = l.migrateVoteNotifications()
+		if err != nil {
+			return err
+		}
+	} else if err != leveldb.ErrNotFound {
 		return err
 	}
 
@@ -58,6 +139,253 @@ func (l *localdb) openUserDB(path string) error {
 	return l.userdb.Put([]byte(UserVersionKey), v, nil)
 }
 
+// indexPublicKeys writes the public key records of all users.
+func (l *localdb) indexPublicKeys() error {
+	log.Infof("Indexing user public keys")
+
+	batch := new(leveldb.Batch)
+	iter := l.userdb.NewIterator(nil, nil)
+	for iter.Next() {
+		// Ignore the records that aren't users.
+		if !isUserRecord(iter.Key()) {
+			continue
+		}
+
+		u, err := DecodeUser(iter.Value())
+		if err != nil {
+			iter.Release()
+			return err
+		}
+		putPublicKeys(batch, *u)
+	}
+	iter.Release()
+
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	return l.userdb.Write(batch, nil)
+}
+
+// indexUserIDs writes the id records of all users.
+func (l *localdb) indexUserIDs() error {
+	log.Infof("Indexing user ids")
+
+	batch := new(leveldb.Batch)
+	iter := l.userdb.NewIterator(nil, nil)
+	for iter.Next() {
+		// Ignore the records that aren't users.
+		if !isUserRecord(iter.Key()) {
+			continue
+		}
+
+		u, err := DecodeUser(iter.Value())
+		if err != nil {
+			iter.Release()
+			return err
+		}
+		putID(batch, *u)
+	}
+	iter.Release()
+
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	return l.userdb.Write(batch, nil)
+}
+
+// assignUUIDs assigns a uuid to the users that don't have one and writes
+// their uuid records.
+func (l *localdb) assignUUIDs() error {
+	log.Infof("Assigning user uuids")
+
+	batch := new(leveldb.Batch)
+	iter := l.userdb.NewIterator(nil, nil)
+	for iter.Next() {
+		// Ignore the records that aren't users.
+		if !isUserRecord(iter.Key()) {
+			continue
+		}
+
+		u, err := DecodeUser(iter.Value())
+		if err != nil {
+			iter.Release()
+			return err
+		}
+		if u.UUID == "" {
+			u.UUID, err = newUUID()
+			if err != nil {
+				iter.Release()
+				return err
+			}
+			payload, err := EncodeUser(*u)
+			if err != nil {
+				iter.Release()
+				return err
+			}
+			batch.Put([]byte(u.Email), payload)
+		}
+		putUUID(batch, *u)
+	}
+	iter.Release()
+
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	return l.userdb.Write(batch, nil)
+}
+
+// legacyVerificationTokens are the verification token fields of the users
+// before version 4.
+type legacyVerificationTokens struct {
+	NewUserVerificationToken        []byte
+	NewUserVerificationExpiry       int64
+	UpdateKeyVerificationToken      []byte
+	UpdateKeyVerificationExpiry     int64
+	ResetPasswordVerificationToken  []byte
+	ResetPasswordVerificationExpiry int64
+	NewEmailVerificationToken       []byte
+	NewEmailVerificationExpiry      int64
+}
+
+// migrateVerificationTokens replaces the verification token fields of all
+// users with hashed verification tokens.  The tokens that were already
+// emailed remain valid.
+func (l *localdb) migrateVerificationTokens() error {
+	log.Infof("Migrating verification tokens")
+
+	batch := new(leveldb.Batch)
+	iter := l.userdb.NewIterator(nil, nil)
+	for iter.Next() {
+		// Ignore the records that aren't users.
+		if !isUserRecord(iter.Key()) {
+			continue
+		}
+
+		u, err := DecodeUser(iter.Value())
+		if err != nil {
+			iter.Release()
+			return err
+		}
+		var legacy legacyVerificationTokens
+		err = json.Unmarshal(iter.Value(), &legacy)
+		if err != nil {
+			iter.Release()
+			return err
+		}
+
+		tokens := []struct {
+			typ    database.VerificationTokenT
+			token  []byte
+			expiry int64
+		}{
+			{database.VerificationTokenNewUser,
+				legacy.NewUserVerificationToken,
+				legacy.NewUserVerificationExpiry},
+			{database.VerificationTokenResetPassword,
+				legacy.ResetPasswordVerificationToken,
+				legacy.ResetPasswordVerificationExpiry},
+			{database.VerificationTokenChangeEmail,
+				legacy.NewEmailVerificationToken,
+				legacy.NewEmailVerificationExpiry},
+			{database.VerificationTokenUpdateKey,
+				legacy.UpdateKeyVerificationToken,
+				legacy.UpdateKeyVerificationExpiry},
+		}
+		for _, v := range tokens {
+			if v.token == nil {
+				continue
+			}
+			hash := sha256.Sum256(v.token)
+			u.VerificationTokens = append(u.VerificationTokens,
+				database.VerificationToken{
+					Type:      v.typ,
+					Hash:      hash[:],
+					Expiry:    v.expiry,
+					SingleUse: true,
+				})
+		}
+
+		// Encoding the user drops the legacy fields.
+		payload, err := EncodeUser(*u)
+		if err != nil {
+			iter.Release()
+			return err
+		}
+		batch.Put(iter.Key(), payload)
+	}
+	iter.Release()
+
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	return l.userdb.Write(batch, nil)
+}
+
+// legacyVoteNotifications are the vote notifications that were emailed to
+// the users, by proposal censorship token, before version 6.
+type legacyVoteNotifications struct {
+	VoteNotifications map[string]uint64
+}
+
+// migrateVoteNotifications moves the vote notifications that were recorded
+// in the users to the records of their proposals so that they aren't emailed
+// again.
+func (l *localdb) migrateVoteNotifications() error {
+	log.Infof("Migrating vote notifications")
+
+	sent := make(map[string]uint64)
+	batch := new(leveldb.Batch)
+	iter := l.userdb.NewIterator(nil, nil)
+	for iter.Next() {
+		// Ignore the records that aren't users.
+		if !isUserRecord(iter.Key()) {
+			continue
+		}
+
+		var legacy legacyVoteNotifications
+		err := json.Unmarshal(iter.Value(), &legacy)
+		if err != nil {
+			iter.Release()
+			return err
+		}
+		if len(legacy.VoteNotifications) == 0 {
+			continue
+		}
+		for token, n := range legacy.VoteNotifications {
+			sent[token] |= n
+		}
+
+		// Encoding the user drops the legacy field.
+		u, err := DecodeUser(iter.Value())
+		if err != nil {
+			iter.Release()
+			return err
+		}
+		payload, err := EncodeUser(*u)
+		if err != nil {
+			iter.Release()
+			return err
+		}
+		batch.Put(iter.Key(), payload)
+	}
+	iter.Release()
+
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	for token, n := range sent {
+		batch.Put([]byte(VoteNotificationsPrefix+token),
+			[]byte(strconv.FormatUint(n, 10)))
+	}
+
+	return l.userdb.Write(batch, nil)
+}
+
 // EncodeUser encodes User into a JSON byte slice.
 func EncodeUser(u database.User) ([]byte, error) {
 	b, err := json.Marshal(u)
@@ -79,3 +407,230 @@ func DecodeUser(payload []byte) (*database.User, error) {
 
 	return &u, nil
 }
+
+// EncodeIPBans encodes a list of IPBan into a JSON byte slice.
+func EncodeIPBans(bans []database.IPBan) ([]byte, error) {
+	b, err := json.Marshal(bans)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeIPBans decodes a JSON byte slice into a list of IPBan.
+func DecodeIPBans(payload []byte) ([]database.IPBan, error) {
+	var bans []database.IPBan
+
+	err := json.Unmarshal(payload, &bans)
+	if err != nil {
+		return nil, err
+	}
+
+	return bans, nil
+}
+
+// EncodeStatusChanges encodes a list of StatusChange into a JSON byte slice.
+func EncodeStatusChanges(changes []database.StatusChange) ([]byte, error) {
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeStatusChanges decodes a JSON byte slice into a list of StatusChange.
+func DecodeStatusChanges(payload []byte) ([]database.StatusChange, error) {
+	var changes []database.StatusChange
+
+	err := json.Unmarshal(payload, &changes)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// EncodeSecurityEvents encodes a list of SecurityEvent into a JSON byte
+// slice.
+func EncodeSecurityEvents(events []database.SecurityEvent) ([]byte, error) {
+	b, err := json.Marshal(events)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeSecurityEvents decodes a JSON byte slice into a list of
+// SecurityEvent.
+func DecodeSecurityEvents(payload []byte) ([]database.SecurityEvent, error) {
+	var events []database.SecurityEvent
+
+	err := json.Unmarshal(payload, &events)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// EncodeSession encodes Session into a JSON byte slice.
+func EncodeSession(session database.Session) ([]byte, error) {
+	b, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeSession decodes a JSON byte slice into a Session.
+func DecodeSession(payload []byte) (*database.Session, error) {
+	var session database.Session
+
+	err := json.Unmarshal(payload, &session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// EncodeAdminActions encodes a list of AdminAction into a JSON byte slice.
+func EncodeAdminActions(actions []database.AdminAction) ([]byte, error) {
+	b, err := json.Marshal(actions)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeAdminActions decodes a JSON byte slice into a list of AdminAction.
+func DecodeAdminActions(payload []byte) ([]database.AdminAction, error) {
+	var actions []database.AdminAction
+
+	err := json.Unmarshal(payload, &actions)
+	if err != nil {
+		return nil, err
+	}
+
+	return actions, nil
+}
+
+// EncodePendingActions encodes a list of PendingAction into a JSON byte slice.
+func EncodePendingActions(actions []database.PendingAction) ([]byte, error) {
+	b, err := json.Marshal(actions)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// EncodeDrafts encodes a list of Draft into a JSON byte slice.
+func EncodeDrafts(drafts []database.Draft) ([]byte, error) {
+	b, err := json.Marshal(drafts)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeDrafts decodes a JSON byte slice into a list of Draft.
+func DecodeDrafts(payload []byte) ([]database.Draft, error) {
+	var drafts []database.Draft
+
+	err := json.Unmarshal(payload, &drafts)
+	if err != nil {
+		return nil, err
+	}
+
+	return drafts, nil
+}
+
+// EncodeHeldProposals encodes a list of HeldProposal into a JSON byte slice.
+func EncodeHeldProposals(proposals []database.HeldProposal) ([]byte, error) {
+	b, err := json.Marshal(proposals)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeHeldProposals decodes a JSON byte slice into a list of HeldProposal.
+func DecodeHeldProposals(payload []byte) ([]database.HeldProposal, error) {
+	var proposals []database.HeldProposal
+
+	err := json.Unmarshal(payload, &proposals)
+	if err != nil {
+		return nil, err
+	}
+
+	return proposals, nil
+}
+
+// EncodeScheduledPublications encodes a list of ScheduledPublication into a
+// JSON byte slice.
+func EncodeScheduledPublications(publications []database.ScheduledPublication) ([]byte, error) {
+	b, err := json.Marshal(publications)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeScheduledPublications decodes a JSON byte slice into a list of
+// ScheduledPublication.
+func DecodeScheduledPublications(payload []byte) ([]database.ScheduledPublication, error) {
+	var publications []database.ScheduledPublication
+
+	err := json.Unmarshal(payload, &publications)
+	if err != nil {
+		return nil, err
+	}
+
+	return publications, nil
+}
+
+// EncodeProposalViews encodes the view counts of the proposals into a JSON
+// byte slice.
+func EncodeProposalViews(views map[string]uint64) ([]byte, error) {
+	b, err := json.Marshal(views)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeProposalViews decodes a JSON byte slice into the view counts of the
+// proposals.
+func DecodeProposalViews(payload []byte) (map[string]uint64, error) {
+	views := make(map[string]uint64)
+
+	err := json.Unmarshal(payload, &views)
+	if err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
+
+// DecodePendingActions decodes a JSON byte slice into a list of
+// PendingAction.
+func DecodePendingActions(payload []byte) ([]database.PendingAction, error) {
+	var actions []database.PendingAction
+
+	err := json.Unmarshal(payload, &actions)
+	if err != nil {
+		return nil, err
+	}
+
+	return actions, nil
+}
@@ -1,23 +1,99 @@
 package localdb
 
 import (
+	"crypto/rand"
 	"encoding/binary"
+	"encoding/hex"
+	"fmt"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/decred/politeia/politeiawww/database"
 
 	"github.com/badoux/checkmail"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 const (
 	UserdbPath    = "users"
 	LastUserIdKey = "lastuserid"
 
-	UserVersion    uint32 = 1
+	UserVersion    uint32 = 6
 	UserVersionKey        = "userversion"
+
+	// IPBansKey is the key of the record that holds all IP bans.
+	IPBansKey = "ipbans"
+
+	// AdminActionsKey is the key of the record that holds the admin audit
+	// log.
+	AdminActionsKey = "adminactions"
+
+	// PendingActionsKey is the key of the record that holds the admin
+	// actions that await approval.
+	PendingActionsKey = "pendingactions"
+
+	// HeldProposalsKey is the key of the record that holds the proposals
+	// that await admin review.
+	HeldProposalsKey = "heldproposals"
+
+	// ScheduledPublicationsKey is the key of the record that holds the
+	// proposals that await their publication time.
+	ScheduledPublicationsKey = "scheduledpublications"
+
+	// ProposalViewsKey is the key of the record that holds the view counts
+	// of the proposals.
+	ProposalViewsKey = "proposalviews"
+
+	// VoteNotificationsPrefix prefixes the censorship token in the keys of
+	// the records that hold the vote notifications that were emailed for a
+	// proposal.
+	VoteNotificationsPrefix = "votenotifications:"
+
+	// StatusChangesPrefix prefixes the censorship token in the keys of the
+	// records that hold the status changes of a proposal.
+	StatusChangesPrefix = "statuschanges:"
+
+	// SecurityEventsPrefix prefixes the user id in the keys of the records
+	// that hold the security events of a user.
+	SecurityEventsPrefix = "securityevents:"
+
+	// DevicesPrefix prefixes the user id and the device id in the keys of
+	// the records of the devices a user logged in from.
+	DevicesPrefix = "devices:"
+
+	// MaxSecurityEvents is the maximum number of security events that are
+	// kept per user.  The oldest events are dropped.
+	MaxSecurityEvents = 1000
+
+	// DraftsPrefix prefixes the user id in the keys of the records that
+	// hold the proposal drafts of a user.
+	DraftsPrefix = "drafts:"
+
+	// SessionPrefix prefixes the session id in the keys of the session
+	// records.
+	SessionPrefix = "session:"
+
+	// PublicKeyPrefix prefixes the hex encoded public key in the keys of
+	// the records that map the public keys users have used to their email.
+	PublicKeyPrefix = "publickey:"
+
+	// OIDCSubjectPrefix prefixes the issuer and subject in the keys of the
+	// records that map the linked OpenID Connect accounts to the email of
+	// their user.
+	OIDCSubjectPrefix = "oidc:"
+
+	// UUIDPrefix prefixes the uuid in the keys of the records that map the
+	// uuids of the users to their email.
+	UUIDPrefix = "uuid:"
+
+	// IDPrefix prefixes the user id in the keys of the records that map
+	// the ids of the users to their email.
+	IDPrefix = "id:"
 )
 
 var (
@@ -76,6 +152,10 @@ func (l *localdb) UserNew(u database.User) error {
 
 	// Set the new id on the user.
 	u.ID = lastUserId
+	u.UUID, err = newUUID()
+	if err != nil {
+		return err
+	}
 
 	// Write the new id back to the db.
 	b = make([]byte, 8)
@@ -90,7 +170,13 @@ func (l *localdb) UserNew(u database.User) error {
 		return err
 	}
 
-	return l.userdb.Put([]byte(u.Email), payload, nil)
+	batch := new(leveldb.Batch)
+	batch.Put([]byte(u.Email), payload)
+	putPublicKeys(batch, u)
+	putOIDCSubjects(batch, u)
+	putUUID(batch, u)
+	putID(batch, u)
+	return l.userdb.Write(batch, nil)
 }
 
 // UserGet returns a user record if found in the database.
@@ -119,6 +205,36 @@ func (l *localdb) UserGet(email string) (*database.User, error) {
 	return u, nil
 }
 
+// UserGetById returns a user record given its id, if found in the database.
+//
+// UserGetById satisfies the backend interface.
+func (l *localdb) UserGetById(id uint64) (*database.User, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("UserGetById: %v", id)
+
+	email, err := l.userdb.Get(idKey(id), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, database.ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	payload, err := l.userdb.Get(email, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, database.ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return DecodeUser(payload)
+}
+
 // Update existing user.
 //
 // UserUpdate satisfies the backend interface.
@@ -145,7 +261,165 @@ func (l *localdb) UserUpdate(u database.User) error {
 		return err
 	}
 
-	return l.userdb.Put([]byte(u.Email), payload, nil)
+	batch := new(leveldb.Batch)
+	batch.Put([]byte(u.Email), payload)
+	putPublicKeys(batch, u)
+	putOIDCSubjects(batch, u)
+	putUUID(batch, u)
+	putID(batch, u)
+	return l.userdb.Write(batch, nil)
+}
+
+// UserChangeEmail updates an existing user and moves it from the provided old
+// email to its email.
+//
+// UserChangeEmail satisfies the backend interface.
+func (l *localdb) UserChangeEmail(oldEmail string, u database.User) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("UserChangeEmail: %v %v", oldEmail, u)
+
+	if err := checkmail.ValidateFormat(u.Email); err != nil {
+		return database.ErrInvalidEmail
+	}
+
+	// Make sure the user exists and the new email is not in use.
+	exists, err := l.userdb.Has([]byte(oldEmail), nil)
+	if err != nil {
+		return err
+	} else if !exists {
+		return database.ErrUserNotFound
+	}
+	exists, err = l.userdb.Has([]byte(u.Email), nil)
+	if err != nil {
+		return err
+	} else if exists {
+		return database.ErrUserExists
+	}
+
+	payload, err := EncodeUser(u)
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Delete([]byte(oldEmail))
+	batch.Put([]byte(u.Email), payload)
+	putPublicKeys(batch, u)
+	putOIDCSubjects(batch, u)
+	putUUID(batch, u)
+	putID(batch, u)
+	return l.userdb.Write(batch, nil)
+}
+
+// UserGetByPubKey returns the user that has used the provided public key,
+// if found in the database.
+//
+// UserGetByPubKey satisfies the backend interface.
+func (l *localdb) UserGetByPubKey(publicKey string) (*database.User, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("UserGetByPubKey: %v", publicKey)
+
+	email, err := l.userdb.Get([]byte(PublicKeyPrefix+
+		strings.ToLower(publicKey)), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, database.ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	payload, err := l.userdb.Get(email, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, database.ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return DecodeUser(payload)
+}
+
+// UserGetByUUID returns the user with the provided uuid, if found in the
+// database.
+//
+// UserGetByUUID satisfies the backend interface.
+func (l *localdb) UserGetByUUID(uuid string) (*database.User, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("UserGetByUUID: %v", uuid)
+
+	email, err := l.userdb.Get([]byte(UUIDPrefix+strings.ToLower(uuid)),
+		nil)
+	if err == leveldb.ErrNotFound {
+		return nil, database.ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	payload, err := l.userdb.Get(email, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, database.ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return DecodeUser(payload)
+}
+
+// UserGetByOIDCSubject returns the user that linked the provided OpenID
+// Connect account, if found in the database.
+//
+// UserGetByOIDCSubject satisfies the backend interface.
+func (l *localdb) UserGetByOIDCSubject(issuer, subject string) (*database.User, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("UserGetByOIDCSubject: %v %v", issuer, subject)
+
+	email, err := l.userdb.Get(oidcSubjectKey(issuer, subject), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, database.ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	payload, err := l.userdb.Get(email, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, database.ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	u, err := DecodeUser(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// The record remains when the account is unlinked.
+	for _, v := range u.OIDCIdentities {
+		if v.Issuer == issuer && v.Subject == subject {
+			return u, nil
+		}
+	}
+	return nil, database.ErrUserNotFound
 }
 
 // Update existing user.
@@ -166,8 +440,8 @@ func (l *localdb) AllUsers(callbackFn func(u *database.User)) error {
 		key := iter.Key()
 		value := iter.Value()
 
-		// Ignore the userversion and lastuserid records.
-		if string(key) == UserVersionKey || string(key) == LastUserIdKey {
+		// Ignore the records that aren't users.
+		if !isUserRecord(key) {
 			continue
 		}
 
@@ -183,29 +457,1091 @@ func (l *localdb) AllUsers(callbackFn func(u *database.User)) error {
 	return iter.Error()
 }
 
-// Close shuts down the database.  All interface functions MUST return with
-// errShutdown if the backend is shutting down.
+// UsersPage returns the page of users that is selected by the provided query.
 //
-// Close satisfies the backend interface.
-func (l *localdb) Close() error {
+// UsersPage satisfies the backend interface.
+func (l *localdb) UsersPage(q database.UsersQuery) ([]database.User, error) {
 	l.Lock()
 	defer l.Unlock()
 
-	l.shutdown = true
-	return l.userdb.Close()
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("UsersPage: %v", q)
+
+	switch q.Sort {
+	case database.UserSortID, database.UserSortEmail:
+	default:
+		return nil, fmt.Errorf("invalid sort order: %v", q.Sort)
+	}
+	if q.Limit <= 0 {
+		return []database.User{}, nil
+	}
+
+	// Users are keyed by email so the records are already sorted by email
+	// and the page can be read starting at the provided email.  Sorting by
+	// id requires reading all users.
+	var rng *util.Range
+	if q.Sort == database.UserSortEmail {
+		rng = &util.Range{
+			Start: []byte(strings.ToLower(q.FromEmail)),
+		}
+	}
+
+	users := make([]database.User, 0, q.Limit)
+	iter := l.userdb.NewIterator(rng, nil)
+	for iter.Next() {
+		key := iter.Key()
+		value := iter.Value()
+
+		// Ignore the records that aren't users.
+		if !isUserRecord(key) {
+			continue
+		}
+
+		u, err := DecodeUser(value)
+		if err != nil {
+			iter.Release()
+			return nil, err
+		}
+
+		if q.Sort == database.UserSortID {
+			if u.ID >= q.FromID {
+				users = append(users, *u)
+			}
+			continue
+		}
+		users = append(users, *u)
+		if len(users) == q.Limit {
+			iter.Release()
+			return users, nil
+		}
+	}
+	iter.Release()
+
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+
+	if q.Sort == database.UserSortID {
+		sort.Slice(users, func(i, j int) bool {
+			return users[i].ID < users[j].ID
+		})
+		if len(users) > q.Limit {
+			users = users[:q.Limit]
+		}
+	}
+
+	return users, nil
 }
 
-// New creates a new localdb instance.
-func New(root string) (*localdb, error) {
-	log.Tracef("localdb New: %v", root)
+// isUserRecord returns true if the record of the provided key is a user.
+func isUserRecord(key []byte) bool {
+	switch string(key) {
+	case UserVersionKey, LastUserIdKey, IPBansKey, AdminActionsKey,
+		PendingActionsKey, HeldProposalsKey, ScheduledPublicationsKey,
+		ProposalViewsKey:
+		return false
+	}
+	return !strings.HasPrefix(string(key), StatusChangesPrefix) &&
+		!strings.HasPrefix(string(key), SecurityEventsPrefix) &&
+		!strings.HasPrefix(string(key), DevicesPrefix) &&
+		!strings.HasPrefix(string(key), DraftsPrefix) &&
+		!strings.HasPrefix(string(key), PublicKeyPrefix) &&
+		!strings.HasPrefix(string(key), OIDCSubjectPrefix) &&
+		!strings.HasPrefix(string(key), UUIDPrefix) &&
+		!strings.HasPrefix(string(key), IDPrefix) &&
+		!strings.HasPrefix(string(key), SessionPrefix) &&
+		!strings.HasPrefix(string(key), VoteNotificationsPrefix)
+}
 
-	l := &localdb{
-		root: root,
+// putPublicKeys adds the records that map the public keys of all identities
+// of the provided user to its email to the provided batch.
+func putPublicKeys(batch *leveldb.Batch, u database.User) {
+	for _, v := range u.Identities {
+		batch.Put([]byte(PublicKeyPrefix+hex.EncodeToString(v.Key[:])),
+			[]byte(u.Email))
 	}
-	err := l.openUserDB(filepath.Join(l.root, UserdbPath))
+}
+
+// oidcSubjectKey returns the key of the record that maps the provided
+// OpenID Connect account to the email of its user.
+func oidcSubjectKey(issuer, subject string) []byte {
+	return []byte(OIDCSubjectPrefix + issuer + " " + subject)
+}
+
+// putOIDCSubjects adds the records that map the linked OpenID Connect
+// accounts of the provided user to its email to the provided batch.
+func putOIDCSubjects(batch *leveldb.Batch, u database.User) {
+	for _, v := range u.OIDCIdentities {
+		batch.Put(oidcSubjectKey(v.Issuer, v.Subject), []byte(u.Email))
+	}
+}
+
+// putUUID adds the record that maps the uuid of the provided user to its
+// email to the provided batch.
+func putUUID(batch *leveldb.Batch, u database.User) {
+	if u.UUID != "" {
+		batch.Put([]byte(UUIDPrefix+u.UUID), []byte(u.Email))
+	}
+}
+
+// idKey returns the key of the record that maps the provided user id to the
+// email of its user.
+func idKey(id uint64) []byte {
+	return []byte(IDPrefix + strconv.FormatUint(id, 10))
+}
+
+// putID adds the record that maps the id of the provided user to its email
+// to the provided batch.
+func putID(batch *leveldb.Batch, u database.User) {
+	batch.Put(idKey(u.ID), []byte(u.Email))
+}
+
+// newUUID returns a random version 4 uuid as specified by RFC 4122.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
 	if err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10],
+		b[10:]), nil
+}
+
+// ipBans returns all IP bans.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) ipBans() ([]database.IPBan, error) {
+	payload, err := l.userdb.Get([]byte(IPBansKey), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
 		return nil, err
 	}
 
-	return l, nil
+	return DecodeIPBans(payload)
+}
+
+// putIPBans stores the provided IP bans.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) putIPBans(bans []database.IPBan) error {
+	payload, err := EncodeIPBans(bans)
+	if err != nil {
+		return err
+	}
+
+	return l.userdb.Put([]byte(IPBansKey), payload, nil)
+}
+
+// Store new IP ban.  An existing ban of the same network is replaced.
+//
+// IPBanNew satisfies the backend interface.
+func (l *localdb) IPBanNew(ban database.IPBan) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("IPBanNew: %v", ban)
+
+	bans, err := l.ipBans()
+	if err != nil {
+		return err
+	}
+	for k, v := range bans {
+		if v.Network == ban.Network {
+			bans = append(bans[:k], bans[k+1:]...)
+			break
+		}
+	}
+
+	return l.putIPBans(append(bans, ban))
+}
+
+// Remove existing IP ban.
+//
+// IPBanDelete satisfies the backend interface.
+func (l *localdb) IPBanDelete(network string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("IPBanDelete: %v", network)
+
+	bans, err := l.ipBans()
+	if err != nil {
+		return err
+	}
+	for k, v := range bans {
+		if v.Network == network {
+			return l.putIPBans(append(bans[:k], bans[k+1:]...))
+		}
+	}
+
+	return database.ErrIPBanNotFound
+}
+
+// AllIPBans returns all IP bans, including the expired ones.
+//
+// AllIPBans satisfies the backend interface.
+func (l *localdb) AllIPBans() ([]database.IPBan, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("AllIPBans")
+
+	return l.ipBans()
+}
+
+// statusChanges returns the status changes of the provided proposal.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) statusChanges(token string) ([]database.StatusChange, error) {
+	payload, err := l.userdb.Get([]byte(StatusChangesPrefix+token), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return DecodeStatusChanges(payload)
+}
+
+// Append status change to the history of its proposal.
+//
+// StatusChangeNew satisfies the backend interface.
+func (l *localdb) StatusChangeNew(sc database.StatusChange) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("StatusChangeNew: %v", sc)
+
+	changes, err := l.statusChanges(sc.Token)
+	if err != nil {
+		return err
+	}
+
+	payload, err := EncodeStatusChanges(append(changes, sc))
+	if err != nil {
+		return err
+	}
+
+	return l.userdb.Put([]byte(StatusChangesPrefix+sc.Token), payload, nil)
+}
+
+// StatusChanges returns the status changes of a proposal, oldest first.
+//
+// StatusChanges satisfies the backend interface.
+func (l *localdb) StatusChanges(token string) ([]database.StatusChange, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("StatusChanges: %v", token)
+
+	return l.statusChanges(token)
+}
+
+// securityEventsKey returns the key of the record that holds the security
+// events of the provided user.
+func securityEventsKey(userID uint64) []byte {
+	return []byte(SecurityEventsPrefix + strconv.FormatUint(userID, 10))
+}
+
+// securityEvents returns the security events of the provided user.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) securityEvents(userID uint64) ([]database.SecurityEvent, error) {
+	payload, err := l.userdb.Get(securityEventsKey(userID), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return DecodeSecurityEvents(payload)
+}
+
+// Append security event to the log of its user.  Only the last
+// MaxSecurityEvents events of a user are kept.
+//
+// SecurityEventNew satisfies the backend interface.
+func (l *localdb) SecurityEventNew(se database.SecurityEvent) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("SecurityEventNew: %v", se)
+
+	events, err := l.securityEvents(se.UserID)
+	if err != nil {
+		return err
+	}
+
+	events = append(events, se)
+	if len(events) > MaxSecurityEvents {
+		events = events[len(events)-MaxSecurityEvents:]
+	}
+	payload, err := EncodeSecurityEvents(events)
+	if err != nil {
+		return err
+	}
+
+	return l.userdb.Put(securityEventsKey(se.UserID), payload, nil)
+}
+
+// SecurityEvents returns the security events of a user, oldest first.
+//
+// SecurityEvents satisfies the backend interface.
+func (l *localdb) SecurityEvents(userID uint64) ([]database.SecurityEvent, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("SecurityEvents: %v", userID)
+
+	return l.securityEvents(userID)
+}
+
+// deviceKey returns the key of the record of the provided device of the
+// provided user.
+func deviceKey(userID uint64, device string) []byte {
+	return []byte(DevicesPrefix + strconv.FormatUint(userID, 10) + ":" +
+		device)
+}
+
+// Add device to the devices of its user.
+//
+// DeviceNew satisfies the backend interface.
+func (l *localdb) DeviceNew(userID uint64, device string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("DeviceNew: %v %v", userID, device)
+
+	return l.userdb.Put(deviceKey(userID, device), []byte{}, nil)
+}
+
+// DeviceExists returns true if the provided device of the provided user is
+// known.
+//
+// DeviceExists satisfies the backend interface.
+func (l *localdb) DeviceExists(userID uint64, device string) (bool, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return false, database.ErrShutdown
+	}
+
+	log.Debugf("DeviceExists: %v %v", userID, device)
+
+	return l.userdb.Has(deviceKey(userID, device), nil)
+}
+
+// adminActions returns the admin audit log.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) adminActions() ([]database.AdminAction, error) {
+	payload, err := l.userdb.Get([]byte(AdminActionsKey), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return DecodeAdminActions(payload)
+}
+
+// Append admin action to the audit log.
+//
+// AdminActionNew satisfies the backend interface.
+func (l *localdb) AdminActionNew(aa database.AdminAction) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("AdminActionNew: %v", aa)
+
+	actions, err := l.adminActions()
+	if err != nil {
+		return err
+	}
+
+	payload, err := EncodeAdminActions(append(actions, aa))
+	if err != nil {
+		return err
+	}
+
+	return l.userdb.Put([]byte(AdminActionsKey), payload, nil)
+}
+
+// AllAdminActions returns the admin audit log, oldest first.
+//
+// AllAdminActions satisfies the backend interface.
+func (l *localdb) AllAdminActions() ([]database.AdminAction, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("AllAdminActions")
+
+	return l.adminActions()
+}
+
+// pendingActions returns the admin actions that await approval.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) pendingActions() ([]database.PendingAction, error) {
+	payload, err := l.userdb.Get([]byte(PendingActionsKey), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return DecodePendingActions(payload)
+}
+
+// putPendingActions stores the provided pending admin actions.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) putPendingActions(actions []database.PendingAction) error {
+	payload, err := EncodePendingActions(actions)
+	if err != nil {
+		return err
+	}
+
+	return l.userdb.Put([]byte(PendingActionsKey), payload, nil)
+}
+
+// Store new pending admin action.
+//
+// PendingActionNew satisfies the backend interface.
+func (l *localdb) PendingActionNew(pa database.PendingAction) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("PendingActionNew: %v %v", pa.ID, pa.Action)
+
+	actions, err := l.pendingActions()
+	if err != nil {
+		return err
+	}
+
+	return l.putPendingActions(append(actions, pa))
+}
+
+// Remove existing pending admin action.  Only one caller can remove an action
+// so removing it claims it.
+//
+// PendingActionDelete satisfies the backend interface.
+func (l *localdb) PendingActionDelete(id string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("PendingActionDelete: %v", id)
+
+	actions, err := l.pendingActions()
+	if err != nil {
+		return err
+	}
+	for k, v := range actions {
+		if v.ID == id {
+			return l.putPendingActions(append(actions[:k],
+				actions[k+1:]...))
+		}
+	}
+
+	return database.ErrPendingActionNotFound
+}
+
+// AllPendingActions returns the admin actions that await approval, oldest
+// first, including the expired ones.
+//
+// AllPendingActions satisfies the backend interface.
+func (l *localdb) AllPendingActions() ([]database.PendingAction, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("AllPendingActions")
+
+	return l.pendingActions()
+}
+
+// draftsKey returns the key of the record that holds the proposal drafts of
+// the provided user.
+func draftsKey(userID uint64) []byte {
+	return []byte(DraftsPrefix + strconv.FormatUint(userID, 10))
+}
+
+// drafts returns the proposal drafts of the provided user.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) drafts(userID uint64) ([]database.Draft, error) {
+	payload, err := l.userdb.Get(draftsKey(userID), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return DecodeDrafts(payload)
+}
+
+// putDrafts stores the provided proposal drafts of the provided user.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) putDrafts(userID uint64, drafts []database.Draft) error {
+	if len(drafts) == 0 {
+		return l.userdb.Delete(draftsKey(userID), nil)
+	}
+
+	payload, err := EncodeDrafts(drafts)
+	if err != nil {
+		return err
+	}
+
+	return l.userdb.Put(draftsKey(userID), payload, nil)
+}
+
+// Store a proposal draft, replacing the draft of the same user with the same
+// id.
+//
+// DraftSet satisfies the backend interface.
+func (l *localdb) DraftSet(draft database.Draft) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("DraftSet: %v %v", draft.UserID, draft.ID)
+
+	drafts, err := l.drafts(draft.UserID)
+	if err != nil {
+		return err
+	}
+	for k, v := range drafts {
+		if v.ID == draft.ID {
+			drafts[k] = draft
+			return l.putDrafts(draft.UserID, drafts)
+		}
+	}
+
+	return l.putDrafts(draft.UserID, append(drafts, draft))
+}
+
+// Remove existing proposal draft.
+//
+// DraftDelete satisfies the backend interface.
+func (l *localdb) DraftDelete(userID uint64, id string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("DraftDelete: %v %v", userID, id)
+
+	drafts, err := l.drafts(userID)
+	if err != nil {
+		return err
+	}
+	for k, v := range drafts {
+		if v.ID == id {
+			return l.putDrafts(userID, append(drafts[:k],
+				drafts[k+1:]...))
+		}
+	}
+
+	return database.ErrDraftNotFound
+}
+
+// Drafts returns the proposal drafts of a user in the order they were created.
+//
+// Drafts satisfies the backend interface.
+func (l *localdb) Drafts(userID uint64) ([]database.Draft, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("Drafts: %v", userID)
+
+	return l.drafts(userID)
+}
+
+// heldProposals returns the proposals that await admin review.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) heldProposals() ([]database.HeldProposal, error) {
+	payload, err := l.userdb.Get([]byte(HeldProposalsKey), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return DecodeHeldProposals(payload)
+}
+
+// putHeldProposals stores the provided held proposals.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) putHeldProposals(proposals []database.HeldProposal) error {
+	if len(proposals) == 0 {
+		return l.userdb.Delete([]byte(HeldProposalsKey), nil)
+	}
+
+	payload, err := EncodeHeldProposals(proposals)
+	if err != nil {
+		return err
+	}
+
+	return l.userdb.Put([]byte(HeldProposalsKey), payload, nil)
+}
+
+// Store new held proposal.
+//
+// HeldProposalNew satisfies the backend interface.
+func (l *localdb) HeldProposalNew(hp database.HeldProposal) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("HeldProposalNew: %v %v", hp.ID, hp.UserID)
+
+	proposals, err := l.heldProposals()
+	if err != nil {
+		return err
+	}
+
+	return l.putHeldProposals(append(proposals, hp))
+}
+
+// Remove existing held proposal.  Only one caller can remove a proposal so
+// removing it claims it.
+//
+// HeldProposalDelete satisfies the backend interface.
+func (l *localdb) HeldProposalDelete(id string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("HeldProposalDelete: %v", id)
+
+	proposals, err := l.heldProposals()
+	if err != nil {
+		return err
+	}
+	for k, v := range proposals {
+		if v.ID == id {
+			return l.putHeldProposals(append(proposals[:k],
+				proposals[k+1:]...))
+		}
+	}
+
+	return database.ErrHeldProposalNotFound
+}
+
+// AllHeldProposals returns the proposals that await admin review, oldest
+// first.
+//
+// AllHeldProposals satisfies the backend interface.
+func (l *localdb) AllHeldProposals() ([]database.HeldProposal, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("AllHeldProposals")
+
+	return l.heldProposals()
+}
+
+// scheduledPublications returns the proposals that await their publication
+// time.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) scheduledPublications() ([]database.ScheduledPublication, error) {
+	payload, err := l.userdb.Get([]byte(ScheduledPublicationsKey), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return DecodeScheduledPublications(payload)
+}
+
+// putScheduledPublications stores the provided scheduled publications.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) putScheduledPublications(publications []database.ScheduledPublication) error {
+	if len(publications) == 0 {
+		return l.userdb.Delete([]byte(ScheduledPublicationsKey), nil)
+	}
+
+	payload, err := EncodeScheduledPublications(publications)
+	if err != nil {
+		return err
+	}
+
+	return l.userdb.Put([]byte(ScheduledPublicationsKey), payload, nil)
+}
+
+// Store scheduled publication, replacing the one of the same proposal.
+//
+// ScheduledPublicationSet satisfies the backend interface.
+func (l *localdb) ScheduledPublicationSet(sp database.ScheduledPublication) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("ScheduledPublicationSet: %v %v", sp.Token, sp.PublishAt)
+
+	publications, err := l.scheduledPublications()
+	if err != nil {
+		return err
+	}
+	for k, v := range publications {
+		if v.Token == sp.Token {
+			publications[k] = sp
+			return l.putScheduledPublications(publications)
+		}
+	}
+
+	return l.putScheduledPublications(append(publications, sp))
+}
+
+// Remove existing scheduled publication.  Only one caller can remove a
+// publication so removing it claims it.
+//
+// ScheduledPublicationDelete satisfies the backend interface.
+func (l *localdb) ScheduledPublicationDelete(token string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("ScheduledPublicationDelete: %v", token)
+
+	publications, err := l.scheduledPublications()
+	if err != nil {
+		return err
+	}
+	for k, v := range publications {
+		if v.Token == token {
+			return l.putScheduledPublications(append(publications[:k],
+				publications[k+1:]...))
+		}
+	}
+
+	return database.ErrScheduledPublicationNotFound
+}
+
+// AllScheduledPublications returns the proposals that await their
+// publication time.
+//
+// AllScheduledPublications satisfies the backend interface.
+func (l *localdb) AllScheduledPublications() ([]database.ScheduledPublication, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("AllScheduledPublications")
+
+	return l.scheduledPublications()
+}
+
+// proposalViews returns the view counts of the proposals by token.
+//
+// This function must be called WITH the mutex held.
+func (l *localdb) proposalViews() (map[string]uint64, error) {
+	payload, err := l.userdb.Get([]byte(ProposalViewsKey), nil)
+	if err == leveldb.ErrNotFound {
+		return make(map[string]uint64), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return DecodeProposalViews(payload)
+}
+
+// Add views to the view counts of the proposals.  The views of all the
+// proposals are written at once.
+//
+// ProposalViewsAdd satisfies the backend interface.
+func (l *localdb) ProposalViewsAdd(views map[string]uint64) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("ProposalViewsAdd: %v proposals", len(views))
+
+	counts, err := l.proposalViews()
+	if err != nil {
+		return err
+	}
+	for k, v := range views {
+		counts[k] += v
+	}
+
+	payload, err := EncodeProposalViews(counts)
+	if err != nil {
+		return err
+	}
+
+	return l.userdb.Put([]byte(ProposalViewsKey), payload, nil)
+}
+
+// AllProposalViews returns the view counts of the proposals by token.
+//
+// AllProposalViews satisfies the backend interface.
+func (l *localdb) AllProposalViews() (map[string]uint64, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("AllProposalViews")
+
+	return l.proposalViews()
+}
+
+// SessionGet returns a session if found in the database and not expired.
+//
+// SessionGet satisfies the backend interface.
+func (l *localdb) SessionGet(id string) (*database.Session, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	payload, err := l.userdb.Get([]byte(SessionPrefix+id), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, database.ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	session, err := DecodeSession(payload)
+	if err != nil {
+		return nil, err
+	}
+	if session.Expiry <= time.Now().Unix() {
+		return nil, database.ErrSessionNotFound
+	}
+
+	return session, nil
+}
+
+// SessionSet stores a session, replacing the session with the same id.
+//
+// SessionSet satisfies the backend interface.
+func (l *localdb) SessionSet(session database.Session) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	payload, err := EncodeSession(session)
+	if err != nil {
+		return err
+	}
+
+	return l.userdb.Put([]byte(SessionPrefix+session.ID), payload, nil)
+}
+
+// SessionDelete removes a session.  Removing a session that doesn't exist is
+// not an error.
+//
+// SessionDelete satisfies the backend interface.
+func (l *localdb) SessionDelete(id string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	return l.userdb.Delete([]byte(SessionPrefix+id), nil)
+}
+
+// SessionsPurge removes the sessions that expire before the provided time.
+//
+// SessionsPurge satisfies the backend interface.
+func (l *localdb) SessionsPurge(before int64) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return database.ErrShutdown
+	}
+
+	log.Debugf("SessionsPurge: %v", before)
+
+	batch := new(leveldb.Batch)
+	iter := l.userdb.NewIterator(util.BytesPrefix([]byte(SessionPrefix)),
+		nil)
+	for iter.Next() {
+		session, err := DecodeSession(iter.Value())
+		if err != nil {
+			iter.Release()
+			return err
+		}
+		if session.Expiry < before {
+			batch.Delete(append([]byte(nil), iter.Key()...))
+		}
+	}
+	iter.Release()
+
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	return l.userdb.Write(batch, nil)
+}
+
+// Close shuts down the database.  All interface functions MUST return with
+// errShutdown if the backend is shutting down.
+//
+// Close satisfies the backend interface.
+func (l *localdb) Close() error {
+	l.Lock()
+	defer l.Unlock()
+
+	l.shutdown = true
+	return l.userdb.Close()
+}
+
+// New creates a new localdb instance.
+func New(root string) (*localdb, error) {
+	log.Tracef("localdb New: %v", root)
+
+	l := &localdb{
+		root: root,
+	}
+	err := l.openUserDB(filepath.Join(l.root, UserdbPath))
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Record that the provided vote notification was emailed for the provided
+// proposal.  It returns false if the notification was already recorded, in
+// which case it must not be emailed again.
+//
+// VoteNotificationNew satisfies the backend interface.
+func (l *localdb) VoteNotificationNew(token string, n uint64) (bool, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return false, database.ErrShutdown
+	}
+
+	log.Debugf("VoteNotificationNew: %v %v", token, n)
+
+	key := []byte(VoteNotificationsPrefix + token)
+	var sent uint64
+	payload, err := l.userdb.Get(key, nil)
+	if err == nil {
+		sent, err = strconv.ParseUint(string(payload), 10, 64)
+		if err != nil {
+			return false, err
+		}
+	} else if err != leveldb.ErrNotFound {
+		return false, err
+	}
+	if sent&n == n {
+		return false, nil
+	}
+
+	err = l.userdb.Put(key, []byte(strconv.FormatUint(sent|n, 10)), nil)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
 }
@@ -0,0 +1,221 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"time"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+// digestInterval is the interval at which the digest job looks for users
+// that are due a digest email.
+const digestInterval = time.Hour
+
+// digestPeriod returns the amount of time that is covered by a digest of the
+// provided frequency.
+func digestPeriod(d www.EmailDigestT) time.Duration {
+	switch d {
+	case www.EmailDigestDaily:
+		return 24 * time.Hour
+	case www.EmailDigestWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// digestProposal is a proposal that is listed in a digest email.
+type digestProposal struct {
+	Name string
+	Link string
+}
+
+// digestVote is a vote that is ending soon and is listed in a digest email.
+type digestVote struct {
+	Name      string
+	Link      string
+	EndHeight string
+}
+
+// digestActivity lists the new comments on a proposal that the user authored
+// or commented on.
+type digestActivity struct {
+	Name     string
+	Link     string
+	Comments int
+}
+
+// digestWorker periodically emails the users that are due a digest.  It
+// returns once the quit channel is closed.
+func (b *backend) digestWorker() {
+	ticker := time.NewTicker(digestInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.quit:
+			return
+		}
+
+		err := b.sendDigests()
+		if err != nil {
+			log.Errorf("digestWorker: %v", err)
+		}
+	}
+}
+
+// sendDigests emails a digest to all users whose digest period has elapsed.
+func (b *backend) sendDigests() error {
+	now := time.Now()
+
+	// Find the users that are due a digest.  The users can't be updated
+	// from within the callback.
+	var users []database.User
+	err := b.db.AllUsers(func(u *database.User) {
+		period := digestPeriod(www.EmailDigestT(u.EmailDigest))
+		if period == 0 {
+			return
+		}
+		if now.Sub(time.Unix(u.LastEmailDigest, 0)) < period {
+			return
+		}
+		users = append(users, *u)
+	})
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	bestBlock, err := b.getBestBlock(context.Background())
+	if err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		since := u.LastEmailDigest
+		if since == 0 {
+			period := digestPeriod(www.EmailDigestT(u.EmailDigest))
+			since = now.Add(-period).Unix()
+		}
+
+		tplData, err := b.digest(&u, since, bestBlock)
+		if err != nil {
+			return err
+		}
+
+		u.LastEmailDigest = now.Unix()
+		err = b.db.UserUpdate(u)
+		if err != nil {
+			return err
+		}
+
+		// Don't bother the user when nothing happened.
+		if len(tplData.Proposals) == 0 && len(tplData.Votes) == 0 &&
+			len(tplData.Activity) == 0 {
+			continue
+		}
+
+		var buf bytes.Buffer
+		err = templateDigest.Execute(&buf, tplData)
+		if err != nil {
+			return err
+		}
+
+		msg, err := b.newNotificationEmail(&u, 0,
+			"Your Politeia Digest", buf.String())
+		if err != nil {
+			return err
+		}
+		b.enqueueEmail(msg)
+	}
+
+	return nil
+}
+
+// digest assembles the digest for the provided user.  It covers the new
+// public proposals and comments since the provided timestamp and the votes
+// that end before the user's next digest.
+//
+// This function must be called WITHOUT the mutex held.
+func (b *backend) digest(user *database.User, since int64, bestBlock uint64) (*digestTemplateData, error) {
+	// Determine the last block that is covered by the next digest.
+	period := digestPeriod(www.EmailDigestT(user.EmailDigest))
+	endBlock := bestBlock +
+		uint64(period/b.params.TargetTimePerBlock)
+	userID := strconv.FormatUint(user.ID, 10)
+
+	b.RLock()
+	defer b.RUnlock()
+
+	var tplData digestTemplateData
+	for token, ir := range b.inventory {
+		p := convertPropFromInventoryRecord(ir, b.userPubkeys)
+		if p.Status != www.PropStatusPublic {
+			continue
+		}
+		link, err := b.proposalLink(token)
+		if err != nil {
+			return nil, err
+		}
+
+		// New public proposals.
+		published := ir.record.Timestamp
+		for _, v := range ir.changes {
+			if v.NewStatus == pd.RecordStatusPublic {
+				published = v.Timestamp
+			}
+		}
+		if published > since {
+			tplData.Proposals = append(tplData.Proposals,
+				digestProposal{
+					Name: p.Name,
+					Link: link,
+				})
+		}
+
+		// Votes that are ending soon.
+		if len(ir.voting.EndHeight) != 0 {
+			end, err := strconv.ParseUint(ir.voting.EndHeight, 10, 64)
+			if err == nil && end >= bestBlock && end <= endBlock {
+				tplData.Votes = append(tplData.Votes, digestVote{
+					Name:      p.Name,
+					Link:      link,
+					EndHeight: ir.voting.EndHeight,
+				})
+			}
+		}
+
+		// New comments on proposals the user is involved in.
+		involved := p.UserId == userID
+		var comments int
+		for _, c := range ir.comments {
+			if c.UserID == userID {
+				involved = true
+				continue
+			}
+			if c.Timestamp > since {
+				comments++
+			}
+		}
+		if involved && comments > 0 {
+			tplData.Activity = append(tplData.Activity,
+				digestActivity{
+					Name:     p.Name,
+					Link:     link,
+					Comments: comments,
+				})
+		}
+	}
+
+	return &tplData, nil
+}
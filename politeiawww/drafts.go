@@ -0,0 +1,224 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+// draftIDSize is the size of the ids of the proposal drafts in bytes.
+const draftIDSize = 16
+
+// validateDraftFiles checks that the files of a proposal draft follow the
+// file policy of proposals.  Unlike proposals, drafts may lack the index file
+// or a valid name.
+func (b *backend) validateDraftFiles(files []www.File) error {
+	var numMDs, numImages int
+	filenames := make(map[string]bool, len(files))
+	for _, v := range files {
+		if filenames[v.Name] {
+			return www.UserError{
+				ErrorCode:    www.ErrorStatusProposalDuplicateFilenames,
+				ErrorContext: []string{v.Name},
+			}
+		}
+		filenames[v.Name] = true
+
+		data, err := base64.StdEncoding.DecodeString(v.Payload)
+		if err != nil {
+			return www.UserError{
+				ErrorCode: www.ErrorStatusInvalidBase64,
+			}
+		}
+		if strings.HasPrefix(v.MIME, "image/") {
+			numImages++
+			if len(data) > b.cfg.MaxProposalImageSize {
+				return www.UserError{
+					ErrorCode: www.ErrorStatusMaxImageSizeExceededPolicy,
+				}
+			}
+		} else {
+			numMDs++
+			if len(data) > b.cfg.MaxProposalMDSize {
+				return www.UserError{
+					ErrorCode: www.ErrorStatusMaxMDSizeExceededPolicy,
+				}
+			}
+		}
+	}
+
+	if numMDs > b.cfg.MaxProposalMDs {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusMaxMDsExceededPolicy,
+		}
+	}
+	if numImages > b.cfg.MaxProposalImages {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusMaxImagesExceededPolicy,
+		}
+	}
+
+	return nil
+}
+
+// convertWWWDraftFromDatabase converts a proposal draft from the database to
+// www.
+func convertWWWDraftFromDatabase(d database.Draft) (*www.Draft, error) {
+	var files []www.File
+	err := json.Unmarshal(d.Files, &files)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drafts don't have to be named yet.
+	name, err := getProposalName(files)
+	if err != nil {
+		name = ""
+	}
+
+	return &www.Draft{
+		ID:        d.ID,
+		Name:      name,
+		Files:     files,
+		Timestamp: d.Timestamp,
+	}, nil
+}
+
+// saveDraft stores the provided files as the proposal draft with the provided
+// id of the provided user.
+func (b *backend) saveDraft(id string, files []www.File, user *database.User) (*www.Draft, error) {
+	if files == nil {
+		files = []www.File{}
+	}
+	payload, err := json.Marshal(files)
+	if err != nil {
+		return nil, err
+	}
+	draft := database.Draft{
+		ID:        id,
+		UserID:    user.ID,
+		Files:     payload,
+		Timestamp: time.Now().Unix(),
+	}
+	err = b.db.DraftSet(draft)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertWWWDraftFromDatabase(draft)
+}
+
+// ProcessDrafts returns the proposal drafts of the provided user.
+func (b *backend) ProcessDrafts(user *database.User) (*www.DraftsReply, error) {
+	drafts, err := b.db.Drafts(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := www.DraftsReply{
+		Drafts: make([]www.Draft, 0, len(drafts)),
+	}
+	for _, v := range drafts {
+		d, err := convertWWWDraftFromDatabase(v)
+		if err != nil {
+			return nil, err
+		}
+		reply.Drafts = append(reply.Drafts, *d)
+	}
+	sort.SliceStable(reply.Drafts, func(i, j int) bool {
+		return reply.Drafts[i].Timestamp > reply.Drafts[j].Timestamp
+	})
+
+	return &reply, nil
+}
+
+// ProcessNewDraft saves a new proposal draft of the provided user.
+func (b *backend) ProcessNewDraft(nd www.NewDraft, user *database.User) (*www.NewDraftReply, error) {
+	err := b.validateDraftFiles(nd.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	drafts, err := b.db.Drafts(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(drafts) >= www.PolicyMaxDrafts {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusMaxDraftsExceededPolicy,
+		}
+	}
+
+	id, err := util.Random(draftIDSize)
+	if err != nil {
+		return nil, err
+	}
+	draft, err := b.saveDraft(hex.EncodeToString(id), nd.Files, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.NewDraftReply{
+		Draft: *draft,
+	}, nil
+}
+
+// ProcessEditDraft replaces the files of a proposal draft of the provided
+// user.
+func (b *backend) ProcessEditDraft(ed www.EditDraft, user *database.User) (*www.EditDraftReply, error) {
+	err := b.validateDraftFiles(ed.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	drafts, err := b.db.Drafts(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	var found bool
+	for _, v := range drafts {
+		if v.ID == ed.ID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusDraftNotFound,
+		}
+	}
+
+	draft, err := b.saveDraft(ed.ID, ed.Files, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.EditDraftReply{
+		Draft: *draft,
+	}, nil
+}
+
+// ProcessDeleteDraft deletes a proposal draft of the provided user.
+func (b *backend) ProcessDeleteDraft(dd www.DeleteDraft, user *database.User) (*www.DeleteDraftReply, error) {
+	err := b.db.DraftDelete(user.ID, dd.ID)
+	if err == database.ErrDraftNotFound {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusDraftNotFound,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &www.DeleteDraftReply{}, nil
+}
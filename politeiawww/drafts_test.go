@@ -0,0 +1,151 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/gorilla/sessions"
+)
+
+func TestDrafts(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, _ := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	nu2, _ := createAndVerifyUser(t, b)
+	user2, err := b.db.UserGet(nu2.Email)
+	assertSuccess(t, err)
+
+	files := func(payload string) []www.File {
+		return []www.File{{
+			Name:    indexFile,
+			MIME:    "text/plain; charset=utf-8",
+			Payload: base64.StdEncoding.EncodeToString([]byte(payload)),
+		}}
+	}
+
+	// Drafts follow the file policy of proposals.
+	_, err = b.ProcessNewDraft(www.NewDraft{
+		Files: append(files("A"), files("B")...),
+	}, user)
+	assertError(t, err, www.ErrorStatusProposalDuplicateFilenames)
+	_, err = b.ProcessNewDraft(www.NewDraft{
+		Files: []www.File{{Name: indexFile, Payload: "!"}},
+	}, user)
+	assertError(t, err, www.ErrorStatusInvalidBase64)
+
+	ndr, err := b.ProcessNewDraft(www.NewDraft{
+		Files: files("Work in progress\nTODO"),
+	}, user)
+	assertSuccess(t, err)
+	if ndr.Draft.Name != "Work in progress" {
+		t.Fatalf("unexpected draft name %v", ndr.Draft.Name)
+	}
+	id := ndr.Draft.ID
+
+	// Drafts are only visible to their user.
+	dr, err := b.ProcessDrafts(user2)
+	assertSuccess(t, err)
+	if len(dr.Drafts) != 0 {
+		t.Fatalf("unexpected drafts %v", dr.Drafts)
+	}
+	_, err = b.ProcessEditDraft(www.EditDraft{
+		ID:    id,
+		Files: files("Stolen"),
+	}, user2)
+	assertError(t, err, www.ErrorStatusDraftNotFound)
+	_, err = b.ProcessDeleteDraft(www.DeleteDraft{ID: id}, user2)
+	assertError(t, err, www.ErrorStatusDraftNotFound)
+
+	_, err = b.ProcessEditDraft(www.EditDraft{
+		ID:    id,
+		Files: files("Finished proposal\nDone"),
+	}, user)
+	assertSuccess(t, err)
+	dr, err = b.ProcessDrafts(user)
+	assertSuccess(t, err)
+	if len(dr.Drafts) != 1 || dr.Drafts[0].Name != "Finished proposal" {
+		t.Fatalf("unexpected drafts %v", dr.Drafts)
+	}
+
+	// The number of drafts is limited.
+	for i := 1; i < www.PolicyMaxDrafts; i++ {
+		_, err = b.ProcessNewDraft(www.NewDraft{}, user)
+		assertSuccess(t, err)
+	}
+	_, err = b.ProcessNewDraft(www.NewDraft{}, user)
+	assertError(t, err, www.ErrorStatusMaxDraftsExceededPolicy)
+
+	_, err = b.ProcessDeleteDraft(www.DeleteDraft{ID: id}, user)
+	assertSuccess(t, err)
+	dr, err = b.ProcessDrafts(user)
+	assertSuccess(t, err)
+	if len(dr.Drafts) != www.PolicyMaxDrafts-1 {
+		t.Fatalf("got %v drafts, want %v", len(dr.Drafts),
+			www.PolicyMaxDrafts-1)
+	}
+}
+
+// Tests that drafts which carry images fit within the request body limit of
+// the draft routes.
+func TestDraftWithImage(t *testing.T) {
+	p := &politeiawww{
+		backend: createBackend(t),
+		store:   sessions.NewCookieStore([]byte("draft test key")),
+	}
+	p.cfg = p.backend.cfg
+	b := p.backend
+	defer b.db.Close()
+
+	nu, _ := createAndVerifyUser(t, b)
+	cookies := loginCookies(t, p, nu.Email)
+
+	img := generatePNG(t, b.cfg.MaxProposalImageSize)
+	files := []www.File{{
+		Name:    indexFile,
+		MIME:    "text/plain; charset=utf-8",
+		Payload: base64.StdEncoding.EncodeToString([]byte("Draft\nText")),
+	}, {
+		Name:    "image.png",
+		MIME:    "image/png",
+		Payload: base64.StdEncoding.EncodeToString(img),
+	}}
+	do := func(route string, body interface{}, f http.HandlerFunc) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodPost,
+			www.PoliteiaWWWAPIRoute+route, bytes.NewReader(payload))
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		limitBody(maxRequestBodySize(p.cfg, route), p.isLoggedIn(f))(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%v: unexpected status %v: %v", route, w.Code,
+				w.Body.String())
+		}
+		return w
+	}
+
+	w := do(www.RouteNewDraft, www.NewDraft{Files: files}, p.handleNewDraft)
+	var ndr www.NewDraftReply
+	err := json.Unmarshal(w.Body.Bytes(), &ndr)
+	assertSuccess(t, err)
+	do(www.RouteEditDraft, www.EditDraft{
+		ID:    ndr.Draft.ID,
+		Files: files,
+	}, p.handleEditDraft)
+}
@@ -0,0 +1,541 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+const (
+	// emailQueueSize is the number of notification emails that can be
+	// waiting to be sent.  Emails that don't fit are dropped.
+	emailQueueSize = 256
+
+	// maxDeferredEmails is the number of emails that can be waiting for
+	// the rate limit of their recipients.  Emails that don't fit are
+	// dropped.
+	maxDeferredEmails = 256
+
+	// emailFrom is the sender address of all emails.
+	emailFrom = "noreply@decred.org"
+
+	// unsubscribeKeyFilename is the name of the file in the data directory
+	// that contains the key used to sign unsubscribe links.
+	unsubscribeKeyFilename = "unsubscribe.key"
+)
+
+// emailRateLimiter caps the number of emails that are sent to a single
+// recipient per hour and per day.  A cap of 0 disables the corresponding
+// limit.  The emails that were sent are recorded in the state store.
+type emailRateLimiter struct {
+	sync.Mutex
+
+	perHour int
+	perDay  int
+	store   stateStore
+}
+
+// newEmailRateLimiter returns an email rate limiter with the provided caps
+// that records the emails in the provided state store.
+func newEmailRateLimiter(perHour, perDay int, store stateStore) *emailRateLimiter {
+	return &emailRateLimiter{
+		perHour: perHour,
+		perDay:  perDay,
+		store:   store,
+	}
+}
+
+// emailRateKey returns the state store key of the emails that were sent to
+// the provided recipient.
+func emailRateKey(recipient string) string {
+	return "email:" + recipient
+}
+
+// allow returns true if an email may be sent to all of the provided
+// recipients at the provided time, in which case the email is accounted for.
+// Otherwise it returns the time to wait until the email may be sent, and
+// nothing is accounted for.  Emails are allowed when the state store fails.
+func (l *emailRateLimiter) allow(recipients []string, now time.Time) (time.Duration, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	hourAgo := now.Add(-time.Hour)
+	dayAgo := now.Add(-24 * time.Hour)
+	var wait time.Duration
+	for _, r := range recipients {
+		sent, err := l.store.Hits(emailRateKey(r), dayAgo)
+		if err != nil {
+			log.Errorf("emailRateLimiter: %v", err)
+			return 0, true
+		}
+		sort.Slice(sent, func(i, j int) bool {
+			return sent[i].Before(sent[j])
+		})
+
+		// The email may be sent once the oldest email that counts
+		// towards a reached cap expires.
+		if l.perDay > 0 && len(sent) >= l.perDay {
+			w := sent[len(sent)-l.perDay].Add(24 * time.Hour).Sub(now)
+			if w > wait {
+				wait = w
+			}
+		}
+		var lastHour []time.Time
+		for _, t := range sent {
+			if t.After(hourAgo) {
+				lastHour = append(lastHour, t)
+			}
+		}
+		if l.perHour > 0 && len(lastHour) >= l.perHour {
+			w := lastHour[len(lastHour)-l.perHour].Add(time.Hour).Sub(now)
+			if w > wait {
+				wait = w
+			}
+		}
+	}
+	if wait > 0 {
+		return wait, false
+	}
+
+	for _, r := range recipients {
+		err := l.store.Hit(emailRateKey(r), now, 24*time.Hour)
+		if err != nil {
+			log.Errorf("emailRateLimiter: %v", err)
+		}
+	}
+	return 0, true
+}
+
+// sendEmail sends the provided email unless one of its recipients has reached
+// the email rate limit, in which case the email is deferred until the limit
+// allows it.  All emails must be sent through this function.
+func (b *backend) sendEmail(msg *emailMessage) error {
+	if b.emailLimiter != nil {
+		wait, ok := b.emailLimiter.allow(msg.To, time.Now())
+		if !ok {
+			b.deferEmail(msg, wait)
+			return nil
+		}
+	}
+	if err := injectFault(faultMailer); err != nil {
+		return err
+	}
+	return b.cfg.Mailer.Send(msg)
+}
+
+// deferEmail places the provided email on the email queue once the provided
+// duration has elapsed.  The email is dropped if too many emails are deferred
+// already.  Deferred emails are lost on shutdown.
+func (b *backend) deferEmail(msg *emailMessage, wait time.Duration) {
+	b.emailQueueMtx.Lock()
+	if b.emailDeferred >= maxDeferredEmails {
+		b.emailQueueMtx.Unlock()
+		log.Errorf("Email rate limit reached and too many deferred "+
+			"emails, dropping email to %v: %v",
+			strings.Join(msg.To, ", "), msg.Subject)
+		return
+	}
+	b.emailDeferred++
+	b.emailQueueMtx.Unlock()
+
+	log.Warnf("Email rate limit reached, deferring email to %v for %v: %v",
+		strings.Join(msg.To, ", "), wait, msg.Subject)
+
+	time.AfterFunc(wait, func() {
+		b.emailQueueMtx.Lock()
+		b.emailDeferred--
+		b.emailQueueMtx.Unlock()
+
+		b.enqueueEmail(msg)
+	})
+}
+
+// emailWorker sends the emails that are placed on the email queue.  It exits
+// once the queue is closed and drained.
+func (b *backend) emailWorker() {
+	defer close(b.emailWorkerDone)
+
+	for msg := range b.emailQueue {
+		err := b.sendEmail(msg)
+		if err != nil {
+			log.Errorf("emailWorker: %v", err)
+		}
+	}
+}
+
+// enqueueEmail places an email on the email queue so that it is sent
+// asynchronously.  It is a no-op if the email server is not set up, if the
+// email address of a recipient is known to be undeliverable or if a recipient
+// is deactivated.  It never blocks since it is called with the backend mutex
+// held: the email is dropped if the queue is full.
+func (b *backend) enqueueEmail(msg *emailMessage) {
+	if b.emailQueue == nil {
+		return
+	}
+	for _, v := range msg.To {
+		user, err := b.db.UserGet(v)
+		if err == nil && user.EmailUndeliverable {
+			log.Debugf("Not emailing undeliverable address %v", v)
+			return
+		}
+		if err == nil && user.Deactivated != 0 {
+			log.Debugf("Not emailing deactivated user %v", v)
+			return
+		}
+	}
+
+	b.emailQueueMtx.RLock()
+	defer b.emailQueueMtx.RUnlock()
+	if b.emailQueueClosed {
+		log.Warnf("Not emailing %v during shutdown: %v",
+			strings.Join(msg.To, ", "), msg.Subject)
+		return
+	}
+	select {
+	case b.emailQueue <- msg:
+	default:
+		log.Errorf("Email queue full, dropping email to %v: %v",
+			strings.Join(msg.To, ", "), msg.Subject)
+	}
+}
+
+// proposalLink returns the link to the proposal details page on the web
+// server.
+func (b *backend) proposalLink(token string) (string, error) {
+	l, err := url.Parse(b.cfg.WebServerAddress + "/proposals/" + token)
+	if err != nil {
+		return "", err
+	}
+	return l.String(), nil
+}
+
+// wantsEmailNotification returns true if the user has opted into the provided
+// email notification.
+func wantsEmailNotification(user *database.User, n www.EmailNotificationT) bool {
+	return user.EmailNotifications&uint64(n) != 0
+}
+
+// unsubscribeSignature returns the signature of the unsubscribe link for the
+// provided user and notification.
+func (b *backend) unsubscribeSignature(userID string, n uint64) string {
+	h := hmac.New(sha256.New, b.unsubscribeKey)
+	h.Write([]byte(userID + ":" + strconv.FormatUint(n, 10)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// unsubscribeLink returns the signed link below the provided address that
+// unsubscribes the provided user from the provided notification.  A
+// notification of 0 refers to the email digest.
+func (b *backend) unsubscribeLink(address string, user *database.User, n www.EmailNotificationT) (string, error) {
+	l, err := url.Parse(address + www.RouteUnsubscribe)
+	if err != nil {
+		return "", err
+	}
+	userID := strconv.FormatUint(user.ID, 10)
+	q := l.Query()
+	q.Set("userid", userID)
+	q.Set("notification", strconv.FormatUint(uint64(n), 10))
+	q.Set("signature", b.unsubscribeSignature(userID, uint64(n)))
+	l.RawQuery = q.Encode()
+	return l.String(), nil
+}
+
+// newNotificationEmail returns a non-transactional email to the provided
+// user.  The email includes a link and a List-Unsubscribe header that
+// unsubscribe the user from the provided notification.  The header points
+// directly at the API when its address is configured so that mail clients
+// can unsubscribe with one click as specified by RFC 8058.
+func (b *backend) newNotificationEmail(user *database.User, n www.EmailNotificationT, subject, body string) (*emailMessage, error) {
+	link, err := b.unsubscribeLink(b.cfg.WebServerAddress, user, n)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(body)
+	tplData := unsubscribeTemplateData{
+		Link: link,
+	}
+	err = templateUnsubscribe.Execute(&buf, &tplData)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := newEmailMessage(user.Email, subject, buf.String())
+	msg.Headers = map[string]string{
+		"List-Unsubscribe": "<" + link + ">",
+	}
+	if b.cfg.APIAddress != "" {
+		link, err = b.unsubscribeLink(b.cfg.APIAddress+
+			www.PoliteiaWWWAPIRoute, user, n)
+		if err != nil {
+			return nil, err
+		}
+		msg.Headers["List-Unsubscribe"] = "<" + link + ">"
+		msg.Headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+	}
+	return msg, nil
+}
+
+// emailProposalStatusChange notifies the author of a proposal that an admin
+// has changed the status of the proposal.  The email is only sent if the
+// author has opted into these notifications.
+func (b *backend) emailProposalStatusChange(proposal www.ProposalRecord, reason string) error {
+	if b.emailQueue == nil {
+		return nil
+	}
+
+	var (
+		tpl     = templateProposalVetted
+		subject = "Your Proposal Has Been Published"
+	)
+	switch proposal.Status {
+	case www.PropStatusPublic:
+	case www.PropStatusCensored:
+		tpl = templateProposalCensored
+		subject = "Your Proposal Has Been Censored"
+	default:
+		// No notification for this status.
+		return nil
+	}
+
+	userID, err := strconv.ParseUint(proposal.UserId, 10, 64)
+	if err != nil {
+		return err
+	}
+	author, err := b.db.UserGetById(userID)
+	if err != nil {
+		return err
+	}
+	if !wantsEmailNotification(author,
+		www.NotificationEmailMyProposalStatusChange) {
+		return nil
+	}
+
+	link, err := b.proposalLink(proposal.CensorshipRecord.Token)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	tplData := proposalStatusChangeTemplateData{
+		Link:   link,
+		Name:   proposal.Name,
+		Reason: reason,
+		Email:  author.Email,
+	}
+	err = tpl.Execute(&buf, &tplData)
+	if err != nil {
+		return err
+	}
+
+	msg, err := b.newNotificationEmail(author,
+		www.NotificationEmailMyProposalStatusChange, subject, buf.String())
+	if err != nil {
+		return err
+	}
+	b.enqueueEmail(msg)
+	return nil
+}
+
+// newProposalNotification describes a newly submitted proposal that is
+// awaiting review, along with the submission history of its author.
+type newProposalNotification struct {
+	Name     string // Proposal name
+	Link     string // Link to the proposal
+	Author   string // Email address of the author
+	Previous int    // Number of proposals previously submitted by the author
+	Public   int    // Number of the author's proposals that were published
+	Censored int    // Number of the author's proposals that were censored
+}
+
+// authorHistory returns the number of proposals that the provided user has
+// submitted, excluding the proposal with the provided token, along with how
+// many of them were published or censored.
+//
+// This function must be called WITHOUT the mutex held.
+func (b *backend) authorHistory(userID, token string) (int, int, int) {
+	b.RLock()
+	defer b.RUnlock()
+
+	var previous, public, censored int
+	for t, v := range b.inventory {
+		if t == token {
+			continue
+		}
+		p := convertPropFromInventoryRecord(v, b.userPubkeys)
+		if p.UserId != userID {
+			continue
+		}
+		previous++
+		switch p.Status {
+		case www.PropStatusPublic:
+			public++
+		case www.PropStatusCensored:
+			censored++
+		}
+	}
+	return previous, public, censored
+}
+
+// notifyAdminsNewProposal notifies the admins that a new proposal is awaiting
+// review.  Depending on the configuration the notification is either emailed
+// immediately or added to the next batch.
+func (b *backend) notifyAdminsNewProposal(user *database.User, name, token string) error {
+	if b.emailQueue == nil ||
+		b.cfg.AdminNotifications == adminNotificationsOff {
+		return nil
+	}
+
+	link, err := b.proposalLink(token)
+	if err != nil {
+		return err
+	}
+
+	n := newProposalNotification{
+		Name:   name,
+		Link:   link,
+		Author: user.Email,
+	}
+	n.Previous, n.Public, n.Censored = b.authorHistory(
+		strconv.FormatUint(user.ID, 10), token)
+
+	if b.cfg.AdminNotifications == adminNotificationsImmediate {
+		return b.emailAdminsNewProposals([]newProposalNotification{n})
+	}
+
+	b.adminNotificationsMtx.Lock()
+	b.adminNotifications = append(b.adminNotifications, n)
+	b.adminNotificationsMtx.Unlock()
+
+	return nil
+}
+
+// adminNotificationWorker periodically emails the admins the batch of new
+// proposals that were submitted since the last run.
+func (b *backend) adminNotificationWorker() {
+	ticker := time.NewTicker(b.cfg.AdminNotificationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.quit:
+			return
+		}
+
+		b.adminNotificationsMtx.Lock()
+		batch := b.adminNotifications
+		b.adminNotifications = nil
+		b.adminNotificationsMtx.Unlock()
+
+		if len(batch) == 0 {
+			continue
+		}
+		err := b.emailAdminsNewProposals(batch)
+		if err != nil {
+			log.Errorf("adminNotificationWorker: %v", err)
+		}
+	}
+}
+
+// emailAdminsNewProposals emails all admins that opted into new proposal
+// notifications the provided list of proposals awaiting review.
+func (b *backend) emailAdminsNewProposals(proposals []newProposalNotification) error {
+	var buf bytes.Buffer
+	tplData := adminProposalNewTemplateData{
+		Proposals: proposals,
+	}
+	err := templateAdminProposalNew.Execute(&buf, &tplData)
+	if err != nil {
+		return err
+	}
+
+	subject := "New Proposal Awaiting Review"
+	if len(proposals) > 1 {
+		subject = fmt.Sprintf("%v New Proposals Awaiting Review",
+			len(proposals))
+	}
+
+	var admins []database.User
+	err = b.db.AllUsers(func(u *database.User) {
+		if u.Admin && wantsEmailNotification(u,
+			www.NotificationEmailAdminProposalNew) {
+			admins = append(admins, *u)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, u := range admins {
+		msg, err := b.newNotificationEmail(&u,
+			www.NotificationEmailAdminProposalNew, subject, buf.String())
+		if err != nil {
+			return err
+		}
+		b.enqueueEmail(msg)
+	}
+
+	return nil
+}
+
+// emailVoteNotification emails the provided vote notification to all users
+// that opted into it.  Each type of notification is only emailed once per
+// proposal; the notifications that were sent are recorded along with the
+// proposal.
+func (b *backend) emailVoteNotification(token string, n www.EmailNotificationT, tpl *template.Template, subject string, tplData interface{}) error {
+	if b.emailQueue == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	err := tpl.Execute(&buf, tplData)
+	if err != nil {
+		return err
+	}
+
+	ok, err := b.db.VoteNotificationNew(token, uint64(n))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	var users []database.User
+	err = b.db.AllUsers(func(u *database.User) {
+		if wantsEmailNotification(u, n) {
+			users = append(users, *u)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		msg, err := b.newNotificationEmail(&u, n, subject, buf.String())
+		if err != nil {
+			return err
+		}
+		b.enqueueEmail(msg)
+	}
+
+	return nil
+}
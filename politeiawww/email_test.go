@@ -0,0 +1,296 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestEmailRateLimiter(t *testing.T) {
+	l := newEmailRateLimiter(2, 3, newMemoryStore())
+	now := time.Now()
+	to := []string{"a@example.com"}
+	allowed := func(to []string, now time.Time) bool {
+		_, ok := l.allow(to, now)
+		return ok
+	}
+
+	// Hourly cap.
+	for i := 0; i < 2; i++ {
+		if !allowed(to, now) {
+			t.Fatalf("email %v not allowed", i)
+		}
+	}
+	wait, ok := l.allow(to, now.Add(time.Minute))
+	if ok {
+		t.Fatalf("hourly cap not enforced")
+	}
+	if wait != time.Hour-time.Minute {
+		t.Fatalf("got wait %v, want %v", wait, time.Hour-time.Minute)
+	}
+
+	// Other recipients are not affected.
+	if !allowed([]string{"b@example.com"}, now) {
+		t.Fatalf("unrelated recipient not allowed")
+	}
+
+	// Nothing is accounted for when one of the recipients is capped.
+	if allowed([]string{"c@example.com", "a@example.com"}, now) {
+		t.Fatalf("capped recipient allowed")
+	}
+	sent, err := l.store.Hits(emailRateKey("c@example.com"),
+		now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 0 {
+		t.Fatalf("email accounted for uncapped recipient")
+	}
+
+	// Daily cap.
+	now = now.Add(time.Hour)
+	if !allowed(to, now) {
+		t.Fatalf("hourly cap not reset")
+	}
+	wait, ok = l.allow(to, now.Add(time.Hour))
+	if ok {
+		t.Fatalf("daily cap not enforced")
+	}
+	if wait != 22*time.Hour {
+		t.Fatalf("got wait %v, want %v", wait, 22*time.Hour)
+	}
+
+	// Caps are reset after a day.
+	if !allowed(to, now.Add(24*time.Hour)) {
+		t.Fatalf("daily cap not reset")
+	}
+}
+
+func TestSendEmailDeferred(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	// The recipient reached the hourly cap, which expires shortly.
+	b.emailQueue = make(chan *emailMessage, 1)
+	b.emailLimiter = newEmailRateLimiter(1, 0, b.stateStore)
+	err := b.stateStore.Hit(emailRateKey("a@example.com"),
+		time.Now().Add(-time.Hour+100*time.Millisecond), 24*time.Hour)
+	assertSuccess(t, err)
+
+	// The email is queued once the cap expires rather than dropped.
+	msg := newEmailMessage("a@example.com", "subject", "body")
+	err = b.sendEmail(msg)
+	assertSuccess(t, err)
+	b.emailQueueMtx.RLock()
+	deferred := b.emailDeferred
+	b.emailQueueMtx.RUnlock()
+	if deferred != 1 {
+		t.Fatalf("got %v deferred emails, want 1", deferred)
+	}
+	select {
+	case m := <-b.emailQueue:
+		if m != msg {
+			t.Fatalf("unexpected email %v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for the deferred email")
+	}
+	b.emailQueueMtx.RLock()
+	deferred = b.emailDeferred
+	b.emailQueueMtx.RUnlock()
+	if deferred != 0 {
+		t.Fatalf("got %v deferred emails, want 0", deferred)
+	}
+}
+
+func TestEnqueueEmailFull(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	// Nothing drains the queue, so the emails that don't fit are dropped
+	// rather than blocking.
+	b.emailQueue = make(chan *emailMessage, 1)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			b.enqueueEmail(newEmailMessage("a@example.com", "subject",
+				"body"))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("enqueueEmail blocked on a full queue")
+	}
+	if len(b.emailQueue) != 1 {
+		t.Fatalf("got %v queued emails, want 1", len(b.emailQueue))
+	}
+}
+
+func TestEmailProposalStatusChange(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+	b.cfg.WebServerAddress = "https://proposals.example.com"
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	_, published, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	_, censored, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	b.emailQueue = make(chan *emailMessage, 8)
+
+	setNotifications := func(n uint64) {
+		t.Helper()
+		user, err = b.db.UserGet(nu.Email)
+		assertSuccess(t, err)
+		user.EmailNotifications = n
+		assertSuccess(t, b.db.UserUpdate(*user))
+	}
+
+	// Authors that opted in are notified.
+	setNotifications(uint64(www.NotificationEmailMyProposalStatusChange))
+	publishProposal(b, published.CensorshipRecord.Token, t, user, id)
+	if len(b.emailQueue) != 1 {
+		t.Fatalf("got %v queued emails, want 1", len(b.emailQueue))
+	}
+	msg := <-b.emailQueue
+	if len(msg.To) != 1 || msg.To[0] != user.Email ||
+		msg.Subject != "Your Proposal Has Been Published" {
+		t.Fatalf("unexpected email to %v: %v", msg.To, msg.Subject)
+	}
+
+	// Authors that opted out are not.
+	setNotifications(0)
+	censorProposal(b, censored.CensorshipRecord.Token, t, user, id)
+	if len(b.emailQueue) != 0 {
+		t.Fatalf("got %v queued emails, want 0", len(b.emailQueue))
+	}
+}
+
+func TestEmailVoteNotification(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+	b.cfg.WebServerAddress = "https://proposals.example.com"
+	b.emailQueue = make(chan *emailMessage, 8)
+
+	nu, _ := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	user.EmailNotifications = uint64(www.NotificationEmailVoteStarted)
+	assertSuccess(t, b.db.UserUpdate(*user))
+	createAndVerifyUser(t, b)
+
+	// Only the users that opted in are notified, once per proposal.
+	tplData := voteStartedTemplateData{
+		Link: b.cfg.WebServerAddress + "/proposals/token",
+		Name: "A proposal",
+	}
+	for i := 0; i < 2; i++ {
+		err = b.emailVoteNotification("token",
+			www.NotificationEmailVoteStarted, templateVoteStarted,
+			"Voting Started On A Proposal", &tplData)
+		assertSuccess(t, err)
+	}
+	if len(b.emailQueue) != 1 {
+		t.Fatalf("got %v queued emails, want 1", len(b.emailQueue))
+	}
+	msg := <-b.emailQueue
+	if len(msg.To) != 1 || msg.To[0] != user.Email {
+		t.Fatalf("unexpected email to %v", msg.To)
+	}
+
+	// Other proposals are notified separately.
+	err = b.emailVoteNotification("other",
+		www.NotificationEmailVoteStarted, templateVoteStarted,
+		"Voting Started On A Proposal", &tplData)
+	assertSuccess(t, err)
+	if len(b.emailQueue) != 1 {
+		t.Fatalf("got %v queued emails, want 1", len(b.emailQueue))
+	}
+}
+
+func TestNotificationEmailUnsubscribe(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+	b.cfg.WebServerAddress = "https://proposals.example.com"
+
+	nu, _ := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	user.EmailNotifications = uint64(www.NotificationEmailVoteStarted |
+		www.NotificationEmailVoteEnded)
+	err = b.db.UserUpdate(*user)
+	assertSuccess(t, err)
+
+	n := www.NotificationEmailVoteStarted
+	msg, err := b.newNotificationEmail(user, n, "subject", "body")
+	assertSuccess(t, err)
+	header := msg.Headers["List-Unsubscribe"]
+	if !strings.HasPrefix(header, "<"+b.cfg.WebServerAddress+
+		www.RouteUnsubscribe+"?") {
+		t.Fatalf("unexpected List-Unsubscribe header %v", header)
+	}
+	if _, ok := msg.Headers["List-Unsubscribe-Post"]; ok {
+		t.Fatalf("one-click unsubscribe without an API address")
+	}
+
+	// The one-click link points at the API.
+	b.cfg.APIAddress = "https://proposals.example.com/api"
+	msg, err = b.newNotificationEmail(user, n, "subject", "body")
+	assertSuccess(t, err)
+	header = msg.Headers["List-Unsubscribe"]
+	prefix := "<" + b.cfg.APIAddress + www.PoliteiaWWWAPIRoute +
+		www.RouteUnsubscribe + "?"
+	if !strings.HasPrefix(header, prefix) {
+		t.Fatalf("unexpected List-Unsubscribe header %v", header)
+	}
+	if msg.Headers["List-Unsubscribe-Post"] != "List-Unsubscribe=One-Click" {
+		t.Fatalf("missing List-Unsubscribe-Post header")
+	}
+
+	// Following the link only asks for a confirmation.
+	p := &politeiawww{
+		backend: b,
+	}
+	link := www.RouteUnsubscribe + "?" +
+		strings.TrimSuffix(header[len(prefix):], ">")
+	w := httptest.NewRecorder()
+	p.handleUnsubscribe(w, httptest.NewRequest(http.MethodGet, link, nil))
+	if w.Code != http.StatusOK ||
+		!strings.Contains(w.Body.String(), `method="post"`) {
+		t.Fatalf("unexpected confirmation page %v %v", w.Code, w.Body)
+	}
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	if user.EmailNotifications != uint64(www.NotificationEmailVoteStarted|
+		www.NotificationEmailVoteEnded) {
+		t.Fatalf("unsubscribed without a confirmation")
+	}
+
+	// Posting to the link unsubscribes the user from the notification
+	// only.
+	r := httptest.NewRequest(http.MethodPost, link,
+		strings.NewReader("List-Unsubscribe=One-Click"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	p.handleUnsubscribe(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %v", w.Code)
+	}
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	if user.EmailNotifications != uint64(www.NotificationEmailVoteEnded) {
+		t.Fatalf("unexpected notifications %v", user.EmailNotifications)
+	}
+}
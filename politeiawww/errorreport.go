@@ -0,0 +1,137 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/decred/politeia/util"
+)
+
+// errorReportTimeout is the timeout of the requests to the error reporting
+// service.
+const errorReportTimeout = 10 * time.Second
+
+// errorReport describes an unexpected error that occurred while handling a
+// request.
+type errorReport struct {
+	RequestID string    // Request ID
+	ErrorCode int64     // Error code returned to the client
+	Method    string    // Request method
+	URL       string    // Request URL
+	Remote    string    // Remote address of the client
+	Message   string    // Error message
+	Stack     []byte    // Stack trace
+	Timestamp time.Time // Time of the error
+}
+
+// errorReporter forwards unexpected errors to an error tracking service.
+// Report must not block the caller.
+type errorReporter interface {
+	Report(report errorReport)
+}
+
+// sentryReporter is an errorReporter that sends the errors to Sentry.
+type sentryReporter struct {
+	client   *http.Client
+	storeURL string
+	auth     string
+}
+
+// newSentryReporter returns a reporter that sends the errors to the project
+// of the provided Sentry DSN, e.g. https://<key>@sentry.io/<project>.
+func newSentryReporter(dsn string) (*sentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid error report DSN: %v", err)
+	}
+	project := strings.Trim(u.Path, "/")
+	if u.User == nil || u.User.Username() == "" || project == "" {
+		return nil, fmt.Errorf("invalid error report DSN: %v", u.Host)
+	}
+
+	auth := "Sentry sentry_version=7, sentry_client=politeiawww, " +
+		"sentry_key=" + u.User.Username()
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+
+	return &sentryReporter{
+		client:   &http.Client{Timeout: errorReportTimeout},
+		storeURL: u.Scheme + "://" + u.Host + "/api/" + project + "/store/",
+		auth:     auth,
+	}, nil
+}
+
+// Report satisfies the errorReporter interface.  The error is sent in the
+// background.
+func (s *sentryReporter) Report(report errorReport) {
+	go func() {
+		err := s.send(report)
+		if err != nil {
+			log.Errorf("sentryReporter: reqid=%v %v",
+				report.RequestID, err)
+		}
+	}()
+}
+
+// send sends the provided report to Sentry.
+func (s *sentryReporter) send(report errorReport) error {
+	id, err := util.Random(16)
+	if err != nil {
+		return err
+	}
+
+	event := map[string]interface{}{
+		"event_id":  hex.EncodeToString(id),
+		"timestamp": report.Timestamp.UTC().Format("2006-01-02T15:04:05"),
+		"level":     "fatal",
+		"logger":    "politeiawww",
+		"platform":  "go",
+		"message":   report.Message,
+		"request": map[string]interface{}{
+			"method": report.Method,
+			"url":    report.URL,
+		},
+		"tags": map[string]string{
+			"reqid": report.RequestID,
+		},
+		"extra": map[string]interface{}{
+			"errorcode":  report.ErrorCode,
+			"remote":     report.Remote,
+			"stacktrace": string(report.Stack),
+		},
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.storeURL,
+		bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	r, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v", r.Status)
+	}
+	return nil
+}
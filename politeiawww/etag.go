@@ -0,0 +1,87 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/util"
+)
+
+// writeProposalVersion writes the state of the provided proposal that its
+// replies depend on to the provided writer.  The files only change along with
+// the version, so they are left out.  The name and the number of files are
+// part of the state since they are only returned to some users, as are the
+// scheduled publication time and the spam score, which change without the
+// version.
+func writeProposalVersion(w io.Writer, p www.ProposalRecord) {
+	fmt.Fprintf(w, "%v\x00%v\x00%v\x00%v\x00%v\x00%v\x00%v\x00%q\x00%q\x00",
+		p.CensorshipRecord.Token, p.Version, p.Status, p.Timestamp,
+		p.NumComments, len(p.Files), p.UserId, p.Name,
+		p.StatusChangeMessage)
+	for _, v := range p.Tags {
+		fmt.Fprintf(w, "%q\x00", v)
+	}
+	fmt.Fprintf(w, "%v\x00%v\x00%v\x00%v\x00%v\x00", len(p.Pages), p.Views,
+		p.PublishAt, len(p.CoAuthors), p.SpamScore)
+	for _, v := range p.SpamReasons {
+		fmt.Fprintf(w, "%q\x00", v)
+	}
+	if p.Timeline != nil {
+		fmt.Fprintf(w, "%v\x00%v\x00%v\x00", p.Timeline.Amount,
+			p.Timeline.StartDate, p.Timeline.EndDate)
+	}
+}
+
+// proposalsETag returns the entity tag of a reply that holds the provided
+// proposals and total.
+func proposalsETag(proposals []www.ProposalRecord, total uint) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%v\x00", total)
+	for _, v := range proposals {
+		writeProposalVersion(h, v)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header of the provided request
+// matches the provided entity tag.
+func etagMatches(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// respondWithETag replies with the provided reply and its entity tag, or
+// with 304 Not Modified if the client already has the reply.  Caches have to
+// revalidate the reply before using it.  Replies that depend on the session
+// are private so that shared caches don't serve them to other users.
+func respondWithETag(w http.ResponseWriter, r *http.Request, etag string, private bool, reply interface{}) {
+	w.Header().Set("ETag", etag)
+	if private {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	if etagMatches(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestProposalsETag(t *testing.T) {
+	p := www.ProposalRecord{
+		Name:    "A proposal",
+		Status:  www.PropStatusPublic,
+		Version: 1,
+		CensorshipRecord: www.CensorshipRecord{
+			Token: "token",
+		},
+	}
+	etag := proposalsETag([]www.ProposalRecord{p}, 1)
+
+	for _, v := range []func(p *www.ProposalRecord){
+		func(p *www.ProposalRecord) { p.Version++ },
+		func(p *www.ProposalRecord) { p.Status = www.PropStatusLocked },
+		func(p *www.ProposalRecord) { p.NumComments++ },
+		func(p *www.ProposalRecord) { p.Name = "" },
+		func(p *www.ProposalRecord) { p.Tags = []string{"tag"} },
+		func(p *www.ProposalRecord) { p.PublishAt = 1 },
+		func(p *www.ProposalRecord) { p.SpamScore = 1 },
+		func(p *www.ProposalRecord) {
+			p.CoAuthors = []www.CoAuthor{{}}
+		},
+		func(p *www.ProposalRecord) {
+			p.Timeline = &www.ProposalTimeline{Amount: 1}
+		},
+	} {
+		changed := p
+		v(&changed)
+		if proposalsETag([]www.ProposalRecord{changed}, 1) == etag {
+			t.Fatalf("entity tag didn't change with %v", changed)
+		}
+	}
+	if proposalsETag([]www.ProposalRecord{p}, 2) == etag {
+		t.Fatalf("entity tag didn't change with the total")
+	}
+	if proposalsETag([]www.ProposalRecord{p}, 1) != etag {
+		t.Fatalf("entity tag is not stable")
+	}
+}
+
+func TestRespondWithETag(t *testing.T) {
+	for _, v := range []struct {
+		ifNoneMatch string
+		code        int
+	}{
+		{"", http.StatusOK},
+		{`"other"`, http.StatusOK},
+		{`"etag"`, http.StatusNotModified},
+		{`"other", W/"etag"`, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if v.ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", v.ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		respondWithETag(w, r, `"etag"`, false, www.GetAllVettedReply{})
+		if w.Code != v.code {
+			t.Fatalf("expected %v for %q, got %v", v.code,
+				v.ifNoneMatch, w.Code)
+		}
+		if w.Header().Get("ETag") != `"etag"` {
+			t.Fatalf("unexpected entity tag %v", w.Header().Get("ETag"))
+		}
+		if w.Header().Get("Cache-Control") != "no-cache" {
+			t.Fatalf("unexpected cache control %v",
+				w.Header().Get("Cache-Control"))
+		}
+	}
+
+	// Replies that depend on the session are kept out of shared caches.
+	w := httptest.NewRecorder()
+	respondWithETag(w, httptest.NewRequest(http.MethodGet, "/", nil),
+		`"etag"`, true, www.GetAllUnvettedReply{})
+	if w.Header().Get("Cache-Control") != "private, no-cache" {
+		t.Fatalf("unexpected cache control %v",
+			w.Header().Get("Cache-Control"))
+	}
+}
@@ -0,0 +1,16 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+// faultPoint identifies a place where a failure can be injected when
+// politeiawww is built with the faultinject build tag.  Integration tests use
+// injected failures to verify that politeiawww degrades gracefully.
+type faultPoint string
+
+const (
+	faultPoliteiad faultPoint = "politeiad" // Requests to politeiad
+	faultMailer    faultPoint = "mailer"    // Emails sent to the provider
+	faultDBWrite   faultPoint = "dbwrite"   // Writes to the user database
+)
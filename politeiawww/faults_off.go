@@ -0,0 +1,25 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// +build !faultinject
+
+package main
+
+import (
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+// injectFault never fails since fault injection is not built in.
+func injectFault(point faultPoint) error {
+	return nil
+}
+
+// faultDatabase returns the provided database as is since fault injection is
+// not built in.
+func faultDatabase(db database.Database) database.Database {
+	return db
+}
+
+// addFaultRoutes is a no-op since fault injection is not built in.
+func (p *politeiawww) addFaultRoutes() {}
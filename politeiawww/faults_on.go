@@ -0,0 +1,221 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// +build faultinject
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+// routeFaults is the route that configures the injected faults.  It only
+// exists when politeiawww is built with the faultinject build tag.
+const routeFaults = "/faults"
+
+// fault is a failure that is injected at a fault point.
+type fault struct {
+	Point   faultPoint `json:"point"`   // Where the failure is injected
+	Error   string     `json:"error"`   // Error that is returned
+	DelayMs int64      `json:"delayms"` // Delay before failing, e.g. to simulate timeouts
+	Count   int        `json:"count"`   // Number of failures, 0 to fail until cleared
+}
+
+var (
+	faultsMtx sync.Mutex
+	faults    = make(map[faultPoint]*fault)
+)
+
+// setFault injects the provided fault.  It replaces the fault that is
+// injected at the same point.
+func setFault(f fault) {
+	faultsMtx.Lock()
+	defer faultsMtx.Unlock()
+
+	faults[f.Point] = &f
+}
+
+// clearFaults removes all injected faults.
+func clearFaults() {
+	faultsMtx.Lock()
+	defer faultsMtx.Unlock()
+
+	faults = make(map[faultPoint]*fault)
+}
+
+// injectFault returns the error of the fault that is injected at the provided
+// point, if any.
+func injectFault(point faultPoint) error {
+	faultsMtx.Lock()
+	f, ok := faults[point]
+	if !ok {
+		faultsMtx.Unlock()
+		return nil
+	}
+	if f.Count > 0 {
+		f.Count--
+		if f.Count == 0 {
+			delete(faults, point)
+		}
+	}
+	delay := time.Duration(f.DelayMs) * time.Millisecond
+	msg := f.Error
+	faultsMtx.Unlock()
+
+	if msg == "" {
+		msg = "injected " + string(point) + " fault"
+	}
+	log.Debugf("injectFault: %v: %v", point, msg)
+
+	time.Sleep(delay)
+	return errors.New(msg)
+}
+
+// faultyDatabase fails the writes to the wrapped database when a dbwrite
+// fault is injected.
+type faultyDatabase struct {
+	database.Database
+}
+
+// faultDatabase wraps the provided database so that writes can fail.
+func faultDatabase(db database.Database) database.Database {
+	return &faultyDatabase{db}
+}
+
+func (d *faultyDatabase) UserNew(u database.User) error {
+	if err := injectFault(faultDBWrite); err != nil {
+		return err
+	}
+	return d.Database.UserNew(u)
+}
+
+func (d *faultyDatabase) UserUpdate(u database.User) error {
+	if err := injectFault(faultDBWrite); err != nil {
+		return err
+	}
+	return d.Database.UserUpdate(u)
+}
+
+func (d *faultyDatabase) UserChangeEmail(oldEmail string, u database.User) error {
+	if err := injectFault(faultDBWrite); err != nil {
+		return err
+	}
+	return d.Database.UserChangeEmail(oldEmail, u)
+}
+
+func (d *faultyDatabase) IPBanNew(ban database.IPBan) error {
+	if err := injectFault(faultDBWrite); err != nil {
+		return err
+	}
+	return d.Database.IPBanNew(ban)
+}
+
+func (d *faultyDatabase) IPBanDelete(network string) error {
+	if err := injectFault(faultDBWrite); err != nil {
+		return err
+	}
+	return d.Database.IPBanDelete(network)
+}
+
+func (d *faultyDatabase) SessionSet(session database.Session) error {
+	if err := injectFault(faultDBWrite); err != nil {
+		return err
+	}
+	return d.Database.SessionSet(session)
+}
+
+func (d *faultyDatabase) SessionDelete(id string) error {
+	if err := injectFault(faultDBWrite); err != nil {
+		return err
+	}
+	return d.Database.SessionDelete(id)
+}
+
+func (d *faultyDatabase) SessionsPurge(before int64) error {
+	if err := injectFault(faultDBWrite); err != nil {
+		return err
+	}
+	return d.Database.SessionsPurge(before)
+}
+
+func (d *faultyDatabase) StatusChangeNew(sc database.StatusChange) error {
+	if err := injectFault(faultDBWrite); err != nil {
+		return err
+	}
+	return d.Database.StatusChangeNew(sc)
+}
+
+func (d *faultyDatabase) SecurityEventNew(se database.SecurityEvent) error {
+	if err := injectFault(faultDBWrite); err != nil {
+		return err
+	}
+	return d.Database.SecurityEventNew(se)
+}
+
+func (d *faultyDatabase) DeviceNew(userID uint64, device string) error {
+	if err := injectFault(faultDBWrite); err != nil {
+		return err
+	}
+	return d.Database.DeviceNew(userID, device)
+}
+
+func (d *faultyDatabase) AdminActionNew(aa database.AdminAction) error {
+	if err := injectFault(faultDBWrite); err != nil {
+		return err
+	}
+	return d.Database.AdminActionNew(aa)
+}
+
+// handleSetFault injects the fault that is provided in the request body.
+func (p *politeiawww) handleSetFault(w http.ResponseWriter, r *http.Request) {
+	var f fault
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&f); err != nil {
+		RespondWithError(w, r, 0, "handleSetFault: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+	switch f.Point {
+	case faultPoliteiad, faultMailer, faultDBWrite:
+	default:
+		RespondWithError(w, r, 0, "handleSetFault: invalid point",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	log.Warnf("Injecting %v fault: %v", f.Point, f.Error)
+	setFault(f)
+
+	util.RespondWithJSON(w, http.StatusOK, struct{}{})
+}
+
+// handleClearFaults removes all injected faults.
+func (p *politeiawww) handleClearFaults(w http.ResponseWriter, r *http.Request) {
+	log.Warnf("Clearing injected faults")
+	clearFaults()
+
+	util.RespondWithJSON(w, http.StatusOK, struct{}{})
+}
+
+// addFaultRoutes adds the routes that configure the injected faults.
+func (p *politeiawww) addFaultRoutes() {
+	log.Warnf("FAULT INJECTION IS ENABLED, DO NOT USE IN PRODUCTION")
+
+	p.addRoute(http.MethodPost, routeFaults, p.handleSetFault,
+		permissionPublic, false)
+	p.addRoute(http.MethodDelete, routeFaults, p.handleClearFaults,
+		permissionPublic, false)
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// +build faultinject
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+)
+
+func TestFaultInjection(t *testing.T) {
+	defer clearFaults()
+
+	b := createBackend(t)
+
+	// A failed database write fails the signup without creating the user.
+	setFault(fault{Point: faultDBWrite, Count: 1})
+	nu, _ := createNewUserCommandWithIdentity(t)
+	_, err := b.ProcessNewUser(nu)
+	if err == nil {
+		t.Fatalf("expected injected dbwrite fault")
+	}
+	_, err = b.db.UserGet(nu.Email)
+	if err == nil {
+		t.Fatalf("user was created despite the fault")
+	}
+
+	// The fault is cleared after the configured number of failures.
+	_, err = b.ProcessNewUser(nu)
+	assertSuccess(t, err)
+
+	// Requests to politeiad fail until the fault is cleared.
+	setFault(fault{Point: faultPoliteiad, Error: "timeout"})
+	for i := 0; i < 2; i++ {
+		_, err = b.makeRequest(context.Background(), http.MethodPost,
+			pd.InventoryRoute, pd.Inventory{})
+		if err == nil || err.Error() != "timeout" {
+			t.Fatalf("got error %v, want timeout", err)
+		}
+	}
+	clearFaults()
+
+	// Emails fail while the mailer fault is injected.
+	setFault(fault{Point: faultMailer})
+	err = b.sendEmail(newEmailMessage("user@example.com", "subject",
+		"body"))
+	if err == nil {
+		t.Fatalf("expected injected mailer fault")
+	}
+
+	b.db.Close()
+}
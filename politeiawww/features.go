@@ -0,0 +1,138 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+// featureFlag describes a feature that can be enabled or disabled so that
+// new subsystems can be rolled out gradually.
+type featureFlag struct {
+	enabled    bool // Default state
+	toggleable bool // Set if admins can toggle the feature at runtime
+}
+
+// featureFlags are the known features.  The paywall can't be toggled at
+// runtime since the users that sign up while it is disabled get no paywall
+// address and could never pay once it is enabled again.
+var featureFlags = map[string]featureFlag{
+	www.FeatureComments:   {enabled: true, toggleable: true},
+	www.FeatureCredits:    {enabled: false, toggleable: true},
+	www.FeaturePaywall:    {enabled: true, toggleable: false},
+	www.FeatureSearch:     {enabled: false, toggleable: true},
+	www.FeatureWebSockets: {enabled: false, toggleable: true},
+}
+
+// validFeature returns true if the provided feature is known.
+func validFeature(name string) bool {
+	_, ok := featureFlags[name]
+	return ok
+}
+
+// initFeatures sets the state of the features from their defaults and the
+// configuration.
+func (b *backend) initFeatures() {
+	b.featuresMtx.Lock()
+	defer b.featuresMtx.Unlock()
+
+	b.features = make(map[string]bool, len(featureFlags))
+	for name, f := range featureFlags {
+		b.features[name] = f.enabled
+	}
+	for _, name := range b.cfg.EnableFeatures {
+		b.features[name] = true
+	}
+	for _, name := range b.cfg.DisableFeatures {
+		b.features[name] = false
+	}
+}
+
+// featureEnabled returns true if the provided feature is enabled.
+func (b *backend) featureEnabled(name string) bool {
+	b.featuresMtx.RLock()
+	defer b.featuresMtx.RUnlock()
+
+	return b.features[name]
+}
+
+// checkFeature returns an error if the provided feature is disabled.
+func (b *backend) checkFeature(name string) error {
+	if !b.featureEnabled(name) {
+		return www.UserError{
+			ErrorCode:    www.ErrorStatusFeatureDisabled,
+			ErrorContext: []string{name},
+		}
+	}
+	return nil
+}
+
+// setFeature enables or disables the provided feature.
+func (b *backend) setFeature(name string, enabled bool) {
+	b.featuresMtx.Lock()
+	defer b.featuresMtx.Unlock()
+
+	b.features[name] = enabled
+}
+
+// convertWWWFeature returns the www representation of the provided feature.
+func (b *backend) convertWWWFeature(name string) www.Feature {
+	return www.Feature{
+		Name:       name,
+		Enabled:    b.featureEnabled(name),
+		Toggleable: featureFlags[name].toggleable,
+	}
+}
+
+// ProcessFeatures returns the feature flags sorted by name.
+func (b *backend) ProcessFeatures() *www.FeaturesReply {
+	names := make([]string, 0, len(featureFlags))
+	for name := range featureFlags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	reply := www.FeaturesReply{
+		Features: make([]www.Feature, 0, len(names)),
+	}
+	for _, name := range names {
+		reply.Features = append(reply.Features, b.convertWWWFeature(name))
+	}
+	return &reply
+}
+
+// ProcessSetFeature enables or disables a toggleable feature on behalf of the
+// provided admin.  The change is lost on restart; the configuration must be
+// updated to make it permanent.
+func (b *backend) ProcessSetFeature(sf www.SetFeature, user *database.User) (*www.SetFeatureReply, error) {
+	f, ok := featureFlags[sf.Name]
+	if !ok {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidFeature,
+		}
+	}
+	if !f.toggleable {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusFeatureNotToggleable,
+		}
+	}
+
+	b.setFeature(sf.Name, sf.Enabled)
+	b.publish(clusterEvent{
+		Type:    clusterEventFeature,
+		Feature: sf.Name,
+		Enabled: sf.Enabled,
+	})
+
+	log.Infof("Admin %v set feature %v enabled: %v", user.ID, sf.Name,
+		sf.Enabled)
+
+	return &www.SetFeatureReply{
+		Feature: b.convertWWWFeature(sf.Name),
+	}, nil
+}
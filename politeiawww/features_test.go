@@ -0,0 +1,66 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+func TestFeatures(t *testing.T) {
+	b := createBackend(t)
+	admin := &database.User{ID: 1, Admin: true}
+
+	features := b.ProcessFeatures().Features
+	if len(features) != len(featureFlags) {
+		t.Fatalf("got %v features, want %v", len(features),
+			len(featureFlags))
+	}
+	for _, f := range features {
+		if f.Enabled != featureFlags[f.Name].enabled {
+			t.Fatalf("unexpected default state: %v", f)
+		}
+	}
+
+	// Unknown features and features that can only be set in the
+	// configuration can't be toggled.
+	_, err := b.ProcessSetFeature(www.SetFeature{Name: "invalid"}, admin)
+	assertError(t, err, www.ErrorStatusInvalidFeature)
+	_, err = b.ProcessSetFeature(www.SetFeature{Name: www.FeaturePaywall},
+		admin)
+	assertError(t, err, www.ErrorStatusFeatureNotToggleable)
+
+	// Disabled comments can't be read.
+	reply, err := b.ProcessSetFeature(www.SetFeature{
+		Name: www.FeatureComments,
+	}, admin)
+	assertSuccess(t, err)
+	if reply.Feature.Enabled || !reply.Feature.Toggleable {
+		t.Fatalf("unexpected feature: %v", reply.Feature)
+	}
+	_, err = b.ProcessCommentGet("token")
+	assertErrorWithContext(t, err, www.ErrorStatusFeatureDisabled,
+		[]string{www.FeatureComments})
+
+	_, err = b.ProcessSetFeature(www.SetFeature{
+		Name:    www.FeatureComments,
+		Enabled: true,
+	}, admin)
+	assertSuccess(t, err)
+	if !b.featureEnabled(www.FeatureComments) {
+		t.Fatalf("comments are disabled")
+	}
+
+	// The configuration overrides the defaults.
+	b.cfg.EnableFeatures = []string{www.FeatureSearch}
+	b.cfg.DisableFeatures = []string{www.FeaturePaywall}
+	b.initFeatures()
+	if !b.featureEnabled(www.FeatureSearch) ||
+		b.featureEnabled(www.FeaturePaywall) {
+		t.Fatalf("configuration ignored")
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+const (
+	// feedSize is the number of proposals in the feeds.
+	feedSize = 20
+
+	// feedSummaryLength is the maximum length of the summaries of the
+	// proposals in the feeds, in characters.
+	feedSummaryLength = 300
+
+	// feedTitle is the title of the feeds.
+	feedTitle = "Politeia proposals"
+
+	// feedDescription is the description of the feeds.
+	feedDescription = "Proposals that were published on Politeia"
+
+	atomNamespace = "http://www.w3.org/2005/Atom"
+)
+
+// feedFormat is the format of a proposal feed.
+type feedFormat int
+
+const (
+	feedFormatRSS  feedFormat = iota // RSS 2.0
+	feedFormatAtom                   // Atom 1.0
+)
+
+// feedEntry is a proposal in the feeds.
+type feedEntry struct {
+	token     string
+	title     string
+	author    string // User ID of the author
+	link      string // Link to the proposal on the web server
+	published int64  // Time the proposal was published
+	summary   string // Beginning of the index file
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	Author      string  `xml:"author"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Link      atomLink   `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Author    atomAuthor `xml:"author"`
+	Summary   string     `xml:"summary"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+// summarizeProposal returns the first paragraph of the provided index file
+// after the title on a single line, truncated to feedSummaryLength
+// characters.
+func summarizeProposal(index []byte) string {
+	index = bytes.Replace(index, []byte("\r\n"), []byte("\n"), -1)
+	var paragraph []string
+	for i, line := range strings.Split(string(index), "\n") {
+		line = strings.TrimSpace(line)
+		if i == 0 {
+			// Skip the title.
+			continue
+		}
+		if line == "" {
+			if len(paragraph) != 0 {
+				break
+			}
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+
+	summary := strings.Join(strings.Fields(strings.Join(paragraph, " ")), " ")
+	if utf8.RuneCountInString(summary) > feedSummaryLength {
+		summary = string([]rune(summary)[:feedSummaryLength-1]) + "…"
+	}
+	return summary
+}
+
+// proposalSummary returns the summary of the vetted proposal with the
+// provided token.
+func (b *backend) proposalSummary(ctx context.Context, token string) (string, error) {
+	b.feedSummariesMtx.Lock()
+	summary, ok := b.feedSummaries[token]
+	b.feedSummariesMtx.Unlock()
+	if ok {
+		return summary, nil
+	}
+
+	pdr, err := b.ProcessProposalDetails(ctx, www.ProposalsDetails{
+		Token: token,
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	for _, v := range pdr.Proposal.Files {
+		if v.Name != indexFile {
+			continue
+		}
+		index, err := base64.StdEncoding.DecodeString(v.Payload)
+		if err != nil {
+			return "", err
+		}
+		summary = summarizeProposal(index)
+	}
+
+	b.feedSummariesMtx.Lock()
+	if b.feedSummaries == nil {
+		b.feedSummaries = make(map[string]string)
+	}
+	b.feedSummaries[token] = summary
+	b.feedSummariesMtx.Unlock()
+
+	return summary, nil
+}
+
+// feedEntries returns the feedSize proposals that were published last, in
+// the order they were published, newest first.
+func (b *backend) feedEntries(ctx context.Context) ([]feedEntry, error) {
+	b.RLock()
+	entries := make([]feedEntry, 0, feedSize)
+	for _, v := range b.inventory {
+		p := convertPropFromInventoryRecord(v, b.userPubkeys)
+		if p.Status != www.PropStatusPublic &&
+			p.Status != www.PropStatusLocked {
+			continue
+		}
+
+		// Proposals are published by the last status change that
+		// made them public.
+		published := p.Timestamp
+		for _, c := range v.changes {
+			if convertPropStatusFromPD(c.NewStatus) ==
+				www.PropStatusPublic {
+				published = c.Timestamp
+			}
+		}
+
+		entries = append(entries, feedEntry{
+			token:     p.CensorshipRecord.Token,
+			title:     p.Name,
+			author:    p.UserId,
+			published: published,
+		})
+	}
+	b.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].published != entries[j].published {
+			return entries[i].published > entries[j].published
+		}
+		return entries[i].token > entries[j].token
+	})
+	if len(entries) > feedSize {
+		entries = entries[:feedSize]
+	}
+
+	for i := range entries {
+		var err error
+		entries[i].link, err = b.proposalLink(entries[i].token)
+		if err != nil {
+			return nil, err
+		}
+		entries[i].summary, err = b.proposalSummary(ctx,
+			entries[i].token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// encodeRSS encodes the provided entries into an RSS 2.0 feed.
+func (b *backend) encodeRSS(entries []feedEntry) ([]byte, error) {
+	feed := rss{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         feedTitle,
+			Link:          b.cfg.WebServerAddress,
+			Description:   feedDescription,
+			LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+			Items:         make([]rssItem, 0, len(entries)),
+		},
+	}
+	for _, v := range entries {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       v.title,
+			Link:        v.link,
+			Description: v.summary,
+			Author:      v.author,
+			GUID:        rssGUID{Value: v.token},
+			PubDate: time.Unix(v.published, 0).UTC().
+				Format(time.RFC1123Z),
+		})
+	}
+
+	return xml.MarshalIndent(feed, "", "  ")
+}
+
+// encodeAtom encodes the provided entries into an Atom 1.0 feed.  The feed
+// was last updated when its newest entry was published.
+func (b *backend) encodeAtom(entries []feedEntry) ([]byte, error) {
+	var updated int64
+	if len(entries) != 0 {
+		updated = entries[0].published
+	}
+	feed := atomFeed{
+		XMLNS:   atomNamespace,
+		ID:      b.cfg.WebServerAddress + "/",
+		Title:   feedTitle,
+		Updated: time.Unix(updated, 0).UTC().Format(time.RFC3339),
+		Link:    atomLink{Href: b.cfg.WebServerAddress},
+		Entries: make([]atomEntry, 0, len(entries)),
+	}
+	for _, v := range entries {
+		published := time.Unix(v.published, 0).UTC().Format(time.RFC3339)
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        v.link,
+			Title:     v.title,
+			Link:      atomLink{Href: v.link},
+			Published: published,
+			Updated:   published,
+			Author:    atomAuthor{Name: v.author},
+			Summary:   v.summary,
+		})
+	}
+
+	return xml.MarshalIndent(feed, "", "  ")
+}
+
+// ProcessProposalsFeed returns the feed of the proposals that were published
+// last in the provided format.
+func (b *backend) ProcessProposalsFeed(ctx context.Context, format feedFormat) ([]byte, error) {
+	entries, err := b.feedEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var feed []byte
+	switch format {
+	case feedFormatAtom:
+		feed, err = b.encodeAtom(entries)
+	default:
+		feed, err = b.encodeRSS(entries)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), feed...), nil
+}
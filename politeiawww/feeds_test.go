@@ -0,0 +1,79 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSummarizeProposal(t *testing.T) {
+	index := "Title\n\n  First   paragraph\r\nspans lines.\n\nSecond paragraph."
+	summary := summarizeProposal([]byte(index))
+	if summary != "First paragraph spans lines." {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+
+	summary = summarizeProposal([]byte("Title\n" +
+		strings.Repeat("é", feedSummaryLength+1)))
+	if utf8.RuneCountInString(summary) != feedSummaryLength ||
+		!strings.HasSuffix(summary, "…") {
+		t.Fatalf("summary was not truncated: %q", summary)
+	}
+
+	if summary := summarizeProposal([]byte("Title")); summary != "" {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+}
+
+func TestProposalsFeed(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+	b.cfg.WebServerAddress = "https://proposals.example.com"
+
+	u, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(u.Email)
+	assertSuccess(t, err)
+
+	np, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	_, _, err = createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+	publishProposal(b, token, t, user, id)
+
+	index, err := base64.StdEncoding.DecodeString(np.Files[0].Payload)
+	assertSuccess(t, err)
+	link := b.cfg.WebServerAddress + "/proposals/" + token
+
+	// Only the published proposal is in the feeds.
+	feed, err := b.ProcessProposalsFeed(context.Background(), feedFormatRSS)
+	assertSuccess(t, err)
+	var r rss
+	err = xml.Unmarshal(feed, &r)
+	assertSuccess(t, err)
+	if len(r.Channel.Items) != 1 {
+		t.Fatalf("expected 1 item, got %v", len(r.Channel.Items))
+	}
+	item := r.Channel.Items[0]
+	if item.GUID.Value != token || item.Link != link ||
+		item.Description != summarizeProposal(index) {
+		t.Fatalf("unexpected item %v", item)
+	}
+
+	feed, err = b.ProcessProposalsFeed(context.Background(), feedFormatAtom)
+	assertSuccess(t, err)
+	var a atomFeed
+	err = xml.Unmarshal(feed, &a)
+	assertSuccess(t, err)
+	if len(a.Entries) != 1 || a.Entries[0].ID != link ||
+		a.Entries[0].Summary != summarizeProposal(index) {
+		t.Fatalf("unexpected entries %v", a.Entries)
+	}
+}
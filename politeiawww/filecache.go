@@ -0,0 +1,102 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"container/list"
+	"sync"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+)
+
+// fileCacheEntry holds the files of a proposal in the file cache.
+type fileCacheEntry struct {
+	token  string
+	merkle string    // Merkle root of the files
+	files  []pd.File // Files with their payloads
+	size   int       // Size of the payloads in bytes
+}
+
+// fileCache keeps the files of the proposals that were fetched from politeiad
+// last in memory, up to a total payload size.  The inventory doesn't hold the
+// files of the proposals, so without the cache every proposal details request
+// hits politeiad.  Files are immutable for a given merkle root, so the cached
+// files are only served while the merkle root of the proposal in the inventory
+// matches theirs.
+type fileCache struct {
+	sync.Mutex
+
+	maxSize int                      // Maximum size of the payloads in bytes
+	size    int                      // Current size of the payloads in bytes
+	lru     *list.List               // Entries, most recently used first
+	entries map[string]*list.Element // [token]entry
+}
+
+// newFileCache returns a file cache that holds at most maxSize bytes of
+// payloads.  The cache holds nothing if maxSize is 0.
+func newFileCache(maxSize int) *fileCache {
+	return &fileCache{
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached files of the proposal with the provided token and
+// merkle root.  Files of a previous version of the proposal are dropped.
+func (c *fileCache) get(token, merkle string) ([]pd.File, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.entries[token]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*fileCacheEntry)
+	if entry.merkle != merkle {
+		c.remove(e)
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return entry.files, true
+}
+
+// put caches the files of the proposal with the provided token and merkle
+// root, evicting the least recently used files to make room for them.
+func (c *fileCache) put(token, merkle string, files []pd.File) {
+	size := 0
+	for _, v := range files {
+		size += len(v.Payload)
+	}
+	if c.maxSize == 0 || size > c.maxSize {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if e, ok := c.entries[token]; ok {
+		c.remove(e)
+	}
+	for c.size+size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+	c.entries[token] = c.lru.PushFront(&fileCacheEntry{
+		token:  token,
+		merkle: merkle,
+		files:  files,
+		size:   size,
+	})
+	c.size += size
+}
+
+// remove drops the provided entry from the cache.
+//
+// This function must be called WITH the mutex held.
+func (c *fileCache) remove(e *list.Element) {
+	entry := c.lru.Remove(e).(*fileCacheEntry)
+	delete(c.entries, entry.token)
+	c.size -= entry.size
+}
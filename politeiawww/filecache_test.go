@@ -0,0 +1,50 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+)
+
+func TestFileCache(t *testing.T) {
+	c := newFileCache(10)
+	files := func(payload string) []pd.File {
+		return []pd.File{{Name: indexFile, Payload: payload}}
+	}
+
+	c.put("a", "merkle a", files("aaaa"))
+	c.put("b", "merkle b", files("bbbb"))
+	if _, ok := c.get("a", "merkle a"); !ok {
+		t.Fatalf("files of a are not cached")
+	}
+
+	// b is evicted since a was used last.
+	c.put("c", "merkle c", files("cccc"))
+	if _, ok := c.get("b", "merkle b"); ok {
+		t.Fatalf("files of b were not evicted")
+	}
+	if f, ok := c.get("a", "merkle a"); !ok || f[0].Payload != "aaaa" {
+		t.Fatalf("unexpected files of a: %v", f)
+	}
+
+	// The files of a previous version are dropped.
+	if _, ok := c.get("c", "merkle c2"); ok {
+		t.Fatalf("files of a previous version were served")
+	}
+	if _, ok := c.get("c", "merkle c"); ok {
+		t.Fatalf("files of a previous version were not dropped")
+	}
+
+	// Files that don't fit are not cached.
+	c.put("d", "merkle d", files("ddddddddddd"))
+	if _, ok := c.get("d", "merkle d"); ok {
+		t.Fatalf("files larger than the cache were cached")
+	}
+	if c.size != 4 {
+		t.Fatalf("expected size 4, got %v", c.size)
+	}
+}
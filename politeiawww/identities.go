@@ -0,0 +1,258 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// keyChallengeSize is the size of public key challenges in bytes.
+	keyChallengeSize = 32
+
+	// keyChallengeExpiry is the time users have to sign a public key
+	// challenge and add the key.
+	keyChallengeExpiry = 10 * time.Minute
+)
+
+// keyChallengeKey returns the state store key of the challenge that the
+// provided public key of the provided user must sign.
+func keyChallengeKey(userID uint64, publicKey string) string {
+	return "keychallenge:" + strconv.FormatUint(userID, 10) + ":" +
+		publicKey
+}
+
+// decodePublicKey decodes the provided hex encoded ed25519 public key.
+func decodePublicKey(publicKey string) ([]byte, error) {
+	var emptyPK [identity.PublicKeySize]byte
+	pk, err := hex.DecodeString(publicKey)
+	if err != nil || len(pk) != len(emptyPK) ||
+		bytes.Equal(pk, emptyPK[:]) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidPublicKey,
+		}
+	}
+	return pk, nil
+}
+
+// ProcessUserKeyChallenge issues a single use challenge that the provided
+// public key must sign before the provided user can add it.
+func (b *backend) ProcessUserKeyChallenge(user *database.User, kc www.UserKeyChallenge) (*www.UserKeyChallengeReply, error) {
+	pk, err := decodePublicKey(kc.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := util.Random(keyChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	err = b.stateStore.Set(keyChallengeKey(user.ID, hex.EncodeToString(pk)),
+		challenge, keyChallengeExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.UserKeyChallengeReply{
+		Challenge: hex.EncodeToString(challenge),
+		Expiry:    time.Now().Add(keyChallengeExpiry).Unix(),
+	}, nil
+}
+
+// verifyKeyChallenge verifies that the provided public key signed the
+// outstanding challenge of the provided user.  The challenge is consumed so
+// that it can't be reused.
+func (b *backend) verifyKeyChallenge(user *database.User, pk []byte, challenge, signature string) error {
+	return b.verifyChallenge(keyChallengeKey(user.ID, hex.EncodeToString(pk)),
+		pk, challenge, signature)
+}
+
+// verifyChallenge verifies that the provided public key signed the challenge
+// that is stored under the provided state store key.  The challenge is
+// consumed so that it can't be reused.
+func (b *backend) verifyChallenge(key string, pk []byte, challenge, signature string) error {
+	expected, err := b.stateStore.Get(key)
+	if err == errStateNotFound {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusInvalidKeyChallenge,
+		}
+	} else if err != nil {
+		return err
+	}
+	err = b.stateStore.Delete(key)
+	if err != nil {
+		return err
+	}
+
+	c, err := hex.DecodeString(challenge)
+	if err != nil || subtle.ConstantTimeCompare(c, expected) != 1 {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusInvalidKeyChallenge,
+		}
+	}
+
+	sig, err := util.ConvertSignature(signature)
+	if err != nil {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusInvalidSignature,
+		}
+	}
+	pi, err := identity.PublicIdentityFromBytes(pk)
+	if err != nil {
+		return err
+	}
+	if !pi.VerifyMessage([]byte(challenge), sig) {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusInvalidSignature,
+		}
+	}
+
+	return nil
+}
+
+// ProcessAddUserKey activates an additional public key for the provided
+// user.  The new key must be signed by one of the active keys of the user,
+// which replaces the email verification of ProcessUpdateUserKey, and must
+// sign a challenge to prove that the user controls it.
+func (b *backend) ProcessAddUserKey(user *database.User, ak www.AddUserKey) (*www.AddUserKeyReply, error) {
+	pk, err := decodePublicKey(ak.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	publicKey := hex.EncodeToString(pk)
+
+	ids := database.ActiveIdentities(user.Identities)
+	if len(ids) == 0 {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusNoPublicKey,
+		}
+	}
+	if len(ids) >= www.UserKeyMaxActive {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusTooManyPublicKeys,
+		}
+	}
+
+	// Check that one of the active keys signed the new key.
+	sig, err := util.ConvertSignature(ak.Signature)
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidSignature,
+		}
+	}
+	var signed bool
+	for _, id := range ids {
+		pi, err := identity.PublicIdentityFromBytes(id[:])
+		if err != nil {
+			return nil, err
+		}
+		if pi.VerifyMessage([]byte(ak.PublicKey), sig) {
+			signed = true
+			break
+		}
+	}
+	if !signed {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidSignature,
+		}
+	}
+
+	err = b.verifyKeyChallenge(user, pk, ak.Challenge,
+		ak.ChallengeSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys can't be shared because proposals are attributed to the user
+	// of the key that signed them.
+	b.RLock()
+	_, ok := b.userPubkeys[publicKey]
+	b.RUnlock()
+	if ok {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusDuplicatePublicKey,
+		}
+	}
+
+	id := database.Identity{
+		Activated: time.Now().Unix(),
+	}
+	copy(id.Key[:], pk)
+	user.Identities = append(user.Identities, id)
+
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Associate the user id with the new public key.
+	b.setUserPubkeyAssociaton(user, publicKey)
+
+	return &www.AddUserKeyReply{}, nil
+}
+
+// ProcessDeactivateUserKey deactivates the provided public key of the
+// provided user.  The key is kept in the identity history so that the
+// signatures it made still verify.
+func (b *backend) ProcessDeactivateUserKey(user *database.User, dk www.DeactivateUserKey) (*www.DeactivateUserKeyReply, error) {
+	active := 0
+	index := -1
+	for k, v := range user.Identities {
+		if v.Activated == 0 || v.Deactivated != 0 {
+			continue
+		}
+		active++
+		if hex.EncodeToString(v.Key[:]) == dk.PublicKey {
+			index = k
+		}
+	}
+	if index < 0 {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidPublicKey,
+		}
+	}
+	if active == 1 {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusLastPublicKey,
+		}
+	}
+
+	user.Identities[index].Deactivated = time.Now().Unix()
+	err := b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.DeactivateUserKeyReply{}, nil
+}
+
+// ProcessUserIdentities returns the public keys that the provided user has
+// activated, including the deactivated ones.
+func (b *backend) ProcessUserIdentities(user *database.User) *www.UserIdentitiesReply {
+	reply := www.UserIdentitiesReply{
+		Identities: make([]www.UserIdentity, 0, len(user.Identities)),
+	}
+	for _, v := range user.Identities {
+		// Skip the keys that were never verified.
+		if v.Activated == 0 {
+			continue
+		}
+		reply.Identities = append(reply.Identities, www.UserIdentity{
+			PublicKey:   hex.EncodeToString(v.Key[:]),
+			Activated:   v.Activated,
+			Deactivated: v.Deactivated,
+		})
+	}
+	return &reply
+}
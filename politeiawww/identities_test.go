@@ -0,0 +1,133 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+func TestUserIdentities(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	// Keys are only activated once the new user signs the verification
+	// token.
+	nu, _ := createNewUserCommandWithIdentity(t)
+	_, err := b.ProcessNewUser(nu)
+	assertSuccess(t, err)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	if _, ok := database.ActiveIdentity(user.Identities); ok {
+		t.Fatalf("unverified key is active")
+	}
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+
+	newID, err := generateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := newID.Public.String()
+
+	// The new key must be signed by an active key.
+	sig := newID.SignMessage([]byte(publicKey))
+	_, err = b.ProcessAddUserKey(user, www.AddUserKey{
+		PublicKey: publicKey,
+		Signature: hex.EncodeToString(sig[:]),
+	})
+	assertError(t, err, www.ErrorStatusInvalidSignature)
+
+	sig = id.SignMessage([]byte(publicKey))
+	_, err = b.ProcessAddUserKey(user, www.AddUserKey{
+		PublicKey: id.Public.String(),
+		Signature: hex.EncodeToString(sig[:]),
+	})
+	assertError(t, err, www.ErrorStatusInvalidSignature)
+
+	// The new key must sign a challenge.
+	ak := www.AddUserKey{
+		PublicKey: publicKey,
+		Signature: hex.EncodeToString(sig[:]),
+	}
+	_, err = b.ProcessAddUserKey(user, ak)
+	assertError(t, err, www.ErrorStatusInvalidKeyChallenge)
+
+	kcr, err := b.ProcessUserKeyChallenge(user, www.UserKeyChallenge{
+		PublicKey: publicKey,
+	})
+	assertSuccess(t, err)
+	ak.Challenge = kcr.Challenge
+	csig := id.SignMessage([]byte(kcr.Challenge))
+	ak.ChallengeSignature = hex.EncodeToString(csig[:])
+	_, err = b.ProcessAddUserKey(user, ak)
+	assertError(t, err, www.ErrorStatusInvalidSignature)
+
+	// Challenges can only be used once.
+	csig = newID.SignMessage([]byte(kcr.Challenge))
+	ak.ChallengeSignature = hex.EncodeToString(csig[:])
+	_, err = b.ProcessAddUserKey(user, ak)
+	assertError(t, err, www.ErrorStatusInvalidKeyChallenge)
+
+	kcr, err = b.ProcessUserKeyChallenge(user, www.UserKeyChallenge{
+		PublicKey: publicKey,
+	})
+	assertSuccess(t, err)
+	ak.Challenge = kcr.Challenge
+	csig = newID.SignMessage([]byte(kcr.Challenge))
+	ak.ChallengeSignature = hex.EncodeToString(csig[:])
+	_, err = b.ProcessAddUserKey(user, ak)
+	assertSuccess(t, err)
+
+	kcr, err = b.ProcessUserKeyChallenge(user, www.UserKeyChallenge{
+		PublicKey: publicKey,
+	})
+	assertSuccess(t, err)
+	ak.Challenge = kcr.Challenge
+	csig = newID.SignMessage([]byte(kcr.Challenge))
+	ak.ChallengeSignature = hex.EncodeToString(csig[:])
+	_, err = b.ProcessAddUserKey(user, ak)
+	assertError(t, err, www.ErrorStatusDuplicatePublicKey)
+
+	// Both keys can sign proposals.
+	_, _, err = createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	_, _, err = createNewProposal(b, t, user, newID)
+	assertSuccess(t, err)
+
+	// Deactivated keys can't sign proposals anymore.
+	_, err = b.ProcessDeactivateUserKey(user, www.DeactivateUserKey{
+		PublicKey: id.Public.String(),
+	})
+	assertSuccess(t, err)
+	_, _, err = createNewProposal(b, t, user, id)
+	assertError(t, err, www.ErrorStatusInvalidSigningKey)
+	_, _, err = createNewProposal(b, t, user, newID)
+	assertSuccess(t, err)
+
+	_, err = b.ProcessDeactivateUserKey(user, www.DeactivateUserKey{
+		PublicKey: id.Public.String(),
+	})
+	assertError(t, err, www.ErrorStatusInvalidPublicKey)
+	_, err = b.ProcessDeactivateUserKey(user, www.DeactivateUserKey{
+		PublicKey: publicKey,
+	})
+	assertError(t, err, www.ErrorStatusLastPublicKey)
+
+	// The history keeps the deactivated key.
+	reply := b.ProcessUserIdentities(user)
+	if len(reply.Identities) != 2 ||
+		reply.Identities[0].PublicKey != id.Public.String() ||
+		reply.Identities[0].Deactivated == 0 ||
+		reply.Identities[1].PublicKey != publicKey ||
+		reply.Identities[1].Deactivated != 0 {
+		t.Fatalf("unexpected identities %v", reply.Identities)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+// imageDecoder decodes the images of a MIME type.
+type imageDecoder struct {
+	decodeConfig func(io.Reader) (image.Config, error)
+	decode       func(io.Reader) (image.Image, error)
+}
+
+// imageDecoders are the decoders of the image MIME types that proposals may
+// contain.
+var imageDecoders = map[string]imageDecoder{
+	"image/png":  {png.DecodeConfig, png.Decode},
+	"image/jpeg": {jpeg.DecodeConfig, jpeg.Decode},
+}
+
+// imageError returns the error of an image file that can't be part of a
+// proposal.  The error context holds the file name and the reason.
+func imageError(name, reason string) error {
+	return www.UserError{
+		ErrorCode:    www.ErrorStatusInvalidImage,
+		ErrorContext: []string{name, reason},
+	}
+}
+
+// validateImage decodes the provided image file of a proposal to check that
+// it is an image of its MIME type whose dimensions follow the image policy.
+// The client supplied MIME type is not trusted.
+func validateImage(name, mimeType string, data []byte) error {
+	detected := http.DetectContentType(data)
+	if detected != mimeType {
+		return www.UserError{
+			ErrorCode:    www.ErrorStatusInvalidMIMEType,
+			ErrorContext: []string{name, detected},
+		}
+	}
+	decoder, ok := imageDecoders[mimeType]
+	if !ok {
+		return www.UserError{
+			ErrorCode:    www.ErrorStatusUnsupportedMIMEType,
+			ErrorContext: []string{name, mimeType},
+		}
+	}
+
+	// Check the dimensions in the header before decoding the pixels so
+	// that small files can't claim huge images.
+	cfg, err := decoder.decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return imageError(name, www.ImageReasonCorrupt)
+	}
+	if cfg.Width > www.PolicyMaxImageWidth ||
+		cfg.Height > www.PolicyMaxImageHeight ||
+		cfg.Width*cfg.Height > www.PolicyMaxImagePixels {
+		return imageError(name, www.ImageReasonDimensions)
+	}
+
+	_, err = decoder.decode(bytes.NewReader(data))
+	if err != nil {
+		return imageError(name, www.ImageReasonCorrupt)
+	}
+
+	return nil
+}
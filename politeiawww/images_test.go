@@ -0,0 +1,71 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestValidateImage(t *testing.T) {
+	encode := func(width, height int, jpg bool) []byte {
+		var buf bytes.Buffer
+		img := image.NewGray(image.Rect(0, 0, width, height))
+		var err error
+		if jpg {
+			err = jpeg.Encode(&buf, img, nil)
+		} else {
+			err = png.Encode(&buf, img)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	small := encode(16, 16, false)
+
+	tests := []struct {
+		mime    string
+		data    []byte
+		code    www.ErrorStatusT
+		context []string
+	}{
+		{"image/png", small, 0, nil},
+		{"image/jpeg", encode(16, 16, true), 0, nil},
+		{"image/jpeg", small, www.ErrorStatusInvalidMIMEType,
+			[]string{"a.png", "image/png"}},
+		{"image/png", []byte("not an image"), www.ErrorStatusInvalidMIMEType,
+			[]string{"a.png", "text/plain; charset=utf-8"}},
+		{"image/png", small[:len(small)-20], www.ErrorStatusInvalidImage,
+			[]string{"a.png", www.ImageReasonCorrupt}},
+		{"image/png", encode(www.PolicyMaxImageWidth+1, 1, false),
+			www.ErrorStatusInvalidImage,
+			[]string{"a.png", www.ImageReasonDimensions}},
+		{"image/png", encode(www.PolicyMaxImageWidth,
+			www.PolicyMaxImageHeight, false), www.ErrorStatusInvalidImage,
+			[]string{"a.png", www.ImageReasonDimensions}},
+	}
+	for i, test := range tests {
+		err := validateImage("a.png", test.mime, test.data)
+		if test.code == 0 {
+			if err != nil {
+				t.Errorf("%v: unexpected error %v", i, err)
+			}
+			continue
+		}
+		ue, ok := err.(www.UserError)
+		if !ok || ue.ErrorCode != test.code ||
+			!reflect.DeepEqual(ue.ErrorContext, test.context) {
+			t.Errorf("%v: got %v %v, want %v %v", i, ue.ErrorCode,
+				ue.ErrorContext, test.code, test.context)
+		}
+	}
+}
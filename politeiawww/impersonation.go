@@ -0,0 +1,212 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+	"github.com/gorilla/sessions"
+)
+
+const (
+	// impersonationMaxAge is the duration of an impersonation.
+	impersonationMaxAge = time.Hour
+
+	// Session keys of an impersonation.
+	sessionImpersonate       = "impersonate"
+	sessionImpersonateExpiry = "impersonateexpiry"
+)
+
+// ProcessImpersonate lets the provided admin impersonate a user.  The
+// impersonation is signed by the admin and recorded in the admin audit log.
+// The caller stores the returned user id and expiry in the session of the
+// admin.
+func (b *backend) ProcessImpersonate(imp www.Impersonate, admin *database.User) (*www.ImpersonateReply, error) {
+	err := checkPublicKeyAndSignature(admin, imp.PublicKey, imp.Signature,
+		imp.UserID, www.AdminActionImpersonate)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.ParseUint(imp.UserID, 10, 64)
+	if err != nil || id == admin.ID || strings.TrimSpace(imp.Reason) == "" {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+	user, err := b.db.UserGetById(id)
+	if err != nil {
+		if err == database.ErrUserNotFound {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusUserNotFound,
+			}
+		}
+		return nil, err
+	}
+	if user.Deactivated != 0 {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusUserDeactivated,
+		}
+	}
+
+	now := time.Now()
+	err = b.db.AdminActionNew(database.AdminAction{
+		AdminID:   admin.ID,
+		UserID:    user.ID,
+		Action:    www.AdminActionImpersonate,
+		PublicKey: imp.PublicKey,
+		Signature: imp.Signature,
+		Reason:    imp.Reason,
+		Timestamp: now.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Admin %v impersonates user %v: %v", admin.ID, user.ID,
+		imp.Reason)
+
+	return &www.ImpersonateReply{
+		UserID: imp.UserID,
+		Expiry: now.Add(impersonationMaxAge).Unix(),
+	}, nil
+}
+
+// ProcessStopImpersonation ends the impersonation of the provided user by the
+// provided admin and records it in the admin audit log.
+func (b *backend) ProcessStopImpersonation(admin, user *database.User) (*www.StopImpersonationReply, error) {
+	err := b.db.AdminActionNew(database.AdminAction{
+		AdminID:   admin.ID,
+		UserID:    user.ID,
+		Action:    www.AdminActionStopImpersonation,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Admin %v stopped impersonating user %v", admin.ID, user.ID)
+
+	return &www.StopImpersonationReply{}, nil
+}
+
+// impersonatedUser returns the user that the provided admin impersonates in
+// the provided session.  It returns nil if the session doesn't impersonate a
+// user, if the impersonation expired or if the admin lost its privileges.
+func (p *politeiawww) impersonatedUser(session *sessions.Session, admin *database.User) (*database.User, error) {
+	userID, _ := session.Values[sessionImpersonate].(string)
+	if userID == "" || !admin.Admin {
+		return nil, nil
+	}
+	expiry, _ := session.Values[sessionImpersonateExpiry].(int64)
+	if time.Now().Unix() >= expiry {
+		return nil, nil
+	}
+
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	user, err := p.backend.db.UserGetById(id)
+	if err == database.ErrUserNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if user.Deactivated != 0 {
+		return nil, nil
+	}
+
+	return user, nil
+}
+
+// getImpersonation returns the admin that is logged in by the provided
+// request and the user that it impersonates.  Both are nil if the request
+// doesn't impersonate a user.  Only session cookies can impersonate users.
+func (p *politeiawww) getImpersonation(r *http.Request) (*database.User, *database.User, error) {
+	if _, ok := bearerToken(r); ok {
+		return nil, nil, nil
+	}
+
+	session, err := p.store.Get(r, www.CookieSession)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, ok := session.Values[sessionImpersonate]; !ok {
+		return nil, nil, nil
+	}
+
+	admin, _, err := p.sessionLogin(session)
+	if err != nil || admin == nil {
+		return nil, nil, err
+	}
+	user, err := p.impersonatedUser(session, admin)
+	if err != nil || user == nil {
+		return nil, nil, err
+	}
+
+	return admin, user, nil
+}
+
+// setSessionImpersonation makes the session impersonate the user with the
+// provided id until the provided expiry.  An empty id ends the impersonation.
+func (p *politeiawww) setSessionImpersonation(w http.ResponseWriter, r *http.Request, userID string, expiry int64) error {
+	session, err := p.store.Get(r, www.CookieSession)
+	if err != nil {
+		return err
+	}
+
+	if userID == "" {
+		delete(session.Values, sessionImpersonate)
+		delete(session.Values, sessionImpersonateExpiry)
+	} else {
+		session.Values[sessionImpersonate] = userID
+		session.Values[sessionImpersonateExpiry] = expiry
+	}
+	return session.Save(r, w)
+}
+
+// impersonation flags the responses to the requests that impersonate a user
+// and records these requests in the audit log before calling the next
+// function.  Impersonations are read-only, so requests that could change
+// state are rejected unless they end the impersonation.
+func (p *politeiawww) impersonation(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin, user, err := p.getImpersonation(r)
+		if err != nil {
+			RespondWithError(w, r, 0,
+				"impersonation: getImpersonation %v", err)
+			return
+		}
+		if user == nil {
+			f(w, r)
+			return
+		}
+
+		w.Header().Set(www.Impersonating, strconv.FormatUint(user.ID, 10))
+
+		allowed := r.Method == http.MethodGet ||
+			r.Method == http.MethodHead ||
+			r.URL.Path == www.PoliteiaWWWAPIRoute+www.RouteStopImpersonation ||
+			r.URL.Path == www.PoliteiaWWWAPIRoute+www.RouteLogout
+		auditLog.Infof("reqid=%v admin=%v user=%v remote=%v method=%v "+
+			"url=%v allowed=%v", util.RequestID(r.Context()), admin.ID,
+			user.ID, remoteAddr(r), r.Method, r.URL, allowed)
+		if !allowed {
+			util.RespondWithJSON(w, http.StatusForbidden, www.ErrorReply{
+				ErrorCode: int64(www.ErrorStatusImpersonationReadOnly),
+			})
+			return
+		}
+
+		f(w, r)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/gorilla/sessions"
+)
+
+func TestImpersonation(t *testing.T) {
+	p := &politeiawww{
+		backend: createBackend(t),
+		store:   sessions.NewCookieStore([]byte("impersonation test key")),
+	}
+	p.cfg = p.backend.cfg
+	b := p.backend
+	defer b.db.Close()
+
+	anu, id := createAndVerifyUser(t, b)
+	admin, err := b.db.UserGet(anu.Email)
+	assertSuccess(t, err)
+	admin.Admin = true
+	assertSuccess(t, b.db.UserUpdate(*admin))
+
+	nu, _ := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	userID := strconv.FormatUint(user.ID, 10)
+
+	impersonate := func(userID, reason string) www.Impersonate {
+		sig := id.SignMessage([]byte(userID + www.AdminActionImpersonate))
+		return www.Impersonate{
+			UserID:    userID,
+			Reason:    reason,
+			PublicKey: id.Public.String(),
+			Signature: hex.EncodeToString(sig[:]),
+		}
+	}
+	_, err = b.ProcessImpersonate(impersonate(userID, ""), admin)
+	assertError(t, err, www.ErrorStatusInvalidInput)
+	_, err = b.ProcessImpersonate(impersonate(
+		strconv.FormatUint(admin.ID, 10), "debug"), admin)
+	assertError(t, err, www.ErrorStatusInvalidInput)
+	imp := impersonate(userID, "debug")
+	imp.UserID = "1000"
+	_, err = b.ProcessImpersonate(imp, admin)
+	assertError(t, err, www.ErrorStatusInvalidSignature)
+
+	// Log the admin in.
+	sessionID, err := b.newLoginSession(admin.Email, "10.0.0.1", "agent",
+		sessionMaxAge)
+	assertSuccess(t, err)
+	w := httptest.NewRecorder()
+	err = p.setSessionLogin(w, httptest.NewRequest(http.MethodGet, "/", nil),
+		admin.Email, sessionID)
+	assertSuccess(t, err)
+	cookies := w.Result().Cookies()
+
+	do := func(method, route string, body interface{}, f http.HandlerFunc) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(method, www.PoliteiaWWWAPIRoute+route,
+			bytes.NewReader(payload))
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		p.impersonation(f)(w, r)
+		if c := w.Result().Cookies(); len(c) != 0 {
+			cookies = c
+		}
+		return w
+	}
+	me := func() www.LoginReply {
+		w := do(http.MethodGet, www.RouteUserMe, nil, p.handleMe)
+		var reply www.LoginReply
+		err := json.Unmarshal(w.Body.Bytes(), &reply)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reply
+	}
+
+	w = do(http.MethodPost, www.RouteImpersonate, impersonate(userID, "debug"),
+		p.handleImpersonate)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", w.Code, http.StatusOK)
+	}
+
+	// Impersonated requests are logged in as the user and flagged.
+	w = do(http.MethodGet, www.RouteUserMe, nil, p.handleMe)
+	if w.Header().Get(www.Impersonating) != userID {
+		t.Fatalf("impersonation header not set")
+	}
+	reply := me()
+	if reply.UserID != userID ||
+		reply.ImpersonatedBy != strconv.FormatUint(admin.ID, 10) {
+		t.Fatalf("unexpected me reply %v", reply)
+	}
+
+	// Impersonations are read-only.
+	w = do(http.MethodPost, www.RouteChangePassword, www.ChangePassword{},
+		p.handleChangePassword)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got %v, want %v", w.Code, http.StatusForbidden)
+	}
+
+	w = do(http.MethodPost, www.RouteStopImpersonation, nil,
+		p.handleStopImpersonation)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", w.Code, http.StatusOK)
+	}
+	reply = me()
+	if reply.UserID != strconv.FormatUint(admin.ID, 10) ||
+		reply.ImpersonatedBy != "" {
+		t.Fatalf("unexpected me reply %v", reply)
+	}
+
+	actions, err := b.ProcessAdminActions()
+	assertSuccess(t, err)
+	if len(actions.Actions) != 2 ||
+		actions.Actions[0].Action != www.AdminActionImpersonate ||
+		actions.Actions[1].Action != www.AdminActionStopImpersonation {
+		t.Fatalf("unexpected admin actions %v", actions.Actions)
+	}
+}
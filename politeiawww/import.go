@@ -0,0 +1,507 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrtime/merkle"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	BackendProposalImportVersion = 1
+)
+
+// BackendProposalImport is the metadata stream that records the origin of an
+// imported proposal.  politeiad timestamps the record when it is imported, so
+// the original timestamp is restored from this stream whenever the record is
+// loaded.
+type BackendProposalImport struct {
+	Version       uint64 `json:"version"`       // BackendProposalImport version
+	OriginalToken string `json:"originaltoken"` // Token in the original deployment
+	Timestamp     int64  `json:"timestamp"`     // Original timestamp of the record
+	AdminID       string `json:"adminid"`       // Admin who imported the proposal
+	ImportedAt    int64  `json:"importedat"`    // Time of the import
+}
+
+// encodeBackendProposalImport encodes BackendProposalImport into a JSON byte
+// slice.
+func encodeBackendProposalImport(md BackendProposalImport) ([]byte, error) {
+	b, err := json.Marshal(md)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// decodeBackendProposalImport decodes a JSON byte slice into a
+// BackendProposalImport.
+func decodeBackendProposalImport(payload []byte) (*BackendProposalImport, error) {
+	var md BackendProposalImport
+
+	err := json.Unmarshal(payload, &md)
+	if err != nil {
+		return nil, err
+	}
+
+	return &md, nil
+}
+
+// restoreImportTimestamp sets the timestamp of the provided record to its
+// original one if the record was imported.
+func restoreImportTimestamp(record *pd.Record) {
+	for _, v := range record.Metadata {
+		if v.ID != mdStreamImport {
+			continue
+		}
+		md, err := decodeBackendProposalImport([]byte(v.Payload))
+		if err != nil {
+			log.Errorf("restoreImportTimestamp %v: %v",
+				record.CensorshipRecord.Token, err)
+			return
+		}
+		record.Timestamp = md.Timestamp
+		return
+	}
+}
+
+// bundleError returns the error of a proposal bundle that is rejected for the
+// provided reason.
+func bundleError(reason, detail string) error {
+	return www.UserError{
+		ErrorCode:    www.ErrorStatusInvalidProposalBundle,
+		ErrorContext: []string{reason, detail},
+	}
+}
+
+// importedProposal is a proposal that was read from a bundle.
+type importedProposal struct {
+	proposal www.ProposalRecord  // Proposal of the bundle
+	merkle   string              // Merkle root of the files
+	files    []pd.File           // Files with their payloads
+	metadata []pd.MetadataStream // Streams that are replayed on creation
+	changes  *pd.MetadataStream  // Status changes, if any
+	status   pd.RecordStatusT    // Status that the proposal is imported in
+	origin   BackendProposalImport
+}
+
+// parseProposalBundle reads the proposal of the provided bundle and verifies
+// its files, its author signature and, if the bundle carries the key of
+// politeiad, its censorship record.
+func (b *backend) parseProposalBundle(ip www.ImportProposal) (*importedProposal, error) {
+	bundle, err := base64.StdEncoding.DecodeString(ip.Bundle)
+	if err != nil {
+		return nil, bundleError(www.BundleReasonMalformed, "base64")
+	}
+
+	// The bundle holds the files of the proposal along with some JSON.
+	maxSize := int64(b.cfg.MaxProposalImages)*
+		int64(b.cfg.MaxProposalImageSize) +
+		int64(b.cfg.MaxProposalMDs)*int64(b.cfg.MaxProposalMDSize) +
+		1024*1024
+	entries, err := readBundle(ip.Format, bundle, maxSize)
+	if err != nil {
+		if _, ok := err.(www.UserError); ok {
+			return nil, err
+		}
+		return nil, bundleError(www.BundleReasonMalformed, err.Error())
+	}
+
+	// Entries are in a directory named after the token.
+	var dir string
+	for k := range entries {
+		if path.Base(k) == "proposal.json" && path.Dir(path.Dir(k)) == "." {
+			dir = path.Dir(k)
+			break
+		}
+	}
+	if dir == "" {
+		return nil, bundleError(www.BundleReasonMissing, "proposal.json")
+	}
+
+	var imp importedProposal
+	p := &imp.proposal
+	err = json.Unmarshal(entries[path.Join(dir, "proposal.json")], p)
+	if err != nil {
+		return nil, bundleError(www.BundleReasonMalformed, "proposal.json")
+	}
+	if len(p.Files) == 0 {
+		return nil, bundleError(www.BundleReasonMissing, indexFile)
+	}
+
+	// Files are verified against the digests of the proposal.
+	digests := make([]*[sha256.Size]byte, 0, len(p.Files))
+	for _, v := range p.Files {
+		name := path.Join(dir, "files", path.Base(v.Name))
+		payload, ok := entries[name]
+		if !ok {
+			return nil, bundleError(www.BundleReasonMissing, name)
+		}
+		digest := util.Digest(payload)
+		if hex.EncodeToString(digest) != v.Digest {
+			return nil, bundleError(www.BundleReasonDigest, v.Name)
+		}
+		var d [sha256.Size]byte
+		copy(d[:], digest)
+		digests = append(digests, &d)
+
+		imp.files = append(imp.files, pd.File{
+			Name:    v.Name,
+			MIME:    v.MIME,
+			Digest:  v.Digest,
+			Payload: base64.StdEncoding.EncodeToString(payload),
+		})
+	}
+	mr := merkle.Root(digests)
+	imp.merkle = hex.EncodeToString(mr[:])
+
+	// The author signed the merkle root of the files.
+	pk, err := util.IdentityFromString(p.PublicKey)
+	if err != nil {
+		return nil, bundleError(www.BundleReasonSignature, p.PublicKey)
+	}
+	sig, err := util.ConvertSignature(p.Signature)
+	if err != nil || !pk.VerifyMessage([]byte(imp.merkle), sig) {
+		return nil, bundleError(www.BundleReasonSignature, p.Signature)
+	}
+
+	// The censorship record can only be verified with the key of the
+	// deployment that the proposal was exported from.
+	var cr bundleCensorshipRecord
+	name := path.Join(dir, "censorshiprecord.json")
+	if data, ok := entries[name]; ok {
+		err = json.Unmarshal(data, &cr)
+		if err != nil {
+			return nil, bundleError(www.BundleReasonMalformed, name)
+		}
+	}
+	if cr.ServerPublicKey != "" {
+		id, err := util.IdentityFromString(cr.ServerPublicKey)
+		if err != nil {
+			return nil, bundleError(www.BundleReasonCensorship,
+				cr.ServerPublicKey)
+		}
+		err = pd.Verify(*id, convertPropCensorFromWWW(cr.CensorshipRecord),
+			imp.files)
+		if err != nil {
+			return nil, bundleError(www.BundleReasonCensorship,
+				err.Error())
+		}
+	}
+
+	// Metadata streams are replayed, except the comments and the votes:
+	// their signatures cover the original token.
+	general := false
+	var origin *BackendProposalImport
+	for k, v := range entries {
+		if path.Dir(k) != path.Join(dir, "metadata") ||
+			!strings.HasSuffix(k, ".metadata.txt") {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(path.Base(k),
+			".metadata.txt"), 10, 64)
+		if err != nil {
+			return nil, bundleError(www.BundleReasonMalformed, k)
+		}
+		ms := pd.MetadataStream{
+			ID:      id,
+			Payload: string(v),
+		}
+		switch id {
+		case mdStreamGeneral:
+			general = true
+		case mdStreamChanges:
+			imp.changes = &ms
+			continue
+		case mdStreamAuthor:
+			// The author is a user of this deployment only if
+			// their key is.
+			md, err := decodeBackendProposalAuthor(v)
+			if err != nil {
+				return nil, bundleError(www.BundleReasonMalformed, k)
+			}
+			b.RLock()
+			md.UserID = b.userPubkeys[md.PublicKey]
+			b.RUnlock()
+			author, err := encodeBackendProposalAuthor(*md)
+			if err != nil {
+				return nil, err
+			}
+			ms.Payload = string(author)
+		case mdStreamCoAuthors:
+			// So are the co-authors, who signed the same files.
+			md, err := decodeBackendProposalCoAuthors(v)
+			if err != nil {
+				return nil, bundleError(www.BundleReasonMalformed, k)
+			}
+			coAuthors := make([]www.CoAuthor, 0, len(md.CoAuthors))
+			for _, c := range md.CoAuthors {
+				coAuthors = append(coAuthors, www.CoAuthor{
+					PublicKey: c.PublicKey,
+					Signature: c.Signature,
+				})
+			}
+			err = validateCoAuthors(coAuthors, p.PublicKey, imp.merkle)
+			if err != nil {
+				return nil, bundleError(www.BundleReasonSignature, k)
+			}
+			b.RLock()
+			for i := range md.CoAuthors {
+				md.CoAuthors[i].UserID =
+					b.userPubkeys[md.CoAuthors[i].PublicKey]
+			}
+			b.RUnlock()
+			payload, err := encodeBackendProposalCoAuthors(*md)
+			if err != nil {
+				return nil, err
+			}
+			ms.Payload = string(payload)
+		case mdStreamImport:
+			// Proposals that were imported before keep their
+			// first origin.
+			md, err := decodeBackendProposalImport(v)
+			if err != nil {
+				return nil, bundleError(www.BundleReasonMalformed, k)
+			}
+			origin = md
+			continue
+		case mdStreamTags, mdStreamTimeline, mdStreamPages:
+			// Replayed as they are.
+		default:
+			continue
+		}
+		imp.metadata = append(imp.metadata, ms)
+	}
+	if !general {
+		return nil, bundleError(www.BundleReasonMissing,
+			path.Join(dir, "metadata",
+				fmt.Sprintf("%02v.metadata.txt", mdStreamGeneral)))
+	}
+
+	// The origin of the proposal is recorded along with it.
+	imp.origin = BackendProposalImport{
+		Version:       BackendProposalImportVersion,
+		OriginalToken: p.CensorshipRecord.Token,
+		Timestamp:     p.Timestamp,
+	}
+	if origin != nil {
+		imp.origin.OriginalToken = origin.OriginalToken
+		imp.origin.Timestamp = origin.Timestamp
+	}
+
+	// Votes can't be replayed, so proposals that were voted on are
+	// imported as public.
+	switch p.Status {
+	case www.PropStatusPublic, www.PropStatusLocked:
+		imp.status = pd.RecordStatusPublic
+	case www.PropStatusCensored:
+		imp.status = pd.RecordStatusCensored
+	default:
+		imp.status = pd.RecordStatusNotReviewed
+	}
+
+	return &imp, nil
+}
+
+// importRecord creates the record of the provided proposal in politeiad and
+// sets its status, and returns the record.  The origin of the proposal is
+// stored in the import metadata stream of the record.
+//
+// This function must be called WITHOUT the mutex held.
+func (b *backend) importRecord(ctx context.Context, ip *importedProposal, user *database.User) (*pd.Record, error) {
+	origin := ip.origin
+	origin.AdminID = strconv.FormatUint(user.ID, 10)
+	origin.ImportedAt = time.Now().Unix()
+	payload, err := encodeBackendProposalImport(origin)
+	if err != nil {
+		return nil, err
+	}
+	metadata := append(ip.metadata, pd.MetadataStream{
+		ID:      mdStreamImport,
+		Payload: string(payload),
+	})
+
+	if b.test {
+		tokenBytes, err := util.Random(pd.TokenSize)
+		if err != nil {
+			return nil, err
+		}
+		record := pd.Record{
+			Status:    ip.status,
+			Timestamp: ip.proposal.Timestamp,
+			CensorshipRecord: pd.CensorshipRecord{
+				Token:  hex.EncodeToString(tokenBytes),
+				Merkle: ip.merkle,
+			},
+			Metadata: metadata,
+			Files:    ip.files,
+		}
+		if ip.changes != nil {
+			record.Metadata = append(record.Metadata, *ip.changes)
+		}
+		return &record, nil
+	}
+
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := b.makeRequest(ctx, http.MethodPost,
+		pd.NewRecordRoute, pd.NewRecord{
+			Challenge: hex.EncodeToString(challenge),
+			Metadata:  metadata,
+			Files:     ip.files,
+		})
+	if err != nil {
+		return nil, err
+	}
+	var nrr pd.NewRecordReply
+	err = json.Unmarshal(responseBody, &nrr)
+	if err != nil {
+		return nil, fmt.Errorf("Unmarshal NewRecordReply: %v", err)
+	}
+	err = util.VerifyChallenge(b.cfg.Identity, challenge, nrr.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	record := pd.Record{
+		Status:           pd.RecordStatusNotReviewed,
+		Timestamp:        ip.proposal.Timestamp,
+		CensorshipRecord: nrr.CensorshipRecord,
+		Metadata:         metadata,
+		Files:            ip.files,
+	}
+	if ip.status == pd.RecordStatusNotReviewed {
+		return &record, nil
+	}
+
+	// The original status changes are appended as they are.  Bundles
+	// without them get a change by the importing admin.
+	changes := ip.changes
+	if changes == nil {
+		adminPubKey, ok := database.ActiveIdentityString(user.Identities)
+		if !ok {
+			return nil, fmt.Errorf("invalid admin identity: %v",
+				user.ID)
+		}
+		blob, err := json.Marshal(MDStreamChanges{
+			AdminPubKey: adminPubKey,
+			NewStatus:   ip.status,
+			Timestamp:   time.Now().Unix(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		changes = &pd.MetadataStream{
+			ID:      mdStreamChanges,
+			Payload: string(blob),
+		}
+	}
+
+	challenge, err = util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err = b.makeRequest(ctx, http.MethodPost,
+		pd.SetUnvettedStatusRoute, pd.SetUnvettedStatus{
+			Token:     nrr.CensorshipRecord.Token,
+			Status:    ip.status,
+			Challenge: hex.EncodeToString(challenge),
+			MDAppend:  []pd.MetadataStream{*changes},
+		})
+	if err != nil {
+		return nil, err
+	}
+	var susr pd.SetUnvettedStatusReply
+	err = json.Unmarshal(responseBody, &susr)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal "+
+			"SetUnvettedStatusReply: %v", err)
+	}
+	err = util.VerifyChallenge(b.cfg.Identity, challenge, susr.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &susr.Record, nil
+}
+
+// ProcessImportProposal replays a proposal bundle that was exported from
+// another deployment into politeiad.  politeiad assigns the proposal a new
+// token, but its timestamps, its metadata and its status are preserved.  A
+// proposal can only be imported once.
+func (b *backend) ProcessImportProposal(ctx context.Context, ip www.ImportProposal, user *database.User) (*www.ImportProposalReply, error) {
+	log.Tracef("ProcessImportProposal")
+
+	imported, err := b.parseProposalBundle(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	// politeiad is called without the mutex held; the imports are
+	// serialized instead so that a bundle can't be imported twice.
+	b.importMtx.Lock()
+	defer b.importMtx.Unlock()
+
+	b.RLock()
+	for _, v := range b.inventory {
+		if v.record.CensorshipRecord.Merkle != imported.merkle {
+			continue
+		}
+		if convertPropFromPD(v.record).Signature ==
+			imported.proposal.Signature {
+			b.RUnlock()
+			return nil, bundleError(www.BundleReasonDuplicate,
+				v.record.CensorshipRecord.Token)
+		}
+	}
+	b.RUnlock()
+
+	record, err := b.importRecord(ctx, imported, user)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Lock()
+	err = b.newInventoryRecord(*record)
+	if err != nil {
+		b.Unlock()
+		return nil, err
+	}
+	b.loadRecord(*record)
+	ir := b.inventory[record.CensorshipRecord.Token]
+	proposal := convertPropFromInventoryRecord(ir, b.userPubkeys)
+	b.Unlock()
+
+	token := record.CensorshipRecord.Token
+	b.publish(clusterEvent{
+		Type:  clusterEventInventory,
+		Token: token,
+	})
+
+	log.Infof("Imported proposal %v as %v",
+		imported.proposal.CensorshipRecord.Token, token)
+
+	return &www.ImportProposalReply{
+		OriginalToken: imported.origin.OriginalToken,
+		Proposal:      proposal,
+	}, nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestImportProposal(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+	publishProposal(b, token, t, user, id)
+	original := getProposalDetails(b, token, t).Proposal
+
+	_, _, bundle, err := b.ProcessProposalBundle(context.Background(),
+		www.ProposalBundle{
+			Token:  token,
+			Format: www.ProposalBundleFormatZip,
+		}, user)
+	assertSuccess(t, err)
+
+	// Proposals can't be imported where they already are.
+	ip := www.ImportProposal{
+		Bundle: base64.StdEncoding.EncodeToString(bundle),
+		Format: www.ProposalBundleFormatZip,
+	}
+	_, err = b.ProcessImportProposal(context.Background(), ip, user)
+	assertErrorWithContext(t, err, www.ErrorStatusInvalidProposalBundle,
+		[]string{www.BundleReasonDuplicate, token})
+
+	b2 := createBackend(t)
+	defer b2.db.Close()
+
+	reply, err := b2.ProcessImportProposal(context.Background(), ip, user)
+	assertSuccess(t, err)
+	p := reply.Proposal
+	if reply.OriginalToken != token || p.CensorshipRecord.Token == token {
+		t.Fatalf("unexpected tokens %v %v", reply.OriginalToken,
+			p.CensorshipRecord.Token)
+	}
+	if p.Status != www.PropStatusPublic ||
+		p.Timestamp != original.Timestamp || p.Name != original.Name ||
+		p.Signature != original.Signature {
+		t.Fatalf("unexpected imported proposal %v", p)
+	}
+	imported := getProposalDetails(b2, p.CensorshipRecord.Token, t).Proposal
+	if imported.Files[0].Payload != original.Files[0].Payload {
+		t.Fatalf("unexpected imported files")
+	}
+
+	// The original timestamp survives reloading the record, which
+	// politeiad timestamps when it is imported.
+	b2.Lock()
+	record := b2.inventory[p.CensorshipRecord.Token].record
+	record.Timestamp = time.Now().Unix() + 3600
+	b2.updateInventoryRecord(record)
+	reloaded := b2.inventory[p.CensorshipRecord.Token].record
+	b2.Unlock()
+	if reloaded.Timestamp != original.Timestamp {
+		t.Fatalf("got timestamp %v, want %v", reloaded.Timestamp,
+			original.Timestamp)
+	}
+	var origin *BackendProposalImport
+	for _, v := range reloaded.Metadata {
+		if v.ID == mdStreamImport {
+			origin, err = decodeBackendProposalImport([]byte(v.Payload))
+			assertSuccess(t, err)
+		}
+	}
+	if origin == nil || origin.OriginalToken != token ||
+		origin.AdminID != strconv.FormatUint(user.ID, 10) {
+		t.Fatalf("unexpected origin %v", origin)
+	}
+
+	// Tampered files are rejected.
+	entries, err := readBundle(ip.Format, bundle, int64(len(bundle))*100)
+	assertSuccess(t, err)
+	name := path.Join(token, "files", indexFile)
+	entries[name] = append(entries[name], '!')
+	var buf bytes.Buffer
+	w, _, err := newBundleWriter(ip.Format, &buf, time.Now())
+	assertSuccess(t, err)
+	for k, v := range entries {
+		assertSuccess(t, w.writeFile(k, v))
+	}
+	assertSuccess(t, w.close())
+	ip.Bundle = base64.StdEncoding.EncodeToString(buf.Bytes())
+	_, err = b2.ProcessImportProposal(context.Background(), ip, user)
+	assertErrorWithContext(t, err, www.ErrorStatusInvalidProposalBundle,
+		[]string{www.BundleReasonDigest, indexFile})
+}
@@ -5,10 +5,9 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strconv"
 	"strings"
 
-	"github.com/davecgh/go-spew/spew"
-
 	"github.com/decred/politeia/decredplugin"
 	pd "github.com/decred/politeia/politeiad/api/v1"
 	www "github.com/decred/politeia/politeiawww/api/v1"
@@ -19,12 +18,14 @@ var (
 )
 
 type inventoryRecord struct {
-	record     pd.Record                   // actual record
-	proposalMD BackendProposalMetadata     // proposal metadata
-	comments   map[uint64]BackendComment   // [token][parent]comment
-	changes    []MDStreamChanges           // changes metadata
-	votebits   decredplugin.Vote           // vote bits and options
-	voting     decredplugin.StartVoteReply // voting metadata
+	record       pd.Record                   // actual record
+	proposalMD   BackendProposalMetadata     // proposal metadata
+	comments     map[uint64]BackendComment   // [token][parent]comment
+	commentVotes map[uint64]map[string]int64 // [comment id][user id]vote
+	changes      []MDStreamChanges           // changes metadata
+	votebits     decredplugin.Vote           // vote bits and options
+	voting       decredplugin.StartVoteReply // voting metadata
+	voteTally    *voteTally                  // cached vote results, nil until loaded
 }
 
 // proposalsRequest is used for passing parameters into the
@@ -34,16 +35,100 @@ type proposalsRequest struct {
 	Before    string
 	UserId    string
 	StatusMap map[www.PropStatusT]bool
+
+	// Only proposals submitted within [SubmittedAfter, SubmittedBefore)
+	// are returned; zero leaves that side of the window open.
+	SubmittedAfter  int64
+	SubmittedBefore int64
+
+	// Only proposals with the Tag are returned when it's provided.
+	Tag string
+
+	// Sort is one of the www.ProposalSort* orders; the proposals are
+	// listed newest first when it's empty.  BestBlock tells apart the
+	// ongoing votes from the ended ones when sorting by vote end.
+	Sort      string
+	BestBlock uint64
+}
+
+// statusIndexEntry is a proposal in the status index.
+type statusIndexEntry struct {
+	timestamp int64 // Last update of the proposal
+	token     string
+}
+
+// newer returns true if the proposal of the entry is listed before the
+// proposal of the provided entry, like proposalNewer.
+func (e statusIndexEntry) newer(o statusIndexEntry) bool {
+	if e.timestamp != o.timestamp {
+		return e.timestamp > o.timestamp
+	}
+	return e.token > o.token
+}
+
+// indexStatus inserts the proposal with the provided token and statistics in
+// the status index.
+//
+// This function must be called WITH the mutex held.
+func (b *backend) indexStatus(s recordStats, token string) {
+	e := statusIndexEntry{
+		timestamp: s.timestamp,
+		token:     token,
+	}
+	entries := b.statusIndex[s.status]
+	i := sort.Search(len(entries), func(i int) bool {
+		return !entries[i].newer(e)
+	})
+	entries = append(entries, statusIndexEntry{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = e
+	b.statusIndex[s.status] = entries
+}
+
+// unindexStatus removes the proposal with the provided token and previous
+// statistics from the status index.
+//
+// This function must be called WITH the mutex held.
+func (b *backend) unindexStatus(s recordStats, token string) {
+	e := statusIndexEntry{
+		timestamp: s.timestamp,
+		token:     token,
+	}
+	entries := b.statusIndex[s.status]
+	i := sort.Search(len(entries), func(i int) bool {
+		return !entries[i].newer(e)
+	})
+	if i == len(entries) || entries[i] != e {
+		return
+	}
+	b.statusIndex[s.status] = append(entries[:i], entries[i+1:]...)
+}
+
+// filterStatuses narrows the statuses that a listing may return down to the
+// requested ones.  All of them remain when none are requested.
+func filterStatuses(statusMap map[www.PropStatusT]bool, statuses []www.PropStatusT) map[www.PropStatusT]bool {
+	if len(statuses) == 0 {
+		return statusMap
+	}
+
+	filtered := make(map[www.PropStatusT]bool, len(statuses))
+	for _, v := range statuses {
+		filtered[v] = statusMap[v]
+	}
+	return filtered
 }
 
 // updateInventoryRecord updates an existing record.
 //
 // This function must be called WITH the mutex held.
 func (b *backend) updateInventoryRecord(record pd.Record) {
+	restoreImportTimestamp(&record)
 	b.inventory[record.CensorshipRecord.Token] = &inventoryRecord{
-		record:   record,
-		comments: make(map[uint64]BackendComment),
+		record:       record,
+		comments:     make(map[uint64]BackendComment),
+		commentVotes: make(map[uint64]map[string]int64),
 	}
+	b.indexUserStats(record.CensorshipRecord.Token)
 }
 
 // newInventoryRecord adds a record to the inventory.
@@ -161,6 +246,11 @@ func (b *backend) loadRecord(v pd.Record) {
 					err)
 				continue
 			}
+		case mdStreamTags, mdStreamAuthor, mdStreamTimeline,
+			mdStreamPages, mdStreamSpam, mdStreamCoAuthors:
+			// Tags, authors, timelines, pages, spam scores and
+			// co-authors are decoded along with the record.
+			continue
 		case decredplugin.MDStreamVotes:
 			// This is all handled in the plugin bits.
 			log.Debugf("initializeInventory skipping MDStreamVotes")
@@ -186,6 +276,8 @@ func (b *backend) loadRecord(v pd.Record) {
 				m.ID, t)
 		}
 	}
+
+	b.indexUserStats(t)
 }
 
 // initializeInventory initializes the inventory map and loads it with a
@@ -194,6 +286,10 @@ func (b *backend) loadRecord(v pd.Record) {
 // This function must be called WITH the mutex held.
 func (b *backend) initializeInventory(inv *pd.InventoryReply) error {
 	b.inventory = make(map[string]*inventoryRecord)
+	b.userStats = make(map[string]*userStats)
+	b.recordStats = make(map[string]recordStats)
+	b.proposalStats = newProposalStats()
+	b.statusIndex = make(map[www.PropStatusT][]statusIndexEntry)
 
 	for _, v := range append(inv.Vetted, inv.Branches...) {
 		err := b.newInventoryRecord(v)
@@ -226,113 +322,172 @@ func (b *backend) getInventoryRecord(token string) (inventoryRecord, error) {
 	return b._getInventoryRecord(token)
 }
 
-// getProposals returns a list of proposals that adheres to the requirements
-// specified in the provided request.
-//
-// This function must be called WITHOUT the mutex held.
-func (b *backend) getProposals(pr proposalsRequest) []www.ProposalRecord {
-	b.RLock()
-
-	allProposals := make([]www.ProposalRecord, 0, len(b.inventory))
-	for _, vv := range b.inventory {
-		v := convertPropFromInventoryRecord(vv, b.userPubkeys)
+// proposalNewer returns true if the first proposal is listed before the
+// second one.  Proposals are listed newest first; proposals with the same
+// timestamp are ordered by token so that the order is stable across pages.
+func proposalNewer(p1, p2 www.ProposalRecord) bool {
+	if p1.Timestamp != p2.Timestamp {
+		return p1.Timestamp > p2.Timestamp
+	}
+	return p1.CensorshipRecord.Token > p2.CensorshipRecord.Token
+}
 
-		// Set the number of comments.
-		v.NumComments = uint(len(vv.comments))
+// listedProposal is a proposal of a listing along with the end of its vote.
+type listedProposal struct {
+	proposal  www.ProposalRecord
+	voteEnd   uint64 // End height of the vote, 0 if it didn't start
+	voteEnded bool   // Whether the vote ended at the best block
+}
 
-		// Look up and set the user id.
-		var ok bool
-		v.UserId, ok = b.userPubkeys[v.PublicKey]
-		if !ok {
-			log.Infof("%v", spew.Sdump(b.userPubkeys))
-			log.Errorf("user not found for public key %v, for proposal %v",
-				v.PublicKey, v.CensorshipRecord.Token)
+// newListedProposal returns the listed proposal of the provided inventory
+// record.
+//
+// This function must be called WITH the mutex held.
+func (b *backend) newListedProposal(ir *inventoryRecord, bestBlock uint64) listedProposal {
+	lp := listedProposal{
+		proposal: convertPropFromInventoryRecord(ir, b.userPubkeys),
+	}
+	lp.proposal.Views = b.views.count(lp.proposal.CensorshipRecord.Token)
+	if ir.voting.StartBlockHeight != "" {
+		end, err := strconv.ParseUint(ir.voting.EndHeight, 10, 64)
+		if err != nil {
+			log.Errorf("newListedProposal: invalid end height %v: %v",
+				lp.proposal.CensorshipRecord.Token, err)
+			return lp
 		}
+		lp.voteEnd = end
+		lp.voteEnded = bestBlock > end
+	}
+	return lp
+}
 
-		len := len(allProposals)
-		if len == 0 {
-			allProposals = append(allProposals, v)
-			continue
+// listedBefore returns true if the first proposal is listed before the second
+// one in the provided sort order.  Ties are listed newest first.
+func listedBefore(sortOrder string, p1, p2 listedProposal) bool {
+	switch sortOrder {
+	case www.ProposalSortOldest:
+		return proposalNewer(p2.proposal, p1.proposal)
+	case www.ProposalSortMostCommented:
+		if p1.proposal.NumComments != p2.proposal.NumComments {
+			return p1.proposal.NumComments > p2.proposal.NumComments
+		}
+	case www.ProposalSortMostViewed:
+		if p1.proposal.Views != p2.proposal.Views {
+			return p1.proposal.Views > p2.proposal.Views
+		}
+	case www.ProposalSortVoteEnding:
+		// Ongoing votes come first, the ones that end soonest
+		// first.
+		ongoing1 := p1.voteEnd != 0 && !p1.voteEnded
+		ongoing2 := p2.voteEnd != 0 && !p2.voteEnded
+		if ongoing1 != ongoing2 {
+			return ongoing1
 		}
+		if ongoing1 && p1.voteEnd != p2.voteEnd {
+			return p1.voteEnd < p2.voteEnd
+		}
+	}
+	return proposalNewer(p1.proposal, p2.proposal)
+}
 
-		// Insertion sort from oldest to newest.
-		idx := sort.Search(len, func(i int) bool {
-			return v.Timestamp < allProposals[i].Timestamp
-		})
+// getProposals returns a page of the proposals that adhere to the
+// requirements specified in the provided request, in the requested sort
+// order, and the total number of proposals that adhere to them.  The page
+// holds at most www.ProposalListPageSize proposals that are listed after or
+// before the proposal with the After or Before token.  The page is empty if
+// that proposal doesn't exist.
+//
+// This function must be called WITHOUT the mutex held.
+func (b *backend) getProposals(pr proposalsRequest) ([]www.ProposalRecord, int) {
+	b.RLock()
 
-		allProposals = append(allProposals[:idx],
-			append([]www.ProposalRecord{v},
-				allProposals[idx:]...)...)
+	var (
+		pivot    listedProposal
+		hasPivot bool
+	)
+	pivotToken := pr.After
+	if pivotToken == "" {
+		pivotToken = pr.Before
+	}
+	if pivotToken != "" {
+		ir, ok := b.inventory[pivotToken]
+		if !ok {
+			b.RUnlock()
+			return []www.ProposalRecord{}, 0
+		}
+		pivot = b.newListedProposal(ir, pr.BestBlock)
+		hasPivot = true
 	}
 
-	b.RUnlock()
-
-	// pageStarted stores whether or not it's okay to start adding
-	// proposals to the array. If the after or before parameter is
-	// supplied, we must find the beginning (or end) of the page first.
-	pageStarted := (pr.After == "" && pr.Before == "")
-	beforeIdx := -1
-	proposals := make([]www.ProposalRecord, 0)
+	// Only the proposals of the requested statuses are visited.
+	var entries []statusIndexEntry
+	for status, ok := range pr.StatusMap {
+		if ok {
+			entries = append(entries, b.statusIndex[status]...)
+		}
+	}
 
-	// Iterate in reverse order because they're sorted by oldest timestamp
-	// first.
-	for i := len(allProposals) - 1; i >= 0; i-- {
-		proposal := allProposals[i]
+	listed := make([]listedProposal, 0, len(entries))
+	for _, e := range entries {
+		vv, ok := b.inventory[e.token]
+		if !ok {
+			continue
+		}
+		lp := b.newListedProposal(vv, pr.BestBlock)
+		v := lp.proposal
 
 		// Filter by user if it's provided.
-		if pr.UserId != "" && pr.UserId != proposal.UserId {
+		if pr.UserId != "" && pr.UserId != v.UserId {
 			continue
 		}
 
-		// Filter by the status.
-		if val, ok := pr.StatusMap[proposal.Status]; !ok || !val {
+		// Filter by the submission time.
+		if pr.SubmittedAfter != 0 && v.Timestamp < pr.SubmittedAfter {
+			continue
+		}
+		if pr.SubmittedBefore != 0 && v.Timestamp >= pr.SubmittedBefore {
 			continue
 		}
 
-		if pageStarted {
-			proposals = append(proposals, proposal)
-			if len(proposals) >= www.ProposalListPageSize {
-				break
-			}
-		} else if pr.After != "" {
-			// The beginning of the page has been found, so
-			// the next public proposal is added.
-			pageStarted = proposal.CensorshipRecord.Token == pr.After
-		} else if pr.Before != "" {
-			// The end of the page has been found, so we'll
-			// have to iterate in the other direction to
-			// add the proposals; save the current index.
-			if proposal.CensorshipRecord.Token == pr.Before {
-				beforeIdx = i
-				break
-			}
+		// Filter by the tag.
+		if pr.Tag != "" && !hasProposalTag(v, pr.Tag) {
+			continue
 		}
-	}
 
-	// If beforeIdx is set, the caller is asking for vetted proposals whose
-	// last result is before the provided proposal.
-	if beforeIdx >= 0 {
-		for _, proposal := range allProposals[beforeIdx+1:] {
-			// Filter by user if it's provided.
-			if pr.UserId != "" && pr.UserId != proposal.UserId {
-				continue
-			}
+		listed = append(listed, lp)
+	}
 
-			// Filter by the status.
-			if val, ok := pr.StatusMap[proposal.Status]; !ok || !val {
-				continue
-			}
+	b.RUnlock()
 
-			// The iteration direction is oldest -> newest,
-			// so proposals are prepended to the array so
-			// the result will be newest -> oldest.
-			proposals = append([]www.ProposalRecord{proposal},
-				proposals...)
-			if len(proposals) >= www.ProposalListPageSize {
-				break
-			}
+	sort.Slice(listed, func(i, j int) bool {
+		return listedBefore(pr.Sort, listed[i], listed[j])
+	})
+	total := len(listed)
+
+	// Find the proposals after or before the pivot; the pivot itself
+	// doesn't need to match the request.
+	page := listed
+	if hasPivot && pr.After != "" {
+		idx := sort.Search(len(listed), func(i int) bool {
+			return listedBefore(pr.Sort, pivot, listed[i])
+		})
+		page = listed[idx:]
+	} else if hasPivot {
+		idx := sort.Search(len(listed), func(i int) bool {
+			return !listedBefore(pr.Sort, listed[i], pivot)
+		})
+		page = listed[:idx]
+		if len(page) > www.ProposalListPageSize {
+			page = page[len(page)-www.ProposalListPageSize:]
 		}
 	}
+	if len(page) > www.ProposalListPageSize {
+		page = page[:www.ProposalListPageSize]
+	}
 
-	return proposals
+	proposals := make([]www.ProposalRecord, 0, len(page))
+	for _, v := range page {
+		proposals = append(proposals, v.proposal)
+	}
+	return proposals, total
 }
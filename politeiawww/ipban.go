@@ -0,0 +1,332 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+const (
+	// minIPBanPrefixV4 and minIPBanPrefixV6 are the shortest prefixes of
+	// the networks that admins can ban.  Shorter prefixes would ban large
+	// parts of the internet, or all of it for 0.0.0.0/0 and ::/0.
+	minIPBanPrefixV4 = 8
+	minIPBanPrefixV6 = 32
+)
+
+// ipBan is an IP ban along with its parsed network.
+type ipBan struct {
+	database.IPBan
+	network *net.IPNet
+}
+
+// parseIPNetwork parses an IP address or a network in CIDR notation.  An
+// address is returned as a network that contains only that address.
+func parseIPNetwork(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusInvalidIPNetwork,
+			}
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 8 * net.IPv4len
+		}
+		return &net.IPNet{
+			IP:   ip,
+			Mask: net.CIDRMask(bits, bits),
+		}, nil
+	}
+
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidIPNetwork,
+		}
+	}
+	return network, nil
+}
+
+// expired returns true if the provided ban expired at the provided time.
+func expired(ban database.IPBan, now time.Time) bool {
+	return ban.Expiry != 0 && ban.Expiry <= now.Unix()
+}
+
+// convertWWWIPBanFromDatabase converts a database IP ban to a www IP ban.
+func convertWWWIPBanFromDatabase(ban database.IPBan) www.IPBan {
+	var adminID string
+	if !ban.Automatic {
+		adminID = strconv.FormatUint(ban.AdminID, 10)
+	}
+	return www.IPBan{
+		Network:   ban.Network,
+		Reason:    ban.Reason,
+		AdminID:   adminID,
+		Automatic: ban.Automatic,
+		Timestamp: ban.Timestamp,
+		Expiry:    ban.Expiry,
+	}
+}
+
+// initIPBans loads the IP bans from the database.
+func (b *backend) initIPBans() error {
+	bans, err := b.db.AllIPBans()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	b.ipBans = make([]ipBan, 0, len(bans))
+	for _, v := range bans {
+		if expired(v, now) {
+			continue
+		}
+		network, err := parseIPNetwork(v.Network)
+		if err != nil {
+			log.Errorf("initIPBans: invalid network %v", v.Network)
+			continue
+		}
+		b.ipBans = append(b.ipBans, ipBan{
+			IPBan:   v,
+			network: network,
+		})
+	}
+
+	return nil
+}
+
+// ipBanned returns the ban of the provided IP address or nil if the address
+// isn't banned.
+func (b *backend) ipBanned(ip net.IP) *database.IPBan {
+	if ip == nil {
+		return nil
+	}
+
+	b.ipBansMtx.RLock()
+	defer b.ipBansMtx.RUnlock()
+
+	now := time.Now()
+	for _, v := range b.ipBans {
+		if expired(v.IPBan, now) {
+			continue
+		}
+		if v.network.Contains(ip) {
+			ban := v.IPBan
+			return &ban
+		}
+	}
+	return nil
+}
+
+// banIP stores the provided ban and enforces it.  A ban of the same network
+// is replaced.  The stored ban is returned.
+func (b *backend) banIP(ban database.IPBan) (*database.IPBan, error) {
+	network, err := parseIPNetwork(ban.Network)
+	if err != nil {
+		return nil, err
+	}
+	ban.Network = network.String()
+
+	b.ipBansMtx.Lock()
+	defer b.ipBansMtx.Unlock()
+
+	err = b.db.IPBanNew(ban)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range b.ipBans {
+		if v.Network == ban.Network {
+			b.ipBans = append(b.ipBans[:k], b.ipBans[k+1:]...)
+			break
+		}
+	}
+	b.ipBans = append(b.ipBans, ipBan{
+		IPBan:   ban,
+		network: network,
+	})
+
+	b.publish(clusterEvent{
+		Type:  clusterEventIPBan,
+		IPBan: &ban,
+	})
+
+	return &ban, nil
+}
+
+// unbanIP lifts the ban of the provided network.
+func (b *backend) unbanIP(network string) error {
+	b.ipBansMtx.Lock()
+	defer b.ipBansMtx.Unlock()
+
+	err := b.db.IPBanDelete(network)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range b.ipBans {
+		if v.Network == network {
+			b.ipBans = append(b.ipBans[:k], b.ipBans[k+1:]...)
+			break
+		}
+	}
+
+	b.publish(clusterEvent{
+		Type:    clusterEventIPUnban,
+		Network: network,
+	})
+
+	return nil
+}
+
+// reportAbuse records that the provided IP address abused politeiawww, for
+// example by failing to log in.  The address is banned once it reaches the
+// configured number of abuses within the auto ban window.
+func (b *backend) reportAbuse(ip net.IP, reason string) {
+	if ip == nil || b.cfg.AutoBanThreshold == 0 {
+		return
+	}
+
+	now := time.Now()
+	key := ip.String()
+	abuseKey := "abuse:" + key
+
+	err := b.stateStore.Hit(abuseKey, now, b.cfg.AutoBanWindow)
+	if err != nil {
+		log.Errorf("reportAbuse: %v", err)
+		return
+	}
+	recent, err := b.stateStore.Hits(abuseKey,
+		now.Add(-b.cfg.AutoBanWindow))
+	if err != nil {
+		log.Errorf("reportAbuse: %v", err)
+		return
+	}
+	if len(recent) < b.cfg.AutoBanThreshold {
+		return
+	}
+	err = b.stateStore.Delete(abuseKey)
+	if err != nil {
+		log.Errorf("reportAbuse: %v", err)
+	}
+
+	log.Warnf("Banning %v for %v: %v", key, b.cfg.AutoBanDuration, reason)
+
+	_, err = b.banIP(database.IPBan{
+		Network:   key,
+		Reason:    reason,
+		Automatic: true,
+		Timestamp: now.Unix(),
+		Expiry:    now.Add(b.cfg.AutoBanDuration).Unix(),
+	})
+	if err != nil {
+		log.Errorf("reportAbuse: banIP %v: %v", key, err)
+	}
+}
+
+// ProcessIPBans returns the IP bans that are in effect.
+func (b *backend) ProcessIPBans() (*www.IPBansReply, error) {
+	b.ipBansMtx.RLock()
+	defer b.ipBansMtx.RUnlock()
+
+	now := time.Now()
+	reply := www.IPBansReply{
+		Bans: make([]www.IPBan, 0, len(b.ipBans)),
+	}
+	for _, v := range b.ipBans {
+		if expired(v.IPBan, now) {
+			continue
+		}
+		reply.Bans = append(reply.Bans,
+			convertWWWIPBanFromDatabase(v.IPBan))
+	}
+
+	return &reply, nil
+}
+
+// ProcessBanIP bans an IP address or network on behalf of the provided
+// admin, whose IP address is adminIP.  Admins can't ban their own address or
+// networks that are too broad.
+func (b *backend) ProcessBanIP(bi www.BanIP, user *database.User, adminIP net.IP) (*www.BanIPReply, error) {
+	if bi.Duration < 0 {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+	network, err := parseIPNetwork(bi.Network)
+	if err != nil {
+		return nil, err
+	}
+	prefix, bits := network.Mask.Size()
+	if (bits == 8*net.IPv4len && prefix < minIPBanPrefixV4) ||
+		(bits == 8*net.IPv6len && prefix < minIPBanPrefixV6) {
+		return nil, www.UserError{
+			ErrorCode:    www.ErrorStatusInvalidIPBan,
+			ErrorContext: []string{"network too broad"},
+		}
+	}
+	if adminIP != nil && network.Contains(adminIP) {
+		return nil, www.UserError{
+			ErrorCode:    www.ErrorStatusInvalidIPBan,
+			ErrorContext: []string{"network contains your address"},
+		}
+	}
+
+	now := time.Now()
+	ban := database.IPBan{
+		Network:   bi.Network,
+		Reason:    bi.Reason,
+		AdminID:   user.ID,
+		Timestamp: now.Unix(),
+	}
+	if bi.Duration != 0 {
+		ban.Expiry = now.Add(time.Duration(bi.Duration) * time.Second).Unix()
+	}
+
+	stored, err := b.banIP(ban)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Admin %v banned %v: %v", user.ID, stored.Network,
+		stored.Reason)
+
+	return &www.BanIPReply{
+		Ban: convertWWWIPBanFromDatabase(*stored),
+	}, nil
+}
+
+// ProcessUnbanIP lifts the ban of an IP address or network on behalf of the
+// provided admin.
+func (b *backend) ProcessUnbanIP(ub www.UnbanIP, user *database.User) (*www.UnbanIPReply, error) {
+	network, err := parseIPNetwork(ub.Network)
+	if err != nil {
+		return nil, err
+	}
+	key := network.String()
+
+	err = b.unbanIP(key)
+	if err != nil {
+		if err == database.ErrIPBanNotFound {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusIPBanNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	log.Infof("Admin %v unbanned %v", user.ID, key)
+
+	return &www.UnbanIPReply{}, nil
+}
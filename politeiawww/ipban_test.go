@@ -0,0 +1,113 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+func TestIPBans(t *testing.T) {
+	b := createBackend(t)
+	admin := &database.User{ID: 1, Admin: true}
+	adminIP := net.ParseIP("198.51.100.1")
+
+	// Invalid networks and durations.
+	_, err := b.ProcessBanIP(www.BanIP{Network: "10.0.0.0/33"}, admin,
+		adminIP)
+	assertError(t, err, www.ErrorStatusInvalidIPNetwork)
+	_, err = b.ProcessBanIP(www.BanIP{Network: "example.com"}, admin,
+		adminIP)
+	assertError(t, err, www.ErrorStatusInvalidIPNetwork)
+	_, err = b.ProcessBanIP(www.BanIP{Network: "10.0.0.1", Duration: -1},
+		admin, adminIP)
+	assertError(t, err, www.ErrorStatusInvalidInput)
+
+	// Admins can't ban everyone or themselves.
+	for _, network := range []string{"0.0.0.0/0", "::/0", "10.0.0.0/7",
+		"2001::/31", "198.51.100.0/24", "198.51.100.1"} {
+		_, err = b.ProcessBanIP(www.BanIP{Network: network}, admin,
+			adminIP)
+		assertError(t, err, www.ErrorStatusInvalidIPBan)
+	}
+
+	// Ban a network and an address.
+	reply, err := b.ProcessBanIP(www.BanIP{
+		Network: "10.0.0.0/8",
+		Reason:  "spam",
+	}, admin, adminIP)
+	assertSuccess(t, err)
+	if reply.Ban.Expiry != 0 || reply.Ban.AdminID != "1" {
+		t.Fatalf("unexpected ban: %v", reply.Ban)
+	}
+	reply, err = b.ProcessBanIP(www.BanIP{
+		Network:  "192.168.1.1",
+		Duration: 60,
+	}, admin, adminIP)
+	assertSuccess(t, err)
+	if reply.Ban.Network != "192.168.1.1/32" || reply.Ban.Expiry == 0 {
+		t.Fatalf("unexpected ban: %v", reply.Ban)
+	}
+
+	tests := []struct {
+		ip     string
+		banned bool
+	}{
+		{"10.1.2.3", true},
+		{"11.1.2.3", false},
+		{"192.168.1.1", true},
+		{"192.168.1.2", false},
+		{"::1", false},
+	}
+	for _, test := range tests {
+		ban := b.ipBanned(net.ParseIP(test.ip))
+		if (ban != nil) != test.banned {
+			t.Fatalf("%v: got banned %v, want %v", test.ip,
+				ban != nil, test.banned)
+		}
+	}
+
+	ibr, err := b.ProcessIPBans()
+	assertSuccess(t, err)
+	if len(ibr.Bans) != 2 {
+		t.Fatalf("got %v bans, want 2", len(ibr.Bans))
+	}
+
+	// Lift a ban.
+	_, err = b.ProcessUnbanIP(www.UnbanIP{Network: "192.168.1.1"}, admin)
+	assertSuccess(t, err)
+	if b.ipBanned(net.ParseIP("192.168.1.1")) != nil {
+		t.Fatalf("address is still banned")
+	}
+	_, err = b.ProcessUnbanIP(www.UnbanIP{Network: "192.168.1.1"}, admin)
+	assertError(t, err, www.ErrorStatusIPBanNotFound)
+}
+
+func TestAutoBan(t *testing.T) {
+	b := createBackend(t)
+	b.cfg.AutoBanThreshold = 3
+	b.cfg.AutoBanWindow = time.Hour
+	b.cfg.AutoBanDuration = time.Hour
+
+	ip := net.ParseIP("172.16.0.1")
+	for i := 0; i < b.cfg.AutoBanThreshold; i++ {
+		if b.ipBanned(ip) != nil {
+			t.Fatalf("banned after %v abuses", i)
+		}
+		b.reportAbuse(ip, "failed logins")
+	}
+
+	ban := b.ipBanned(ip)
+	if ban == nil {
+		t.Fatalf("not banned after %v abuses", b.cfg.AutoBanThreshold)
+	}
+	if !ban.Automatic || ban.Expiry == 0 {
+		t.Fatalf("unexpected ban: %v", ban)
+	}
+}
@@ -43,12 +43,16 @@ var (
 
 	log        = backendLog.Logger("PWWW")
 	localdbLog = backendLog.Logger("LODB")
+
+	// auditLog records the requests of admins that impersonate users.
+	auditLog = backendLog.Logger("AUDT")
 )
 
 // subsystemLoggers maps each subsystem identifier to its associated logger.
 var subsystemLoggers = map[string]btclog.Logger{
 	"PWWW": log,
 	"LODB": localdbLog,
+	"AUDT": auditLog,
 }
 
 // initLogRotator initializes the logging rotater to write logs to logFile and
@@ -0,0 +1,346 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"time"
+
+	"github.com/dajohi/goemail"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// mailProvider* are the supported email providers.
+	mailProviderSMTP     = "smtp"
+	mailProviderSES      = "ses"
+	mailProviderSendgrid = "sendgrid"
+
+	// mailerTimeout is the timeout of the requests that are made to the
+	// email providers.
+	mailerTimeout = 30 * time.Second
+
+	sendgridEndpoint = "https://api.sendgrid.com/v3"
+)
+
+// emailMessage is a provider agnostic HTML email.
+type emailMessage struct {
+	From    string   // Sender address
+	Name    string   // Sender name
+	To      []string // Recipients
+	Subject string
+	Body    string // HTML body
+
+	// Additional email headers, such as List-Unsubscribe.
+	Headers map[string]string
+}
+
+// newEmailMessage returns a new HTML email from politeia to the provided
+// recipient.
+func newEmailMessage(to, subject, body string) *emailMessage {
+	return &emailMessage{
+		From:    emailFrom,
+		Name:    politeiaMailName,
+		To:      []string{to},
+		Subject: subject,
+		Body:    body,
+	}
+}
+
+// mailer is the interface that email providers implement.
+type mailer interface {
+	// Send sends the provided email.
+	Send(*emailMessage) error
+
+	// Verify checks the connectivity and the credentials of the provider
+	// without sending an email.
+	Verify() error
+}
+
+// smtpMailer sends emails through an SMTP relay over TLS using goemail.
+type smtpMailer struct {
+	client   *goemail.SMTP
+	host     string // Relay address in the form <host>:<port>
+	hostname string // Relay host name
+	auth     smtp.Auth
+}
+
+// newSMTPMailer returns a mailer that sends emails through the provided SMTP
+// relay using the provided credentials.
+func newSMTPMailer(host, user, pass string) (*smtpMailer, error) {
+	hostname, _, err := net.SplitHostPort(host)
+	if err != nil {
+		return nil, err
+	}
+	client, err := goemail.NewSMTP((&url.URL{
+		Scheme: "smtps",
+		User:   url.UserPassword(user, pass),
+		Host:   host,
+	}).String())
+	if err != nil {
+		return nil, err
+	}
+	return &smtpMailer{
+		client:   client,
+		host:     host,
+		hostname: hostname,
+		auth:     smtp.PlainAuth("", user, pass, hostname),
+	}, nil
+}
+
+// Send sends the provided email.  goemail doesn't set additional headers, so
+// the emails that are sent over SMTP only carry the unsubscribe link in
+// their body.
+//
+// Send satisfies the mailer interface.
+func (m *smtpMailer) Send(e *emailMessage) error {
+	msg := goemail.NewHTMLMessage(e.From, e.Subject, e.Body)
+	msg.SetName(e.Name)
+	for _, v := range e.To {
+		msg.AddTo(v)
+	}
+	return m.client.Send(msg)
+}
+
+// Verify checks that the relay accepts the credentials.  goemail only
+// authenticates when it sends an email, so the relay is contacted directly.
+//
+// Verify satisfies the mailer interface.
+func (m *smtpMailer) Verify() error {
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: mailerTimeout},
+		"tcp", m.host, &tls.Config{ServerName: m.hostname})
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, m.hostname)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	err = c.Auth(m.auth)
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// sendJSON sends the provided request to a JSON API and returns an error if
+// the request did not succeed.
+func sendJSON(client *http.Client, req *http.Request) error {
+	req.Header.Set("Content-Type", "application/json")
+	r, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(r.Body)
+		return fmt.Errorf("%v: %v", r.Status, string(body))
+	}
+	return nil
+}
+
+// sesMailer sends emails through the AWS SES v2 API.
+type sesMailer struct {
+	client    *http.Client
+	endpoint  string // SES endpoint of the region
+	region    string
+	accessKey string
+	secretKey string
+}
+
+// newSESMailer returns a mailer that sends emails through AWS SES in the
+// provided region.
+func newSESMailer(region, accessKey, secretKey string) *sesMailer {
+	return &sesMailer{
+		client:    &http.Client{Timeout: mailerTimeout},
+		endpoint:  "https://email." + region + ".amazonaws.com",
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+	}
+}
+
+type sesContent struct {
+	Data string `json:"Data"`
+}
+
+type sesHeader struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+type sesSendEmail struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    struct {
+				Html sesContent `json:"Html"`
+			} `json:"Body"`
+			Headers []sesHeader `json:"Headers,omitempty"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+// sign signs the provided request using AWS signature version 4.
+func (m *sesMailer) sign(req *http.Request, payload []byte, now time.Time) {
+	req.Header.Set("Content-Type", "application/json")
+	util.SignAWSv4(req, payload, "ses", util.AWSCredentials{
+		Region:    m.region,
+		AccessKey: m.accessKey,
+		SecretKey: m.secretKey,
+	}, now)
+}
+
+// Send sends the provided email.
+//
+// Send satisfies the mailer interface.
+func (m *sesMailer) Send(e *emailMessage) error {
+	var se sesSendEmail
+	se.FromEmailAddress = fmt.Sprintf("%v <%v>", e.Name, e.From)
+	se.Destination.ToAddresses = e.To
+	se.Content.Simple.Subject.Data = e.Subject
+	se.Content.Simple.Body.Html.Data = e.Body
+	for k, v := range e.Headers {
+		se.Content.Simple.Headers = append(se.Content.Simple.Headers,
+			sesHeader{
+				Name:  k,
+				Value: v,
+			})
+	}
+
+	payload, err := json.Marshal(se)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost,
+		m.endpoint+"/v2/email/outbound-emails",
+		bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	m.sign(req, payload, time.Now())
+
+	return sendJSON(m.client, req)
+}
+
+// Verify checks that SES accepts the credentials by retrieving the account
+// details.
+//
+// Verify satisfies the mailer interface.
+func (m *sesMailer) Verify() error {
+	req, err := http.NewRequest(http.MethodGet,
+		m.endpoint+"/v2/email/account", nil)
+	if err != nil {
+		return err
+	}
+	m.sign(req, nil, time.Now())
+
+	return sendJSON(m.client, req)
+}
+
+// sendgridMailer sends emails through the Sendgrid v3 API.
+type sendgridMailer struct {
+	client   *http.Client
+	endpoint string // Sendgrid API endpoint
+	apiKey   string
+}
+
+// newSendgridMailer returns a mailer that sends emails through Sendgrid using
+// the provided API key.
+func newSendgridMailer(apiKey string) *sendgridMailer {
+	return &sendgridMailer{
+		client:   &http.Client{Timeout: mailerTimeout},
+		endpoint: sendgridEndpoint,
+		apiKey:   apiKey,
+	}
+}
+
+type sendgridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendgridPersonalization struct {
+	To []sendgridAddress `json:"to"`
+}
+
+type sendgridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendgridSend struct {
+	Personalizations []sendgridPersonalization `json:"personalizations"`
+	From             sendgridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendgridContent         `json:"content"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+}
+
+// Send sends the provided email.
+//
+// Send satisfies the mailer interface.
+func (m *sendgridMailer) Send(e *emailMessage) error {
+	to := make([]sendgridAddress, 0, len(e.To))
+	for _, v := range e.To {
+		to = append(to, sendgridAddress{Email: v})
+	}
+	payload, err := json.Marshal(sendgridSend{
+		Personalizations: []sendgridPersonalization{{To: to}},
+		From: sendgridAddress{
+			Email: e.From,
+			Name:  e.Name,
+		},
+		Subject: e.Subject,
+		Content: []sendgridContent{{
+			Type:  "text/html",
+			Value: e.Body,
+		}},
+		Headers: e.Headers,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, m.endpoint+"/mail/send",
+		bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+
+	return sendJSON(m.client, req)
+}
+
+// Verify checks that Sendgrid accepts the API key by retrieving its scopes.
+//
+// Verify satisfies the mailer interface.
+func (m *sendgridMailer) Verify() error {
+	req, err := http.NewRequest(http.MethodGet, m.endpoint+"/scopes",
+		nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+
+	return sendJSON(m.client, req)
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestEmailMessage returns the email that the mailer tests send.
+func newTestEmailMessage() *emailMessage {
+	msg := newEmailMessage("a@example.com", "subject", "<div>body</div>")
+	msg.Headers = map[string]string{
+		"List-Unsubscribe": "<https://example.com/unsubscribe>",
+	}
+	return msg
+}
+
+// mailerRequest is a request that a test email provider received.
+type mailerRequest struct {
+	method  string
+	path    string
+	headers http.Header
+	body    string
+}
+
+// newTestMailerServer returns an email provider that records the requests it
+// receives on the returned channel and replies with the provided status.
+func newTestMailerServer(t *testing.T, status int) (*httptest.Server, chan mailerRequest) {
+	requests := make(chan mailerRequest, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			requests <- mailerRequest{
+				method:  r.Method,
+				path:    r.URL.Path,
+				headers: r.Header,
+				body:    string(body),
+			}
+			w.WriteHeader(status)
+			w.Write([]byte("{}"))
+		}))
+	return server, requests
+}
+
+func TestSESMailerSign(t *testing.T) {
+	m := newSESMailer("us-east-1", "AKIDEXAMPLE",
+		"wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	if m.endpoint != "https://email.us-east-1.amazonaws.com" {
+		t.Fatalf("unexpected endpoint %v", m.endpoint)
+	}
+
+	payload := []byte(`{"FromEmailAddress":"Politeia <noreply@decred.org>"}`)
+	req, err := http.NewRequest(http.MethodPost,
+		m.endpoint+"/v2/email/outbound-emails", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.sign(req, payload, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/" +
+		"us-east-1/ses/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=cd80e7000516628964c2bf89bd2ba4cc285ffa7a80ffde5" +
+		"97e760cfe064a9e05"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestSESMailer(t *testing.T) {
+	server, requests := newTestMailerServer(t, http.StatusOK)
+	defer server.Close()
+
+	m := newSESMailer("us-east-1", "AKIDEXAMPLE",
+		"wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	m.client = server.Client()
+	m.endpoint = server.URL
+
+	err := m.Send(newTestEmailMessage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-requests
+	if r.method != http.MethodPost || r.path != "/v2/email/outbound-emails" {
+		t.Fatalf("unexpected request %v %v", r.method, r.path)
+	}
+	if r.headers.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected content type %v",
+			r.headers.Get("Content-Type"))
+	}
+	prefix := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/" +
+		r.headers.Get("X-Amz-Date")[:8] + "/us-east-1/ses/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, Signature="
+	if !strings.HasPrefix(r.headers.Get("Authorization"), prefix) {
+		t.Fatalf("unexpected authorization %v",
+			r.headers.Get("Authorization"))
+	}
+	// The HTML characters are escaped by the JSON encoding.
+	want := `{"FromEmailAddress":"Politeia \u003cnoreply@decred.org\u003e",` +
+		`"Destination":{"ToAddresses":["a@example.com"]},` +
+		`"Content":{"Simple":{"Subject":{"Data":"subject"},` +
+		`"Body":{"Html":{"Data":"\u003cdiv\u003ebody\u003c/div\u003e"}},` +
+		`"Headers":[{"Name":"List-Unsubscribe",` +
+		`"Value":"\u003chttps://example.com/unsubscribe\u003e"}]}}}`
+	if r.body != want {
+		t.Fatalf("got %v, want %v", r.body, want)
+	}
+
+	err = m.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = <-requests
+	if r.method != http.MethodGet || r.path != "/v2/email/account" {
+		t.Fatalf("unexpected request %v %v", r.method, r.path)
+	}
+
+	// Errors of the provider are returned.
+	failing, requests := newTestMailerServer(t, http.StatusBadRequest)
+	defer failing.Close()
+	m.client = failing.Client()
+	m.endpoint = failing.URL
+	err = m.Send(newTestEmailMessage())
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	<-requests
+}
+
+func TestSendgridMailer(t *testing.T) {
+	server, requests := newTestMailerServer(t, http.StatusAccepted)
+	defer server.Close()
+
+	m := newSendgridMailer("SG.apikey")
+	if m.endpoint != "https://api.sendgrid.com/v3" {
+		t.Fatalf("unexpected endpoint %v", m.endpoint)
+	}
+	m.client = server.Client()
+	m.endpoint = server.URL + "/v3"
+
+	err := m.Send(newTestEmailMessage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-requests
+	if r.method != http.MethodPost || r.path != "/v3/mail/send" {
+		t.Fatalf("unexpected request %v %v", r.method, r.path)
+	}
+	if r.headers.Get("Authorization") != "Bearer SG.apikey" ||
+		r.headers.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected headers %v", r.headers)
+	}
+	want := `{"personalizations":[{"to":[{"email":"a@example.com"}]}],` +
+		`"from":{"email":"noreply@decred.org","name":"Politeia"},` +
+		`"subject":"subject",` +
+		`"content":[{"type":"text/html",` +
+		`"value":"\u003cdiv\u003ebody\u003c/div\u003e"}],` +
+		`"headers":{"List-Unsubscribe":` +
+		`"\u003chttps://example.com/unsubscribe\u003e"}}`
+	if r.body != want {
+		t.Fatalf("got %v, want %v", r.body, want)
+	}
+
+	err = m.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = <-requests
+	if r.method != http.MethodGet || r.path != "/v3/scopes" ||
+		r.headers.Get("Authorization") != "Bearer SG.apikey" {
+		t.Fatalf("unexpected request %v %v", r.method, r.path)
+	}
+
+	// Errors of the provider are returned.
+	failing, requests := newTestMailerServer(t, http.StatusUnauthorized)
+	defer failing.Close()
+	m.client = failing.Client()
+	m.endpoint = failing.URL + "/v3"
+	err = m.Verify()
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	<-requests
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+// rawHTML matches HTML tags and comments in markdown.  Autolinks such as
+// <https://decred.org> are not HTML.
+var rawHTML = regexp.MustCompile(`<!--|</?[A-Za-z][A-Za-z0-9-]*(\s[^<>]*)?/?>`)
+
+// markdownError returns the error of a markdown file that breaks the
+// structure of proposals.  The error context holds the file name, the reason
+// and the line of the offending content.
+func markdownError(name, reason string, line int) error {
+	return www.UserError{
+		ErrorCode:    www.ErrorStatusMalformedMarkdown,
+		ErrorContext: []string{name, reason, strconv.Itoa(line)},
+	}
+}
+
+// validateMarkdown checks that the provided markdown file of a proposal is
+// UTF-8 text.  Raw HTML is rejected as well if rejectHTML is set.  The index
+// file must start with the title of the proposal.
+func validateMarkdown(name string, data []byte, rejectHTML bool) error {
+	if name == indexFile && len(bytes.TrimSpace(firstLine(data))) == 0 {
+		return markdownError(name, www.MarkdownReasonMissingTitle, 1)
+	}
+
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if !utf8.Valid(line) {
+			return markdownError(name, www.MarkdownReasonInvalidUTF8, i+1)
+		}
+
+		// Text may only contain the tab and the carriage return of all
+		// the control characters.
+		for _, c := range line {
+			if c < 0x20 && c != '\t' && c != '\r' || c == 0x7f {
+				return markdownError(name, www.MarkdownReasonBinary,
+					i+1)
+			}
+		}
+
+		if rejectHTML && rawHTML.Match(line) {
+			return markdownError(name, www.MarkdownReasonHTML, i+1)
+		}
+	}
+
+	return nil
+}
+
+// firstLine returns the first line of the provided text.
+func firstLine(data []byte) []byte {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return data[:i]
+	}
+	return data
+}
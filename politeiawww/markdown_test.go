@@ -0,0 +1,51 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestValidateMarkdown(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		rejectHTML bool
+		reason     string
+		line       string
+	}{
+		{indexFile, "A valid proposal\n\nSome *text*.\r\n", true, "", ""},
+		{indexFile, "\nA late title", false, www.MarkdownReasonMissingTitle,
+			"1"},
+		{indexFile, "Title\nbad \xff byte", false,
+			www.MarkdownReasonInvalidUTF8, "2"},
+		{indexFile, "Title\n\x00\x01\x02", false, www.MarkdownReasonBinary,
+			"2"},
+		{indexFile, "Title\n\n<script>alert(1)</script>", true,
+			www.MarkdownReasonHTML, "3"},
+		{indexFile, "Title\n\n<script>alert(1)</script>", false, "", ""},
+		{indexFile, "Title\n<https://decred.org>", true, "", ""},
+		{"other.md", "\nNo title needed", false, "", ""},
+	}
+	for _, test := range tests {
+		err := validateMarkdown(test.name, []byte(test.data),
+			test.rejectHTML)
+		if test.reason == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected error %v", test.data, err)
+			}
+			continue
+		}
+		ue, ok := err.(www.UserError)
+		want := []string{test.name, test.reason, test.line}
+		if !ok || ue.ErrorCode != www.ErrorStatusMalformedMarkdown ||
+			!reflect.DeepEqual(ue.ErrorContext, want) {
+			t.Errorf("%q: got %v, want context %v", test.data, err, want)
+		}
+	}
+}
@@ -2,8 +2,12 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httputil"
+	"runtime/debug"
+	"strings"
+	"time"
 
 	"github.com/decred/politeia/politeiawww/api/v1"
 	"github.com/decred/politeia/util"
@@ -74,7 +78,125 @@ func logging(f http.HandlerFunc) http.HandlerFunc {
 		}))
 
 		// Log incoming connection
-		log.Infof("%v %v %v %v", remoteAddr(r), r.Method, r.URL, r.Proto)
+		log.Infof("reqid=%v remote=%v method=%v url=%v proto=%v",
+			util.RequestID(r.Context()), remoteAddr(r), r.Method, r.URL,
+			r.Proto)
+		f(w, r)
+	}
+}
+
+// redirectToHTTPS redirects plain http requests to the same URL over https.
+func redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(),
+		http.StatusMovedPermanently)
+}
+
+// withRequestID assigns a request ID to the incoming request before calling
+// the next function.  A valid request ID that was provided by the client is
+// reused.  The request ID is returned to the client, logged and forwarded to
+// politeiad.
+func withRequestID(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := util.RequestIDFromHTTP(r)
+		w.Header().Set(util.RequestIDHeader, id)
+		f(w, r.WithContext(util.WithRequestID(r.Context(), id)))
+	}
+}
+
+// recoverPanic recovers from a panic in the next function so that it doesn't
+// take down politeiawww.  The panic is logged along with its stack trace, an
+// internal error is returned to the client and the panic is forwarded to the
+// error reporter when one is configured.
+func (p *politeiawww) recoverPanic(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				// Let net/http abort the response.
+				panic(rec)
+			}
+
+			errorCode := time.Now().Unix()
+			stack := debug.Stack()
+			log.Criticalf("reqid=%v %v %v %v %v Internal error %v: "+
+				"panic: %v\nStacktrace: %s", util.RequestID(r.Context()),
+				remoteAddr(r), r.Method, r.URL, r.Proto, errorCode, rec,
+				stack)
+
+			if p.reporter != nil {
+				p.reporter.Report(errorReport{
+					RequestID: util.RequestID(r.Context()),
+					ErrorCode: errorCode,
+					Method:    r.Method,
+					URL:       r.URL.String(),
+					Remote:    remoteAddr(r),
+					Message:   fmt.Sprintf("panic: %v", rec),
+					Stack:     stack,
+					Timestamp: time.Unix(errorCode, 0),
+				})
+			}
+
+			util.RespondWithJSON(w, http.StatusInternalServerError,
+				v1.ErrorReply{
+					ErrorCode: errorCode,
+				})
+		}()
+
+		f(w, r)
+	}
+}
+
+// maxRequestBodySize returns the maximum size in bytes of the request body
+// of the provided route.  The limits are derived from the configured policy
+// and leave room for the base64 encoding of files and for the JSON encoding.
+func maxRequestBodySize(cfg *config, route string) int64 {
+	const overhead = 64 * 1024
+
+	switch route {
+	case v1.RouteNewProposal, v1.RouteEditProposal,
+		v1.RouteImportProposal, v1.RouteNewDraft, v1.RouteEditDraft:
+		// Drafts hold the same files as proposals.
+		files := int64(cfg.MaxProposalImages)*
+			int64(cfg.MaxProposalImageSize) +
+			int64(cfg.MaxProposalMDs)*int64(cfg.MaxProposalMDSize)
+		if route == v1.RouteImportProposal {
+			// Bundles also hold the metadata of the proposal.
+			files += 1024 * 1024
+		}
+		return files*4/3 + overhead
+	case v1.RouteNewComment:
+		// A character takes up to 4 bytes in UTF-8.
+		return 4*int64(cfg.MaxCommentLength) + overhead
+	case v1.RouteCastVotes, v1.RouteStartVote:
+		// Ballots and vote eligibility span many tickets.
+		return 16 * 1024 * 1024
+	case v1.RouteSESWebhook, v1.RouteSendgridWebhook:
+		// Providers batch events.
+		return 1024 * 1024
+	}
+	return overhead
+}
+
+// limitBody rejects requests whose body exceeds the provided size before
+// calling the next function.  Requests that don't announce their size fail
+// to decode once the limit is reached.
+func limitBody(maxSize int64, f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxSize {
+			log.Debugf("limitBody: %v %v body too large: %v > %v",
+				remoteAddr(r), r.URL, r.ContentLength, maxSize)
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge),
+				http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
 		f(w, r)
 	}
 }
@@ -87,6 +209,42 @@ func closeBody(f http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// clientIP returns the IP address of the client.  The address is only taken
+// from the proxy header in proxy mode or when a replica forwarded the request
+// since clients can forge the header.
+func (p *politeiawww) clientIP(r *http.Request) net.IP {
+	addr := r.RemoteAddr
+	trusted := p.cfg.Proxy || validClusterToken(p.cfg, r)
+	if xff := r.Header.Get(v1.Forward); trusted && xff != "" {
+		// The proxy appends the address it received the request from.
+		hops := strings.Split(xff, ",")
+		return net.ParseIP(strings.TrimSpace(hops[len(hops)-1]))
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return net.ParseIP(host)
+}
+
+// checkIPBan rejects the requests of banned IP addresses before calling the
+// next function.
+func (p *politeiawww) checkIPBan(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ban := p.backend.ipBanned(p.clientIP(r))
+		if ban != nil {
+			log.Debugf("checkIPBan: %v banned by %v: %v",
+				remoteAddr(r), ban.Network, ban.Reason)
+			util.RespondWithJSON(w, http.StatusForbidden, v1.ErrorReply{
+				ErrorCode: int64(v1.ErrorStatusIPBanned),
+			})
+			return
+		}
+
+		f(w, r)
+	}
+}
+
 func remoteAddr(r *http.Request) string {
 	via := r.RemoteAddr
 	xff := r.Header.Get(v1.Forward)
@@ -98,7 +256,7 @@ func remoteAddr(r *http.Request) string {
 
 func (p *politeiawww) loadInventory(f http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if err := p.backend.LoadInventory(); err != nil {
+		if err := p.backend.LoadInventory(r.Context()); err != nil {
 			RespondWithError(w, r, 0,
 				"failed to get Load Inventory", err)
 			return
@@ -106,3 +264,35 @@ func (p *politeiawww) loadInventory(f http.HandlerFunc) http.HandlerFunc {
 		f(w, r)
 	}
 }
+
+// bearerToken returns the token of the Authorization header of the provided
+// request if it uses the bearer scheme.
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(auth[len(prefix):]), true
+}
+
+// csrfExempt bypasses the CSRF protection for the provided routes, which are
+// called by third parties that can't provide a CSRF token.  These routes must
+// authenticate their callers by other means.  Requests that carry a bearer
+// token are exempt as well since they are not authenticated by the session
+// cookie.
+func csrfExempt(protected, unprotected http.Handler, routes ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := bearerToken(r); ok {
+			unprotected.ServeHTTP(w, r)
+			return
+		}
+		for _, route := range routes {
+			if r.URL.Path == v1.PoliteiaWWWAPIRoute+route {
+				unprotected.ServeHTTP(w, r)
+				return
+			}
+		}
+		protected.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,441 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// oidcTimeout is the timeout of the requests to the OpenID Connect
+	// provider.
+	oidcTimeout = 10 * time.Second
+
+	// oidcStateExpiry is the time a user has to complete a federated
+	// login.
+	oidcStateExpiry = 10 * time.Minute
+
+	// oidcStateSize is the size of the state and nonce of authorization
+	// requests in bytes.
+	oidcStateSize = 16
+
+	// oidcMaxResponseSize is the maximum size of the responses of the
+	// provider.
+	oidcMaxResponseSize = 1 << 20
+
+	// sessionOIDCState is the session key of the state of the
+	// authorization request that the browser started.  Callbacks are only
+	// accepted from the browser that holds the state, so a victim can't be
+	// logged into the account of an attacker who started the request.
+	sessionOIDCState = "oidcstate"
+)
+
+// oidcProvider is the OpenID Connect provider that users may log in with.
+// Only the authorization code flow is supported.  The endpoints are
+// discovered on first use.
+type oidcProvider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	client       *http.Client
+
+	sync.Mutex
+	authEndpoint  string // Authorization endpoint, set by discover
+	tokenEndpoint string // Token endpoint, set by discover
+}
+
+// oidcClaims are the ID token claims that are used to identify users.
+type oidcClaims struct {
+	Issuer   string          `json:"iss"`
+	Subject  string          `json:"sub"`
+	Audience json.RawMessage `json:"aud"`
+	Expiry   int64           `json:"exp"`
+	Nonce    string          `json:"nonce"`
+	Email    string          `json:"email"`
+}
+
+// oidcState is the state of an authorization request that is kept in the
+// state store until the provider redirects back.
+type oidcState struct {
+	Nonce  string `json:"nonce"`  // Nonce of the ID token
+	UserID string `json:"userid"` // User to link the account to, empty for logins
+}
+
+// newOIDCProvider returns the provider that is configured by the provided
+// config.
+func newOIDCProvider(cfg *config) *oidcProvider {
+	return &oidcProvider{
+		issuer:       strings.TrimSuffix(cfg.OIDCIssuer, "/"),
+		clientID:     cfg.OIDCClientID,
+		clientSecret: cfg.OIDCClientSecret,
+		redirectURL:  cfg.OIDCRedirectURL,
+		client:       &http.Client{Timeout: oidcTimeout},
+	}
+}
+
+// oidcStateKey returns the state store key of the provided authorization
+// request state.
+func oidcStateKey(state string) string {
+	return "oidc:" + state
+}
+
+// do sends the provided request and decodes the JSON response into the
+// provided reply.
+func (o *oidcProvider) do(req *http.Request, reply interface{}) error {
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body,
+		oidcMaxResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v %v: %v %s", req.Method, req.URL,
+			resp.Status, body)
+	}
+
+	return json.Unmarshal(body, reply)
+}
+
+// discover fetches the endpoints of the provider unless they are known.
+func (o *oidcProvider) discover() error {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.tokenEndpoint != "" {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet,
+		o.issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return err
+	}
+	var doc struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+	}
+	err = o.do(req, &doc)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != o.issuer ||
+		doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" {
+		return fmt.Errorf("invalid provider configuration: %+v", doc)
+	}
+	o.authEndpoint = doc.AuthorizationEndpoint
+	o.tokenEndpoint = doc.TokenEndpoint
+
+	return nil
+}
+
+// authURL returns the URL that starts an authorization request with the
+// provided state and nonce.
+func (o *oidcProvider) authURL(state, nonce string) (string, error) {
+	err := o.discover()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(o.authEndpoint)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", o.clientID)
+	q.Set("redirect_uri", o.redirectURL)
+	q.Set("scope", "openid email")
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// exchange redeems the provided authorization code and returns the claims of
+// the ID token.  The token is received directly from the provider over TLS so
+// its signature isn't checked, as allowed by OpenID Connect Core 3.1.3.7.
+func (o *oidcProvider) exchange(code, nonce string) (*oidcClaims, error) {
+	err := o.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.redirectURL)
+	req, err := http.NewRequest(http.MethodPost, o.tokenEndpoint,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(o.clientID),
+		url.QueryEscape(o.clientSecret))
+	var reply struct {
+		IDToken string `json:"id_token"`
+	}
+	err = o.do(req, &reply)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(reply.IDToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid id token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %v", err)
+	}
+	var claims oidcClaims
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %v", err)
+	}
+
+	// The audience is either a string or an array of strings.
+	var audience []string
+	if json.Unmarshal(claims.Audience, &audience) != nil {
+		var aud string
+		if json.Unmarshal(claims.Audience, &aud) != nil {
+			return nil, fmt.Errorf("invalid id token audience")
+		}
+		audience = []string{aud}
+	}
+	validAudience := false
+	for _, v := range audience {
+		if v == o.clientID {
+			validAudience = true
+		}
+	}
+
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != o.issuer:
+		return nil, fmt.Errorf("invalid id token issuer: %v",
+			claims.Issuer)
+	case !validAudience:
+		return nil, fmt.Errorf("invalid id token audience: %v",
+			audience)
+	case time.Now().Unix() >= claims.Expiry:
+		return nil, fmt.Errorf("id token expired")
+	case claims.Nonce != nonce:
+		return nil, fmt.Errorf("invalid id token nonce")
+	case claims.Subject == "":
+		return nil, fmt.Errorf("id token without subject")
+	}
+
+	return &claims, nil
+}
+
+// ProcessOIDCAuth starts a federated login.  The provider account is linked
+// to the provided user instead if one is provided.  The returned state must be
+// stored in the session of the client and provided to ProcessOIDCCallback.
+func (b *backend) ProcessOIDCAuth(user *database.User) (*www.OIDCAuthReply, string, error) {
+	if b.oidc == nil {
+		return nil, "", www.UserError{
+			ErrorCode: www.ErrorStatusFeatureDisabled,
+		}
+	}
+
+	state, err := util.Random(oidcStateSize)
+	if err != nil {
+		return nil, "", err
+	}
+	nonce, err := util.Random(oidcStateSize)
+	if err != nil {
+		return nil, "", err
+	}
+	s := oidcState{
+		Nonce: hex.EncodeToString(nonce),
+	}
+	if user != nil {
+		s.UserID = strconv.FormatUint(user.ID, 10)
+	}
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return nil, "", err
+	}
+	err = b.stateStore.Set(oidcStateKey(hex.EncodeToString(state)), payload,
+		oidcStateExpiry)
+	if err != nil {
+		return nil, "", err
+	}
+
+	u, err := b.oidc.authURL(hex.EncodeToString(state), s.Nonce)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &www.OIDCAuthReply{
+		URL: u,
+	}, hex.EncodeToString(state), nil
+}
+
+// ProcessOIDCCallback completes a federated login or link.  It returns the
+// user and whether the user is logging in.  The provided session state must be
+// the state of the request, which ties the callback to the client that started
+// the request.  Links also require the provided session user to be the user
+// that started the link.
+func (b *backend) ProcessOIDCCallback(sessionUser *database.User, sessionState string, c www.OIDCCallback) (*database.User, bool, error) {
+	if b.oidc == nil {
+		return nil, false, www.UserError{
+			ErrorCode: www.ErrorStatusFeatureDisabled,
+		}
+	}
+
+	if sessionState == "" || subtle.ConstantTimeCompare([]byte(sessionState),
+		[]byte(c.State)) != 1 {
+		return nil, false, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidOIDCLogin,
+		}
+	}
+
+	// The state can only be used once.
+	key := oidcStateKey(c.State)
+	payload, err := b.stateStore.Get(key)
+	if err == errStateNotFound {
+		return nil, false, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidOIDCLogin,
+		}
+	} else if err != nil {
+		return nil, false, err
+	}
+	err = b.stateStore.Delete(key)
+	if err != nil {
+		return nil, false, err
+	}
+	var s oidcState
+	err = json.Unmarshal(payload, &s)
+	if err != nil {
+		return nil, false, err
+	}
+
+	claims, err := b.oidc.exchange(c.Code, s.Nonce)
+	if err != nil {
+		log.Debugf("ProcessOIDCCallback: exchange %v", err)
+		return nil, false, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidOIDCLogin,
+		}
+	}
+
+	if s.UserID != "" {
+		if sessionUser == nil ||
+			strconv.FormatUint(sessionUser.ID, 10) != s.UserID {
+			return nil, false, www.UserError{
+				ErrorCode: www.ErrorStatusInvalidOIDCLogin,
+			}
+		}
+		err = b.linkOIDCAccount(sessionUser, claims)
+		if err != nil {
+			return nil, false, err
+		}
+		return sessionUser, false, nil
+	}
+
+	user, err := b.db.UserGetByOIDCSubject(b.oidc.issuer, claims.Subject)
+	if err == database.ErrUserNotFound {
+		return nil, false, www.UserError{
+			ErrorCode: www.ErrorStatusOIDCAccountNotLinked,
+		}
+	} else if err != nil {
+		return nil, false, err
+	}
+	if user.Deactivated != 0 {
+		return nil, false, www.UserError{
+			ErrorCode: www.ErrorStatusUserDeactivated,
+		}
+	}
+
+	// The provider replaces the password but not the second factor.
+	err = b.verifyTOTP(user, c.TOTPCode)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return user, true, nil
+}
+
+// linkOIDCAccount links the provider account of the provided claims to the
+// provided user.  It replaces the account the user linked before.
+func (b *backend) linkOIDCAccount(user *database.User, claims *oidcClaims) error {
+	u, err := b.db.UserGetByOIDCSubject(b.oidc.issuer, claims.Subject)
+	if err == nil && u.ID != user.ID {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusOIDCAccountLinked,
+		}
+	} else if err != nil && err != database.ErrUserNotFound {
+		return err
+	}
+
+	identities := make([]database.OIDCIdentity, 0,
+		len(user.OIDCIdentities)+1)
+	for _, v := range user.OIDCIdentities {
+		if v.Issuer != b.oidc.issuer {
+			identities = append(identities, v)
+		}
+	}
+	user.OIDCIdentities = append(identities, database.OIDCIdentity{
+		Issuer:    b.oidc.issuer,
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		Timestamp: time.Now().Unix(),
+	})
+
+	return b.db.UserUpdate(*user)
+}
+
+// ProcessOIDCUnlink unlinks the provider account of the provided user.
+func (b *backend) ProcessOIDCUnlink(user *database.User) (*www.OIDCUnlinkReply, error) {
+	if b.oidc == nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusFeatureDisabled,
+		}
+	}
+
+	identities := make([]database.OIDCIdentity, 0,
+		len(user.OIDCIdentities))
+	for _, v := range user.OIDCIdentities {
+		if v.Issuer != b.oidc.issuer {
+			identities = append(identities, v)
+		}
+	}
+	if len(identities) == len(user.OIDCIdentities) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusOIDCAccountNotLinked,
+		}
+	}
+	user.OIDCIdentities = identities
+
+	err := b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.OIDCUnlinkReply{}, nil
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/util"
+)
+
+// newTestOIDCProvider returns a provider that issues ID tokens for the
+// account with the provided subject.  The authorization code is the nonce of
+// the authorization request.
+func newTestOIDCProvider(t *testing.T, subject *string) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			util.RespondWithJSON(w, http.StatusOK, map[string]string{
+				"issuer":                 srv.URL,
+				"authorization_endpoint": srv.URL + "/auth",
+				"token_endpoint":         srv.URL + "/token",
+			})
+		case "/token":
+			id, secret, ok := r.BasicAuth()
+			if !ok || id != "client" || secret != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			token, err := util.JWTSign(map[string]interface{}{
+				"iss":   srv.URL,
+				"sub":   *subject,
+				"aud":   "client",
+				"exp":   time.Now().Add(time.Minute).Unix(),
+				"nonce": r.FormValue("code"),
+				"email": "user@example.com",
+			}, []byte("key"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			json.NewEncoder(w).Encode(map[string]string{
+				"id_token": token,
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return srv
+}
+
+// oidcCallback returns the callback of the provided authorization URL as if
+// the user authenticated with the provider.
+func oidcCallback(t *testing.T, reply *www.OIDCAuthReply) www.OIDCCallback {
+	u, err := url.Parse(reply.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return www.OIDCCallback{
+		Code:  u.Query().Get("nonce"),
+		State: u.Query().Get("state"),
+	}
+}
+
+func TestOIDC(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	_, _, err := b.ProcessOIDCAuth(nil)
+	assertError(t, err, www.ErrorStatusFeatureDisabled)
+
+	subject := "account1"
+	srv := newTestOIDCProvider(t, &subject)
+	defer srv.Close()
+	b.oidc = newOIDCProvider(&config{
+		OIDCIssuer:       srv.URL,
+		OIDCClientID:     "client",
+		OIDCClientSecret: "secret",
+		OIDCRedirectURL:  "https://example.com/oidc",
+	})
+
+	nu, _ := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+
+	// Callbacks are only accepted from the client that started the
+	// request.
+	reply, state, err := b.ProcessOIDCAuth(nil)
+	assertSuccess(t, err)
+	_, _, err = b.ProcessOIDCCallback(nil, "", oidcCallback(t, reply))
+	assertError(t, err, www.ErrorStatusInvalidOIDCLogin)
+	_, otherState, err := b.ProcessOIDCAuth(nil)
+	assertSuccess(t, err)
+	_, _, err = b.ProcessOIDCCallback(nil, otherState,
+		oidcCallback(t, reply))
+	assertError(t, err, www.ErrorStatusInvalidOIDCLogin)
+
+	// Unlinked accounts can't log in.
+	reply, state, err = b.ProcessOIDCAuth(nil)
+	assertSuccess(t, err)
+	_, _, err = b.ProcessOIDCCallback(nil, state, oidcCallback(t, reply))
+	assertError(t, err, www.ErrorStatusOIDCAccountNotLinked)
+
+	// Links require the user that started them.
+	reply, state, err = b.ProcessOIDCAuth(user)
+	assertSuccess(t, err)
+	c := oidcCallback(t, reply)
+	_, _, err = b.ProcessOIDCCallback(nil, state, c)
+	assertError(t, err, www.ErrorStatusInvalidOIDCLogin)
+
+	reply, state, err = b.ProcessOIDCAuth(user)
+	assertSuccess(t, err)
+	c = oidcCallback(t, reply)
+	_, login, err := b.ProcessOIDCCallback(user, state, c)
+	assertSuccess(t, err)
+	if login {
+		t.Fatalf("link logged in")
+	}
+
+	// States can only be used once.
+	_, _, err = b.ProcessOIDCCallback(user, state, c)
+	assertError(t, err, www.ErrorStatusInvalidOIDCLogin)
+
+	reply, state, err = b.ProcessOIDCAuth(nil)
+	assertSuccess(t, err)
+	u, login, err := b.ProcessOIDCCallback(nil, state, oidcCallback(t, reply))
+	assertSuccess(t, err)
+	if !login || u.ID != user.ID {
+		t.Fatalf("unexpected login of user %v", u)
+	}
+
+	// The account can't be linked to another user.
+	nu2, _ := createAndVerifyUser(t, b)
+	user2, err := b.db.UserGet(nu2.Email)
+	assertSuccess(t, err)
+	reply, state, err = b.ProcessOIDCAuth(user2)
+	assertSuccess(t, err)
+	_, _, err = b.ProcessOIDCCallback(user2, state, oidcCallback(t, reply))
+	assertError(t, err, www.ErrorStatusOIDCAccountLinked)
+
+	// Unlinked accounts can't log in anymore.
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	_, err = b.ProcessOIDCUnlink(user)
+	assertSuccess(t, err)
+	_, err = b.ProcessOIDCUnlink(user)
+	assertError(t, err, www.ErrorStatusOIDCAccountNotLinked)
+	reply, state, err = b.ProcessOIDCAuth(nil)
+	assertSuccess(t, err)
+	_, _, err = b.ProcessOIDCCallback(nil, state, oidcCallback(t, reply))
+	assertError(t, err, www.ErrorStatusOIDCAccountNotLinked)
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+// BackendProposalPagesVersion is the version of BackendProposalPages.
+const BackendProposalPagesVersion = 1
+
+// BackendProposalPages is the metadata stream that holds the order of the
+// markdown files of a proposal that has more than one.  It is overwritten
+// when the files are edited.
+type BackendProposalPages struct {
+	Version   uint64   `json:"version"`   // BackendProposalPages version
+	Timestamp int64    `json:"timestamp"` // Last change of the pages
+	Pages     []string `json:"pages"`     // Ordered markdown file names
+}
+
+// encodeBackendProposalPages encodes BackendProposalPages into a JSON byte
+// slice.
+func encodeBackendProposalPages(md BackendProposalPages) ([]byte, error) {
+	b, err := json.Marshal(md)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// decodeBackendProposalPages decodes a JSON byte slice into a
+// BackendProposalPages.
+func decodeBackendProposalPages(payload []byte) (*BackendProposalPages, error) {
+	var md BackendProposalPages
+
+	err := json.Unmarshal(payload, &md)
+	if err != nil {
+		return nil, err
+	}
+
+	return &md, nil
+}
+
+// pagesError returns the error of a page order that lists the provided file
+// wrongly for the provided reason.
+func pagesError(reason, name string) error {
+	return www.UserError{
+		ErrorCode:    www.ErrorStatusInvalidProposalPages,
+		ErrorContext: []string{reason, name},
+	}
+}
+
+// isMarkdownFile reports whether the provided proposal file is a page of the
+// proposal, as opposed to an image.
+func isMarkdownFile(mimeType string) bool {
+	return !strings.HasPrefix(mimeType, "image/")
+}
+
+// defaultProposalPages returns the markdown files of the provided files in
+// the default order: the index file first, then the other ones by name.
+func defaultProposalPages(files []www.File) []string {
+	var pages []string
+	for _, v := range files {
+		if isMarkdownFile(v.MIME) && v.Name != indexFile {
+			pages = append(pages, v.Name)
+		}
+	}
+	sort.Strings(pages)
+	return append([]string{indexFile}, pages...)
+}
+
+// proposalPages validates the page order that the author provided for the
+// provided files and returns the order of the pages.  The pages are in the
+// default order when the author didn't provide one.  The files are expected
+// to have been validated already.
+func proposalPages(files []www.File, pages []string) ([]string, error) {
+	if len(pages) == 0 {
+		return defaultProposalPages(files), nil
+	}
+
+	if pages[0] != indexFile {
+		return nil, pagesError(www.PagesReasonIndex, pages[0])
+	}
+	mds := make(map[string]bool, len(files))
+	for _, v := range files {
+		if isMarkdownFile(v.MIME) {
+			mds[v.Name] = true
+		}
+	}
+	listed := make(map[string]bool, len(pages))
+	for _, v := range pages {
+		if !mds[v] {
+			return nil, pagesError(www.PagesReasonNotFound, v)
+		}
+		if listed[v] {
+			return nil, pagesError(www.PagesReasonDuplicate, v)
+		}
+		listed[v] = true
+	}
+	for _, v := range files {
+		if isMarkdownFile(v.MIME) && !listed[v.Name] {
+			return nil, pagesError(www.PagesReasonMissing, v.Name)
+		}
+	}
+
+	return pages, nil
+}
+
+// proposalPagesStream returns the metadata stream that holds the provided
+// page order.
+func proposalPagesStream(pages []string, ts int64) (*pd.MetadataStream, error) {
+	md, err := encodeBackendProposalPages(BackendProposalPages{
+		Version:   BackendProposalPagesVersion,
+		Timestamp: ts,
+		Pages:     pages,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pd.MetadataStream{
+		ID:      mdStreamPages,
+		Payload: string(md),
+	}, nil
+}
+
+// recordPages returns the order of the pages of the provided record.  Records
+// without a page order have their pages in the default order, which is only
+// known when the record carries its files.
+func recordPages(p pd.Record) []string {
+	for _, v := range p.Metadata {
+		if v.ID != mdStreamPages {
+			continue
+		}
+		md, err := decodeBackendProposalPages([]byte(v.Payload))
+		if err != nil {
+			log.Errorf("could not decode pages '%v' token '%v': %v",
+				v.Payload, p.CensorshipRecord.Token, err)
+			break
+		}
+		return md.Pages
+	}
+
+	if len(p.Files) == 0 {
+		return nil
+	}
+	return defaultProposalPages(convertPropFilesFromPD(p.Files))
+}
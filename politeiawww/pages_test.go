@@ -0,0 +1,73 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestProposalPages(t *testing.T) {
+	md := "text/plain; charset=utf-8"
+	files := []www.File{
+		{Name: "budget.md", MIME: md},
+		{Name: indexFile, MIME: md},
+		{Name: "chart.png", MIME: "image/png"},
+		{Name: "appendix.md", MIME: md},
+	}
+
+	pages, err := proposalPages(files, nil)
+	assertSuccess(t, err)
+	expected := []string{indexFile, "appendix.md", "budget.md"}
+	if !reflect.DeepEqual(pages, expected) {
+		t.Fatalf("expected default pages %v, got %v", expected, pages)
+	}
+
+	expected = []string{indexFile, "budget.md", "appendix.md"}
+	pages, err = proposalPages(files, expected)
+	assertSuccess(t, err)
+	if !reflect.DeepEqual(pages, expected) {
+		t.Fatalf("expected pages %v, got %v", expected, pages)
+	}
+
+	_, err = proposalPages(files,
+		[]string{"budget.md", indexFile, "appendix.md"})
+	assertErrorWithContext(t, err, www.ErrorStatusInvalidProposalPages,
+		[]string{www.PagesReasonIndex, "budget.md"})
+
+	_, err = proposalPages(files,
+		[]string{indexFile, "chart.png", "budget.md", "appendix.md"})
+	assertErrorWithContext(t, err, www.ErrorStatusInvalidProposalPages,
+		[]string{www.PagesReasonNotFound, "chart.png"})
+
+	_, err = proposalPages(files,
+		[]string{indexFile, "budget.md", "budget.md", "appendix.md"})
+	assertErrorWithContext(t, err, www.ErrorStatusInvalidProposalPages,
+		[]string{www.PagesReasonDuplicate, "budget.md"})
+
+	_, err = proposalPages(files, []string{indexFile, "budget.md"})
+	assertErrorWithContext(t, err, www.ErrorStatusInvalidProposalPages,
+		[]string{www.PagesReasonMissing, "appendix.md"})
+}
+
+func TestNewProposalPages(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	u, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(u.Email)
+	assertSuccess(t, err)
+
+	np, npr, err := createNewProposalWithFiles(b, t, user, id, 3, 0)
+	assertSuccess(t, err)
+
+	publishProposal(b, npr.CensorshipRecord.Token, t, user, id)
+	pdr := getProposalDetails(b, npr.CensorshipRecord.Token, t)
+	if !reflect.DeepEqual(pdr.Proposal.Pages, defaultProposalPages(np.Files)) {
+		t.Fatalf("unexpected pages %v", pdr.Proposal.Pages)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+const (
+	// minEmailMatchLength is the minimum length of the local part of an
+	// email address that passwords must not contain.
+	minEmailMatchLength = 3
+)
+
+var (
+	// commonPasswords are passwords and words that attackers try first.
+	// Passwords are compared after leetspeak substitutions and without
+	// trailing digits and symbols.
+	commonPasswords = map[string]struct{}{
+		"123456": {}, "1234567": {}, "12345678": {}, "123456789": {},
+		"1234567890": {}, "111111": {}, "000000": {}, "654321": {},
+		"666666": {}, "121212": {}, "123123": {}, "696969": {},
+		"password": {}, "passw": {}, "passwd": {}, "pass": {},
+		"qwerty": {}, "qwertyuiop": {}, "asdf": {}, "asdfgh": {},
+		"asdfghjkl": {}, "zxcvbn": {}, "zxcvbnm": {}, "qazwsx": {},
+		"abc": {}, "abcd": {}, "abcdef": {}, "abcdefg": {},
+		"letmein": {}, "welcome": {}, "admin": {}, "administrator": {},
+		"login": {}, "master": {}, "root": {}, "secret": {},
+		"changeme": {}, "default": {}, "guest": {}, "test": {},
+		"iloveyou": {}, "monkey": {}, "dragon": {}, "football": {},
+		"baseball": {}, "soccer": {}, "hockey": {}, "basketball": {},
+		"sunshine": {}, "princess": {}, "shadow": {}, "superman": {},
+		"batman": {}, "michael": {}, "jennifer": {}, "jordan": {},
+		"hunter": {}, "ranger": {}, "buster": {}, "charlie": {},
+		"trustno": {}, "starwars": {}, "whatever": {}, "freedom": {},
+		"computer": {}, "internet": {}, "access": {}, "flower": {},
+		"cheese": {}, "killer": {}, "pepper": {}, "summer": {},
+		"winter": {}, "spring": {}, "autumn": {}, "hello": {},
+		"love": {}, "lovely": {}, "money": {}, "bitcoin": {},
+		"crypto": {}, "blockchain": {}, "decred": {}, "politeia": {},
+		"proposal": {}, "proposals": {},
+	}
+
+	// leetSubstitutions undoes the common leetspeak substitutions.
+	leetSubstitutions = strings.NewReplacer("@", "a", "4", "a", "8", "b",
+		"3", "e", "6", "g", "1", "i", "!", "i", "0", "o", "5", "s",
+		"$", "s", "7", "t", "+", "t", "2", "z")
+)
+
+// passwordCharsetSize returns the number of characters an attacker has to try
+// for each character of the provided password.
+func passwordCharsetSize(password string) int {
+	var lower, upper, digit, symbol, other bool
+	for _, c := range password {
+		switch {
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= '0' && c <= '9':
+			digit = true
+		case c < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0
+	if lower {
+		size += 26
+	}
+	if upper {
+		size += 26
+	}
+	if digit {
+		size += 10
+	}
+	if symbol {
+		size += 33
+	}
+	if other {
+		size += 100
+	}
+	return size
+}
+
+// passwordEntropy estimates the entropy of the provided password in bits.
+// Characters that repeat or continue a sequence of the previous character,
+// such as "aaa", "abc" or "321", are predictable and count as one bit.
+func passwordEntropy(password string) float64 {
+	bits := math.Log2(float64(passwordCharsetSize(password)))
+	runes := []rune(password)
+
+	var entropy float64
+	for k, c := range runes {
+		if k > 0 {
+			delta := c - runes[k-1]
+			if delta >= -1 && delta <= 1 {
+				entropy++
+				continue
+			}
+		}
+		entropy += bits
+	}
+	return entropy
+}
+
+// isCommonPassword returns true if the provided password is a common password
+// with optional leetspeak substitutions and trailing digits and symbols.
+func isCommonPassword(password string) bool {
+	p := strings.ToLower(password)
+	if _, ok := commonPasswords[p]; ok {
+		return true
+	}
+
+	// Strip the trailing digits and symbols before undoing the leetspeak
+	// so that "password123!" and "p@ssw0rd" both match.
+	base := strings.TrimRightFunc(p, func(c rune) bool {
+		return !unicode.IsLetter(c)
+	})
+	for _, v := range []string{base, leetSubstitutions.Replace(base),
+		leetSubstitutions.Replace(p)} {
+		if _, ok := commonPasswords[v]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// passwordContainsEmail returns true if the provided password contains the
+// provided email address or its local part.
+func passwordContainsEmail(password, email string) bool {
+	p := strings.ToLower(password)
+	email = strings.ToLower(email)
+	if email == "" {
+		return false
+	}
+	if strings.Contains(p, email) {
+		return true
+	}
+	local := email
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		local = email[:i]
+	}
+	return len(local) >= minEmailMatchLength && strings.Contains(p, local)
+}
+
+// validatePassword rejects passwords that are too short or too predictable,
+// or that contain the email address of the provided user.  The reason is
+// returned in the error context.
+func (b *backend) validatePassword(password, email string) error {
+	var reason string
+	switch {
+	case len(password) < www.PolicyPasswordMinChars:
+		reason = www.PasswordReasonTooShort
+	case passwordContainsEmail(password, email):
+		reason = www.PasswordReasonContainsEmail
+	case isCommonPassword(password):
+		reason = www.PasswordReasonCommon
+	case passwordEntropy(password) < www.PolicyPasswordMinEntropy:
+		reason = www.PasswordReasonTooWeak
+	default:
+		return nil
+	}
+
+	return www.UserError{
+		ErrorCode:    www.ErrorStatusMalformedPassword,
+		ErrorContext: []string{reason},
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestValidatePassword(t *testing.T) {
+	b := &backend{}
+
+	tests := []struct {
+		password string
+		reason   string // Empty if the password is valid
+	}{
+		{"Xk9#", www.PasswordReasonTooShort},
+		{"alice.smith2018", www.PasswordReasonContainsEmail},
+		{"ALICE.SMITH@EXAMPLE.COM", www.PasswordReasonContainsEmail},
+		{"password", www.PasswordReasonCommon},
+		{"Password2018!", www.PasswordReasonCommon},
+		{"p@ssw0rd", www.PasswordReasonCommon},
+		{"12345678", www.PasswordReasonCommon},
+		{"aaaaaaaaaaaa", www.PasswordReasonTooWeak},
+		{"abcdefghijklmnop", www.PasswordReasonTooWeak},
+		{"98765432", www.PasswordReasonTooWeak},
+		{"73019462", www.PasswordReasonTooWeak},
+		{"correct horse battery staple", ""},
+		{"Tr0ub4dor&3x", ""},
+		{"9f86d081884c7d65", ""},
+	}
+	for _, test := range tests {
+		err := b.validatePassword(test.password, "alice.smith@example.com")
+		if test.reason == "" {
+			if err != nil {
+				t.Errorf("%v: unexpected error %v", test.password, err)
+			}
+			continue
+		}
+		e, ok := err.(www.UserError)
+		if !ok || e.ErrorCode != www.ErrorStatusMalformedPassword ||
+			len(e.ErrorContext) != 1 || e.ErrorContext[0] != test.reason {
+			t.Errorf("%v: got %v, want %v", test.password, err,
+				test.reason)
+		}
+	}
+}
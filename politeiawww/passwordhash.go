@@ -0,0 +1,190 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// Password hashing algorithms.
+	passwordHashBcrypt   = "bcrypt"
+	passwordHashArgon2id = "argon2id"
+
+	// argon2idPrefix prefixes the argon2id hashes, which are encoded in the
+	// PHC string format.
+	argon2idPrefix = "$argon2id$"
+
+	// Sizes of the argon2id salts and keys in bytes.
+	argon2idSaltSize = 16
+	argon2idKeySize  = 32
+)
+
+// passwordHasher hashes the passwords of the users with the configured
+// algorithm and parameters.
+type passwordHasher interface {
+	// Hash returns the hash of the provided password.
+	Hash(password string) ([]byte, error)
+
+	// NeedsRehash returns true if the provided hash was not produced with
+	// the algorithm and the parameters of the hasher.
+	NeedsRehash(hash []byte) bool
+}
+
+// bcryptHasher is a passwordHasher that uses bcrypt with the provided cost.
+type bcryptHasher struct {
+	cost int
+}
+
+// Hash satisfies the passwordHasher interface.
+func (h bcryptHasher) Hash(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), h.cost)
+}
+
+// NeedsRehash satisfies the passwordHasher interface.
+func (h bcryptHasher) NeedsRehash(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err != nil || cost != h.cost
+}
+
+// argon2idHasher is a passwordHasher that uses argon2id with the provided
+// parameters.
+type argon2idHasher struct {
+	time    uint32 // Number of passes over the memory
+	memory  uint32 // Memory in KiB
+	threads uint8  // Degree of parallelism
+}
+
+// Hash satisfies the passwordHasher interface.
+func (h argon2idHasher) Hash(password string) ([]byte, error) {
+	salt, err := util.Random(argon2idSaltSize)
+	if err != nil {
+		return nil, err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.time, h.memory, h.threads,
+		argon2idKeySize)
+	return []byte(fmt.Sprintf("%vv=%v$m=%v,t=%v,p=%v$%v$%v",
+		argon2idPrefix, argon2.Version, h.memory, h.time, h.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key))), nil
+}
+
+// NeedsRehash satisfies the passwordHasher interface.
+func (h argon2idHasher) NeedsRehash(hash []byte) bool {
+	params, _, _, err := decodeArgon2id(hash)
+	return err != nil || params != h
+}
+
+// decodeArgon2id returns the parameters, the salt and the key of the
+// provided argon2id hash.
+func decodeArgon2id(hash []byte) (argon2idHasher, []byte, []byte, error) {
+	var (
+		h       argon2idHasher
+		version int
+	)
+	fields := strings.Split(strings.TrimPrefix(string(hash), argon2idPrefix),
+		"$")
+	if !bytes.HasPrefix(hash, []byte(argon2idPrefix)) || len(fields) != 4 {
+		return h, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+	_, err := fmt.Sscanf(fields[0], "v=%d", &version)
+	if err != nil {
+		return h, nil, nil, err
+	}
+	if version != argon2.Version {
+		return h, nil, nil, fmt.Errorf("unsupported argon2 version: %v",
+			version)
+	}
+	_, err = fmt.Sscanf(fields[1], "m=%d,t=%d,p=%d", &h.memory, &h.time,
+		&h.threads)
+	if err != nil {
+		return h, nil, nil, err
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(fields[2])
+	if err != nil {
+		return h, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(fields[3])
+	if err != nil {
+		return h, nil, nil, err
+	}
+	return h, salt, key, nil
+}
+
+// newPasswordHasher returns the passwordHasher that is configured by the
+// provided config.
+func newPasswordHasher(cfg *config) passwordHasher {
+	if cfg.PasswordHash == passwordHashArgon2id {
+		return argon2idHasher{
+			time:    cfg.Argon2Time,
+			memory:  cfg.Argon2Memory,
+			threads: cfg.Argon2Threads,
+		}
+	}
+	// Like bcrypt, use the default cost if none was configured.
+	cost := cfg.BcryptCost
+	if cost < bcrypt.MinCost {
+		cost = bcrypt.DefaultCost
+	}
+	return bcryptHasher{
+		cost: cost,
+	}
+}
+
+// comparePasswordHash returns errInvalidPassword if the provided password
+// doesn't match the provided hash.  Hashes of all algorithms are compared
+// with the parameters that are encoded in the hash so that passwords keep
+// working when the configuration changes.
+func comparePasswordHash(hash []byte, password string) error {
+	if !bytes.HasPrefix(hash, []byte(argon2idPrefix)) {
+		err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+		if err != nil {
+			return errInvalidPassword
+		}
+		return nil
+	}
+
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+	k := argon2.IDKey([]byte(password), salt, params.time, params.memory,
+		params.threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(k, key) != 1 {
+		return errInvalidPassword
+	}
+	return nil
+}
+
+// rehashPassword hashes the password of the provided user again if its hash
+// was not produced with the configured algorithm and parameters.  It must
+// only be called once the password was verified.  Failures are only logged
+// since the current hash remains valid.
+func (b *backend) rehashPassword(user *database.User, password string) {
+	if _, ok := b.authenticator.(localAuthenticator); !ok ||
+		!b.passwordHasher.NeedsRehash(user.HashedPassword) {
+		return
+	}
+
+	hashedPassword, err := b.hashPassword(password)
+	if err != nil {
+		log.Errorf("rehashPassword %v: %v", user.ID, err)
+		return
+	}
+	user.HashedPassword = hashedPassword
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		log.Errorf("rehashPassword %v: %v", user.ID, err)
+	}
+}
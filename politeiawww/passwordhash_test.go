@@ -0,0 +1,80 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHashers(t *testing.T) {
+	hashers := []passwordHasher{
+		bcryptHasher{cost: bcrypt.MinCost},
+		argon2idHasher{time: 1, memory: 64, threads: 1},
+	}
+	for _, h := range hashers {
+		hash, err := h.Hash("password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := comparePasswordHash(hash, "password"); err != nil {
+			t.Fatalf("%T: %v", h, err)
+		}
+		err = comparePasswordHash(hash, "wrong")
+		if err != errInvalidPassword {
+			t.Fatalf("%T: got %v, want %v", h, err, errInvalidPassword)
+		}
+		if h.NeedsRehash(hash) {
+			t.Fatalf("%T: hash needs rehash", h)
+		}
+	}
+
+	// Hashes with other parameters or algorithms need a rehash.
+	hash, err := hashers[1].Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hashers[0].NeedsRehash(hash) ||
+		!(argon2idHasher{time: 2, memory: 64, threads: 1}).NeedsRehash(hash) {
+		t.Fatalf("hash doesn't need rehash")
+	}
+}
+
+func TestRehashPasswordOnLogin(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, _ := createAndVerifyUser(t, b)
+	login := func() []byte {
+		_, err := b.ProcessLogin(www.Login{
+			Email:    nu.Email,
+			Password: nu.Password,
+		})
+		assertSuccess(t, err)
+		user, err := b.db.UserGet(nu.Email)
+		assertSuccess(t, err)
+		return user.HashedPassword
+	}
+
+	// Unchanged parameters don't rehash.
+	hash := login()
+	if !bytes.Equal(login(), hash) {
+		t.Fatalf("password was hashed again")
+	}
+
+	// The bcrypt hash is replaced by an argon2id hash that is used by the
+	// following logins.
+	b.passwordHasher = argon2idHasher{time: 1, memory: 64, threads: 1}
+	hash = login()
+	if !bytes.HasPrefix(hash, []byte(argon2idPrefix)) {
+		t.Fatalf("password was not hashed with argon2id: %s", hash)
+	}
+	if !bytes.Equal(login(), hash) {
+		t.Fatalf("password was hashed again")
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+const (
+	// week is the length of a week in seconds.
+	week = 7 * 24 * 60 * 60
+
+	// weekOffset is the time from the Unix epoch, a Thursday, to the
+	// first Monday.
+	weekOffset = 4 * 24 * 60 * 60
+)
+
+// proposalStats are the statistics of all proposals.
+type proposalStats struct {
+	statuses    map[www.PropStatusT]int // [status]proposals
+	submissions map[int64]int           // [week]proposals submitted
+	censorships map[int64]int           // [week]proposals censored
+}
+
+// newProposalStats returns empty proposal statistics.
+func newProposalStats() *proposalStats {
+	return &proposalStats{
+		statuses:    make(map[www.PropStatusT]int),
+		submissions: make(map[int64]int),
+		censorships: make(map[int64]int),
+	}
+}
+
+// startOfWeek returns the start of the week, Monday at 00:00 UTC, of the
+// provided Unix time.
+func startOfWeek(ts int64) int64 {
+	offset := (ts - weekOffset) % week
+	if offset < 0 {
+		offset += week
+	}
+	return ts - offset
+}
+
+// proposalSubmitted returns the submission time that the author metadata
+// stream of the provided record holds.  Records without one return the
+// provided fallback.
+func proposalSubmitted(p pd.Record, fallback int64) int64 {
+	for _, v := range p.Metadata {
+		if v.ID != mdStreamAuthor {
+			continue
+		}
+		md, err := decodeBackendProposalAuthor([]byte(v.Payload))
+		if err != nil {
+			// proposalAuthor logs the error.
+			break
+		}
+		return md.Timestamp
+	}
+
+	return fallback
+}
+
+// addProposalStats adds n times the contribution of the provided record to
+// the statistics of the proposals.
+//
+// This function must be called WITH the mutex held.
+func (b *backend) addProposalStats(s recordStats, n int) {
+	ps := b.proposalStats
+	ps.statuses[s.status] += n
+	if ps.statuses[s.status] == 0 {
+		delete(ps.statuses, s.status)
+	}
+
+	w := startOfWeek(s.submitted)
+	ps.submissions[w] += n
+	if ps.submissions[w] == 0 {
+		delete(ps.submissions, w)
+	}
+
+	if s.censored != 0 {
+		w = startOfWeek(s.censored)
+		ps.censorships[w] += n
+		if ps.censorships[w] == 0 {
+			delete(ps.censorships, w)
+		}
+	}
+}
+
+// ProcessProposalsStats returns the statistics of all proposals.
+func (b *backend) ProcessProposalsStats(ps www.ProposalsStats) *www.ProposalsStatsReply {
+	b.RLock()
+	defer b.RUnlock()
+
+	reply := www.ProposalsStatsReply{
+		Weeks: []www.ProposalsWeekStats{},
+	}
+	if b.proposalStats == nil {
+		return &reply
+	}
+
+	for status, count := range b.proposalStats.statuses {
+		reply.Proposals += uint64(count)
+		switch status {
+		case www.PropStatusNotReviewed:
+			reply.NotReviewed += uint64(count)
+		case www.PropStatusCensored:
+			reply.Censored += uint64(count)
+		case www.PropStatusPublic:
+			reply.Public += uint64(count)
+		case www.PropStatusLocked:
+			reply.Locked += uint64(count)
+		}
+	}
+
+	weeks := make(map[int64]*www.ProposalsWeekStats)
+	get := func(w int64) *www.ProposalsWeekStats {
+		ws, ok := weeks[w]
+		if !ok {
+			ws = &www.ProposalsWeekStats{Week: w}
+			weeks[w] = ws
+		}
+		return ws
+	}
+	for w, count := range b.proposalStats.submissions {
+		get(w).Submissions = uint64(count)
+	}
+	for w, count := range b.proposalStats.censorships {
+		get(w).Censorships = uint64(count)
+	}
+	for _, v := range weeks {
+		reply.Weeks = append(reply.Weeks, *v)
+	}
+	sort.Slice(reply.Weeks, func(i, j int) bool {
+		return reply.Weeks[i].Week < reply.Weeks[j].Week
+	})
+
+	return &reply
+}
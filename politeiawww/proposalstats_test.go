@@ -0,0 +1,64 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestStartOfWeek(t *testing.T) {
+	monday := time.Date(2018, 10, 15, 0, 0, 0, 0, time.UTC).Unix()
+	for _, v := range []int64{monday, monday + 1, monday + week - 1} {
+		if w := startOfWeek(v); w != monday {
+			t.Fatalf("expected week %v for %v, got %v", monday, v, w)
+		}
+	}
+	if w := startOfWeek(monday + week); w != monday+week {
+		t.Fatalf("expected week %v, got %v", monday+week, w)
+	}
+}
+
+func TestProposalsStats(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	psr := b.ProcessProposalsStats(www.ProposalsStats{})
+	if psr.Proposals != 0 || len(psr.Weeks) != 0 {
+		t.Fatalf("unexpected stats %v", *psr)
+	}
+
+	u, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(u.Email)
+	assertSuccess(t, err)
+
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	_, npr2, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	_, _, err = createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	publishProposal(b, npr.CensorshipRecord.Token, t, user, id)
+	censorProposal(b, npr2.CensorshipRecord.Token, t, user, id)
+
+	psr = b.ProcessProposalsStats(www.ProposalsStats{})
+	expected := www.ProposalsStatsReply{
+		Proposals:   3,
+		NotReviewed: 1,
+		Censored:    1,
+		Public:      1,
+		Weeks: []www.ProposalsWeekStats{{
+			Week:        startOfWeek(time.Now().Unix()),
+			Submissions: 3,
+			Censorships: 1,
+		}},
+	}
+	if !reflect.DeepEqual(*psr, expected) {
+		t.Fatalf("expected stats %v, got %v", expected, *psr)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/decred/politeia/decredplugin"
+)
+
+// ranking is a ranking of the vote options along with the number of votes
+// that ranked them that way.
+type ranking struct {
+	bits  []uint64 // Bits of the ranked options, most preferred first
+	votes uint64   // Number of votes with the ranking
+}
+
+// decodeRankings returns the rankings of the provided vote bits of a
+// ranked-choice vote.  Vote bits that don't decode are left out, as politeiad
+// rejects them.
+func decodeRankings(vote decredplugin.Vote, voteBits map[string]uint64) []ranking {
+	rankings := make([]ranking, 0, len(voteBits))
+	for voteBit, votes := range voteBits {
+		bits, err := vote.DecodeRanking(voteBit)
+		if err != nil {
+			continue
+		}
+		rankings = append(rankings, ranking{
+			bits:  bits,
+			votes: votes,
+		})
+	}
+	return rankings
+}
+
+// instantRunoff returns the id of the option that wins the provided
+// ranked-choice vote.  Each round, the votes go to the most preferred option
+// of every ranking that was not eliminated, and an option wins once it
+// receives the pass percentage of those votes.  Otherwise the options with
+// the fewest votes are eliminated.  It returns an empty string when no vote
+// ranks a remaining option or when all the remaining options tie.
+func instantRunoff(vote decredplugin.Vote, rankings []ranking) string {
+	vote.SetDefaults()
+
+	eliminated := make(map[uint64]bool, len(vote.Options))
+	for {
+		var continuing uint64
+		counts := make(map[uint64]uint64, len(vote.Options))
+		for _, r := range rankings {
+			for _, bits := range r.bits {
+				if !eliminated[bits] {
+					counts[bits] += r.votes
+					continuing += r.votes
+					break
+				}
+			}
+		}
+		if continuing == 0 {
+			return ""
+		}
+
+		var (
+			remaining int
+			fewest    uint64
+		)
+		for _, o := range vote.Options {
+			if eliminated[o.Bits] {
+				continue
+			}
+			if counts[o.Bits]*100 >=
+				continuing*uint64(vote.PassPercentage) {
+				return o.Id
+			}
+			if remaining == 0 || counts[o.Bits] < fewest {
+				fewest = counts[o.Bits]
+			}
+			remaining++
+		}
+
+		var last []uint64
+		for _, o := range vote.Options {
+			if !eliminated[o.Bits] && counts[o.Bits] == fewest {
+				last = append(last, o.Bits)
+			}
+		}
+		if len(last) == remaining {
+			return ""
+		}
+		for _, bits := range last {
+			eliminated[bits] = true
+		}
+	}
+}
+
+// tallyRankedChoiceVote counts the first preferences of the provided vote bits
+// of a ranked-choice vote for each of the vote options.  It returns the
+// results per option and the option that wins the instant runoff, which is nil
+// if there was none.
+func tallyRankedChoiceVote(vote decredplugin.Vote, voteBits map[string]uint64) ([]voteOptionResult, *voteOptionResult) {
+	rankings := decodeRankings(vote, voteBits)
+	results := make([]voteOptionResult, 0, len(vote.Options))
+	for _, o := range vote.Options {
+		var count uint64
+		for _, r := range rankings {
+			if r.bits[0] == o.Bits {
+				count += r.votes
+			}
+		}
+		results = append(results, voteOptionResult{
+			Id:          o.Id,
+			Description: o.Description,
+			Votes:       count,
+		})
+	}
+
+	id := instantRunoff(vote, rankings)
+	for i := range results {
+		if results[i].Id == id {
+			return results, &results[i]
+		}
+	}
+	return results, nil
+}
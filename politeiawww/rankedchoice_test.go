@@ -0,0 +1,85 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/decred/politeia/decredplugin"
+)
+
+func TestInstantRunoff(t *testing.T) {
+	vote := decredplugin.Vote{
+		Type:           decredplugin.VoteTypeRankedChoice,
+		Mask:           0x7,
+		PassPercentage: 51,
+		Options: []decredplugin.VoteOption{
+			{Id: "a", Description: "Option A", Bits: 0x1},
+			{Id: "b", Description: "Option B", Bits: 0x2},
+			{Id: "c", Description: "Option C", Bits: 0x4},
+		},
+	}
+	ballots := func(voteBits ...string) []decredplugin.CastVote {
+		castVotes := make([]decredplugin.CastVote, 0, len(voteBits))
+		for _, v := range voteBits {
+			castVotes = append(castVotes,
+				decredplugin.CastVote{VoteBit: v})
+		}
+		return castVotes
+	}
+
+	tests := []struct {
+		name      string
+		castVotes []decredplugin.CastVote
+		first     []uint64
+		winner    string
+	}{
+		{"majority", ballots("1", "1", "2,1"), []uint64{2, 1, 0}, "a"},
+		{"runoff", ballots("1", "1", "1", "2,4", "4,2", "4,2", "4"),
+			[]uint64{3, 1, 3}, "c"},
+		{"tie", ballots("1", "2"), []uint64{1, 1, 0}, ""},
+		{"invalid rankings", ballots("1,1", "8", "", "2"),
+			[]uint64{0, 1, 0}, "b"},
+		{"no votes", nil, []uint64{0, 0, 0}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, winner := tallyVote(vote, 0, test.castVotes)
+			for k, v := range results {
+				if v.Votes != test.first[k] {
+					t.Fatalf("unexpected results %v", results)
+				}
+			}
+			switch {
+			case winner == nil && test.winner != "":
+				t.Fatalf("expected winner %v", test.winner)
+			case winner != nil && winner.Id != test.winner:
+				t.Fatalf("unexpected winner %v", winner.Id)
+			}
+		})
+	}
+}
+
+func TestDecodeRanking(t *testing.T) {
+	vote := decredplugin.Vote{
+		Type: decredplugin.VoteTypeRankedChoice,
+		Options: []decredplugin.VoteOption{
+			{Id: "a", Bits: 0x1},
+			{Id: "b", Bits: 0x2},
+		},
+	}
+
+	ranking, err := vote.DecodeRanking("2,1")
+	assertSuccess(t, err)
+	if len(ranking) != 2 || ranking[0] != 0x2 || ranking[1] != 0x1 {
+		t.Fatalf("unexpected ranking %v", ranking)
+	}
+	for _, voteBit := range []string{"", "2,2", "4", "1,2,4", "x"} {
+		_, err = vote.DecodeRanking(voteBit)
+		if err == nil {
+			t.Fatalf("expected %q not to decode", voteBit)
+		}
+	}
+}
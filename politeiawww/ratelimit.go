@@ -0,0 +1,82 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/util"
+)
+
+// Actions that are rate limited per IP address.
+const (
+	rateLimitLogin              = "login"
+	rateLimitNewUser            = "newuser"
+	rateLimitRecoverAccount     = "recoveraccount"
+	rateLimitResetPassword      = "resetpassword"
+	rateLimitResendVerification = "resendverification"
+)
+
+// rateLimited records an attempt of the provided action by the provided IP
+// address.  It returns true and the time until the next attempt is allowed
+// if the address already made the configured number of attempts within the
+// rate limit window.  Attempts that are refused are not recorded.  The state
+// store errors are logged and don't limit the address.
+func (b *backend) rateLimited(ip net.IP, action string) (bool, time.Duration) {
+	if ip == nil || b.cfg.RateLimitBurst == 0 {
+		return false, 0
+	}
+
+	now := time.Now()
+	key := "ratelimit:" + action + ":" + ip.String()
+	hits, ok, err := b.stateStore.HitLimit(key, now, b.cfg.RateLimitWindow,
+		b.cfg.RateLimitBurst)
+	if err != nil {
+		log.Errorf("rateLimited: %v", err)
+		return false, 0
+	}
+	if ok {
+		return false, 0
+	}
+
+	// The oldest attempt within the window expires first.
+	oldest := now
+	for _, v := range hits {
+		if v.Before(oldest) {
+			oldest = v
+		}
+	}
+	return true, oldest.Add(b.cfg.RateLimitWindow).Sub(now)
+}
+
+// rateLimit rejects the provided action with 429 Too Many Requests once the
+// client IP address exceeded its rate limit.  Exceeding the rate limit counts
+// as abuse of politeiawww.
+func (p *politeiawww) rateLimit(action string, f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := p.clientIP(r)
+		limited, retry := p.backend.rateLimited(ip, action)
+		if limited {
+			log.Debugf("rateLimit: %v exceeded the %v rate limit",
+				remoteAddr(r), action)
+			p.backend.reportAbuse(ip, "exceeded the "+action+
+				" rate limit")
+
+			seconds := int64((retry + time.Second - 1) / time.Second)
+			w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+			util.RespondWithJSON(w, http.StatusTooManyRequests,
+				www.ErrorReply{
+					ErrorCode: int64(www.ErrorStatusRateLimited),
+				})
+			return
+		}
+
+		f(w, r)
+	}
+}
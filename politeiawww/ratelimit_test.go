@@ -0,0 +1,65 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	p := &politeiawww{
+		backend: createBackend(t),
+	}
+	p.cfg = p.backend.cfg
+	p.cfg.RateLimitBurst = 2
+	p.cfg.RateLimitWindow = time.Minute
+
+	var served int
+	handler := p.rateLimit(rateLimitLogin,
+		func(w http.ResponseWriter, r *http.Request) {
+			served++
+		})
+
+	request := func(remote string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		w := request("10.0.0.1:1234")
+		if w.Code != http.StatusOK {
+			t.Fatalf("attempt %v: got %v", i, w.Code)
+		}
+	}
+	w := request("10.0.0.1:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("got %v, want %v", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatalf("missing Retry-After header")
+	}
+
+	// Other addresses and other actions have their own limits.
+	w = request("10.0.0.2:1234")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", w.Code, http.StatusOK)
+	}
+	limited, _ := p.backend.rateLimited(net.ParseIP("10.0.0.1"),
+		rateLimitNewUser)
+	if limited {
+		t.Fatalf("unexpected rate limit")
+	}
+
+	if served != 3 {
+		t.Fatalf("served %v requests, want 3", served)
+	}
+}
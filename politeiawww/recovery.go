@@ -0,0 +1,176 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/badoux/checkmail"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+// recoveryChallengeKey returns the state store key of the recovery challenge
+// that the provided public key must sign.
+func recoveryChallengeKey(publicKey string) string {
+	return "recoverychallenge:" + publicKey
+}
+
+// ProcessRecoveryChallenge issues a single use challenge that the provided
+// public key must sign to recover the account of its user.  A challenge is
+// issued for any valid public key so that the reply doesn't reveal which
+// keys are registered.
+func (b *backend) ProcessRecoveryChallenge(rc www.RecoveryChallenge) (*www.RecoveryChallengeReply, error) {
+	pk, err := decodePublicKey(rc.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := util.Random(keyChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	err = b.stateStore.Set(recoveryChallengeKey(hex.EncodeToString(pk)),
+		challenge, keyChallengeExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.RecoveryChallengeReply{
+		Challenge: hex.EncodeToString(challenge),
+		Expiry:    time.Now().Add(keyChallengeExpiry).Unix(),
+	}, nil
+}
+
+// ProcessRecoverAccount sets a new password for the user of the provided
+// public key once the key signed the recovery challenge, and emails a
+// verification token to the new email address.  The email address of the
+// user is only replaced once the new one is verified with
+// ProcessVerifyChangeEmail.  All login sessions and API tokens of the user
+// are revoked and the current email address is notified.  It returns the
+// recovered user.
+func (b *backend) ProcessRecoverAccount(ra www.RecoverAccount) (*www.RecoverAccountReply, *database.User, error) {
+	var reply www.RecoverAccountReply
+
+	pk, err := decodePublicKey(ra.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey := hex.EncodeToString(pk)
+	err = b.verifyChallenge(recoveryChallengeKey(publicKey), pk,
+		ra.Challenge, ra.Signature)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Only the active keys of a user can recover the account.
+	user, err := b.db.UserGetByPubKey(publicKey)
+	if err == database.ErrUserNotFound {
+		return nil, nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidPublicKey,
+		}
+	} else if err != nil {
+		return nil, nil, err
+	}
+	var active bool
+	for _, v := range database.ActiveIdentities(user.Identities) {
+		if bytes.Equal(v[:], pk) {
+			active = true
+			break
+		}
+	}
+	if !active {
+		return nil, nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidPublicKey,
+		}
+	}
+	if user.Deactivated != 0 {
+		return nil, nil, www.UserError{
+			ErrorCode: www.ErrorStatusUserDeactivated,
+		}
+	}
+
+	// Validate the new email address and password.
+	newEmail := strings.ToLower(ra.NewEmail)
+	if err := checkmail.ValidateFormat(newEmail); err != nil {
+		return nil, nil, www.UserError{
+			ErrorCode: www.ErrorStatusMalformedEmail,
+		}
+	}
+	if newEmail != user.Email {
+		_, err = b.db.UserGet(newEmail)
+		if err == nil {
+			return nil, nil, www.UserError{
+				ErrorCode: www.ErrorStatusDuplicateEmail,
+			}
+		} else if err != database.ErrUserNotFound {
+			return nil, nil, err
+		}
+	}
+	err = b.validatePassword(ra.NewPassword, newEmail)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashedPassword, err := b.hashPassword(ra.NewPassword)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Set the new password and log out everyone that used the previous
+	// credentials.  Pending email changes and password resets are
+	// cancelled.  The new email address must be verified like any other
+	// email change so that a stolen key can't redirect the account to an
+	// address that nobody proved to own.
+	user.HashedPassword = hashedPassword
+	clearVerificationToken(user, verificationResetPassword)
+	user.NewEmail = ""
+	clearVerificationToken(user, verificationChangeEmail)
+	user.LoginSessions = nil
+	user.APITokens = nil
+	var token []byte
+	if newEmail != user.Email {
+		token, err = b.issueVerificationToken(user,
+			verificationChangeEmail)
+		if err != nil {
+			return nil, nil, err
+		}
+		user.NewEmail = newEmail
+	}
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Infof("User %v recovered the account with public key %v, email "+
+		"change to %v pending", user.ID, publicKey, user.NewEmail)
+
+	err = b.emailAccountRecovered(user.Email)
+	if err != nil {
+		log.Errorf("ProcessRecoverAccount: emailAccountRecovered %v: %v",
+			user.ID, err)
+	}
+
+	if token != nil {
+		if !b.test {
+			// This is conditional on the email server being setup.
+			err := b.emailChangeEmailVerificationLink(newEmail,
+				hex.EncodeToString(token))
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// Only set the token if email verification is disabled.
+		if b.cfg.Mailer == nil {
+			reply.VerificationToken = hex.EncodeToString(token)
+		}
+	}
+
+	return &reply, user, nil
+}
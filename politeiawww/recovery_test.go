@@ -0,0 +1,106 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestRecoverAccount(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	publicKey := id.Public.String()
+	ra := www.RecoverAccount{
+		PublicKey:   publicKey,
+		NewEmail:    generateRandomEmail(),
+		NewPassword: generateRandomPassword(),
+	}
+
+	// The key must sign an outstanding challenge.
+	_, _, err := b.ProcessRecoverAccount(ra)
+	assertError(t, err, www.ErrorStatusInvalidKeyChallenge)
+
+	otherID, err := generateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rcr, err := b.ProcessRecoveryChallenge(www.RecoveryChallenge{
+		PublicKey: publicKey,
+	})
+	assertSuccess(t, err)
+	ra.Challenge = rcr.Challenge
+	sig := otherID.SignMessage([]byte(rcr.Challenge))
+	ra.Signature = hex.EncodeToString(sig[:])
+	_, _, err = b.ProcessRecoverAccount(ra)
+	assertError(t, err, www.ErrorStatusInvalidSignature)
+
+	// Unknown keys get a challenge but can't recover anything.
+	rcr, err = b.ProcessRecoveryChallenge(www.RecoveryChallenge{
+		PublicKey: otherID.Public.String(),
+	})
+	assertSuccess(t, err)
+	sig = otherID.SignMessage([]byte(rcr.Challenge))
+	_, _, err = b.ProcessRecoverAccount(www.RecoverAccount{
+		PublicKey:   otherID.Public.String(),
+		Challenge:   rcr.Challenge,
+		Signature:   hex.EncodeToString(sig[:]),
+		NewEmail:    ra.NewEmail,
+		NewPassword: ra.NewPassword,
+	})
+	assertError(t, err, www.ErrorStatusInvalidPublicKey)
+
+	rcr, err = b.ProcessRecoveryChallenge(www.RecoveryChallenge{
+		PublicKey: publicKey,
+	})
+	assertSuccess(t, err)
+	ra.Challenge = rcr.Challenge
+	sig = id.SignMessage([]byte(rcr.Challenge))
+	ra.Signature = hex.EncodeToString(sig[:])
+	rar, user, err := b.ProcessRecoverAccount(ra)
+	assertSuccess(t, err)
+	if user.Email != nu.Email || user.NewEmail != ra.NewEmail {
+		t.Fatalf("email is %v, new email %v, expected %v and %v",
+			user.Email, user.NewEmail, nu.Email, ra.NewEmail)
+	}
+
+	// Challenges can only be used once.
+	_, _, err = b.ProcessRecoverAccount(ra)
+	assertError(t, err, www.ErrorStatusInvalidKeyChallenge)
+
+	// Only the new password logs in, and the email address isn't replaced
+	// until the new one is verified.
+	_, err = b.ProcessLogin(www.Login{
+		Email:    nu.Email,
+		Password: nu.Password,
+	})
+	assertError(t, err, www.ErrorStatusInvalidEmailOrPassword)
+	_, err = b.ProcessLogin(www.Login{
+		Email:    ra.NewEmail,
+		Password: ra.NewPassword,
+	})
+	assertError(t, err, www.ErrorStatusInvalidEmailOrPassword)
+	_, err = b.ProcessLogin(www.Login{
+		Email:    nu.Email,
+		Password: ra.NewPassword,
+	})
+	assertSuccess(t, err)
+
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	_, err = b.ProcessVerifyChangeEmail(user, www.VerifyChangeEmail{
+		VerificationToken: rar.VerificationToken,
+	})
+	assertSuccess(t, err)
+	_, err = b.ProcessLogin(www.Login{
+		Email:    ra.NewEmail,
+		Password: ra.NewPassword,
+	})
+	assertSuccess(t, err)
+}
@@ -0,0 +1,354 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// redisTimeout is the timeout of the connections and commands to the
+	// Redis server.
+	redisTimeout = 5 * time.Second
+
+	// redisMaxIdle is the number of idle connections to the Redis server
+	// that are kept open.
+	redisMaxIdle = 16
+)
+
+// redisHitLimitScript drops the expired hits of KEYS[1], which are the ones
+// scored up to ARGV[1], and adds the member ARGV[4] scored ARGV[3] unless
+// ARGV[2] hits remain.  The key then expires after ARGV[5] milliseconds.  It
+// returns whether the member was added and the remaining hits.
+const redisHitLimitScript = `
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+local hits = redis.call('ZRANGE', KEYS[1], 0, -1)
+if #hits >= tonumber(ARGV[2]) then
+	return {0, hits}
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[4])
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+return {1, hits}
+`
+
+// redisError is an error that the Redis server replied with.
+type redisError string
+
+// Error satisfies the error interface.
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisConn is a connection to the Redis server.
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// redisStore is a stateStore that keeps the state in a Redis server so that
+// it survives restarts and is shared by all politeiawww instances that use
+// the same server.  Hits are kept in sorted sets scored by milliseconds.
+type redisStore struct {
+	address  string
+	password string
+	db       int
+	idle     chan *redisConn
+}
+
+// newRedisStore returns a state store that uses the Redis server at the
+// provided address.  Connections are established on demand.
+func newRedisStore(address, password string, db int) *redisStore {
+	return &redisStore{
+		address:  address,
+		password: password,
+		db:       db,
+		idle:     make(chan *redisConn, redisMaxIdle),
+	}
+}
+
+// dial opens an authenticated connection to the Redis server.
+func (s *redisStore) dial() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", s.address, redisTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+	}
+
+	if s.password != "" {
+		_, err = c.do("AUTH", s.password)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.db != 0 {
+		_, err = c.do("SELECT", strconv.Itoa(s.db))
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// do runs the provided command on an idle connection or on a new one.
+func (s *redisStore) do(args ...string) (interface{}, error) {
+	var c *redisConn
+	select {
+	case c = <-s.idle:
+	default:
+		var err error
+		c, err = s.dial()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.do(args...)
+	if _, ok := err.(redisError); err != nil && !ok {
+		// The connection is in an unknown state.
+		c.conn.Close()
+		return nil, err
+	}
+
+	select {
+	case s.idle <- c:
+	default:
+		c.conn.Close()
+	}
+	return reply, err
+}
+
+// do sends the provided command and reads its reply.
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(redisTimeout))
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, v := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(v), v)
+	}
+	_, err := c.conn.Write(b.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return c.readReply()
+}
+
+// readLine reads a line of a reply without its terminator.
+func (c *redisConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", fmt.Errorf("redis: invalid reply line")
+	}
+	return line[:len(line)-2], nil
+}
+
+// readReply reads a reply.  Strings are returned as string, bulk strings as
+// []byte, integers as int64 and arrays as []interface{}.  Null replies are
+// returned as nil.
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		_, err = io.ReadFull(c.r, b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		a := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			v, err := c.readReply()
+			if _, ok := err.(redisError); err != nil && !ok {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		return a, nil
+	}
+	return nil, fmt.Errorf("redis: invalid reply type %q", line[0])
+}
+
+// redisKey returns the Redis key of the provided state store key.
+func redisKey(key string) string {
+	return "politeiawww:" + key
+}
+
+// milliseconds returns the provided duration in milliseconds, rounded up.
+func milliseconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Millisecond-1)/time.Millisecond),
+		10)
+}
+
+// Get satisfies the stateStore interface.
+func (s *redisStore) Get(key string) ([]byte, error) {
+	reply, err := s.do("GET", redisKey(key))
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, errStateNotFound
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected GET reply %T", reply)
+	}
+	return value, nil
+}
+
+// Set satisfies the stateStore interface.
+func (s *redisStore) Set(key string, value []byte, ttl time.Duration) error {
+	_, err := s.do("SET", redisKey(key), string(value), "PX",
+		milliseconds(ttl))
+	return err
+}
+
+// Delete satisfies the stateStore interface.
+func (s *redisStore) Delete(key string) error {
+	_, err := s.do("DEL", redisKey(key), redisKey(key)+":hits")
+	return err
+}
+
+// Hit satisfies the stateStore interface.
+func (s *redisStore) Hit(key string, t time.Time, ttl time.Duration) error {
+	// Members must be unique so they carry a random suffix.
+	nonce, err := util.Random(4)
+	if err != nil {
+		return err
+	}
+	k := redisKey(key) + ":hits"
+	ms := t.UnixNano() / int64(time.Millisecond)
+	member := strconv.FormatInt(t.UnixNano(), 10) + ":" +
+		hex.EncodeToString(nonce)
+
+	_, err = s.do("ZADD", k, strconv.FormatInt(ms, 10), member)
+	if err != nil {
+		return err
+	}
+	_, err = s.do("ZREMRANGEBYSCORE", k, "-inf",
+		strconv.FormatInt(t.Add(-ttl).UnixNano()/int64(time.Millisecond),
+			10))
+	if err != nil {
+		return err
+	}
+	_, err = s.do("PEXPIRE", k, milliseconds(ttl))
+	return err
+}
+
+// Hits satisfies the stateStore interface.
+func (s *redisStore) Hits(key string, since time.Time) ([]time.Time, error) {
+	ms := since.UnixNano() / int64(time.Millisecond)
+	reply, err := s.do("ZRANGEBYSCORE", redisKey(key)+":hits",
+		"("+strconv.FormatInt(ms, 10), "+inf")
+	if err != nil {
+		return nil, err
+	}
+	members, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected ZRANGEBYSCORE reply %T",
+			reply)
+	}
+
+	return parseRedisHits(members)
+}
+
+// parseRedisHits returns the times of the provided hit members.
+func parseRedisHits(members []interface{}) ([]time.Time, error) {
+	hits := make([]time.Time, 0, len(members))
+	for _, v := range members {
+		member, ok := v.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("redis: unexpected member %T", v)
+		}
+		i := bytes.IndexByte(member, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("redis: invalid member %s", member)
+		}
+		ns, err := strconv.ParseInt(string(member[:i]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid member %s", member)
+		}
+		hits = append(hits, time.Unix(0, ns))
+	}
+	return hits, nil
+}
+
+// HitLimit satisfies the stateStore interface.  The hits are counted and
+// recorded by a script so that concurrent hits, from this instance or from
+// others, can't exceed the limit.
+func (s *redisStore) HitLimit(key string, t time.Time, window time.Duration, limit int) ([]time.Time, bool, error) {
+	nonce, err := util.Random(4)
+	if err != nil {
+		return nil, false, err
+	}
+	ms := t.UnixNano() / int64(time.Millisecond)
+	expired := t.Add(-window).UnixNano() / int64(time.Millisecond)
+	member := strconv.FormatInt(t.UnixNano(), 10) + ":" +
+		hex.EncodeToString(nonce)
+
+	reply, err := s.do("EVAL", redisHitLimitScript, "1",
+		redisKey(key)+":hits", strconv.FormatInt(expired, 10),
+		strconv.Itoa(limit), strconv.FormatInt(ms, 10), member,
+		milliseconds(window))
+	if err != nil {
+		return nil, false, err
+	}
+	a, ok := reply.([]interface{})
+	if !ok || len(a) != 2 {
+		return nil, false, fmt.Errorf("redis: unexpected EVAL reply %v",
+			reply)
+	}
+	recorded, ok1 := a[0].(int64)
+	members, ok2 := a[1].([]interface{})
+	if !ok1 || !ok2 {
+		return nil, false, fmt.Errorf("redis: unexpected EVAL reply %v",
+			reply)
+	}
+	hits, err := parseRedisHits(members)
+	if err != nil {
+		return nil, false, err
+	}
+	return hits, recorded == 1, nil
+}
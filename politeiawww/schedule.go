@@ -0,0 +1,165 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+// publicationSchedulerInterval is the interval at which the scheduler
+// publishes the proposals whose publication time came.
+const publicationSchedulerInterval = time.Minute
+
+// schedulePublication records the approval of the provided admin to publish
+// an unvetted proposal at the time of the provided status change.  An earlier
+// schedule of the proposal is replaced.
+func (b *backend) schedulePublication(sps www.SetProposalStatus, admin *database.User) (*www.SetProposalStatusReply, error) {
+	if sps.ProposalStatus != www.PropStatusPublic ||
+		sps.PublishAt <= time.Now().Unix() {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidPublishTime,
+		}
+	}
+
+	b.RLock()
+	ir, ok := b.inventory[sps.Token]
+	b.RUnlock()
+	if !ok {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+	proposal := convertPropFromPD(ir.record)
+	if proposal.Status != www.PropStatusNotReviewed {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidPropStatusTransition,
+		}
+	}
+
+	payload, err := json.Marshal(sps)
+	if err != nil {
+		return nil, err
+	}
+	err = b.db.ScheduledPublicationSet(database.ScheduledPublication{
+		Token:     sps.Token,
+		AdminID:   admin.ID,
+		Payload:   payload,
+		PublishAt: sps.PublishAt,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Admin %v scheduled the publication of %v at %v", admin.ID,
+		sps.Token, time.Unix(sps.PublishAt, 0).UTC())
+
+	return &www.SetProposalStatusReply{
+		Proposal:  proposal,
+		PublishAt: sps.PublishAt,
+	}, nil
+}
+
+// scheduledPublications returns the times that the proposals are scheduled to
+// be published at by token.
+func (b *backend) scheduledPublications() (map[string]int64, error) {
+	publications, err := b.db.AllScheduledPublications()
+	if err != nil {
+		return nil, err
+	}
+
+	publishAt := make(map[string]int64, len(publications))
+	for _, v := range publications {
+		publishAt[v.Token] = v.PublishAt
+	}
+	return publishAt, nil
+}
+
+// publishScheduledProposals publishes the proposals whose publication time
+// came by the provided unix time.  The proposals are published as the admin
+// that approved them, provided that admin is still an admin.  Publications
+// that fail for a reason other than the proposal are retried.
+func (b *backend) publishScheduledProposals(ctx context.Context, now int64) error {
+	publications, err := b.db.AllScheduledPublications()
+	if err != nil {
+		return err
+	}
+
+	for _, v := range publications {
+		if v.PublishAt > now {
+			continue
+		}
+
+		// Removing the publication claims it.
+		err := b.db.ScheduledPublicationDelete(v.Token)
+		if err == database.ErrScheduledPublicationNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		admin, err := b.db.UserGetById(v.AdminID)
+		if err != nil {
+			return err
+		}
+		if !admin.Admin || admin.Deactivated != 0 {
+			log.Infof("Scheduled publication of %v dropped: admin %v "+
+				"lost its privileges", v.Token, admin.ID)
+			continue
+		}
+
+		var sps www.SetProposalStatus
+		err = json.Unmarshal(v.Payload, &sps)
+		if err != nil {
+			return err
+		}
+		_, err = b.setProposalStatus(ctx, sps, admin)
+		if err != nil {
+			if _, ok := err.(www.UserError); ok {
+				log.Infof("Scheduled publication of %v dropped: %v",
+					v.Token, err)
+				continue
+			}
+			log.Errorf("Scheduled publication of %v failed: %v",
+				v.Token, err)
+			err = b.db.ScheduledPublicationSet(v)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		log.Infof("Published %v as scheduled by admin %v", v.Token,
+			admin.ID)
+	}
+
+	return nil
+}
+
+// publicationScheduler periodically publishes the proposals whose
+// publication time came.  It returns once the quit channel is closed.
+func (b *backend) publicationScheduler() {
+	ticker := time.NewTicker(publicationSchedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.quit:
+			return
+		}
+
+		err := b.publishScheduledProposals(context.Background(),
+			time.Now().Unix())
+		if err != nil {
+			log.Errorf("publicationScheduler: %v", err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestScheduledPublication(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	admin, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	admin.Admin = true
+	assertSuccess(t, b.db.UserUpdate(*admin))
+	_, npr, err := createNewProposal(b, t, admin, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+
+	schedule := func(status www.PropStatusT, publishAt int64) (*www.SetProposalStatusReply, error) {
+		sps := www.SetProposalStatus{
+			Token:          token,
+			ProposalStatus: status,
+			PublicKey:      id.Public.String(),
+			PublishAt:      publishAt,
+		}
+		sps.Signature, err = getSignature([]byte(token+
+			strconv.FormatUint(uint64(status), 10)+
+			strconv.FormatInt(publishAt, 10)), id)
+		assertSuccess(t, err)
+		return b.ProcessSetProposalStatus(context.Background(), sps,
+			admin)
+	}
+
+	now := time.Now().Unix()
+	_, err = schedule(www.PropStatusPublic, now-1)
+	assertError(t, err, www.ErrorStatusInvalidPublishTime)
+	_, err = schedule(www.PropStatusCensored, now+3600)
+	assertError(t, err, www.ErrorStatusInvalidPublishTime)
+
+	reply, err := schedule(www.PropStatusPublic, now+3600)
+	assertSuccess(t, err)
+	if reply.PublishAt != now+3600 ||
+		reply.Proposal.Status != www.PropStatusNotReviewed {
+		t.Fatalf("unexpected reply %v", reply)
+	}
+	ur, err := b.ProcessAllUnvetted(www.GetAllUnvetted{}, admin)
+	assertSuccess(t, err)
+	if len(ur.Proposals) != 1 || ur.Proposals[0].PublishAt != now+3600 {
+		t.Fatalf("unexpected proposals %v", ur.Proposals)
+	}
+
+	// Nothing is published before its time.
+	assertSuccess(t, b.publishScheduledProposals(context.Background(),
+		now+3599))
+	pdr := getProposalDetails(b, token, t)
+	if pdr.Proposal.Status != www.PropStatusNotReviewed {
+		t.Fatalf("unexpected status %v", pdr.Proposal.Status)
+	}
+
+	assertSuccess(t, b.publishScheduledProposals(context.Background(),
+		now+3600))
+	pdr = getProposalDetails(b, token, t)
+	if pdr.Proposal.Status != www.PropStatusPublic {
+		t.Fatalf("unexpected status %v", pdr.Proposal.Status)
+	}
+	publications, err := b.db.AllScheduledPublications()
+	assertSuccess(t, err)
+	if len(publications) != 0 {
+		t.Fatalf("unexpected scheduled publications %v", publications)
+	}
+
+	// Vetted proposals can't be scheduled.
+	_, err = schedule(www.PropStatusPublic, now+3600)
+	assertError(t, err, www.ErrorStatusInvalidPropStatusTransition)
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+// recordSecurityEvent appends an event to the security log of the provided
+// user.  Failures are only logged so that they don't fail the action that
+// the event records.
+func (b *backend) recordSecurityEvent(userID uint64, typ, ip, userAgent, context string) {
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	err := b.db.SecurityEventNew(database.SecurityEvent{
+		UserID:    userID,
+		Type:      typ,
+		IP:        ip,
+		UserAgent: userAgent,
+		Context:   context,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		log.Errorf("recordSecurityEvent %v %v: %v", userID, typ, err)
+	}
+}
+
+// securityEvent appends an event of the client of the provided request to
+// the security log of the provided user.
+func (p *politeiawww) securityEvent(r *http.Request, userID uint64, typ, context string) {
+	p.backend.recordSecurityEvent(userID, typ, p.clientIP(r).String(),
+		r.UserAgent(), context)
+}
+
+// deviceID returns the id of the device with the provided IP address and user
+// agent.
+func deviceID(ip, userAgent string) string {
+	d := sha256.Sum256([]byte(ip + "\n" + userAgent))
+	return hex.EncodeToString(d[:])
+}
+
+// recordDevice adds the device with the provided IP address and user agent to
+// the known devices of the provided user.  Failures are only logged so that
+// they don't fail the login.
+func (b *backend) recordDevice(userID uint64, ip, userAgent string) {
+	err := b.db.DeviceNew(userID, deviceID(ip, userAgent))
+	if err != nil {
+		log.Errorf("recordDevice %v: %v", userID, err)
+	}
+}
+
+// isNewDevice returns true if the provided user never logged in from the
+// provided IP address and user agent.  The devices are recorded separately
+// from the security log since the log drops its oldest events.
+func (b *backend) isNewDevice(userID uint64, ip, userAgent string) bool {
+	known, err := b.db.DeviceExists(userID, deviceID(ip, userAgent))
+	if err != nil {
+		log.Errorf("isNewDevice %v: %v", userID, err)
+		return false
+	}
+	if known {
+		return false
+	}
+
+	// The devices of the logins that happened before the devices were
+	// recorded are only found in the security log.
+	events, err := b.db.SecurityEvents(userID)
+	if err != nil {
+		log.Errorf("isNewDevice %v: %v", userID, err)
+		return false
+	}
+	for _, v := range events {
+		if v.Type == www.SecurityEventLogin && v.IP == ip &&
+			v.UserAgent == userAgent {
+			return false
+		}
+	}
+	return true
+}
+
+// ProcessSecurityEvents returns the security events of the provided user.
+func (b *backend) ProcessSecurityEvents(user *database.User) (*www.SecurityEventsReply, error) {
+	events, err := b.db.SecurityEvents(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := www.SecurityEventsReply{
+		Events: make([]www.SecurityEvent, 0, len(events)),
+	}
+	for _, v := range events {
+		reply.Events = append(reply.Events, www.SecurityEvent{
+			Type:      v.Type,
+			IP:        v.IP,
+			UserAgent: v.UserAgent,
+			Context:   v.Context,
+			Timestamp: v.Timestamp,
+		})
+	}
+	return &reply, nil
+}
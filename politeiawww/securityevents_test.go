@@ -0,0 +1,126 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database/localdb"
+)
+
+func TestSecurityEvents(t *testing.T) {
+	p := &politeiawww{
+		backend: createBackend(t),
+	}
+	p.cfg = p.backend.cfg
+	b := p.backend
+	defer b.db.Close()
+
+	nu, _ := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+
+	// Failed logins of users are recorded with the client.
+	login := func(email string) {
+		body, err := json.Marshal(www.Login{
+			Email:    email,
+			Password: "invalid",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodPost, www.RouteLogin,
+			bytes.NewReader(body))
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("User-Agent", "agent")
+		w := httptest.NewRecorder()
+		p.handleLogin(w, r)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("got %v, want %v", w.Code,
+				http.StatusUnauthorized)
+		}
+	}
+	login(nu.Email)
+	login("unknown@example.com")
+
+	_, err = b.newLoginSession(nu.Email, "10.0.0.2", "client",
+		sessionMaxAge)
+	assertSuccess(t, err)
+
+	reply, err := b.ProcessSecurityEvents(user)
+	assertSuccess(t, err)
+	if len(reply.Events) != 2 {
+		t.Fatalf("got %v events, want 2", len(reply.Events))
+	}
+	e := reply.Events[0]
+	if e.Type != www.SecurityEventLoginFailed || e.IP != "10.0.0.1" ||
+		e.UserAgent != "agent" || e.Context == "" {
+		t.Fatalf("unexpected event %v", e)
+	}
+	e = reply.Events[1]
+	if e.Type != www.SecurityEventLogin || e.IP != "10.0.0.2" {
+		t.Fatalf("unexpected event %v", e)
+	}
+
+	// Users only see their own events.
+	nu2, _ := createAndVerifyUser(t, b)
+	user2, err := b.db.UserGet(nu2.Email)
+	assertSuccess(t, err)
+	reply, err = b.ProcessSecurityEvents(user2)
+	assertSuccess(t, err)
+	if len(reply.Events) != 0 {
+		t.Fatalf("unexpected events %v", reply.Events)
+	}
+}
+
+func TestIsNewDevice(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, _ := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+
+	if !b.isNewDevice(user.ID, "10.0.0.1", "agent") {
+		t.Fatalf("unknown device is not new")
+	}
+	_, err = b.newLoginSession(nu.Email, "10.0.0.1", "agent",
+		sessionMaxAge)
+	assertSuccess(t, err)
+
+	if b.isNewDevice(user.ID, "10.0.0.1", "agent") {
+		t.Fatalf("known device is new")
+	}
+	if !b.isNewDevice(user.ID, "10.0.0.2", "agent") ||
+		!b.isNewDevice(user.ID, "10.0.0.1", "other agent") {
+		t.Fatalf("unknown device is not new")
+	}
+
+	// Devices stay known after their logins are dropped from the security
+	// log.
+	for i := 0; i < localdb.MaxSecurityEvents; i++ {
+		b.recordSecurityEvent(user.ID, www.SecurityEventLoginFailed,
+			"10.0.0.3", "agent", "")
+	}
+	events, err := b.db.SecurityEvents(user.ID)
+	assertSuccess(t, err)
+	if len(events) != localdb.MaxSecurityEvents {
+		t.Fatalf("got %v events, want %v", len(events),
+			localdb.MaxSecurityEvents)
+	}
+	for _, v := range events {
+		if v.Type == www.SecurityEventLogin {
+			t.Fatalf("login event was not dropped")
+		}
+	}
+	if b.isNewDevice(user.ID, "10.0.0.1", "agent") {
+		t.Fatalf("known device is new")
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// sessionMaxAge is the duration of a login session.
+	sessionMaxAge = 24 * time.Hour
+
+	// loginSessionIDSize is the size of login session ids in bytes.
+	loginSessionIDSize = 16
+
+	// maxUserAgentLength is the maximum length of the user agents that are
+	// recorded.
+	maxUserAgentLength = 256
+)
+
+// loginSessionExpired returns true if the provided login session expired at
+// the provided time.  Login sessions that were recorded before they had an
+// expiry last sessionMaxAge.
+func loginSessionExpired(ls database.LoginSession, t time.Time) bool {
+	expiry := ls.Expiry
+	if expiry == 0 {
+		expiry = ls.Timestamp + int64(sessionMaxAge/time.Second)
+	}
+	return t.Unix() >= expiry
+}
+
+// loginSession returns the provided login session of the provided user if it
+// has neither expired nor been revoked.
+func loginSession(user *database.User, id string) (*database.LoginSession, bool) {
+	if id == "" {
+		return nil, false
+	}
+	now := time.Now()
+	for k, v := range user.LoginSessions {
+		if v.ID == id && !loginSessionExpired(v, now) {
+			return &user.LoginSessions[k], true
+		}
+	}
+	return nil, false
+}
+
+// loginSessionValid returns true if the provided login session of the
+// provided user has neither expired nor been revoked.
+func loginSessionValid(user *database.User, id string) bool {
+	_, ok := loginSession(user, id)
+	return ok
+}
+
+// newLoginSession records a new login session of the user with the provided
+// email that lasts for the provided duration and returns its id.  The expired
+// login sessions of the user are removed.
+func (b *backend) newLoginSession(email, ip, userAgent string, maxAge time.Duration) (string, error) {
+	user, err := b.db.UserGet(email)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := util.Random(loginSessionIDSize)
+	if err != nil {
+		return "", err
+	}
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	now := time.Now()
+	sessions := make([]database.LoginSession, 0, len(user.LoginSessions)+1)
+	for _, v := range user.LoginSessions {
+		if !loginSessionExpired(v, now) {
+			sessions = append(sessions, v)
+		}
+	}
+	ls := database.LoginSession{
+		ID:        hex.EncodeToString(id),
+		IP:        ip,
+		UserAgent: userAgent,
+		Timestamp: now.Unix(),
+		Expiry:    now.Add(maxAge).Unix(),
+	}
+	firstLogin := user.LastLogin == 0
+	user.LoginSessions = append(sessions, ls)
+	user.LastLogin = ls.Timestamp
+	user.FailedLogins = 0
+
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return "", err
+	}
+
+	// Warn the user about logins from unknown devices.  The device of
+	// the first login is simply recorded.
+	if !firstLogin && b.isNewDevice(user.ID, ip, userAgent) {
+		err = b.emailNewDeviceLogin(user.Email, ip, userAgent, now)
+		if err != nil {
+			log.Errorf("newLoginSession: emailNewDeviceLogin %v: %v",
+				user.ID, err)
+		}
+	}
+	b.recordDevice(user.ID, ip, userAgent)
+	b.recordSecurityEvent(user.ID, www.SecurityEventLogin, ip, userAgent, "")
+
+	return ls.ID, nil
+}
+
+// deleteLoginSession removes the provided login session from the provided
+// user.  It returns false if the user has no such login session.
+func deleteLoginSession(user *database.User, id string) bool {
+	for k, v := range user.LoginSessions {
+		if v.ID == id {
+			user.LoginSessions = append(user.LoginSessions[:k],
+				user.LoginSessions[k+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessLogout removes the provided login session of the provided user.
+func (b *backend) ProcessLogout(user *database.User, id string) error {
+	if !deleteLoginSession(user, id) {
+		return nil
+	}
+	return b.db.UserUpdate(*user)
+}
+
+// ProcessUserSessions returns the login sessions of the provided user.  The
+// provided id is the login session of the request.
+func (b *backend) ProcessUserSessions(user *database.User, id string) *www.UserSessionsReply {
+	reply := www.UserSessionsReply{
+		Sessions: make([]www.UserSession, 0, len(user.LoginSessions)),
+	}
+	now := time.Now()
+	for _, v := range user.LoginSessions {
+		if loginSessionExpired(v, now) {
+			continue
+		}
+		reply.Sessions = append(reply.Sessions, www.UserSession{
+			ID:        v.ID,
+			IP:        v.IP,
+			UserAgent: v.UserAgent,
+			Timestamp: v.Timestamp,
+			Current:   v.ID == id,
+		})
+	}
+	return &reply
+}
+
+// ProcessRevokeSessions revokes one or all login sessions of the provided
+// user.  The revoked sessions are logged out on their next request.
+func (b *backend) ProcessRevokeSessions(user *database.User, rs www.RevokeSessions) (*www.RevokeSessionsReply, error) {
+	if rs.All {
+		user.LoginSessions = nil
+	} else if !deleteLoginSession(user, rs.ID) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusSessionNotFound,
+		}
+	}
+
+	err := b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.RevokeSessionsReply{}, nil
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+// loginCookies logs the user with the provided email in and returns the
+// session cookies.
+func loginCookies(t *testing.T, p *politeiawww, email string) []*http.Cookie {
+	t.Helper()
+
+	sessionID, err := p.backend.newLoginSession(email, "127.0.0.1",
+		"agent", sessionMaxAge)
+	assertSuccess(t, err)
+	w := httptest.NewRecorder()
+	err = p.setSessionLogin(w, httptest.NewRequest(http.MethodGet, "/", nil),
+		email, sessionID)
+	assertSuccess(t, err)
+	return w.Result().Cookies()
+}
+
+func TestLoginSessions(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, _ := createAndVerifyUser(t, b)
+
+	// Expired login sessions are removed on login.
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	user.LoginSessions = []database.LoginSession{{
+		ID:        "expired",
+		Timestamp: time.Now().Add(-sessionMaxAge).Unix(),
+	}}
+	assertSuccess(t, b.db.UserUpdate(*user))
+
+	id1, err := b.newLoginSession(nu.Email, "127.0.0.1", "agent1", sessionMaxAge)
+	assertSuccess(t, err)
+	id2, err := b.newLoginSession(nu.Email, "127.0.0.2", "agent2", sessionMaxAge)
+	assertSuccess(t, err)
+
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	if loginSessionValid(user, "expired") || !loginSessionValid(user, id1) ||
+		!loginSessionValid(user, id2) || loginSessionValid(user, "") {
+		t.Fatalf("unexpected login sessions: %v", user.LoginSessions)
+	}
+	reply := b.ProcessUserSessions(user, id2)
+	if len(reply.Sessions) != 2 || reply.Sessions[0].Current ||
+		!reply.Sessions[1].Current || reply.Sessions[1].IP != "127.0.0.2" ||
+		reply.Sessions[1].UserAgent != "agent2" {
+		t.Fatalf("unexpected sessions: %v", reply.Sessions)
+	}
+
+	// Revoke a single session.
+	_, err = b.ProcessRevokeSessions(user, www.RevokeSessions{ID: "invalid"})
+	assertError(t, err, www.ErrorStatusSessionNotFound)
+	_, err = b.ProcessRevokeSessions(user, www.RevokeSessions{ID: id1})
+	assertSuccess(t, err)
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	if loginSessionValid(user, id1) || !loginSessionValid(user, id2) {
+		t.Fatalf("unexpected login sessions: %v", user.LoginSessions)
+	}
+
+	// Revoke all sessions.
+	_, err = b.ProcessRevokeSessions(user, www.RevokeSessions{All: true})
+	assertSuccess(t, err)
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	if len(user.LoginSessions) != 0 {
+		t.Fatalf("unexpected login sessions: %v", user.LoginSessions)
+	}
+}
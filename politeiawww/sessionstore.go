@@ -0,0 +1,209 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+const (
+	// Session stores.
+	sessionStoreState    = "statestore"
+	sessionStoreDatabase = "database"
+
+	// sessionIDSize is the size of session IDs in bytes.
+	sessionIDSize = 32
+
+	// sessionPurgeInterval is the interval at which the expired sessions
+	// are removed from the database.
+	sessionPurgeInterval = time.Hour
+)
+
+// sessionStorage stores the encoded sessions of a stateSessionStore.  It is
+// satisfied by the state stores.
+type sessionStorage interface {
+	// Get returns the value of the provided key or errStateNotFound.
+	Get(key string) ([]byte, error)
+
+	// Set sets the value of the provided key.  The key expires after the
+	// provided duration.
+	Set(key string, value []byte, ttl time.Duration) error
+
+	// Delete deletes the provided key.
+	Delete(key string) error
+}
+
+// dbSessionStorage is a sessionStorage that keeps the sessions in the user
+// database so that they survive restarts.
+type dbSessionStorage struct {
+	db database.Database
+}
+
+// Get satisfies the sessionStorage interface.
+func (d dbSessionStorage) Get(key string) ([]byte, error) {
+	session, err := d.db.SessionGet(strings.TrimPrefix(key, sessionKey("")))
+	if err == database.ErrSessionNotFound {
+		return nil, errStateNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return session.Values, nil
+}
+
+// Set satisfies the sessionStorage interface.
+func (d dbSessionStorage) Set(key string, value []byte, ttl time.Duration) error {
+	return d.db.SessionSet(database.Session{
+		ID:     strings.TrimPrefix(key, sessionKey("")),
+		Values: value,
+		Expiry: time.Now().Add(ttl).Unix(),
+	})
+}
+
+// Delete satisfies the sessionStorage interface.
+func (d dbSessionStorage) Delete(key string) error {
+	return d.db.SessionDelete(strings.TrimPrefix(key, sessionKey("")))
+}
+
+// sessionPurger periodically removes the expired sessions from the database.
+// It returns once the quit channel is closed.
+func (b *backend) sessionPurger() {
+	ticker := time.NewTicker(sessionPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.quit:
+			return
+		}
+
+		err := b.db.SessionsPurge(time.Now().Unix())
+		if err != nil {
+			log.Errorf("sessionPurger: %v", err)
+		}
+	}
+}
+
+// stateSessionStore is a session store that keeps the sessions in a state
+// store or the database.  Like the filesystem session store, the cookie only
+// holds the signed session ID.
+type stateSessionStore struct {
+	store   sessionStorage
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+}
+
+// newStateSessionStore returns a session store that keeps the sessions in the
+// provided storage and signs the cookies with the provided keys.
+func newStateSessionStore(store sessionStorage, keyPairs ...[]byte) *stateSessionStore {
+	return &stateSessionStore{
+		store:  store,
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:   "/",
+			MaxAge: 86400 * 30,
+		},
+	}
+}
+
+// sessionKey returns the state store key of the provided session.
+func sessionKey(id string) string {
+	return "session:" + id
+}
+
+// Get satisfies the sessions.Store interface.  The session is cached for the
+// duration of the request.
+func (s *stateSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New satisfies the sessions.Store interface.  It returns the session of the
+// request or a new session.
+func (s *stateSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	options := *s.Options
+	session.Options = &options
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	err = securecookie.DecodeMulti(name, c.Value, &session.ID, s.Codecs...)
+	if err != nil {
+		return session, err
+	}
+	value, err := s.store.Get(sessionKey(session.ID))
+	if err == errStateNotFound {
+		return session, nil
+	} else if err != nil {
+		return session, err
+	}
+	err = securecookie.DecodeMulti(name, string(value), &session.Values,
+		s.Codecs...)
+	if err != nil {
+		return session, err
+	}
+	session.IsNew = false
+
+	return session, nil
+}
+
+// Save satisfies the sessions.Store interface.  A negative MaxAge deletes the
+// session.
+func (s *stateSessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			err := s.store.Delete(sessionKey(session.ID))
+			if err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "",
+			session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		id, err := util.Random(sessionIDSize)
+		if err != nil {
+			return err
+		}
+		session.ID = strings.TrimRight(
+			base32.StdEncoding.EncodeToString(id), "=")
+	}
+
+	value, err := securecookie.EncodeMulti(session.Name(), session.Values,
+		s.Codecs...)
+	if err != nil {
+		return err
+	}
+	// Sessions that end with the browser session are kept for a day.
+	ttl := time.Duration(session.Options.MaxAge) * time.Second
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	err = s.store.Set(sessionKey(session.ID), []byte(value), ttl)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID,
+		s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded,
+		session.Options))
+	return nil
+}
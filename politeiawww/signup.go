@@ -0,0 +1,133 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// signupChallengeRandomSize is the size of the random part of signup
+	// challenges in bytes.  A challenge is made up of the random part, its
+	// expiry as a big endian UNIX time and the HMAC-SHA256 of both.
+	signupChallengeRandomSize = 16
+	signupChallengeSize       = signupChallengeRandomSize + 8 + sha256.Size
+
+	// signupNonceSize is the maximum size of signup challenge solutions in
+	// bytes.
+	signupNonceSize = 32
+
+	// signupChallengeExpiry is the time clients have to solve a signup
+	// challenge and register.
+	signupChallengeExpiry = 10 * time.Minute
+
+	// signupKeyFilename is the name of the file in the data directory that
+	// holds the key that signs the signup challenges.
+	signupKeyFilename = "signup.key"
+)
+
+// signupChallengeKey returns the state store key that records that the
+// provided signup challenge was used.
+func signupChallengeKey(challenge []byte) string {
+	return "signupchallenge:" + hex.EncodeToString(challenge)
+}
+
+// signupChallengeMAC returns the HMAC of the provided random part and expiry
+// of a signup challenge.
+func (b *backend) signupChallengeMAC(data []byte) []byte {
+	h := hmac.New(sha256.New, b.signupKey)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// newSignupChallenge returns a signup challenge that expires at the provided
+// time.  Challenges are signed rather than stored so that requesting them
+// costs politeiawww no memory.
+func (b *backend) newSignupChallenge(expiry time.Time) ([]byte, error) {
+	challenge, err := util.Random(signupChallengeRandomSize)
+	if err != nil {
+		return nil, err
+	}
+	var e [8]byte
+	binary.BigEndian.PutUint64(e[:], uint64(expiry.Unix()))
+	challenge = append(challenge, e[:]...)
+	return append(challenge, b.signupChallengeMAC(challenge)...), nil
+}
+
+// ProcessSignupChallenge issues a proof of work challenge that must be solved
+// before creating a new user.
+func (b *backend) ProcessSignupChallenge() (*www.SignupChallengeReply, error) {
+	expiry := time.Now().Add(signupChallengeExpiry)
+	challenge, err := b.newSignupChallenge(expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.SignupChallengeReply{
+		Challenge:  hex.EncodeToString(challenge),
+		Difficulty: b.cfg.SignupPoWDifficulty,
+		Expiry:     expiry.Unix(),
+	}, nil
+}
+
+// verifySignupProofOfWork verifies that the provided new user command solves
+// a valid and unexpired signup challenge when signups require a proof of
+// work.  Solved challenges are recorded until they expire so that they can't
+// be reused.
+func (b *backend) verifySignupProofOfWork(u www.NewUser) error {
+	if b.cfg.SignupPoWDifficulty == 0 {
+		return nil
+	}
+
+	challenge, err := hex.DecodeString(u.Challenge)
+	if err != nil || len(challenge) != signupChallengeSize {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusInvalidSignupChallenge,
+		}
+	}
+	nonce, err := hex.DecodeString(u.Nonce)
+	if err != nil || len(nonce) > signupNonceSize {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusInvalidProofOfWork,
+		}
+	}
+
+	data := challenge[:signupChallengeRandomSize+8]
+	mac := challenge[signupChallengeRandomSize+8:]
+	expiry := time.Unix(int64(binary.BigEndian.Uint64(
+		data[signupChallengeRandomSize:])), 0)
+	if !hmac.Equal(mac, b.signupChallengeMAC(data)) ||
+		!time.Now().Before(expiry) {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusInvalidSignupChallenge,
+		}
+	}
+	if !util.ProofOfWorkValid(challenge, nonce,
+		b.cfg.SignupPoWDifficulty) {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusInvalidProofOfWork,
+		}
+	}
+
+	// Only solved challenges are recorded, so filling the state store
+	// costs as much work as registering.
+	key := signupChallengeKey(challenge)
+	_, err = b.stateStore.Get(key)
+	if err == nil {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusInvalidSignupChallenge,
+		}
+	} else if err != errStateNotFound {
+		return err
+	}
+	return b.stateStore.Set(key, []byte{1}, time.Until(expiry))
+}
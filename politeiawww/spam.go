@@ -0,0 +1,469 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// BackendProposalSpamVersion is the version of BackendProposalSpam.
+	BackendProposalSpamVersion = 1
+
+	// heldProposalIDSize is the size of the ids of the held proposals in
+	// bytes.
+	heldProposalIDSize = 16
+
+	// spamKeywordScore is the score that each keyword adds.
+	spamKeywordScore = 25
+
+	// spamMinLinks is the number of links from which a proposal may be
+	// mostly links.
+	spamMinLinks = 3
+
+	// spamMinLinkDensity is the percentage of the words of a proposal that
+	// must be links for it to be mostly links.
+	spamMinLinkDensity = 10
+
+	// spamRecentSubmissions is the number of recent submissions that new
+	// proposals are compared to.
+	spamRecentSubmissions = 100
+
+	// spamMinSimilarity is the percentage of resemblance from which a
+	// proposal resembles a recent submission.
+	spamMinSimilarity = 50
+
+	// spamShingleSize is the number of words of the shingles that the
+	// proposals are compared by.
+	spamShingleSize = 3
+)
+
+// spamLink matches the links in the lowercased text of a proposal.
+var spamLink = regexp.MustCompile(`https?://|www\.`)
+
+// BackendProposalSpam is the metadata stream that holds the spam score of a
+// proposal that the spam filters flagged on submission.
+type BackendProposalSpam struct {
+	Version uint64   `json:"version"` // BackendProposalSpam version
+	Score   int      `json:"score"`   // Spam score of the proposal
+	Reasons []string `json:"reasons"` // Filters that contributed to the score
+}
+
+// encodeBackendProposalSpam encodes BackendProposalSpam into a JSON byte
+// slice.
+func encodeBackendProposalSpam(md BackendProposalSpam) ([]byte, error) {
+	b, err := json.Marshal(md)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// decodeBackendProposalSpam decodes a JSON byte slice into a
+// BackendProposalSpam.
+func decodeBackendProposalSpam(payload []byte) (*BackendProposalSpam, error) {
+	var md BackendProposalSpam
+
+	err := json.Unmarshal(payload, &md)
+	if err != nil {
+		return nil, err
+	}
+
+	return &md, nil
+}
+
+// recordSpam returns the spam score of the provided record, or nil if it
+// wasn't flagged.
+func recordSpam(p pd.Record) *BackendProposalSpam {
+	for _, v := range p.Metadata {
+		if v.ID != mdStreamSpam {
+			continue
+		}
+		md, err := decodeBackendProposalSpam([]byte(v.Payload))
+		if err != nil {
+			log.Errorf("could not decode spam score '%v' token '%v': %v",
+				v.Payload, p.CensorshipRecord.Token, err)
+			return nil
+		}
+		return md
+	}
+
+	return nil
+}
+
+// spamProposal is a new proposal as the spam filters see it.
+type spamProposal struct {
+	text  string   // Lowercased markdown files of the proposal
+	words []string // Words of the text
+}
+
+// spamFilter scores new proposals on how much they look like spam.  The
+// scores of the filters add up to the spam score of a proposal.
+type spamFilter interface {
+	// reason returns the www spam reason of the filter.
+	reason() string
+
+	// score returns the score of the provided proposal, 0 if it doesn't
+	// look like spam to the filter.
+	score(p *spamProposal) int
+}
+
+// spamRecorder is implemented by the spam filters that remember the
+// proposals that were scored.
+type spamRecorder interface {
+	record(p *spamProposal)
+}
+
+// keywordFilter scores the proposals that contain configured keywords.
+type keywordFilter struct {
+	keywords []string // Lowercased keywords
+}
+
+func (f *keywordFilter) reason() string {
+	return www.SpamReasonKeyword
+}
+
+func (f *keywordFilter) score(p *spamProposal) int {
+	score := 0
+	for _, v := range f.keywords {
+		if strings.Contains(p.text, v) {
+			score += spamKeywordScore
+		}
+	}
+	return score
+}
+
+// linkFilter scores the proposals that are mostly links by the percentage
+// of their words that are links.
+type linkFilter struct{}
+
+func (f *linkFilter) reason() string {
+	return www.SpamReasonLinks
+}
+
+func (f *linkFilter) score(p *spamProposal) int {
+	links := len(spamLink.FindAllStringIndex(p.text, -1))
+	if links < spamMinLinks || len(p.words) == 0 {
+		return 0
+	}
+	density := links * 100 / len(p.words)
+	if density < spamMinLinkDensity {
+		return 0
+	}
+	if density > 100 {
+		density = 100
+	}
+	return density
+}
+
+// duplicateFilter scores the proposals that resemble a recent submission by
+// the percentage of the resemblance.  Proposals are compared by the sets of
+// their word shingles.
+type duplicateFilter struct {
+	sync.Mutex
+
+	recent []map[uint64]struct{} // Shingles of the recent submissions, oldest first
+}
+
+// shingles returns the hashes of the runs of spamShingleSize words of the
+// provided proposal.
+func shingles(p *spamProposal) map[uint64]struct{} {
+	s := make(map[uint64]struct{})
+	for i := 0; i+spamShingleSize <= len(p.words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(p.words[i:i+spamShingleSize], " ")))
+		s[h.Sum64()] = struct{}{}
+	}
+	return s
+}
+
+// similarity returns the percentage of the shingles of either set that are
+// in both.
+func similarity(s1, s2 map[uint64]struct{}) int {
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+	both := 0
+	for k := range s1 {
+		if _, ok := s2[k]; ok {
+			both++
+		}
+	}
+	return both * 100 / (len(s1) + len(s2) - both)
+}
+
+func (f *duplicateFilter) reason() string {
+	return www.SpamReasonDuplicate
+}
+
+func (f *duplicateFilter) score(p *spamProposal) int {
+	s := shingles(p)
+
+	f.Lock()
+	defer f.Unlock()
+
+	score := 0
+	for _, v := range f.recent {
+		if sim := similarity(s, v); sim > score {
+			score = sim
+		}
+	}
+	if score < spamMinSimilarity {
+		return 0
+	}
+	return score
+}
+
+func (f *duplicateFilter) record(p *spamProposal) {
+	s := shingles(p)
+
+	f.Lock()
+	defer f.Unlock()
+
+	f.recent = append(f.recent, s)
+	if len(f.recent) > spamRecentSubmissions {
+		f.recent = f.recent[len(f.recent)-spamRecentSubmissions:]
+	}
+}
+
+// newSpamFilters returns the spam filters that the provided configuration
+// calls for.
+func newSpamFilters(cfg *config) []spamFilter {
+	filters := []spamFilter{
+		&linkFilter{},
+		&duplicateFilter{},
+	}
+	if len(cfg.SpamKeywords) != 0 {
+		filters = append(filters, &keywordFilter{
+			keywords: cfg.SpamKeywords,
+		})
+	}
+	return filters
+}
+
+// spamScore runs the provided proposal through the spam filters and returns
+// its spam score along with the filters that contributed to it.  Nothing is
+// scored when neither flagging nor holding is enabled.
+func (b *backend) spamScore(np www.NewProposal) (int, []string) {
+	if b.cfg.SpamFlagScore == 0 && b.cfg.SpamHoldScore == 0 {
+		return 0, nil
+	}
+
+	var texts []string
+	for _, v := range np.Files {
+		if !isMarkdownFile(v.MIME) {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(v.Payload)
+		if err != nil {
+			continue
+		}
+		texts = append(texts, string(data))
+	}
+	text := strings.ToLower(strings.Join(texts, "\n"))
+	p := &spamProposal{
+		text:  text,
+		words: strings.Fields(text),
+	}
+
+	score := 0
+	var reasons []string
+	for _, v := range b.spamFilters {
+		if s := v.score(p); s > 0 {
+			score += s
+			reasons = append(reasons, v.reason())
+		}
+	}
+	for _, v := range b.spamFilters {
+		if r, ok := v.(spamRecorder); ok {
+			r.record(p)
+		}
+	}
+
+	return score, reasons
+}
+
+// holdProposal stores the provided proposal of the provided user for admin
+// review and returns the error that the submission is answered with.  The
+// proposal credit is spent up front, like for any other submission, and
+// refunded if the proposal is rejected.
+func (b *backend) holdProposal(np www.NewProposal, user *database.User, score int, reasons []string) error {
+	payload, err := json.Marshal(np)
+	if err != nil {
+		return err
+	}
+	id, err := util.Random(heldProposalIDSize)
+	if err != nil {
+		return err
+	}
+	spent, err := b.spendProposalCredit(user)
+	if err != nil {
+		return err
+	}
+	hp := database.HeldProposal{
+		ID:          hex.EncodeToString(id),
+		UserID:      user.ID,
+		Proposal:    payload,
+		Score:       score,
+		Reasons:     reasons,
+		Timestamp:   time.Now().Unix(),
+		CreditSpent: spent,
+	}
+	err = b.db.HeldProposalNew(hp)
+	if err != nil {
+		if spent {
+			b.refundProposalCredit(user)
+		}
+		return err
+	}
+
+	log.Infof("Held proposal %v of user %v: score %v %v", hp.ID, user.ID,
+		score, reasons)
+
+	return www.UserError{
+		ErrorCode:    www.ErrorStatusProposalHeld,
+		ErrorContext: []string{hp.ID},
+	}
+}
+
+// heldProposal returns the held proposal with the provided id.
+func (b *backend) heldProposal(id string) (*database.HeldProposal, error) {
+	proposals, err := b.db.AllHeldProposals()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range proposals {
+		if v.ID == id {
+			return &v, nil
+		}
+	}
+	return nil, www.UserError{
+		ErrorCode: www.ErrorStatusHeldProposalNotFound,
+	}
+}
+
+// ProcessHeldProposals returns the proposals that await admin review.
+func (b *backend) ProcessHeldProposals() (*www.HeldProposalsReply, error) {
+	proposals, err := b.db.AllHeldProposals()
+	if err != nil {
+		return nil, err
+	}
+
+	reply := www.HeldProposalsReply{
+		Proposals: make([]www.HeldProposal, 0, len(proposals)),
+	}
+	for _, v := range proposals {
+		var np www.NewProposal
+		err := json.Unmarshal(v.Proposal, &np)
+		if err != nil {
+			return nil, err
+		}
+		name, err := getProposalName(np.Files)
+		if err != nil {
+			return nil, err
+		}
+		reply.Proposals = append(reply.Proposals, www.HeldProposal{
+			ID:        v.ID,
+			UserID:    strconv.FormatUint(v.UserID, 10),
+			Name:      name,
+			Files:     np.Files,
+			Score:     v.Score,
+			Reasons:   v.Reasons,
+			Timestamp: v.Timestamp,
+		})
+	}
+
+	return &reply, nil
+}
+
+// ProcessReleaseHeldProposal submits a held proposal on behalf of its author.
+// The proposal carries its spam score.  It is held again if the submission
+// fails.
+func (b *backend) ProcessReleaseHeldProposal(ctx context.Context, rhp www.ReleaseHeldProposal) (*www.ReleaseHeldProposalReply, error) {
+	hp, err := b.heldProposal(rhp.ID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := b.db.UserGetById(hp.UserID)
+	if err != nil {
+		return nil, err
+	}
+	var np www.NewProposal
+	err = json.Unmarshal(hp.Proposal, &np)
+	if err != nil {
+		return nil, err
+	}
+
+	// Removing the proposal claims it.
+	err = b.db.HeldProposalDelete(hp.ID)
+	if err == database.ErrHeldProposalNotFound {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusHeldProposalNotFound,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	npr, err := b.submitProposal(ctx, np, user, &BackendProposalSpam{
+		Version: BackendProposalSpamVersion,
+		Score:   hp.Score,
+		Reasons: hp.Reasons,
+	}, hp.CreditSpent)
+	if err != nil {
+		if err := b.db.HeldProposalNew(*hp); err != nil {
+			log.Errorf("ProcessReleaseHeldProposal: HeldProposalNew "+
+				"%v: %v", hp.ID, err)
+			if hp.CreditSpent {
+				b.refundProposalCredit(user)
+			}
+		}
+		return nil, err
+	}
+
+	return &www.ReleaseHeldProposalReply{
+		CensorshipRecord: npr.CensorshipRecord,
+	}, nil
+}
+
+// ProcessRejectHeldProposal discards a held proposal and refunds the proposal
+// credit that was spent on it.
+func (b *backend) ProcessRejectHeldProposal(rhp www.RejectHeldProposal) (*www.RejectHeldProposalReply, error) {
+	hp, err := b.heldProposal(rhp.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Removing the proposal claims it.
+	err = b.db.HeldProposalDelete(hp.ID)
+	if err == database.ErrHeldProposalNotFound {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusHeldProposalNotFound,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if hp.CreditSpent {
+		b.refundProposalCredit(&database.User{
+			ID: hp.UserID,
+		})
+	}
+
+	return &www.RejectHeldProposalReply{}, nil
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+func TestSpamFilters(t *testing.T) {
+	newProposal := func(text string) *spamProposal {
+		text = strings.ToLower(text)
+		return &spamProposal{
+			text:  text,
+			words: strings.Fields(text),
+		}
+	}
+
+	links := newProposal("Visit https://a.example https://b.example " +
+		"www.c.example now")
+	if score := (&linkFilter{}).score(links); score != 60 {
+		t.Fatalf("expected link score 60, got %v", score)
+	}
+	few := newProposal("See https://a.example for the budget details")
+	if score := (&linkFilter{}).score(few); score != 0 {
+		t.Fatalf("expected no link score, got %v", score)
+	}
+
+	kf := &keywordFilter{keywords: []string{"casino", "guaranteed returns"}}
+	p := newProposal("Guaranteed returns from our Casino")
+	if score := kf.score(p); score != 2*spamKeywordScore {
+		t.Fatalf("expected keyword score %v, got %v",
+			2*spamKeywordScore, score)
+	}
+
+	df := &duplicateFilter{}
+	p1 := newProposal("we will build a bridge over the river next year")
+	p2 := newProposal("we will build a bridge over the river next month")
+	p3 := newProposal("a completely different proposal about marketing")
+	if score := df.score(p1); score != 0 {
+		t.Fatalf("expected no duplicate score, got %v", score)
+	}
+	df.record(p1)
+	if score := df.score(p2); score < spamMinSimilarity {
+		t.Fatalf("expected duplicate score, got %v", score)
+	}
+	if score := df.score(p3); score != 0 {
+		t.Fatalf("expected no duplicate score, got %v", score)
+	}
+}
+
+// newTextProposal returns a new proposal of the provided identity whose index
+// file holds the provided text.
+func newTextProposal(t *testing.T, id *identity.FullIdentity, text string) www.NewProposal {
+	files := []pd.File{{
+		Name:    indexFile,
+		MIME:    "text/plain; charset=utf-8",
+		Payload: base64.StdEncoding.EncodeToString([]byte(text)),
+	}}
+	signature, err := getProposalSignature(files, id)
+	assertSuccess(t, err)
+
+	return www.NewProposal{
+		Files:     convertPropFilesFromPD(files),
+		PublicKey: id.Public.String(),
+		Signature: signature,
+	}
+}
+
+func TestHeldProposals(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	b.cfg.SpamKeywords = []string{"casino"}
+	b.cfg.SpamFlagScore = spamKeywordScore
+	b.cfg.SpamHoldScore = 2 * spamKeywordScore
+	b.spamFilters = newSpamFilters(b.cfg)
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+
+	text := "Casino marketing\nWe will advertise the casino on the " +
+		"radio for three months and report the results."
+	_, err = b.ProcessNewProposal(context.Background(),
+		newTextProposal(t, id, text), user)
+	assertSuccess(t, err)
+
+	// The same proposal again resembles the first one.
+	_, err = b.ProcessNewProposal(context.Background(),
+		newTextProposal(t, id, text+"\nThanks."), user)
+	assertError(t, err, www.ErrorStatusProposalHeld)
+
+	hpr, err := b.ProcessHeldProposals()
+	assertSuccess(t, err)
+	if len(hpr.Proposals) != 1 {
+		t.Fatalf("expected 1 held proposal, got %v", len(hpr.Proposals))
+	}
+	held := hpr.Proposals[0]
+	if held.Name != "Casino marketing" || held.Score < b.cfg.SpamHoldScore ||
+		!reflect.DeepEqual(held.Reasons, []string{www.SpamReasonDuplicate,
+			www.SpamReasonKeyword}) {
+		t.Fatalf("unexpected held proposal %v", held)
+	}
+
+	_, err = b.ProcessReleaseHeldProposal(context.Background(),
+		www.ReleaseHeldProposal{ID: held.ID})
+	assertSuccess(t, err)
+	_, err = b.ProcessRejectHeldProposal(www.RejectHeldProposal{
+		ID: held.ID,
+	})
+	assertError(t, err, www.ErrorStatusHeldProposalNotFound)
+
+	ur, err := b.ProcessAllUnvetted(www.GetAllUnvetted{},
+		&database.User{ID: user.ID + 1, Admin: true})
+	assertSuccess(t, err)
+	if len(ur.Proposals) != 2 {
+		t.Fatalf("expected 2 unvetted proposals, got %v",
+			len(ur.Proposals))
+	}
+	for _, v := range ur.Proposals {
+		if v.SpamScore < b.cfg.SpamFlagScore {
+			t.Fatalf("expected flagged proposal, got %v", v)
+		}
+	}
+}
+
+func TestHeldProposalCredits(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	b.cfg.SpamKeywords = []string{"casino"}
+	b.cfg.SpamHoldScore = spamKeywordScore
+	b.spamFilters = newSpamFilters(b.cfg)
+	b.setFeature(www.FeatureCredits, true)
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	_, err = b.ProcessGrantCredits(www.GrantCredits{
+		UserID:  strconv.FormatUint(user.ID, 10),
+		Credits: 1,
+	}, &database.User{ID: user.ID + 1, Admin: true})
+	assertSuccess(t, err)
+	credits := func() uint64 {
+		t.Helper()
+		u, err := b.db.UserGet(nu.Email)
+		assertSuccess(t, err)
+		return u.ProposalCredits
+	}
+
+	// Held proposals spend their credit up front.
+	hold := func(text string) string {
+		t.Helper()
+		_, err := b.ProcessNewProposal(context.Background(),
+			newTextProposal(t, id, text), user)
+		assertError(t, err, www.ErrorStatusProposalHeld)
+		return err.(www.UserError).ErrorContext[0]
+	}
+	held := hold("Casino marketing\nWe will advertise the casino.")
+	if credits() != 0 {
+		t.Fatalf("held proposal didn't spend a credit")
+	}
+	_, err = b.ProcessNewProposal(context.Background(),
+		newTextProposal(t, id, "Casino night\nA casino night."), user)
+	assertError(t, err, www.ErrorStatusNoProposalCredits)
+
+	// Rejecting the proposal refunds the credit.
+	_, err = b.ProcessRejectHeldProposal(www.RejectHeldProposal{
+		ID: held,
+	})
+	assertSuccess(t, err)
+	if credits() != 1 {
+		t.Fatalf("rejected proposal wasn't refunded")
+	}
+
+	// Releasing the proposal doesn't spend another credit.
+	held = hold("Casino night\nA casino night.")
+	_, err = b.ProcessReleaseHeldProposal(context.Background(),
+		www.ReleaseHeldProposal{ID: held})
+	assertSuccess(t, err)
+	if credits() != 0 {
+		t.Fatalf("got %v credits, want 0", credits())
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// State stores.
+	stateStoreMemory = "memory"
+	stateStoreRedis  = "redis"
+)
+
+var (
+	errStateNotFound = fmt.Errorf("state not found")
+)
+
+// stateStore holds the state that has to survive restarts or be shared by
+// several politeiawww instances: the sessions and the rate limit counters.
+// Rate limits are recorded as hits on a key and count the hits within a
+// sliding window.
+type stateStore interface {
+	// Get returns the value of the provided key or errStateNotFound.
+	Get(key string) ([]byte, error)
+
+	// Set sets the value of the provided key.  The key expires after the
+	// provided duration.
+	Set(key string, value []byte, ttl time.Duration) error
+
+	// Delete deletes the provided key and its hits.
+	Delete(key string) error
+
+	// Hit records a hit on the provided key at the provided time.  The hit
+	// expires after the provided duration.
+	Hit(key string, t time.Time, ttl time.Duration) error
+
+	// Hits returns the times of the hits on the provided key that occurred
+	// after the provided time.  The hits that occurred before may be
+	// discarded.
+	Hits(key string, since time.Time) ([]time.Time, error)
+
+	// HitLimit records a hit on the provided key at the provided time
+	// unless the provided number of hits already occurred within the
+	// window that precedes it.  It returns the hits within the window,
+	// excluding the new one, and whether the hit was recorded.  The hit
+	// expires after the window.  The check and the hit are atomic.
+	HitLimit(key string, t time.Time, window time.Duration, limit int) ([]time.Time, bool, error)
+}
+
+// memoryValue is a value of the memory state store.
+type memoryValue struct {
+	value  []byte
+	expiry time.Time
+}
+
+// memoryStore is a stateStore that keeps the state in memory.  The state is
+// lost on restart.
+type memoryStore struct {
+	sync.Mutex
+
+	values map[string]memoryValue
+	hits   map[string][]time.Time
+}
+
+// newMemoryStore returns an empty memory state store.
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		values: make(map[string]memoryValue),
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// Get satisfies the stateStore interface.
+func (m *memoryStore) Get(key string) ([]byte, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	v, ok := m.values[key]
+	if !ok {
+		return nil, errStateNotFound
+	}
+	if !time.Now().Before(v.expiry) {
+		delete(m.values, key)
+		return nil, errStateNotFound
+	}
+	return v.value, nil
+}
+
+// Set satisfies the stateStore interface.
+func (m *memoryStore) Set(key string, value []byte, ttl time.Duration) error {
+	m.Lock()
+	defer m.Unlock()
+
+	// Forget the expired values from time to time.
+	now := time.Now()
+	if len(m.values)%1024 == 0 {
+		for k, v := range m.values {
+			if !now.Before(v.expiry) {
+				delete(m.values, k)
+			}
+		}
+	}
+
+	m.values[key] = memoryValue{
+		value:  value,
+		expiry: now.Add(ttl),
+	}
+	return nil
+}
+
+// Delete satisfies the stateStore interface.
+func (m *memoryStore) Delete(key string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.values, key)
+	delete(m.hits, key)
+	return nil
+}
+
+// Hit satisfies the stateStore interface.
+func (m *memoryStore) Hit(key string, t time.Time, ttl time.Duration) error {
+	m.Lock()
+	defer m.Unlock()
+
+	// Forget the hits that expired.
+	hits := m.hits[key]
+	expired := t.Add(-ttl)
+	for len(hits) > 0 && !hits[0].After(expired) {
+		hits = hits[1:]
+	}
+	m.hits[key] = append(hits, t)
+	return nil
+}
+
+// Hits satisfies the stateStore interface.
+func (m *memoryStore) Hits(key string, since time.Time) ([]time.Time, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	hits := m.hits[key]
+	for len(hits) > 0 && !hits[0].After(since) {
+		hits = hits[1:]
+	}
+	if len(hits) == 0 {
+		delete(m.hits, key)
+	} else {
+		m.hits[key] = hits
+	}
+	return append([]time.Time(nil), hits...), nil
+}
+
+// HitLimit satisfies the stateStore interface.
+func (m *memoryStore) HitLimit(key string, t time.Time, window time.Duration, limit int) ([]time.Time, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	// Forget the hits that expired.
+	hits := m.hits[key]
+	expired := t.Add(-window)
+	for len(hits) > 0 && !hits[0].After(expired) {
+		hits = hits[1:]
+	}
+	recent := append([]time.Time(nil), hits...)
+	if len(hits) >= limit {
+		m.hits[key] = hits
+		return recent, false, nil
+	}
+	m.hits[key] = append(hits, t)
+	return recent, true, nil
+}
+
+// newStateStore returns the state store selected by the configuration.
+func newStateStore(cfg *config) (stateStore, error) {
+	switch cfg.StateStore {
+	case stateStoreMemory:
+		return newMemoryStore(), nil
+	case stateStoreRedis:
+		return newRedisStore(cfg.RedisAddress, cfg.RedisPassword,
+			cfg.RedisDB), nil
+	}
+	return nil, fmt.Errorf("invalid state store: %v", cfg.StateStore)
+}
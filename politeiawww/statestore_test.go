@@ -0,0 +1,219 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+func TestMemoryStore(t *testing.T) {
+	m := newMemoryStore()
+
+	_, err := m.Get("a")
+	if err != errStateNotFound {
+		t.Fatalf("got %v, want %v", err, errStateNotFound)
+	}
+	err = m.Set("a", []byte("value"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := m.Get("a")
+	if err != nil || string(v) != "value" {
+		t.Fatalf("got %s %v", v, err)
+	}
+
+	// Expired values are not returned.
+	err = m.Set("b", []byte("value"), -time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Get("b")
+	if err != errStateNotFound {
+		t.Fatalf("got %v, want %v", err, errStateNotFound)
+	}
+
+	// Hits are counted within the window.
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		err = m.Hit("h", now.Add(time.Duration(i)*time.Minute), time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	hits, err := m.Hits("h", now)
+	if err != nil || len(hits) != 2 {
+		t.Fatalf("got %v hits %v, want 2", len(hits), err)
+	}
+
+	err = m.Delete("h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, err = m.Hits("h", now.Add(-time.Hour))
+	if err != nil || len(hits) != 0 {
+		t.Fatalf("got %v hits %v, want 0", len(hits), err)
+	}
+}
+
+func TestMemoryStoreHitLimit(t *testing.T) {
+	m := newMemoryStore()
+
+	// Hits are recorded until the limit is reached within the window.
+	now := time.Now()
+	tests := []struct {
+		ok   bool
+		hits int
+	}{
+		{true, 0},
+		{true, 1},
+		{false, 2},
+	}
+	for i, test := range tests {
+		hits, ok, err := m.HitLimit("h", now, time.Hour, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != test.ok || len(hits) != test.hits {
+			t.Fatalf("hit %v: got %v %v hits, want %v %v hits", i, ok,
+				len(hits), test.ok, test.hits)
+		}
+	}
+
+	// Expired hits don't count.
+	_, ok, err := m.HitLimit("h", now.Add(time.Hour), time.Hour, 2)
+	if err != nil || !ok {
+		t.Fatalf("got %v %v, want hit recorded", ok, err)
+	}
+
+	// Concurrent hits can't exceed the limit.
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	recorded := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := m.HitLimit("c", now, time.Hour, 10)
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				mtx.Lock()
+				recorded++
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if recorded != 10 {
+		t.Fatalf("got %v hits recorded, want 10", recorded)
+	}
+}
+
+func TestRedisReply(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  interface{}
+		err   bool
+	}{
+		{"+OK\r\n", "OK", false},
+		{"-ERR wrong type\r\n", nil, true},
+		{":42\r\n", int64(42), false},
+		{"$5\r\nhello\r\n", []byte("hello"), false},
+		{"$-1\r\n", nil, false},
+		{"*2\r\n$1\r\na\r\n:1\r\n", []interface{}{[]byte("a"),
+			int64(1)}, false},
+		{"?\r\n", nil, true},
+	}
+	for _, test := range tests {
+		c := &redisConn{
+			r: bufio.NewReader(strings.NewReader(test.reply)),
+		}
+		got, err := c.readReply()
+		if (err != nil) != test.err {
+			t.Fatalf("%q: unexpected error %v", test.reply, err)
+		}
+		if !test.err && !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("%q: got %#v, want %#v", test.reply, got,
+				test.want)
+		}
+	}
+}
+
+func TestDatabaseSessionStore(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+	key := []byte("01234567890123456789012345678901")
+
+	// Save a session.
+	store := newStateSessionStore(dbSessionStorage{b.db}, key)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	session, err := store.New(r, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values["email"] = "user@example.com"
+	w := httptest.NewRecorder()
+	err = store.Save(r, w, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %v cookies, want 1", len(cookies))
+	}
+
+	// The session is loaded from the database by a new store.
+	store = newStateSessionStore(dbSessionStorage{b.db}, key)
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookies[0])
+	session, err = store.New(r, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.IsNew || session.Values["email"] != "user@example.com" {
+		t.Fatalf("session not loaded: %v", session.Values)
+	}
+
+	// Deleted sessions are gone.
+	session.Options.MaxAge = -1
+	err = store.Save(r, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err = store.New(r, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !session.IsNew {
+		t.Fatalf("session not deleted")
+	}
+
+	// Expired sessions are not returned and are purged.
+	err = b.db.SessionSet(database.Session{
+		ID:     "expired",
+		Expiry: time.Now().Add(-time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.db.SessionGet("expired")
+	if err != database.ErrSessionNotFound {
+		t.Fatalf("got %v, want %v", err, database.ErrSessionNotFound)
+	}
+	err = b.db.SessionsPurge(time.Now().Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+// BackendProposalTagsVersion is the version of BackendProposalTags.
+const BackendProposalTagsVersion = 1
+
+// validProposalTag matches the tags that proposals may have.
+var validProposalTag = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// BackendProposalTags is the metadata stream that holds the tags of a
+// proposal.  It is overwritten when the tags change.
+type BackendProposalTags struct {
+	Version   uint64   `json:"version"`   // BackendProposalTags version
+	Timestamp int64    `json:"timestamp"` // Last change of the tags
+	Tags      []string `json:"tags"`      // Tags of the proposal
+}
+
+// encodeBackendProposalTags encodes BackendProposalTags into a JSON byte
+// slice.
+func encodeBackendProposalTags(md BackendProposalTags) ([]byte, error) {
+	b, err := json.Marshal(md)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// decodeBackendProposalTags decodes a JSON byte slice into a
+// BackendProposalTags.
+func decodeBackendProposalTags(payload []byte) (*BackendProposalTags, error) {
+	var md BackendProposalTags
+
+	err := json.Unmarshal(payload, &md)
+	if err != nil {
+		return nil, err
+	}
+
+	return &md, nil
+}
+
+// normalizeProposalTags validates the provided proposal tags and returns
+// them lowercased, sorted and without duplicates.
+func normalizeProposalTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, v := range tags {
+		tag := strings.ToLower(strings.TrimSpace(v))
+		if len(tag) > www.PolicyMaxProposalTagLength ||
+			!validProposalTag.MatchString(tag) {
+			return nil, www.UserError{
+				ErrorCode:    www.ErrorStatusInvalidProposalTag,
+				ErrorContext: []string{v},
+			}
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > www.PolicyMaxProposalTags {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusMaxProposalTagsExceeded,
+		}
+	}
+	sort.Strings(normalized)
+
+	return normalized, nil
+}
+
+// proposalTagsStream returns the metadata stream that holds the provided
+// proposal tags.
+func proposalTagsStream(tags []string) (*pd.MetadataStream, error) {
+	md, err := encodeBackendProposalTags(BackendProposalTags{
+		Version:   BackendProposalTagsVersion,
+		Timestamp: time.Now().Unix(),
+		Tags:      tags,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pd.MetadataStream{
+		ID:      mdStreamTags,
+		Payload: string(md),
+	}, nil
+}
+
+// proposalTags returns the tags of the provided record.
+func proposalTags(p pd.Record) []string {
+	for _, v := range p.Metadata {
+		if v.ID != mdStreamTags {
+			continue
+		}
+		md, err := decodeBackendProposalTags([]byte(v.Payload))
+		if err != nil {
+			log.Errorf("could not decode tags '%v' token '%v': %v",
+				v.Payload, p.CensorshipRecord.Token, err)
+			break
+		}
+		return md.Tags
+	}
+
+	return []string{}
+}
+
+// hasProposalTag returns true if the provided proposal has the provided tag.
+func hasProposalTag(p www.ProposalRecord, tag string) bool {
+	for _, v := range p.Tags {
+		if v == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessSetProposalTags replaces the tags of an unvetted or public proposal
+// on behalf of the provided admin.
+func (b *backend) ProcessSetProposalTags(ctx context.Context, spt www.SetProposalTags, user *database.User) (*www.SetProposalTagsReply, error) {
+	err := checkPublicKeyAndSignature(user, spt.PublicKey, spt.Signature,
+		spt.Token, strings.Join(spt.Tags, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := normalizeProposalTags(spt.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	b.RLock()
+	ir, ok := b.inventory[spt.Token]
+	var status www.PropStatusT
+	if ok {
+		status = convertPropFromInventoryRecord(ir, b.userPubkeys).Status
+	}
+	b.RUnlock()
+	if !ok {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+	if status != www.PropStatusNotReviewed &&
+		status != www.PropStatusPublic {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusWrongStatus,
+		}
+	}
+
+	ms, err := proposalTagsStream(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if !b.test {
+		challenge, err := util.Random(pd.ChallengeSize)
+		if err != nil {
+			return nil, err
+		}
+
+		// Vetted records only allow their metadata to be updated.
+		var (
+			route string
+			cmd   interface{}
+		)
+		if status == www.PropStatusPublic {
+			route = pd.UpdateVettedMetadataRoute
+			cmd = pd.UpdateVettedMetadata{
+				Challenge:   hex.EncodeToString(challenge),
+				Token:       spt.Token,
+				MDOverwrite: []pd.MetadataStream{*ms},
+			}
+		} else {
+			route = pd.UpdateUnvettedRoute
+			cmd = pd.UpdateUnvetted{
+				Challenge:   hex.EncodeToString(challenge),
+				Token:       spt.Token,
+				MDOverwrite: []pd.MetadataStream{*ms},
+			}
+		}
+
+		responseBody, err := b.makeRequest(ctx, http.MethodPost, route,
+			cmd)
+		if err != nil {
+			return nil, err
+		}
+
+		var pdReply pd.UpdateVettedMetadataReply
+		err = json.Unmarshal(responseBody, &pdReply)
+		if err != nil {
+			return nil, fmt.Errorf("Unmarshal %v reply: %v", route,
+				err)
+		}
+
+		// Verify the challenge.
+		err = util.VerifyChallenge(b.cfg.Identity, challenge,
+			pdReply.Response)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Replace the tags in the inventory cache.
+	b.Lock()
+	ir, ok = b.inventory[spt.Token]
+	if !ok {
+		b.Unlock()
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+	metadata := []pd.MetadataStream{*ms}
+	for _, v := range ir.record.Metadata {
+		if v.ID != mdStreamTags {
+			metadata = append(metadata, v)
+		}
+	}
+	ir.record.Metadata = metadata
+	reply := www.SetProposalTagsReply{
+		Proposal: convertPropFromInventoryRecord(ir, b.userPubkeys),
+	}
+	b.Unlock()
+
+	b.publish(clusterEvent{
+		Type:  clusterEventInventory,
+		Token: spt.Token,
+	})
+
+	log.Infof("Admin %v set the tags of proposal %v: %v", user.ID,
+		spt.Token, strings.Join(tags, ","))
+
+	return &reply, nil
+}
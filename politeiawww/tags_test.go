@@ -0,0 +1,99 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+func TestNormalizeProposalTags(t *testing.T) {
+	tags, err := normalizeProposalTags([]string{"Marketing", " dev-ops",
+		"marketing"})
+	assertSuccess(t, err)
+	if !reflect.DeepEqual(tags, []string{"dev-ops", "marketing"}) {
+		t.Fatalf("unexpected tags %v", tags)
+	}
+
+	for _, v := range []string{"", "two words", "-dev", "dev-",
+		strings.Repeat("a", www.PolicyMaxProposalTagLength+1)} {
+		_, err = normalizeProposalTags([]string{v})
+		assertError(t, err, www.ErrorStatusInvalidProposalTag)
+	}
+
+	tags = make([]string, 0, www.PolicyMaxProposalTags+1)
+	for i := 0; i < cap(tags); i++ {
+		tags = append(tags, strings.Repeat("a", i+1))
+	}
+	_, err = normalizeProposalTags(tags)
+	assertError(t, err, www.ErrorStatusMaxProposalTagsExceeded)
+}
+
+func TestProposalTags(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+
+	// Tags are provided at submission.
+	np, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	np.Tags = []string{"Marketing"}
+	npr2, err := b.ProcessNewProposal(context.Background(), *np, user)
+	assertSuccess(t, err)
+	pdr := getProposalDetails(b, npr2.CensorshipRecord.Token, t)
+	if !reflect.DeepEqual(pdr.Proposal.Tags, []string{"marketing"}) {
+		t.Fatalf("unexpected tags %v", pdr.Proposal.Tags)
+	}
+
+	// Admins replace the tags.
+	token := npr.CensorshipRecord.Token
+	setTags := func(tags ...string) error {
+		sig, err := getSignature([]byte(token+strings.Join(tags, ",")), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = b.ProcessSetProposalTags(context.Background(),
+			www.SetProposalTags{
+				Token:     token,
+				Tags:      tags,
+				Signature: sig,
+				PublicKey: id.Public.String(),
+			}, user)
+		return err
+	}
+	assertSuccess(t, setTags("development", "marketing"))
+	pdr = getProposalDetails(b, token, t)
+	if !reflect.DeepEqual(pdr.Proposal.Tags,
+		[]string{"development", "marketing"}) {
+		t.Fatalf("unexpected tags %v", pdr.Proposal.Tags)
+	}
+
+	// The listings are filtered by tag.
+	admin := &database.User{ID: user.ID + 1, Admin: true}
+	ur, err := b.ProcessAllUnvetted(www.GetAllUnvetted{Tag: "Marketing"},
+		admin)
+	assertSuccess(t, err)
+	if ur.Total != 2 {
+		t.Fatalf("expected 2 proposals, got %v", ur.Total)
+	}
+	ur, err = b.ProcessAllUnvetted(www.GetAllUnvetted{Tag: "development"},
+		admin)
+	assertSuccess(t, err)
+	if ur.Total != 1 || ur.Proposals[0].CensorshipRecord.Token != token {
+		t.Fatalf("unexpected proposals %v", ur.Proposals)
+	}
+
+	// The tags of censored proposals can't be changed.
+	censorProposal(b, token, t, user, id)
+	assertError(t, setTags("spam"), www.ErrorStatusWrongStatus)
+}
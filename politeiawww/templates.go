@@ -25,3 +25,150 @@ const templateUpdateUserKeyEmailRaw = `
 (public key: {{.PublicKey}}) was generated for
 <span style="font-weight: bold">{{.Email}}</span> on Politeia.</div>
 `
+
+const templateChangeEmailRaw = `
+<div>Click the link below to verify your new email address:</div>
+<div style="margin: 20px 0 0 10px"><a href="{{.Link}}">{{.Link}}</a></div>
+<div style="margin-top: 20px">You are receiving this email because
+<span style="font-weight: bold">{{.Email}}</span> was requested to replace
+the email address of a Politeia account.</div>
+`
+
+const templateNewDeviceLoginRaw = `
+<div>Your account was logged into from a new device:</div>
+<div style="margin: 20px 0 0 10px">
+<div>Time: {{.Time}}</div>
+<div>IP address: {{.IP}}</div>
+<div>Browser: {{.UserAgent}}</div>
+</div>
+<div style="margin-top: 20px">If this wasn't you, reset your password right
+away:</div>
+<div style="margin: 20px 0 0 10px"><a href="{{.Link}}">{{.Link}}</a></div>
+<div style="margin-top: 20px">You are receiving this email because
+<span style="font-weight: bold">{{.Email}}</span> is the email address of a
+Politeia account.</div>
+`
+
+const templateUserAdminChangedRaw = `
+<div>An administrator has {{if .Admin}}granted admin privileges to{{else}}revoked
+the admin privileges of{{end}} your account.</div>
+{{if .Reason}}<div style="margin: 20px 0 0 10px">Reason: {{.Reason}}</div>
+{{end}}<div style="margin-top: 20px">You are receiving this email because
+<span style="font-weight: bold">{{.Email}}</span> is the email address of a
+Politeia account.</div>
+`
+
+const templateUserVerifiedByAdminRaw = `
+<div>An administrator has verified the email address of your account because
+the verification email could not be delivered.  You can now log in.</div>
+{{if .Reason}}<div style="margin: 20px 0 0 10px">Reason: {{.Reason}}</div>
+{{end}}<div style="margin-top: 20px">You are receiving this email because
+<span style="font-weight: bold">{{.Email}}</span> is the email address of a
+Politeia account.</div>
+`
+
+const templateAccountRecoveredRaw = `
+<div>Your Politeia account was recovered with one of its public keys.  The
+password of the account was replaced, a change of its email address was
+requested and all sessions were logged out.</div>
+<div style="margin-top: 20px">If this wasn't you, contact an administrator
+right away.</div>
+<div style="margin-top: 20px">You are receiving this email because
+<span style="font-weight: bold">{{.Email}}</span> was the email address of the
+account.</div>
+`
+
+const templateProposalVettedRaw = `
+<div>Your proposal <span style="font-weight: bold">{{.Name}}</span> has been
+reviewed by an administrator and is now publicly visible:</div>
+<div style="margin: 20px 0 0 10px"><a href="{{.Link}}">{{.Link}}</a></div>
+<div style="margin-top: 20px">You are receiving this email because
+<span style="font-weight: bold">{{.Email}}</span> opted into proposal status
+notifications on Politeia.</div>
+`
+
+const templateProposalCensoredRaw = `
+<div>Your proposal <span style="font-weight: bold">{{.Name}}</span> has been
+censored by an administrator.</div>
+{{if .Reason}}<div style="margin: 20px 0 0 10px">Reason: {{.Reason}}</div>
+{{end}}<div style="margin: 20px 0 0 10px"><a href="{{.Link}}">{{.Link}}</a></div>
+<div style="margin-top: 20px">You are receiving this email because
+<span style="font-weight: bold">{{.Email}}</span> opted into proposal status
+notifications on Politeia.</div>
+`
+
+const templateAdminProposalNewRaw = `
+<div>The following proposals have been submitted and are awaiting review:</div>
+{{range .Proposals}}<div style="margin: 20px 0 0 10px">
+<div style="font-weight: bold">{{.Name}}</div>
+<div><a href="{{.Link}}">{{.Link}}</a></div>
+<div>Submitted by {{.Author}}, who previously submitted {{.Previous}}
+proposal(s) of which {{.Public}} were published and {{.Censored}} were
+censored.</div>
+</div>
+{{end}}<div style="margin-top: 20px">You are receiving this email because you
+opted into new proposal notifications as a Politeia administrator.</div>
+`
+
+const templateVoteStartedRaw = `
+<div>Voting has started on the proposal
+<span style="font-weight: bold">{{.Name}}</span>.  The vote ends at block
+height {{.EndHeight}}.</div>
+<div style="margin: 20px 0 0 10px"><a href="{{.Link}}">{{.Link}}</a></div>
+<div style="margin-top: 20px">You are receiving this email because you
+opted into vote notifications on Politeia.</div>
+`
+
+const templateVoteEndedRaw = `
+<div>Voting has ended on the proposal
+<span style="font-weight: bold">{{.Name}}</span>.</div>
+<div style="margin: 20px 0 0 10px">
+{{range .Results}}<div>{{.Id}} ({{.Description}}): {{.Votes}} votes</div>
+{{end}}</div>
+<div style="margin: 20px 0 0 10px">Outcome: {{if .Winner}}<span
+style="font-weight: bold">{{.Winner.Id}}</span>{{else}}undecided{{end}}</div>
+<div style="margin: 20px 0 0 10px"><a href="{{.Link}}">{{.Link}}</a></div>
+<div style="margin-top: 20px">You are receiving this email because you
+opted into vote notifications on Politeia.</div>
+`
+
+const templateDigestRaw = `
+<div>Here is what happened on Politeia since your last digest.</div>
+{{if .Proposals}}<div style="margin-top: 20px; font-weight: bold">New
+proposals</div>
+<div style="margin: 10px 0 0 10px">
+{{range .Proposals}}<div>{{.Name}}: <a href="{{.Link}}">{{.Link}}</a></div>
+{{end}}</div>
+{{end}}{{if .Votes}}<div style="margin-top: 20px; font-weight: bold">Votes
+ending soon</div>
+<div style="margin: 10px 0 0 10px">
+{{range .Votes}}<div>{{.Name}} (ends at block {{.EndHeight}}):
+<a href="{{.Link}}">{{.Link}}</a></div>
+{{end}}</div>
+{{end}}{{if .Activity}}<div style="margin-top: 20px; font-weight: bold">Activity
+on your proposals and discussions</div>
+<div style="margin: 10px 0 0 10px">
+{{range .Activity}}<div>{{.Name}}: {{.Comments}} new comments
+<a href="{{.Link}}">{{.Link}}</a></div>
+{{end}}</div>
+{{end}}<div style="margin-top: 20px">You are receiving this email because you
+opted into digest emails on Politeia.</div>
+`
+
+const templateUnsubscribeRaw = `
+<div style="margin-top: 20px; font-size: small"><a
+href="{{.Link}}">Unsubscribe</a> from these emails.</div>
+`
+
+const templateUnsubscribeConfirmRaw = `<!DOCTYPE html>
+<html>
+<head><title>Politeia</title></head>
+<body>
+<form method="post" action="{{.Link}}">
+<input type="hidden" name="List-Unsubscribe" value="One-Click">
+<div>Do you want to unsubscribe from these emails?</div>
+<div style="margin-top: 20px"><button type="submit">Unsubscribe</button></div>
+</form>
+</body>
+</html>
+`
@@ -0,0 +1,128 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+// BackendProposalTimelineVersion is the version of BackendProposalTimeline.
+const BackendProposalTimelineVersion = 1
+
+// BackendProposalTimeline is the metadata stream that holds the funding and
+// the schedule of a proposal.  It is only written at submission.
+type BackendProposalTimeline struct {
+	Version   uint64 `json:"version"`   // BackendProposalTimeline version
+	Timestamp int64  `json:"timestamp"` // Submission of the timeline
+	Amount    uint64 `json:"amount"`    // Requested funding in US cents
+	StartDate int64  `json:"startdate"` // Unix timestamp of the start
+	EndDate   int64  `json:"enddate"`   // Unix timestamp of the end
+}
+
+// encodeBackendProposalTimeline encodes BackendProposalTimeline into a JSON
+// byte slice.
+func encodeBackendProposalTimeline(md BackendProposalTimeline) ([]byte, error) {
+	b, err := json.Marshal(md)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// decodeBackendProposalTimeline decodes a JSON byte slice into a
+// BackendProposalTimeline.
+func decodeBackendProposalTimeline(payload []byte) (*BackendProposalTimeline, error) {
+	var md BackendProposalTimeline
+
+	err := json.Unmarshal(payload, &md)
+	if err != nil {
+		return nil, err
+	}
+
+	return &md, nil
+}
+
+// timelineError returns the error of a proposal timeline that breaks the
+// policy for the provided reason.
+func timelineError(reason string) error {
+	return www.UserError{
+		ErrorCode:    www.ErrorStatusInvalidProposalTimeline,
+		ErrorContext: []string{reason},
+	}
+}
+
+// validateProposalTimeline checks the optional timeline of a proposal that is
+// submitted at the provided time against the policy.
+func validateProposalTimeline(t *www.ProposalTimeline, now int64) error {
+	if t == nil {
+		return nil
+	}
+
+	if t.Amount > www.PolicyMaxProposalAmount {
+		return timelineError(www.TimelineReasonAmount)
+	}
+
+	if t.StartDate == 0 && t.EndDate == 0 {
+		return nil
+	}
+	if t.StartDate <= 0 || t.EndDate <= t.StartDate {
+		return timelineError(www.TimelineReasonDates)
+	}
+	if t.EndDate <= now {
+		return timelineError(www.TimelineReasonPast)
+	}
+	if t.EndDate-t.StartDate > www.PolicyMaxProposalDuration {
+		return timelineError(www.TimelineReasonDuration)
+	}
+
+	return nil
+}
+
+// proposalTimelineStream returns the metadata stream that holds the provided
+// proposal timeline.
+func proposalTimelineStream(t www.ProposalTimeline, ts int64) (*pd.MetadataStream, error) {
+	md, err := encodeBackendProposalTimeline(BackendProposalTimeline{
+		Version:   BackendProposalTimelineVersion,
+		Timestamp: ts,
+		Amount:    t.Amount,
+		StartDate: t.StartDate,
+		EndDate:   t.EndDate,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pd.MetadataStream{
+		ID:      mdStreamTimeline,
+		Payload: string(md),
+	}, nil
+}
+
+// proposalTimeline returns the timeline of the provided record, or nil if the
+// author didn't provide one.
+func proposalTimeline(p pd.Record) *www.ProposalTimeline {
+	for _, v := range p.Metadata {
+		if v.ID != mdStreamTimeline {
+			continue
+		}
+		md, err := decodeBackendProposalTimeline([]byte(v.Payload))
+		if err != nil {
+			log.Errorf("could not decode timeline '%v' token '%v': %v",
+				v.Payload, p.CensorshipRecord.Token, err)
+			break
+		}
+		return &www.ProposalTimeline{
+			Amount:    md.Amount,
+			StartDate: md.StartDate,
+			EndDate:   md.EndDate,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestValidateProposalTimeline(t *testing.T) {
+	const (
+		now = 1540000000
+		day = 24 * 60 * 60
+	)
+
+	valid := []*www.ProposalTimeline{
+		nil,
+		{},
+		{Amount: www.PolicyMaxProposalAmount},
+		{StartDate: now - day, EndDate: now + day},
+		{StartDate: now, EndDate: now + www.PolicyMaxProposalDuration},
+	}
+	for _, v := range valid {
+		assertSuccess(t, validateProposalTimeline(v, now))
+	}
+
+	invalid := []struct {
+		timeline www.ProposalTimeline
+		reason   string
+	}{
+		{www.ProposalTimeline{Amount: www.PolicyMaxProposalAmount + 1},
+			www.TimelineReasonAmount},
+		{www.ProposalTimeline{StartDate: now}, www.TimelineReasonDates},
+		{www.ProposalTimeline{EndDate: now + day}, www.TimelineReasonDates},
+		{www.ProposalTimeline{StartDate: now + day, EndDate: now},
+			www.TimelineReasonDates},
+		{www.ProposalTimeline{StartDate: now - 2*day, EndDate: now - day},
+			www.TimelineReasonPast},
+		{www.ProposalTimeline{StartDate: now,
+			EndDate: now + www.PolicyMaxProposalDuration + 1},
+			www.TimelineReasonDuration},
+	}
+	for _, v := range invalid {
+		err := validateProposalTimeline(&v.timeline, now)
+		assertError(t, err, www.ErrorStatusInvalidProposalTimeline)
+		if reason := err.(www.UserError).ErrorContext[0]; reason != v.reason {
+			t.Fatalf("expected reason %v, got %v", v.reason, reason)
+		}
+	}
+}
+
+func TestProposalTimeline(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+
+	// Proposals without a timeline don't return one.
+	np, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	pdr := getProposalDetails(b, npr.CensorshipRecord.Token, t)
+	if pdr.Proposal.Timeline != nil {
+		t.Fatalf("unexpected timeline %v", pdr.Proposal.Timeline)
+	}
+
+	// The timeline is returned along with the proposal.
+	start := time.Now().Unix()
+	timeline := www.ProposalTimeline{
+		Amount:    2500000,
+		StartDate: start,
+		EndDate:   start + 90*24*60*60,
+	}
+	np.Timeline = &timeline
+	npr, err = b.ProcessNewProposal(context.Background(), *np, user)
+	assertSuccess(t, err)
+	pdr = getProposalDetails(b, npr.CensorshipRecord.Token, t)
+	if pdr.Proposal.Timeline == nil || *pdr.Proposal.Timeline != timeline {
+		t.Fatalf("unexpected timeline %v", pdr.Proposal.Timeline)
+	}
+
+	// Timelines that break the policy are rejected.
+	np.Timeline = &www.ProposalTimeline{
+		Amount: www.PolicyMaxProposalAmount + 1,
+	}
+	_, err = b.ProcessNewProposal(context.Background(), *np, user)
+	assertError(t, err, www.ErrorStatusInvalidProposalTimeline)
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// jwtKeyFilename is the name of the file in the data directory that
+	// holds the key that signs the API tokens.
+	jwtKeyFilename = "jwt.key"
+
+	// jwtAccessExpiry is the lifetime of access tokens.
+	jwtAccessExpiry = 15 * time.Minute
+
+	// jwtRefreshExpiry is the lifetime of the login sessions of API
+	// clients.  Refresh tokens expire with their login session.
+	jwtRefreshExpiry = 30 * 24 * time.Hour
+
+	// jwtIDSize is the size of token ids in bytes.
+	jwtIDSize = 16
+
+	// Token types.
+	jwtTypeAccess  = "access"
+	jwtTypeRefresh = "refresh"
+)
+
+// jwtClaims are the claims of the tokens that are issued to API clients.  The
+// subject is the user id.
+type jwtClaims struct {
+	util.JWTClaims
+	Email     string `json:"email"` // User email
+	SessionID string `json:"sid"`   // Login session id
+	Type      string `json:"typ"`   // Token type
+}
+
+// revokedTokenKey returns the state store key that marks the token with the
+// provided id as revoked.
+func revokedTokenKey(id string) string {
+	return "jwtrevoked:" + id
+}
+
+// newToken returns a signed token of the provided type.
+func (b *backend) newToken(user *database.User, sessionID, typ string, expiry int64) (string, error) {
+	id, err := util.Random(jwtIDSize)
+	if err != nil {
+		return "", err
+	}
+	return util.JWTSign(jwtClaims{
+		JWTClaims: util.JWTClaims{
+			Subject:  strconv.FormatUint(user.ID, 10),
+			ID:       hex.EncodeToString(id),
+			IssuedAt: time.Now().Unix(),
+			Expiry:   expiry,
+		},
+		Email:     user.Email,
+		SessionID: sessionID,
+		Type:      typ,
+	}, b.jwtKey)
+}
+
+// issueTokens returns an access and a refresh token for the provided login
+// session of the provided user.  The tokens don't outlive the login session.
+func (b *backend) issueTokens(user *database.User, sessionID string) (*www.RefreshTokenReply, error) {
+	ls, ok := loginSession(user, sessionID)
+	if !ok {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusSessionNotFound,
+		}
+	}
+
+	expiry := time.Now().Add(jwtAccessExpiry).Unix()
+	if expiry > ls.Expiry {
+		expiry = ls.Expiry
+	}
+	access, err := b.newToken(user, ls.ID, jwtTypeAccess, expiry)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := b.newToken(user, ls.ID, jwtTypeRefresh, ls.Expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.RefreshTokenReply{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenExpiry:  expiry,
+	}, nil
+}
+
+// revokeToken adds the provided token to the revocation list until it
+// expires.
+func (b *backend) revokeToken(claims *jwtClaims) error {
+	ttl := time.Until(time.Unix(claims.Expiry, 0))
+	if ttl <= 0 {
+		return nil
+	}
+	return b.stateStore.Set(revokedTokenKey(claims.ID), []byte{1}, ttl)
+}
+
+// verifyToken returns the user and the claims of the provided token if it is
+// a valid token of the provided type.  A nil user is returned if the token is
+// invalid, revoked, or if its user or login session no longer exists.
+func (b *backend) verifyToken(token, typ string) (*database.User, *jwtClaims, error) {
+	var claims jwtClaims
+	err := util.JWTVerify(token, b.jwtKey, &claims, time.Now())
+	if err != nil || claims.Type != typ || claims.ID == "" {
+		return nil, nil, nil
+	}
+
+	_, err = b.stateStore.Get(revokedTokenKey(claims.ID))
+	if err == nil {
+		return nil, nil, nil
+	} else if err != errStateNotFound {
+		return nil, nil, err
+	}
+
+	user, err := b.db.UserGet(claims.Email)
+	if err == database.ErrUserNotFound {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if strconv.FormatUint(user.ID, 10) != claims.Subject ||
+		user.Deactivated != 0 || !loginSessionValid(user, claims.SessionID) {
+		return nil, nil, nil
+	}
+
+	return user, &claims, nil
+}
+
+// ProcessRefreshToken returns new tokens in exchange for the provided refresh
+// token, which is revoked so that it can only be used once.
+func (b *backend) ProcessRefreshToken(rt www.RefreshToken) (*www.RefreshTokenReply, error) {
+	// Serialize the refreshes so that concurrent requests can't reuse a
+	// refresh token.
+	b.jwtMtx.Lock()
+	defer b.jwtMtx.Unlock()
+
+	user, claims, err := b.verifyToken(rt.RefreshToken, jwtTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidToken,
+		}
+	}
+
+	err = b.revokeToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.issueTokens(user, claims.SessionID)
+}
+
+// ProcessRevokeToken revokes the provided access or refresh token.  The login
+// session of the token is not affected, see ProcessRevokeSessions.
+func (b *backend) ProcessRevokeToken(rt www.RevokeToken) (*www.RevokeTokenReply, error) {
+	var claims jwtClaims
+	err := util.JWTVerify(rt.Token, b.jwtKey, &claims, time.Now())
+	if err == util.ErrJWTExpired {
+		// Expired tokens can't be used anyway.
+		return &www.RevokeTokenReply{}, nil
+	} else if err != nil || claims.ID == "" {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidToken,
+		}
+	}
+
+	err = b.revokeToken(&claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.RevokeTokenReply{}, nil
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestTokens(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, _ := createAndVerifyUser(t, b)
+	id, err := b.newLoginSession(nu.Email, "127.0.0.1", "client",
+		jwtRefreshExpiry)
+	assertSuccess(t, err)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	tokens, err := b.issueTokens(user, id)
+	assertSuccess(t, err)
+
+	// Only access tokens authenticate requests.
+	u, claims, err := b.verifyToken(tokens.AccessToken, jwtTypeAccess)
+	assertSuccess(t, err)
+	if u == nil || u.ID != user.ID || claims.SessionID != id {
+		t.Fatalf("unexpected access token user %v claims %v", u, claims)
+	}
+	u, _, err = b.verifyToken(tokens.RefreshToken, jwtTypeAccess)
+	assertSuccess(t, err)
+	if u != nil {
+		t.Fatalf("refresh token accepted as access token")
+	}
+
+	// Refresh tokens can only be used once.
+	refreshed, err := b.ProcessRefreshToken(www.RefreshToken{
+		RefreshToken: tokens.RefreshToken,
+	})
+	assertSuccess(t, err)
+	_, err = b.ProcessRefreshToken(www.RefreshToken{
+		RefreshToken: tokens.RefreshToken,
+	})
+	assertError(t, err, www.ErrorStatusInvalidToken)
+
+	// Revoked access tokens are rejected.
+	_, err = b.ProcessRevokeToken(www.RevokeToken{Token: "invalid"})
+	assertError(t, err, www.ErrorStatusInvalidToken)
+	_, err = b.ProcessRevokeToken(www.RevokeToken{
+		Token: refreshed.AccessToken,
+	})
+	assertSuccess(t, err)
+	u, _, err = b.verifyToken(refreshed.AccessToken, jwtTypeAccess)
+	assertSuccess(t, err)
+	if u != nil {
+		t.Fatalf("revoked access token accepted")
+	}
+
+	// Revoking the login session invalidates its tokens.
+	_, err = b.ProcessRevokeSessions(user, www.RevokeSessions{All: true})
+	assertSuccess(t, err)
+	_, err = b.ProcessRefreshToken(www.RefreshToken{
+		RefreshToken: refreshed.RefreshToken,
+	})
+	assertError(t, err, www.ErrorStatusInvalidToken)
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strings"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// totpIssuer is the issuer that authenticator apps display next to
+	// the account.
+	totpIssuer = "Politeia"
+
+	// totpRecoveryCodes is the number of recovery codes that are issued
+	// when a user enables TOTP.
+	totpRecoveryCodes = 10
+
+	// totpRecoveryCodeSize is the size of recovery codes in bytes.
+	totpRecoveryCodeSize = 10
+)
+
+// hashRecoveryCode returns the digest of the provided recovery code that is
+// stored in the user database.  Recovery codes are random so a plain digest
+// is sufficient.
+func hashRecoveryCode(code string) []byte {
+	digest := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(code))))
+	return digest[:]
+}
+
+// useRecoveryCode removes the provided recovery code from the recovery codes
+// of the provided user.  It returns false if the code is not a recovery code
+// of the user.
+func useRecoveryCode(user *database.User, code string) bool {
+	digest := hashRecoveryCode(code)
+	for k, v := range user.TOTPRecoveryCodes {
+		if bytes.Equal(v, digest) {
+			user.TOTPRecoveryCodes = append(user.TOTPRecoveryCodes[:k],
+				user.TOTPRecoveryCodes[k+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// checkTOTPCode verifies the provided TOTP or recovery code of a user that
+// enabled TOTP and records its use so that it can't be used again.
+//
+// This function must be called WITH the totp mutex held.
+func (b *backend) checkTOTPCode(user *database.User, code string) error {
+	if code == "" {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusTOTPCodeRequired,
+		}
+	}
+
+	// Codes can't be replayed.
+	step, ok := util.TOTPValid(user.TOTPSecret, code, time.Now())
+	if ok && step > user.TOTPLastStep {
+		user.TOTPLastStep = step
+	} else if !useRecoveryCode(user, code) {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusInvalidTOTPCode,
+		}
+	}
+
+	return b.db.UserUpdate(*user)
+}
+
+// verifyTOTP requires a valid TOTP or recovery code from the provided user if
+// the user enabled TOTP.
+func (b *backend) verifyTOTP(user *database.User, code string) error {
+	if !user.TOTPEnabled {
+		return nil
+	}
+
+	b.totpMtx.Lock()
+	defer b.totpMtx.Unlock()
+
+	// Reload the user so that concurrent logins can't reuse a code.
+	u, err := b.db.UserGet(user.Email)
+	if err != nil {
+		return err
+	}
+	err = b.checkTOTPCode(u, code)
+	if err != nil {
+		return err
+	}
+	*user = *u
+
+	return nil
+}
+
+// ProcessSetTOTP generates a new pending TOTP secret for the provided user.
+func (b *backend) ProcessSetTOTP(user *database.User) (*www.SetTOTPReply, error) {
+	if user.TOTPEnabled {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusTOTPEnabled,
+		}
+	}
+
+	secret, err := util.Random(util.TOTPSecretSize)
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = secret
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.SetTOTPReply{
+		Secret: util.TOTPEncoding.EncodeToString(secret),
+		URI:    util.TOTPURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ProcessVerifyTOTP enables TOTP for the provided user once the user proves
+// that the pending secret was imported into an authenticator app.  It returns
+// the recovery codes, which are not stored in the clear.
+func (b *backend) ProcessVerifyTOTP(user *database.User, vt www.VerifyTOTP) (*www.VerifyTOTPReply, error) {
+	if user.TOTPEnabled {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusTOTPEnabled,
+		}
+	}
+	if len(user.TOTPSecret) == 0 {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusTOTPNotEnabled,
+		}
+	}
+	step, ok := util.TOTPValid(user.TOTPSecret, vt.Code, time.Now())
+	if !ok {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidTOTPCode,
+		}
+	}
+
+	reply := www.VerifyTOTPReply{
+		RecoveryCodes: make([]string, 0, totpRecoveryCodes),
+	}
+	user.TOTPRecoveryCodes = make([][]byte, 0, totpRecoveryCodes)
+	for i := 0; i < totpRecoveryCodes; i++ {
+		r, err := util.Random(totpRecoveryCodeSize)
+		if err != nil {
+			return nil, err
+		}
+		code := util.TOTPEncoding.EncodeToString(r)
+		reply.RecoveryCodes = append(reply.RecoveryCodes, code)
+		user.TOTPRecoveryCodes = append(user.TOTPRecoveryCodes,
+			hashRecoveryCode(code))
+	}
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+
+	err := b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reply, nil
+}
+
+// ProcessDisableTOTP disables TOTP for the provided user.
+func (b *backend) ProcessDisableTOTP(user *database.User, dt www.DisableTOTP) (*www.DisableTOTPReply, error) {
+	if !user.TOTPEnabled {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusTOTPNotEnabled,
+		}
+	}
+
+	err := b.verifyTOTP(user, dt.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPSecret = nil
+	user.TOTPEnabled = false
+	user.TOTPLastStep = 0
+	user.TOTPRecoveryCodes = nil
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.DisableTOTPReply{}, nil
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+const (
+	// userExportCooldown is the minimum time between two user data
+	// exports of a user.
+	userExportCooldown = 10 * time.Minute
+
+	// userExportExpiry is the time a user data export can be downloaded
+	// after it was generated.
+	userExportExpiry = 24 * time.Hour
+)
+
+// userExportKey returns the state store key of the user data export of the
+// provided user.
+func userExportKey(userID uint64) string {
+	return "userexport:" + strconv.FormatUint(userID, 10)
+}
+
+// userExportStartedKey returns the state store key that is set while the
+// provided user can't start another user data export.
+func userExportStartedKey(userID uint64) string {
+	return "userexport:started:" + strconv.FormatUint(userID, 10)
+}
+
+// ProcessUserExport starts generating the archive of the data stored about
+// the provided user in the background.  A user starts at most one export per
+// userExportCooldown, further requests are ignored.
+func (b *backend) ProcessUserExport(user *database.User) (*www.UserExportReply, error) {
+	var reply www.UserExportReply
+
+	key := userExportStartedKey(user.ID)
+	_, err := b.stateStore.Get(key)
+	if err == nil {
+		return &reply, nil
+	} else if err != errStateNotFound {
+		return nil, err
+	}
+	err = b.stateStore.Set(key, []byte{1}, userExportCooldown)
+	if err != nil {
+		return nil, err
+	}
+
+	// The previous export is replaced.
+	err = b.stateStore.Delete(userExportKey(user.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	userID := user.ID
+	b.startWorker(func() {
+		err := b.exportUserData(userID)
+		if err != nil {
+			log.Errorf("exportUserData %v: %v", userID, err)
+		}
+	})
+
+	return &reply, nil
+}
+
+// exportUserData generates the archive of the data stored about the provided
+// user and stores it in the state store until it expires.
+func (b *backend) exportUserData(userID uint64) error {
+	user, err := b.db.UserGetById(userID)
+	if err != nil {
+		return err
+	}
+
+	events, err := b.ProcessSecurityEvents(user)
+	if err != nil {
+		return err
+	}
+	drafts, err := b.ProcessDrafts(user)
+	if err != nil {
+		return err
+	}
+
+	// Collect all proposals of the user, page by page.
+	id := strconv.FormatUint(user.ID, 10)
+	pr := proposalsRequest{
+		UserId: id,
+		StatusMap: map[www.PropStatusT]bool{
+			www.PropStatusNotReviewed: true,
+			www.PropStatusCensored:    true,
+			www.PropStatusPublic:      true,
+		},
+	}
+	proposals := make([]www.ProposalRecord, 0)
+	for {
+		page, _ := b.getProposals(pr)
+		proposals = append(proposals, page...)
+		if len(page) < www.ProposalListPageSize {
+			break
+		}
+		pr.After = page[len(page)-1].CensorshipRecord.Token
+	}
+
+	export := www.UserDataExport{
+		Timestamp: time.Now().Unix(),
+		Account: www.UserDataAccount{
+			ID:                 id,
+			Email:              user.Email,
+			Admin:              user.Admin,
+			PaywallAddress:     user.NewUserPaywallAddress,
+			PaywallAmount:      user.NewUserPaywallAmount,
+			PaywallTxID:        user.NewUserPaywallTx,
+			EmailNotifications: user.EmailNotifications,
+			EmailDigest:        user.EmailDigest,
+			TOTPEnabled:        user.TOTPEnabled,
+			ProposalCredits:    user.ProposalCredits,
+			Deactivated:        user.Deactivated,
+		},
+		Identities:      b.ProcessUserIdentities(user).Identities,
+		Sessions:        b.ProcessUserSessions(user, "").Sessions,
+		APITokens:       b.ProcessAPITokens(user).Tokens,
+		CreditPurchases: b.ProcessProposalCredits(user).Purchases,
+		Proposals:       proposals,
+		SecurityEvents:  events.Events,
+		Drafts:          drafts.Drafts,
+	}
+	payload, err := json.Marshal(export)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("User data export of user %v generated", user.ID)
+
+	return b.stateStore.Set(userExportKey(user.ID), payload,
+		userExportExpiry)
+}
+
+// ProcessUserExportDownload returns the archive of the data stored about the
+// provided user.
+func (b *backend) ProcessUserExportDownload(user *database.User) ([]byte, error) {
+	payload, err := b.stateStore.Get(userExportKey(user.ID))
+	if err == errStateNotFound {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusUserExportNotReady,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
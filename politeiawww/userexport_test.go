@@ -0,0 +1,62 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestUserExport(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	_, _, err = createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	b.recordSecurityEvent(user.ID, www.SecurityEventLogin, "10.0.0.1",
+		"agent", "")
+
+	_, err = b.ProcessUserExportDownload(user)
+	assertError(t, err, www.ErrorStatusUserExportNotReady)
+
+	_, err = b.ProcessUserExport(user)
+	assertSuccess(t, err)
+
+	// The archive is generated in the background.
+	var payload []byte
+	for i := 0; i < 100; i++ {
+		payload, err = b.ProcessUserExportDownload(user)
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assertSuccess(t, err)
+
+	var export www.UserDataExport
+	err = json.Unmarshal(payload, &export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if export.Account.Email != user.Email ||
+		len(export.Identities) != 1 ||
+		export.Identities[0].PublicKey != id.Public.String() ||
+		len(export.Proposals) != 1 ||
+		len(export.SecurityEvents) != 1 {
+		t.Fatalf("unexpected export %v", export)
+	}
+
+	// Exports can't be restarted right away.
+	_, err = b.ProcessUserExport(user)
+	assertSuccess(t, err)
+	_, err = b.ProcessUserExportDownload(user)
+	assertSuccess(t, err)
+}
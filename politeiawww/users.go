@@ -0,0 +1,453 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+// convertWWWAbridgedUserFromDatabase returns the summary of the provided user
+// that is returned in lists of users.
+func convertWWWAbridgedUserFromDatabase(u database.User) www.AbridgedUser {
+	activeIdentity, ok := database.ActiveIdentityString(u.Identities)
+	if !ok {
+		activeIdentity = ""
+	}
+
+	return www.AbridgedUser{
+		ID:          strconv.FormatUint(u.ID, 10),
+		UUID:        u.UUID,
+		Email:       u.Email,
+		Admin:       u.Admin,
+		Verified:    userVerified(&u),
+		PublicKey:   activeIdentity,
+		Deactivated: u.Deactivated,
+	}
+}
+
+// ProcessUsers returns a page of users.  The cursor of the next page is the
+// id or the email of the first user of the next page, depending on the sort
+// order.
+func (b *backend) ProcessUsers(u www.Users) (*www.UsersReply, error) {
+	// Request one more user than fits on the page to find out whether
+	// there is a next page.
+	q := database.UsersQuery{
+		Limit: www.UserListPageSize + 1,
+	}
+	switch u.Sort {
+	case "", www.UserSortCreated:
+		q.Sort = database.UserSortID
+		if u.Start != "" {
+			id, err := strconv.ParseUint(u.Start, 10, 64)
+			if err != nil {
+				return nil, www.UserError{
+					ErrorCode: www.ErrorStatusInvalidInput,
+				}
+			}
+			q.FromID = id
+		}
+	case www.UserSortEmail:
+		q.Sort = database.UserSortEmail
+		q.FromEmail = u.Start
+	default:
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+
+	users, err := b.db.UsersPage(q)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply www.UsersReply
+	if len(users) > www.UserListPageSize {
+		next := users[www.UserListPageSize]
+		if q.Sort == database.UserSortID {
+			reply.Next = strconv.FormatUint(next.ID, 10)
+		} else {
+			reply.Next = next.Email
+		}
+		users = users[:www.UserListPageSize]
+	}
+
+	reply.Users = make([]www.AbridgedUser, 0, len(users))
+	for _, v := range users {
+		reply.Users = append(reply.Users,
+			convertWWWAbridgedUserFromDatabase(v))
+	}
+
+	return &reply, nil
+}
+
+// ProcessUserSearch returns the users that match the provided search.  Public
+// keys are looked up directly while partial emails require reading all users.
+func (b *backend) ProcessUserSearch(us www.UserSearch) (*www.UserSearchReply, error) {
+	err := b.checkFeature(www.FeatureSearch)
+	if err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(us.Email))
+	if email == "" && us.PublicKey == "" {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+
+	var users []database.User
+	if us.PublicKey != "" {
+		u, err := b.db.UserGetByPubKey(us.PublicKey)
+		switch err {
+		case nil:
+			if strings.Contains(u.Email, email) {
+				users = append(users, *u)
+			}
+		case database.ErrUserNotFound:
+		default:
+			return nil, err
+		}
+	} else {
+		err = b.db.AllUsers(func(u *database.User) {
+			if len(users) < www.UserListPageSize &&
+				strings.Contains(u.Email, email) {
+				users = append(users, *u)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reply := www.UserSearchReply{
+		Users: make([]www.AbridgedUser, 0, len(users)),
+	}
+	for _, v := range users {
+		reply.Users = append(reply.Users,
+			convertWWWAbridgedUserFromDatabase(v))
+	}
+
+	return &reply, nil
+}
+
+// ProcessUserDetails returns the public details of the user with the provided
+// uuid.
+func (b *backend) ProcessUserDetails(ud www.UserDetails) (*www.UserDetailsReply, error) {
+	user, err := b.db.UserGetByUUID(ud.UUID)
+	if err != nil {
+		if err == database.ErrUserNotFound {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusUserNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	activeIdentity, ok := database.ActiveIdentityString(user.Identities)
+	if !ok {
+		activeIdentity = ""
+	}
+
+	return &www.UserDetailsReply{
+		UUID:        user.UUID,
+		UserID:      strconv.FormatUint(user.ID, 10),
+		PublicKey:   activeIdentity,
+		Admin:       user.Admin,
+		Deactivated: user.Deactivated != 0,
+	}, nil
+}
+
+// ProcessDeactivateUser deactivates the account of the provided user once the
+// user confirmed it with the password.
+func (b *backend) ProcessDeactivateUser(user *database.User, du www.DeactivateUser) (*www.DeactivateUserReply, error) {
+	err := b.verifyPassword(user, du.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Deactivated = time.Now().Unix()
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("User %v deactivated", user.ID)
+
+	return &www.DeactivateUserReply{}, nil
+}
+
+// ProcessAdminDeactivateUser deactivates the account of a user on behalf of
+// the provided admin.  The deactivation is signed by the admin and recorded in
+// the admin audit log.  It awaits the approval of another admin if the server
+// requires it.
+func (b *backend) ProcessAdminDeactivateUser(adu www.AdminDeactivateUser, admin *database.User) (*www.AdminDeactivateUserReply, error) {
+	err := checkPublicKeyAndSignature(admin, adu.PublicKey, adu.Signature,
+		adu.UserID, www.AdminActionDeactivateUser)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.approvalRequired() {
+		_, err := b.getActiveUser(adu.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		pa, err := b.newPendingAction(www.PendingActionDeactivateUser,
+			adu, admin)
+		if err != nil {
+			return nil, err
+		}
+		return &www.AdminDeactivateUserReply{
+			PendingAction: pa,
+		}, nil
+	}
+
+	err = b.adminDeactivateUser(adu, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.AdminDeactivateUserReply{}, nil
+}
+
+// getActiveUser returns the user with the provided id unless it was
+// deactivated.
+func (b *backend) getActiveUser(userID string) (*database.User, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+	user, err := b.db.UserGetById(id)
+	if err != nil {
+		if err == database.ErrUserNotFound {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusUserNotFound,
+			}
+		}
+		return nil, err
+	}
+	if user.Deactivated != 0 {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusUserDeactivated,
+		}
+	}
+
+	return user, nil
+}
+
+// adminDeactivateUser deactivates the account of a user on behalf of the
+// provided admin and records the signed deactivation in the admin audit log.
+// The user is reactivated if the deactivation can't be recorded.
+func (b *backend) adminDeactivateUser(adu www.AdminDeactivateUser, admin *database.User) error {
+	user, err := b.getActiveUser(adu.UserID)
+	if err != nil {
+		return err
+	}
+
+	user.Deactivated = time.Now().Unix()
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return err
+	}
+
+	err = b.db.AdminActionNew(database.AdminAction{
+		AdminID:   admin.ID,
+		UserID:    user.ID,
+		Action:    www.AdminActionDeactivateUser,
+		PublicKey: adu.PublicKey,
+		Signature: adu.Signature,
+		Reason:    adu.Reason,
+		Timestamp: user.Deactivated,
+	})
+	if err != nil {
+		user.Deactivated = 0
+		uerr := b.db.UserUpdate(*user)
+		if uerr != nil {
+			log.Errorf("adminDeactivateUser: reactivate %v: %v",
+				user.ID, uerr)
+		}
+		return err
+	}
+
+	log.Infof("Admin %v deactivated user %v: %v", admin.ID, user.ID,
+		adu.Reason)
+
+	return nil
+}
+
+// ProcessSetUserAdmin grants or revokes the admin privileges of a user on
+// behalf of the provided admin.  The change is signed by the admin, recorded
+// in the admin audit log and notified to the user by email.
+func (b *backend) ProcessSetUserAdmin(sua www.SetUserAdmin, admin *database.User) (*www.SetUserAdminReply, error) {
+	action := www.AdminActionRevokeAdmin
+	if sua.Admin {
+		action = www.AdminActionGrantAdmin
+	}
+	err := checkPublicKeyAndSignature(admin, sua.PublicKey, sua.Signature,
+		sua.UserID, action)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.ParseUint(sua.UserID, 10, 64)
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+	// Admins can't lock themselves out.
+	if id == admin.ID {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+	user, err := b.db.UserGetById(id)
+	if err != nil {
+		if err == database.ErrUserNotFound {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusUserNotFound,
+			}
+		}
+		return nil, err
+	}
+	if user.Deactivated != 0 {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusUserDeactivated,
+		}
+	}
+	if user.Admin == sua.Admin {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+
+	user.Admin = sua.Admin
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	// The change is undone if it can't be recorded in the audit log.
+	err = b.db.AdminActionNew(database.AdminAction{
+		AdminID:   admin.ID,
+		UserID:    user.ID,
+		Action:    action,
+		PublicKey: sua.PublicKey,
+		Signature: sua.Signature,
+		Reason:    sua.Reason,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		user.Admin = !sua.Admin
+		uerr := b.db.UserUpdate(*user)
+		if uerr != nil {
+			log.Errorf("ProcessSetUserAdmin: revert %v: %v", user.ID,
+				uerr)
+		}
+		return nil, err
+	}
+
+	log.Infof("Admin %v changed admin privileges of user %v to %v: %v",
+		admin.ID, user.ID, user.Admin, sua.Reason)
+
+	err = b.emailUserAdminChanged(user.Email, user.Admin, sua.Reason)
+	if err != nil {
+		log.Errorf("ProcessSetUserAdmin: emailUserAdminChanged %v: %v",
+			user.ID, err)
+	}
+
+	return &www.SetUserAdminReply{}, nil
+}
+
+// ProcessAdminVerifyUser marks the email address of a user as verified on
+// behalf of the provided admin, for users whose verification emails can't be
+// delivered.  The override is signed by the admin, recorded in the admin audit
+// log and notified to the user by email if possible.
+func (b *backend) ProcessAdminVerifyUser(avu www.AdminVerifyUser, admin *database.User) (*www.AdminVerifyUserReply, error) {
+	err := checkPublicKeyAndSignature(admin, avu.PublicKey, avu.Signature,
+		avu.UserID, www.AdminActionVerifyUser)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := b.getActiveUser(avu.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if userVerified(user) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+
+	// Like a verification by the user, activate the key that the user
+	// signed up with.
+	clearVerificationToken(user, verificationNewUser)
+	for k, v := range user.Identities {
+		if v.Deactivated == 0 && v.Activated == 0 {
+			user.Identities[k].Activated = time.Now().Unix()
+		}
+	}
+	err = b.db.UserUpdate(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.db.AdminActionNew(database.AdminAction{
+		AdminID:   admin.ID,
+		UserID:    user.ID,
+		Action:    www.AdminActionVerifyUser,
+		PublicKey: avu.PublicKey,
+		Signature: avu.Signature,
+		Reason:    avu.Reason,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Admin %v verified user %v: %v", admin.ID, user.ID,
+		avu.Reason)
+
+	err = b.emailUserVerifiedByAdmin(user.Email, avu.Reason)
+	if err != nil {
+		log.Errorf("ProcessAdminVerifyUser: emailUserVerifiedByAdmin %v: %v",
+			user.ID, err)
+	}
+
+	return &www.AdminVerifyUserReply{}, nil
+}
+
+// ProcessAdminActions returns the admin audit log.
+func (b *backend) ProcessAdminActions() (*www.AdminActionsReply, error) {
+	actions, err := b.db.AllAdminActions()
+	if err != nil {
+		return nil, err
+	}
+
+	reply := www.AdminActionsReply{
+		Actions: make([]www.AdminAction, 0, len(actions)),
+	}
+	for _, v := range actions {
+		reply.Actions = append(reply.Actions, www.AdminAction{
+			AdminID:   strconv.FormatUint(v.AdminID, 10),
+			UserID:    strconv.FormatUint(v.UserID, 10),
+			Action:    v.Action,
+			PublicKey: v.PublicKey,
+			Signature: v.Signature,
+			Reason:    v.Reason,
+			Timestamp: v.Timestamp,
+		})
+	}
+	return &reply, nil
+}
@@ -0,0 +1,351 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+func TestProcessUsers(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	// Create the users in reverse email order so that the sort orders
+	// differ.
+	count := www.UserListPageSize + 5
+	for i := count - 1; i >= 0; i-- {
+		err := b.db.UserNew(database.User{
+			Email: fmt.Sprintf("user%02d@example.com", i),
+		})
+		assertSuccess(t, err)
+	}
+
+	// Page through the users in both orders.
+	for _, sort := range []string{www.UserSortCreated, www.UserSortEmail} {
+		var users []www.AbridgedUser
+		u := www.Users{Sort: sort}
+		for {
+			reply, err := b.ProcessUsers(u)
+			assertSuccess(t, err)
+			if len(reply.Users) > www.UserListPageSize {
+				t.Fatalf("%v: page of %v users", sort, len(reply.Users))
+			}
+			users = append(users, reply.Users...)
+			if reply.Next == "" {
+				break
+			}
+			u.Start = reply.Next
+		}
+		if len(users) != count {
+			t.Fatalf("%v: got %v users, want %v", sort, len(users), count)
+		}
+
+		for k, v := range users {
+			want := fmt.Sprintf("user%02d@example.com", k)
+			if sort == www.UserSortCreated {
+				want = strconv.Itoa(k)
+				if v.ID != want {
+					t.Fatalf("%v: got id %v, want %v", sort, v.ID, want)
+				}
+			} else if v.Email != want {
+				t.Fatalf("%v: got email %v, want %v", sort, v.Email,
+					want)
+			}
+		}
+	}
+
+	_, err := b.ProcessUsers(www.Users{Sort: "invalid"})
+	assertError(t, err, www.ErrorStatusInvalidInput)
+	_, err = b.ProcessUsers(www.Users{Start: "invalid"})
+	assertError(t, err, www.ErrorStatusInvalidInput)
+}
+
+func TestProcessUserSearch(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	publicKey := hex.EncodeToString(id.Public.Key[:])
+	err := b.db.UserNew(database.User{Email: "other@example.com"})
+	assertSuccess(t, err)
+
+	// Search is disabled by default.
+	us := www.UserSearch{Email: nu.Email}
+	_, err = b.ProcessUserSearch(us)
+	assertErrorWithContext(t, err, www.ErrorStatusFeatureDisabled,
+		[]string{www.FeatureSearch})
+	b.setFeature(www.FeatureSearch, true)
+
+	_, err = b.ProcessUserSearch(www.UserSearch{})
+	assertError(t, err, www.ErrorStatusInvalidInput)
+
+	tests := []struct {
+		search www.UserSearch
+		count  int
+	}{
+		{www.UserSearch{Email: strings.ToUpper(nu.Email[:4])}, 1},
+		{www.UserSearch{Email: "@"}, 2},
+		{www.UserSearch{PublicKey: publicKey}, 1},
+		{www.UserSearch{PublicKey: strings.ToUpper(publicKey)}, 1},
+		{www.UserSearch{PublicKey: publicKey, Email: "other"}, 0},
+		{www.UserSearch{PublicKey: "invalid"}, 0},
+	}
+	for _, test := range tests {
+		reply, err := b.ProcessUserSearch(test.search)
+		assertSuccess(t, err)
+		if len(reply.Users) != test.count {
+			t.Fatalf("%v: got %v users, want %v", test.search,
+				len(reply.Users), test.count)
+		}
+		if test.count == 1 && reply.Users[0].PublicKey != publicKey {
+			t.Fatalf("%v: unexpected user %v", test.search,
+				reply.Users[0])
+		}
+	}
+}
+
+func TestProcessUserDetails(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	if user.UUID == "" {
+		t.Fatalf("user has no uuid")
+	}
+
+	// The uuid doesn't change with the email.
+	oldEmail := user.Email
+	user.Email = generateRandomEmail()
+	err = b.db.UserChangeEmail(oldEmail, *user)
+	assertSuccess(t, err)
+
+	reply, err := b.ProcessUserDetails(www.UserDetails{UUID: user.UUID})
+	assertSuccess(t, err)
+	if reply.UUID != user.UUID ||
+		reply.UserID != strconv.FormatUint(user.ID, 10) ||
+		reply.PublicKey != hex.EncodeToString(id.Public.Key[:]) {
+		t.Fatalf("unexpected user details %v", reply)
+	}
+
+	_, err = b.ProcessUserDetails(www.UserDetails{
+		UUID: "00000000-0000-4000-8000-000000000000",
+	})
+	assertError(t, err, www.ErrorStatusUserNotFound)
+}
+
+func TestProcessDeactivateUser(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	anu, id := createAndVerifyUser(t, b)
+	admin, err := b.db.UserGet(anu.Email)
+	assertSuccess(t, err)
+	admin.Admin = true
+	assertSuccess(t, b.db.UserUpdate(*admin))
+	adminDeactivateUser := func(userID, reason string) www.AdminDeactivateUser {
+		sig := id.SignMessage([]byte(userID +
+			www.AdminActionDeactivateUser))
+		return www.AdminDeactivateUser{
+			UserID:    userID,
+			Reason:    reason,
+			PublicKey: id.Public.String(),
+			Signature: hex.EncodeToString(sig[:]),
+		}
+	}
+
+	nu, _ := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+
+	_, err = b.ProcessDeactivateUser(user, www.DeactivateUser{
+		Password: "invalid",
+	})
+	assertError(t, err, www.ErrorStatusInvalidEmailOrPassword)
+	_, err = b.ProcessDeactivateUser(user, www.DeactivateUser{
+		Password: nu.Password,
+	})
+	assertSuccess(t, err)
+
+	// Deactivated users can't log in or submit proposals.
+	_, err = b.ProcessLogin(www.Login{
+		Email:    nu.Email,
+		Password: nu.Password,
+	})
+	assertError(t, err, www.ErrorStatusUserDeactivated)
+	_, err = b.ProcessNewProposal(context.Background(), www.NewProposal{},
+		user)
+	assertError(t, err, www.ErrorStatusUserDeactivated)
+
+	// Admins deactivate users by id.
+	nu, _ = createAndVerifyUser(t, b)
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	userID := strconv.FormatUint(user.ID, 10)
+
+	// The deactivation must be signed by the admin.
+	adu := adminDeactivateUser(userID, "spam")
+	adu.Signature = adminDeactivateUser("1000", "spam").Signature
+	_, err = b.ProcessAdminDeactivateUser(adu, admin)
+	assertError(t, err, www.ErrorStatusInvalidSignature)
+
+	adu = adminDeactivateUser(userID, "spam")
+	_, err = b.ProcessAdminDeactivateUser(adu, admin)
+	assertSuccess(t, err)
+	_, err = b.ProcessAdminDeactivateUser(adu, admin)
+	assertError(t, err, www.ErrorStatusUserDeactivated)
+	_, err = b.ProcessLogin(www.Login{
+		Email:    nu.Email,
+		Password: nu.Password,
+	})
+	assertError(t, err, www.ErrorStatusUserDeactivated)
+
+	_, err = b.ProcessAdminDeactivateUser(adminDeactivateUser("1000", ""),
+		admin)
+	assertError(t, err, www.ErrorStatusUserNotFound)
+
+	// The deactivation is recorded in the audit log.
+	reply, err := b.ProcessAdminActions()
+	assertSuccess(t, err)
+	if len(reply.Actions) != 1 ||
+		reply.Actions[0].Action != www.AdminActionDeactivateUser ||
+		reply.Actions[0].UserID != userID ||
+		reply.Actions[0].AdminID != strconv.FormatUint(admin.ID, 10) ||
+		reply.Actions[0].Signature != adu.Signature ||
+		reply.Actions[0].Reason != "spam" {
+		t.Fatalf("unexpected audit log %v", reply.Actions)
+	}
+}
+
+func TestSetUserAdmin(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	anu, id := createAndVerifyUser(t, b)
+	admin, err := b.db.UserGet(anu.Email)
+	assertSuccess(t, err)
+	admin.Admin = true
+	assertSuccess(t, b.db.UserUpdate(*admin))
+
+	nu, _ := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+
+	setAdmin := func(userID string, isAdmin bool) error {
+		action := www.AdminActionRevokeAdmin
+		if isAdmin {
+			action = www.AdminActionGrantAdmin
+		}
+		sig := id.SignMessage([]byte(userID + action))
+		_, err := b.ProcessSetUserAdmin(www.SetUserAdmin{
+			UserID:    userID,
+			Admin:     isAdmin,
+			Reason:    "moderator",
+			PublicKey: id.Public.String(),
+			Signature: hex.EncodeToString(sig[:]),
+		}, admin)
+		return err
+	}
+	userID := strconv.FormatUint(user.ID, 10)
+
+	// The signature must match the requested change.
+	sig := id.SignMessage([]byte(userID + www.AdminActionRevokeAdmin))
+	_, err = b.ProcessSetUserAdmin(www.SetUserAdmin{
+		UserID:    userID,
+		Admin:     true,
+		PublicKey: id.Public.String(),
+		Signature: hex.EncodeToString(sig[:]),
+	}, admin)
+	assertError(t, err, www.ErrorStatusInvalidSignature)
+
+	assertSuccess(t, setAdmin(userID, true))
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	if !user.Admin {
+		t.Fatalf("admin privileges not granted")
+	}
+	assertError(t, setAdmin(userID, true), www.ErrorStatusInvalidInput)
+	assertSuccess(t, setAdmin(userID, false))
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	if user.Admin {
+		t.Fatalf("admin privileges not revoked")
+	}
+
+	// Admins can't change their own privileges.
+	assertError(t, setAdmin(strconv.FormatUint(admin.ID, 10), false),
+		www.ErrorStatusInvalidInput)
+	assertError(t, setAdmin("1000", true), www.ErrorStatusUserNotFound)
+
+	reply, err := b.ProcessAdminActions()
+	assertSuccess(t, err)
+	if len(reply.Actions) != 2 ||
+		reply.Actions[0].Action != www.AdminActionGrantAdmin ||
+		reply.Actions[0].UserID != userID ||
+		reply.Actions[0].AdminID != strconv.FormatUint(admin.ID, 10) ||
+		reply.Actions[1].Action != www.AdminActionRevokeAdmin {
+		t.Fatalf("unexpected audit log %v", reply.Actions)
+	}
+}
+
+func TestProcessAdminVerifyUser(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	anu, id := createAndVerifyUser(t, b)
+	admin, err := b.db.UserGet(anu.Email)
+	assertSuccess(t, err)
+	admin.Admin = true
+	assertSuccess(t, b.db.UserUpdate(*admin))
+
+	nu, _ := createNewUserCommandWithIdentity(t)
+	_, err = b.ProcessNewUser(nu)
+	assertSuccess(t, err)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	userID := strconv.FormatUint(user.ID, 10)
+
+	verify := func() error {
+		sig := id.SignMessage([]byte(userID + www.AdminActionVerifyUser))
+		_, err := b.ProcessAdminVerifyUser(www.AdminVerifyUser{
+			UserID:    userID,
+			Reason:    "mail bounces",
+			PublicKey: id.Public.String(),
+			Signature: hex.EncodeToString(sig[:]),
+		}, admin)
+		return err
+	}
+	assertSuccess(t, verify())
+	user, err = b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	if !userVerified(user) || user.Identities[0].Activated == 0 {
+		t.Fatalf("user was not verified")
+	}
+	_, err = b.ProcessLogin(www.Login{
+		Email:    nu.Email,
+		Password: nu.Password,
+	})
+	assertSuccess(t, err)
+
+	// Verified users can't be verified again.
+	assertError(t, verify(), www.ErrorStatusInvalidInput)
+
+	reply, err := b.ProcessAdminActions()
+	assertSuccess(t, err)
+	if len(reply.Actions) != 1 ||
+		reply.Actions[0].Action != www.AdminActionVerifyUser ||
+		reply.Actions[0].UserID != userID {
+		t.Fatalf("unexpected audit log %v", reply.Actions)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strconv"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+// userStats are the statistics of a user.
+type userStats struct {
+	proposals int // Proposals submitted
+	published int // Proposals published
+	censored  int // Proposals censored
+	voted     int // Proposals put up for a vote
+	comments  int // Comments written
+}
+
+// recordStats is the contribution of an inventory record to the statistics
+// of the users and of the proposals.
+type recordStats struct {
+	author    string          // User ID of the author
+	status    www.PropStatusT // Current status of the proposal
+	voted     bool            // Whether the vote started
+	comments  map[string]int  // [userid]comments
+	submitted int64           // Submission time of the proposal
+	timestamp int64           // Last update of the proposal
+	censored  int64           // Censorship time, 0 if not censored
+}
+
+// addUserStats adds n times the contribution of the provided record to the
+// statistics of the users.
+//
+// This function must be called WITH the mutex held.
+func (b *backend) addUserStats(s recordStats, n int) {
+	get := func(userID string) *userStats {
+		us, ok := b.userStats[userID]
+		if !ok {
+			us = &userStats{}
+			b.userStats[userID] = us
+		}
+		return us
+	}
+
+	if s.author != "" {
+		us := get(s.author)
+		us.proposals += n
+		switch s.status {
+		case www.PropStatusPublic, www.PropStatusLocked:
+			us.published += n
+		case www.PropStatusCensored:
+			us.censored += n
+		}
+		if s.voted {
+			us.voted += n
+		}
+	}
+	for userID, comments := range s.comments {
+		get(userID).comments += n * comments
+	}
+}
+
+// indexUserStats updates the statistics of the users and of the proposals,
+// and the status index, with the current state of the inventory record with
+// the provided token.  Only the previous contribution of the record is
+// replaced so that the inventory doesn't have to be scanned.
+//
+// This function must be called WITH the mutex held.
+func (b *backend) indexUserStats(token string) {
+	if b.userStats == nil {
+		b.userStats = make(map[string]*userStats)
+		b.recordStats = make(map[string]recordStats)
+		b.proposalStats = newProposalStats()
+		b.statusIndex = make(map[www.PropStatusT][]statusIndexEntry)
+	}
+
+	if s, ok := b.recordStats[token]; ok {
+		b.addUserStats(s, -1)
+		b.addProposalStats(s, -1)
+		b.unindexStatus(s, token)
+		delete(b.recordStats, token)
+	}
+	ir, ok := b.inventory[token]
+	if !ok {
+		return
+	}
+
+	proposal := convertPropFromPD(ir.record)
+	if proposal.UserId == "" {
+		proposal.UserId = b.userPubkeys[proposal.PublicKey]
+	}
+	s := recordStats{
+		author:    proposal.UserId,
+		status:    proposal.Status,
+		voted:     ir.voting.StartBlockHeight != "",
+		comments:  make(map[string]int),
+		submitted: proposalSubmitted(ir.record, proposal.Timestamp),
+		timestamp: proposal.Timestamp,
+	}
+	for _, v := range ir.changes {
+		s.status = convertPropStatusFromPD(v.NewStatus)
+		if v.NewStatus == pd.RecordStatusCensored {
+			s.censored = v.Timestamp
+		}
+	}
+	for _, v := range ir.comments {
+		s.comments[v.UserID]++
+	}
+	b.recordStats[token] = s
+	b.addUserStats(s, 1)
+	b.addProposalStats(s, 1)
+	b.indexStatus(s, token)
+}
+
+// ProcessUserStats returns the statistics of the provided user.
+func (b *backend) ProcessUserStats(us www.UserStats) (*www.UserStatsReply, error) {
+	if _, err := strconv.ParseUint(us.UserID, 10, 64); err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidInput,
+		}
+	}
+
+	b.RLock()
+	defer b.RUnlock()
+
+	var reply www.UserStatsReply
+	if s, ok := b.userStats[us.UserID]; ok {
+		reply = www.UserStatsReply{
+			Proposals: uint64(s.proposals),
+			Published: uint64(s.published),
+			Censored:  uint64(s.censored),
+			Voted:     uint64(s.voted),
+			Comments:  uint64(s.comments),
+		}
+	}
+	return &reply, nil
+}
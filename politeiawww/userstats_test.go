@@ -0,0 +1,83 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestUserStats(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	u, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(u.Email)
+	assertSuccess(t, err)
+	userID := strconv.FormatUint(user.ID, 10)
+
+	_, err = b.ProcessUserStats(www.UserStats{UserID: "invalid"})
+	assertError(t, err, www.ErrorStatusInvalidInput)
+	usr, err := b.ProcessUserStats(www.UserStats{UserID: userID})
+	assertSuccess(t, err)
+	if *usr != (www.UserStatsReply{}) {
+		t.Fatalf("unexpected stats %v", *usr)
+	}
+
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	_, _, err = createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+
+	// Publish the first proposal, comment on it and start its vote the
+	// way politeiad replies update the inventory.
+	b.Lock()
+	record := b.inventory[token].record
+	record.Status = pd.RecordStatusPublic
+	b.updateInventoryRecord(record)
+	_, err = b.addComment(www.NewComment{
+		Token:    token,
+		ParentID: "0",
+		Comment:  "comment",
+	}, user.ID)
+	if err != nil {
+		b.Unlock()
+		t.Fatal(err)
+	}
+	b.inventory[token].voting.StartBlockHeight = "1"
+	b.indexUserStats(token)
+	b.Unlock()
+
+	usr, err = b.ProcessUserStats(www.UserStats{UserID: userID})
+	assertSuccess(t, err)
+	expected := www.UserStatsReply{
+		Proposals: 2,
+		Published: 1,
+		Voted:     1,
+		Comments:  1,
+	}
+	if *usr != expected {
+		t.Fatalf("got stats %v, expected %v", *usr, expected)
+	}
+
+	// Records that are replaced only count once.
+	b.Lock()
+	record.Status = pd.RecordStatusCensored
+	b.updateInventoryRecord(record)
+	b.Unlock()
+	usr, err = b.ProcessUserStats(www.UserStats{UserID: userID})
+	assertSuccess(t, err)
+	expected = www.UserStatsReply{
+		Proposals: 2,
+		Censored:  1,
+	}
+	if *usr != expected {
+		t.Fatalf("got stats %v, expected %v", *usr, expected)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database/localdb"
+	"github.com/decred/politeia/util"
+)
+
+// validateTimeout is the timeout of the connectivity checks.
+const validateTimeout = 30 * time.Second
+
+// dcrdataStatusURL returns the status URL of the dcrdata instance that is
+// used to verify paywall transactions on the provided network.
+func dcrdataStatusURL(params *chaincfg.Params) string {
+	if params.Name == chaincfg.MainNetParams.Name {
+		return "https://explorer.dcrdata.org/api/status"
+	}
+	return "https://testnet.dcrdata.org/api/status"
+}
+
+// validate checks the configuration files, the user database and the
+// connectivity to the email provider, dcrdata and politeiad.  The checks of
+// the features that aren't configured are skipped and none of the checks
+// modify any state.  It prints a report and returns an error if any of the
+// checks failed.
+func validate(cfg *config) error {
+	checks := []util.Check{
+		{
+			Name: "https certificate and key",
+			Run: func() error {
+				if len(cfg.AutoCertHosts) != 0 {
+					return util.SkipCheck("obtained from " +
+						"Let's Encrypt")
+				}
+				_, err := tls.LoadX509KeyPair(cfg.HTTPSCert,
+					cfg.HTTPSKey)
+				return err
+			},
+		},
+		{
+			Name: "politeiad certificate",
+			Run: func() error {
+				cert, err := ioutil.ReadFile(cfg.RPCCert)
+				if err != nil {
+					return err
+				}
+				if !x509.NewCertPool().AppendCertsFromPEM(cert) {
+					return fmt.Errorf("no certificates found "+
+						"in %v", cfg.RPCCert)
+				}
+				return nil
+			},
+		},
+		{
+			Name: "politeiad identity",
+			Run: func() error {
+				_, err := identity.LoadPublicIdentity(
+					cfg.RPCIdentityFile)
+				return err
+			},
+		},
+		{
+			Name: "user database",
+			Run: func() error {
+				// The database is opened read only so that
+				// validating doesn't create or upgrade it.
+				return localdb.Verify(cfg.DataDir)
+			},
+		},
+		{
+			Name: "email provider",
+			Run: func() error {
+				if cfg.Mailer == nil {
+					return util.SkipCheck("not configured, " +
+						"emails are disabled")
+				}
+				return cfg.Mailer.Verify()
+			},
+		},
+		{
+			Name: "dcrdata",
+			Run: func() error {
+				// dcrdata is only used to verify paywall
+				// payments.
+				if cfg.PaywallXpub == "" {
+					return util.SkipCheck("paywall not " +
+						"configured")
+				}
+				for _, v := range cfg.DisableFeatures {
+					if v == www.FeaturePaywall {
+						return util.SkipCheck("paywall " +
+							"disabled")
+					}
+				}
+				client := &http.Client{Timeout: validateTimeout}
+				r, err := client.Get(dcrdataStatusURL(
+					activeNetParams.Params))
+				if err != nil {
+					return err
+				}
+				r.Body.Close()
+				if r.StatusCode != http.StatusOK {
+					return fmt.Errorf("%v", r.Status)
+				}
+				return nil
+			},
+		},
+		{
+			Name: "politeiad",
+			Run: func() error {
+				remote, err := util.RemoteIdentity(false,
+					cfg.RPCHost, cfg.RPCCert)
+				if err != nil {
+					return err
+				}
+				local, err := identity.LoadPublicIdentity(
+					cfg.RPCIdentityFile)
+				if err != nil {
+					return nil // Reported by the identity check
+				}
+				if !bytes.Equal(remote.Key[:], local.Key[:]) {
+					return fmt.Errorf("politeiad identity " +
+						"does not match the identity file")
+				}
+				return nil
+			},
+		},
+	}
+
+	fmt.Printf("Validating politeiawww configuration\n")
+	return util.RunChecks(os.Stdout, checks)
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+	"github.com/decred/politeia/util"
+)
+
+// Types of the verification tokens that are emailed to users.
+const (
+	verificationNewUser       = database.VerificationTokenNewUser
+	verificationResetPassword = database.VerificationTokenResetPassword
+	verificationChangeEmail   = database.VerificationTokenChangeEmail
+	verificationUpdateKey     = database.VerificationTokenUpdateKey
+)
+
+// getVerificationExpiryTime returns the time the verification tokens of the
+// provided type are valid.  The expiries that are not configured default to
+// www.VerificationExpiryHours.
+func (b *backend) getVerificationExpiryTime(t database.VerificationTokenT) time.Duration {
+	if b.verificationExpiryTime != time.Duration(0) {
+		return b.verificationExpiryTime
+	}
+
+	var expiry time.Duration
+	switch t {
+	case verificationNewUser:
+		expiry = b.cfg.NewUserTokenExpiry
+	case verificationResetPassword:
+		expiry = b.cfg.ResetPasswordTokenExpiry
+	case verificationChangeEmail:
+		expiry = b.cfg.ChangeEmailTokenExpiry
+	}
+	if expiry != time.Duration(0) {
+		return expiry
+	}
+	return time.Duration(www.VerificationExpiryHours) * time.Hour
+}
+
+// hashVerificationToken returns the digest of the provided verification token
+// that is stored in the database.
+func hashVerificationToken(token []byte) []byte {
+	hash := sha256.Sum256(token)
+	return hash[:]
+}
+
+// verificationToken returns the verification token of the provided type of
+// the provided user, or nil if the user has none.
+func verificationToken(user *database.User, t database.VerificationTokenT) *database.VerificationToken {
+	for k, v := range user.VerificationTokens {
+		if v.Type == t {
+			return &user.VerificationTokens[k]
+		}
+	}
+	return nil
+}
+
+// pendingVerificationToken returns true if the provided user has a
+// verification token of the provided type that hasn't expired.
+func pendingVerificationToken(user *database.User, t database.VerificationTokenT) bool {
+	vt := verificationToken(user, t)
+	return vt != nil && time.Now().Unix() < vt.Expiry
+}
+
+// userVerified returns true if the provided user verified its email address.
+// The new user token is kept until then, even once it expired.
+func userVerified(user *database.User) bool {
+	return verificationToken(user, verificationNewUser) == nil
+}
+
+// issueVerificationToken generates a single-use verification token of the
+// provided type for the provided user and returns it.  The token replaces the
+// previous token of the same type.  The caller must update the user in the
+// database.
+func (b *backend) issueVerificationToken(user *database.User, t database.VerificationTokenT) ([]byte, error) {
+	token, err := util.Random(www.VerificationTokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	clearVerificationToken(user, t)
+	user.VerificationTokens = append(user.VerificationTokens,
+		database.VerificationToken{
+			Type:      t,
+			Hash:      hashVerificationToken(token),
+			Expiry:    time.Now().Add(b.getVerificationExpiryTime(t)).Unix(),
+			SingleUse: true,
+		})
+
+	return token, nil
+}
+
+// verifyVerificationToken checks that the provided hex encoded token is the
+// verification token of the provided type of the provided user and that it
+// hasn't expired.  The token is not used up; see useVerificationToken.
+func verifyVerificationToken(user *database.User, t database.VerificationTokenT, token string) error {
+	decoded, err := hex.DecodeString(token)
+	if err != nil {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusVerificationTokenInvalid,
+		}
+	}
+
+	vt := verificationToken(user, t)
+	if vt == nil ||
+		subtle.ConstantTimeCompare(hashVerificationToken(decoded), vt.Hash) != 1 {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusVerificationTokenInvalid,
+		}
+	}
+	if time.Now().Unix() > vt.Expiry {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusVerificationTokenExpired,
+		}
+	}
+
+	return nil
+}
+
+// useVerificationToken removes the verification token of the provided type
+// of the provided user once it was verified, unless the token can be used
+// more than once.  The caller must update the user in the database.
+func useVerificationToken(user *database.User, t database.VerificationTokenT) {
+	vt := verificationToken(user, t)
+	if vt != nil && vt.SingleUse {
+		clearVerificationToken(user, t)
+	}
+}
+
+// clearVerificationToken removes the verification token of the provided type
+// of the provided user.  The caller must update the user in the database.
+func clearVerificationToken(user *database.User, t database.VerificationTokenT) {
+	tokens := user.VerificationTokens[:0]
+	for _, v := range user.VerificationTokens {
+		if v.Type != t {
+			tokens = append(tokens, v)
+		}
+	}
+	user.VerificationTokens = tokens
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/politeiawww/database"
+)
+
+func TestVerificationTokens(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	var user database.User
+	reset, err := b.issueVerificationToken(&user, verificationResetPassword)
+	assertSuccess(t, err)
+	email, err := b.issueVerificationToken(&user, verificationChangeEmail)
+	assertSuccess(t, err)
+
+	// Only the digests of the tokens are stored.
+	vt := verificationToken(&user, verificationResetPassword)
+	if vt == nil || !vt.SingleUse || bytes.Equal(vt.Hash, reset) {
+		t.Fatalf("unexpected token %v", vt)
+	}
+
+	// Tokens are only valid for their own type.
+	err = verifyVerificationToken(&user, verificationResetPassword,
+		hex.EncodeToString(email))
+	assertError(t, err, www.ErrorStatusVerificationTokenInvalid)
+	err = verifyVerificationToken(&user, verificationResetPassword, "zz")
+	assertError(t, err, www.ErrorStatusVerificationTokenInvalid)
+	err = verifyVerificationToken(&user, verificationResetPassword,
+		hex.EncodeToString(reset))
+	assertSuccess(t, err)
+
+	// Using a token leaves the tokens of the other types.
+	useVerificationToken(&user, verificationResetPassword)
+	err = verifyVerificationToken(&user, verificationResetPassword,
+		hex.EncodeToString(reset))
+	assertError(t, err, www.ErrorStatusVerificationTokenInvalid)
+	if !pendingVerificationToken(&user, verificationChangeEmail) {
+		t.Fatalf("change email token was removed")
+	}
+
+	// Expired tokens are rejected.
+	verificationToken(&user, verificationChangeEmail).Expiry =
+		time.Now().Unix() - 1
+	err = verifyVerificationToken(&user, verificationChangeEmail,
+		hex.EncodeToString(email))
+	assertError(t, err, www.ErrorStatusVerificationTokenExpired)
+	if pendingVerificationToken(&user, verificationChangeEmail) {
+		t.Fatalf("expired token is pending")
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// viewFlushInterval is the interval at which the proposal views are
+	// written to the database.
+	viewFlushInterval = time.Minute
+
+	// viewWindow is the period during which the views of a proposal by the
+	// same viewer are counted once.
+	viewWindow = 30 * time.Minute
+
+	// viewMaxViewers is the number of viewers that are remembered at most.
+	// Views by new viewers aren't counted until the window of the older
+	// ones elapses.
+	viewMaxViewers = 100000
+)
+
+// viewCounter counts the views of the proposals.  Views are counted in memory
+// and written to the database in batches so that views don't cost a write
+// each.
+type viewCounter struct {
+	sync.Mutex
+
+	views   map[string]uint64 // [token]views, including the pending ones
+	pending map[string]uint64 // [token]views that are not written yet
+	seen    map[string]int64  // [token+viewer]time of the counted view
+}
+
+// newViewCounter returns a view counter that starts at the provided counts.
+func newViewCounter(views map[string]uint64) *viewCounter {
+	if views == nil {
+		views = make(map[string]uint64)
+	}
+	return &viewCounter{
+		views:   views,
+		pending: make(map[string]uint64),
+		seen:    make(map[string]int64),
+	}
+}
+
+// view counts a view of the proposal with the provided token by the provided
+// viewer at the provided unix time, unless the viewer already viewed it
+// within the view window.  It returns whether the view was counted.
+func (c *viewCounter) view(token, viewer string, now int64) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	key := token + "\x00" + viewer
+	if ts, ok := c.seen[key]; ok && now-ts < int64(viewWindow.Seconds()) {
+		return false
+	}
+	if len(c.seen) >= viewMaxViewers {
+		return false
+	}
+	c.seen[key] = now
+	c.views[token]++
+	c.pending[token]++
+	return true
+}
+
+// count returns the number of views of the proposal with the provided token.
+func (c *viewCounter) count(token string) uint64 {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.views[token]
+}
+
+// takePending returns the views that are not written yet and forgets the
+// viewers whose window elapsed at the provided unix time.
+func (c *viewCounter) takePending(now int64) map[string]uint64 {
+	c.Lock()
+	defer c.Unlock()
+
+	for k, ts := range c.seen {
+		if now-ts >= int64(viewWindow.Seconds()) {
+			delete(c.seen, k)
+		}
+	}
+
+	pending := c.pending
+	c.pending = make(map[string]uint64)
+	return pending
+}
+
+// restorePending puts back the provided views that could not be written.
+func (c *viewCounter) restorePending(pending map[string]uint64) {
+	c.Lock()
+	defer c.Unlock()
+
+	for k, v := range pending {
+		c.pending[k] += v
+	}
+}
+
+// recordProposalView counts a view of the proposal with the provided token by
+// the provided viewer, which is either a user or an IP address.
+func (b *backend) recordProposalView(token, viewer string) {
+	b.views.view(token, viewer, time.Now().Unix())
+}
+
+// flushProposalViews writes the views that were counted since the last flush
+// to the database.
+func (b *backend) flushProposalViews() error {
+	pending := b.views.takePending(time.Now().Unix())
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := b.db.ProposalViewsAdd(pending)
+	if err != nil {
+		b.views.restorePending(pending)
+		return err
+	}
+
+	return nil
+}
+
+// viewWorker periodically writes the proposal views to the database, and
+// once more when the backend shuts down.
+func (b *backend) viewWorker() {
+	ticker := time.NewTicker(viewFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.quit:
+			err := b.flushProposalViews()
+			if err != nil {
+				log.Errorf("viewWorker: %v", err)
+			}
+			return
+		}
+
+		err := b.flushProposalViews()
+		if err != nil {
+			log.Errorf("viewWorker: %v", err)
+		}
+	}
+}
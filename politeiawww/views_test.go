@@ -0,0 +1,91 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestViewCounter(t *testing.T) {
+	c := newViewCounter(map[string]uint64{"a": 3})
+	window := int64(viewWindow.Seconds())
+
+	if !c.view("a", "ip:1", 100) || !c.view("a", "ip:2", 100) ||
+		!c.view("b", "ip:1", 100) {
+		t.Fatalf("expected the first views to be counted")
+	}
+	if c.view("a", "ip:1", 100+window-1) {
+		t.Fatalf("expected the view within the window not to be counted")
+	}
+	if c.count("a") != 5 || c.count("b") != 1 {
+		t.Fatalf("unexpected counts %v %v", c.count("a"), c.count("b"))
+	}
+
+	// The pending views are handed over once, and the viewers are
+	// forgotten once their window elapses.
+	pending := c.takePending(100 + window)
+	if len(pending) != 2 || pending["a"] != 2 || pending["b"] != 1 {
+		t.Fatalf("unexpected pending views %v", pending)
+	}
+	if len(c.takePending(100+window)) != 0 || len(c.seen) != 0 {
+		t.Fatalf("expected no pending views and viewers")
+	}
+	c.restorePending(pending)
+	if !c.view("a", "ip:1", 100+window) {
+		t.Fatalf("expected the view after the window to be counted")
+	}
+	if c.count("a") != 6 || c.takePending(0)["a"] != 3 {
+		t.Fatalf("unexpected count %v", c.count("a"))
+	}
+}
+
+func TestProposalViews(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+	publishProposal(b, token, t, user, id)
+	_, npr2, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token2 := npr2.CensorshipRecord.Token
+	publishProposal(b, token2, t, user, id)
+
+	b.recordProposalView(token, "ip:1")
+	b.recordProposalView(token, "ip:1")
+	b.recordProposalView(token, "ip:2")
+	b.recordProposalView(token2, "ip:1")
+
+	vr, err := b.ProcessAllVetted(context.Background(),
+		www.GetAllVetted{Sort: www.ProposalSortMostViewed})
+	assertSuccess(t, err)
+	if len(vr.Proposals) != 2 ||
+		vr.Proposals[0].CensorshipRecord.Token != token ||
+		vr.Proposals[0].Views != 2 || vr.Proposals[1].Views != 1 {
+		t.Fatalf("unexpected proposals %v", vr.Proposals)
+	}
+
+	// The views are written in a batch.
+	assertSuccess(t, b.flushProposalViews())
+	views, err := b.db.AllProposalViews()
+	assertSuccess(t, err)
+	if views[token] != 2 || views[token2] != 1 {
+		t.Fatalf("unexpected views %v", views)
+	}
+	b.recordProposalView(token2, "ip:2")
+	assertSuccess(t, b.flushProposalViews())
+	views, err = b.db.AllProposalViews()
+	assertSuccess(t, err)
+	if views[token] != 2 || views[token2] != 2 {
+		t.Fatalf("unexpected views %v", views)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/util"
+)
+
+// ProcessVoteReceipt replies with the vote that a ticket cast on a proposal
+// and the signature of politeiad that proves that the vote was accepted.  It
+// only fetches that vote from politeiad instead of all the cast votes.
+func (b *backend) ProcessVoteReceipt(ctx context.Context, vr www.VoteReceipt) (*www.VoteReceiptReply, error) {
+	log.Tracef("ProcessVoteReceipt: %v %v", vr.Token, vr.Ticket)
+
+	b.RLock()
+	ir, ok := b.inventory[vr.Token]
+	var (
+		status www.PropStatusT
+		voting decredplugin.StartVoteReply
+	)
+	if ok {
+		status = convertPropStatusFromPD(ir.record.Status)
+		voting = ir.voting
+	}
+	b.RUnlock()
+	if !ok || (status != www.PropStatusPublic &&
+		status != www.PropStatusLocked) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+
+	// Votes are only cast once the vote started.
+	if voting.StartBlockHeight == "" {
+		return nil, www.UserError{
+			ErrorCode:    www.ErrorStatusVoteNotFound,
+			ErrorContext: []string{vr.Ticket},
+		}
+	}
+
+	if b.test {
+		return &www.VoteReceiptReply{}, nil
+	}
+
+	payload, err := decredplugin.EncodeVoteReceipt(decredplugin.VoteReceipt{
+		Token:  vr.Token,
+		Ticket: vr.Ticket,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	pc := pd.PluginCommand{
+		Challenge: hex.EncodeToString(challenge),
+		ID:        decredplugin.ID,
+		Command:   decredplugin.CmdVoteReceipt,
+		CommandID: decredplugin.CmdVoteReceipt + " " + vr.Token,
+		Payload:   string(payload),
+	}
+
+	responseBody, err := b.makeRequest(ctx, http.MethodPost,
+		pd.PluginCommandRoute, pc)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply pd.PluginCommandReply
+	err = json.Unmarshal(responseBody, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal "+
+			"PluginCommandReply: %v", err)
+	}
+
+	// Verify the challenge.
+	err = util.VerifyChallenge(b.cfg.Identity, challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	vrr, err := decredplugin.DecodeVoteReceiptReply([]byte(reply.Payload))
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the receipt is the one that politeiad signed.
+	sig, err := util.ConvertSignature(vrr.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if !b.cfg.Identity.VerifyMessage([]byte(vrr.CastVote.Signature), sig) {
+		return nil, fmt.Errorf("invalid vote receipt signature")
+	}
+
+	return &www.VoteReceiptReply{
+		CastVote:  vrr.CastVote,
+		Signature: vrr.Signature,
+	}, nil
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/decred/politeia/decredplugin"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestVoteReceipt(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+
+	// Unvetted proposals don't have votes.
+	vr := www.VoteReceipt{Token: token, Ticket: "ticket"}
+	_, err = b.ProcessVoteReceipt(context.Background(), vr)
+	assertError(t, err, www.ErrorStatusProposalNotFound)
+
+	// Neither do proposals whose vote did not start.
+	publishProposal(b, token, t, user, id)
+	_, err = b.ProcessVoteReceipt(context.Background(), vr)
+	assertErrorWithContext(t, err, www.ErrorStatusVoteNotFound,
+		[]string{"ticket"})
+
+	b.Lock()
+	b.inventory[token].voting = decredplugin.StartVoteReply{
+		StartBlockHeight: "100",
+		EndHeight:        "200",
+	}
+	b.Unlock()
+	_, err = b.ProcessVoteReceipt(context.Background(), vr)
+	assertSuccess(t, err)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/decred/politeia/decredplugin"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+// voteStatus summarizes the provided vote at the provided best block.  The
+// cast votes are only counted once the vote started.
+func voteStatus(token string, vote decredplugin.Vote, voting decredplugin.StartVoteReply, castVotes []decredplugin.CastVote, bestBlock uint64) (*www.VoteStatusReply, error) {
+	return voteStatusBits(token, vote, voting, countVoteBits(castVotes),
+		bestBlock)
+}
+
+// voteStatusBits is voteStatus for the number of cast votes of each vote bit.
+func voteStatusBits(token string, vote decredplugin.Vote, voting decredplugin.StartVoteReply, voteBits map[string]uint64, bestBlock uint64) (*www.VoteStatusReply, error) {
+	reply := www.VoteStatusReply{
+		Token:         token,
+		Status:        www.PropVoteStatusNotStarted,
+		BestBlock:     bestBlock,
+		OptionsResult: []www.VoteOptionResult{},
+	}
+	if voting.StartBlockHeight == "" {
+		return &reply, nil
+	}
+
+	var err error
+	reply.StartBlockHeight, err = strconv.ParseUint(voting.StartBlockHeight,
+		10, 64)
+	if err != nil {
+		return nil, err
+	}
+	reply.EndHeight, err = strconv.ParseUint(voting.EndHeight, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	reply.Status = www.PropVoteStatusActive
+	if bestBlock > reply.EndHeight {
+		reply.Status = www.PropVoteStatusFinished
+	}
+	// Votes that predate the vote parameters use the defaults.
+	vote.SetDefaults()
+	reply.Type = vote.Type
+	reply.EligibleTickets = uint64(len(voting.EligibleTickets))
+	reply.QuorumVotes = quorumVotes(vote, reply.EligibleTickets)
+	reply.PassPercentage = vote.PassPercentage
+
+	// The results are in the order of the options.
+	results, winner := tallyVoteBits(vote, reply.EligibleTickets, voteBits)
+	for k, v := range results {
+		reply.OptionsResult = append(reply.OptionsResult,
+			www.VoteOptionResult{
+				Option:        vote.Options[k],
+				VotesReceived: v.Votes,
+			})
+		reply.TotalVotes += v.Votes
+	}
+	if winner != nil {
+		reply.Winner = winner.Id
+		reply.Passing = true
+	}
+
+	return &reply, nil
+}
+
+// ProcessVoteStatus returns the summary of the vote on a public proposal, so
+// that clients don't need to download and count its cast votes.  The votes
+// are counted from the cached tally of the proposal.
+func (b *backend) ProcessVoteStatus(ctx context.Context, vs www.VoteStatus) (*www.VoteStatusReply, error) {
+	log.Tracef("ProcessVoteStatus: %v", vs.Token)
+
+	b.RLock()
+	ir, ok := b.inventory[vs.Token]
+	var (
+		status www.PropStatusT
+		voting decredplugin.StartVoteReply
+	)
+	if ok {
+		status = convertPropStatusFromPD(ir.record.Status)
+		voting = ir.voting
+	}
+	b.RUnlock()
+	if !ok || (status != www.PropStatusPublic &&
+		status != www.PropStatusLocked) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+
+	var (
+		bestBlock uint64
+		vote      decredplugin.Vote
+		voteBits  map[string]uint64
+	)
+	if voting.StartBlockHeight != "" && !b.test {
+		var err error
+		bestBlock, err = b.getBestBlock(ctx)
+		if err != nil {
+			return nil, err
+		}
+		vote, voteBits, err = b.getVoteTally(ctx, vs.Token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return voteStatusBits(vs.Token, vote, voting, voteBits, bestBlock)
+}
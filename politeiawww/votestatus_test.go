@@ -0,0 +1,249 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/decred/politeia/decredplugin"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestVoteStatus(t *testing.T) {
+	vote := decredplugin.Vote{
+		Mask: 0x3,
+		Options: []decredplugin.VoteOption{
+			{Id: "no", Bits: 0x1},
+			{Id: "yes", Bits: 0x2},
+		},
+	}
+	tickets := make([]string, 10)
+	for i := range tickets {
+		tickets[i] = strconv.Itoa(i)
+	}
+	voting := decredplugin.StartVoteReply{
+		StartBlockHeight: "100",
+		EndHeight:        "200",
+		EligibleTickets:  tickets,
+	}
+	castVotes := func(no, yes int) []decredplugin.CastVote {
+		var cv []decredplugin.CastVote
+		for i := 0; i < no; i++ {
+			cv = append(cv, decredplugin.CastVote{VoteBit: "1"})
+		}
+		for i := 0; i < yes; i++ {
+			cv = append(cv, decredplugin.CastVote{VoteBit: "2"})
+		}
+		return cv
+	}
+
+	tests := []struct {
+		name      string
+		voting    decredplugin.StartVoteReply
+		castVotes []decredplugin.CastVote
+		bestBlock uint64
+		status    www.PropVoteStatusT
+		total     uint64
+		passing   bool
+	}{
+		{"not started", decredplugin.StartVoteReply{}, nil, 50,
+			www.PropVoteStatusNotStarted, 0, false},
+		{"no votes", voting, nil, 150,
+			www.PropVoteStatusActive, 0, false},
+		{"passing", voting, castVotes(1, 2), 150,
+			www.PropVoteStatusActive, 3, true},
+		{"below pass percentage", voting, castVotes(2, 2), 150,
+			www.PropVoteStatusActive, 4, false},
+		{"quorum not met", voting, castVotes(0, 1), 150,
+			www.PropVoteStatusActive, 1, false},
+		{"last block", voting, castVotes(0, 2), 200,
+			www.PropVoteStatusActive, 2, true},
+		{"finished", voting, castVotes(1, 4), 201,
+			www.PropVoteStatusFinished, 5, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vsr, err := voteStatus("token", vote, test.voting,
+				test.castVotes, test.bestBlock)
+			assertSuccess(t, err)
+			if vsr.Status != test.status || vsr.TotalVotes != test.total ||
+				vsr.Passing != test.passing {
+				t.Fatalf("unexpected vote status %v", vsr)
+			}
+			if test.status == www.PropVoteStatusNotStarted {
+				return
+			}
+			if vsr.QuorumVotes != 2 || vsr.EligibleTickets != 10 ||
+				len(vsr.OptionsResult) != 2 ||
+				vsr.OptionsResult[0].VotesReceived+
+					vsr.OptionsResult[1].VotesReceived != test.total {
+				t.Fatalf("unexpected vote status %v", vsr)
+			}
+		})
+	}
+}
+
+func TestVoteParams(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+	publishProposal(b, token, t, user, id)
+
+	no := decredplugin.VoteOption{Id: "no", Bits: 0x1}
+	yes := decredplugin.VoteOption{Id: "yes", Bits: 0x2}
+	for _, test := range []struct {
+		vote   decredplugin.Vote
+		reason string
+	}{
+		{decredplugin.Vote{Token: token, Duration: 100},
+			"invalid duration: 100 (2016 - 4032)"},
+		{decredplugin.Vote{Token: token, Duration: 2016,
+			QuorumPercentage: 101},
+			"invalid quorum percentage: 101 (1 - 100)"},
+		{decredplugin.Vote{Token: token, Duration: 2016,
+			PassPercentage: 50},
+			"invalid pass percentage: 50 (51 - 100)"},
+		{decredplugin.Vote{Token: token, Duration: 2016, Mask: 0x3,
+			Options: []decredplugin.VoteOption{no, {Id: "yes", Bits: 0x3}}},
+			"invalid option bits: yes 0x3"},
+		{decredplugin.Vote{Token: token, Duration: 2016, Mask: 0x3,
+			Options: []decredplugin.VoteOption{no, {Id: "yes", Bits: 0x1}}},
+			"duplicate option bits: yes 0x1"},
+		{decredplugin.Vote{Token: token, Duration: 2016, Mask: 0x3,
+			Options: []decredplugin.VoteOption{no, {Id: "no", Bits: 0x2}}},
+			"duplicate option id: no"},
+		{decredplugin.Vote{Token: token, Duration: 2016, Mask: 0x1,
+			Options: []decredplugin.VoteOption{no, yes}},
+			"option bits not in mask: yes 0x2"},
+		{decredplugin.Vote{Token: token, Duration: 2016, Mask: 0x3,
+			Options: []decredplugin.VoteOption{yes}},
+			"missing option: no"},
+		{decredplugin.Vote{Token: token, Duration: 2016, Mask: 0x7,
+			Options: []decredplugin.VoteOption{no, yes,
+				{Id: "maybe", Bits: 0x4}}},
+			"invalid approval option: maybe"},
+		{decredplugin.Vote{Token: token, Duration: 2016, Mask: 0x3,
+			Type: decredplugin.VoteTypeMultiOption,
+			Options: []decredplugin.VoteOption{
+				{Id: "a", Description: "A", Bits: 0x1}}},
+			"too few options: 1 (2)"},
+		{decredplugin.Vote{Token: token, Duration: 2016, Mask: 0x3,
+			Type:    decredplugin.VoteTypeMultiOption,
+			Options: []decredplugin.VoteOption{no, yes}},
+			"missing option description: no"},
+		{decredplugin.Vote{Token: token, Duration: 2016, Mask: 0x3,
+			Type:    4,
+			Options: []decredplugin.VoteOption{no, yes}},
+			"invalid vote type: 4"},
+	} {
+		_, err = b.ProcessStartVote(context.Background(),
+			www.StartVote{Vote: test.vote}, user)
+		assertErrorWithContext(t, err, www.ErrorStatusInvalidVoteParams,
+			[]string{test.reason})
+	}
+
+	// A proposal is only voted on once.
+	b.Lock()
+	b.inventory[token].voting = decredplugin.StartVoteReply{
+		StartBlockHeight: "100",
+		EndHeight:        "2116",
+	}
+	b.Unlock()
+	_, err = b.ProcessStartVote(context.Background(), www.StartVote{
+		Vote: decredplugin.Vote{Token: token, Duration: 2016, Mask: 0x3,
+			Options: []decredplugin.VoteOption{no, yes}},
+	}, user)
+	assertError(t, err, www.ErrorStatusVoteAlreadyStarted)
+
+	// The outcome follows the percentages of the vote.
+	vote := decredplugin.Vote{
+		QuorumPercentage: 50,
+		PassPercentage:   75,
+		Options: []decredplugin.VoteOption{
+			{Id: "no", Bits: 0x1},
+			{Id: "yes", Bits: 0x2},
+		},
+	}
+	voting := decredplugin.StartVoteReply{
+		StartBlockHeight: "100",
+		EndHeight:        "200",
+		EligibleTickets:  []string{"a", "b", "c", "d"},
+	}
+	castVotes := []decredplugin.CastVote{
+		{VoteBit: "1"}, {VoteBit: "2"}, {VoteBit: "2"},
+	}
+	vsr, err := voteStatus(token, vote, voting, castVotes, 150)
+	assertSuccess(t, err)
+	if vsr.QuorumVotes != 2 || vsr.PassPercentage != 75 || vsr.Passing {
+		t.Fatalf("unexpected vote status %v", vsr)
+	}
+	castVotes = append(castVotes, decredplugin.CastVote{VoteBit: "2"})
+	vsr, err = voteStatus(token, vote, voting, castVotes, 150)
+	assertSuccess(t, err)
+	if !vsr.Passing {
+		t.Fatalf("unexpected vote status %v", vsr)
+	}
+}
+
+func TestMultiOptionVoteStatus(t *testing.T) {
+	vote := decredplugin.Vote{
+		Type:           decredplugin.VoteTypeMultiOption,
+		Mask:           0x7,
+		PassPercentage: 51,
+		Options: []decredplugin.VoteOption{
+			{Id: "a", Description: "Option A", Bits: 0x1},
+			{Id: "b", Description: "Option B", Bits: 0x2},
+			{Id: "c", Description: "Option C", Bits: 0x4},
+		},
+	}
+	voting := decredplugin.StartVoteReply{
+		StartBlockHeight: "100",
+		EndHeight:        "200",
+		EligibleTickets:  []string{"1", "2", "3", "4", "5"},
+	}
+
+	// No option has a majority.
+	castVotes := []decredplugin.CastVote{
+		{VoteBit: "1"}, {VoteBit: "2"}, {VoteBit: "4"}, {VoteBit: "4"},
+	}
+	vsr, err := voteStatus("token", vote, voting, castVotes, 150)
+	assertSuccess(t, err)
+	if vsr.Type != decredplugin.VoteTypeMultiOption || vsr.Passing ||
+		vsr.Winner != "" || len(vsr.OptionsResult) != 3 {
+		t.Fatalf("unexpected vote status %v", vsr)
+	}
+
+	castVotes = append(castVotes, decredplugin.CastVote{VoteBit: "4"})
+	vsr, err = voteStatus("token", vote, voting, castVotes, 150)
+	assertSuccess(t, err)
+	if !vsr.Passing || vsr.Winner != "c" ||
+		vsr.OptionsResult[2].VotesReceived != 3 {
+		t.Fatalf("unexpected vote status %v", vsr)
+	}
+
+	// Only the approve option wins approval votes.
+	vote = decredplugin.Vote{
+		Mask: 0x3,
+		Options: []decredplugin.VoteOption{
+			{Id: "no", Bits: 0x1},
+			{Id: "yes", Bits: 0x2},
+		},
+	}
+	castVotes = []decredplugin.CastVote{{VoteBit: "1"}, {VoteBit: "1"}}
+	vsr, err = voteStatus("token", vote, voting, castVotes, 150)
+	assertSuccess(t, err)
+	if vsr.Type != decredplugin.VoteTypeApproval || vsr.Passing ||
+		vsr.Winner != "" {
+		t.Fatalf("unexpected vote status %v", vsr)
+	}
+}
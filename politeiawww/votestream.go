@@ -0,0 +1,352 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/btcsuite/websocket"
+	"github.com/decred/politeia/decredplugin"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// maxVoteStreamTokens is the maximum number of proposals that a vote
+	// stream can subscribe to.
+	maxVoteStreamTokens = 100
+
+	// voteStreamBufferSize is the number of events that are queued for a
+	// vote stream.  Streams that fall further behind are closed.
+	voteStreamBufferSize = 64
+
+	// voteStreamReadLimit is the maximum size of the messages that are
+	// read from a vote stream.
+	voteStreamReadLimit = 16 * 1024
+
+	// voteStreamWriteTimeout is the time allowed to write an event to a
+	// vote stream.
+	voteStreamWriteTimeout = 10 * time.Second
+
+	// voteStreamPingInterval is the interval at which vote streams are
+	// pinged.  Streams that don't answer within voteStreamPongTimeout are
+	// closed.
+	voteStreamPingInterval = 30 * time.Second
+	voteStreamPongTimeout  = 2 * voteStreamPingInterval
+
+	// maxVoteStreams is the maximum number of open vote streams, and
+	// maxVoteStreamsPerIP the maximum number of vote streams that a
+	// single IP address can open.
+	maxVoteStreams      = 1000
+	maxVoteStreamsPerIP = 10
+
+	// voteStreamSubscribeBurst is the number of subscriptions that a vote
+	// stream can send within voteStreamSubscribeWindow.  Streams that
+	// subscribe faster are closed.
+	voteStreamSubscribeBurst  = 10
+	voteStreamSubscribeWindow = time.Minute
+)
+
+var (
+	errTooManyVoteStreams = fmt.Errorf("too many vote streams")
+)
+
+// voteStream is a client that is subscribed to the votes on proposals.
+type voteStream struct {
+	ip         string                   // IP address of the client
+	tokens     map[string]struct{}      // Subscribed proposals
+	subscribes []time.Time              // Times of the recent subscriptions
+	events     chan www.VoteStreamEvent // Closed when the stream is removed
+}
+
+// addVoteStream registers and returns a new vote stream without
+// subscriptions for the provided IP address.  It returns
+// errTooManyVoteStreams if the maximum number of vote streams are open, in
+// total or for the IP address.  The per IP limit doesn't apply if the
+// address is nil.
+func (b *backend) addVoteStream(ip net.IP) (*voteStream, error) {
+	s := &voteStream{
+		tokens: make(map[string]struct{}),
+		events: make(chan www.VoteStreamEvent, voteStreamBufferSize),
+	}
+	if ip != nil {
+		s.ip = ip.String()
+	}
+
+	b.voteStreamMtx.Lock()
+	defer b.voteStreamMtx.Unlock()
+
+	if len(b.voteStreams) >= maxVoteStreams {
+		return nil, errTooManyVoteStreams
+	}
+	if s.ip != "" {
+		var n int
+		for v := range b.voteStreams {
+			if v.ip == s.ip {
+				n++
+			}
+		}
+		if n >= maxVoteStreamsPerIP {
+			return nil, errTooManyVoteStreams
+		}
+	}
+	b.voteStreams[s] = struct{}{}
+
+	return s, nil
+}
+
+// _removeVoteStream unregisters the provided vote stream and closes its
+// events.
+//
+// This function must be called WITH the vote stream mutex held.
+func (b *backend) _removeVoteStream(s *voteStream) {
+	if _, ok := b.voteStreams[s]; !ok {
+		return
+	}
+	delete(b.voteStreams, s)
+	close(s.events)
+}
+
+// removeVoteStream unregisters the provided vote stream and closes its
+// events.
+//
+// This function must be called WITHOUT the vote stream mutex held.
+func (b *backend) removeVoteStream(s *voteStream) {
+	b.voteStreamMtx.Lock()
+	b._removeVoteStream(s)
+	b.voteStreamMtx.Unlock()
+}
+
+// _sendVoteStream queues the provided event on the provided vote stream.  A
+// stream whose queue is full is removed rather than blocking the other
+// streams.
+//
+// This function must be called WITH the vote stream mutex held.
+func (b *backend) _sendVoteStream(s *voteStream, e www.VoteStreamEvent) {
+	if _, ok := b.voteStreams[s]; !ok {
+		return
+	}
+	select {
+	case s.events <- e:
+	default:
+		log.Debugf("vote stream fell behind, closing it")
+		b._removeVoteStream(s)
+	}
+}
+
+// publishVoteStream queues the provided event on the vote streams that are
+// subscribed to its proposal.
+//
+// This function must be called WITHOUT the vote stream mutex held.
+func (b *backend) publishVoteStream(e www.VoteStreamEvent) {
+	b.voteStreamMtx.Lock()
+	defer b.voteStreamMtx.Unlock()
+
+	for s := range b.voteStreams {
+		if _, ok := s.tokens[e.Token]; ok {
+			b._sendVoteStream(s, e)
+		}
+	}
+}
+
+// subscribeVoteStream replaces the subscriptions of the provided vote stream
+// with the provided proposals and queues the current vote status of the
+// proposals that it wasn't subscribed to yet.  Streams that subscribe more
+// than voteStreamSubscribeBurst times within voteStreamSubscribeWindow are
+// refused.
+//
+// This function must be called WITHOUT the vote stream mutex held.
+func (b *backend) subscribeVoteStream(ctx context.Context, s *voteStream, tokens []string) error {
+	if len(tokens) > maxVoteStreamTokens {
+		return fmt.Errorf("too many tokens: %v", len(tokens))
+	}
+	subscribed := make(map[string]struct{}, len(tokens))
+	for _, v := range tokens {
+		_, err := util.ConvertStringToken(v)
+		if err != nil {
+			return fmt.Errorf("invalid token %v: %v", v, err)
+		}
+		subscribed[v] = struct{}{}
+	}
+
+	b.voteStreamMtx.Lock()
+	if _, ok := b.voteStreams[s]; !ok {
+		b.voteStreamMtx.Unlock()
+		return fmt.Errorf("vote stream closed")
+	}
+
+	// Each subscription queries the vote status of its proposals, so
+	// clients can't resubscribe at will.
+	now := time.Now()
+	recent := s.subscribes[:0]
+	for _, v := range s.subscribes {
+		if now.Sub(v) < voteStreamSubscribeWindow {
+			recent = append(recent, v)
+		}
+	}
+	s.subscribes = recent
+	if len(s.subscribes) >= voteStreamSubscribeBurst {
+		b.voteStreamMtx.Unlock()
+		return fmt.Errorf("too many subscriptions")
+	}
+	s.subscribes = append(s.subscribes, now)
+
+	added := make([]string, 0, len(subscribed))
+	for k := range subscribed {
+		if _, ok := s.tokens[k]; !ok {
+			added = append(added, k)
+		}
+	}
+	s.tokens = subscribed
+	b.voteStreamMtx.Unlock()
+
+	// Proposals that aren't public have no vote status and are never
+	// voted on, so their subscriptions are simply idle.
+	for _, token := range added {
+		status, err := b.ProcessVoteStatus(ctx, www.VoteStatus{
+			Token: token,
+		})
+		if err != nil {
+			log.Debugf("subscribeVoteStream %v: %v", token, err)
+			continue
+		}
+
+		b.voteStreamMtx.Lock()
+		if _, ok := s.tokens[token]; ok {
+			b._sendVoteStream(s, www.VoteStreamEvent{
+				Token:  token,
+				Status: *status,
+			})
+		}
+		b.voteStreamMtx.Unlock()
+	}
+
+	return nil
+}
+
+// streamBallot publishes the provided votes that politeiad accepted, along
+// with the updated vote status of their proposals, to the vote streams that
+// are subscribed to the proposals.  The vote statuses are computed in the
+// background so that the ballot isn't held up.
+//
+// This function must be called WITHOUT the vote stream mutex held.
+func (b *backend) streamBallot(votes []decredplugin.CastVote, receipts []decredplugin.CastVoteReply) {
+	accepted := make(map[string][]decredplugin.CastVote)
+	for k, v := range votes {
+		if receipts[k].Error != "" || receipts[k].Signature == "" {
+			continue
+		}
+		accepted[v.Token] = append(accepted[v.Token], v)
+	}
+
+	// Skip the proposals that nobody is subscribed to.
+	b.voteStreamMtx.Lock()
+	subscribed := make(map[string]struct{}, len(accepted))
+	for s := range b.voteStreams {
+		for token := range s.tokens {
+			subscribed[token] = struct{}{}
+		}
+	}
+	b.voteStreamMtx.Unlock()
+	for token := range accepted {
+		if _, ok := subscribed[token]; !ok {
+			delete(accepted, token)
+		}
+	}
+	if len(accepted) == 0 {
+		return
+	}
+
+	b.startWorker(func() {
+		for token, castVotes := range accepted {
+			status, err := b.ProcessVoteStatus(context.Background(),
+				www.VoteStatus{
+					Token: token,
+				})
+			if err != nil {
+				log.Errorf("streamBallot %v: %v", token, err)
+				continue
+			}
+			b.publishVoteStream(www.VoteStreamEvent{
+				Token:     token,
+				CastVotes: castVotes,
+				Status:    *status,
+			})
+		}
+	})
+}
+
+// serveVoteStream pushes the votes on the proposals that the client of the
+// provided websocket connection subscribes to over the provided vote stream
+// until the connection fails or the backend shuts down.  The client is pinged
+// periodically and the connection is closed if it stops answering.  The
+// connection is closed and the vote stream is removed before returning.
+func (b *backend) serveVoteStream(conn *websocket.Conn, s *voteStream) {
+	defer conn.Close()
+	defer b.removeVoteStream(s)
+
+	// The server read timeout doesn't apply to the lifetime of the
+	// stream; the pongs of the client extend the read deadline instead.
+	conn.SetReadLimit(voteStreamReadLimit)
+	conn.SetReadDeadline(time.Now().Add(voteStreamPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(voteStreamPongTimeout))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Read the subscriptions of the client.  The reader stops when the
+	// connection is closed.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var vss www.VoteStreamSubscribe
+			err := conn.ReadJSON(&vss)
+			if err != nil {
+				log.Debugf("serveVoteStream: read %v", err)
+				return
+			}
+			err = b.subscribeVoteStream(ctx, s, vss.Tokens)
+			if err != nil {
+				log.Debugf("serveVoteStream: subscribe %v", err)
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(voteStreamPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case e, ok := <-s.events:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(voteStreamWriteTimeout))
+			err := conn.WriteJSON(e)
+			if err != nil {
+				log.Debugf("serveVoteStream: write %v", err)
+				return
+			}
+		case <-ping.C:
+			err := conn.WriteControl(websocket.PingMessage, nil,
+				time.Now().Add(voteStreamWriteTimeout))
+			if err != nil {
+				log.Debugf("serveVoteStream: ping %v", err)
+				return
+			}
+		case <-done:
+			return
+		case <-b.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/politeia/decredplugin"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func expectVoteStreamEvent(t *testing.T, s *voteStream) www.VoteStreamEvent {
+	t.Helper()
+
+	select {
+	case e, ok := <-s.events:
+		if !ok {
+			t.Fatalf("vote stream closed")
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for a vote stream event")
+	}
+	return www.VoteStreamEvent{}
+}
+
+func TestVoteStream(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+	publishProposal(b, token, t, user, id)
+
+	ctx := context.Background()
+	s, err := b.addVoteStream(nil)
+	assertSuccess(t, err)
+	idle, err := b.addVoteStream(nil)
+	assertSuccess(t, err)
+	defer b.removeVoteStream(s)
+	defer b.removeVoteStream(idle)
+
+	// Invalid subscriptions are rejected.
+	err = b.subscribeVoteStream(ctx, s, []string{"x"})
+	if err == nil {
+		t.Fatalf("expected an invalid token error")
+	}
+	tokens := make([]string, maxVoteStreamTokens+1)
+	for k := range tokens {
+		tokens[k] = token
+	}
+	err = b.subscribeVoteStream(ctx, s, tokens)
+	if err == nil {
+		t.Fatalf("expected a too many tokens error")
+	}
+
+	// Subscribing pushes the current vote status.
+	err = b.subscribeVoteStream(ctx, s, []string{token})
+	assertSuccess(t, err)
+	e := expectVoteStreamEvent(t, s)
+	if e.Token != token || e.Status.Token != token ||
+		len(e.CastVotes) != 0 {
+		t.Fatalf("unexpected event %v", e)
+	}
+
+	// Only the accepted votes are pushed, and only to the subscribers.
+	votes := []decredplugin.CastVote{
+		{Token: token, Ticket: "a", VoteBit: "2"},
+		{Token: token, Ticket: "b", VoteBit: "1"},
+	}
+	receipts := []decredplugin.CastVoteReply{
+		{Signature: "s0"},
+		{Error: "ticket not eligible to vote b"},
+	}
+	b.streamBallot(votes, receipts)
+	e = expectVoteStreamEvent(t, s)
+	if e.Token != token || len(e.CastVotes) != 1 ||
+		e.CastVotes[0].Ticket != "a" {
+		t.Fatalf("unexpected event %v", e)
+	}
+	select {
+	case e := <-idle.events:
+		t.Fatalf("unexpected event %v", e)
+	default:
+	}
+
+	// A stream that falls behind is closed.
+	for i := 0; i <= voteStreamBufferSize; i++ {
+		b.publishVoteStream(www.VoteStreamEvent{Token: token})
+	}
+	for i := 0; i < voteStreamBufferSize; i++ {
+		expectVoteStreamEvent(t, s)
+	}
+	if _, ok := <-s.events; ok {
+		t.Fatalf("expected the vote stream to be closed")
+	}
+	err = b.subscribeVoteStream(ctx, s, []string{token})
+	if err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestVoteStreamLimits(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	// Streams are capped per IP address.
+	ip := net.ParseIP("192.0.2.1")
+	for i := 0; i < maxVoteStreamsPerIP; i++ {
+		_, err := b.addVoteStream(ip)
+		assertSuccess(t, err)
+	}
+	_, err := b.addVoteStream(ip)
+	if err != errTooManyVoteStreams {
+		t.Fatalf("got %v, want %v", err, errTooManyVoteStreams)
+	}
+	s, err := b.addVoteStream(net.ParseIP("192.0.2.2"))
+	assertSuccess(t, err)
+
+	// Removing a stream makes room for another one.
+	b.removeVoteStream(s)
+	for i := 0; i < maxVoteStreams-maxVoteStreamsPerIP; i++ {
+		_, err = b.addVoteStream(nil)
+		assertSuccess(t, err)
+	}
+	_, err = b.addVoteStream(nil)
+	if err != errTooManyVoteStreams {
+		t.Fatalf("got %v, want %v", err, errTooManyVoteStreams)
+	}
+
+	// Subscriptions are rate limited.
+	b.voteStreamMtx.Lock()
+	for v := range b.voteStreams {
+		b._removeVoteStream(v)
+	}
+	b.voteStreamMtx.Unlock()
+	s, err = b.addVoteStream(nil)
+	assertSuccess(t, err)
+	for i := 0; i < voteStreamSubscribeBurst; i++ {
+		err = b.subscribeVoteStream(context.Background(), s, nil)
+		assertSuccess(t, err)
+	}
+	err = b.subscribeVoteStream(context.Background(), s, nil)
+	if err == nil || !strings.Contains(err.Error(), "too many") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
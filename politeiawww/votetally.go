@@ -0,0 +1,125 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/decred/politeia/decredplugin"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+// voteTallyRefreshInterval is the interval after which a cached vote tally
+// is refreshed from politeiad, which picks up the votes that were cast through
+// other politeiawww instances.
+const voteTallyRefreshInterval = time.Minute
+
+// voteTally is the cached tally of the votes that were cast on a proposal.
+// Votes are counted once per ticket, so the votes that are fetched from
+// politeiad merge with the votes that were counted as their ballot passed
+// through.
+type voteTally struct {
+	vote      decredplugin.Vote   // Vote bits and options
+	tickets   map[string]struct{} // Tickets whose vote is counted
+	voteBits  map[string]uint64   // [vote bit]number of votes
+	refreshed time.Time           // Last time the votes were fetched
+}
+
+// newVoteTally returns an empty tally of the provided vote.
+func newVoteTally(vote decredplugin.Vote) *voteTally {
+	return &voteTally{
+		vote:     vote,
+		tickets:  make(map[string]struct{}),
+		voteBits: make(map[string]uint64),
+	}
+}
+
+// add counts the provided cast vote unless its ticket was already counted.
+func (t *voteTally) add(cv decredplugin.CastVote) {
+	if _, ok := t.tickets[cv.Ticket]; ok {
+		return
+	}
+	t.tickets[cv.Ticket] = struct{}{}
+	t.voteBits[cv.VoteBit]++
+}
+
+// copyVoteBits returns a copy of the counted votes of the tally.
+func (t *voteTally) copyVoteBits() map[string]uint64 {
+	voteBits := make(map[string]uint64, len(t.voteBits))
+	for k, v := range t.voteBits {
+		voteBits[k] = v
+	}
+	return voteBits
+}
+
+// tallyBallot counts the provided votes that politeiad accepted in the
+// loaded tallies of their proposals.  The tallies that are not loaded yet
+// count the votes when they are loaded.
+//
+// This function must be called WITHOUT the mutex held.
+func (b *backend) tallyBallot(votes []decredplugin.CastVote, receipts []decredplugin.CastVoteReply) {
+	b.Lock()
+	defer b.Unlock()
+
+	for k, v := range votes {
+		if receipts[k].Error != "" || receipts[k].Signature == "" {
+			continue
+		}
+		ir, ok := b.inventory[v.Token]
+		if !ok || ir.voteTally == nil {
+			continue
+		}
+		ir.voteTally.add(v)
+	}
+}
+
+// getVoteTally returns the vote on the proposal with the provided token and
+// the number of cast votes of each vote bit.  The tally is loaded from
+// politeiad on first use and refreshed once it is older than
+// voteTallyRefreshInterval.
+//
+// This function must be called WITHOUT the mutex held.
+func (b *backend) getVoteTally(ctx context.Context, token string) (decredplugin.Vote, map[string]uint64, error) {
+	b.RLock()
+	ir, ok := b.inventory[token]
+	if ok && ir.voteTally != nil &&
+		time.Since(ir.voteTally.refreshed) < voteTallyRefreshInterval {
+		vote, voteBits := ir.voteTally.vote, ir.voteTally.copyVoteBits()
+		b.RUnlock()
+		return vote, voteBits, nil
+	}
+	b.RUnlock()
+
+	// Fetch the votes without the mutex held.
+	pvr, err := b.ProcessProposalVotes(ctx, &www.ProposalVotes{
+		Vote: decredplugin.VoteResults{
+			Token: token,
+		},
+	})
+	if err != nil {
+		return decredplugin.Vote{}, nil, err
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	ir, ok = b.inventory[token]
+	if !ok {
+		return decredplugin.Vote{}, nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+	if ir.voteTally == nil {
+		ir.voteTally = newVoteTally(pvr.Vote)
+	}
+	ir.voteTally.vote = pvr.Vote
+	for _, cv := range pvr.CastVotes {
+		ir.voteTally.add(cv)
+	}
+	ir.voteTally.refreshed = time.Now()
+
+	return ir.voteTally.vote, ir.voteTally.copyVoteBits(), nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/decred/politeia/decredplugin"
+)
+
+func TestVoteTally(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	nu, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(nu.Email)
+	assertSuccess(t, err)
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+	token := npr.CensorshipRecord.Token
+	publishProposal(b, token, t, user, id)
+
+	vote := decredplugin.Vote{
+		Mask: 0x3,
+		Options: []decredplugin.VoteOption{
+			{Id: "no", Bits: 0x1},
+			{Id: "yes", Bits: 0x2},
+		},
+	}
+	votes := []decredplugin.CastVote{
+		{Token: token, Ticket: "a", VoteBit: "2"},
+		{Token: token, Ticket: "b", VoteBit: "1"},
+		{Token: token, Ticket: "c", VoteBit: "2"},
+	}
+	receipts := []decredplugin.CastVoteReply{
+		{Signature: "s0"},
+		{Signature: "s1"},
+		{Error: "ticket not eligible to vote c"},
+	}
+
+	// Ballots aren't counted until the tally is loaded.
+	b.tallyBallot(votes, receipts)
+	b.RLock()
+	if b.inventory[token].voteTally != nil {
+		t.Fatalf("unexpected vote tally")
+	}
+	b.RUnlock()
+
+	// Only the accepted votes are counted, once per ticket.
+	b.Lock()
+	b.inventory[token].voteTally = newVoteTally(vote)
+	b.inventory[token].voteTally.refreshed = time.Now()
+	b.Unlock()
+	b.tallyBallot(votes, receipts)
+	b.tallyBallot(votes[:1], receipts[:1])
+
+	tv, voteBits, err := b.getVoteTally(context.Background(), token)
+	assertSuccess(t, err)
+	if len(voteBits) != 2 || voteBits["2"] != 1 || voteBits["1"] != 1 {
+		t.Fatalf("unexpected vote bits %v", voteBits)
+	}
+	results, winner := tallyVoteBits(tv, 0, voteBits)
+	expected, _ := tallyVote(vote, 0, votes[:2])
+	for k := range results {
+		if results[k] != expected[k] {
+			t.Fatalf("unexpected results %v want %v", results,
+				expected)
+		}
+	}
+	if winner != nil {
+		t.Fatalf("unexpected winner %v", winner)
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/decred/politeia/decredplugin"
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+// voteWatcherInterval is the interval at which the vote watcher checks for
+// votes that started or ended.
+const voteWatcherInterval = 5 * time.Minute
+
+// voteEventT is the type of a vote lifecycle event.
+type voteEventT int
+
+const (
+	voteEventStarted voteEventT = iota // Vote has started
+	voteEventEnded                     // Vote has ended
+)
+
+// voteEvent is emitted by the vote watcher whenever a vote starts or ends.
+type voteEvent struct {
+	Type      voteEventT
+	Token     string                      // Censorship token
+	Name      string                      // Proposal name
+	Vote      decredplugin.Vote           // Vote bits and options
+	Details   decredplugin.StartVoteReply // Vote start and end parameters
+	BestBlock uint64                      // Best block when the event fired
+}
+
+// voteWatcherState tracks the votes that have been seen by the vote watcher.
+type voteWatcherState struct {
+	started map[string]bool // Votes that are known to have started
+	ended   map[string]bool // Votes that are known to have ended
+}
+
+// voteWatcher periodically inspects the inventory and emits events for the
+// votes that started or ended since the last run.  Votes that already started
+// or ended when the watcher is launched do not generate events.
+func (b *backend) voteWatcher() {
+	state := voteWatcherState{
+		started: make(map[string]bool),
+		ended:   make(map[string]bool),
+	}
+
+	// Seed the state without emitting events.
+	_, err := b.checkVotes(&state)
+	if err != nil {
+		log.Errorf("voteWatcher: %v", err)
+	}
+
+	ticker := time.NewTicker(voteWatcherInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.quit:
+			return
+		}
+
+		events, err := b.checkVotes(&state)
+		if err != nil {
+			log.Errorf("voteWatcher: %v", err)
+			continue
+		}
+		for _, e := range events {
+			b.handleVoteEvent(e)
+		}
+	}
+}
+
+// checkVotes compares the votes in the inventory with the provided state and
+// returns the events for the votes that started or ended since the state was
+// last updated.
+//
+// This function must be called WITHOUT the mutex held.
+func (b *backend) checkVotes(state *voteWatcherState) ([]voteEvent, error) {
+	bestBlock, err := b.getBestBlock(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	b.RLock()
+	defer b.RUnlock()
+
+	var events []voteEvent
+	for token, ir := range b.inventory {
+		// Use StartBlockHeight as a canary
+		if len(ir.voting.StartBlockHeight) == 0 {
+			continue
+		}
+		endHeight, err := strconv.ParseUint(ir.voting.EndHeight, 10, 64)
+		if err != nil {
+			log.Errorf("checkVotes: invalid end height %v: %v",
+				token, err)
+			continue
+		}
+
+		e := voteEvent{
+			Token:     token,
+			Name:      convertPropFromPD(ir.record).Name,
+			Vote:      ir.votebits,
+			Details:   ir.voting,
+			BestBlock: bestBlock,
+		}
+		if !state.started[token] {
+			state.started[token] = true
+			e.Type = voteEventStarted
+			events = append(events, e)
+		}
+		if !state.ended[token] && bestBlock > endHeight {
+			state.ended[token] = true
+			e.Type = voteEventEnded
+			events = append(events, e)
+		}
+	}
+
+	return events, nil
+}
+
+// handleVoteEvent notifies the webhooks and the users of a vote lifecycle
+// event.
+func (b *backend) handleVoteEvent(e voteEvent) {
+	switch e.Type {
+	case voteEventStarted:
+		b.emitWebhook(www.WebhookEventVoteStarted, e.Token,
+			www.PropStatusPublic, e.Name)
+	case voteEventEnded:
+		b.emitWebhook(www.WebhookEventVoteEnded, e.Token,
+			www.PropStatusPublic, e.Name)
+	}
+
+	if b.emailQueue == nil {
+		return
+	}
+	var err error
+	switch e.Type {
+	case voteEventStarted:
+		err = b.emailVoteStarted(e)
+	case voteEventEnded:
+		err = b.emailVoteEnded(e)
+	}
+	if err != nil {
+		log.Errorf("handleVoteEvent %v: %v", e.Token, err)
+	}
+}
+
+// voteOptionResult is the tally of a single vote option.
+type voteOptionResult struct {
+	Id          string
+	Description string
+	Votes       uint64
+}
+
+// countVoteBits returns the number of cast votes for each of the vote bits of
+// the provided cast votes.
+func countVoteBits(castVotes []decredplugin.CastVote) map[string]uint64 {
+	voteBits := make(map[string]uint64)
+	for _, cv := range castVotes {
+		voteBits[cv.VoteBit]++
+	}
+	return voteBits
+}
+
+// quorumVotes returns the number of votes that must be cast for the provided
+// vote to be decided when the provided number of tickets are eligible.
+func quorumVotes(vote decredplugin.Vote, eligibleTickets uint64) uint64 {
+	return (eligibleTickets*uint64(vote.QuorumPercentage) + 99) / 100
+}
+
+// tallyVote counts the votes that were cast for each of the vote options.  It
+// returns the results per option and the winning option, which is nil if the
+// quorum of the provided number of eligible tickets wasn't reached or if no
+// option received the pass percentage of the votes.  Only the approve option
+// wins approval votes and the winner of ranked-choice votes is the one of the
+// instant runoff.
+func tallyVote(vote decredplugin.Vote, eligibleTickets uint64, castVotes []decredplugin.CastVote) ([]voteOptionResult, *voteOptionResult) {
+	return tallyVoteBits(vote, eligibleTickets, countVoteBits(castVotes))
+}
+
+// tallyVoteBits is tallyVote for the number of cast votes of each vote bit.
+// It only depends on the number of distinct vote bits, so the votes of a
+// cached tally are counted without going over every cast vote.
+func tallyVoteBits(vote decredplugin.Vote, eligibleTickets uint64, voteBits map[string]uint64) ([]voteOptionResult, *voteOptionResult) {
+	// Votes that predate the vote parameters use the defaults.
+	vote.SetDefaults()
+
+	var (
+		results []voteOptionResult
+		winner  *voteOptionResult
+	)
+	if vote.Type == decredplugin.VoteTypeRankedChoice {
+		results, winner = tallyRankedChoiceVote(vote, voteBits)
+	} else {
+		results = make([]voteOptionResult, 0, len(vote.Options))
+		for _, o := range vote.Options {
+			var count uint64
+			for voteBit, votes := range voteBits {
+				bits, err := strconv.ParseUint(voteBit, 16, 64)
+				if err != nil {
+					continue
+				}
+				if bits == o.Bits {
+					count += votes
+				}
+			}
+			results = append(results, voteOptionResult{
+				Id:          o.Id,
+				Description: o.Description,
+				Votes:       count,
+			})
+		}
+	}
+
+	var total uint64
+	for _, v := range results {
+		total += v.Votes
+	}
+	if total == 0 || total < quorumVotes(vote, eligibleTickets) {
+		return results, nil
+	}
+	if vote.Type == decredplugin.VoteTypeRankedChoice {
+		return results, winner
+	}
+
+	// The pass percentage is a majority, so at most one option wins.
+	for i := range results {
+		if vote.Type == decredplugin.VoteTypeApproval &&
+			results[i].Id != www.VoteOptionIDApprove {
+			continue
+		}
+		if results[i].Votes*100 >= total*uint64(vote.PassPercentage) {
+			return results, &results[i]
+		}
+	}
+
+	return results, nil
+}
+
+// emailVoteStarted notifies the users that opted in that voting on a proposal
+// has started.
+func (b *backend) emailVoteStarted(e voteEvent) error {
+	link, err := b.proposalLink(e.Token)
+	if err != nil {
+		return err
+	}
+
+	tplData := voteStartedTemplateData{
+		Link:      link,
+		Name:      e.Name,
+		EndHeight: e.Details.EndHeight,
+	}
+	return b.emailVoteNotification(e.Token,
+		www.NotificationEmailVoteStarted, templateVoteStarted,
+		"Voting Started On A Proposal", &tplData)
+}
+
+// emailVoteEnded notifies the users that opted in that voting on a proposal
+// has ended, along with the outcome of the vote.
+func (b *backend) emailVoteEnded(e voteEvent) error {
+	link, err := b.proposalLink(e.Token)
+	if err != nil {
+		return err
+	}
+
+	b.RLock()
+	var eligibleTickets uint64
+	if ir, ok := b.inventory[e.Token]; ok {
+		eligibleTickets = uint64(len(ir.voting.EligibleTickets))
+	}
+	b.RUnlock()
+
+	vote, voteBits, err := b.getVoteTally(context.Background(), e.Token)
+	if err != nil {
+		return err
+	}
+	results, winner := tallyVoteBits(vote, eligibleTickets, voteBits)
+
+	tplData := voteEndedTemplateData{
+		Link:    link,
+		Name:    e.Name,
+		Results: results,
+		Winner:  winner,
+	}
+	return b.emailVoteNotification(e.Token,
+		www.NotificationEmailVoteEnded, templateVoteEnded,
+		"Voting Ended On A Proposal", &tplData)
+}
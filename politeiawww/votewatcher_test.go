@@ -0,0 +1,111 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/decred/politeia/decredplugin"
+)
+
+func TestTallyVote(t *testing.T) {
+	approval := decredplugin.Vote{
+		Type:             decredplugin.VoteTypeApproval,
+		Mask:             0x7,
+		QuorumPercentage: 20,
+		PassPercentage:   60,
+		Options: []decredplugin.VoteOption{
+			{Id: "no", Description: "Reject", Bits: 0x1},
+			{Id: "yes", Description: "Approve", Bits: 0x2},
+			{Id: "abstain", Description: "Abstain", Bits: 0x4},
+		},
+	}
+	multiOption := decredplugin.Vote{
+		Type:             decredplugin.VoteTypeMultiOption,
+		Mask:             0x7,
+		QuorumPercentage: 20,
+		PassPercentage:   51,
+		Options: []decredplugin.VoteOption{
+			{Id: "a", Description: "Option A", Bits: 0x1},
+			{Id: "b", Description: "Option B", Bits: 0x2},
+			{Id: "c", Description: "Option C", Bits: 0x4},
+		},
+	}
+	rankedChoice := multiOption
+	rankedChoice.Type = decredplugin.VoteTypeRankedChoice
+	legacy := decredplugin.Vote{
+		Mask: 0x3,
+		Options: []decredplugin.VoteOption{
+			{Id: "no", Bits: 0x1},
+			{Id: "yes", Bits: 0x2},
+		},
+	}
+	ballots := func(voteBits ...string) []decredplugin.CastVote {
+		castVotes := make([]decredplugin.CastVote, 0, len(voteBits))
+		for _, v := range voteBits {
+			castVotes = append(castVotes,
+				decredplugin.CastVote{VoteBit: v})
+		}
+		return castVotes
+	}
+
+	tests := []struct {
+		name      string
+		vote      decredplugin.Vote
+		eligible  uint64
+		castVotes []decredplugin.CastVote
+		votes     []uint64
+		winner    string
+	}{
+		{"approved", approval, 10, ballots("2", "2", "1"),
+			[]uint64{1, 2, 0}, "yes"},
+		{"below quorum", approval, 100, ballots("2", "2", "2"),
+			[]uint64{0, 3, 0}, ""},
+		{"at quorum", approval, 15, ballots("2", "2", "2"),
+			[]uint64{0, 3, 0}, "yes"},
+		{"below pass percentage", approval, 10,
+			ballots("2", "2", "1", "1"), []uint64{2, 2, 0}, ""},
+		{"at pass percentage", approval, 10,
+			ballots("2", "2", "2", "1", "4"), []uint64{1, 3, 1}, "yes"},
+		{"rejected", approval, 10, ballots("1", "1", "1"),
+			[]uint64{3, 0, 0}, ""},
+		{"no votes", approval, 0, nil, []uint64{0, 0, 0}, ""},
+		{"legacy defaults", legacy, 10, ballots("2", "2", "2", "1"),
+			[]uint64{1, 3}, "yes"},
+		{"legacy below pass percentage", legacy, 10,
+			ballots("2", "1"), []uint64{1, 1}, ""},
+		{"multi-option majority", multiOption, 10,
+			ballots("4", "4", "1"), []uint64{1, 0, 2}, "c"},
+		{"multi-option plurality", multiOption, 10,
+			ballots("4", "4", "1", "2"), []uint64{1, 1, 2}, ""},
+		{"multi-option below quorum", multiOption, 20,
+			ballots("4", "4", "4"), []uint64{0, 0, 3}, ""},
+		{"ranked-choice runoff", rankedChoice, 10,
+			ballots("1", "1", "1", "2,4", "4,2", "4,2", "4"),
+			[]uint64{3, 1, 3}, "c"},
+		{"ranked-choice below quorum", rankedChoice, 100,
+			ballots("1", "1", "2,1"), []uint64{2, 1, 0}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, winner := tallyVote(test.vote, test.eligible,
+				test.castVotes)
+			if len(results) != len(test.votes) {
+				t.Fatalf("unexpected results %v", results)
+			}
+			for k, v := range results {
+				if v.Votes != test.votes[k] {
+					t.Fatalf("unexpected results %v", results)
+				}
+			}
+			switch {
+			case winner == nil && test.winner != "":
+				t.Fatalf("expected winner %v", test.winner)
+			case winner != nil && winner.Id != test.winner:
+				t.Fatalf("unexpected winner %v", winner.Id)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// webhookSignatureHeader is the header that carries the HMAC-SHA256 of
+	// the payload of a webhook, keyed with the webhook secret.
+	webhookSignatureHeader = "X-Politeia-Signature"
+
+	// webhookEventHeader is the header that carries the type of the event
+	// of a webhook.
+	webhookEventHeader = "X-Politeia-Event"
+
+	// webhookTimeout is the timeout of a webhook request.
+	webhookTimeout = 30 * time.Second
+
+	// webhookMaxAttempts is the number of times a webhook is sent before
+	// its delivery is given up.
+	webhookMaxAttempts = 6
+
+	// webhookInitialBackoff is the time before a failed webhook is sent
+	// again.  It doubles after every failure.
+	webhookInitialBackoff = 30 * time.Second
+
+	// webhookLogSize is the number of delivery attempts that are kept in
+	// the delivery log.
+	webhookLogSize = 200
+)
+
+// newWebhookClient returns the client that webhooks are sent with.
+func newWebhookClient() *http.Client {
+	return &http.Client{
+		Timeout: webhookTimeout,
+	}
+}
+
+// webhookSignature returns the signature of the provided webhook payload.
+func webhookSignature(secret string, payload []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(payload)
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// emitWebhook sends the proposal lifecycle event of the provided type to the
+// configured webhooks in the background.  The name is only sent for public
+// proposals since webhooks are not restricted to admins.  Replicas don't send
+// webhooks since the primary sees all the events.
+func (b *backend) emitWebhook(typ, token string, status www.PropStatusT, name string) {
+	if len(b.cfg.Webhooks) == 0 || b.isReplica() {
+		return
+	}
+
+	id, err := util.Random(16)
+	if err != nil {
+		log.Errorf("emitWebhook %v %v: %v", typ, token, err)
+		return
+	}
+	e := www.WebhookEvent{
+		ID:        hex.EncodeToString(id),
+		Type:      typ,
+		Timestamp: time.Now().Unix(),
+		Token:     token,
+		Status:    status,
+	}
+	if status == www.PropStatusPublic {
+		e.Name = name
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Errorf("emitWebhook %v %v: %v", typ, token, err)
+		return
+	}
+
+	for _, v := range b.cfg.Webhooks {
+		url := v
+		b.startWorker(func() {
+			b.deliverWebhook(url, e, payload)
+		})
+	}
+}
+
+// deliverWebhook sends the provided encoded event to the provided webhook
+// until it is accepted, backing off exponentially between the attempts.
+// Every attempt is recorded in the delivery log.  Pending retries are
+// dropped on shutdown.
+func (b *backend) deliverWebhook(url string, e www.WebhookEvent, payload []byte) {
+	backoff := b.webhookBackoff
+	for attempt := 1; ; attempt++ {
+		statusCode, err := b.postWebhook(url, e.Type, payload)
+		delivery := www.WebhookDelivery{
+			EventID:    e.ID,
+			Type:       e.Type,
+			Token:      e.Token,
+			URL:        url,
+			Attempt:    attempt,
+			StatusCode: statusCode,
+			Timestamp:  time.Now().Unix(),
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		b.logWebhookDelivery(delivery)
+		if err == nil {
+			return
+		}
+
+		if attempt == webhookMaxAttempts {
+			log.Errorf("deliverWebhook %v %v to %v: giving up after "+
+				"%v attempts: %v", e.Type, e.Token, url, attempt,
+				err)
+			return
+		}
+		log.Debugf("deliverWebhook %v %v to %v: attempt %v: %v",
+			e.Type, e.Token, url, attempt, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-b.quit:
+			log.Warnf("deliverWebhook %v %v to %v: dropped on "+
+				"shutdown", e.Type, e.Token, url)
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// postWebhook sends the provided encoded event of the provided type to the
+// provided webhook.  The webhook accepts the event by replying with a 2xx
+// status code.
+func (b *backend) postWebhook(url, typ string, payload []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url,
+		bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, typ)
+	req.Header.Set(webhookSignatureHeader,
+		webhookSignature(b.cfg.WebhookSecret, payload))
+
+	r, err := b.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Body.Close()
+	io.Copy(ioutil.Discard, r.Body)
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		return r.StatusCode, fmt.Errorf("%v", r.Status)
+	}
+	return r.StatusCode, nil
+}
+
+// logWebhookDelivery appends the provided delivery attempt to the delivery
+// log.  Only the last webhookLogSize attempts are kept.
+func (b *backend) logWebhookDelivery(d www.WebhookDelivery) {
+	b.webhookLogMtx.Lock()
+	defer b.webhookLogMtx.Unlock()
+
+	b.webhookLog = append(b.webhookLog, d)
+	if len(b.webhookLog) > webhookLogSize {
+		b.webhookLog = b.webhookLog[len(b.webhookLog)-webhookLogSize:]
+	}
+}
+
+// ProcessWebhookDeliveries returns the recent webhook delivery attempts,
+// newest first.
+func (b *backend) ProcessWebhookDeliveries() *www.WebhookDeliveriesReply {
+	b.webhookLogMtx.Lock()
+	defer b.webhookLogMtx.Unlock()
+
+	reply := www.WebhookDeliveriesReply{
+		Deliveries: make([]www.WebhookDelivery, 0, len(b.webhookLog)),
+	}
+	for i := len(b.webhookLog) - 1; i >= 0; i-- {
+		reply.Deliveries = append(reply.Deliveries, b.webhookLog[i])
+	}
+	return &reply
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/v1"
+)
+
+func TestWebhooks(t *testing.T) {
+	b := createBackend(t)
+	defer b.db.Close()
+
+	// The webhook fails the first delivery.
+	var (
+		mtx      sync.Mutex
+		attempts int
+		events   []www.WebhookEvent
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if r.Header.Get(webhookSignatureHeader) !=
+			webhookSignature("secret", payload) {
+			t.Errorf("invalid signature %v",
+				r.Header.Get(webhookSignatureHeader))
+		}
+		var e www.WebhookEvent
+		err = json.Unmarshal(payload, &e)
+		if err != nil {
+			t.Error(err)
+		}
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		events = append(events, e)
+	}))
+	defer ts.Close()
+	b.cfg.Webhooks = []string{ts.URL}
+	b.cfg.WebhookSecret = "secret"
+	b.webhookBackoff = time.Millisecond
+
+	u, id := createAndVerifyUser(t, b)
+	user, err := b.db.UserGet(u.Email)
+	assertSuccess(t, err)
+	_, npr, err := createNewProposal(b, t, user, id)
+	assertSuccess(t, err)
+
+	// Wait for the retry.
+	var wdr *www.WebhookDeliveriesReply
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		wdr = b.ProcessWebhookDeliveries()
+		if len(wdr.Deliveries) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 deliveries, got %v",
+				len(wdr.Deliveries))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mtx.Lock()
+	if len(events) != 1 {
+		mtx.Unlock()
+		t.Fatalf("expected 1 event, got %v", len(events))
+	}
+	e := events[0]
+	mtx.Unlock()
+	// Unvetted proposals are only identified by their token.
+	if e.Type != www.WebhookEventProposalSubmitted ||
+		e.Token != npr.CensorshipRecord.Token ||
+		e.Status != www.PropStatusNotReviewed || e.Name != "" {
+		t.Fatalf("unexpected event %v", e)
+	}
+
+	// Both attempts are logged, newest first.
+	if d := wdr.Deliveries[0]; d.Attempt != 2 ||
+		d.StatusCode != http.StatusOK || d.Error != "" ||
+		d.EventID != e.ID {
+		t.Fatalf("unexpected delivery %v", d)
+	}
+	if d := wdr.Deliveries[1]; d.Attempt != 1 ||
+		d.StatusCode != http.StatusServiceUnavailable || d.Error == "" {
+		t.Fatalf("unexpected delivery %v", d)
+	}
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"crypto/elliptic"
+	"crypto/subtle"
 	"crypto/tls"
 	_ "encoding/gob"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/http/httputil"
 	"os"
@@ -17,15 +20,19 @@ import (
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/btcsuite/websocket"
 	"github.com/decred/politeia/politeiawww/api/v1"
 	"github.com/decred/politeia/politeiawww/database"
 	"github.com/decred/politeia/util"
 	"github.com/gorilla/csrf"
 	"github.com/gorilla/mux"
+	"github.com/gorilla/schema"
 	"github.com/gorilla/sessions"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 type permission uint
@@ -36,6 +43,17 @@ const (
 	permissionAdmin
 
 	csrfKeyLength = 32
+
+	// shutdownTimeout is the time that the requests in flight and the
+	// pending notification emails are given to complete on shutdown.
+	shutdownTimeout = 30 * time.Second
+
+	// Timeouts that protect the listeners against slow clients.  The read
+	// timeout leaves time to upload the largest proposals.
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 60 * time.Second
+	writeTimeout      = 60 * time.Second
+	idleTimeout       = 120 * time.Second
 )
 
 // politeiawww application context.
@@ -43,9 +61,17 @@ type politeiawww struct {
 	cfg    *config
 	router *mux.Router
 
-	store *sessions.FilesystemStore
+	store sessions.Store
 
 	backend *backend
+
+	// reporter forwards handler panics to an error tracking service.  It
+	// is nil when no service is configured.
+	reporter errorReporter
+
+	// primary forwards requests to the primary of the cluster.  It is nil
+	// unless this instance is a replica.
+	primary *httputil.ReverseProxy
 }
 
 type newUserEmailTemplateData struct {
@@ -61,36 +87,191 @@ type resetPasswordEmailTemplateData struct {
 	Link  string
 	Email string
 }
+type changeEmailTemplateData struct {
+	Link  string
+	Email string
+}
+type newDeviceLoginTemplateData struct {
+	Email     string
+	IP        string
+	UserAgent string
+	Time      string
+	Link      string
+}
+type userAdminChangedTemplateData struct {
+	Email  string
+	Admin  bool
+	Reason string
+}
+type userVerifiedByAdminTemplateData struct {
+	Email  string
+	Reason string
+}
+type accountRecoveredTemplateData struct {
+	Email string
+}
+type proposalStatusChangeTemplateData struct {
+	Link   string
+	Name   string
+	Reason string
+	Email  string
+}
+type adminProposalNewTemplateData struct {
+	Proposals []newProposalNotification
+}
+type voteStartedTemplateData struct {
+	Link      string
+	Name      string
+	EndHeight string
+}
+type voteEndedTemplateData struct {
+	Link    string
+	Name    string
+	Results []voteOptionResult
+	Winner  *voteOptionResult
+}
+
+type unsubscribeTemplateData struct {
+	Link string
+}
+
+type digestTemplateData struct {
+	Proposals []digestProposal
+	Votes     []digestVote
+	Activity  []digestActivity
+}
+
+// getSession returns the currently logged in user and the id of its login
+// session from the session store or from the bearer token of the request.
+// The user is nil if the session is not
+// logged in.  Sessions of users that are deactivated or no longer exist and
+// sessions that were revoked are not logged in.  Sessions of admins that
+// impersonate a user are logged in as that user without a login session.
+func (p *politeiawww) getSession(r *http.Request) (*database.User, string, error) {
+	// API clients authenticate with an access token or a personal access
+	// token instead of the session cookie.  Personal access tokens have no
+	// login session and are limited to the requests of their scope.
+	if token, ok := bearerToken(r); ok {
+		if isAPIToken(token) {
+			user, t, err := p.backend.verifyAPIToken(token)
+			if err != nil || user == nil || !apiTokenAllows(t.Scope, r) {
+				return nil, "", err
+			}
+			return user, "", nil
+		}
+
+		user, claims, err := p.backend.verifyToken(token, jwtTypeAccess)
+		if err != nil || user == nil {
+			return nil, "", err
+		}
+		return user, claims.SessionID, nil
+	}
 
-// getSessionEmail returns the email address of the currently logged in user
-// from the session store.
-func (p *politeiawww) getSessionEmail(r *http.Request) (string, error) {
 	session, err := p.store.Get(r, v1.CookieSession)
 	if err != nil {
-		return "", err
+		return nil, "", err
+	}
+
+	user, id, err := p.sessionLogin(session)
+	if err != nil || user == nil {
+		return nil, "", err
+	}
+	impersonated, err := p.impersonatedUser(session, user)
+	if err != nil {
+		return nil, "", err
+	}
+	if impersonated != nil {
+		return impersonated, "", nil
 	}
 
+	return user, id, nil
+}
+
+// sessionLogin returns the user that the provided session logged in as and
+// the id of its login session.  The user is nil if the session is not logged
+// in.
+func (p *politeiawww) sessionLogin(session *sessions.Session) (*database.User, string, error) {
 	email, ok := session.Values["email"].(string)
-	if !ok {
-		// No email in session so return "" to indicate that.
-		return "", nil
+	if !ok || email == "" {
+		// No email in session so return nil to indicate that.
+		return nil, "", nil
+	}
+	id, _ := session.Values["sessionid"].(string)
+
+	user, err := p.backend.db.UserGet(email)
+	if err == database.ErrUserNotFound {
+		return nil, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+	if user.Deactivated != 0 || !loginSessionValid(user, id) {
+		return nil, "", nil
+	}
+
+	return user, id, nil
+}
+
+// getSessionEmail returns the email address of the currently logged in user
+// from the session store.
+func (p *politeiawww) getSessionEmail(r *http.Request) (string, error) {
+	user, _, err := p.getSession(r)
+	if err != nil || user == nil {
+		return "", err
 	}
 
-	return email, nil
+	return user.Email, nil
 }
 
 // getSessionUser retrieves the current session user from the database.
 func (p *politeiawww) getSessionUser(r *http.Request) (*database.User, error) {
 	log.Tracef("getSessionUser")
-	email, err := p.getSessionEmail(r)
+	user, _, err := p.getSession(r)
 	if err != nil {
 		return nil, err
 	}
+	if user == nil {
+		return nil, database.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// setSessionLogin logs the session in as the user with the provided email
+// and login session id.
+func (p *politeiawww) setSessionLogin(w http.ResponseWriter, r *http.Request, email, id string) error {
+	log.Tracef("setSessionLogin: %v %v", email, v1.CookieSession)
+	session, err := p.store.Get(r, v1.CookieSession)
+	if err != nil {
+		return err
+	}
+
+	session.Values["email"] = email
+	session.Values["sessionid"] = id
+	delete(session.Values, sessionImpersonate)
+	delete(session.Values, sessionImpersonateExpiry)
+	return session.Save(r, w)
+}
+
+// setSessionOIDCState stores the provided state of a federated login in the
+// session, or clears it when the state is empty.  It returns the state that
+// the session held.
+func (p *politeiawww) setSessionOIDCState(w http.ResponseWriter, r *http.Request, state string) (string, error) {
+	session, err := p.store.Get(r, v1.CookieSession)
+	if err != nil {
+		return "", err
+	}
 
-	return p.backend.db.UserGet(email)
+	old, _ := session.Values[sessionOIDCState].(string)
+	if state == "" {
+		delete(session.Values, sessionOIDCState)
+	} else {
+		session.Values[sessionOIDCState] = state
+	}
+	return old, session.Save(r, w)
 }
 
-// setSessionUser sets the "email" session key to the provided value.
+// setSessionUser sets the "email" session key to the provided value and
+// ends any impersonation.
 func (p *politeiawww) setSessionUser(w http.ResponseWriter, r *http.Request, email string) error {
 	log.Tracef("setSessionUser: %v %v", email, v1.CookieSession)
 	session, err := p.store.Get(r, v1.CookieSession)
@@ -99,6 +280,8 @@ func (p *politeiawww) setSessionUser(w http.ResponseWriter, r *http.Request, ema
 	}
 
 	session.Values["email"] = email
+	delete(session.Values, sessionImpersonate)
+	delete(session.Values, sessionImpersonateExpiry)
 	return session.Save(r, w)
 }
 
@@ -109,6 +292,17 @@ func (p *politeiawww) isAdmin(r *http.Request) (bool, error) {
 		return false, err
 	}
 
+	// Personal access tokens need the admin scope for the admin routes.
+	if token, ok := bearerToken(r); ok && isAPIToken(token) {
+		_, t, err := p.backend.verifyAPIToken(token)
+		if err != nil {
+			return false, err
+		}
+		if t == nil || t.Scope != v1.APITokenScopeAdmin {
+			return false, nil
+		}
+	}
+
 	return user.Admin, nil
 }
 
@@ -168,11 +362,13 @@ func RespondWithError(w http.ResponseWriter, r *http.Request, userHttpCode int,
 		}
 
 		if len(userErr.ErrorContext) == 0 {
-			log.Errorf("RespondWithError: %v %v",
+			log.Errorf("RespondWithError: reqid=%v %v %v",
+				util.RequestID(r.Context()),
 				int64(userErr.ErrorCode),
 				v1.ErrorStatus[userErr.ErrorCode])
 		} else {
-			log.Errorf("RespondWithError: %v %v: %v",
+			log.Errorf("RespondWithError: reqid=%v %v %v: %v",
+				util.RequestID(r.Context()),
 				int64(userErr.ErrorCode),
 				v1.ErrorStatus[userErr.ErrorCode],
 				strings.Join(userErr.ErrorContext, ", "))
@@ -189,8 +385,9 @@ func RespondWithError(w http.ResponseWriter, r *http.Request, userHttpCode int,
 		pdErrorCode := convertErrorStatusFromPD(pdError.ErrorReply.ErrorCode)
 		if pdErrorCode == v1.ErrorStatusInvalid {
 			errorCode := time.Now().Unix()
-			log.Errorf("%v %v %v %v Internal error %v: error "+
-				"code from politeiad: %v", remoteAddr(r),
+			log.Errorf("reqid=%v %v %v %v %v Internal error %v: "+
+				"error code from politeiad: %v",
+				util.RequestID(r.Context()), remoteAddr(r),
 				r.Method, r.URL, r.Proto, errorCode,
 				pdError.ErrorReply.ErrorCode)
 			util.RespondWithJSON(w, http.StatusInternalServerError,
@@ -209,8 +406,9 @@ func RespondWithError(w http.ResponseWriter, r *http.Request, userHttpCode int,
 	}
 
 	errorCode := time.Now().Unix()
-	ec := fmt.Sprintf("%v %v %v %v Internal error %v: ", remoteAddr(r),
-		r.Method, r.URL, r.Proto, errorCode)
+	ec := fmt.Sprintf("reqid=%v %v %v %v %v Internal error %v: ",
+		util.RequestID(r.Context()), remoteAddr(r), r.Method, r.URL,
+		r.Proto, errorCode)
 	log.Errorf(ec+format, args...)
 	log.Errorf("Stacktrace: %s", debug.Stack())
 	util.RespondWithJSON(w, http.StatusInternalServerError,
@@ -270,6 +468,21 @@ func (p *politeiawww) handleNewUser(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
+// handleSignupChallenge replies with a proof of work challenge that must be
+// solved before creating a new user.
+func (p *politeiawww) handleSignupChallenge(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSignupChallenge")
+
+	reply, err := p.backend.ProcessSignupChallenge()
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSignupChallenge: ProcessSignupChallenge %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
 // handleVerifyNewUser handles the incoming new user verify command. It verifies
 // that the user with the provided email has a verification token that matches
 // the provided token and that the verification token has not yet expired.
@@ -287,16 +500,110 @@ func (p *politeiawww) handleVerifyNewUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	_, err = p.backend.ProcessVerifyNewUser(vnu)
+	user, err := p.backend.ProcessVerifyNewUser(vnu)
 	if err != nil {
 		RespondWithError(w, r, 0, "handleVerifyNewUser: "+
 			"ProcessVerifyNewUser %v", err)
 		return
 	}
 
+	p.securityEvent(r, user.ID, v1.SecurityEventVerify, "")
+
 	util.RespondWithJSON(w, http.StatusOK, v1.VerifyNewUserReply{})
 }
 
+// handleUnsubscribe handles the signed unsubscribe links that are included in
+// notification emails and the one-click unsubscribes of mail clients.  It
+// does not require the user to be logged in.  Link scanners and previews
+// follow the links of emails, so a GET only renders a page that asks the user
+// to confirm and the unsubscribe itself requires a POST.
+func (p *politeiawww) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUnsubscribe")
+
+	// Mail clients post a List-Unsubscribe=One-Click form to the link of
+	// the List-Unsubscribe header, see RFC 8058.  The parameters are those
+	// of the link.
+	var u v1.Unsubscribe
+	err := schema.NewDecoder().Decode(&u, r.URL.Query())
+	if err != nil {
+		RespondWithError(w, r, 0, "handleUnsubscribe: Decode",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err = templateUnsubscribeConfirm.Execute(w,
+			&unsubscribeTemplateData{
+				Link: r.URL.RequestURI(),
+			})
+		if err != nil {
+			log.Errorf("handleUnsubscribe: Execute %v", err)
+		}
+		return
+	}
+
+	reply, err := p.backend.ProcessUnsubscribe(u)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleUnsubscribe: "+
+			"ProcessUnsubscribe %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleEmailWebhook authenticates a call to an email provider webhook and
+// processes the bounce events that the provided parser extracts from it.
+func (p *politeiawww) handleEmailWebhook(w http.ResponseWriter, r *http.Request, parse func([]byte) ([]bounceEvent, error)) {
+	token := r.URL.Query().Get("token")
+	if subtle.ConstantTimeCompare([]byte(token),
+		[]byte(p.cfg.MailWebhookToken)) != 1 {
+		util.RespondWithJSON(w, http.StatusForbidden, v1.ErrorReply{})
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleEmailWebhook: ReadAll %v", err)
+		return
+	}
+	events, err := parse(body)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleEmailWebhook: parse",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	err = p.backend.ProcessBounceEvents(events)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleEmailWebhook: "+
+			"ProcessBounceEvents %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, struct{}{})
+}
+
+// handleSESWebhook handles the bounce and complaint notifications that AWS
+// SES delivers through SNS.
+func (p *politeiawww) handleSESWebhook(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSESWebhook")
+
+	p.handleEmailWebhook(w, r, p.backend.parseSESNotification)
+}
+
+// handleSendgridWebhook handles the events of the Sendgrid event webhook.
+func (p *politeiawww) handleSendgridWebhook(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSendgridWebhook")
+
+	p.handleEmailWebhook(w, r, parseSendgridEvents)
+}
+
 // handleUpdateUserKey handles the incoming update user key command. It generates
 // a random code used for verification. The code is intended to be sent to the
 // email of the logged in user.
@@ -353,182 +660,1196 @@ func (p *politeiawww) handleVerifyUpdateUserKey(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	_, err = p.backend.ProcessVerifyUpdateUserKey(user, vuu)
+	user, err = p.backend.ProcessVerifyUpdateUserKey(user, vuu)
 	if err != nil {
 		RespondWithError(w, r, 0, "handleVerifyUpdateUserKey: "+
 			"ProcessVerifyUpdateUserKey %v", err)
 		return
 	}
 
+	publicKey, _ := database.ActiveIdentityString(user.Identities)
+	p.securityEvent(r, user.ID, v1.SecurityEventKeyAdded, publicKey)
+
 	util.RespondWithJSON(w, http.StatusOK, v1.VerifyUpdateUserKeyReply{})
 }
 
-// handleLogin handles the incoming login command.  It verifies that the user
-// exists and the accompanying password.  On success a cookie is added to the
-// gorilla sessions that must be returned on subsequent calls.
-func (p *politeiawww) handleLogin(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleLogin")
+// handleUserKeyChallenge replies with a challenge that a new public key of
+// the logged in user must sign before it can be added.
+func (p *politeiawww) handleUserKeyChallenge(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUserKeyChallenge")
 
-	// Get the login command.
-	var l v1.Login
+	var kc v1.UserKeyChallenge
 	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&l); err != nil {
-		RespondWithError(w, r, 0, "handleLogin: failed to decode: %v", err)
+	if err := decoder.Decode(&kc); err != nil {
+		RespondWithError(w, r, 0, "handleUserKeyChallenge: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
 		return
 	}
 
-	reply, err := p.backend.ProcessLogin(l)
+	user, err := p.getSessionUser(r)
 	if err != nil {
-		RespondWithError(w, r, http.StatusUnauthorized,
-			"handleLogin: ProcessLogin %v", err)
+		RespondWithError(w, r, 0,
+			"handleUserKeyChallenge: getSessionUser %v", err)
 		return
 	}
 
-	// Mark user as logged in if there's no error.
-	err = p.setSessionUser(w, r, l.Email)
+	reply, err := p.backend.ProcessUserKeyChallenge(user, kc)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleLogin: setSessionUser %v", err)
+			"handleUserKeyChallenge: ProcessUserKeyChallenge %v", err)
 		return
 	}
 
-	// Reply with the user information.
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-// handleLogout logs the user out.  A login will be required to resume sending
-// commands,
-func (p *politeiawww) handleLogout(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleLogout")
+// handleAddUserKey activates an additional public key for the logged in user.
+func (p *politeiawww) handleAddUserKey(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleAddUserKey")
+
+	var ak v1.AddUserKey
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&ak); err != nil {
+		RespondWithError(w, r, 0, "handleAddUserKey: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
 
-	err := p.setSessionUser(w, r, "")
+	user, err := p.getSessionUser(r)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleLogout: setSessionUser %v", err)
+			"handleAddUserKey: getSessionUser %v", err)
 		return
 	}
 
-	// Reply with the user information.
-	var reply v1.LogoutReply
-	util.RespondWithJSON(w, http.StatusOK, reply)
-}
-
-// handleSecret is a mock handler to test privileged routes.
-func (p *politeiawww) handleSecret(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleSecret")
-
-	fmt.Fprintf(w, "secret sauce")
-}
-
-// handleMe returns logged in user information.
-func (p *politeiawww) handleMe(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleMe")
-
-	user, err := p.getSessionUser(r)
+	reply, err := p.backend.ProcessAddUserKey(user, ak)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleMe: getSessionUser %v", err)
+			"handleAddUserKey: ProcessAddUserKey %v", err)
 		return
 	}
 
-	reply := p.backend.CreateLoginReply(user)
-	util.RespondWithJSON(w, http.StatusOK, *reply)
+	p.securityEvent(r, user.ID, v1.SecurityEventKeyAdded, ak.PublicKey)
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-func (p *politeiawww) handleChangePassword(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleChangePassword")
+// handleDeactivateUserKey deactivates a public key of the logged in user.
+func (p *politeiawww) handleDeactivateUserKey(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleDeactivateUserKey")
 
-	// Get the change password command.
-	var cp v1.ChangePassword
+	var dk v1.DeactivateUserKey
 	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&cp); err != nil {
-		RespondWithError(w, r, 0, "handleChangePassword: unmarshal", v1.UserError{
-			ErrorCode: v1.ErrorStatusInvalidInput,
-		})
+	if err := decoder.Decode(&dk); err != nil {
+		RespondWithError(w, r, 0, "handleDeactivateUserKey: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
 		return
 	}
 
 	user, err := p.getSessionUser(r)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleChangePassword: getSessionUser %v", err)
+			"handleDeactivateUserKey: getSessionUser %v", err)
 		return
 	}
 
-	reply, err := p.backend.ProcessChangePassword(user.Email, cp)
+	reply, err := p.backend.ProcessDeactivateUserKey(user, dk)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleChangePassword: ProcessChangePassword %v", err)
+			"handleDeactivateUserKey: ProcessDeactivateUserKey %v", err)
 		return
 	}
 
-	// Reply with the error code.
+	p.securityEvent(r, user.ID, v1.SecurityEventKeyDeactivated,
+		dk.PublicKey)
+
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-func (p *politeiawww) handleResetPassword(w http.ResponseWriter, r *http.Request) {
-	log.Trace("handleResetPassword")
-
-	// Get the reset password command.
-	var rp v1.ResetPassword
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&rp); err != nil {
-		RespondWithError(w, r, 0, "handleResetPassword: unmarshal", v1.UserError{
-			ErrorCode: v1.ErrorStatusInvalidInput,
-		})
-		return
-	}
+// handleUserIdentities returns the public key history of the logged in user.
+func (p *politeiawww) handleUserIdentities(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUserIdentities")
 
-	rpr, err := p.backend.ProcessResetPassword(rp)
+	user, err := p.getSessionUser(r)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleResetPassword: ProcessResetPassword %v", err)
+			"handleUserIdentities: getSessionUser %v", err)
 		return
 	}
 
-	// Reply with the error code.
-	util.RespondWithJSON(w, http.StatusOK, rpr)
+	util.RespondWithJSON(w, http.StatusOK,
+		p.backend.ProcessUserIdentities(user))
 }
 
-// handleNewProposal handles the incoming new proposal command.
-func (p *politeiawww) handleNewProposal(w http.ResponseWriter, r *http.Request) {
-	// Get the new proposal command.
-	log.Tracef("handleNewProposal")
-	var np v1.NewProposal
+// handleLogin handles the incoming login command.  It verifies that the user
+// exists and the accompanying password.  On success a cookie is added to the
+// gorilla sessions that must be returned on subsequent calls, unless tokens
+// were requested for an API client.
+func (p *politeiawww) handleLogin(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleLogin")
+
+	// Get the login command.
+	var l v1.Login
 	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&np); err != nil {
-		RespondWithError(w, r, 0, "handleNewProposal: unmarshal", v1.UserError{
-			ErrorCode: v1.ErrorStatusInvalidInput,
-		})
+	if err := decoder.Decode(&l); err != nil {
+		RespondWithError(w, r, 0, "handleLogin: failed to decode: %v", err)
 		return
 	}
 
-	user, err := p.getSessionUser(r)
+	reply, err := p.backend.ProcessLogin(l)
 	if err != nil {
-		RespondWithError(w, r, 0,
-			"handleNewProposal: getSessionUser %v", err)
+		if e, ok := err.(v1.UserError); ok && (e.ErrorCode ==
+			v1.ErrorStatusInvalidEmailOrPassword || e.ErrorCode ==
+			v1.ErrorStatusInvalidTOTPCode) {
+			p.backend.reportAbuse(p.clientIP(r), "failed logins")
+
+			// Record the failure if the email belongs to a user.
+			user, err := p.backend.db.UserGet(l.Email)
+			if err == nil {
+				p.securityEvent(r, user.ID,
+					v1.SecurityEventLoginFailed,
+					v1.ErrorStatus[e.ErrorCode])
+			}
+		}
+		RespondWithError(w, r, http.StatusUnauthorized,
+			"handleLogin: ProcessLogin %v", err)
 		return
 	}
 
-	reply, err := p.backend.ProcessNewProposal(np, user)
+	// Mark user as logged in if there's no error.
+	err = p.login(w, r, l.Email, l.IssueTokens, reply)
 	if err != nil {
-		RespondWithError(w, r, 0,
-			"handleNewProposal: ProcessNewProposal %v", err)
+		RespondWithError(w, r, 0, "handleLogin: login %v", err)
 		return
 	}
 
-	// Reply with the challenge response and censorship token.
+	// Reply with the user information.
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-// handleSetProposalStatus handles the incoming set proposal status command.
-// It's used for either publishing or censoring a proposal.
-func (p *politeiawww) handleSetProposalStatus(w http.ResponseWriter, r *http.Request) {
-	// Get the proposal status command.
-	log.Tracef("handleSetProposalStatus")
-	var sps v1.SetProposalStatus
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&sps); err != nil {
+// login records a new login session of the user with the provided email that
+// authenticated.  The session is added to the gorilla sessions, or the API
+// tokens of the session are added to the provided reply if requested.
+func (p *politeiawww) login(w http.ResponseWriter, r *http.Request, email string, issueTokens bool, reply *v1.LoginReply) error {
+	maxAge := sessionMaxAge
+	if issueTokens {
+		maxAge = jwtRefreshExpiry
+	}
+	id, err := p.backend.newLoginSession(email, p.clientIP(r).String(),
+		r.UserAgent(), maxAge)
+	if err != nil {
+		return err
+	}
+	if !issueTokens {
+		return p.setSessionLogin(w, r, email, id)
+	}
+
+	user, err := p.backend.db.UserGet(email)
+	if err != nil {
+		return err
+	}
+	tokens, err := p.backend.issueTokens(user, id)
+	if err != nil {
+		return err
+	}
+	reply.AccessToken = tokens.AccessToken
+	reply.RefreshToken = tokens.RefreshToken
+	reply.TokenExpiry = tokens.TokenExpiry
+
+	return nil
+}
+
+// handleLogout logs the user out.  A login will be required to resume sending
+// commands,
+func (p *politeiawww) handleLogout(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleLogout")
+
+	user, id, err := p.getSession(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleLogout: getSession %v", err)
+		return
+	}
+	if user != nil {
+		err = p.backend.ProcessLogout(user, id)
+		if err != nil {
+			RespondWithError(w, r, 0,
+				"handleLogout: ProcessLogout %v", err)
+			return
+		}
+	}
+
+	err = p.setSessionUser(w, r, "")
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleLogout: setSessionUser %v", err)
+		return
+	}
+
+	// Reply with the user information.
+	var reply v1.LogoutReply
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleOIDCLogin returns the URL of the OpenID Connect provider that starts a
+// federated login.
+func (p *politeiawww) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleOIDCLogin")
+
+	reply, state, err := p.backend.ProcessOIDCAuth(nil)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleOIDCLogin: ProcessOIDCAuth %v", err)
+		return
+	}
+
+	_, err = p.setSessionOIDCState(w, r, state)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleOIDCLogin: setSessionOIDCState %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleOIDCLink returns the URL of the OpenID Connect provider that links a
+// provider account to the logged in user.
+func (p *politeiawww) handleOIDCLink(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleOIDCLink")
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleOIDCLink: getSessionUser %v", err)
+		return
+	}
+
+	reply, state, err := p.backend.ProcessOIDCAuth(user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleOIDCLink: ProcessOIDCAuth %v", err)
+		return
+	}
+
+	_, err = p.setSessionOIDCState(w, r, state)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleOIDCLink: setSessionOIDCState %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleOIDCCallback completes a federated login or link.
+func (p *politeiawww) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleOIDCCallback")
+
+	var c v1.OIDCCallback
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&c); err != nil {
+		RespondWithError(w, r, 0, "handleOIDCCallback: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	sessionUser, _, err := p.getSession(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleOIDCCallback: getSession %v", err)
+		return
+	}
+
+	// The state is cleared from the session whatever the outcome.
+	state, err := p.setSessionOIDCState(w, r, "")
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleOIDCCallback: setSessionOIDCState %v", err)
+		return
+	}
+
+	user, login, err := p.backend.ProcessOIDCCallback(sessionUser, state,
+		c)
+	if err != nil {
+		if e, ok := err.(v1.UserError); ok && e.ErrorCode ==
+			v1.ErrorStatusInvalidTOTPCode {
+			p.backend.reportAbuse(p.clientIP(r), "failed logins")
+		}
+		RespondWithError(w, r, http.StatusUnauthorized,
+			"handleOIDCCallback: ProcessOIDCCallback %v", err)
+		return
+	}
+
+	reply := p.backend.CreateLoginReply(user)
+	if login {
+		err = p.login(w, r, user.Email, c.IssueTokens, reply)
+		if err != nil {
+			RespondWithError(w, r, 0,
+				"handleOIDCCallback: login %v", err)
+			return
+		}
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleOIDCUnlink unlinks the provider account of the logged in user.
+func (p *politeiawww) handleOIDCUnlink(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleOIDCUnlink")
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleOIDCUnlink: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessOIDCUnlink(user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleOIDCUnlink: ProcessOIDCUnlink %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleRefreshToken exchanges a refresh token for new API tokens.
+func (p *politeiawww) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleRefreshToken")
+
+	var rt v1.RefreshToken
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&rt); err != nil {
+		RespondWithError(w, r, 0, "handleRefreshToken: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	reply, err := p.backend.ProcessRefreshToken(rt)
+	if err != nil {
+		RespondWithError(w, r, http.StatusUnauthorized,
+			"handleRefreshToken: ProcessRefreshToken %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleRevokeToken revokes an API token.
+func (p *politeiawww) handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleRevokeToken")
+
+	var rt v1.RevokeToken
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&rt); err != nil {
+		RespondWithError(w, r, 0, "handleRevokeToken: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	reply, err := p.backend.ProcessRevokeToken(rt)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleRevokeToken: ProcessRevokeToken %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleSecret is a mock handler to test privileged routes.
+func (p *politeiawww) handleSecret(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSecret")
+
+	fmt.Fprintf(w, "secret sauce")
+}
+
+// handleMe returns logged in user information.
+func (p *politeiawww) handleMe(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleMe")
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleMe: getSessionUser %v", err)
+		return
+	}
+
+	reply := p.backend.CreateLoginReply(user)
+
+	admin, _, err := p.getImpersonation(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleMe: getImpersonation %v", err)
+		return
+	}
+	if admin != nil {
+		reply.ImpersonatedBy = strconv.FormatUint(admin.ID, 10)
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, *reply)
+}
+
+func (p *politeiawww) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleChangePassword")
+
+	// Get the change password command.
+	var cp v1.ChangePassword
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&cp); err != nil {
+		RespondWithError(w, r, 0, "handleChangePassword: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleChangePassword: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessChangePassword(user.Email, cp)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleChangePassword: ProcessChangePassword %v", err)
+		return
+	}
+
+	p.securityEvent(r, user.ID, v1.SecurityEventPasswordChange, "")
+
+	// Reply with the error code.
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleChangeEmail handles the request to change the email address of the
+// logged in user.
+func (p *politeiawww) handleChangeEmail(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleChangeEmail")
+
+	var ce v1.ChangeEmail
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&ce); err != nil {
+		RespondWithError(w, r, 0, "handleChangeEmail: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleChangeEmail: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessChangeEmail(user, ce)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleChangeEmail: ProcessChangeEmail %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleDeactivateUser deactivates the account of the logged in user and logs
+// the user out.
+func (p *politeiawww) handleDeactivateUser(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleDeactivateUser")
+
+	var du v1.DeactivateUser
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&du); err != nil {
+		RespondWithError(w, r, 0, "handleDeactivateUser: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleDeactivateUser: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessDeactivateUser(user, du)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleDeactivateUser: ProcessDeactivateUser %v", err)
+		return
+	}
+
+	err = p.setSessionUser(w, r, "")
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleDeactivateUser: setSessionUser %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleSecurityEvents returns the security events of the logged in user.
+func (p *politeiawww) handleSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSecurityEvents")
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSecurityEvents: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessSecurityEvents(user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSecurityEvents: ProcessSecurityEvents %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleDrafts returns the proposal drafts of the logged in user.
+func (p *politeiawww) handleDrafts(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleDrafts")
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleDrafts: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessDrafts(user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleDrafts: ProcessDrafts %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleNewDraft saves a new proposal draft of the logged in user.
+func (p *politeiawww) handleNewDraft(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleNewDraft")
+
+	var nd v1.NewDraft
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&nd); err != nil {
+		RespondWithError(w, r, 0, "handleNewDraft: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleNewDraft: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessNewDraft(nd, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleNewDraft: ProcessNewDraft %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleEditDraft replaces the files of a proposal draft of the logged in
+// user.
+func (p *politeiawww) handleEditDraft(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleEditDraft")
+
+	var ed v1.EditDraft
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&ed); err != nil {
+		RespondWithError(w, r, 0, "handleEditDraft: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleEditDraft: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessEditDraft(ed, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleEditDraft: ProcessEditDraft %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleDeleteDraft deletes a proposal draft of the logged in user.
+func (p *politeiawww) handleDeleteDraft(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleDeleteDraft")
+
+	var dd v1.DeleteDraft
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dd); err != nil {
+		RespondWithError(w, r, 0, "handleDeleteDraft: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleDeleteDraft: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessDeleteDraft(dd, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleDeleteDraft: ProcessDeleteDraft %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleUserExport starts generating the archive of the data stored about the
+// logged in user.
+func (p *politeiawww) handleUserExport(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUserExport")
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUserExport: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessUserExport(user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUserExport: ProcessUserExport %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleUserExportDownload downloads the archive of the data stored about the
+// logged in user.
+func (p *politeiawww) handleUserExportDownload(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUserExportDownload")
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUserExportDownload: getSessionUser %v", err)
+		return
+	}
+
+	payload, err := p.backend.ProcessUserExportDownload(user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUserExportDownload: ProcessUserExportDownload %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"politeia-user-%v.json\"",
+			user.ID))
+	err = util.RespondWithCopy(w, http.StatusOK, "application/json", payload)
+	if err != nil {
+		log.Errorf("handleUserExportDownload: RespondWithCopy %v", err)
+	}
+}
+
+// handleUserSessions returns the login sessions of the logged in user.
+func (p *politeiawww) handleUserSessions(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUserSessions")
+
+	user, id, err := p.getSession(r)
+	if err != nil || user == nil {
+		RespondWithError(w, r, 0,
+			"handleUserSessions: getSession %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK,
+		p.backend.ProcessUserSessions(user, id))
+}
+
+// handleRevokeSessions revokes login sessions of the logged in user.
+func (p *politeiawww) handleRevokeSessions(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleRevokeSessions")
+
+	var rs v1.RevokeSessions
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&rs); err != nil {
+		RespondWithError(w, r, 0, "handleRevokeSessions: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleRevokeSessions: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessRevokeSessions(user, rs)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleRevokeSessions: ProcessRevokeSessions %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleAPITokens returns the personal access tokens of the logged in user.
+func (p *politeiawww) handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleAPITokens")
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleAPITokens: getSessionUser %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, p.backend.ProcessAPITokens(user))
+}
+
+// handleNewAPIToken creates a personal access token for the logged in user.
+func (p *politeiawww) handleNewAPIToken(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleNewAPIToken")
+
+	var nt v1.NewAPIToken
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&nt); err != nil {
+		RespondWithError(w, r, 0, "handleNewAPIToken: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleNewAPIToken: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessNewAPIToken(user, nt)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleNewAPIToken: ProcessNewAPIToken %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleRevokeAPIToken revokes a personal access token of the logged in user.
+func (p *politeiawww) handleRevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleRevokeAPIToken")
+
+	var rt v1.RevokeAPIToken
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&rt); err != nil {
+		RespondWithError(w, r, 0, "handleRevokeAPIToken: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleRevokeAPIToken: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessRevokeAPIToken(user, rt)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleRevokeAPIToken: ProcessRevokeAPIToken %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleVerifyChangeEmail verifies the new email address of the logged in
+// user and moves the session to it.
+func (p *politeiawww) handleVerifyChangeEmail(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleVerifyChangeEmail")
+
+	var vce v1.VerifyChangeEmail
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&vce); err != nil {
+		RespondWithError(w, r, 0, "handleVerifyChangeEmail: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleVerifyChangeEmail: getSessionUser %v", err)
+		return
+	}
+
+	user, err = p.backend.ProcessVerifyChangeEmail(user, vce)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleVerifyChangeEmail: "+
+			"ProcessVerifyChangeEmail %v", err)
+		return
+	}
+
+	p.securityEvent(r, user.ID, v1.SecurityEventEmailChange, user.Email)
+
+	// The session refers to the user by email.
+	err = p.setSessionUser(w, r, user.Email)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleVerifyChangeEmail: setSessionUser %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, v1.VerifyChangeEmailReply{})
+}
+
+// handleSetTOTP starts the TOTP enrollment of the user.
+func (p *politeiawww) handleSetTOTP(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSetTOTP")
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSetTOTP: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessSetTOTP(user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSetTOTP: ProcessSetTOTP %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleVerifyTOTP confirms the TOTP enrollment of the user.
+func (p *politeiawww) handleVerifyTOTP(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleVerifyTOTP")
+
+	var vt v1.VerifyTOTP
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&vt); err != nil {
+		RespondWithError(w, r, 0, "handleVerifyTOTP: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleVerifyTOTP: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessVerifyTOTP(user, vt)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleVerifyTOTP: ProcessVerifyTOTP %v", err)
+		return
+	}
+
+	p.securityEvent(r, user.ID, v1.SecurityEventTOTPEnabled, "")
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleDisableTOTP disables the TOTP second factor of the user.
+func (p *politeiawww) handleDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleDisableTOTP")
+
+	var dt v1.DisableTOTP
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dt); err != nil {
+		RespondWithError(w, r, 0, "handleDisableTOTP: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleDisableTOTP: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessDisableTOTP(user, dt)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleDisableTOTP: ProcessDisableTOTP %v", err)
+		return
+	}
+
+	p.securityEvent(r, user.ID, v1.SecurityEventTOTPDisabled, "")
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleEditUser handles editing the settings of the logged in user.
+func (p *politeiawww) handleEditUser(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleEditUser")
+
+	var eu v1.EditUser
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&eu); err != nil {
+		RespondWithError(w, r, 0, "handleEditUser: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleEditUser: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessEditUser(user, eu)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleEditUser: ProcessEditUser %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+func (p *politeiawww) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	log.Trace("handleResetPassword")
+
+	// Get the reset password command.
+	var rp v1.ResetPassword
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&rp); err != nil {
+		RespondWithError(w, r, 0, "handleResetPassword: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	rpr, err := p.backend.ProcessResetPassword(rp)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleResetPassword: ProcessResetPassword %v", err)
+		return
+	}
+
+	// Unknown users are ignored by ProcessResetPassword.
+	if rp.VerificationToken != "" {
+		user, err := p.backend.db.UserGet(rp.Email)
+		if err == nil {
+			p.securityEvent(r, user.ID, v1.SecurityEventPasswordReset,
+				"")
+		}
+	}
+
+	// Reply with the error code.
+	util.RespondWithJSON(w, http.StatusOK, rpr)
+}
+
+// handleRecoveryChallenge replies with a challenge that a public key must
+// sign to recover the account of its user.
+func (p *politeiawww) handleRecoveryChallenge(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleRecoveryChallenge")
+
+	var rc v1.RecoveryChallenge
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&rc); err != nil {
+		RespondWithError(w, r, 0, "handleRecoveryChallenge: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	reply, err := p.backend.ProcessRecoveryChallenge(rc)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleRecoveryChallenge: ProcessRecoveryChallenge %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleRecoverAccount sets a new password and requests an email change for a
+// user that signed a recovery challenge with one of its public keys.
+func (p *politeiawww) handleRecoverAccount(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleRecoverAccount")
+
+	var ra v1.RecoverAccount
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&ra); err != nil {
+		RespondWithError(w, r, 0, "handleRecoverAccount: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	reply, user, err := p.backend.ProcessRecoverAccount(ra)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleRecoverAccount: ProcessRecoverAccount %v", err)
+		return
+	}
+
+	p.securityEvent(r, user.ID, v1.SecurityEventRecovery, ra.PublicKey)
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleResendVerification sends the verification email of a new user again.
+func (p *politeiawww) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleResendVerification")
+
+	var rv v1.ResendVerification
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&rv); err != nil {
+		RespondWithError(w, r, 0, "handleResendVerification: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	reply, err := p.backend.ProcessResendVerification(rv)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleResendVerification: "+
+			"ProcessResendVerification %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleNewProposal handles the incoming new proposal command.
+func (p *politeiawww) handleNewProposal(w http.ResponseWriter, r *http.Request) {
+	// Get the new proposal command.
+	log.Tracef("handleNewProposal")
+	var np v1.NewProposal
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&np); err != nil {
+		RespondWithError(w, r, 0, "handleNewProposal: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleNewProposal: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessNewProposal(r.Context(), np, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleNewProposal: ProcessNewProposal %v", err)
+		return
+	}
+
+	// Reply with the challenge response and censorship token.
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleEditProposal handles the edits of unvetted proposals by their
+// authors.
+func (p *politeiawww) handleEditProposal(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleEditProposal")
+
+	var ep v1.EditProposal
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&ep); err != nil {
+		RespondWithError(w, r, 0, "handleEditProposal: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleEditProposal: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessEditProposal(r.Context(), ep, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleEditProposal: ProcessEditProposal %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleSetProposalStatus handles the incoming set proposal status command.
+// It's used for either publishing or censoring a proposal.
+func (p *politeiawww) handleSetProposalStatus(w http.ResponseWriter, r *http.Request) {
+	// Get the proposal status command.
+	log.Tracef("handleSetProposalStatus")
+	var sps v1.SetProposalStatus
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&sps); err != nil {
 		RespondWithError(w, r, 0, "handleSetProposalStatus: unmarshal", v1.UserError{
 			ErrorCode: v1.ErrorStatusInvalidInput,
 		})
@@ -538,152 +1859,1194 @@ func (p *politeiawww) handleSetProposalStatus(w http.ResponseWriter, r *http.Req
 	user, err := p.getSessionUser(r)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleSetProposalStatus: getSessionUser %v", err)
+			"handleSetProposalStatus: getSessionUser %v", err)
+		return
+	}
+
+	// Set status
+	reply, err := p.backend.ProcessSetProposalStatus(r.Context(), sps,
+		user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSetProposalStatus: ProcessSetProposalStatus %v", err)
+		return
+	}
+
+	// Reply with the new proposal status.
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleSetProposalTags handles the incoming set proposal tags command.
+func (p *politeiawww) handleSetProposalTags(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSetProposalTags")
+
+	var spt v1.SetProposalTags
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&spt); err != nil {
+		RespondWithError(w, r, 0, "handleSetProposalTags: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSetProposalTags: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessSetProposalTags(r.Context(), spt, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSetProposalTags: ProcessSetProposalTags %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleImportProposal imports a proposal bundle that was downloaded from
+// another deployment.
+func (p *politeiawww) handleImportProposal(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleImportProposal")
+
+	var ip v1.ImportProposal
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&ip); err != nil {
+		RespondWithError(w, r, 0, "handleImportProposal: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleImportProposal: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessImportProposal(r.Context(), ip, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleImportProposal: ProcessImportProposal %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleStatusHistory returns the status changes of a proposal.
+func (p *politeiawww) handleStatusHistory(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleStatusHistory")
+
+	var sh v1.StatusHistory
+	sh.Token = mux.Vars(r)["token"]
+
+	reply, err := p.backend.ProcessStatusHistory(sh)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleStatusHistory: ProcessStatusHistory %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleProposalDetails handles the incoming proposal details command. It fetches
+// the complete details for an existing proposal.
+func (p *politeiawww) handleProposalDetails(w http.ResponseWriter, r *http.Request) {
+	// Add the path param to the struct.
+	log.Tracef("handleProposalDetails")
+	pathParams := mux.Vars(r)
+	var pd v1.ProposalsDetails
+	pd.Token = pathParams["token"]
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		if err != database.ErrUserNotFound {
+			RespondWithError(w, r, 0,
+				"handleProposalDetails: getSessionUser %v", err)
+			return
+		}
+	}
+	reply, err := p.backend.ProcessProposalDetails(r.Context(), pd, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleProposalDetails: ProcessProposalDetails %v", err)
+		return
+	}
+
+	// Views of public proposals are counted once per user, or per IP
+	// address for anonymous viewers, within the view window.
+	if reply.Proposal.Status == v1.PropStatusPublic {
+		viewer := "ip:" + p.clientIP(r).String()
+		if user != nil {
+			viewer = "user:" + strconv.FormatUint(user.ID, 10)
+		}
+		p.backend.recordProposalView(pd.Token, viewer)
+	}
+
+	// Reply with the proposal details.
+	respondWithETag(w, r, proposalsETag(
+		[]v1.ProposalRecord{reply.Proposal}, 1), true, reply)
+}
+
+// handleProposalFile downloads a file of a proposal.
+func (p *politeiawww) handleProposalFile(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleProposalFile")
+
+	pathParams := mux.Vars(r)
+	pf := v1.ProposalFile{
+		Token: pathParams["token"],
+		Name:  pathParams["name"],
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		if err != database.ErrUserNotFound {
+			RespondWithError(w, r, 0,
+				"handleProposalFile: getSessionUser %v", err)
+			return
+		}
+	}
+	mimeType, payload, err := p.backend.ProcessProposalFile(r.Context(), pf,
+		user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleProposalFile: ProcessProposalFile %v", err)
+		return
+	}
+
+	// The files are provided by the authors, so browsers must not render
+	// them as anything but their MIME type.
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment",
+		map[string]string{"filename": pf.Name}))
+	w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	err = util.RespondWithCopy(w, http.StatusOK, mimeType, payload)
+	if err != nil {
+		log.Errorf("handleProposalFile: RespondWithCopy %v", err)
+	}
+}
+
+// handleProposalBundle downloads an archive of a proposal.
+func (p *politeiawww) handleProposalBundle(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleProposalBundle")
+
+	var pb v1.ProposalBundle
+	err := util.ParseGetParams(r, &pb)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleProposalBundle: ParseGetParams",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+	pb.Token = mux.Vars(r)["token"]
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		if err != database.ErrUserNotFound {
+			RespondWithError(w, r, 0,
+				"handleProposalBundle: getSessionUser %v", err)
+			return
+		}
+	}
+	mimeType, name, bundle, err := p.backend.ProcessProposalBundle(
+		r.Context(), pb, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleProposalBundle: ProcessProposalBundle %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment",
+		map[string]string{"filename": name}))
+	w.Header().Set("Content-Length", strconv.Itoa(len(bundle)))
+	err = util.RespondWithCopy(w, http.StatusOK, mimeType, bundle)
+	if err != nil {
+		log.Errorf("handleProposalBundle: RespondWithCopy %v", err)
+	}
+}
+
+func (p *politeiawww) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	// Get the policy command.
+	log.Tracef("handlePolicy")
+	var policy v1.Policy
+	reply := p.backend.ProcessPolicy(policy)
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleAllVetted replies with the list of vetted proposals.
+func (p *politeiawww) handleAllVetted(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleAllVetted")
+
+	// Get the all vetted command.
+	var v v1.GetAllVetted
+	err := util.ParseGetParams(r, &v)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleAllVetted: ParseGetParams",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	vr, err := p.backend.ProcessAllVetted(r.Context(), v)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleAllVetted: ProcessAllVetted %v", err)
+		return
+	}
+	respondWithETag(w, r, proposalsETag(vr.Proposals, vr.Total), false,
+		vr)
+}
+
+// handleAllUnvetted replies with the list of unvetted proposals.
+func (p *politeiawww) handleAllUnvetted(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleAllUnvetted")
+
+	// Get the all unvetted command.
+	var u v1.GetAllUnvetted
+	err := util.ParseGetParams(r, &u)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleAllUnvetted: ParseGetParams",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleAllUnvetted: getSessionUser %v", err)
+		return
+	}
+
+	ur, err := p.backend.ProcessAllUnvetted(u, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleAllUnvetted: ProcessAllUnvetted %v", err)
+		return
+	}
+	respondWithETag(w, r, proposalsETag(ur.Proposals, ur.Total), true,
+		ur)
+}
+
+// handleNewComment handles incomming comments.
+func (p *politeiawww) handleNewComment(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleNewComment")
+
+	var sc v1.NewComment
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&sc); err != nil {
+		RespondWithError(w, r, 0, "handleNewComment: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleNewComment: getSessionUser %v", err)
+		return
+	}
+
+	cr, err := p.backend.ProcessComment(sc, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleNewComment: ProcessComment %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, cr)
+}
+
+// handleLikeComment handles up and down votes on comments.
+func (p *politeiawww) handleLikeComment(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleLikeComment")
+
+	var lc v1.LikeComment
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&lc); err != nil {
+		RespondWithError(w, r, 0, "handleLikeComment: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleLikeComment: getSessionUser %v", err)
+		return
+	}
+
+	lcr, err := p.backend.ProcessLikeComment(lc, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleLikeComment: ProcessLikeComment %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, lcr)
+}
+
+// handleCensorComment handles the censorship of comments by admins.
+func (p *politeiawww) handleCensorComment(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleCensorComment")
+
+	var cc v1.CensorComment
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&cc); err != nil {
+		RespondWithError(w, r, 0, "handleCensorComment: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleCensorComment: getSessionUser %v", err)
+		return
+	}
+
+	ccr, err := p.backend.ProcessCensorComment(cc, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleCensorComment: ProcessCensorComment %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, ccr)
+}
+
+// handleCommentsGet handles batched comments get.
+func (p *politeiawww) handleCommentsGet(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleCommentsGet")
+
+	pathParams := mux.Vars(r)
+	gcr, err := p.backend.ProcessCommentGet(pathParams["token"])
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleCommentsGet: ProcessCommentGet %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, gcr)
+}
+
+// handleVerifyUserPaymentTx checks whether the provided transaction
+// is on the blockchain and meets the requirements to consider the user
+// registration fee as paid.
+func (p *politeiawww) handleVerifyUserPaymentTx(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleVerifyUserPaymentTx")
+
+	// Get the verify user payment tx command.
+	var vupt v1.VerifyUserPaymentTx
+	err := util.ParseGetParams(r, &vupt)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleVerifyUserPaymentTx: ParseGetParams",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleVerifyUserPaymentTx: getSessionUser %v", err)
+		return
+	}
+
+	vuptr, err := p.backend.ProcessVerifyUserPaymentTx(user, vupt)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleVerifyUserPaymentTx: ProcessVerifyUserPaymentTx %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, vuptr)
+}
+
+// handleProposalCredits returns the proposal credits of the logged in user.
+func (p *politeiawww) handleProposalCredits(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleProposalCredits")
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleProposalCredits: getSessionUser %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK,
+		p.backend.ProcessProposalCredits(user))
+}
+
+// handlePurchaseCredits starts a purchase of proposal credits by the logged in
+// user.
+func (p *politeiawww) handlePurchaseCredits(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handlePurchaseCredits")
+
+	var pc v1.PurchaseCredits
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&pc); err != nil {
+		RespondWithError(w, r, 0, "handlePurchaseCredits: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handlePurchaseCredits: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessPurchaseCredits(user, pc)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handlePurchaseCredits: ProcessPurchaseCredits %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleVerifyCreditsTx verifies the payment of the pending proposal credit
+// purchase of the logged in user.
+func (p *politeiawww) handleVerifyCreditsTx(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleVerifyCreditsTx")
+
+	var vc v1.VerifyCreditsTx
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&vc); err != nil {
+		RespondWithError(w, r, 0, "handleVerifyCreditsTx: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleVerifyCreditsTx: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessVerifyCreditsTx(user, vc)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleVerifyCreditsTx: ProcessVerifyCreditsTx %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleUserStats replies with the statistics of a user.
+func (p *politeiawww) handleUserStats(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUserStats")
+
+	var us v1.UserStats
+	err := util.ParseGetParams(r, &us)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleUserStats: ParseGetParams",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	reply, err := p.backend.ProcessUserStats(us)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUserStats: ProcessUserStats %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleProposalsStats replies with the statistics of all proposals.
+func (p *politeiawww) handleProposalsStats(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleProposalsStats")
+
+	reply := p.backend.ProcessProposalsStats(v1.ProposalsStats{})
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// respondWithFeed replies with the feed of the proposals that were
+// published last in the provided format.
+func (p *politeiawww) respondWithFeed(w http.ResponseWriter, r *http.Request, format feedFormat, contentType string) {
+	feed, err := p.backend.ProcessProposalsFeed(r.Context(), format)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"respondWithFeed: ProcessProposalsFeed %v", err)
+		return
+	}
+
+	err = util.RespondWithCopy(w, http.StatusOK, contentType, feed)
+	if err != nil {
+		log.Errorf("respondWithFeed: RespondWithCopy %v", err)
+	}
+}
+
+// handleProposalsRSS replies with the RSS feed of the proposals that were
+// published last.
+func (p *politeiawww) handleProposalsRSS(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleProposalsRSS")
+
+	p.respondWithFeed(w, r, feedFormatRSS,
+		"application/rss+xml; charset=utf-8")
+}
+
+// handleProposalsAtom replies with the Atom feed of the proposals that were
+// published last.
+func (p *politeiawww) handleProposalsAtom(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleProposalsAtom")
+
+	p.respondWithFeed(w, r, feedFormatAtom,
+		"application/atom+xml; charset=utf-8")
+}
+
+// handleUserDetails replies with the public details of a user.
+func (p *politeiawww) handleUserDetails(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUserDetails")
+
+	var ud v1.UserDetails
+	ud.UUID = mux.Vars(r)["uuid"]
+
+	reply, err := p.backend.ProcessUserDetails(ud)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUserDetails: ProcessUserDetails %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleUserProposals returns the proposals for the given user.
+func (p *politeiawww) handleUserProposals(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUserProposals")
+
+	// Get the user proposals command.
+	var up v1.UserProposals
+	err := util.ParseGetParams(r, &up)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleUserProposals: ParseGetParams",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	userId, err := strconv.ParseUint(up.UserId, 10, 64)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleUserProposals: ParseUint",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUserProposals: getSessionUser %v", err)
+		return
+	}
+
+	upr, err := p.backend.ProcessUserProposals(
+		&up,
+		user != nil && user.ID == userId,
+		user != nil && user.Admin)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUserProposals: ProcessUserProposals %v", err)
+		return
+	}
+
+	respondWithETag(w, r, proposalsETag(upr.Proposals, upr.Total), true,
+		upr)
+}
+
+// handleActiveVote returns all active proposals that have an active vote.
+func (p *politeiawww) handleActiveVote(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleActiveVote")
+
+	avr, err := p.backend.ProcessActiveVote(r.Context())
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleActiveVote: ProcessActivateVote %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, avr)
+}
+
+// handleActiveVoteSummary returns the summaries of the active votes.
+func (p *politeiawww) handleActiveVoteSummary(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleActiveVoteSummary")
+
+	avsr, err := p.backend.ProcessActiveVoteSummary(r.Context())
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleActiveVoteSummary: ProcessActiveVoteSummary %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, avsr)
+}
+
+// handleCastVotes records the user votes in politeiad.
+func (p *politeiawww) handleCastVotes(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleCastVotes")
+
+	var cv v1.Ballot
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&cv); err != nil {
+		RespondWithError(w, r, 0, "handleCastVotes: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	avr, err := p.backend.ProcessCastVotes(r.Context(), &cv)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleCastVotes: ProcessCastVotes %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, avr)
+}
+
+// handleProposalVotes returns a proposal + all voting action.
+func (p *politeiawww) handleProposalVotes(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleProposalVotes")
+
+	var gpv v1.ProposalVotes
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&gpv); err != nil {
+		RespondWithError(w, r, 0, "handleProposalVotes: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	gpvr, err := p.backend.ProcessProposalVotes(r.Context(), &gpv)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleProposalVotes: ProcessProposalVotes %v",
+			err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, gpvr)
+}
+
+// handleVoteStatus replies with the summary of the vote on a proposal.
+func (p *politeiawww) handleVoteStatus(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleVoteStatus")
+
+	var vs v1.VoteStatus
+	vs.Token = mux.Vars(r)["token"]
+
+	reply, err := p.backend.ProcessVoteStatus(r.Context(), vs)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleVoteStatus: ProcessVoteStatus %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleVoteReceipt replies with the vote that a ticket cast on a proposal
+// and its receipt.
+func (p *politeiawww) handleVoteReceipt(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleVoteReceipt")
+
+	var vr v1.VoteReceipt
+	vr.Token = mux.Vars(r)["token"]
+	vr.Ticket = mux.Vars(r)["ticket"]
+
+	reply, err := p.backend.ProcessVoteReceipt(r.Context(), vr)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleVoteReceipt: ProcessVoteReceipt %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleVoteStream upgrades the connection to a websocket that streams the
+// votes on the proposals that the client subscribes to.
+func (p *politeiawww) handleVoteStream(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleVoteStream")
+
+	err := p.backend.checkFeature(v1.FeatureWebSockets)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleVoteStream: checkFeature %v",
+			err)
+		return
+	}
+
+	// The number of open streams is capped, in total and per IP address.
+	s, err := p.backend.addVoteStream(p.clientIP(r))
+	if err != nil {
+		log.Debugf("handleVoteStream: %v: %v", remoteAddr(r), err)
+		util.RespondWithJSON(w, http.StatusTooManyRequests,
+			v1.ErrorReply{
+				ErrorCode: int64(v1.ErrorStatusRateLimited),
+			})
+		return
+	}
+
+	// The stream only carries public data so connections are accepted
+	// from any origin.
+	conn, err := websocket.Upgrade(w, r, nil, 0, 0)
+	if err != nil {
+		p.backend.removeVoteStream(s)
+		if _, ok := err.(websocket.HandshakeError); ok {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+		}
+		log.Debugf("handleVoteStream: upgrade %v", err)
+		return
+	}
+
+	p.backend.serveVoteStream(conn, s)
+}
+
+// handleStartVote handles starting a vote.
+func (p *politeiawww) handleStartVote(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleStartVote")
+
+	var sv v1.StartVote
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&sv); err != nil {
+		RespondWithError(w, r, 0, "handleStartVote: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleStartVote: getSessionUser %v", err)
 		return
 	}
 
-	// Set status
-	reply, err := p.backend.ProcessSetProposalStatus(sps, user)
+	// Sanity
+	if !user.Admin {
+		RespondWithError(w, r, 0,
+			"handleStartVote: admin %v", user.Admin)
+		return
+	}
+
+	svr, err := p.backend.ProcessStartVote(r.Context(), sv, user)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleSetProposalStatus: ProcessSetProposalStatus %v", err)
+			"handleStartVote: ProcessStartVote %v", err)
 		return
 	}
 
-	// Reply with the new proposal status.
+	util.RespondWithJSON(w, http.StatusOK, svr)
+}
+
+// handleWebhookDeliveries returns the recent webhook delivery attempts.
+func (p *politeiawww) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleWebhookDeliveries")
+
+	reply := p.backend.ProcessWebhookDeliveries()
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-// handleProposalDetails handles the incoming proposal details command. It fetches
-// the complete details for an existing proposal.
-func (p *politeiawww) handleProposalDetails(w http.ResponseWriter, r *http.Request) {
-	// Add the path param to the struct.
-	log.Tracef("handleProposalDetails")
-	pathParams := mux.Vars(r)
-	var pd v1.ProposalsDetails
-	pd.Token = pathParams["token"]
+// handleIPBans returns the IP bans that are in effect.
+func (p *politeiawww) handleIPBans(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleIPBans")
+
+	reply, err := p.backend.ProcessIPBans()
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleIPBans: ProcessIPBans %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleBanIP bans an IP address or network.
+func (p *politeiawww) handleBanIP(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleBanIP")
+
+	var bi v1.BanIP
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&bi); err != nil {
+		RespondWithError(w, r, 0, "handleBanIP: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleBanIP: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessBanIP(bi, user, p.clientIP(r))
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleBanIP: ProcessBanIP %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleUnbanIP lifts the ban of an IP address or network.
+func (p *politeiawww) handleUnbanIP(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUnbanIP")
+
+	var ub v1.UnbanIP
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&ub); err != nil {
+		RespondWithError(w, r, 0, "handleUnbanIP: unmarshal", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+		})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUnbanIP: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessUnbanIP(ub, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUnbanIP: ProcessUnbanIP %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleUsers returns a page of users.
+func (p *politeiawww) handleUsers(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUsers")
+
+	var u v1.Users
+	err := util.ParseGetParams(r, &u)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleUsers: ParseGetParams",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	reply, err := p.backend.ProcessUsers(u)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUsers: ProcessUsers %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleUserSearch returns the users that match a search.
+func (p *politeiawww) handleUserSearch(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUserSearch")
+
+	var us v1.UserSearch
+	err := util.ParseGetParams(r, &us)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleUserSearch: ParseGetParams",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	reply, err := p.backend.ProcessUserSearch(us)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUserSearch: ProcessUserSearch %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleAdminDeactivateUser deactivates the account of a user.
+func (p *politeiawww) handleAdminDeactivateUser(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleAdminDeactivateUser")
+
+	var adu v1.AdminDeactivateUser
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&adu); err != nil {
+		RespondWithError(w, r, 0, "handleAdminDeactivateUser: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleAdminDeactivateUser: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessAdminDeactivateUser(adu, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleAdminDeactivateUser: ProcessAdminDeactivateUser %v",
+			err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleGrantCredits adds proposal credits to a user.
+func (p *politeiawww) handleGrantCredits(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleGrantCredits")
+
+	var gc v1.GrantCredits
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&gc); err != nil {
+		RespondWithError(w, r, 0, "handleGrantCredits: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleGrantCredits: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessGrantCredits(gc, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleGrantCredits: ProcessGrantCredits %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleSetUserAdmin grants or revokes the admin privileges of a user.
+func (p *politeiawww) handleSetUserAdmin(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSetUserAdmin")
+
+	var sua v1.SetUserAdmin
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&sua); err != nil {
+		RespondWithError(w, r, 0, "handleSetUserAdmin: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSetUserAdmin: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.backend.ProcessSetUserAdmin(sua, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSetUserAdmin: ProcessSetUserAdmin %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleAdminVerifyUser marks the email address of a user as verified.
+func (p *politeiawww) handleAdminVerifyUser(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleAdminVerifyUser")
+
+	var avu v1.AdminVerifyUser
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&avu); err != nil {
+		RespondWithError(w, r, 0, "handleAdminVerifyUser: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
 
 	user, err := p.getSessionUser(r)
 	if err != nil {
-		if err != database.ErrUserNotFound {
-			RespondWithError(w, r, 0,
-				"handleProposalDetails: getSessionUser %v", err)
-			return
-		}
+		RespondWithError(w, r, 0,
+			"handleAdminVerifyUser: getSessionUser %v", err)
+		return
 	}
-	reply, err := p.backend.ProcessProposalDetails(pd, user)
+
+	reply, err := p.backend.ProcessAdminVerifyUser(avu, user)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleProposalDetails: ProcessProposalDetails %v", err)
+			"handleAdminVerifyUser: ProcessAdminVerifyUser %v", err)
 		return
 	}
 
-	// Reply with the proposal details.
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-func (p *politeiawww) handlePolicy(w http.ResponseWriter, r *http.Request) {
-	// Get the policy command.
-	log.Tracef("handlePolicy")
-	var policy v1.Policy
-	reply := p.backend.ProcessPolicy(policy)
-	util.RespondWithJSON(w, http.StatusOK, reply)
-}
+// handleImpersonate starts the impersonation of a user by the admin of the
+// session.
+func (p *politeiawww) handleImpersonate(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleImpersonate")
 
-// handleAllVetted replies with the list of vetted proposals.
-func (p *politeiawww) handleAllVetted(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleAllVetted")
+	var imp v1.Impersonate
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&imp); err != nil {
+		RespondWithError(w, r, 0, "handleImpersonate: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
+		return
+	}
 
-	// Get the all vetted command.
-	var v v1.GetAllVetted
-	err := util.ParseGetParams(r, &v)
-	if err != nil {
-		RespondWithError(w, r, 0, "handleAllVetted: ParseGetParams",
+	// Bearer tokens can't impersonate users.
+	if _, ok := bearerToken(r); ok {
+		RespondWithError(w, r, 0, "handleImpersonate: bearer token",
 			v1.UserError{
 				ErrorCode: v1.ErrorStatusInvalidInput,
 			})
 		return
 	}
 
-	vr := p.backend.ProcessAllVetted(v)
-	util.RespondWithJSON(w, http.StatusOK, vr)
-}
+	user, err := p.getSessionUser(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleImpersonate: getSessionUser %v", err)
+		return
+	}
 
-// handleAllUnvetted replies with the list of unvetted proposals.
-func (p *politeiawww) handleAllUnvetted(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleAllUnvetted")
+	reply, err := p.backend.ProcessImpersonate(imp, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleImpersonate: ProcessImpersonate %v", err)
+		return
+	}
 
-	// Get the all unvetted command.
-	var u v1.GetAllUnvetted
-	err := util.ParseGetParams(r, &u)
+	err = p.setSessionImpersonation(w, r, reply.UserID, reply.Expiry)
 	if err != nil {
-		RespondWithError(w, r, 0, "handleAllUnvetted: ParseGetParams",
-			v1.UserError{
-				ErrorCode: v1.ErrorStatusInvalidInput,
-			})
+		RespondWithError(w, r, 0,
+			"handleImpersonate: setSessionImpersonation %v", err)
 		return
 	}
 
-	ur := p.backend.ProcessAllUnvetted(u)
-	util.RespondWithJSON(w, http.StatusOK, ur)
+	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-// handleNewComment handles incomming comments.
-func (p *politeiawww) handleNewComment(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleNewComment")
+// handleStopImpersonation ends the impersonation of the session.
+func (p *politeiawww) handleStopImpersonation(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleStopImpersonation")
 
-	var sc v1.NewComment
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&sc); err != nil {
-		RespondWithError(w, r, 0, "handleNewComment: unmarshal", v1.UserError{
+	admin, user, err := p.getImpersonation(r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleStopImpersonation: getImpersonation %v", err)
+		return
+	}
+	if user == nil {
+		RespondWithError(w, r, 0, "handleStopImpersonation: not "+
+			"impersonating", v1.UserError{
 			ErrorCode: v1.ErrorStatusInvalidInput,
 		})
 		return
 	}
 
-	user, err := p.getSessionUser(r)
+	reply, err := p.backend.ProcessStopImpersonation(admin, user)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleNewComment: getSessionUser %v", err)
+			"handleStopImpersonation: ProcessStopImpersonation %v", err)
 		return
 	}
 
-	cr, err := p.backend.ProcessComment(sc, user)
+	err = p.setSessionImpersonation(w, r, "", 0)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleNewComment: ProcessComment %v", err)
+			"handleStopImpersonation: setSessionImpersonation %v", err)
 		return
 	}
 
-	util.RespondWithJSON(w, http.StatusOK, cr)
+	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-// handleCommentsGet handles batched comments get.
-func (p *politeiawww) handleCommentsGet(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleCommentsGet")
+// handleAdminActions returns the admin audit log.
+func (p *politeiawww) handleAdminActions(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleAdminActions")
 
-	pathParams := mux.Vars(r)
-	gcr, err := p.backend.ProcessCommentGet(pathParams["token"])
+	reply, err := p.backend.ProcessAdminActions()
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleCommentsGet: ProcessCommentGet %v", err)
+			"handleAdminActions: ProcessAdminActions %v", err)
 		return
 	}
 
-	util.RespondWithJSON(w, http.StatusOK, gcr)
+	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-// handleVerifyUserPaymentTx checks whether the provided transaction
-// is on the blockchain and meets the requirements to consider the user
-// registration fee as paid.
-func (p *politeiawww) handleVerifyUserPaymentTx(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleVerifyUserPaymentTx")
+// handlePendingActions returns the admin actions that await approval.
+func (p *politeiawww) handlePendingActions(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handlePendingActions")
 
-	// Get the verify user payment tx command.
-	var vupt v1.VerifyUserPaymentTx
-	err := util.ParseGetParams(r, &vupt)
+	reply, err := p.backend.ProcessPendingActions()
 	if err != nil {
-		RespondWithError(w, r, 0, "handleVerifyUserPaymentTx: ParseGetParams",
+		RespondWithError(w, r, 0,
+			"handlePendingActions: ProcessPendingActions %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleApprovePendingAction carries out an action that another admin
+// requested.
+func (p *politeiawww) handleApprovePendingAction(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleApprovePendingAction")
+
+	var apa v1.ApprovePendingAction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&apa); err != nil {
+		RespondWithError(w, r, 0, "handleApprovePendingAction: unmarshal",
 			v1.UserError{
 				ErrorCode: v1.ErrorStatusInvalidInput,
 			})
@@ -693,38 +3056,30 @@ func (p *politeiawww) handleVerifyUserPaymentTx(w http.ResponseWriter, r *http.R
 	user, err := p.getSessionUser(r)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleVerifyUserPaymentTx: getSessionUser %v", err)
+			"handleApprovePendingAction: getSessionUser %v", err)
 		return
 	}
 
-	vuptr, err := p.backend.ProcessVerifyUserPaymentTx(user, vupt)
+	reply, err := p.backend.ProcessApprovePendingAction(r.Context(), apa,
+		user)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleVerifyUserPaymentTx: ProcessVerifyUserPaymentTx %v", err)
+			"handleApprovePendingAction: ProcessApprovePendingAction %v",
+			err)
 		return
 	}
 
-	util.RespondWithJSON(w, http.StatusOK, vuptr)
+	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-// handleUserProposals returns the proposals for the given user.
-func (p *politeiawww) handleUserProposals(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleUserProposals")
-
-	// Get the user proposals command.
-	var up v1.UserProposals
-	err := util.ParseGetParams(r, &up)
-	if err != nil {
-		RespondWithError(w, r, 0, "handleUserProposals: ParseGetParams",
-			v1.UserError{
-				ErrorCode: v1.ErrorStatusInvalidInput,
-			})
-		return
-	}
+// handleRejectPendingAction removes an action that awaits approval.
+func (p *politeiawww) handleRejectPendingAction(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleRejectPendingAction")
 
-	userId, err := strconv.ParseUint(up.UserId, 10, 64)
-	if err != nil {
-		RespondWithError(w, r, 0, "handleUserProposals: ParseUint",
+	var rpa v1.RejectPendingAction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&rpa); err != nil {
+		RespondWithError(w, r, 0, "handleRejectPendingAction: unmarshal",
 			v1.UserError{
 				ErrorCode: v1.ErrorStatusInvalidInput,
 			})
@@ -734,120 +3089,121 @@ func (p *politeiawww) handleUserProposals(w http.ResponseWriter, r *http.Request
 	user, err := p.getSessionUser(r)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleUserProposals: getSessionUser %v", err)
+			"handleRejectPendingAction: getSessionUser %v", err)
 		return
 	}
 
-	upr, err := p.backend.ProcessUserProposals(
-		&up,
-		user != nil && user.ID == userId,
-		user != nil && user.Admin)
+	reply, err := p.backend.ProcessRejectPendingAction(rpa, user)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleUserProposals: ProcessUserProposals %v", err)
+			"handleRejectPendingAction: ProcessRejectPendingAction %v",
+			err)
 		return
 	}
 
-	util.RespondWithJSON(w, http.StatusOK, upr)
+	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-// handleActiveVote returns all active proposals that have an active vote.
-func (p *politeiawww) handleActiveVote(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleActiveVote")
+// handleHeldProposals returns the proposals that the spam filters held.
+func (p *politeiawww) handleHeldProposals(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleHeldProposals")
 
-	avr, err := p.backend.ProcessActiveVote()
+	reply, err := p.backend.ProcessHeldProposals()
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleActiveVote: ProcessActivateVote %v", err)
+			"handleHeldProposals: ProcessHeldProposals %v", err)
 		return
 	}
 
-	util.RespondWithJSON(w, http.StatusOK, avr)
+	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-// handleCastVotes records the user votes in politeiad.
-func (p *politeiawww) handleCastVotes(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleCastVotes")
+// handleReleaseHeldProposal submits a proposal that the spam filters held.
+func (p *politeiawww) handleReleaseHeldProposal(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleReleaseHeldProposal")
 
-	var cv v1.Ballot
+	var rhp v1.ReleaseHeldProposal
 	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&cv); err != nil {
-		RespondWithError(w, r, 0, "handleCastVotes: unmarshal", v1.UserError{
-			ErrorCode: v1.ErrorStatusInvalidInput,
-		})
+	if err := decoder.Decode(&rhp); err != nil {
+		RespondWithError(w, r, 0, "handleReleaseHeldProposal: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
 		return
 	}
 
-	avr, err := p.backend.ProcessCastVotes(&cv)
+	reply, err := p.backend.ProcessReleaseHeldProposal(r.Context(), rhp)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleCastVotes: ProcessCastVotes %v", err)
+			"handleReleaseHeldProposal: ProcessReleaseHeldProposal %v",
+			err)
 		return
 	}
 
-	util.RespondWithJSON(w, http.StatusOK, avr)
+	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-// handleProposalVotes returns a proposal + all voting action.
-func (p *politeiawww) handleProposalVotes(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleProposalVotes")
+// handleRejectHeldProposal discards a proposal that the spam filters held.
+func (p *politeiawww) handleRejectHeldProposal(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleRejectHeldProposal")
 
-	var gpv v1.ProposalVotes
+	var rhp v1.RejectHeldProposal
 	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&gpv); err != nil {
-		RespondWithError(w, r, 0, "handleProposalVotes: unmarshal",
+	if err := decoder.Decode(&rhp); err != nil {
+		RespondWithError(w, r, 0, "handleRejectHeldProposal: unmarshal",
 			v1.UserError{
 				ErrorCode: v1.ErrorStatusInvalidInput,
 			})
 		return
 	}
 
-	gpvr, err := p.backend.ProcessProposalVotes(&gpv)
+	reply, err := p.backend.ProcessRejectHeldProposal(rhp)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleProposalVotes: ProcessProposalVotes %v",
+			"handleRejectHeldProposal: ProcessRejectHeldProposal %v",
 			err)
 		return
 	}
 
-	util.RespondWithJSON(w, http.StatusOK, gpvr)
+	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-// handleStartVote handles starting a vote.
-func (p *politeiawww) handleStartVote(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handleStartVote")
+// handleFeatures returns the feature flags.
+func (p *politeiawww) handleFeatures(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleFeatures")
 
-	var sv v1.StartVote
+	util.RespondWithJSON(w, http.StatusOK, p.backend.ProcessFeatures())
+}
+
+// handleSetFeature enables or disables a feature.
+func (p *politeiawww) handleSetFeature(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSetFeature")
+
+	var sf v1.SetFeature
 	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&sv); err != nil {
-		RespondWithError(w, r, 0, "handleStartVote: unmarshal", v1.UserError{
-			ErrorCode: v1.ErrorStatusInvalidInput,
-		})
+	if err := decoder.Decode(&sf); err != nil {
+		RespondWithError(w, r, 0, "handleSetFeature: unmarshal",
+			v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidInput,
+			})
 		return
 	}
 
 	user, err := p.getSessionUser(r)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleStartVote: getSessionUser %v", err)
-		return
-	}
-
-	// Sanity
-	if !user.Admin {
-		RespondWithError(w, r, 0,
-			"handleStartVote: admin %v", user.Admin)
+			"handleSetFeature: getSessionUser %v", err)
 		return
 	}
 
-	svr, err := p.backend.ProcessStartVote(sv, user)
+	reply, err := p.backend.ProcessSetFeature(sf, user)
 	if err != nil {
 		RespondWithError(w, r, 0,
-			"handleStartVote: ProcessStartVote %v", err)
+			"handleSetFeature: ProcessSetFeature %v", err)
 		return
 	}
 
-	util.RespondWithJSON(w, http.StatusOK, svr)
+	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
 // handleNotFound is a generic handler for an invalid route.
@@ -884,9 +3240,15 @@ func (p *politeiawww) addRoute(method string, route string, handler http.Handler
 	default:
 		handler = logging(handler)
 	}
+	handler = p.impersonation(handler)
+	handler = p.forwardToPrimary(method, route, perm, handler)
+	if perm == permissionAdmin {
+		handler = p.restrictAdmin(handler)
+	}
 
-	// All handlers need to close the body
-	handler = closeBody(handler)
+	// All handlers need to close the body and recover from panics
+	handler = withRequestID(p.recoverPanic(p.checkIPBan(closeBody(
+		limitBody(maxRequestBodySize(p.cfg, route), handler)))))
 
 	p.router.StrictSlash(true).HandleFunc(fullRoute, handler).Methods(method)
 }
@@ -904,6 +3266,10 @@ func _main() error {
 		}
 	}()
 
+	if loadedCfg.Validate {
+		return validate(loadedCfg)
+	}
+
 	log.Infof("Version : %v", version())
 	log.Infof("Network : %v", activeNetParams.Params.Name)
 	log.Infof("Home dir: %v", loadedCfg.HomeDir)
@@ -918,8 +3284,9 @@ func _main() error {
 	}
 
 	// Generate the TLS cert and key file if both don't already
-	// exist.
-	if !fileExists(loadedCfg.HTTPSKey) &&
+	// exist and the certificates are not obtained from Let's Encrypt.
+	if len(loadedCfg.AutoCertHosts) == 0 &&
+		!fileExists(loadedCfg.HTTPSKey) &&
 		!fileExists(loadedCfg.HTTPSCert) {
 		log.Infof("Generating HTTPS keypair...")
 
@@ -943,15 +3310,30 @@ func _main() error {
 		return p.getIdentity()
 	}
 
+	if p.cfg.ErrorReportDSN != "" {
+		p.reporter, err = newSentryReporter(p.cfg.ErrorReportDSN)
+		if err != nil {
+			return err
+		}
+	}
+
 	p.backend, err = NewBackend(p.cfg)
 	if err != nil {
 		return err
 	}
+	if p.backend.isReplica() {
+		p.primary, err = newPrimaryProxy(p.cfg,
+			p.backend.clusterClient)
+		if err != nil {
+			return err
+		}
+		log.Infof("Replica of %v", p.cfg.ClusterPrimary)
+	}
 	p.backend.params = activeNetParams.Params
 
 	// Try to load inventory but do not fail.
 	log.Infof("Attempting to load proposal inventory")
-	err = p.backend.LoadInventory()
+	err = p.backend.LoadInventory(context.Background())
 	if err != nil {
 		log.Errorf("LoadInventory: %v", err)
 	}
@@ -1014,60 +3396,238 @@ func _main() error {
 	p.router.NotFoundHandler = closeBody(p.handleNotFound)
 	p.addRoute(http.MethodGet, v1.RouteVersion, p.handleVersion,
 		permissionPublic, false)
-	p.addRoute(http.MethodPost, v1.RouteNewUser, p.handleNewUser,
-		permissionPublic, false)
+	p.addRoute(http.MethodPost, v1.RouteNewUser,
+		p.rateLimit(rateLimitNewUser, p.handleNewUser), permissionPublic,
+		false)
+	p.addRoute(http.MethodGet, v1.RouteSignupChallenge,
+		p.handleSignupChallenge, permissionPublic, false)
+	p.addRoute(http.MethodPost, v1.RouteResendVerification,
+		p.rateLimit(rateLimitResendVerification,
+			p.handleResendVerification), permissionPublic, false)
 	p.addRoute(http.MethodGet, v1.RouteVerifyNewUser,
 		p.handleVerifyNewUser, permissionPublic, false)
-	p.addRoute(http.MethodPost, v1.RouteLogin, p.handleLogin,
-		permissionPublic, false)
+	p.addRoute(http.MethodPost, v1.RouteLogin,
+		p.rateLimit(rateLimitLogin, p.handleLogin), permissionPublic, false)
 	p.addRoute(http.MethodGet, v1.RouteLogout, p.handleLogout,
 		permissionPublic, false)
 	p.addRoute(http.MethodPost, v1.RouteLogout, p.handleLogout,
 		permissionPublic, false)
+	p.addRoute(http.MethodPost, v1.RouteRefreshToken, p.handleRefreshToken,
+		permissionPublic, false)
+	p.addRoute(http.MethodPost, v1.RouteRevokeToken, p.handleRevokeToken,
+		permissionPublic, false)
+	p.addRoute(http.MethodGet, v1.RouteOIDCLogin, p.handleOIDCLogin,
+		permissionPublic, false)
+	p.addRoute(http.MethodPost, v1.RouteOIDCCallback,
+		p.rateLimit(rateLimitLogin, p.handleOIDCCallback),
+		permissionPublic, false)
 	p.addRoute(http.MethodPost, v1.RouteResetPassword,
-		p.handleResetPassword, permissionPublic, false)
+		p.rateLimit(rateLimitResetPassword, p.handleResetPassword),
+		permissionPublic, false)
+	p.addRoute(http.MethodPost, v1.RouteRecoveryChallenge,
+		p.rateLimit(rateLimitRecoverAccount, p.handleRecoveryChallenge),
+		permissionPublic, false)
+	p.addRoute(http.MethodPost, v1.RouteRecoverAccount,
+		p.rateLimit(rateLimitRecoverAccount, p.handleRecoverAccount),
+		permissionPublic, false)
+	p.addRoute(http.MethodGet, v1.RouteUnsubscribe, p.handleUnsubscribe,
+		permissionPublic, false)
+	p.addRoute(http.MethodPost, v1.RouteUnsubscribe, p.handleUnsubscribe,
+		permissionPublic, false)
 	p.addRoute(http.MethodGet, v1.RouteAllVetted, p.handleAllVetted,
 		permissionPublic, true)
 	p.addRoute(http.MethodGet, v1.RouteProposalDetails,
 		p.handleProposalDetails, permissionPublic, true)
+	p.addRoute(http.MethodGet, v1.RouteProposalFile,
+		p.handleProposalFile, permissionPublic, true)
+	p.addRoute(http.MethodGet, v1.RouteProposalBundle,
+		p.handleProposalBundle, permissionPublic, true)
+	p.addRoute(http.MethodGet, v1.RouteStatusHistory,
+		p.handleStatusHistory, permissionPublic, true)
+	p.addRoute(http.MethodGet, v1.RouteProposalsStats,
+		p.handleProposalsStats, permissionPublic, true)
+	p.addRoute(http.MethodGet, v1.RouteProposalsRSS,
+		p.handleProposalsRSS, permissionPublic, true)
+	p.addRoute(http.MethodGet, v1.RouteProposalsAtom,
+		p.handleProposalsAtom, permissionPublic, true)
 	p.addRoute(http.MethodGet, v1.RoutePolicy, p.handlePolicy,
 		permissionPublic, false)
 	p.addRoute(http.MethodGet, v1.RouteCommentsGet, p.handleCommentsGet,
 		permissionPublic, true)
 	p.addRoute(http.MethodGet, v1.RouteUserProposals, p.handleUserProposals,
 		permissionPublic, true)
+	p.addRoute(http.MethodGet, v1.RouteUserStats, p.handleUserStats,
+		permissionPublic, true)
+	p.addRoute(http.MethodGet, v1.RouteUserDetails, p.handleUserDetails,
+		permissionPublic, false)
 	p.addRoute(http.MethodGet, v1.RouteActiveVote, p.handleActiveVote,
 		permissionPublic, true)
+	p.addRoute(http.MethodGet, v1.RouteActiveVoteSummary,
+		p.handleActiveVoteSummary, permissionPublic, true)
 	p.addRoute(http.MethodPost, v1.RouteCastVotes, p.handleCastVotes,
 		permissionPublic, true)
 	p.addRoute(http.MethodPost, v1.RouteProposalVotes,
 		p.handleProposalVotes, permissionPublic, true)
+	p.addRoute(http.MethodGet, v1.RouteVoteStatus, p.handleVoteStatus,
+		permissionPublic, true)
+	p.addRoute(http.MethodGet, v1.RouteVoteReceipt, p.handleVoteReceipt,
+		permissionPublic, true)
+	p.addRoute(http.MethodGet, v1.RouteVoteStream, p.handleVoteStream,
+		permissionPublic, true)
+	p.addRoute(http.MethodGet, v1.RouteFeatures, p.handleFeatures,
+		permissionPublic, false)
+
+	// Cluster events that the primary publishes to the replicas,
+	// authenticated by the cluster token.
+	if p.primary != nil {
+		p.addRoute(http.MethodPost, v1.RouteClusterEvent,
+			p.handleClusterEvent, permissionPublic, false)
+	}
+
+	// Email provider webhooks, authenticated by a secret token.
+	if p.cfg.MailWebhookToken != "" {
+		p.addRoute(http.MethodPost, v1.RouteSESWebhook,
+			p.handleSESWebhook, permissionPublic, false)
+		p.addRoute(http.MethodPost, v1.RouteSendgridWebhook,
+			p.handleSendgridWebhook, permissionPublic, false)
+	}
 
 	// Routes that require being logged in.
 	p.addRoute(http.MethodPost, v1.RouteSecret, p.handleSecret,
 		permissionLogin, false)
 	p.addRoute(http.MethodPost, v1.RouteNewProposal, p.handleNewProposal,
 		permissionLogin, true)
+	p.addRoute(http.MethodPost, v1.RouteEditProposal, p.handleEditProposal,
+		permissionLogin, true)
+	p.addRoute(http.MethodGet, v1.RouteAllUnvetted, p.handleAllUnvetted,
+		permissionLogin, true)
 	p.addRoute(http.MethodGet, v1.RouteUserMe, p.handleMe, permissionLogin,
 		false)
 	p.addRoute(http.MethodPost, v1.RouteUpdateUserKey,
 		p.handleUpdateUserKey, permissionLogin, false)
 	p.addRoute(http.MethodPost, v1.RouteVerifyUpdateUserKey,
 		p.handleVerifyUpdateUserKey, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteUserKeyChallenge,
+		p.handleUserKeyChallenge, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteAddUserKey,
+		p.handleAddUserKey, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteDeactivateUserKey,
+		p.handleDeactivateUserKey, permissionLogin, false)
+	p.addRoute(http.MethodGet, v1.RouteUserIdentities,
+		p.handleUserIdentities, permissionLogin, false)
 	p.addRoute(http.MethodPost, v1.RouteChangePassword,
 		p.handleChangePassword, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteChangeEmail,
+		p.handleChangeEmail, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteVerifyChangeEmail,
+		p.handleVerifyChangeEmail, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteDeactivateUser,
+		p.handleDeactivateUser, permissionLogin, false)
+	p.addRoute(http.MethodGet, v1.RouteSecurityEvents,
+		p.handleSecurityEvents, permissionLogin, false)
+	p.addRoute(http.MethodGet, v1.RouteDrafts,
+		p.handleDrafts, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteNewDraft,
+		p.handleNewDraft, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteEditDraft,
+		p.handleEditDraft, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteDeleteDraft,
+		p.handleDeleteDraft, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteUserExport,
+		p.handleUserExport, permissionLogin, false)
+	p.addRoute(http.MethodGet, v1.RouteUserExportDownload,
+		p.handleUserExportDownload, permissionLogin, false)
+	p.addRoute(http.MethodGet, v1.RouteUserSessions,
+		p.handleUserSessions, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteRevokeSessions,
+		p.handleRevokeSessions, permissionLogin, false)
+	p.addRoute(http.MethodGet, v1.RouteAPITokens,
+		p.handleAPITokens, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteNewAPIToken,
+		p.handleNewAPIToken, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteRevokeAPIToken,
+		p.handleRevokeAPIToken, permissionLogin, false)
+	p.addRoute(http.MethodGet, v1.RouteOIDCLink,
+		p.handleOIDCLink, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteOIDCUnlink,
+		p.handleOIDCUnlink, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteEditUser,
+		p.handleEditUser, permissionLogin, false)
 	p.addRoute(http.MethodPost, v1.RouteNewComment,
 		p.handleNewComment, permissionLogin, true)
+	p.addRoute(http.MethodPost, v1.RouteLikeComment,
+		p.handleLikeComment, permissionLogin, true)
 	p.addRoute(http.MethodGet, v1.RouteVerifyUserPaymentTx,
 		p.handleVerifyUserPaymentTx, permissionLogin, false)
+	p.addRoute(http.MethodGet, v1.RouteProposalCredits,
+		p.handleProposalCredits, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RoutePurchaseCredits,
+		p.handlePurchaseCredits, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteVerifyCreditsTx,
+		p.handleVerifyCreditsTx, permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteSetTOTP, p.handleSetTOTP,
+		permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteVerifyTOTP, p.handleVerifyTOTP,
+		permissionLogin, false)
+	p.addRoute(http.MethodPost, v1.RouteDisableTOTP, p.handleDisableTOTP,
+		permissionLogin, false)
 
 	// Routes that require being logged in as an admin user.
-	p.addRoute(http.MethodGet, v1.RouteAllUnvetted, p.handleAllUnvetted,
-		permissionAdmin, true)
 	p.addRoute(http.MethodPost, v1.RouteSetProposalStatus,
 		p.handleSetProposalStatus, permissionAdmin, true)
+	p.addRoute(http.MethodPost, v1.RouteSetProposalTags,
+		p.handleSetProposalTags, permissionAdmin, true)
+	p.addRoute(http.MethodPost, v1.RouteImportProposal,
+		p.handleImportProposal, permissionAdmin, true)
 	p.addRoute(http.MethodPost, v1.RouteStartVote,
 		p.handleStartVote, permissionAdmin, true)
+	p.addRoute(http.MethodPost, v1.RouteCensorComment,
+		p.handleCensorComment, permissionAdmin, true)
+	p.addRoute(http.MethodGet, v1.RouteIPBans, p.handleIPBans,
+		permissionAdmin, false)
+	p.addRoute(http.MethodGet, v1.RouteWebhookDeliveries,
+		p.handleWebhookDeliveries, permissionAdmin, false)
+	p.addRoute(http.MethodPost, v1.RouteBanIP, p.handleBanIP,
+		permissionAdmin, false)
+	p.addRoute(http.MethodPost, v1.RouteUnbanIP, p.handleUnbanIP,
+		permissionAdmin, false)
+	p.addRoute(http.MethodPost, v1.RouteSetFeature, p.handleSetFeature,
+		permissionAdmin, false)
+	p.addRoute(http.MethodGet, v1.RouteUsers, p.handleUsers,
+		permissionAdmin, false)
+	p.addRoute(http.MethodGet, v1.RouteUserSearch, p.handleUserSearch,
+		permissionAdmin, false)
+	p.addRoute(http.MethodPost, v1.RouteAdminDeactivateUser,
+		p.handleAdminDeactivateUser, permissionAdmin, false)
+	p.addRoute(http.MethodPost, v1.RouteAdminVerifyUser,
+		p.handleAdminVerifyUser, permissionAdmin, false)
+	p.addRoute(http.MethodPost, v1.RouteGrantCredits,
+		p.handleGrantCredits, permissionAdmin, false)
+	p.addRoute(http.MethodPost, v1.RouteSetUserAdmin,
+		p.handleSetUserAdmin, permissionAdmin, false)
+	p.addRoute(http.MethodGet, v1.RouteAdminActions,
+		p.handleAdminActions, permissionAdmin, false)
+	p.addRoute(http.MethodPost, v1.RouteImpersonate,
+		p.handleImpersonate, permissionAdmin, false)
+	p.addRoute(http.MethodGet, v1.RoutePendingActions,
+		p.handlePendingActions, permissionAdmin, false)
+	p.addRoute(http.MethodPost, v1.RouteApprovePendingAction,
+		p.handleApprovePendingAction, permissionAdmin, true)
+	p.addRoute(http.MethodPost, v1.RouteRejectPendingAction,
+		p.handleRejectPendingAction, permissionAdmin, false)
+	p.addRoute(http.MethodGet, v1.RouteHeldProposals,
+		p.handleHeldProposals, permissionAdmin, false)
+	p.addRoute(http.MethodPost, v1.RouteReleaseHeldProposal,
+		p.handleReleaseHeldProposal, permissionAdmin, true)
+	p.addRoute(http.MethodPost, v1.RouteRejectHeldProposal,
+		p.handleRejectHeldProposal, permissionAdmin, false)
+
+	// The impersonated user is logged in while the impersonation lasts.
+	p.addRoute(http.MethodPost, v1.RouteStopImpersonation,
+		p.handleStopImpersonation, permissionLogin, false)
+
+	// Routes that only exist in fault injection builds.
+	p.addFaultRoutes()
 
 	// Persist session cookies.
 	var cookieKey []byte
@@ -1083,23 +3643,85 @@ func _main() error {
 		}
 		log.Infof("Cookie key generated.")
 	}
-	sessionsDir := filepath.Join(p.cfg.DataDir, "sessions")
-	err = os.MkdirAll(sessionsDir, 0700)
-	if err != nil {
-		return err
-	}
-	p.store = sessions.NewFilesystemStore(sessionsDir, cookieKey)
-	p.store.Options = &sessions.Options{
+	sessionOptions := &sessions.Options{
 		Path:     "/",
-		MaxAge:   86400, // One day
+		MaxAge:   int(sessionMaxAge / time.Second),
 		Secure:   true,
 		HttpOnly: true,
 	}
-
-	// Bind to a port and pass our router in
-	listenC := make(chan error)
-	for _, listener := range loadedCfg.Listeners {
+	if p.cfg.SessionStore == sessionStoreDatabase {
+		store := newStateSessionStore(dbSessionStorage{p.backend.db},
+			cookieKey)
+		store.Options = sessionOptions
+		p.store = store
+	} else if p.cfg.StateStore == stateStoreMemory {
+		sessionsDir := filepath.Join(p.cfg.DataDir, "sessions")
+		err = os.MkdirAll(sessionsDir, 0700)
+		if err != nil {
+			return err
+		}
+		store := sessions.NewFilesystemStore(sessionsDir, cookieKey)
+		store.Options = sessionOptions
+		p.store = store
+	} else {
+		store := newStateSessionStore(p.backend.stateStore, cookieKey)
+		store.Options = sessionOptions
+		p.store = store
+	}
+
+	// Obtain the certificates from Let's Encrypt if requested.
+	var certManager *autocert.Manager
+	if len(loadedCfg.AutoCertHosts) != 0 {
+		cacheDir := filepath.Join(p.cfg.DataDir, "autocert")
+		certManager = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(cacheDir),
+			HostPolicy: autocert.HostWhitelist(loadedCfg.AutoCertHosts...),
+			Email:      loadedCfg.AutoCertEmail,
+		}
+		log.Infof("Obtaining certificates from Let's Encrypt for %v",
+			strings.Join(loadedCfg.AutoCertHosts, ", "))
+	}
+
+	// Bind to a port and pass our router in.  The channel is buffered so
+	// that the listeners can exit once the servers are shut down.
+	listenC := make(chan error, len(loadedCfg.Listeners)+
+		len(loadedCfg.AdminListeners)+1)
+	var (
+		serversMtx sync.Mutex
+		servers    []*http.Server
+	)
+	addServer := func(srv *http.Server) {
+		serversMtx.Lock()
+		servers = append(servers, srv)
+		serversMtx.Unlock()
+	}
+	if loadedCfg.HTTPRedirect != "" {
+		var handler http.Handler = http.HandlerFunc(redirectToHTTPS)
+		if certManager != nil {
+			handler = certManager.HTTPHandler(handler)
+		}
+		srv := &http.Server{
+			Addr:              loadedCfg.HTTPRedirect,
+			Handler:           handler,
+			ReadHeaderTimeout: readHeaderTimeout,
+			ReadTimeout:       readTimeout,
+			WriteTimeout:      writeTimeout,
+			IdleTimeout:       idleTimeout,
+		}
+		addServer(srv)
+		go func() {
+			log.Infof("Listen http redirect: %v", loadedCfg.HTTPRedirect)
+			listenC <- srv.ListenAndServe()
+		}()
+	}
+	listeners := make([]string, 0, len(loadedCfg.Listeners)+
+		len(loadedCfg.AdminListeners))
+	listeners = append(listeners, loadedCfg.Listeners...)
+	listeners = append(listeners, loadedCfg.AdminListeners...)
+	for k, listener := range listeners {
 		listen := listener
+		admin := k >= len(loadedCfg.Listeners)
 		go func() {
 			cfg := &tls.Config{
 				MinVersion: tls.VersionTLS12,
@@ -1120,16 +3742,34 @@ func _main() error {
 				TLSConfig: cfg,
 				TLSNextProto: make(map[string]func(*http.Server,
 					*tls.Conn, http.Handler)),
+				ReadHeaderTimeout: readHeaderTimeout,
+				ReadTimeout:       readTimeout,
+				WriteTimeout:      writeTimeout,
+				IdleTimeout:       idleTimeout,
 			}
 			var mode string
 			if p.cfg.Proxy {
 				srv.Handler = p.router
 				mode = "proxy"
 			} else {
-				srv.Handler = csrfHandle(p.router)
+				srv.Handler = csrfExempt(csrfHandle(p.router),
+					p.router, v1.RouteSESWebhook,
+					v1.RouteSendgridWebhook, v1.RouteClusterEvent,
+					v1.RouteRefreshToken, v1.RouteRevokeToken,
+					v1.RouteUnsubscribe)
 				mode = "non-proxy"
 			}
+			if admin {
+				srv.Handler = withAdminListener(srv.Handler)
+				mode += " admin"
+			}
+			addServer(srv)
 			log.Infof("Listen %v: %v", mode, listen)
+			if certManager != nil {
+				cfg.GetCertificate = certManager.GetCertificate
+				listenC <- srv.ListenAndServeTLS("", "")
+				return
+			}
 			listenC <- srv.ListenAndServeTLS(loadedCfg.HTTPSCert,
 				loadedCfg.HTTPSKey)
 		}()
@@ -1154,6 +3794,26 @@ func _main() error {
 	}
 done:
 
+	// Stop accepting connections and give the requests in flight time to
+	// complete before the backend is shut down.
+	ctx, cancel := context.WithTimeout(context.Background(),
+		shutdownTimeout)
+	defer cancel()
+
+	serversMtx.Lock()
+	for _, srv := range servers {
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			log.Errorf("Shutdown %v: %v", srv.Addr, err)
+		}
+	}
+	serversMtx.Unlock()
+
+	err = p.backend.Close(ctx)
+	if err != nil {
+		log.Errorf("Close backend: %v", err)
+	}
+
 	log.Infof("Exiting")
 
 	return nil
@@ -0,0 +1,130 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSCredentials are the credentials of an AWS API user.
+type AWSCredentials struct {
+	Region    string // AWS region, e.g. us-east-1
+	AccessKey string // Access key ID
+	SecretKey string // Secret access key
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// awsEscape escapes the provided string as required by AWS signature version
+// 4: every byte except the unreserved characters is percent encoded.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z',
+			'0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalAWSQuery returns the canonical query string of the provided URL,
+// in which the parameters are escaped and sorted by name and value.
+func canonicalAWSQuery(u *url.URL) string {
+	var params [][2]string
+	for k, values := range u.Query() {
+		for _, v := range values {
+			params = append(params, [2]string{awsEscape(k),
+				awsEscape(v)})
+		}
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i][0] != params[j][0] {
+			return params[i][0] < params[j][0]
+		}
+		return params[i][1] < params[j][1]
+	})
+
+	query := make([]string, 0, len(params))
+	for _, v := range params {
+		query = append(query, v[0]+"="+v[1])
+	}
+	return strings.Join(query, "&")
+}
+
+// awsSigningKey derives the key that signs the requests for the provided
+// service in the provided region on the provided date (YYYYMMDD).
+func awsSigningKey(secretKey, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// SignAWSv4 signs the provided request for the provided AWS service using
+// AWS signature version 4.  The Content-Type header and the X-Amz-* headers
+// that are already set on the request are signed along with the host.
+func SignAWSv4(req *http.Request, payload []byte, service string, creds AWSCredentials, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	scope := date + "/" + creds.Region + "/" + service + "/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	// Collect the signed headers in canonical order.
+	headers := map[string]string{
+		"host": req.URL.Host,
+	}
+	for k := range req.Header {
+		name := strings.ToLower(k)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(req.Header.Get(k))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders string
+	for _, v := range names {
+		canonicalHeaders += v + ":" + headers[v] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := req.Method + "\n" +
+		req.URL.EscapedPath() + "\n" +
+		canonicalAWSQuery(req.URL) + "\n" +
+		canonicalHeaders + "\n" +
+		signedHeaders + "\n" +
+		hexSHA256(payload)
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" +
+		hexSHA256([]byte(canonicalRequest))
+
+	key := awsSigningKey(creds.SecretKey, date, creds.Region, service)
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+
+		creds.AccessKey+"/"+scope+", SignedHeaders="+signedHeaders+
+		", Signature="+signature)
+}
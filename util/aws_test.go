@@ -0,0 +1,88 @@
+package util
+
+import (
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSignAWSv4 signs the requests of the AWS signature version 4 test suite
+// and compares the signatures with the published ones.
+func TestSignAWSv4(t *testing.T) {
+	creds := AWSCredentials{
+		Region:    "us-east-1",
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		method        string
+		url           string
+		contentType   string
+		payload       string
+		signedHeaders string
+		signature     string
+	}{
+		{"get-vanilla", http.MethodGet,
+			"https://example.amazonaws.com/", "", "",
+			"host;x-amz-date",
+			"5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"post-vanilla", http.MethodPost,
+			"https://example.amazonaws.com/", "", "",
+			"host;x-amz-date",
+			"5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+		{"get-vanilla-query-order-key-case", http.MethodGet,
+			"https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			"", "", "host;x-amz-date",
+			"b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"get-vanilla-utf8-query", http.MethodGet,
+			"https://example.amazonaws.com/?%E1%88%B4=bar", "", "",
+			"host;x-amz-date",
+			"2cdec8eed098649ff3a119c94853b13c643bcf08f8b0a1d91e12c9027818dd04"},
+		{"post-x-www-form-urlencoded", http.MethodPost,
+			"https://example.amazonaws.com/",
+			"application/x-www-form-urlencoded", "Param1=value1",
+			"content-type;host;x-amz-date",
+			"ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, test.url,
+				strings.NewReader(test.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+			SignAWSv4(req, []byte(test.payload), "service", creds, now)
+
+			if req.Header.Get("X-Amz-Date") != "20150830T123600Z" {
+				t.Fatalf("unexpected date %v",
+					req.Header.Get("X-Amz-Date"))
+			}
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/" +
+				"20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=" + test.signedHeaders + ", " +
+				"Signature=" + test.signature
+			if got := req.Header.Get("Authorization"); got != want {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestAWSSigningKey derives the signing key of the example of the AWS
+// documentation.
+func TestAWSSigningKey(t *testing.T) {
+	key := awsSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		"20120215", "us-east-1", "iam")
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package journal implements append-only journals of JSON encoded actions.
+//
+// Every entry of a journal is a single line that holds the version of the
+// entry, the action that it records and the action specific payload.  Entries
+// are only ever appended so that a journal can be replayed to recreate its
+// state.  Lines that predate the journal format are bare payloads; they are
+// replayed as additions of the current version.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	// Version is the version of the entries that are appended.
+	Version = "1"
+
+	// Journal actions.
+	ActionAdd = "add" // Add the payload
+)
+
+// Entry is a journal entry.
+type Entry struct {
+	Version string          `json:"version"` // Version of the entry
+	Action  string          `json:"action"`  // Action that the entry records
+	Payload json.RawMessage `json:"payload"` // JSON encoded payload of the action
+}
+
+// NewEntry returns an entry of the current version that records the provided
+// action on the JSON encoding of the provided payload.
+func NewEntry(action string, payload interface{}) (Entry, error) {
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return Entry{
+		Version: Version,
+		Action:  action,
+		Payload: p,
+	}, nil
+}
+
+// KeyFunc returns the key of the provided entry.  Only the first entry of each
+// key is replayed; entries with an empty key are always replayed.
+type KeyFunc func(Entry) (string, error)
+
+// decodeEntry decodes a journal line.  Lines that aren't entries are bare
+// payloads from before the journal format.
+func decodeEntry(line []byte) (Entry, error) {
+	var e Entry
+	err := json.Unmarshal(line, &e)
+	if err == nil && e.Version != "" && e.Action != "" && e.Payload != nil {
+		return e, nil
+	}
+
+	if !json.Valid(line) {
+		return Entry{}, fmt.Errorf("invalid journal line: %q", line)
+	}
+	return Entry{
+		Version: Version,
+		Action:  ActionAdd,
+		Payload: json.RawMessage(line),
+	}, nil
+}
+
+// Replay decodes the journal that is read from r and calls replay with each
+// entry in order.  Entries whose key was already replayed are skipped when key
+// is not nil.  A last line without a newline is the remainder of an append
+// that did not complete and is ignored.
+func Replay(r io.Reader, key KeyFunc, replay func(Entry) error) error {
+	keys := make(map[string]struct{})
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		e, err := decodeEntry(line)
+		if err != nil {
+			return err
+		}
+		if key != nil {
+			k, err := key(e)
+			if err != nil {
+				return err
+			}
+			if _, ok := keys[k]; ok && k != "" {
+				continue
+			}
+			keys[k] = struct{}{}
+		}
+
+		err = replay(e)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// truncateTorn drops the remainder of an append that did not complete from
+// the end of the provided journal file.
+func truncateTorn(f *os.File) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() == 0 {
+		return nil
+	}
+
+	last := make([]byte, 1)
+	_, err = f.ReadAt(last, fi.Size()-1)
+	if err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+
+	content, err := ioutil.ReadAll(io.NewSectionReader(f, 0, fi.Size()))
+	if err != nil {
+		return err
+	}
+	return f.Truncate(int64(bytes.LastIndexByte(content, '\n') + 1))
+}
+
+// encodeEntries encodes the provided entries as journal lines.
+func encodeEntries(entries []Entry) ([]byte, error) {
+	var b bytes.Buffer
+	for _, v := range entries {
+		e, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(e)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// Append appends the provided entries to the journal file with the provided
+// filename, which is created if it doesn't exist.  The entries are written at
+// once and synced to disk before Append returns.  A previous append that did
+// not complete is dropped first so that it can't corrupt the new entries.
+func Append(filename string, entries ...Entry) error {
+	b, err := encodeEntries(entries)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = truncateTorn(f)
+	if err != nil {
+		return err
+	}
+	_, err = f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// Rebuild rewrites the journal file with the provided filename with the
+// entries that are replayed from it, which drops the entries whose key was
+// already replayed and the remainder of an append that did not complete.  Bare
+// payloads are rewritten as entries.  The journal is replaced atomically.
+func Rebuild(filename string, key KeyFunc) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	err = Replay(f, key, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	b, err := encodeEntries(entries)
+	if err != nil {
+		return err
+	}
+
+	tmp := filename + ".tmp"
+	tf, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	_, err = tf.Write(b)
+	if err == nil {
+		err = tf.Sync()
+	}
+	if cerr := tf.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, filename)
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package journal
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testPayload struct {
+	Key   string `json:"key"`
+	Value int    `json:"value"`
+}
+
+func testKey(e Entry) (string, error) {
+	var p testPayload
+	err := json.Unmarshal(e.Payload, &p)
+	return p.Key, err
+}
+
+func replayAll(t *testing.T, filename string, key KeyFunc) []testPayload {
+	t.Helper()
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var payloads []testPayload
+	err = Replay(f, key, func(e Entry) error {
+		if e.Version != Version || e.Action != ActionAdd {
+			t.Fatalf("unexpected entry %v %v", e.Version, e.Action)
+		}
+		var p testPayload
+		err := json.Unmarshal(e.Payload, &p)
+		payloads = append(payloads, p)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payloads
+}
+
+func appendPayloads(t *testing.T, filename string, payloads ...testPayload) {
+	t.Helper()
+
+	entries := make([]Entry, 0, len(payloads))
+	for _, v := range payloads {
+		e, err := NewEntry(ActionAdd, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	err := Append(filename, entries...)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "journal")
+
+	// Lines from before the journal format are replayed as additions.
+	err = ioutil.WriteFile(filename, []byte(`{"key":"a","value":1}`+"\n"),
+		0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendPayloads(t, filename, testPayload{"b", 2}, testPayload{"a", 3})
+
+	payloads := replayAll(t, filename, nil)
+	if len(payloads) != 3 || payloads[0].Value != 1 ||
+		payloads[1].Value != 2 || payloads[2].Value != 3 {
+		t.Fatalf("unexpected payloads %v", payloads)
+	}
+	payloads = replayAll(t, filename, testKey)
+	if len(payloads) != 2 || payloads[0].Value != 1 ||
+		payloads[1].Value != 2 {
+		t.Fatalf("unexpected deduplicated payloads %v", payloads)
+	}
+
+	// The remainder of an append that did not complete is ignored and
+	// dropped by the next append.
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteString(`{"version":"1","action":"add","pay`)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloads = replayAll(t, filename, nil)
+	if len(payloads) != 3 {
+		t.Fatalf("unexpected payloads %v", payloads)
+	}
+	appendPayloads(t, filename, testPayload{"c", 4})
+	payloads = replayAll(t, filename, nil)
+	if len(payloads) != 4 || payloads[3].Value != 4 {
+		t.Fatalf("unexpected payloads %v", payloads)
+	}
+
+	// Rebuilding drops the duplicates and versions every line.
+	err = Rebuild(filename, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("unexpected journal %q", content)
+	}
+	for _, v := range lines {
+		if !strings.HasPrefix(v, `{"version":"1","action":"add",`) {
+			t.Fatalf("unexpected journal line %q", v)
+		}
+	}
+	payloads = replayAll(t, filename, nil)
+	if len(payloads) != 3 || payloads[0].Value != 1 ||
+		payloads[1].Value != 2 || payloads[2].Value != 4 {
+		t.Fatalf("unexpected payloads %v", payloads)
+	}
+}
+
+func TestReplayInvalid(t *testing.T) {
+	err := Replay(strings.NewReader("{\"key\":\"a\"}\nnot json\n"), nil,
+		func(Entry) error { return nil })
+	if err == nil {
+		t.Fatalf("expected an invalid journal line error")
+	}
+}
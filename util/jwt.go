@@ -0,0 +1,96 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrJWTInvalid indicates that a JWT is malformed or that its
+	// signature is invalid.
+	ErrJWTInvalid = errors.New("invalid token")
+
+	// ErrJWTExpired indicates that a JWT expired.
+	ErrJWTExpired = errors.New("token expired")
+
+	// jwtHeader is the encoded header of the JWTs that are signed with
+	// HMAC-SHA256.
+	jwtHeader = base64.RawURLEncoding.EncodeToString(
+		[]byte(`{"alg":"HS256","typ":"JWT"}`))
+)
+
+// JWTClaims are the registered JWT claims.  JWTVerify checks the expiry.
+type JWTClaims struct {
+	Subject  string `json:"sub,omitempty"` // Subject of the token
+	ID       string `json:"jti,omitempty"` // Unique id of the token
+	IssuedAt int64  `json:"iat,omitempty"` // Time the token was issued
+	Expiry   int64  `json:"exp,omitempty"` // Time the token expires
+}
+
+// jwtSignature returns the encoded HMAC-SHA256 signature of the provided
+// signing input.
+func jwtSignature(input string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(input))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// JWTSign returns an RFC 7519 JWT of the provided claims that is signed with
+// HMAC-SHA256.
+func JWTSign(claims interface{}, key []byte) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return input + "." + jwtSignature(input, key), nil
+}
+
+// JWTVerify verifies the signature of the provided JWT, which must have been
+// signed with HMAC-SHA256, and decodes its claims into the provided claims.
+// It returns ErrJWTExpired if the token expired at the provided time.
+func JWTVerify(token string, key []byte, claims interface{}, t time.Time) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrJWTInvalid
+	}
+
+	// Only HMAC-SHA256 is accepted so that the algorithm can't be
+	// downgraded by the token.
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrJWTInvalid
+	}
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	if json.Unmarshal(header, &h) != nil || h.Alg != "HS256" {
+		return ErrJWTInvalid
+	}
+	signature := jwtSignature(parts[0]+"."+parts[1], key)
+	if !hmac.Equal([]byte(signature), []byte(parts[2])) {
+		return ErrJWTInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ErrJWTInvalid
+	}
+	var registered JWTClaims
+	if json.Unmarshal(payload, &registered) != nil {
+		return ErrJWTInvalid
+	}
+	if registered.Expiry != 0 && t.Unix() >= registered.Expiry {
+		return ErrJWTExpired
+	}
+	if json.Unmarshal(payload, claims) != nil {
+		return ErrJWTInvalid
+	}
+
+	return nil
+}
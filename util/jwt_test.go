@@ -0,0 +1,54 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWT(t *testing.T) {
+	key := []byte("secret")
+	now := time.Unix(1539692400, 0)
+
+	type claims struct {
+		JWTClaims
+		Email string `json:"email"`
+	}
+	c := claims{
+		JWTClaims: JWTClaims{
+			Subject: "1",
+			Expiry:  now.Add(time.Minute).Unix(),
+		},
+		Email: "user@example.com",
+	}
+	token, err := JWTSign(c, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got claims
+	err = JWTVerify(token, key, &got, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != c {
+		t.Fatalf("got %v, want %v", got, c)
+	}
+
+	err = JWTVerify(token, key, &got, now.Add(time.Minute))
+	if err != ErrJWTExpired {
+		t.Fatalf("got %v, want %v", err, ErrJWTExpired)
+	}
+	err = JWTVerify(token, []byte("other"), &got, now)
+	if err != ErrJWTInvalid {
+		t.Fatalf("got %v, want %v", err, ErrJWTInvalid)
+	}
+
+	// Tokens that are not signed with HMAC-SHA256 are rejected.
+	parts := strings.Split(token, ".")
+	none := "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0." + parts[1] + "."
+	err = JWTVerify(none, key, &got, now)
+	if err != ErrJWTInvalid {
+		t.Fatalf("got %v, want %v", err, ErrJWTInvalid)
+	}
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+)
+
+// ProofOfWorkNonceSize is the size of the nonces that SolveProofOfWork
+// returns.
+const ProofOfWorkNonceSize = 8
+
+// ProofOfWorkValid returns true if the SHA256 digest of the challenge
+// followed by the nonce starts with at least difficulty zero bits.
+func ProofOfWorkValid(challenge, nonce []byte, difficulty uint) bool {
+	h := sha256.New()
+	h.Write(challenge)
+	h.Write(nonce)
+	digest := h.Sum(nil)
+
+	var zeros uint
+	for _, v := range digest {
+		if v != 0 {
+			zeros += uint(bits.LeadingZeros8(v))
+			break
+		}
+		zeros += 8
+	}
+	return zeros >= difficulty
+}
+
+// SolveProofOfWork returns a nonce that solves the provided challenge at the
+// provided difficulty.  Every additional bit of difficulty doubles the
+// expected work.
+func SolveProofOfWork(challenge []byte, difficulty uint) []byte {
+	nonce := make([]byte, ProofOfWorkNonceSize)
+	for i := uint64(0); ; i++ {
+		binary.BigEndian.PutUint64(nonce, i)
+		if ProofOfWorkValid(challenge, nonce, difficulty) {
+			return nonce
+		}
+	}
+}
@@ -40,6 +40,13 @@ func IsValidProposalName(str string) bool {
 
 // CreateProposalTitleRegex returns a regex string for matching the proposal name
 func CreateProposalTitleRegex() string {
+	return ProposalNameRegex(www.PolicyMinProposalNameLength,
+		www.PolicyMaxProposalNameLength)
+}
+
+// ProposalNameRegex returns a regex string for matching the proposal names
+// whose length is within the provided bounds.
+func ProposalNameRegex(minLength, maxLength int) string {
 	var validProposalNameBuffer bytes.Buffer
 	validProposalNameBuffer.WriteString("^[")
 
@@ -51,8 +58,8 @@ func CreateProposalTitleRegex() string {
 		}
 	}
 	validProposalNameBuffer.WriteString("]{")
-	validProposalNameBuffer.WriteString(strconv.Itoa(www.PolicyMinProposalNameLength) + ",")
-	validProposalNameBuffer.WriteString(strconv.Itoa(www.PolicyMaxProposalNameLength) + "}$")
+	validProposalNameBuffer.WriteString(strconv.Itoa(minLength) + ",")
+	validProposalNameBuffer.WriteString(strconv.Itoa(maxLength) + "}$")
 
 	return validProposalNameBuffer.String()
 }
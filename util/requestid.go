@@ -0,0 +1,73 @@
+package util
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	// RequestIDHeader is the HTTP header that carries the request ID.  It
+	// is set on the replies of politeiawww and forwarded to politeiad so
+	// that a request can be traced across both daemons.
+	RequestIDHeader = "X-Request-ID"
+
+	// requestIDMaxLength is the maximum length of a request ID that is
+	// accepted from a client.
+	requestIDMaxLength = 64
+)
+
+// requestIDKey is the context key of the request ID.
+type requestIDKey struct{}
+
+// NewRequestID returns a new random request ID.
+func NewRequestID() string {
+	b, err := Random(8)
+	if err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID returns true if the provided request ID, which was provided
+// by a client, is safe to be logged.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > requestIDMaxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z':
+		case c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDFromHTTP returns the request ID that was provided in the headers
+// of the provided request, or a new request ID if none or an invalid one was
+// provided.
+func RequestIDFromHTTP(r *http.Request) string {
+	id := r.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		return NewRequestID()
+	}
+	return id
+}
+
+// WithRequestID returns a copy of the provided context that carries the
+// provided request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID that is carried by the provided context,
+// or an empty string if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,276 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// secretTimeout is the timeout of the requests to remote secret stores.
+const secretTimeout = 30 * time.Second
+
+// SecretProvider fetches secrets from an external secret store.  The
+// reference identifies the secret within the store.
+type SecretProvider interface {
+	Secret(ref string) (string, error)
+}
+
+// Secrets resolves configuration values that reference a secret in an
+// external secret store.  A reference is made up of the name of the provider
+// followed by a colon and the provider specific reference, for example
+// file:/run/secrets/rpcpass.  Values that don't start with the name of a
+// registered provider are used as is.
+type Secrets struct {
+	providers map[string]SecretProvider
+}
+
+// NewSecrets returns a secret resolver with the providers that don't require
+// any configuration registered: file, fd and env.
+func NewSecrets() *Secrets {
+	s := &Secrets{
+		providers: make(map[string]SecretProvider),
+	}
+	s.Register("file", fileSecrets{})
+	s.Register("fd", fdSecrets{})
+	s.Register("env", envSecrets{})
+	return s
+}
+
+// NewConfiguredSecrets returns a secret resolver that, in addition to the
+// providers of NewSecrets, reads secrets from Vault and decrypts secrets with
+// AWS KMS when these are configured.  The Vault token and the AWS keys may
+// themselves reference a file, fd or env secret.
+func NewConfiguredSecrets(vaultAddress, vaultToken string, kms AWSCredentials) (*Secrets, error) {
+	s := NewSecrets()
+	err := s.ResolveAll(&vaultToken, &kms.AccessKey, &kms.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if vaultAddress != "" {
+		s.Register("vault", NewVaultSecrets(vaultAddress, vaultToken))
+	}
+	if kms.Region != "" {
+		s.Register("kms", NewKMSSecrets(kms))
+	}
+	return s, nil
+}
+
+// Register registers the provided provider under the provided name.
+func (s *Secrets) Register(name string, p SecretProvider) {
+	s.providers[name] = p
+}
+
+// Resolve returns the secret that the provided value references, or the
+// value itself if it isn't a reference.
+func (s *Secrets) Resolve(value string) (string, error) {
+	i := strings.Index(value, ":")
+	if i < 0 {
+		return value, nil
+	}
+	p, ok := s.providers[value[:i]]
+	if !ok {
+		return value, nil
+	}
+	secret, err := p.Secret(value[i+1:])
+	if err != nil {
+		return "", fmt.Errorf("%v secret: %v", value[:i], err)
+	}
+	return secret, nil
+}
+
+// ResolveAll resolves all of the provided values in place.
+func (s *Secrets) ResolveAll(values ...*string) error {
+	for _, v := range values {
+		secret, err := s.Resolve(*v)
+		if err != nil {
+			return err
+		}
+		*v = secret
+	}
+	return nil
+}
+
+// fileSecrets reads secrets from files, e.g. file:/run/secrets/rpcpass.
+type fileSecrets struct{}
+
+// Secret satisfies the SecretProvider interface.
+func (fileSecrets) Secret(ref string) (string, error) {
+	b, err := ioutil.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// fdSecrets reads secrets from file descriptors that were inherited from the
+// parent process, e.g. fd:3.
+type fdSecrets struct{}
+
+// Secret satisfies the SecretProvider interface.
+func (fdSecrets) Secret(ref string) (string, error) {
+	fd, err := strconv.ParseUint(ref, 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("invalid file descriptor: %v", ref)
+	}
+	f := os.NewFile(uintptr(fd), "fd"+ref)
+	if f == nil {
+		return "", fmt.Errorf("invalid file descriptor: %v", ref)
+	}
+	defer f.Close()
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// envSecrets reads secrets from environment variables, e.g. env:RPCPASS.
+type envSecrets struct{}
+
+// Secret satisfies the SecretProvider interface.
+func (envSecrets) Secret(ref string) (string, error) {
+	secret, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable not set: %v", ref)
+	}
+	return secret, nil
+}
+
+// VaultSecrets reads secrets from a HashiCorp Vault key/value store.  The
+// reference is the path of the secret followed by the name of the field,
+// e.g. vault:secret/data/politeia#rpcpass.  Both versions of the key/value
+// store are supported.
+type VaultSecrets struct {
+	client  *http.Client
+	address string
+	token   string
+}
+
+// NewVaultSecrets returns a provider that reads secrets from the Vault
+// server at the provided address using the provided token.
+func NewVaultSecrets(address, token string) *VaultSecrets {
+	return &VaultSecrets{
+		client:  &http.Client{Timeout: secretTimeout},
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+	}
+}
+
+// Secret satisfies the SecretProvider interface.
+func (v *VaultSecrets) Secret(ref string) (string, error) {
+	i := strings.LastIndex(ref, "#")
+	if i < 0 {
+		return "", fmt.Errorf("missing field: %v", ref)
+	}
+	path, field := strings.TrimLeft(ref[:i], "/"), ref[i+1:]
+
+	req, err := http.NewRequest(http.MethodGet,
+		v.address+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+
+	r, err := v.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%v: %v", path, r.Status)
+	}
+
+	var reply struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&reply)
+	if err != nil {
+		return "", err
+	}
+
+	// Version 2 of the key/value store nests the fields.
+	data := reply.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data[field]; !ok {
+			data = nested
+		}
+	}
+	secret, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("%v: field not found: %v", path, field)
+	}
+	return secret, nil
+}
+
+// KMSSecrets decrypts secrets that were encrypted with AWS KMS.  The
+// reference is the base64 encoded ciphertext, e.g. kms:AQICAHh...
+type KMSSecrets struct {
+	client   *http.Client
+	endpoint string // KMS endpoint of the region
+	creds    AWSCredentials
+}
+
+// NewKMSSecrets returns a provider that decrypts secrets with AWS KMS using
+// the provided credentials.
+func NewKMSSecrets(creds AWSCredentials) *KMSSecrets {
+	return &KMSSecrets{
+		client:   &http.Client{Timeout: secretTimeout},
+		endpoint: "https://kms." + creds.Region + ".amazonaws.com/",
+		creds:    creds,
+	}
+}
+
+// Secret satisfies the SecretProvider interface.
+func (k *KMSSecrets) Secret(ref string) (string, error) {
+	payload, err := json.Marshal(struct {
+		CiphertextBlob string `json:"CiphertextBlob"`
+	}{
+		CiphertextBlob: ref,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, k.endpoint,
+		bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+	SignAWSv4(req, payload, "kms", k.creds, time.Now())
+
+	r, err := k.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(r.Body)
+		return "", fmt.Errorf("%v: %s", r.Status, body)
+	}
+
+	var reply struct {
+		Plaintext string `json:"Plaintext"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&reply)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(reply.Plaintext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
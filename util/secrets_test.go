@@ -0,0 +1,203 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSecretsResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "rpcpass")
+	err = ioutil.WriteFile(filename, []byte("filesecret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("POLITEIA_TEST_SECRET", "envsecret")
+	defer os.Unsetenv("POLITEIA_TEST_SECRET")
+
+	s := NewSecrets()
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "plain", false},
+		{"", "", false},
+		{"unknown:value", "unknown:value", false},
+		{"file:" + filename, "filesecret", false},
+		{"file:" + filepath.Join(dir, "missing"), "", true},
+		{"env:POLITEIA_TEST_SECRET", "envsecret", false},
+		{"env:POLITEIA_TEST_MISSING", "", true},
+		{"fd:notanumber", "", true},
+	}
+	for _, test := range tests {
+		got, err := s.Resolve(test.value)
+		if (err != nil) != test.wantErr {
+			t.Fatalf("%v: got error %v, want error %v", test.value,
+				err, test.wantErr)
+		}
+		if got != test.want {
+			t.Fatalf("%v: got %q, want %q", test.value, got,
+				test.want)
+		}
+	}
+
+	// Resolve in place.
+	a, b := "env:POLITEIA_TEST_SECRET", "plain"
+	err = s.ResolveAll(&a, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != "envsecret" || b != "plain" {
+		t.Fatalf("unexpected secrets: %v %v", a, b)
+	}
+}
+
+func TestVaultSecrets(t *testing.T) {
+	const token = "s.vaulttoken"
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Vault-Token") != token {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			switch r.URL.Path {
+			case "/v1/secret/politeia":
+				// Version 1 of the key/value store.
+				w.Write([]byte(`{"data":{"rpcpass":"v1secret"}}`))
+			case "/v1/secret/data/politeia":
+				// Version 2 of the key/value store.
+				w.Write([]byte(`{"data":{"data":{"rpcpass":` +
+					`"v2secret"},"metadata":{"version":1}}}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer server.Close()
+
+	v := NewVaultSecrets(server.URL+"/", token)
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"secret/politeia#rpcpass", "v1secret", false},
+		{"/secret/data/politeia#rpcpass", "v2secret", false},
+		{"secret/data/politeia#missing", "", true},
+		{"secret/missing#rpcpass", "", true},
+		{"secret/politeia", "", true},
+	}
+	for _, test := range tests {
+		got, err := v.Secret(test.ref)
+		if (err != nil) != test.wantErr {
+			t.Fatalf("%v: got error %v, want error %v", test.ref,
+				err, test.wantErr)
+		}
+		if got != test.want {
+			t.Fatalf("%v: got %q, want %q", test.ref, got, test.want)
+		}
+	}
+
+	// The token is required.
+	_, err := NewVaultSecrets(server.URL, "wrong").Secret(
+		"secret/politeia#rpcpass")
+	if err == nil {
+		t.Fatalf("expected a forbidden error")
+	}
+}
+
+func TestKMSSecrets(t *testing.T) {
+	creds := AWSCredentials{
+		Region:    "us-east-1",
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	const ciphertext = "AQICAHhciphertext=="
+
+	server := httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			payload, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			// The request must be signed for the kms service at the
+			// time it was sent.
+			now, err := time.Parse("20060102T150405Z",
+				r.Header.Get("X-Amz-Date"))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			req, err := http.NewRequest(r.Method,
+				"https://"+r.Host+r.URL.RequestURI(),
+				bytes.NewReader(payload))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+			req.Header.Set("X-Amz-Target", r.Header.Get("X-Amz-Target"))
+			SignAWSv4(req, payload, "kms", creds, now)
+			if r.Header.Get("Authorization") !=
+				req.Header.Get("Authorization") {
+				t.Errorf("got authorization %v, want %v",
+					r.Header.Get("Authorization"),
+					req.Header.Get("Authorization"))
+			}
+
+			var decrypt struct {
+				CiphertextBlob string `json:"CiphertextBlob"`
+			}
+			err = json.Unmarshal(payload, &decrypt)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if r.Header.Get("X-Amz-Target") !=
+				"TrentService.Decrypt" ||
+				r.Header.Get("Content-Type") !=
+					"application/x-amz-json-1.1" ||
+				decrypt.CiphertextBlob != ciphertext {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			// base64 of kmssecret.
+			w.Write([]byte(`{"KeyId":"key","Plaintext":` +
+				`"a21zc2VjcmV0"}`))
+		}))
+	defer server.Close()
+
+	k := NewKMSSecrets(creds)
+	if k.endpoint != "https://kms.us-east-1.amazonaws.com/" {
+		t.Fatalf("unexpected endpoint %v", k.endpoint)
+	}
+	k.client = server.Client()
+	k.endpoint = server.URL + "/"
+
+	got, err := k.Secret(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "kmssecret" {
+		t.Fatalf("got %q, want %q", got, "kmssecret")
+	}
+	_, err = k.Secret("other")
+	if err == nil {
+		t.Fatalf("expected a decryption error")
+	}
+}
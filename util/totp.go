@@ -0,0 +1,83 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	// TOTPSecretSize is the size of TOTP secrets in bytes.
+	TOTPSecretSize = 20
+
+	// TOTPDigits is the number of digits of TOTP codes.
+	TOTPDigits = 6
+
+	// TOTPPeriod is the number of seconds a TOTP code is valid for.
+	TOTPPeriod = 30
+)
+
+// TOTPEncoding is the encoding of TOTP secrets in provisioning URIs and for
+// manual entry into authenticator apps.
+var TOTPEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPStep returns the RFC 6238 time step of the provided time.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / TOTPPeriod
+}
+
+// TOTPCode returns the RFC 6238 code of the provided secret at the provided
+// time step, using HMAC-SHA1.
+func TOTPCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation.
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", code%1000000)
+}
+
+// TOTPValid returns the time step of the provided code if it is valid for the
+// provided secret at the provided time.  The codes of the adjacent time steps
+// are accepted as well to allow for clock skew.
+func TOTPValid(secret []byte, code string, t time.Time) (int64, bool) {
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+	now := TOTPStep(t)
+	for step := now - 1; step <= now+1; step++ {
+		if subtle.ConstantTimeCompare([]byte(TOTPCode(secret, step)),
+			[]byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPURI returns the provisioning URI of the provided secret that
+// authenticator apps import, usually from a QR code.
+func TOTPURI(issuer, account string, secret []byte) string {
+	v := url.Values{}
+	v.Set("secret", TOTPEncoding.EncodeToString(secret))
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(TOTPDigits))
+	v.Set("period", fmt.Sprint(TOTPPeriod))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: v.Encode(),
+	}
+	return u.String()
+}
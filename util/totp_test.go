@@ -0,0 +1,43 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 appendix B test vectors, truncated to 6 digits.
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, test := range tests {
+		now := time.Unix(test.time, 0)
+		code := TOTPCode(secret, TOTPStep(now))
+		if code != test.code {
+			t.Fatalf("%v: got %v, want %v", test.time, code, test.code)
+		}
+		step, ok := TOTPValid(secret, code, now.Add(TOTPPeriod*time.Second))
+		if !ok || step != TOTPStep(now) {
+			t.Fatalf("%v: code not accepted", test.time)
+		}
+		_, ok = TOTPValid(secret, code, now.Add(2*TOTPPeriod*time.Second))
+		if ok {
+			t.Fatalf("%v: expired code accepted", test.time)
+		}
+	}
+
+	uri := TOTPURI("Politeia", "user@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Politeia:user@example.com?") ||
+		!strings.Contains(uri, "secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ") {
+		t.Fatalf("unexpected uri: %v", uri)
+	}
+}
@@ -0,0 +1,51 @@
+package util
+
+import (
+	"fmt"
+	"io"
+)
+
+// Check is a single check that is performed when validating the
+// configuration of a daemon.
+type Check struct {
+	Name string       // Description of the check
+	Run  func() error // Performs the check
+}
+
+// SkipCheck is returned by a check that doesn't apply to the configuration,
+// for instance because the feature it checks is disabled.  It contains the
+// reason the check was skipped.
+type SkipCheck string
+
+// Error satisfies the error interface.
+func (s SkipCheck) Error() string {
+	return string(s)
+}
+
+// RunChecks runs all of the provided checks and writes a report of their
+// outcome to the provided writer.  It returns an error if any check failed.
+// Skipped checks are reported but don't fail the validation.
+func RunChecks(w io.Writer, checks []Check) error {
+	var failed, skipped int
+	for _, c := range checks {
+		err := c.Run()
+		if s, ok := err.(SkipCheck); ok {
+			skipped++
+			fmt.Fprintf(w, "[SKIP] %v: %v\n", c.Name, s)
+			continue
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "[FAIL] %v: %v\n", c.Name, err)
+			continue
+		}
+		fmt.Fprintf(w, "[ OK ] %v\n", c.Name)
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("%v of %v checks failed", failed, len(checks))
+	}
+	fmt.Fprintf(w, "All %v checks passed, %v skipped\n",
+		len(checks)-skipped, skipped)
+	return nil
+}
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRunChecks(t *testing.T) {
+	ok := Check{Name: "ok", Run: func() error { return nil }}
+	skip := Check{Name: "skip", Run: func() error {
+		return SkipCheck("not configured")
+	}}
+	fail := Check{Name: "fail", Run: func() error {
+		return fmt.Errorf("broken")
+	}}
+
+	// Skipped checks don't fail the validation.
+	var w bytes.Buffer
+	err := RunChecks(&w, []Check{ok, skip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.String(), "[SKIP] skip: not configured") {
+		t.Fatalf("unexpected report: %v", w.String())
+	}
+
+	w.Reset()
+	err = RunChecks(&w, []Check{ok, skip, fail})
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if !strings.Contains(w.String(), "[FAIL] fail: broken") {
+		t.Fatalf("unexpected report: %v", w.String())
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package wallet signs proposal votes with the tickets of a dcrwallet that is
+// reached over gRPC.
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	pb "github.com/decred/dcrwallet/rpc/walletrpc"
+	"github.com/decred/politeia/decredplugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Client is a dcrwallet gRPC client.
+type Client struct {
+	conn   *grpc.ClientConn
+	wallet pb.WalletServiceClient
+}
+
+// Ticket is a ticket of the wallet that is eligible to vote.
+type Ticket struct {
+	Hash    string // Ticket hash
+	Address string // Address that signs the votes of the ticket
+}
+
+// New returns a client that is connected to the dcrwallet gRPC server at the
+// provided host.  The server is authenticated with the certificate in the
+// provided file.
+func New(host, certFile string) (*Client, error) {
+	creds, err := credentials.NewClientTLSFromFile(certFile, "")
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.Dial(host, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		conn:   conn,
+		wallet: pb.NewWalletServiceClient(conn),
+	}, nil
+}
+
+// Close closes the connection to the wallet.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// BestBlock returns the height of the best block of the wallet.
+func (c *Client) BestBlock(ctx context.Context) (uint32, error) {
+	ar, err := c.wallet.Accounts(ctx, &pb.AccountsRequest{})
+	if err != nil {
+		return 0, err
+	}
+	return uint32(ar.CurrentBlockHeight), nil
+}
+
+// EligibleTickets returns the tickets of the wallet among the provided ticket
+// hashes, which usually are the eligible tickets of a vote.
+func (c *Client) EligibleTickets(ctx context.Context, hashes []string) ([]Ticket, error) {
+	tickets := make([][]byte, 0, len(hashes))
+	for _, v := range hashes {
+		h, err := chainhash.NewHashFromStr(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ticket %v: %v", v, err)
+		}
+		tickets = append(tickets, h[:])
+	}
+
+	ctr, err := c.wallet.CommittedTickets(ctx,
+		&pb.CommittedTicketsRequest{
+			Tickets: tickets,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	eligible := make([]Ticket, 0, len(ctr.TicketAddresses))
+	for _, v := range ctr.TicketAddresses {
+		h, err := chainhash.NewHash(v.Ticket)
+		if err != nil {
+			return nil, err
+		}
+		eligible = append(eligible, Ticket{
+			Hash:    h.String(),
+			Address: v.Address,
+		})
+	}
+
+	return eligible, nil
+}
+
+// VoteMessage returns the message that the ticket signs to cast a vote, which
+// is the one that politeiad verifies.
+func VoteMessage(token, ticket, voteBit string) string {
+	return token + ticket + voteBit
+}
+
+// SignVotes returns the votes of the provided tickets for the provided vote
+// bit on the proposal with the provided token.  The votes are signed by the
+// wallet, which is unlocked with the provided passphrase, and are in the order
+// of the tickets.
+func (c *Client) SignVotes(ctx context.Context, passphrase []byte, token, voteBit string, tickets []Ticket) ([]decredplugin.CastVote, error) {
+	sm := &pb.SignMessagesRequest{
+		Passphrase: passphrase,
+		Messages: make([]*pb.SignMessagesRequest_Message, 0,
+			len(tickets)),
+	}
+	for _, v := range tickets {
+		sm.Messages = append(sm.Messages, &pb.SignMessagesRequest_Message{
+			Address: v.Address,
+			Message: VoteMessage(token, v.Hash, voteBit),
+		})
+	}
+	smr, err := c.wallet.SignMessages(ctx, sm)
+	if err != nil {
+		return nil, err
+	}
+	if len(smr.Replies) != len(tickets) {
+		return nil, fmt.Errorf("unexpected number of signatures: got %v "+
+			"want %v", len(smr.Replies), len(tickets))
+	}
+
+	votes := make([]decredplugin.CastVote, 0, len(tickets))
+	for k, v := range smr.Replies {
+		if v.Error != "" {
+			return nil, fmt.Errorf("signature failed ticket %v: %v",
+				tickets[k].Hash, v.Error)
+		}
+		votes = append(votes, decredplugin.CastVote{
+			Token:     token,
+			Ticket:    tickets[k].Hash,
+			VoteBit:   voteBit,
+			Signature: hex.EncodeToString(v.Signature),
+		})
+	}
+
+	return votes, nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	pb "github.com/decred/dcrwallet/rpc/walletrpc"
+	"google.golang.org/grpc"
+)
+
+// testWallet answers the requests of the client like a wallet that owns the
+// tickets in owned.
+type testWallet struct {
+	pb.WalletServiceClient
+	owned map[chainhash.Hash]string // [ticket]address
+}
+
+func (w *testWallet) CommittedTickets(ctx context.Context, in *pb.CommittedTicketsRequest, opts ...grpc.CallOption) (*pb.CommittedTicketsResponse, error) {
+	var ctr pb.CommittedTicketsResponse
+	for _, v := range in.Tickets {
+		h, err := chainhash.NewHash(v)
+		if err != nil {
+			return nil, err
+		}
+		address, ok := w.owned[*h]
+		if !ok {
+			continue
+		}
+		ctr.TicketAddresses = append(ctr.TicketAddresses,
+			&pb.CommittedTicketsResponse_TicketAddress{
+				Ticket:  v,
+				Address: address,
+			})
+	}
+	return &ctr, nil
+}
+
+func (w *testWallet) SignMessages(ctx context.Context, in *pb.SignMessagesRequest, opts ...grpc.CallOption) (*pb.SignMessagesResponse, error) {
+	var smr pb.SignMessagesResponse
+	for _, v := range in.Messages {
+		smr.Replies = append(smr.Replies,
+			&pb.SignMessagesResponse_SignReply{
+				Signature: []byte(v.Address + v.Message),
+			})
+	}
+	return &smr, nil
+}
+
+func TestSignVotes(t *testing.T) {
+	token := "642eb2f3798090b3234d8787aaba046f1f4409436d40994643213b63cb3f41da"
+	hashes := []string{
+		"1257089bfa5223739c27dd10150de71962442f57ee176389c79932c22536b31b",
+		"1c1e0b6968813f8321e721598f9510afae6acaa8576b64297e34fd5777d8d417",
+	}
+	owned, err := chainhash.NewHashFromStr(hashes[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{
+		wallet: &testWallet{
+			owned: map[chainhash.Hash]string{*owned: "address"},
+		},
+	}
+
+	tickets, err := c.EligibleTickets(context.Background(), hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickets) != 1 || tickets[0].Hash != hashes[1] ||
+		tickets[0].Address != "address" {
+		t.Fatalf("unexpected tickets %v", tickets)
+	}
+
+	votes, err := c.SignVotes(context.Background(), nil, token, "2",
+		tickets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(votes) != 1 || votes[0].Token != token ||
+		votes[0].Ticket != hashes[1] || votes[0].VoteBit != "2" {
+		t.Fatalf("unexpected votes %v", votes)
+	}
+	signed := []byte("address" + VoteMessage(token, hashes[1], "2"))
+	if votes[0].Signature != hex.EncodeToString(signed) {
+		t.Fatalf("unexpected signature %v", votes[0].Signature)
+	}
+
+	_, err = c.EligibleTickets(context.Background(), []string{"x"})
+	if err == nil {
+		t.Fatalf("expected an invalid ticket error")
+	}
+}